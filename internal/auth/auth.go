@@ -58,11 +58,29 @@ func WSUpgradeResponseHeader(c *gin.Context) http.Header {
 type Claims struct {
 	UserID       uint   `json:"user_id"`
 	Username     string `json:"username"`
+	Role         string `json:"role,omitempty"`
 	Pending2FA   bool   `json:"pending_2fa,omitempty"`
 	TokenVersion int    `json:"tv,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// Token lifetimes, set from config at startup via SetTokenLifetimes.
+var (
+	jwtTTL       = 24 * time.Hour
+	tempTokenTTL = 5 * time.Minute
+)
+
+// SetTokenLifetimes overrides the lifetimes used by GenerateToken and
+// GenerateTempToken. Non-positive values leave the current setting unchanged.
+func SetTokenLifetimes(session, temp time.Duration) {
+	if session > 0 {
+		jwtTTL = session
+	}
+	if temp > 0 {
+		tempTokenTTL = temp
+	}
+}
+
 // HashPassword hashes a plaintext password with bcrypt
 func HashPassword(password string) (string, error) {
 	bytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
@@ -75,11 +93,13 @@ func CheckPassword(hashedPassword, password string) bool {
 	return err == nil
 }
 
-// GenerateToken creates a JWT token for a given user. The optional
-// tokenVersion embeds the user's current TokenVersion (claim "tv") so the
-// middleware can revoke outstanding tokens on password/role change; omitting
-// it yields tv=0 (legacy callers / tests).
-func GenerateToken(userID uint, username, secret string, tokenVersion ...int) (string, error) {
+// GenerateToken creates a JWT token for a given user. role is embedded as the
+// "role" claim for the RBAC middleware ("" omits it, which makes RequireRole
+// look the role up instead). The optional tokenVersion embeds the user's
+// current TokenVersion (claim "tv") so the middleware can revoke outstanding
+// tokens on password/role change; omitting it yields tv=0 (legacy callers /
+// tests).
+func GenerateToken(userID uint, username, role, secret string, tokenVersion ...int) (string, error) {
 	tv := 0
 	if len(tokenVersion) > 0 {
 		tv = tokenVersion[0]
@@ -87,9 +107,10 @@ func GenerateToken(userID uint, username, secret string, tokenVersion ...int) (s
 	claims := Claims{
 		UserID:       userID,
 		Username:     username,
+		Role:         role,
 		TokenVersion: tv,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(jwtTTL)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			Issuer:    "webcasa",
 		},
@@ -99,7 +120,8 @@ func GenerateToken(userID uint, username, secret string, tokenVersion ...int) (s
 	return token.SignedString([]byte(secret))
 }
 
-// GenerateTempToken creates a short-lived JWT (5 min) with pending_2fa flag
+// GenerateTempToken creates a short-lived JWT (5 min by default) with the
+// pending_2fa flag
 func GenerateTempToken(userID uint, username, secret string, tokenVersion ...int) (string, error) {
 	tv := 0
 	if len(tokenVersion) > 0 {
@@ -111,7 +133,7 @@ func GenerateTempToken(userID uint, username, secret string, tokenVersion ...int
 		Pending2FA:   true,
 		TokenVersion: tv,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(5 * time.Minute)),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(tempTokenTTL)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			Issuer:    "webcasa",
 		},
@@ -227,6 +249,9 @@ func Middleware(secret string, opts ...MiddlewareOption) gin.HandlerFunc {
 		// Store user info in context
 		c.Set("user_id", claims.UserID)
 		c.Set("username", claims.Username)
+		if claims.Role != "" {
+			c.Set("user_role", claims.Role)
+		}
 
 		// Reject pending_2fa tokens from accessing protected routes
 		if claims.Pending2FA {
@@ -242,14 +267,22 @@ func Middleware(secret string, opts ...MiddlewareOption) gin.HandlerFunc {
 		// TokenVersion is bumped (password change, role change, logout-all).
 		// Missing claim (legacy token) = 0 and default user = 0, so existing
 		// tokens stay valid until the first bump. One indexed lookup by id.
+		// A role claim that no longer matches the stored role is treated the
+		// same way, so the claim can be trusted downstream.
 		if cfg.db != nil {
-			var tv int
-			if err := cfg.db.Model(&model.User{}).Select("token_version").Where("id = ?", claims.UserID).Scan(&tv).Error; err != nil {
+			var row struct {
+				TokenVersion int
+				Role         string
+			}
+			err := cfg.db.Model(&model.User{}).Select("token_version, role").Where("id = ?", claims.UserID).Take(&row).Error
+			// Without a role claim a missing user is left for RequireRole to
+			// reject, as before; with one it must exist for the claim to hold.
+			if err != nil && !(errors.Is(err, gorm.ErrRecordNotFound) && claims.Role == "") {
 				c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
 				c.Abort()
 				return
 			}
-			if claims.TokenVersion != tv {
+			if claims.TokenVersion != row.TokenVersion || (claims.Role != "" && claims.Role != row.Role) {
 				c.JSON(http.StatusUnauthorized, gin.H{"error": "Session expired, please log in again", "error_key": "error.session_revoked"})
 				c.Abort()
 				return
@@ -101,7 +101,7 @@ func TestGenerateAndParseToken(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			tokenStr, err := GenerateToken(tt.userID, tt.username, testSecret)
+			tokenStr, err := GenerateToken(tt.userID, tt.username, "", testSecret)
 			if err != nil {
 				t.Fatalf("GenerateToken() error = %v", err)
 			}
@@ -153,7 +153,7 @@ func TestParseToken_Expired(t *testing.T) {
 }
 
 func TestParseToken_InvalidSignature(t *testing.T) {
-	tokenStr, err := GenerateToken(1, "admin", testSecret)
+	tokenStr, err := GenerateToken(1, "admin", "", testSecret)
 	if err != nil {
 		t.Fatalf("GenerateToken() error = %v", err)
 	}
@@ -326,7 +326,7 @@ func TestMiddleware_NoToken(t *testing.T) {
 func TestMiddleware_ValidJWT(t *testing.T) {
 	engine := ginEngine(Middleware(testSecret))
 
-	tokenStr, err := GenerateToken(42, "testuser", testSecret)
+	tokenStr, err := GenerateToken(42, "testuser", "", testSecret)
 	if err != nil {
 		t.Fatalf("GenerateToken() error = %v", err)
 	}
@@ -396,7 +396,7 @@ func TestMiddleware_Pending2FA(t *testing.T) {
 func TestMiddleware_WebSocketToken(t *testing.T) {
 	engine := ginEngine(Middleware(testSecret))
 
-	tokenStr, err := GenerateToken(99, "wsuser", testSecret)
+	tokenStr, err := GenerateToken(99, "wsuser", "", testSecret)
 	if err != nil {
 		t.Fatalf("GenerateToken() error = %v", err)
 	}
@@ -424,7 +424,7 @@ func TestMiddleware_QueryParamIgnoredForNonWS(t *testing.T) {
 	// The ?token= query param should NOT be accepted for non-WebSocket requests.
 	engine := ginEngine(Middleware(testSecret))
 
-	tokenStr, err := GenerateToken(1, "admin", testSecret)
+	tokenStr, err := GenerateToken(1, "admin", "", testSecret)
 	if err != nil {
 		t.Fatalf("GenerateToken() error = %v", err)
 	}
@@ -443,7 +443,7 @@ func TestMiddleware_BearerCaseInsensitive(t *testing.T) {
 	// The middleware lowercases the scheme; verify "bearer" (lowercase) works.
 	engine := ginEngine(Middleware(testSecret))
 
-	tokenStr, err := GenerateToken(1, "admin", testSecret)
+	tokenStr, err := GenerateToken(1, "admin", "", testSecret)
 	if err != nil {
 		t.Fatalf("GenerateToken() error = %v", err)
 	}
@@ -471,7 +471,7 @@ func TestRequireAdmin_AdminUser(t *testing.T) {
 		RequireAdmin(db),
 	)
 
-	tokenStr, err := GenerateToken(admin.ID, admin.Username, testSecret)
+	tokenStr, err := GenerateToken(admin.ID, admin.Username, "", testSecret)
 	if err != nil {
 		t.Fatalf("GenerateToken() error = %v", err)
 	}
@@ -495,7 +495,7 @@ func TestRequireAdmin_ViewerUser(t *testing.T) {
 		RequireAdmin(db),
 	)
 
-	tokenStr, err := GenerateToken(viewer.ID, viewer.Username, testSecret)
+	tokenStr, err := GenerateToken(viewer.ID, viewer.Username, "", testSecret)
 	if err != nil {
 		t.Fatalf("GenerateToken() error = %v", err)
 	}
@@ -610,7 +610,7 @@ func TestRequireAdmin_UserNotFound(t *testing.T) {
 		RequireAdmin(db),
 	)
 
-	tokenStr, err := GenerateToken(999, "ghost", testSecret)
+	tokenStr, err := GenerateToken(999, "ghost", "", testSecret)
 	if err != nil {
 		t.Fatalf("GenerateToken() error = %v", err)
 	}
@@ -629,3 +629,155 @@ func TestRequireAdmin_UserNotFound(t *testing.T) {
 		t.Errorf("error = %v, want %q", body["error"], "User not found")
 	}
 }
+
+// ---------------------------------------------------------------------------
+// Role claim + RequireRole
+// ---------------------------------------------------------------------------
+
+// hostsRouter mirrors main.go: reads on the protected group, writes behind
+// RequireRole(admin).
+func hostsRouter(db *gorm.DB) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	protected := r.Group("/api")
+	protected.Use(Middleware(testSecret, WithDB(db)))
+	adminOnly := r.Group("/api")
+	adminOnly.Use(Middleware(testSecret, WithDB(db)), RequireRole(db, RoleAdmin))
+
+	ok := func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"role": c.GetString("user_role")}) }
+	protected.GET("/hosts", ok)
+	adminOnly.POST("/hosts", ok)
+	return r
+}
+
+func TestGenerateToken_RoleClaim(t *testing.T) {
+	tokenStr, err := GenerateToken(7, "alice", RoleViewer, testSecret, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := ParseToken(tokenStr, testSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Role != RoleViewer || claims.TokenVersion != 3 {
+		t.Errorf("claims = %+v", claims)
+	}
+}
+
+func TestRequireRole_ViewerReadOnly(t *testing.T) {
+	db := setupTestDB(t)
+	viewer := createTestUser(t, db, "viewer", RoleViewer)
+	engine := hostsRouter(db)
+
+	tokenStr, _ := GenerateToken(viewer.ID, viewer.Username, viewer.Role, testSecret)
+	do := func(method string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, "/api/hosts", nil)
+		req.Header.Set("Authorization", "Bearer "+tokenStr)
+		engine.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := do(http.MethodGet); w.Code != http.StatusOK {
+		t.Errorf("GET /hosts as viewer: status = %d, want 200", w.Code)
+	}
+	w := do(http.MethodPost)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("POST /hosts as viewer: status = %d, want 403", w.Code)
+	}
+	if body := jsonBody(t, w); body["error_key"] != "error.forbidden" {
+		t.Errorf("error_key = %v, want error.forbidden", body["error_key"])
+	}
+}
+
+func TestRequireRole_AdminCanWrite(t *testing.T) {
+	db := setupTestDB(t)
+	admin := createTestUser(t, db, "admin", RoleAdmin)
+	engine := hostsRouter(db)
+
+	tokenStr, _ := GenerateToken(admin.ID, admin.Username, admin.Role, testSecret)
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/hosts", nil)
+	req.Header.Set("Authorization", "Bearer "+tokenStr)
+	engine.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST /hosts as admin: status = %d, want 200", w.Code)
+	}
+	if body := jsonBody(t, w); body["role"] != RoleAdmin {
+		t.Errorf("user_role = %v, want admin from the claim", body["role"])
+	}
+}
+
+func TestMiddleware_StaleRoleClaimRejected(t *testing.T) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db, "demoted", RoleViewer)
+	engine := hostsRouter(db)
+
+	// Token claims admin but the stored role is viewer.
+	tokenStr, _ := GenerateToken(user.ID, user.Username, RoleAdmin, testSecret)
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/hosts", nil)
+	req.Header.Set("Authorization", "Bearer "+tokenStr)
+	engine.ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("stale role claim: status = %d, want 401", w.Code)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Token lifetimes
+// ---------------------------------------------------------------------------
+
+// withTokenLifetimes applies lifetimes for one test and restores the previous
+// ones afterwards.
+func withTokenLifetimes(t *testing.T, session, temp time.Duration) {
+	t.Helper()
+	prevSession, prevTemp := jwtTTL, tempTokenTTL
+	t.Cleanup(func() { jwtTTL, tempTokenTTL = prevSession, prevTemp })
+	SetTokenLifetimes(session, temp)
+}
+
+func TestGenerateTempToken_ShortTTLExpires(t *testing.T) {
+	withTokenLifetimes(t, 0, time.Second)
+
+	tokenStr, err := GenerateTempToken(1, "admin", testSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseToken(tokenStr, testSecret); err != nil {
+		t.Fatalf("temp token should be valid right away: %v", err)
+	}
+
+	time.Sleep(2 * time.Second)
+	if _, err := ParseToken(tokenStr, testSecret); err == nil {
+		t.Fatal("temp token should have expired after its 1s TTL")
+	}
+}
+
+func TestGenerateToken_DefaultTTL(t *testing.T) {
+	withTokenLifetimes(t, 0, 0) // non-positive values keep the defaults
+
+	tokenStr, err := GenerateToken(1, "admin", RoleAdmin, testSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := ParseToken(tokenStr, testSecret)
+	if err != nil {
+		t.Fatalf("default token should be valid: %v", err)
+	}
+	// iat and exp are truncated to whole seconds independently.
+	if ttl := claims.ExpiresAt.Sub(claims.IssuedAt.Time); ttl < 24*time.Hour || ttl > 24*time.Hour+time.Second {
+		t.Errorf("session TTL = %v, want 24h", ttl)
+	}
+
+	tempStr, _ := GenerateTempToken(1, "admin", testSecret)
+	tempClaims, err := ParseToken(tempStr, testSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ttl := tempClaims.ExpiresAt.Sub(tempClaims.IssuedAt.Time); ttl < 5*time.Minute || ttl > 5*time.Minute+time.Second {
+		t.Errorf("temp TTL = %v, want 5m", ttl)
+	}
+}
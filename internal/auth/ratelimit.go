@@ -3,25 +3,40 @@ package auth
 import (
 	"math"
 	"net/http"
+	"sort"
 	"strconv"
 	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/web-casa/webcasa/internal/model"
+	"gorm.io/gorm"
 )
 
 // ============ Rate Limiter ============
 
-// RateLimiter tracks request counts per client (IP) within a sliding window.
+// RateLimiter tracks request counts per client within a sliding window. A
+// client is an IP, or an IP and username pair; credential checks apply both
+// (see CheckIPAndUser) and a success clears only the pair.
 // Exponential backoff applies: after each failure, the next attempt must wait
 // 2^(n-1) seconds. Callers explicitly record failures via RecordFail so that
 // unauthenticated/anonymous endpoints can rate-limit without penalising
 // legitimate successful requests.
 type RateLimiter struct {
 	mu          sync.Mutex
-	attempts    map[string]*attemptInfo
+	attempts    map[attemptKey]*attemptInfo
 	maxAttempts int
 	windowSecs  int
+
+	// Optional persistence (see Persist). nil keeps the limiter in-memory.
+	db    *gorm.DB
+	scope string
+}
+
+// attemptKey identifies a client. username is empty for IP-only limits.
+type attemptKey struct {
+	ip       string
+	username string
 }
 
 type attemptInfo struct {
@@ -33,7 +48,7 @@ type attemptInfo struct {
 // NewRateLimiter creates a rate limiter (e.g. 5 attempts per 900 seconds)
 func NewRateLimiter(maxAttempts, windowSecs int) *RateLimiter {
 	rl := &RateLimiter{
-		attempts:    make(map[string]*attemptInfo),
+		attempts:    make(map[attemptKey]*attemptInfo),
 		maxAttempts: maxAttempts,
 		windowSecs:  windowSecs,
 	}
@@ -49,17 +64,23 @@ func NewRateLimiter(maxAttempts, windowSecs int) *RateLimiter {
 
 // Check returns (allowed bool, waitSeconds int)
 func (rl *RateLimiter) Check(ip string) (bool, int) {
+	return rl.CheckUser(ip, "")
+}
+
+// CheckUser is Check for attempts from ip against username.
+func (rl *RateLimiter) CheckUser(ip, username string) (bool, int) {
 	rl.mu.Lock()
 	defer rl.mu.Unlock()
 
-	info, exists := rl.attempts[ip]
+	key := attemptKey{ip, username}
+	info, exists := rl.attempts[key]
 	if !exists {
 		return true, 0
 	}
 
 	// Window expired → reset
 	if time.Since(info.firstAt) > time.Duration(rl.windowSecs)*time.Second {
-		delete(rl.attempts, ip)
+		delete(rl.attempts, key)
 		return true, 0
 	}
 
@@ -80,36 +101,170 @@ func (rl *RateLimiter) Check(ip string) (bool, int) {
 	return true, 0
 }
 
+// CheckIPAndUser applies both the IP-wide limit and the limit for ip
+// against username, so one address can neither keep guessing at one account
+// nor spread its guesses across many. The longer wait wins.
+func (rl *RateLimiter) CheckIPAndUser(ip, username string) (bool, int) {
+	allowed, wait := rl.Check(ip)
+	if username == "" {
+		return allowed, wait
+	}
+	userAllowed, userWait := rl.CheckUser(ip, username)
+	if userWait > wait {
+		wait = userWait
+	}
+	return allowed && userAllowed, wait
+}
+
+// RecordFailIPAndUser records a failure against both limits applied by
+// CheckIPAndUser.
+func (rl *RateLimiter) RecordFailIPAndUser(ip, username string) {
+	rl.RecordFail(ip)
+	if username != "" {
+		rl.RecordFailUser(ip, username)
+	}
+}
+
 // RecordFail records a failed login attempt
 func (rl *RateLimiter) RecordFail(ip string) {
+	rl.RecordFailUser(ip, "")
+}
+
+// RecordFailUser records a failed attempt from ip against username, counted
+// towards CheckUser(ip, username) only.
+func (rl *RateLimiter) RecordFailUser(ip, username string) {
 	rl.mu.Lock()
 	defer rl.mu.Unlock()
 
-	info, exists := rl.attempts[ip]
+	now := time.Now()
+	key := attemptKey{ip, username}
+	info, exists := rl.attempts[key]
 	if !exists {
-		rl.attempts[ip] = &attemptInfo{count: 1, firstAt: time.Now(), lastFail: time.Now()}
-		return
+		info = &attemptInfo{firstAt: now}
+		rl.attempts[key] = info
 	}
 	info.count++
-	info.lastFail = time.Now()
+	info.lastFail = now
+
+	if rl.db != nil {
+		rl.db.Create(&model.LoginAttempt{Scope: rl.scope, IP: ip, Username: username, FailedAt: now})
+	}
 }
 
 // RecordSuccess clears attempts for an IP
 func (rl *RateLimiter) RecordSuccess(ip string) {
+	rl.ClearUser(ip, "")
+}
+
+// RecordSuccessUser clears the attempts from ip against username.
+func (rl *RateLimiter) RecordSuccessUser(ip, username string) {
+	rl.ClearUser(ip, username)
+}
+
+// Clear forgets all failures for ip, against any username, unlocking it.
+func (rl *RateLimiter) Clear(ip string) {
 	rl.mu.Lock()
 	defer rl.mu.Unlock()
-	delete(rl.attempts, ip)
+	for key := range rl.attempts {
+		if key.ip == ip {
+			delete(rl.attempts, key)
+		}
+	}
+	if rl.db != nil {
+		rl.db.Where("scope = ? AND ip = ?", rl.scope, ip).Delete(&model.LoginAttempt{})
+	}
+}
+
+// ClearUser forgets the failures from ip against username only.
+func (rl *RateLimiter) ClearUser(ip, username string) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	delete(rl.attempts, attemptKey{ip, username})
+	if rl.db != nil {
+		rl.db.Where("scope = ? AND ip = ? AND username = ?", rl.scope, ip, username).Delete(&model.LoginAttempt{})
+	}
 }
 
 func (rl *RateLimiter) cleanup() {
 	rl.mu.Lock()
 	defer rl.mu.Unlock()
 	cutoff := time.Now().Add(-time.Duration(rl.windowSecs) * time.Second)
-	for ip, info := range rl.attempts {
+	for key, info := range rl.attempts {
 		if info.firstAt.Before(cutoff) {
-			delete(rl.attempts, ip)
+			delete(rl.attempts, key)
+		}
+	}
+	if rl.db != nil {
+		rl.db.Where("scope = ? AND failed_at < ?", rl.scope, cutoff).Delete(&model.LoginAttempt{})
+	}
+}
+
+// Persist backs the limiter with the login_attempts table under scope, so
+// counts survive a restart. Failures still inside the window are loaded
+// immediately; later failures are written as they are recorded.
+func (rl *RateLimiter) Persist(db *gorm.DB, scope string) error {
+	cutoff := time.Now().Add(-time.Duration(rl.windowSecs) * time.Second)
+	var rows []model.LoginAttempt
+	if err := db.Where("scope = ? AND failed_at >= ?", scope, cutoff).
+		Order("failed_at ASC").Find(&rows).Error; err != nil {
+		return err
+	}
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	rl.db, rl.scope = db, scope
+	for _, row := range rows {
+		key := attemptKey{row.IP, row.Username}
+		info, exists := rl.attempts[key]
+		if !exists {
+			info = &attemptInfo{firstAt: row.FailedAt}
+			rl.attempts[key] = info
+		}
+		info.count++
+		if row.FailedAt.After(info.lastFail) {
+			info.lastFail = row.FailedAt
+		}
+	}
+	return nil
+}
+
+// LockoutEntry describes a client with failures inside the current window.
+type LockoutEntry struct {
+	IP         string    `json:"ip"`
+	Username   string    `json:"username,omitempty"`
+	Failures   int       `json:"failures"`
+	FirstAt    time.Time `json:"first_at"`
+	LastFail   time.Time `json:"last_fail"`
+	Locked     bool      `json:"locked"`
+	RetryAfter int       `json:"retry_after"` // seconds until unlocked; 0 when not locked
+}
+
+// Entries lists clients with recorded failures, most recent first.
+func (rl *RateLimiter) Entries() []LockoutEntry {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	window := time.Duration(rl.windowSecs) * time.Second
+	entries := make([]LockoutEntry, 0, len(rl.attempts))
+	for key, info := range rl.attempts {
+		if time.Since(info.firstAt) > window {
+			continue
+		}
+		e := LockoutEntry{
+			IP:       key.ip,
+			Username: key.username,
+			Failures: info.count,
+			FirstAt:  info.firstAt,
+			LastFail: info.lastFail,
+			Locked:   info.count >= rl.maxAttempts,
+		}
+		if e.Locked {
+			e.RetryAfter = int((window - time.Since(info.firstAt)).Seconds())
 		}
+		entries = append(entries, e)
 	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].LastFail.After(entries[j].LastFail) })
+	return entries
 }
 
 // Middleware returns a gin.HandlerFunc that rate-limits requests by client IP.
@@ -166,3 +321,12 @@ func NewLimiters() *Limiters {
 		Default:  NewRateLimiter(600, 60),
 	}
 }
+
+// Persist backs the Login and TOTP limiters with the database so brute-force
+// lockouts survive restarts. The API limiters stay in-memory.
+func (l *Limiters) Persist(db *gorm.DB) error {
+	if err := l.Login.Persist(db, "login"); err != nil {
+		return err
+	}
+	return l.TOTP.Persist(db, "totp")
+}
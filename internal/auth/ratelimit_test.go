@@ -1,11 +1,15 @@
 package auth
 
 import (
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/web-casa/webcasa/internal/model"
+	"gorm.io/gorm"
 )
 
 func init() {
@@ -124,3 +128,133 @@ func TestMiddleware_DoesNotCallRecordFail(t *testing.T) {
 		t.Error("middleware should not call RecordFail; handler must decide")
 	}
 }
+
+// persistedLimiter returns a login-sized limiter backed by db.
+func persistedLimiter(t *testing.T, db *gorm.DB) *RateLimiter {
+	t.Helper()
+	rl := NewRateLimiter(5, 900)
+	if err := rl.Persist(db, "login"); err != nil {
+		t.Fatalf("persist: %v", err)
+	}
+	return rl
+}
+
+func TestRateLimiter_LockoutSurvivesRestart(t *testing.T) {
+	db := setupTestDB(t)
+	if err := db.AutoMigrate(&model.LoginAttempt{}); err != nil {
+		t.Fatal(err)
+	}
+
+	rl := persistedLimiter(t, db)
+	for i := 0; i < 5; i++ {
+		rl.RecordFailUser("10.0.0.1", "admin")
+	}
+	if allowed, _ := rl.CheckUser("10.0.0.1", "admin"); allowed {
+		t.Fatal("expected lockout after 5 failures")
+	}
+
+	// A fresh limiter (as after a restart) reloads the lockout.
+	restarted := persistedLimiter(t, db)
+	if allowed, wait := restarted.CheckUser("10.0.0.1", "admin"); allowed || wait <= 0 {
+		t.Errorf("lockout should survive a restart: allowed=%v wait=%d", allowed, wait)
+	}
+	if allowed, _ := restarted.CheckUser("10.0.0.2", "admin"); !allowed {
+		t.Error("other IPs must not be affected")
+	}
+	if allowed, _ := restarted.CheckUser("10.0.0.1", "alice"); !allowed {
+		t.Error("other usernames from the same IP must not be affected")
+	}
+
+	entries := restarted.Entries()
+	if len(entries) != 1 {
+		t.Fatalf("entries = %+v, want one", entries)
+	}
+	if e := entries[0]; e.IP != "10.0.0.1" || e.Username != "admin" || e.Failures != 5 || !e.Locked {
+		t.Errorf("entry = %+v", e)
+	}
+
+	// Failures outside the window are not reloaded.
+	db.Create(&model.LoginAttempt{Scope: "login", IP: "10.0.0.3", FailedAt: time.Now().Add(-time.Hour)})
+	if allowed, _ := persistedLimiter(t, db).Check("10.0.0.3"); !allowed {
+		t.Error("expired failures should not count after a restart")
+	}
+	// Other scopes are kept separate.
+	for i := 0; i < 5; i++ {
+		db.Create(&model.LoginAttempt{Scope: "totp", IP: "10.0.0.4", FailedAt: time.Now()})
+	}
+	if allowed, _ := persistedLimiter(t, db).Check("10.0.0.4"); !allowed {
+		t.Error("TOTP failures must not lock the login limiter")
+	}
+}
+
+func TestRateLimiter_ClearUnlocks(t *testing.T) {
+	db := setupTestDB(t)
+	if err := db.AutoMigrate(&model.LoginAttempt{}); err != nil {
+		t.Fatal(err)
+	}
+
+	rl := persistedLimiter(t, db)
+	for i := 0; i < 5; i++ {
+		rl.RecordFail("10.0.0.1")
+	}
+	rl.Clear("10.0.0.1")
+	if allowed, _ := rl.Check("10.0.0.1"); !allowed {
+		t.Error("cleared IP should be allowed")
+	}
+	if len(rl.Entries()) != 0 {
+		t.Errorf("entries after clear = %+v", rl.Entries())
+	}
+	if allowed, _ := persistedLimiter(t, db).Check("10.0.0.1"); !allowed {
+		t.Error("clear should also remove the persisted failures")
+	}
+
+	// A successful login clears persisted failures too.
+	rl.RecordFail("10.0.0.5")
+	rl.RecordSuccess("10.0.0.5")
+	var count int64
+	db.Model(&model.LoginAttempt{}).Where("ip = ?", "10.0.0.5").Count(&count)
+	if count != 0 {
+		t.Errorf("persisted failures after success = %d, want 0", count)
+	}
+
+	// ClearUser and RecordSuccessUser leave the IP's other usernames locked;
+	// Clear unlocks them all.
+	for i := 0; i < 5; i++ {
+		rl.RecordFailUser("10.0.0.6", "admin")
+		rl.RecordFailUser("10.0.0.6", "alice")
+		rl.RecordFailUser("10.0.0.6", "bob")
+	}
+	rl.ClearUser("10.0.0.6", "admin")
+	rl.RecordSuccessUser("10.0.0.6", "alice")
+	if allowed, _ := rl.CheckUser("10.0.0.6", "admin"); !allowed {
+		t.Error("cleared username should be allowed")
+	}
+	if allowed, _ := rl.CheckUser("10.0.0.6", "alice"); !allowed {
+		t.Error("username that logged in should be allowed")
+	}
+	if allowed, _ := persistedLimiter(t, db).CheckUser("10.0.0.6", "bob"); allowed {
+		t.Error("clearing one username must keep the others locked")
+	}
+	rl.Clear("10.0.0.6")
+	if allowed, _ := persistedLimiter(t, db).CheckUser("10.0.0.6", "bob"); !allowed {
+		t.Error("clearing the IP should unlock every username")
+	}
+}
+
+func TestRateLimiter_CheckIPAndUser(t *testing.T) {
+	rl := NewRateLimiter(5, 900)
+	for i := 0; i < 5; i++ {
+		rl.RecordFailIPAndUser("10.0.0.7", fmt.Sprintf("user%d", i))
+	}
+	if allowed, wait := rl.CheckIPAndUser("10.0.0.7", "someone-new"); allowed || wait <= 0 {
+		t.Errorf("IP failing across usernames: allowed=%v wait=%d, want locked", allowed, wait)
+	}
+	if allowed, _ := rl.CheckUser("10.0.0.7", "someone-new"); !allowed {
+		t.Error("the pair bucket alone should not be locked")
+	}
+
+	rl.RecordFailIPAndUser("10.0.0.8", "admin")
+	if allowed, _ := rl.CheckIPAndUser("10.0.0.9", "admin"); !allowed {
+		t.Error("other IPs must not be affected")
+	}
+}
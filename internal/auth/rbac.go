@@ -38,7 +38,14 @@ func roleLevel(role string) int {
 	}
 }
 
-// requireRole creates a middleware that requires at least the given role level.
+// RequireRole creates a middleware that requires at least minRole. The role
+// comes from the JWT "role" claim set by Middleware; API tokens and legacy
+// tokens without the claim fall back to a database lookup.
+func RequireRole(db *gorm.DB, minRole string) gin.HandlerFunc {
+	return requireRole(db, minRole, "Insufficient permissions")
+}
+
+// requireRole implements RequireRole with a custom 403 message.
 func requireRole(db *gorm.DB, minRole string, errorMsg string) gin.HandlerFunc {
 	minLevel := roleLevel(minRole)
 	return func(c *gin.Context) {
@@ -49,22 +56,24 @@ func requireRole(db *gorm.DB, minRole string, errorMsg string) gin.HandlerFunc {
 			return
 		}
 
-		var user model.User
-		if err := db.Select("id, role").First(&user, userID).Error; err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
-			c.Abort()
-			return
+		role := c.GetString("user_role")
+		if role == "" {
+			var user model.User
+			if err := db.Select("id, role").First(&user, userID).Error; err != nil {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+				c.Abort()
+				return
+			}
+			role = user.Role
 		}
 
-		// Backward compatibility: treat legacy "admin" without owner as owner-equivalent.
-		userLevel := roleLevel(user.Role)
-		if userLevel < minLevel {
-			c.JSON(http.StatusForbidden, gin.H{"error": errorMsg})
+		if roleLevel(role) < minLevel {
+			c.JSON(http.StatusForbidden, gin.H{"error": errorMsg, "error_key": "error.forbidden"})
 			c.Abort()
 			return
 		}
 
-		c.Set("user_role", user.Role)
+		c.Set("user_role", role)
 		c.Next()
 	}
 }
@@ -0,0 +1,210 @@
+package caddy
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SharedAccessLog is the access log file for hosts without a log of their
+// own (SeparateAccessLog off).
+const SharedAccessLog = "access.log"
+
+// AccessLogFileName returns the per-host access log file name for domain,
+// "access-<domain>.log". Characters other than letters, digits, '-' and '.'
+// (such as the '*' of a wildcard or the ':' before a port) become '_', and
+// leading dots are dropped, so the name can never leave the log directory.
+// It returns "" when nothing usable is left.
+func AccessLogFileName(domain string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(domain) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '-', r == '.':
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+	}
+	name := strings.TrimLeft(b.String(), ".")
+	if strings.Trim(name, "_.") == "" {
+		return ""
+	}
+	return "access-" + name + ".log"
+}
+
+// AccessLogFiles lists the access logs in dir: the per-host log for host,
+// or every per-host log when host is empty, followed by the shared log.
+func AccessLogFiles(dir, host string) []string {
+	var files []string
+	if host != "" {
+		if name := AccessLogFileName(host); name != "" {
+			files = []string{filepath.Join(dir, name)}
+		}
+	} else {
+		files, _ = filepath.Glob(filepath.Join(dir, "access-*.log"))
+	}
+	return append(files, filepath.Join(dir, SharedAccessLog))
+}
+
+// AccessEntry is one request from a Caddy JSON access log.
+type AccessEntry struct {
+	TS       time.Time `json:"ts"`
+	Status   int       `json:"status"`
+	Method   string    `json:"method"`
+	URI      string    `json:"uri"`
+	Duration float64   `json:"duration"` // seconds
+	Size     int64     `json:"size"`     // response body bytes
+	RemoteIP string    `json:"remote_ip"`
+	Host     string    `json:"host"`
+}
+
+// rawAccessEntry mirrors the fields of Caddy's "handled request" log entry
+// that AccessEntry needs.
+type rawAccessEntry struct {
+	TS       float64 `json:"ts"`
+	Status   int     `json:"status"`
+	Duration float64 `json:"duration"`
+	Size     int64   `json:"size"`
+	Request  *struct {
+		RemoteIP string `json:"remote_ip"`
+		Method   string `json:"method"`
+		Host     string `json:"host"`
+		URI      string `json:"uri"`
+	} `json:"request"`
+}
+
+// ParseAccessLog decodes Caddy's JSON access log format. Lines that are not
+// JSON, or JSON entries that do not describe a request, are skipped.
+func ParseAccessLog(r io.Reader) ([]AccessEntry, error) {
+	var entries []AccessEntry
+	err := ScanAccessLog(r, func(e AccessEntry) { entries = append(entries, e) })
+	return entries, err
+}
+
+// ScanAccessLog decodes r like ParseAccessLog but hands each entry to fn
+// instead of collecting them, so large logs are never held in memory.
+func ScanAccessLog(r io.Reader, fn func(AccessEntry)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 || line[0] != '{' {
+			continue
+		}
+		var raw rawAccessEntry
+		if err := json.Unmarshal(line, &raw); err != nil || raw.Request == nil {
+			continue
+		}
+		whole := int64(raw.TS)
+		fn(AccessEntry{
+			TS:       time.Unix(whole, int64((raw.TS-float64(whole))*1e9)),
+			Status:   raw.Status,
+			Method:   raw.Request.Method,
+			URI:      raw.Request.URI,
+			Duration: raw.Duration,
+			Size:     raw.Size,
+			RemoteIP: raw.Request.RemoteIP,
+			Host:     raw.Request.Host,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("read access log: %w", err)
+	}
+	return nil
+}
+
+// AccessFilter selects access log entries. Host matches case-insensitively
+// and ignores any port; Status is an exact code ("404") or a class ("5xx").
+// Empty fields match everything.
+type AccessFilter struct {
+	Host   string
+	Status string
+}
+
+// ParseAccessFilter validates host and status query values.
+func ParseAccessFilter(host, status string) (AccessFilter, error) {
+	f := AccessFilter{Host: strings.ToLower(strings.TrimSpace(host)), Status: strings.ToLower(strings.TrimSpace(status))}
+	if f.Status == "" {
+		return f, nil
+	}
+	if len(f.Status) == 3 && f.Status[1:] == "xx" && f.Status[0] >= '1' && f.Status[0] <= '5' {
+		return f, nil
+	}
+	if code, err := strconv.Atoi(f.Status); err != nil || code < 100 || code > 599 {
+		return f, fmt.Errorf("status must be a code like 404 or a class like 5xx")
+	}
+	return f, nil
+}
+
+// Match reports whether e passes the filter.
+func (f AccessFilter) Match(e AccessEntry) bool {
+	if f.Host != "" {
+		host := strings.ToLower(e.Host)
+		if i := strings.LastIndexByte(host, ':'); i >= 0 && !strings.HasSuffix(host, "]") {
+			host = host[:i]
+		}
+		if host != f.Host {
+			return false
+		}
+	}
+	if f.Status != "" {
+		code := strconv.Itoa(e.Status)
+		if strings.HasSuffix(f.Status, "xx") {
+			return code[:1] == f.Status[:1]
+		}
+		return code == f.Status
+	}
+	return true
+}
+
+// AccessSummary aggregates a set of access log entries.
+type AccessSummary struct {
+	Requests    int            `json:"requests"`
+	Statuses    map[string]int `json:"statuses"`
+	TopPaths    []PathCount    `json:"top_paths"`
+	AvgDuration float64        `json:"avg_duration"`
+}
+
+// PathCount is the number of requests for one path.
+type PathCount struct {
+	Path  string `json:"path"`
+	Count int    `json:"count"`
+}
+
+// SummarizeAccess counts requests, breaks them down by status code and
+// lists the topN most requested paths (query strings ignored).
+func SummarizeAccess(entries []AccessEntry, topN int) AccessSummary {
+	sum := AccessSummary{Statuses: map[string]int{}, TopPaths: []PathCount{}}
+	paths := map[string]int{}
+	var duration float64
+	for _, e := range entries {
+		sum.Requests++
+		sum.Statuses[strconv.Itoa(e.Status)]++
+		path, _, _ := strings.Cut(e.URI, "?")
+		paths[path]++
+		duration += e.Duration
+	}
+	if sum.Requests > 0 {
+		sum.AvgDuration = duration / float64(sum.Requests)
+	}
+
+	for p, n := range paths {
+		sum.TopPaths = append(sum.TopPaths, PathCount{Path: p, Count: n})
+	}
+	sort.Slice(sum.TopPaths, func(i, j int) bool {
+		if sum.TopPaths[i].Count != sum.TopPaths[j].Count {
+			return sum.TopPaths[i].Count > sum.TopPaths[j].Count
+		}
+		return sum.TopPaths[i].Path < sum.TopPaths[j].Path
+	})
+	if len(sum.TopPaths) > topN {
+		sum.TopPaths = sum.TopPaths[:topN]
+	}
+	return sum
+}
@@ -0,0 +1,67 @@
+package caddy
+
+import (
+	"strings"
+	"testing"
+)
+
+const sampleAccessLog = `{"level":"info","ts":1700000000.25,"logger":"http.log.access.log0","msg":"handled request","request":{"remote_ip":"203.0.113.7","proto":"HTTP/2.0","method":"GET","host":"shop.example.com","uri":"/cart?id=1"},"duration":0.002,"size":512,"status":200}
+not json at all
+{"level":"info","ts":1700000001,"msg":"no request field"}
+{"level":"info","ts":1700000002,"request":{"remote_ip":"203.0.113.8","method":"POST","host":"shop.example.com","uri":"/cart"},"duration":0.004,"status":500}
+{"level":"info","ts":1700000003,"request":{"remote_ip":"198.51.100.1","method":"GET","host":"blog.example.com:443","uri":"/"},"duration":0.001,"status":404}
+{"level":"info","ts":1700000004,"request":{"remote_ip":"203.0.113.7","method":"GET","host":"shop.example.com","uri":"/"},"duration":0.001,"status":200}
+`
+
+func TestParseAccessLog(t *testing.T) {
+	entries, err := ParseAccessLog(strings.NewReader(sampleAccessLog))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 4 {
+		t.Fatalf("got %d entries, want 4 (non-JSON and non-request lines skipped)", len(entries))
+	}
+	e := entries[0]
+	if e.Status != 200 || e.Method != "GET" || e.URI != "/cart?id=1" || e.Host != "shop.example.com" || e.RemoteIP != "203.0.113.7" || e.Duration != 0.002 || e.Size != 512 {
+		t.Errorf("entry = %+v", e)
+	}
+	if e.TS.Unix() != 1700000000 || e.TS.Nanosecond() != 250000000 {
+		t.Errorf("ts = %v", e.TS)
+	}
+}
+
+func TestSummarizeAccess_FilterByHost(t *testing.T) {
+	entries, _ := ParseAccessLog(strings.NewReader(sampleAccessLog))
+
+	filter, err := ParseAccessFilter("Shop.Example.com", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var shop []AccessEntry
+	for _, e := range entries {
+		if filter.Match(e) {
+			shop = append(shop, e)
+		}
+	}
+	sum := SummarizeAccess(shop, 10)
+	if sum.Requests != 3 || sum.Statuses["200"] != 2 || sum.Statuses["500"] != 1 {
+		t.Errorf("summary = %+v", sum)
+	}
+	if len(sum.TopPaths) != 2 || sum.TopPaths[0] != (PathCount{Path: "/cart", Count: 2}) {
+		t.Errorf("top paths = %+v, want /cart first with query strings ignored", sum.TopPaths)
+	}
+
+	// The port in the Host header is ignored.
+	blog, _ := ParseAccessFilter("blog.example.com", "4xx")
+	if !blog.Match(entries[2]) || blog.Match(entries[0]) {
+		t.Error("blog.example.com 4xx filter mismatched")
+	}
+	if only5xx, _ := ParseAccessFilter("", "500"); only5xx.Match(entries[0]) || !only5xx.Match(entries[1]) {
+		t.Error("exact status filter mismatched")
+	}
+	for _, bad := range []string{"abc", "6xx", "42"} {
+		if _, err := ParseAccessFilter("", bad); err == nil {
+			t.Errorf("status %q should be rejected", bad)
+		}
+	}
+}
@@ -0,0 +1,481 @@
+package caddy
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/web-casa/webcasa/internal/model"
+)
+
+// CaddyfileBlock is a top-level block of a Caddyfile: a site, the global
+// options block (no addresses) or a snippet (Snippet set).
+type CaddyfileBlock struct {
+	Line       int
+	Addresses  []string
+	Snippet    string
+	Directives []CaddyfileDirective
+}
+
+// CaddyfileDirective is one directive line with its arguments and, when it
+// opens one, its block of sub-directives.
+type CaddyfileDirective struct {
+	Line     int
+	Tokens   []string // the directive name followed by its arguments
+	HasBlock bool
+	Block    []CaddyfileDirective
+}
+
+// Name returns the directive name.
+func (d CaddyfileDirective) Name() string {
+	if len(d.Tokens) == 0 {
+		return ""
+	}
+	return d.Tokens[0]
+}
+
+// Args returns the directive arguments.
+func (d CaddyfileDirective) Args() []string {
+	if len(d.Tokens) < 2 {
+		return nil
+	}
+	return d.Tokens[1:]
+}
+
+// String renders the directive back to Caddyfile text, its block indented
+// with tabs.
+func (d CaddyfileDirective) String() string {
+	var b strings.Builder
+	writeCaddyfileDirective(&b, d, 0)
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+func writeCaddyfileDirective(b *strings.Builder, d CaddyfileDirective, depth int) {
+	indent := strings.Repeat("\t", depth)
+	tokens := make([]string, len(d.Tokens))
+	for i, tok := range d.Tokens {
+		tokens[i] = quoteCaddyfileToken(tok)
+	}
+	b.WriteString(indent + strings.Join(tokens, " "))
+	if !d.HasBlock {
+		b.WriteString("\n")
+		return
+	}
+	b.WriteString(" {\n")
+	for _, sub := range d.Block {
+		writeCaddyfileDirective(b, sub, depth+1)
+	}
+	b.WriteString(indent + "}\n")
+}
+
+func quoteCaddyfileToken(tok string) string {
+	if tok != "" && tok != "{" && tok != "}" && !strings.ContainsAny(tok, " \t\n\r\"") {
+		return tok
+	}
+	return `"` + strings.ReplaceAll(tok, `"`, `\"`) + `"`
+}
+
+type caddyfileToken struct {
+	text   string
+	line   int
+	quoted bool
+}
+
+// isBrace reports whether the token is an unquoted opening or closing brace.
+func (t caddyfileToken) isBrace(brace string) bool {
+	return !t.quoted && t.text == brace
+}
+
+// ParseCaddyfile splits a Caddyfile into its top-level blocks. It is a
+// focused parser for importing sites: it understands quoting, comments and
+// nested blocks but not heredocs, line continuations or env substitution,
+// which it leaves in the tokens as written.
+func ParseCaddyfile(content string) ([]CaddyfileBlock, error) {
+	tokens, err := tokenizeCaddyfile(content)
+	if err != nil {
+		return nil, err
+	}
+	p := caddyfileParser{tokens: tokens}
+	entries, err := p.directives(false)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, nil
+	}
+
+	// A Caddyfile with a single site may leave out the braces: the first line
+	// holds the addresses and everything after it belongs to that site.
+	if first := entries[0]; !first.HasBlock {
+		return []CaddyfileBlock{{
+			Line:       first.Line,
+			Addresses:  splitSiteAddresses(first.Tokens),
+			Directives: entries[1:],
+		}}, nil
+	}
+
+	blocks := make([]CaddyfileBlock, 0, len(entries))
+	for _, e := range entries {
+		if !e.HasBlock {
+			return nil, fmt.Errorf("line %d: expected a block after '%s'", e.Line, strings.Join(e.Tokens, " "))
+		}
+		block := CaddyfileBlock{Line: e.Line, Directives: e.Block}
+		if len(e.Tokens) == 1 && strings.HasPrefix(e.Tokens[0], "(") && strings.HasSuffix(e.Tokens[0], ")") {
+			block.Snippet = strings.Trim(e.Tokens[0], "()")
+		} else {
+			block.Addresses = splitSiteAddresses(e.Tokens)
+		}
+		blocks = append(blocks, block)
+	}
+	return blocks, nil
+}
+
+// splitSiteAddresses turns the tokens of a site's address line, separated by
+// spaces and/or commas, into addresses.
+func splitSiteAddresses(tokens []string) []string {
+	var addrs []string
+	for _, tok := range tokens {
+		for _, addr := range strings.Split(tok, ",") {
+			if addr = strings.TrimSpace(addr); addr != "" {
+				addrs = append(addrs, addr)
+			}
+		}
+	}
+	return addrs
+}
+
+func tokenizeCaddyfile(content string) ([]caddyfileToken, error) {
+	var tokens []caddyfileToken
+	runes := []rune(content)
+	line := 1
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '\n':
+			line++
+		case r == ' ' || r == '\t' || r == '\r':
+		case r == '#':
+			for i+1 < len(runes) && runes[i+1] != '\n' {
+				i++
+			}
+		case r == '"' || r == '`':
+			start := line
+			var tok strings.Builder
+			closed := false
+			for i++; i < len(runes); i++ {
+				c := runes[i]
+				if c == '\n' {
+					line++
+				}
+				if r == '"' && c == '\\' && i+1 < len(runes) && runes[i+1] == '"' {
+					tok.WriteRune('"')
+					i++
+					continue
+				}
+				if c == r {
+					closed = true
+					break
+				}
+				tok.WriteRune(c)
+			}
+			if !closed {
+				return nil, fmt.Errorf("line %d: unterminated quoted token", start)
+			}
+			tokens = append(tokens, caddyfileToken{text: tok.String(), line: start, quoted: true})
+		default:
+			var tok strings.Builder
+			for ; i < len(runes) && !strings.ContainsRune(" \t\r\n", runes[i]); i++ {
+				tok.WriteRune(runes[i])
+			}
+			i--
+			if strings.HasPrefix(tok.String(), "<<") {
+				return nil, fmt.Errorf("line %d: heredocs are not supported", line)
+			}
+			tokens = append(tokens, caddyfileToken{text: tok.String(), line: line})
+		}
+	}
+	return tokens, nil
+}
+
+type caddyfileParser struct {
+	tokens []caddyfileToken
+	pos    int
+}
+
+// directives reads directives up to the closing brace of the current block,
+// or to the end of input at the top level.
+func (p *caddyfileParser) directives(nested bool) ([]CaddyfileDirective, error) {
+	var out []CaddyfileDirective
+	for p.pos < len(p.tokens) {
+		t := p.tokens[p.pos]
+		if t.isBrace("}") {
+			if !nested {
+				return nil, fmt.Errorf("line %d: unexpected '}'", t.line)
+			}
+			p.pos++
+			return out, nil
+		}
+		d, err := p.directive()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, d)
+	}
+	if nested {
+		return nil, fmt.Errorf("unexpected end of Caddyfile: missing '}'")
+	}
+	return out, nil
+}
+
+// directive reads the tokens on the current line and the block it opens.
+func (p *caddyfileParser) directive() (CaddyfileDirective, error) {
+	line := p.tokens[p.pos].line
+	d := CaddyfileDirective{Line: line}
+	for p.pos < len(p.tokens) && p.tokens[p.pos].line == line {
+		t := p.tokens[p.pos]
+		if t.isBrace("}") {
+			break
+		}
+		p.pos++
+		if t.isBrace("{") {
+			block, err := p.directives(true)
+			if err != nil {
+				return d, err
+			}
+			d.HasBlock = true
+			d.Block = block
+			break
+		}
+		d.Tokens = append(d.Tokens, t.text)
+	}
+	return d, nil
+}
+
+// HostRequestFromSite maps a Caddyfile site block onto a host. It
+// recognises the handlers the panel renders itself — reverse_proxy,
+// file_server with root, php_fastcgi, redir — plus encode, and keeps every
+// other directive verbatim in CustomDirectives. The error explains why the
+// site cannot become a host.
+func HostRequestFromSite(site CaddyfileBlock) (*model.HostCreateRequest, error) {
+	if len(site.Addresses) == 0 {
+		return nil, fmt.Errorf("block has no site address")
+	}
+	var domains []string
+	plainHTTP := 0
+	for _, addr := range site.Addresses {
+		domain, isHTTP, err := siteAddressDomain(addr)
+		if err != nil {
+			return nil, err
+		}
+		if isHTTP {
+			plainHTTP++
+		}
+		domains = append(domains, domain)
+	}
+	if plainHTTP > 0 && plainHTTP < len(domains) {
+		return nil, fmt.Errorf("site mixes http:// and https:// addresses")
+	}
+
+	req := &model.HostCreateRequest{
+		Domain:  domains[0],
+		Aliases: strings.Join(domains[1:], ","),
+	}
+	if plainHTTP > 0 {
+		off := false
+		req.TLSEnabled = &off
+	}
+
+	var (
+		custom                []string
+		rootDirective         string
+		proxies, phps, redirs int
+		fileServer            bool
+	)
+	for _, d := range site.Directives {
+		args := d.Args()
+		switch d.Name() {
+		case "reverse_proxy":
+			if len(args) == 0 && !d.HasBlock || len(args) > 0 && isCaddyfileMatcher(args[0]) {
+				break
+			}
+			if err := importReverseProxy(req, d); err != nil {
+				return nil, err
+			}
+			proxies++
+			continue
+		case "root":
+			// A single argument is always the path, never a matcher.
+			if d.HasBlock || len(args) == 0 || len(args) > 2 || len(args) == 2 && args[0] != "*" || strings.HasPrefix(args[0], "@") {
+				break
+			}
+			req.RootPath = args[len(args)-1]
+			rootDirective = d.String()
+			continue
+		case "file_server":
+			if d.HasBlock || len(args) > 1 || len(args) == 1 && args[0] != "browse" {
+				break
+			}
+			fileServer = true
+			if len(args) == 1 {
+				browse := true
+				req.DirectoryBrowse = &browse
+			}
+			continue
+		case "php_fastcgi":
+			if len(args) != 1 || isCaddyfileMatcher(args[0]) {
+				break
+			}
+			for _, sub := range d.Block {
+				switch sub.Name() {
+				case "split", "index", "try_files", "resolve_root_symlink":
+				default:
+					return nil, fmt.Errorf("php_fastcgi option '%s' is not supported", sub.Name())
+				}
+			}
+			req.PHPFastCGI = args[0]
+			phps++
+			continue
+		case "redir":
+			if d.HasBlock || len(args) == 0 || len(args) > 2 || !strings.HasSuffix(args[0], "{uri}") {
+				break
+			}
+			code := 301
+			if len(args) == 2 {
+				switch args[1] {
+				case "permanent", "301":
+				case "temporary", "302":
+					code = 302
+				default:
+					return nil, fmt.Errorf("redirect code '%s' is not supported", args[1])
+				}
+			}
+			req.RedirectURL = strings.TrimSuffix(args[0], "{uri}")
+			req.RedirectCode = code
+			redirs++
+			continue
+		case "import":
+			return nil, fmt.Errorf("site imports '%s'; inline it before importing", strings.Join(args, " "))
+		case "encode":
+			if d.HasBlock {
+				break
+			}
+			algorithms, err := NormalizeCompressionAlgorithms(strings.Join(args, ","))
+			if err != nil {
+				break
+			}
+			on := true
+			req.Compression = &on
+			req.CompressionAlgorithms = algorithms
+			continue
+		}
+		custom = append(custom, d.String())
+	}
+
+	switch {
+	case proxies+phps+redirs > 1 || proxies+redirs > 0 && fileServer:
+		return nil, fmt.Errorf("site combines several handlers (reverse_proxy, php_fastcgi, file_server, redir)")
+	case proxies == 1:
+		req.HostType = "proxy"
+	case phps == 1:
+		req.HostType = "php"
+	case redirs == 1:
+		req.HostType = "redirect"
+	case fileServer:
+		req.HostType = "static"
+	default:
+		return nil, fmt.Errorf("site has no reverse_proxy, file_server, php_fastcgi or redir handler")
+	}
+	if req.HostType == "static" || req.HostType == "php" {
+		if req.RootPath == "" {
+			return nil, fmt.Errorf("%s site has no root directive", req.HostType)
+		}
+	} else if rootDirective != "" {
+		req.RootPath = ""
+		custom = append(custom, rootDirective)
+	}
+	req.CustomDirectives = strings.Join(custom, "\n")
+	return req, nil
+}
+
+// importReverseProxy takes the upstreams of a reverse_proxy directive and
+// the options the panel renders itself. Any other option is an error.
+func importReverseProxy(req *model.HostCreateRequest, d CaddyfileDirective) error {
+	if len(req.Upstreams) > 0 {
+		return fmt.Errorf("site has more than one reverse_proxy")
+	}
+	upstreams := d.Args()
+	for _, sub := range d.Block {
+		args := sub.Args()
+		switch {
+		case sub.Name() == "to" && len(args) > 0:
+			upstreams = append(upstreams, args...)
+		case sub.Name() == "lb_policy" && len(args) == 1 && args[0] == "round_robin":
+		case sub.Name() == "header_up" && strings.Join(args, " ") == "Host {upstream_hostport}":
+		case sub.Name() == "header_up" && strings.Join(args, " ") == "X-Real-IP {remote_host}":
+		case sub.Name() == "transport" && len(args) == 1 && args[0] == "http":
+			for _, opt := range sub.Block {
+				if len(opt.Args()) != 1 || opt.HasBlock {
+					return fmt.Errorf("reverse_proxy transport option '%s' is not supported", opt.Name())
+				}
+				switch opt.Name() {
+				case "dial_timeout":
+					req.ProxyDialTimeout = opt.Args()[0]
+				case "read_timeout":
+					req.ProxyReadTimeout = opt.Args()[0]
+				case "write_timeout":
+					req.ProxyWriteTimeout = opt.Args()[0]
+				default:
+					return fmt.Errorf("reverse_proxy transport option '%s' is not supported", opt.Name())
+				}
+			}
+		default:
+			return fmt.Errorf("reverse_proxy option '%s' is not supported", sub.Name())
+		}
+	}
+	if len(upstreams) == 0 {
+		return fmt.Errorf("reverse_proxy has no upstream")
+	}
+	for _, u := range upstreams {
+		if strings.HasPrefix(u, ":") {
+			u = "localhost" + u // Caddy's shorthand for a local port
+		}
+		req.Upstreams = append(req.Upstreams, model.UpstreamInput{Address: u, Weight: 1})
+	}
+	return nil
+}
+
+// siteAddressDomain extracts the domain of a site address, reporting whether
+// the site is served over plain HTTP. Addresses with a path or a port other
+// than the scheme's standard one have no host equivalent.
+func siteAddressDomain(addr string) (domain string, plainHTTP bool, err error) {
+	rest := addr
+	switch {
+	case strings.HasPrefix(rest, "http://"):
+		rest, plainHTTP = strings.TrimPrefix(rest, "http://"), true
+	case strings.HasPrefix(rest, "https://"):
+		rest = strings.TrimPrefix(rest, "https://")
+	}
+	if strings.Contains(rest, "/") {
+		return "", false, fmt.Errorf("site address '%s' has a path", addr)
+	}
+	if host, port, splitErr := net.SplitHostPort(rest); splitErr == nil {
+		switch {
+		case port == "80":
+			plainHTTP = true
+		case port == "443" && !plainHTTP:
+		default:
+			return "", false, fmt.Errorf("site address '%s' uses a non-standard port", addr)
+		}
+		rest = host
+	}
+	if err := ValidateDomain(rest); err != nil {
+		return "", false, fmt.Errorf("site address '%s': %w", addr, err)
+	}
+	return rest, plainHTTP, nil
+}
+
+// isCaddyfileMatcher reports whether a directive argument is a request
+// matcher token rather than a value.
+func isCaddyfileMatcher(arg string) bool {
+	return arg == "*" || strings.HasPrefix(arg, "@") || strings.HasPrefix(arg, "/")
+}
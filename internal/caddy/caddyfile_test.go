@@ -0,0 +1,160 @@
+package caddy
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseCaddyfile(t *testing.T) {
+	content := `{
+	email admin@example.com
+}
+
+(common) {
+	encode gzip
+}
+
+# main site
+example.com, www.example.com {
+	reverse_proxy localhost:8080 {
+		header_up X-Real-IP {remote_host}
+	}
+	respond /health "ok {host}" 200
+}
+`
+	blocks, err := ParseCaddyfile(content)
+	if err != nil {
+		t.Fatalf("ParseCaddyfile: %v", err)
+	}
+	if len(blocks) != 3 {
+		t.Fatalf("%d blocks, want 3: %+v", len(blocks), blocks)
+	}
+	if len(blocks[0].Addresses) != 0 || blocks[0].Snippet != "" || len(blocks[0].Directives) != 1 {
+		t.Errorf("global options block = %+v", blocks[0])
+	}
+	if blocks[1].Snippet != "common" {
+		t.Errorf("snippet = %q, want common", blocks[1].Snippet)
+	}
+
+	site := blocks[2]
+	if !reflect.DeepEqual(site.Addresses, []string{"example.com", "www.example.com"}) || site.Line != 10 {
+		t.Errorf("site addresses = %v (line %d)", site.Addresses, site.Line)
+	}
+	if len(site.Directives) != 2 {
+		t.Fatalf("%d site directives, want 2", len(site.Directives))
+	}
+	proxy := site.Directives[0]
+	if proxy.Name() != "reverse_proxy" || !proxy.HasBlock || len(proxy.Block) != 1 {
+		t.Errorf("reverse_proxy = %+v", proxy)
+	}
+	respond := site.Directives[1]
+	if !reflect.DeepEqual(respond.Args(), []string{"/health", "ok {host}", "200"}) {
+		t.Errorf("respond args = %q", respond.Args())
+	}
+	if got := respond.String(); got != `respond /health "ok {host}" 200` {
+		t.Errorf("respond rendered as %q", got)
+	}
+	if got := proxy.String(); got != "reverse_proxy localhost:8080 {\n\theader_up X-Real-IP {remote_host}\n}" {
+		t.Errorf("reverse_proxy rendered as %q", got)
+	}
+}
+
+func TestParseCaddyfile_SingleSiteWithoutBraces(t *testing.T) {
+	blocks, err := ParseCaddyfile("localhost:8443\n\nfile_server browse\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blocks) != 1 || !reflect.DeepEqual(blocks[0].Addresses, []string{"localhost:8443"}) || len(blocks[0].Directives) != 1 {
+		t.Errorf("blocks = %+v", blocks)
+	}
+}
+
+func TestParseCaddyfile_Errors(t *testing.T) {
+	for _, content := range []string{
+		"example.com {\n\treverse_proxy localhost:8080\n",
+		"example.com {\n}\n}\n",
+		"example.com {\n\trespond \"unterminated\n}\n",
+		"example.com {\n\trespond <<EOF\n\t\thi\n\t\tEOF 200\n}\n",
+	} {
+		if _, err := ParseCaddyfile(content); err == nil {
+			t.Errorf("ParseCaddyfile(%q) succeeded", content)
+		}
+	}
+}
+
+func TestHostRequestFromSite(t *testing.T) {
+	parse := func(content string) CaddyfileBlock {
+		t.Helper()
+		blocks, err := ParseCaddyfile(content)
+		if err != nil || len(blocks) != 1 {
+			t.Fatalf("ParseCaddyfile: %v (%d blocks)", err, len(blocks))
+		}
+		return blocks[0]
+	}
+
+	req, err := HostRequestFromSite(parse(`app.example.com, www.app.example.com {
+	encode zstd gzip
+	reverse_proxy :3000 localhost:3001 {
+		lb_policy round_robin
+		transport http {
+			read_timeout 30s
+		}
+	}
+	header X-Frame-Options DENY
+}`))
+	if err != nil {
+		t.Fatalf("proxy site: %v", err)
+	}
+	if req.HostType != "proxy" || req.Domain != "app.example.com" || req.Aliases != "www.app.example.com" {
+		t.Errorf("proxy host = %s %s (aliases %q)", req.HostType, req.Domain, req.Aliases)
+	}
+	if len(req.Upstreams) != 2 || req.Upstreams[0].Address != "localhost:3000" || req.Upstreams[1].Address != "localhost:3001" {
+		t.Errorf("upstreams = %+v", req.Upstreams)
+	}
+	if req.ProxyReadTimeout != "30s" || req.Compression == nil || !*req.Compression || req.CompressionAlgorithms != "zstd,gzip" {
+		t.Errorf("timeout %q, compression %v %q", req.ProxyReadTimeout, req.Compression, req.CompressionAlgorithms)
+	}
+	if req.CustomDirectives != "header X-Frame-Options DENY" {
+		t.Errorf("custom directives = %q", req.CustomDirectives)
+	}
+
+	req, err = HostRequestFromSite(parse("http://files.example.com {\n\troot * /srv/files\n\tfile_server browse\n}"))
+	if err != nil {
+		t.Fatalf("static site: %v", err)
+	}
+	if req.HostType != "static" || req.RootPath != "/srv/files" || req.DirectoryBrowse == nil || !*req.DirectoryBrowse || req.TLSEnabled == nil || *req.TLSEnabled {
+		t.Errorf("static host = %+v", req)
+	}
+
+	req, err = HostRequestFromSite(parse("old.example.com {\n\tredir https://new.example.com{uri} temporary\n}"))
+	if err != nil {
+		t.Fatalf("redirect site: %v", err)
+	}
+	if req.HostType != "redirect" || req.RedirectURL != "https://new.example.com" || req.RedirectCode != 302 {
+		t.Errorf("redirect host = %s %q %d", req.HostType, req.RedirectURL, req.RedirectCode)
+	}
+
+	req, err = HostRequestFromSite(parse("blog.example.com {\n\troot * /var/www/blog\n\tphp_fastcgi unix//run/php/php8.3-fpm.sock\n\tfile_server\n}"))
+	if err != nil {
+		t.Fatalf("php site: %v", err)
+	}
+	if req.HostType != "php" || req.PHPFastCGI != "unix//run/php/php8.3-fpm.sock" || req.CustomDirectives != "" {
+		t.Errorf("php host = %+v", req)
+	}
+
+	for content, want := range map[string]string{
+		":8080 {\n\treverse_proxy localhost:3000\n}":                                  "site address",
+		"example.com/api {\n\treverse_proxy localhost:3000\n}":                        "has a path",
+		"example.com {\n\trespond \"hi\"\n}":                                          "no reverse_proxy",
+		"example.com {\n\treverse_proxy localhost:1 {\n\t\tflush_interval -1\n\t}\n}": "flush_interval",
+		"example.com {\n\treverse_proxy localhost:1\n\tredir https://x.com{uri}\n}":   "several handlers",
+		"example.com {\n\timport common\n\treverse_proxy localhost:1\n}":              "inline it",
+		"example.com {\n\tfile_server\n}":                                             "no root",
+	} {
+		_, err := HostRequestFromSite(parse(content))
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("HostRequestFromSite(%q) error = %v, want %q", content, err, want)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package caddy
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Managed certificate states reported by ManagedCertificate.
+const (
+	CertPending    = "pending"     // Caddy has not obtained a certificate yet
+	CertValid      = "valid"       // issued and not yet due for renewal
+	CertRenewalDue = "renewal_due" // inside the renewal window, Caddy renews it shortly
+	CertExpired    = "expired"
+)
+
+// renewalWindowRatio is the share of a certificate's lifetime left when
+// Caddy renews it (CertMagic's default).
+const renewalWindowRatio = 1.0 / 3
+
+// ManagedCertStatus describes the certificate Caddy obtained for a domain.
+// Only Status and Domain are set while the certificate is pending.
+type ManagedCertStatus struct {
+	Domain           string     `json:"domain"`
+	Status           string     `json:"status"`
+	Issuer           string     `json:"issuer,omitempty"`
+	NotBefore        *time.Time `json:"not_before,omitempty"`
+	NotAfter         *time.Time `json:"not_after,omitempty"`
+	RenewAt          *time.Time `json:"renew_at,omitempty"`
+	DaysUntilRenewal *int       `json:"days_until_renewal,omitempty"`
+}
+
+// ManagedCertificate reports the certificate Caddy manages for domain. Caddy's
+// admin API does not list managed certificates, so it is read from Caddy's
+// certificate storage; when several issuers hold one, the newest counts.
+func (m *Manager) ManagedCertificate(domain string) (*ManagedCertStatus, error) {
+	key := certStorageKey(domain)
+	if key == "" {
+		return nil, fmt.Errorf("invalid domain for certificate lookup: %q", domain)
+	}
+	pattern := filepath.Join(m.certStorageDir(), "*", key, key+".crt")
+	files, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	var newest *x509.Certificate
+	for _, f := range files {
+		cert, err := readLeafCertificate(f)
+		if err != nil {
+			continue
+		}
+		if newest == nil || cert.NotAfter.After(newest.NotAfter) {
+			newest = cert
+		}
+	}
+	return certStatus(domain, newest, time.Now()), nil
+}
+
+// certStorageDir is where Caddy keeps the certificates it obtained, under
+// the XDG data home the manager starts it with.
+func (m *Manager) certStorageDir() string {
+	return filepath.Join(filepath.Dir(m.cfg.CaddyfilePath), "caddy_data", "caddy", "certificates")
+}
+
+// certStorageKey mirrors how CertMagic names a domain's storage directory.
+func certStorageKey(domain string) string {
+	key := strings.ToLower(strings.TrimSpace(domain))
+	key = strings.ReplaceAll(key, "*", "wildcard_")
+	key = strings.ReplaceAll(key, " ", "_")
+	key = strings.ReplaceAll(key, "..", "")
+	if strings.ContainsAny(key, `/\:`) {
+		return ""
+	}
+	return key
+}
+
+// readLeafCertificate parses the first certificate of a PEM bundle.
+func readLeafCertificate(path string) (*x509.Certificate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data in %s", path)
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// certStatus builds the status of cert as of now; a nil cert is pending.
+func certStatus(domain string, cert *x509.Certificate, now time.Time) *ManagedCertStatus {
+	status := &ManagedCertStatus{Domain: domain, Status: CertPending}
+	if cert == nil {
+		return status
+	}
+
+	lifetime := cert.NotAfter.Sub(cert.NotBefore)
+	renewAt := cert.NotAfter.Add(-time.Duration(float64(lifetime) * renewalWindowRatio))
+	days := int(math.Floor(renewAt.Sub(now).Hours() / 24))
+	notBefore, notAfter := cert.NotBefore, cert.NotAfter
+
+	status.Issuer = cert.Issuer.CommonName
+	if status.Issuer == "" {
+		status.Issuer = cert.Issuer.String()
+	}
+	status.NotBefore, status.NotAfter = &notBefore, &notAfter
+	status.RenewAt = &renewAt
+	status.DaysUntilRenewal = &days
+	switch {
+	case now.After(cert.NotAfter):
+		status.Status = CertExpired
+	case now.After(renewAt):
+		status.Status = CertRenewalDue
+	default:
+		status.Status = CertValid
+	}
+	return status
+}
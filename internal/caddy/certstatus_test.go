@@ -0,0 +1,114 @@
+package caddy
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/web-casa/webcasa/internal/config"
+)
+
+// storeManagedCert writes a certificate for domain valid over [notBefore,
+// notAfter] into Caddy's storage under issuerDir, as Caddy would.
+func storeManagedCert(t *testing.T, m *Manager, issuerDir, domain string, notBefore, notAfter time.Time) {
+	t.Helper()
+	caKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "R11"},
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(notAfter.UnixNano()),
+		Subject:      pkix.Name{CommonName: domain},
+		DNSNames:     []string{domain},
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca, &key.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k := certStorageKey(domain)
+	dir := filepath.Join(m.certStorageDir(), issuerDir, k)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	if err := os.WriteFile(filepath.Join(dir, k+".crt"), data, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestManagedCertificate_Issued(t *testing.T) {
+	m := NewManager(&config.Config{CaddyfilePath: filepath.Join(t.TempDir(), "Caddyfile")})
+	now := time.Now()
+	// 90-day certificate issued 10 days ago: renewal at day 60, ~50 days away
+	storeManagedCert(t, m, "acme-v02.api.letsencrypt.org-directory", "shop.example.com",
+		now.Add(-10*24*time.Hour), now.Add(80*24*time.Hour))
+	// An older certificate from another issuer is ignored
+	storeManagedCert(t, m, "acme.zerossl.com-v2-dv90", "shop.example.com",
+		now.Add(-80*24*time.Hour), now.Add(10*24*time.Hour))
+
+	st, err := m.ManagedCertificate("Shop.Example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Status != CertValid || st.Issuer != "R11" {
+		t.Errorf("status = %+v", st)
+	}
+	if st.NotAfter == nil || st.NotAfter.Sub(now) < 79*24*time.Hour {
+		t.Errorf("NotAfter = %v, want the newest certificate", st.NotAfter)
+	}
+	if st.DaysUntilRenewal == nil || *st.DaysUntilRenewal != 49 {
+		t.Errorf("DaysUntilRenewal = %v, want 49", st.DaysUntilRenewal)
+	}
+}
+
+func TestManagedCertificate_Pending(t *testing.T) {
+	m := NewManager(&config.Config{CaddyfilePath: filepath.Join(t.TempDir(), "Caddyfile")})
+	st, err := m.ManagedCertificate("new.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Status != CertPending || st.NotAfter != nil || st.DaysUntilRenewal != nil {
+		t.Errorf("status = %+v, want pending without dates", st)
+	}
+}
+
+func TestCertStatus_Windows(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	cert := &x509.Certificate{NotBefore: start, NotAfter: start.Add(90 * 24 * time.Hour)}
+	for _, tc := range []struct {
+		day  int
+		want string
+	}{
+		{1, CertValid},
+		{61, CertRenewalDue},
+		{91, CertExpired},
+	} {
+		st := certStatus("example.com", cert, start.Add(time.Duration(tc.day)*24*time.Hour))
+		if st.Status != tc.want {
+			t.Errorf("day %d: status = %s, want %s", tc.day, st.Status, tc.want)
+		}
+	}
+	if key := certStorageKey("*.Example.com"); key != "wildcard_.example.com" {
+		t.Errorf("certStorageKey(wildcard) = %q", key)
+	}
+}
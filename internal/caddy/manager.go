@@ -2,6 +2,7 @@ package caddy
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -12,22 +13,47 @@ import (
 	"runtime"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/web-casa/webcasa/internal/config"
 	"github.com/web-casa/webcasa/internal/versions"
 )
 
+// ErrInvalidCaddyfile is returned by WriteCaddyfile when `caddy validate`
+// rejects the content; the live Caddyfile is left untouched.
+var ErrInvalidCaddyfile = errors.New("Caddyfile validation failed")
+
+// Lifecycle event kinds reported to a Manager's listener.
+const (
+	LifecycleStarted      = "started"
+	LifecycleStartFailed  = "start_failed"
+	LifecycleStopped      = "stopped"
+	LifecycleReloadFailed = "reload_failed"
+)
+
+// LifecycleEvent reports a start, stop or failed reload of Caddy.
+type LifecycleEvent struct {
+	Kind string
+	Err  error // for LifecycleStartFailed and LifecycleReloadFailed
+}
+
 // Manager handles Caddy process lifecycle and configuration reloading
 type Manager struct {
 	cfg  *config.Config
 	mu   sync.Mutex
 	proc *os.Process // tracked only when we start Caddy ourselves
 
+	// shouldRun is set once Caddy is started (or found running) and cleared by
+	// an explicit Stop; the watchdog only restarts Caddy while it is set.
+	shouldRun atomic.Bool
+
 	// Reload coalescing: multiple rapid reload requests are merged into one.
 	reloadMu      sync.Mutex
 	reloadTimer   *time.Timer
 	reloadWaiters []chan error // all goroutines waiting for the coalesced reload
+
+	onLifecycle atomic.Pointer[func(LifecycleEvent)]
 }
 
 // NewManager creates a new Caddy manager
@@ -35,6 +61,18 @@ func NewManager(cfg *config.Config) *Manager {
 	return &Manager{cfg: cfg}
 }
 
+// OnLifecycle sets the listener called after Caddy is started or stopped
+// through the manager, or fails to start or reload.
+func (m *Manager) OnLifecycle(fn func(LifecycleEvent)) {
+	m.onLifecycle.Store(&fn)
+}
+
+func (m *Manager) emit(kind string, err error) {
+	if fn := m.onLifecycle.Load(); fn != nil && *fn != nil {
+		(*fn)(LifecycleEvent{Kind: kind, Err: err})
+	}
+}
+
 // WriteCaddyfile atomically writes a Caddyfile:
 //  1. Write to temp file
 //  2. Validate with `caddy validate`
@@ -59,7 +97,7 @@ func (m *Manager) WriteCaddyfile(content string) error {
 		output, err := cmd.CombinedOutput()
 		if err != nil {
 			os.Remove(tmpPath)
-			return fmt.Errorf("Caddyfile validation failed: %s\n%s", err, string(output))
+			return fmt.Errorf("%w: %s\n%s", ErrInvalidCaddyfile, err, string(output))
 		}
 	} else {
 		log.Printf("⚠️  Caddy binary not found (%s), skipping validation", m.cfg.CaddyBin)
@@ -99,7 +137,9 @@ func (m *Manager) Reload() error {
 
 	output, err := cmd.CombinedOutput()
 	if err != nil {
-		return fmt.Errorf("caddy reload failed: %s\n%s", err, string(output))
+		err = fmt.Errorf("caddy reload failed: %s\n%s", err, string(output))
+		m.emit(LifecycleReloadFailed, err)
+		return err
 	}
 	log.Println("Caddy reloaded successfully")
 	return nil
@@ -164,6 +204,7 @@ func (m *Manager) EnsureCaddyfile() error {
 
 {
 	admin localhost:2019
+	metrics
 	log {
 		output file %s/caddy.log {
 			roll_size 100MiB
@@ -195,7 +236,7 @@ func (m *Manager) Start() error {
 	if _, err := os.Stat(m.cfg.CaddyfilePath); os.IsNotExist(err) {
 		log.Println("No Caddyfile found, creating default before starting Caddy...")
 		os.MkdirAll(filepath.Dir(m.cfg.CaddyfilePath), 0755)
-		defaultCfg := fmt.Sprintf("# Auto-generated by Web.Casa (https://web.casa)\n{\n\tadmin localhost:2019\n\tlog {\n\t\toutput file %s/caddy.log {\n\t\t\troll_size 100MiB\n\t\t\troll_keep 5\n\t\t}\n\t\tlevel INFO\n\t}\n}\n", m.cfg.LogDir)
+		defaultCfg := fmt.Sprintf("# Auto-generated by Web.Casa (https://web.casa)\n{\n\tadmin localhost:2019\n\tmetrics\n\tlog {\n\t\toutput file %s/caddy.log {\n\t\t\troll_size 100MiB\n\t\t\troll_keep 5\n\t\t}\n\t\tlevel INFO\n\t}\n}\n", m.cfg.LogDir)
 		if err := os.WriteFile(m.cfg.CaddyfilePath, []byte(defaultCfg), 0600); err != nil {
 			return fmt.Errorf("failed to create default Caddyfile: %w", err)
 		}
@@ -223,11 +264,16 @@ func (m *Manager) Start() error {
 	cmd.Stderr = nil
 	if err := cmd.Run(); err != nil {
 		if ctx.Err() == context.DeadlineExceeded {
-			return fmt.Errorf("caddy start timed out after 15s")
+			err = fmt.Errorf("caddy start timed out after 15s")
+		} else {
+			err = fmt.Errorf("caddy start failed: %v", err)
 		}
-		return fmt.Errorf("caddy start failed: %v", err)
+		m.emit(LifecycleStartFailed, err)
+		return err
 	}
+	m.shouldRun.Store(true)
 	log.Println("Caddy started successfully")
+	m.emit(LifecycleStarted, nil)
 	return nil
 }
 
@@ -241,10 +287,24 @@ func (m *Manager) Stop() error {
 	if err != nil {
 		return fmt.Errorf("caddy stop failed: %s\n%s", err, string(output))
 	}
+	m.shouldRun.Store(false)
 	log.Println("Caddy stopped successfully")
+	m.emit(LifecycleStopped, nil)
 	return nil
 }
 
+// ExpectRunning marks Caddy as meant to be running without starting it, for
+// a Caddy the panel found already up.
+func (m *Manager) ExpectRunning() {
+	m.shouldRun.Store(true)
+}
+
+// ShouldRun reports whether Caddy is meant to be running: it was started or
+// found running and has not been stopped through the panel since.
+func (m *Manager) ShouldRun() bool {
+	return m.shouldRun.Load()
+}
+
 // IsRunning checks if a Caddy process is currently running
 func (m *Manager) IsRunning() bool {
 	// Try to hit the admin API
@@ -0,0 +1,24 @@
+package caddy
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/web-casa/webcasa/internal/config"
+)
+
+func TestManagerReload_EmitsReloadFailed(t *testing.T) {
+	m := NewManager(&config.Config{
+		CaddyBin:      "false", // exits non-zero like a failed reload
+		CaddyfilePath: filepath.Join(t.TempDir(), "Caddyfile"),
+	})
+	var events []LifecycleEvent
+	m.OnLifecycle(func(e LifecycleEvent) { events = append(events, e) })
+
+	if err := m.Reload(); err == nil {
+		t.Fatal("expected reload error")
+	}
+	if len(events) != 1 || events[0].Kind != LifecycleReloadFailed || events[0].Err == nil {
+		t.Fatalf("expected one reload_failed event with an error, got %+v", events)
+	}
+}
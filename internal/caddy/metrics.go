@@ -0,0 +1,154 @@
+package caddy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+// ErrNotRunning is returned when the Caddy admin API cannot be reached.
+var ErrNotRunning = errors.New("caddy is not running")
+
+// Caddy metric families summarized by Metrics.
+const (
+	metricRequests         = "caddy_http_requests_total"
+	metricRequestErrors    = "caddy_http_request_errors_total"
+	metricRequestDuration  = "caddy_http_request_duration_seconds"
+	metricUpstreamsHealthy = "caddy_reverse_proxy_upstreams_healthy"
+)
+
+// MetricsSummary is a dashboard-friendly digest of Caddy's Prometheus metrics.
+type MetricsSummary struct {
+	RequestsTotal    float64            `json:"requests_total"`
+	RequestErrors    float64            `json:"request_errors"`
+	RequestsByServer map[string]float64 `json:"requests_by_server"`
+	Duration         DurationSummary    `json:"duration"`
+	Upstreams        []UpstreamHealth   `json:"upstreams"`
+}
+
+// DurationSummary aggregates the request-duration histogram across all
+// servers, handlers, methods and status codes.
+type DurationSummary struct {
+	Count      uint64           `json:"count"`
+	SumSeconds float64          `json:"sum_seconds"`
+	AvgSeconds float64          `json:"avg_seconds"`
+	Buckets    []DurationBucket `json:"buckets"`
+}
+
+// DurationBucket is one cumulative histogram bucket: Count requests took at
+// most LE seconds.
+type DurationBucket struct {
+	LE    float64 `json:"le"`
+	Count uint64  `json:"count"`
+}
+
+// UpstreamHealth reports whether a reverse-proxy upstream is healthy.
+type UpstreamHealth struct {
+	Address string `json:"address"`
+	Healthy bool   `json:"healthy"`
+}
+
+// Metrics fetches Caddy's metrics from the admin API and summarizes request
+// counts, latency and upstream health. It returns ErrNotRunning when the
+// admin API is unreachable.
+func (m *Manager) Metrics(ctx context.Context) (*MetricsSummary, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, m.cfg.AdminAPI+"/metrics", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, ErrNotRunning
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("caddy metrics returned status %d", resp.StatusCode)
+	}
+	return parseMetricsSummary(resp.Body)
+}
+
+// parseMetricsSummary parses a Prometheus text exposition from Caddy.
+func parseMetricsSummary(r io.Reader) (*MetricsSummary, error) {
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(r)
+	if err != nil {
+		return nil, fmt.Errorf("parse caddy metrics: %w", err)
+	}
+
+	summary := &MetricsSummary{
+		RequestsByServer: map[string]float64{},
+		Duration:         DurationSummary{Buckets: []DurationBucket{}},
+		Upstreams:        []UpstreamHealth{},
+	}
+
+	if f := families[metricRequests]; f != nil {
+		for _, m := range f.GetMetric() {
+			v := m.GetCounter().GetValue()
+			summary.RequestsTotal += v
+			summary.RequestsByServer[labelValue(m, "server")] += v
+		}
+	}
+	if f := families[metricRequestErrors]; f != nil {
+		for _, m := range f.GetMetric() {
+			summary.RequestErrors += m.GetCounter().GetValue()
+		}
+	}
+
+	if f := families[metricRequestDuration]; f != nil {
+		buckets := map[float64]uint64{}
+		for _, m := range f.GetMetric() {
+			h := m.GetHistogram()
+			summary.Duration.Count += h.GetSampleCount()
+			summary.Duration.SumSeconds += h.GetSampleSum()
+			for _, b := range h.GetBucket() {
+				buckets[b.GetUpperBound()] += b.GetCumulativeCount()
+			}
+		}
+		for le, n := range buckets {
+			if math.IsInf(le, 1) {
+				continue // always equal to Count, and +Inf does not encode as JSON
+			}
+			summary.Duration.Buckets = append(summary.Duration.Buckets, DurationBucket{LE: le, Count: n})
+		}
+		sort.Slice(summary.Duration.Buckets, func(i, j int) bool {
+			return summary.Duration.Buckets[i].LE < summary.Duration.Buckets[j].LE
+		})
+		if summary.Duration.Count > 0 {
+			summary.Duration.AvgSeconds = summary.Duration.SumSeconds / float64(summary.Duration.Count)
+		}
+	}
+
+	if f := families[metricUpstreamsHealthy]; f != nil {
+		for _, m := range f.GetMetric() {
+			summary.Upstreams = append(summary.Upstreams, UpstreamHealth{
+				Address: labelValue(m, "upstream"),
+				Healthy: m.GetGauge().GetValue() > 0,
+			})
+		}
+		sort.Slice(summary.Upstreams, func(i, j int) bool {
+			return summary.Upstreams[i].Address < summary.Upstreams[j].Address
+		})
+	}
+
+	return summary, nil
+}
+
+func labelValue(m *dto.Metric, name string) string {
+	for _, l := range m.GetLabel() {
+		if l.GetName() == name {
+			return l.GetValue()
+		}
+	}
+	return ""
+}
@@ -0,0 +1,93 @@
+package caddy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/web-casa/webcasa/internal/config"
+)
+
+const stubCaddyMetrics = `# HELP caddy_http_requests_total Counter of HTTP(S) requests made.
+# TYPE caddy_http_requests_total counter
+caddy_http_requests_total{handler="reverse_proxy",server="srv0"} 120
+caddy_http_requests_total{handler="file_server",server="srv1"} 30
+# HELP caddy_http_request_errors_total Number of requests resulting in middleware errors.
+# TYPE caddy_http_request_errors_total counter
+caddy_http_request_errors_total{handler="reverse_proxy",server="srv0"} 4
+# HELP caddy_http_request_duration_seconds Histogram of round-trip request durations.
+# TYPE caddy_http_request_duration_seconds histogram
+caddy_http_request_duration_seconds_bucket{code="200",handler="reverse_proxy",method="GET",server="srv0",le="0.05"} 80
+caddy_http_request_duration_seconds_bucket{code="200",handler="reverse_proxy",method="GET",server="srv0",le="0.5"} 110
+caddy_http_request_duration_seconds_bucket{code="200",handler="reverse_proxy",method="GET",server="srv0",le="+Inf"} 120
+caddy_http_request_duration_seconds_sum{code="200",handler="reverse_proxy",method="GET",server="srv0"} 9
+caddy_http_request_duration_seconds_count{code="200",handler="reverse_proxy",method="GET",server="srv0"} 120
+caddy_http_request_duration_seconds_bucket{code="200",handler="file_server",method="GET",server="srv1",le="0.05"} 30
+caddy_http_request_duration_seconds_bucket{code="200",handler="file_server",method="GET",server="srv1",le="0.5"} 30
+caddy_http_request_duration_seconds_bucket{code="200",handler="file_server",method="GET",server="srv1",le="+Inf"} 30
+caddy_http_request_duration_seconds_sum{code="200",handler="file_server",method="GET",server="srv1"} 0.6
+caddy_http_request_duration_seconds_count{code="200",handler="file_server",method="GET",server="srv1"} 30
+# HELP caddy_reverse_proxy_upstreams_healthy Health status of reverse proxy upstreams.
+# TYPE caddy_reverse_proxy_upstreams_healthy gauge
+caddy_reverse_proxy_upstreams_healthy{upstream="localhost:3000"} 1
+caddy_reverse_proxy_upstreams_healthy{upstream="localhost:3001"} 0
+`
+
+func TestMetrics_ParsesSummary(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/metrics" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, stubCaddyMetrics)
+	}))
+	defer srv.Close()
+
+	m := NewManager(&config.Config{AdminAPI: srv.URL})
+	s, err := m.Metrics(context.Background())
+	if err != nil {
+		t.Fatalf("Metrics: %v", err)
+	}
+
+	if s.RequestsTotal != 150 || s.RequestsByServer["srv0"] != 120 || s.RequestsByServer["srv1"] != 30 {
+		t.Errorf("requests = %v by server %v", s.RequestsTotal, s.RequestsByServer)
+	}
+	if s.RequestErrors != 4 {
+		t.Errorf("request errors = %v, want 4", s.RequestErrors)
+	}
+
+	d := s.Duration
+	if d.Count != 150 || d.SumSeconds != 9.6 {
+		t.Errorf("duration count/sum = %d/%v, want 150/9.6", d.Count, d.SumSeconds)
+	}
+	if d.AvgSeconds < 0.0639 || d.AvgSeconds > 0.0641 {
+		t.Errorf("avg = %v, want 0.064", d.AvgSeconds)
+	}
+	want := []DurationBucket{{LE: 0.05, Count: 110}, {LE: 0.5, Count: 140}}
+	if len(d.Buckets) != len(want) {
+		t.Fatalf("buckets = %+v, want %+v", d.Buckets, want)
+	}
+	for i := range want {
+		if d.Buckets[i] != want[i] {
+			t.Errorf("bucket %d = %+v, want %+v", i, d.Buckets[i], want[i])
+		}
+	}
+
+	if len(s.Upstreams) != 2 || !s.Upstreams[0].Healthy || s.Upstreams[1].Healthy || s.Upstreams[1].Address != "localhost:3001" {
+		t.Errorf("upstreams = %+v", s.Upstreams)
+	}
+}
+
+func TestMetrics_NotRunning(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	url := srv.URL
+	srv.Close()
+
+	m := NewManager(&config.Config{AdminAPI: url})
+	if _, err := m.Metrics(context.Background()); !errors.Is(err, ErrNotRunning) {
+		t.Errorf("err = %v, want ErrNotRunning", err)
+	}
+}
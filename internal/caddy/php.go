@@ -0,0 +1,77 @@
+package caddy
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// DefaultPHPFastCGI is the PHP-FPM address used when a PHP host names
+// neither an address nor a version.
+const DefaultPHPFastCGI = "localhost:9000"
+
+var phpVersionRegex = regexp.MustCompile(`^[0-9]\.[0-9]{1,2}$`)
+
+// PHPFastCGIOptions are the settings of a rendered php_fastcgi block.
+type PHPFastCGIOptions struct {
+	Address            string   // TCP host:port or unix//path of the FPM pool
+	Split              string   // path suffix that marks a PHP script
+	Index              string   // script used for directory requests
+	TryFiles           []string // front-controller fallback chain
+	ResolveRootSymlink bool     // follow a symlinked site root (deploy tools swap one)
+}
+
+// PHPPreset fills sensible php_fastcgi options for a pool given the way
+// admins usually know it: an explicit address (host:port, unix//path or a
+// bare socket path), or just the PHP version, which selects the Debian/Ubuntu
+// FPM socket /run/php/php<version>-fpm.sock. index is the directory index
+// script; empty means index.php.
+func PHPPreset(address, version, index string) PHPFastCGIOptions {
+	switch {
+	case strings.HasPrefix(address, "/"):
+		address = "unix/" + address
+	case address == "" && version != "":
+		address = fmt.Sprintf("unix//run/php/php%s-fpm.sock", version)
+	case address == "":
+		address = DefaultPHPFastCGI
+	}
+	if index == "" {
+		index = "index.php"
+	}
+	return PHPFastCGIOptions{
+		Address:            address,
+		Split:              ".php",
+		Index:              index,
+		TryFiles:           []string{"{path}", "{path}/" + index, index},
+		ResolveRootSymlink: true,
+	}
+}
+
+// ValidatePHPFastCGI checks a PHP-FPM address: TCP host:port, or a unix
+// socket given as unix//path or an absolute path. Empty is allowed.
+func ValidatePHPFastCGI(addr string) error {
+	if addr == "" {
+		return nil
+	}
+	if strings.ContainsAny(addr, " \t\n\r{}\"'`;#$\\") {
+		return fmt.Errorf("php_fastcgi address contains invalid characters")
+	}
+	if socket, ok := strings.CutPrefix(addr, "unix/"); ok || strings.HasPrefix(addr, "/") {
+		if !strings.HasPrefix(socket, "/") {
+			return fmt.Errorf("php_fastcgi socket path must be absolute")
+		}
+		return nil
+	}
+	if strings.Contains(addr, "://") {
+		return fmt.Errorf("php_fastcgi address must be host:port or a unix socket path, not a URL")
+	}
+	return ValidateUpstream(addr)
+}
+
+// ValidatePHPVersion checks a PHP version such as "8.3". Empty is allowed.
+func ValidatePHPVersion(version string) error {
+	if version != "" && !phpVersionRegex.MatchString(version) {
+		return fmt.Errorf("invalid PHP version '%s' (expected e.g. 8.3)", version)
+	}
+	return nil
+}
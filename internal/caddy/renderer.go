@@ -3,6 +3,7 @@ package caddy
 import (
 	"encoding/json"
 	"fmt"
+	"net/url"
 	"sort"
 	"strings"
 	"time"
@@ -25,6 +26,9 @@ func RenderCaddyfile(hosts []model.Host, cfg *config.Config, dnsProviders map[ui
 	// Global options block
 	b.WriteString("{\n")
 	b.WriteString("\tadmin localhost:2019\n")
+	// Caddy 2.8+ only records the per-request HTTP metrics read by
+	// Manager.Metrics when this is set.
+	b.WriteString("\tmetrics\n")
 	b.WriteString(fmt.Sprintf("\tlog {\n\t\toutput file %s/caddy.log {\n\t\t\troll_size 100MiB\n\t\t\troll_keep 5\n\t\t}\n\t\tlevel INFO\n\t}\n", cfg.LogDir))
 	b.WriteString("}\n\n")
 
@@ -39,20 +43,43 @@ func RenderCaddyfile(hosts []model.Host, cfg *config.Config, dnsProviders map[ui
 	return b.String()
 }
 
+// RenderHostBlock renders the Caddyfile site block for a single host, as it
+// would appear in the full Caddyfile.
+func RenderHostBlock(host model.Host, cfg *config.Config, dnsProviders map[uint]model.DnsProvider) string {
+	var b strings.Builder
+	renderHostBlock(&b, host, cfg, dnsProviders)
+	return b.String()
+}
+
+// HostDomains returns the names a host serves: its primary domain followed
+// by its comma-separated aliases.
+func HostDomains(host model.Host) []string {
+	names := []string{host.Domain}
+	for _, alias := range strings.Split(host.Aliases, ",") {
+		if alias = strings.TrimSpace(alias); alias != "" {
+			names = append(names, alias)
+		}
+	}
+	return names
+}
+
 func renderHostBlock(b *strings.Builder, host model.Host, cfg *config.Config, dnsProviders map[uint]model.DnsProvider) {
-	// Domain line
-	domain := host.Domain
+	// Site address line: the primary domain and any aliases
 	tlsMode := host.TLSMode
 	if tlsMode == "" {
 		tlsMode = "auto"
 	}
+	addresses := HostDomains(host)
 
 	// Handle TLS mode for domain prefix
-	if tlsMode == "off" || (host.TLSEnabled != nil && !*host.TLSEnabled) {
-		domain = "http://" + domain
+	httpOnly := tlsMode == "off" || (host.TLSEnabled != nil && !*host.TLSEnabled)
+	if httpOnly {
+		for i := range addresses {
+			addresses[i] = "http://" + addresses[i]
+		}
 	}
 
-	b.WriteString(fmt.Sprintf("%s {\n", domain))
+	b.WriteString(fmt.Sprintf("%s {\n", strings.Join(addresses, ", ")))
 
 	// TLS configuration based on mode
 	switch tlsMode {
@@ -61,19 +88,54 @@ func renderHostBlock(b *strings.Builder, host model.Host, cfg *config.Config, dn
 			b.WriteString(fmt.Sprintf("\ttls %s %s\n", host.CustomCertPath, host.CustomKeyPath))
 		}
 	case "dns", "wildcard":
+		var challenge string
 		if host.DnsProviderID != nil {
 			if p, ok := dnsProviders[*host.DnsProviderID]; ok {
-				renderDnsTLS(b, p)
+				challenge = dnsChallenge(p)
 			}
+		} else if p, ok := defaultDnsProvider(dnsProviders); ok {
+			challenge = dnsChallenge(p)
 		}
+		renderACMETLS(b, challenge, host.ACMECA)
 	case "off":
 		// no TLS block needed, http:// prefix handles it
-		// case "auto": default Caddy behavior, no tls block needed
+	default:
+		// "auto": default Caddy behavior, a tls block only to pick the CA
+		if !httpOnly {
+			renderACMETLS(b, "", host.ACMECA)
+		}
+	}
+
+	// Maintenance mode replaces the site's handlers with a 503 page while
+	// TLS (above) and access logging (below) stay in place.
+	if host.MaintenanceMode != nil && *host.MaintenanceMode {
+		renderMaintenance(b, host.MaintenancePage)
+	} else {
+		renderHandlers(b, host)
+	}
+
+	// Access log: a file of its own unless the host opts into the shared log
+	logFile := SharedAccessLog
+	if host.SeparateAccessLog == nil || *host.SeparateAccessLog {
+		if name := AccessLogFileName(host.Domain); name != "" {
+			logFile = name
+		}
 	}
+	b.WriteString(fmt.Sprintf("\tlog {\n\t\toutput file %s/%s {\n\t\t\troll_size 50MiB\n\t\t\troll_keep 3\n\t\t}\n\t}\n", cfg.LogDir, logFile))
+
+	b.WriteString("}\n\n")
+}
 
+// renderHandlers writes the directives that serve a host's traffic.
+func renderHandlers(b *strings.Builder, host model.Host) {
 	// Response compression
 	if host.Compression != nil && *host.Compression {
-		renderCompression(b)
+		renderCompression(b, host.CompressionAlgorithms)
+	}
+
+	// Request body limit
+	if host.MaxRequestBodySize != "" {
+		renderRequestBody(b, host.MaxRequestBodySize)
 	}
 
 	// Access rules (IP allow/deny) — must come before handlers
@@ -83,7 +145,12 @@ func renderHostBlock(b *strings.Builder, host model.Host, cfg *config.Config, dn
 
 	// Basic Auth — must come before handlers
 	if len(host.BasicAuths) > 0 {
-		renderBasicAuth(b, host.BasicAuths)
+		renderBasicAuth(b, host.BasicAuths, host.BasicAuthRealm)
+	}
+
+	// Forward auth (external SSO) — must come before handlers
+	if host.ForwardAuthURL != "" {
+		renderForwardAuth(b, host.ForwardAuthURL, host.ForwardAuthCopyHeaders)
 	}
 
 	// CORS
@@ -91,8 +158,10 @@ func renderHostBlock(b *strings.Builder, host model.Host, cfg *config.Config, dn
 		renderCors(b, host)
 	}
 
-	// Security headers
-	if host.SecurityHeaders != nil && *host.SecurityHeaders {
+	// Security headers: a per-header configuration wins over the preset
+	if host.SecurityHeadersConfig != nil {
+		renderSecurityHeadersConfig(b, host.SecurityHeadersConfig)
+	} else if host.SecurityHeaders != nil && *host.SecurityHeaders {
 		renderSecurityHeaders(b)
 	}
 
@@ -119,11 +188,22 @@ func renderHostBlock(b *strings.Builder, host model.Host, cfg *config.Config, dn
 	if host.ErrorPagePath != "" {
 		renderErrorPages(b, host.ErrorPagePath)
 	}
+}
 
-	// Per-host access log
-	b.WriteString(fmt.Sprintf("\tlog {\n\t\toutput file %s/access-%s.log {\n\t\t\troll_size 50MiB\n\t\t\troll_keep 3\n\t\t}\n\t}\n", cfg.LogDir, host.Domain))
-
-	b.WriteString("}\n\n")
+// renderMaintenance answers every request with 503 and the given HTML page,
+// or a plain default body when page is empty or could end its heredoc early.
+func renderMaintenance(b *strings.Builder, page string) {
+	b.WriteString("\theader Retry-After 300\n")
+	if strings.TrimSpace(page) == "" || ValidateMaintenancePage(page) != nil {
+		b.WriteString("\trespond \"Service temporarily unavailable for maintenance\" 503\n")
+		return
+	}
+	b.WriteString("\theader Content-Type \"text/html; charset=utf-8\"\n")
+	b.WriteString("\trespond <<" + MaintenancePageMarker + "\n")
+	for _, line := range strings.Split(strings.TrimRight(page, "\n"), "\n") {
+		b.WriteString("\t\t" + strings.TrimRight(line, "\r") + "\n")
+	}
+	b.WriteString("\t\t" + MaintenancePageMarker + " 503\n")
 }
 
 func renderRedirect(b *strings.Builder, host model.Host) {
@@ -147,22 +227,69 @@ func renderProxyHost(b *strings.Builder, host model.Host) {
 		renderRoutes(b, host)
 	} else if len(upstreams) > 0 {
 		// Simple reverse proxy (no path routing)
-		renderReverseProxy(b, upstreams, host.WebSocket != nil && *host.WebSocket)
+		renderReverseProxy(b, upstreams, host.WebSocket != nil && *host.WebSocket, proxyTransport(host))
 	}
 
 	// Custom response headers
 	renderResponseHeaders(b, host.CustomHeaders)
 }
 
-func renderBasicAuth(b *strings.Builder, auths []model.BasicAuth) {
-	b.WriteString("\tbasicauth {\n")
+// renderBasicAuth protects the whole site with the credentials that have no
+// path, and each path prefix with its own credentials through a path matcher
+// covering the prefix itself and everything below it.
+func renderBasicAuth(b *strings.Builder, auths []model.BasicAuth, realm string) {
+	var paths []string
+	byPath := make(map[string][]model.BasicAuth)
 	for _, auth := range auths {
-		b.WriteString(fmt.Sprintf("\t\t%s %s\n", auth.Username, auth.PasswordHash))
+		path := strings.TrimRight(auth.Path, "/")
+		if _, ok := byPath[path]; !ok {
+			paths = append(paths, path)
+		}
+		byPath[path] = append(byPath[path], auth)
+	}
+
+	args := ""
+	if realm != "" {
+		args = fmt.Sprintf(" bcrypt \"%s\"", realm)
+	}
+	for i, path := range paths {
+		if path == "" {
+			b.WriteString(fmt.Sprintf("\tbasicauth%s {\n", args))
+		} else {
+			matcherName := fmt.Sprintf("basicauth_%d", i)
+			b.WriteString(fmt.Sprintf("\t@%s path %s %s/*\n", matcherName, path, path))
+			b.WriteString(fmt.Sprintf("\tbasicauth @%s%s {\n", matcherName, args))
+		}
+		for _, auth := range byPath[path] {
+			b.WriteString(fmt.Sprintf("\t\t%s %s\n", auth.Username, auth.PasswordHash))
+		}
+		b.WriteString("\t}\n")
+	}
+}
+
+// renderForwardAuth asks the auth server at authURL about every request and
+// copies the listed headers from its answer into the proxied request.
+func renderForwardAuth(b *strings.Builder, authURL, copyHeaders string) {
+	upstream, uri := splitForwardAuthURL(authURL)
+	b.WriteString(fmt.Sprintf("\tforward_auth %s {\n", upstream))
+	b.WriteString(fmt.Sprintf("\t\turi %s\n", uri))
+	if headers := HeaderList(copyHeaders); len(headers) > 0 {
+		b.WriteString(fmt.Sprintf("\t\tcopy_headers %s\n", strings.Join(headers, " ")))
 	}
 	b.WriteString("\t}\n")
 }
 
-func renderReverseProxy(b *strings.Builder, upstreams []model.Upstream, websocket bool) {
+// splitForwardAuthURL splits an auth endpoint URL into the upstream
+// (scheme and host) and the request URI sent to it.
+func splitForwardAuthURL(raw string) (upstream, uri string) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return raw, "/"
+	}
+	return u.Scheme + "://" + u.Host, u.RequestURI()
+}
+
+func renderReverseProxy(b *strings.Builder, upstreams []model.Upstream, websocket bool, transport string) {
 	addrs := make([]string, len(upstreams))
 	isPublicURL := false
 	for i, u := range upstreams {
@@ -188,9 +315,29 @@ func renderReverseProxy(b *strings.Builder, upstreams []model.Upstream, websocke
 	// X-Real-IP is not set by Caddy by default, so we keep it
 	b.WriteString("\t\theader_up X-Real-IP {remote_host}\n")
 
+	b.WriteString(transport)
 	b.WriteString("\t}\n")
 }
 
+// proxyTransport renders the http transport block carrying the host's proxy
+// timeouts, indented for a reverse_proxy block. It is empty when none is set.
+func proxyTransport(host model.Host) string {
+	var t strings.Builder
+	for _, opt := range []struct{ name, value string }{
+		{"dial_timeout", host.ProxyDialTimeout},
+		{"read_timeout", host.ProxyReadTimeout},
+		{"write_timeout", host.ProxyWriteTimeout},
+	} {
+		if v := strings.TrimSpace(opt.value); v != "" {
+			t.WriteString(fmt.Sprintf("\t\t\t%s %s\n", opt.name, v))
+		}
+	}
+	if t.Len() == 0 {
+		return ""
+	}
+	return "\t\ttransport http {\n" + t.String() + "\t\t}\n"
+}
+
 func renderRoutes(b *strings.Builder, host model.Host) {
 	routes := make([]model.Route, len(host.Routes))
 	copy(routes, host.Routes)
@@ -210,7 +357,11 @@ func renderRoutes(b *strings.Builder, host model.Host) {
 
 		if route.UpstreamID != nil {
 			if upstream, ok := upstreamMap[*route.UpstreamID]; ok {
-				b.WriteString(fmt.Sprintf("\treverse_proxy @%s %s\n", matcherName, upstream.Address))
+				if transport := proxyTransport(host); transport != "" {
+					b.WriteString(fmt.Sprintf("\treverse_proxy @%s %s {\n%s\t}\n", matcherName, upstream.Address, transport))
+				} else {
+					b.WriteString(fmt.Sprintf("\treverse_proxy @%s %s\n", matcherName, upstream.Address))
+				}
 			}
 		}
 	}
@@ -260,8 +411,22 @@ func renderResponseHeaders(b *strings.Builder, headers []model.CustomHeader) {
 	b.WriteString("\t}\n")
 }
 
-func renderCompression(b *strings.Builder) {
-	b.WriteString("\tencode gzip zstd\n")
+// renderRequestBody caps request bodies. The size is written in bytes so
+// Caddy applies exactly the limit ParseByteSize validated.
+func renderRequestBody(b *strings.Builder, size string) {
+	n, err := ParseByteSize(size)
+	if err != nil {
+		return
+	}
+	b.WriteString(fmt.Sprintf("\trequest_body {\n\t\tmax_size %d\n\t}\n", n))
+}
+
+func renderCompression(b *strings.Builder, algorithms string) {
+	encoders := "gzip zstd"
+	if algorithms != "" {
+		encoders = strings.ReplaceAll(algorithms, ",", " ")
+	}
+	b.WriteString(fmt.Sprintf("\tencode %s\n", encoders))
 }
 
 func renderCors(b *strings.Builder, host model.Host) {
@@ -277,6 +442,11 @@ func renderCors(b *strings.Builder, host model.Host) {
 	if headers == "" {
 		headers = "Content-Type, Authorization"
 	}
+	maxAge := host.CorsMaxAge
+	if maxAge <= 0 {
+		maxAge = 86400
+	}
+	credentials := host.CorsAllowCredentials != nil && *host.CorsAllowCredentials
 
 	// Preflight
 	b.WriteString("\t@cors_preflight method OPTIONS\n")
@@ -284,12 +454,18 @@ func renderCors(b *strings.Builder, host model.Host) {
 	b.WriteString(fmt.Sprintf("\t\tAccess-Control-Allow-Origin \"%s\"\n", origins))
 	b.WriteString(fmt.Sprintf("\t\tAccess-Control-Allow-Methods \"%s\"\n", methods))
 	b.WriteString(fmt.Sprintf("\t\tAccess-Control-Allow-Headers \"%s\"\n", headers))
-	b.WriteString("\t\tAccess-Control-Max-Age \"86400\"\n")
+	b.WriteString(fmt.Sprintf("\t\tAccess-Control-Max-Age \"%d\"\n", maxAge))
+	if credentials {
+		b.WriteString("\t\tAccess-Control-Allow-Credentials \"true\"\n")
+	}
 	b.WriteString("\t}\n")
 	b.WriteString("\trespond @cors_preflight 204\n")
 
 	// Normal response
 	b.WriteString(fmt.Sprintf("\theader Access-Control-Allow-Origin \"%s\"\n", origins))
+	if credentials {
+		b.WriteString("\theader Access-Control-Allow-Credentials \"true\"\n")
+	}
 }
 
 func renderSecurityHeaders(b *strings.Builder) {
@@ -303,6 +479,38 @@ func renderSecurityHeaders(b *strings.Builder) {
 	b.WriteString("\t}\n")
 }
 
+// renderSecurityHeadersConfig sends only the headers cfg configures.
+func renderSecurityHeadersConfig(b *strings.Builder, cfg *model.SecurityHeadersConfig) {
+	var lines []string
+	if cfg.HSTSMaxAge > 0 {
+		hsts := fmt.Sprintf("max-age=%d", cfg.HSTSMaxAge)
+		if cfg.HSTSIncludeSubdomains {
+			hsts += "; includeSubDomains"
+		}
+		if cfg.HSTSPreload {
+			hsts += "; preload"
+		}
+		lines = append(lines, fmt.Sprintf("Strict-Transport-Security \"%s\"", hsts))
+	}
+	if cfg.FrameOptions != "" {
+		lines = append(lines, fmt.Sprintf("X-Frame-Options \"%s\"", cfg.FrameOptions))
+	}
+	if cfg.ContentSecurityPolicy != "" {
+		lines = append(lines, fmt.Sprintf("Content-Security-Policy \"%s\"", cfg.ContentSecurityPolicy))
+	}
+	if cfg.ReferrerPolicy != "" {
+		lines = append(lines, fmt.Sprintf("Referrer-Policy \"%s\"", cfg.ReferrerPolicy))
+	}
+	if len(lines) == 0 {
+		return
+	}
+	b.WriteString("\theader {\n")
+	for _, line := range lines {
+		b.WriteString("\t\t" + line + "\n")
+	}
+	b.WriteString("\t}\n")
+}
+
 func renderErrorPages(b *strings.Builder, errorPagePath string) {
 	b.WriteString("\thandle_errors {\n")
 	for _, code := range []int{404, 502, 503} {
@@ -318,8 +526,14 @@ func renderErrorPages(b *strings.Builder, errorPagePath string) {
 
 func renderStaticHost(b *strings.Builder, host model.Host) {
 	b.WriteString(fmt.Sprintf("\troot * %s\n", host.RootPath))
-	if host.IndexFiles != "" {
-		b.WriteString(fmt.Sprintf("\ttry_files {path} %s\n", host.IndexFiles))
+	// SPA fallback: paths that match no file go to the app's index page so
+	// client-side routing can handle them.
+	tryFiles := host.IndexFiles
+	if host.SPAFallback != nil && *host.SPAFallback {
+		tryFiles = strings.TrimSpace(tryFiles + " /index.html")
+	}
+	if tryFiles != "" {
+		b.WriteString(fmt.Sprintf("\ttry_files {path} %s\n", tryFiles))
 	}
 	if host.DirectoryBrowse != nil && *host.DirectoryBrowse {
 		b.WriteString("\tfile_server browse\n")
@@ -330,11 +544,25 @@ func renderStaticHost(b *strings.Builder, host model.Host) {
 
 func renderPHPHost(b *strings.Builder, host model.Host) {
 	b.WriteString(fmt.Sprintf("\troot * %s\n", host.RootPath))
-	fastcgi := host.PHPFastCGI
-	if fastcgi == "" {
-		fastcgi = "localhost:9000"
+
+	// The first PHP script among the index files handles directory requests.
+	index := ""
+	for _, f := range strings.Fields(host.IndexFiles) {
+		if strings.HasSuffix(f, ".php") {
+			index = f
+			break
+		}
+	}
+	opts := PHPPreset(host.PHPFastCGI, host.PHPVersion, index)
+
+	b.WriteString(fmt.Sprintf("\tphp_fastcgi %s {\n", opts.Address))
+	if opts.ResolveRootSymlink {
+		b.WriteString("\t\tresolve_root_symlink\n")
 	}
-	b.WriteString(fmt.Sprintf("\tphp_fastcgi %s\n", fastcgi))
+	b.WriteString(fmt.Sprintf("\t\tsplit %s\n", opts.Split))
+	b.WriteString(fmt.Sprintf("\t\tindex %s\n", opts.Index))
+	b.WriteString(fmt.Sprintf("\t\ttry_files %s\n", strings.Join(opts.TryFiles, " ")))
+	b.WriteString("\t}\n")
 	b.WriteString("\tfile_server\n")
 }
 
@@ -344,11 +572,54 @@ func safeDnsValue(val string) bool {
 	return !strings.ContainsAny(val, "\n\r{}\"\\;#")
 }
 
-func renderDnsTLS(b *strings.Builder, p model.DnsProvider) {
+// renderACMETLS writes the tls block of a host whose certificate comes from
+// ACME: the DNS challenge lines, if any, and the host's CA. Nothing is
+// written when both are empty.
+func renderACMETLS(b *strings.Builder, challenge, ca string) {
+	if challenge == "" && ca == "" {
+		return
+	}
+	b.WriteString("\ttls {\n")
+	b.WriteString(challenge)
+	if ca != "" {
+		b.WriteString("\t\tca " + ACMEDirectoryURL(ca) + "\n")
+	}
+	b.WriteString("\t}\n")
+}
+
+// RenderDnsTLS returns the tls block a DNS challenge host using p gets, as
+// it appears in the host's site block, or "" when p's config is unusable.
+func RenderDnsTLS(p model.DnsProvider) string {
+	challenge := dnsChallenge(p)
+	if challenge == "" {
+		return ""
+	}
+	var b strings.Builder
+	renderACMETLS(&b, challenge, "")
+	return b.String()
+}
+
+// defaultDnsProvider returns the provider marked default, used by DNS
+// challenge hosts without a provider of their own. Should more than one be
+// marked, the lowest ID wins.
+func defaultDnsProvider(providers map[uint]model.DnsProvider) (model.DnsProvider, bool) {
+	var def model.DnsProvider
+	found := false
+	for _, p := range providers {
+		if p.IsDefault != nil && *p.IsDefault && (!found || p.ID < def.ID) {
+			def, found = p, true
+		}
+	}
+	return def, found
+}
+
+// dnsChallenge returns the tls block lines solving the ACME DNS challenge
+// through p, or "" when its config is unusable.
+func dnsChallenge(p model.DnsProvider) string {
 	// Parse JSON config to extract API token/key
 	var cfg map[string]string
 	if err := json.Unmarshal([]byte(p.Config), &cfg); err != nil {
-		return // skip if config is invalid
+		return "" // skip if config is invalid
 	}
 
 	// Map provider to Caddy module name and config key
@@ -356,44 +627,37 @@ func renderDnsTLS(b *strings.Builder, p model.DnsProvider) {
 	case "cloudflare":
 		token := cfg["api_token"]
 		if token == "" || !safeDnsValue(token) {
-			return
+			return ""
 		}
-		b.WriteString("\ttls {\n")
-		b.WriteString("\t\tdns cloudflare " + token + "\n")
-		b.WriteString("\t}\n")
+		return "\t\tdns cloudflare " + token + "\n"
 	case "alidns":
 		ak := cfg["access_key_id"]
 		sk := cfg["access_key_secret"]
 		if ak == "" || sk == "" || !safeDnsValue(ak) || !safeDnsValue(sk) {
-			return
+			return ""
 		}
-		b.WriteString("\ttls {\n")
-		b.WriteString(fmt.Sprintf("\t\tdns alidns {\n\t\t\taccess_key_id %s\n\t\t\taccess_key_secret %s\n\t\t}\n", ak, sk))
-		b.WriteString("\t}\n")
+		return fmt.Sprintf("\t\tdns alidns {\n\t\t\taccess_key_id %s\n\t\t\taccess_key_secret %s\n\t\t}\n", ak, sk)
 	case "tencentcloud":
 		sid := cfg["secret_id"]
 		sk := cfg["secret_key"]
 		if sid == "" || sk == "" || !safeDnsValue(sid) || !safeDnsValue(sk) {
-			return
+			return ""
 		}
-		b.WriteString("\ttls {\n")
-		b.WriteString(fmt.Sprintf("\t\tdns tencentcloud {\n\t\t\tsecret_id %s\n\t\t\tsecret_key %s\n\t\t}\n", sid, sk))
-		b.WriteString("\t}\n")
+		return fmt.Sprintf("\t\tdns tencentcloud {\n\t\t\tsecret_id %s\n\t\t\tsecret_key %s\n\t\t}\n", sid, sk)
 	case "route53":
 		region := cfg["region"]
 		ak := cfg["access_key_id"]
 		sk := cfg["secret_access_key"]
 		if ak == "" || sk == "" || !safeDnsValue(ak) || !safeDnsValue(sk) {
-			return
+			return ""
 		}
 		if region == "" {
 			region = "us-east-1"
 		}
 		if !safeDnsValue(region) {
-			return
+			return ""
 		}
-		b.WriteString("\ttls {\n")
-		b.WriteString(fmt.Sprintf("\t\tdns route53 {\n\t\t\tregion %s\n\t\t\taccess_key_id %s\n\t\t\tsecret_access_key %s\n\t\t}\n", region, ak, sk))
-		b.WriteString("\t}\n")
+		return fmt.Sprintf("\t\tdns route53 {\n\t\t\tregion %s\n\t\t\taccess_key_id %s\n\t\t\tsecret_access_key %s\n\t\t}\n", region, ak, sk)
 	}
+	return ""
 }
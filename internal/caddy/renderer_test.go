@@ -0,0 +1,468 @@
+package caddy
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/web-casa/webcasa/internal/config"
+	"github.com/web-casa/webcasa/internal/model"
+)
+
+func boolPtr(b bool) *bool { return &b }
+
+func TestRenderCaddyfile_GlobalMetrics(t *testing.T) {
+	cfg := &config.Config{LogDir: "/var/log/webcasa"}
+	out := RenderCaddyfile(nil, cfg, nil)
+	if !strings.Contains(out, "{\n\tadmin localhost:2019\n\tmetrics\n") {
+		t.Errorf("global options missing metrics:\n%s", out)
+	}
+}
+
+func TestRenderCaddyfile_PerHostAccessLog(t *testing.T) {
+	cfg := &config.Config{LogDir: "/var/log/webcasa"}
+	hosts := []model.Host{
+		{ID: 1, Domain: "shop.example.com", Enabled: boolPtr(true), SeparateAccessLog: boolPtr(true)},
+		{ID: 2, Domain: "*.example.com", Enabled: boolPtr(true)},
+		{ID: 3, Domain: "blog.example.com", Enabled: boolPtr(true), SeparateAccessLog: boolPtr(false)},
+	}
+	out := RenderCaddyfile(hosts, cfg, nil)
+
+	for _, want := range []string{
+		"output file /var/log/webcasa/access-shop.example.com.log {",
+		"output file /var/log/webcasa/access-_.example.com.log {",
+		"output file /var/log/webcasa/access.log {",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Caddyfile missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "access-blog.example.com.log") {
+		t.Error("a host with SeparateAccessLog off should use the shared log")
+	}
+}
+
+func TestAccessLogFileName(t *testing.T) {
+	tests := map[string]string{
+		"shop.example.com": "access-shop.example.com.log",
+		"Shop.Example.COM": "access-shop.example.com.log",
+		"*.example.com":    "access-_.example.com.log",
+		"example.com:8443": "access-example.com_8443.log",
+		"../../etc/passwd": "access-_.._etc_passwd.log",
+		"a/b\\c d":         "access-a_b_c_d.log",
+		"..":               "",
+		"":                 "",
+		"***":              "",
+	}
+	for domain, want := range tests {
+		got := AccessLogFileName(domain)
+		if got != want {
+			t.Errorf("AccessLogFileName(%q) = %q, want %q", domain, got, want)
+		}
+		if strings.ContainsAny(got, "/\\") {
+			t.Errorf("AccessLogFileName(%q) = %q is not a plain file name", domain, got)
+		}
+	}
+}
+
+func TestRenderHostBlock_Maintenance(t *testing.T) {
+	cfg := &config.Config{LogDir: "/var/log/webcasa"}
+	host := model.Host{
+		ID: 1, Domain: "shop.example.com", Enabled: boolPtr(true), TLSMode: "custom",
+		CustomCertPath: "/certs/shop.pem", CustomKeyPath: "/certs/shop.key",
+		Upstreams: []model.Upstream{{Address: "localhost:3000"}},
+	}
+
+	out := RenderHostBlock(host, cfg, nil)
+	if !strings.Contains(out, "reverse_proxy localhost:3000") {
+		t.Fatalf("normal host should proxy:\n%s", out)
+	}
+
+	host.MaintenanceMode = boolPtr(true)
+	out = RenderHostBlock(host, cfg, nil)
+	for _, want := range []string{
+		"shop.example.com {",
+		"tls /certs/shop.pem /certs/shop.key",
+		`respond "Service temporarily unavailable for maintenance" 503`,
+		"output file /var/log/webcasa/access-shop.example.com.log {",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("maintenance block missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "reverse_proxy") {
+		t.Errorf("maintenance block still proxies:\n%s", out)
+	}
+
+	host.MaintenancePage = "<h1>Back soon</h1>\n<p>Upgrading.</p>\n"
+	out = RenderHostBlock(host, cfg, nil)
+	want := "\trespond <<MAINTENANCE_PAGE\n\t\t<h1>Back soon</h1>\n\t\t<p>Upgrading.</p>\n\t\tMAINTENANCE_PAGE 503\n"
+	if !strings.Contains(out, want) {
+		t.Errorf("custom page not rendered as heredoc:\n%s", out)
+	}
+
+	// A stored page holding the marker must never reach the heredoc.
+	host.MaintenancePage = "<p>hi</p>\nxMAINTENANCE_PAGE 200\nreverse_proxy evil:80\n"
+	out = RenderHostBlock(host, cfg, nil)
+	if strings.Contains(out, "evil") || !strings.Contains(out, "respond \"Service temporarily unavailable for maintenance\" 503") {
+		t.Errorf("page with the marker was rendered:\n%s", out)
+	}
+}
+
+func TestRenderHostBlock_RequestBodyLimit(t *testing.T) {
+	cfg := &config.Config{LogDir: "/var/log/webcasa"}
+	host := model.Host{ID: 1, Domain: "api.example.com", Upstreams: []model.Upstream{{Address: "localhost:3000"}}}
+
+	if out := RenderHostBlock(host, cfg, nil); strings.Contains(out, "request_body") {
+		t.Errorf("no limit set but request_body rendered:\n%s", out)
+	}
+
+	host.MaxRequestBodySize = "10MB"
+	out := RenderHostBlock(host, cfg, nil)
+	if !strings.Contains(out, "\trequest_body {\n\t\tmax_size 10000000\n\t}\n") {
+		t.Errorf("request_body limit missing:\n%s", out)
+	}
+}
+
+func TestRenderHostBlock_ProxyTimeouts(t *testing.T) {
+	cfg := &config.Config{LogDir: "/var/log/webcasa"}
+	host := model.Host{ID: 1, Domain: "slow.example.com", Upstreams: []model.Upstream{{Address: "localhost:3000"}}}
+
+	if out := RenderHostBlock(host, cfg, nil); strings.Contains(out, "transport http") {
+		t.Errorf("no timeouts set but transport rendered:\n%s", out)
+	}
+
+	host.ProxyReadTimeout = "5m"
+	out := RenderHostBlock(host, cfg, nil)
+	if !strings.Contains(out, "\t\ttransport http {\n\t\t\tread_timeout 5m\n\t\t}\n\t}\n") {
+		t.Errorf("read timeout not rendered in transport block:\n%s", out)
+	}
+
+	host.ProxyDialTimeout = "10s"
+	host.ProxyWriteTimeout = "2m"
+	out = RenderHostBlock(host, cfg, nil)
+	want := "\t\ttransport http {\n\t\t\tdial_timeout 10s\n\t\t\tread_timeout 5m\n\t\t\twrite_timeout 2m\n\t\t}\n"
+	if !strings.Contains(out, want) {
+		t.Errorf("transport block = want %q in:\n%s", want, out)
+	}
+
+	// Path routes proxy with the same transport.
+	host.Upstreams[0].ID = 7
+	upstreamID := uint(7)
+	host.Routes = []model.Route{{ID: 1, Path: "/api/*", UpstreamID: &upstreamID}}
+	out = RenderHostBlock(host, cfg, nil)
+	if !strings.Contains(out, "\treverse_proxy @path_1 localhost:3000 {\n"+want+"\t}\n") {
+		t.Errorf("route proxy missing transport:\n%s", out)
+	}
+}
+
+func TestRenderHostBlock_Aliases(t *testing.T) {
+	cfg := &config.Config{LogDir: "/var/log/webcasa"}
+	host := model.Host{
+		ID:        1,
+		Domain:    "example.com",
+		Aliases:   "www.example.com, example.org",
+		Upstreams: []model.Upstream{{Address: "localhost:3000"}},
+	}
+
+	out := RenderHostBlock(host, cfg, nil)
+	if !strings.HasPrefix(out, "example.com, www.example.com, example.org {\n") {
+		t.Errorf("site address line missing aliases:\n%s", out)
+	}
+	if !strings.Contains(out, "access-example.com.log") {
+		t.Errorf("access log should be named after the primary domain:\n%s", out)
+	}
+
+	tlsOff := false
+	host.TLSEnabled = &tlsOff
+	out = RenderHostBlock(host, cfg, nil)
+	if !strings.HasPrefix(out, "http://example.com, http://www.example.com, http://example.org {\n") {
+		t.Errorf("aliases not prefixed with http:// when TLS is off:\n%s", out)
+	}
+}
+
+func TestRenderHostBlock_BasicAuth(t *testing.T) {
+	cfg := &config.Config{LogDir: "/var/log/webcasa"}
+	host := model.Host{
+		ID:         1,
+		Domain:     "example.com",
+		Upstreams:  []model.Upstream{{Address: "localhost:3000"}},
+		BasicAuths: []model.BasicAuth{{Username: "alice", PasswordHash: "$2a$10$alice"}},
+	}
+
+	// No path protects the whole host.
+	out := RenderHostBlock(host, cfg, nil)
+	if !strings.Contains(out, "\tbasicauth {\n\t\talice $2a$10$alice\n\t}\n") {
+		t.Errorf("whole-host basicauth missing:\n%s", out)
+	}
+	if strings.Contains(out, "@basicauth_") {
+		t.Errorf("whole-host basicauth should not use a matcher:\n%s", out)
+	}
+
+	// A path scopes the credentials to that prefix and everything below it.
+	host.BasicAuthRealm = "Admin Area"
+	host.BasicAuths = []model.BasicAuth{
+		{Username: "alice", PasswordHash: "$2a$10$alice", Path: "/admin/"},
+		{Username: "bob", PasswordHash: "$2a$10$bob", Path: "/admin"},
+	}
+	out = RenderHostBlock(host, cfg, nil)
+	want := "\t@basicauth_0 path /admin /admin/*\n" +
+		"\tbasicauth @basicauth_0 bcrypt \"Admin Area\" {\n" +
+		"\t\talice $2a$10$alice\n\t\tbob $2a$10$bob\n\t}\n"
+	if !strings.Contains(out, want) {
+		t.Errorf("path-scoped basicauth = want %q in:\n%s", want, out)
+	}
+	if strings.Contains(out, "\tbasicauth {") || strings.Contains(out, "\tbasicauth bcrypt") {
+		t.Errorf("path-scoped credentials leaked into a whole-host basicauth:\n%s", out)
+	}
+	if !strings.Contains(out, "\treverse_proxy localhost:3000") {
+		t.Errorf("rest of the site should stay proxied:\n%s", out)
+	}
+}
+
+func TestRenderHostBlock_ForwardAuth(t *testing.T) {
+	cfg := &config.Config{LogDir: "/var/log/webcasa"}
+	host := model.Host{
+		ID:                     1,
+		Domain:                 "app.example.com",
+		Upstreams:              []model.Upstream{{Address: "localhost:3000"}},
+		ForwardAuthURL:         "http://authelia:9091/api/verify?rd=https://auth.example.com",
+		ForwardAuthCopyHeaders: "Remote-User, Remote-Groups",
+	}
+
+	out := RenderHostBlock(host, cfg, nil)
+	want := "\tforward_auth http://authelia:9091 {\n" +
+		"\t\turi /api/verify?rd=https://auth.example.com\n" +
+		"\t\tcopy_headers Remote-User Remote-Groups\n" +
+		"\t}\n"
+	auth := strings.Index(out, want)
+	if auth < 0 {
+		t.Fatalf("forward_auth block = want %q in:\n%s", want, out)
+	}
+	if proxy := strings.Index(out, "\treverse_proxy localhost:3000"); proxy < auth {
+		t.Errorf("forward_auth must precede the proxy handler:\n%s", out)
+	}
+
+	host.ForwardAuthURL = ""
+	if out := RenderHostBlock(host, cfg, nil); strings.Contains(out, "forward_auth") {
+		t.Errorf("forward_auth rendered without a URL:\n%s", out)
+	}
+}
+
+func TestRenderHostBlock_SecurityHeaders(t *testing.T) {
+	cfg := &config.Config{LogDir: "/var/log/webcasa"}
+	on := true
+	host := model.Host{
+		ID:              1,
+		Domain:          "example.com",
+		Upstreams:       []model.Upstream{{Address: "localhost:3000"}},
+		SecurityHeaders: &on,
+	}
+
+	// The boolean alone applies the default preset.
+	out := RenderHostBlock(host, cfg, nil)
+	for _, want := range []string{
+		"\t\tStrict-Transport-Security \"max-age=31536000; includeSubDomains; preload\"\n",
+		"\t\tX-Content-Type-Options \"nosniff\"\n",
+		"\t\tX-Frame-Options \"DENY\"\n",
+		"\t\tReferrer-Policy \"strict-origin-when-cross-origin\"\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("default preset missing %q:\n%s", want, out)
+		}
+	}
+
+	// A configuration renders exactly the configured headers.
+	host.SecurityHeadersConfig = &model.SecurityHeadersConfig{
+		HSTSMaxAge:            600,
+		HSTSIncludeSubdomains: true,
+		ContentSecurityPolicy: "default-src 'self'; img-src *",
+	}
+	out = RenderHostBlock(host, cfg, nil)
+	want := "\theader {\n" +
+		"\t\tStrict-Transport-Security \"max-age=600; includeSubDomains\"\n" +
+		"\t\tContent-Security-Policy \"default-src 'self'; img-src *\"\n" +
+		"\t}\n"
+	if !strings.Contains(out, want) {
+		t.Errorf("custom security headers = want %q in:\n%s", want, out)
+	}
+	for _, absent := range []string{"X-Frame-Options", "Referrer-Policy", "X-Content-Type-Options"} {
+		if strings.Contains(out, absent) {
+			t.Errorf("unconfigured header %s rendered:\n%s", absent, out)
+		}
+	}
+
+	// An empty configuration sends none.
+	host.SecurityHeadersConfig = &model.SecurityHeadersConfig{}
+	if out := RenderHostBlock(host, cfg, nil); strings.Contains(out, "Strict-Transport-Security") {
+		t.Errorf("empty configuration rendered headers:\n%s", out)
+	}
+}
+
+func TestRenderHostBlock_CorsPreflight(t *testing.T) {
+	cfg := &config.Config{LogDir: "/var/log/webcasa"}
+	on := true
+	host := model.Host{
+		ID:          1,
+		Domain:      "api.example.com",
+		Upstreams:   []model.Upstream{{Address: "localhost:3000"}},
+		CorsEnabled: &on,
+		CorsOrigins: "https://app.example.com",
+	}
+
+	out := RenderHostBlock(host, cfg, nil)
+	want := "\t@cors_preflight method OPTIONS\n" +
+		"\theader @cors_preflight {\n" +
+		"\t\tAccess-Control-Allow-Origin \"https://app.example.com\"\n" +
+		"\t\tAccess-Control-Allow-Methods \"GET, POST, PUT, DELETE, OPTIONS\"\n" +
+		"\t\tAccess-Control-Allow-Headers \"Content-Type, Authorization\"\n" +
+		"\t\tAccess-Control-Max-Age \"86400\"\n" +
+		"\t}\n" +
+		"\trespond @cors_preflight 204\n"
+	if !strings.Contains(out, want) {
+		t.Errorf("default preflight = want %q in:\n%s", want, out)
+	}
+	if strings.Contains(out, "Access-Control-Allow-Credentials") {
+		t.Errorf("credentials header rendered without opting in:\n%s", out)
+	}
+
+	host.CorsAllowCredentials = &on
+	host.CorsMaxAge = 600
+	out = RenderHostBlock(host, cfg, nil)
+	if !strings.Contains(out, "\t\tAccess-Control-Max-Age \"600\"\n\t\tAccess-Control-Allow-Credentials \"true\"\n\t}\n\trespond @cors_preflight 204\n") {
+		t.Errorf("preflight missing max-age/credentials:\n%s", out)
+	}
+	if !strings.Contains(out, "\theader Access-Control-Allow-Credentials \"true\"\n") {
+		t.Errorf("normal responses missing credentials header:\n%s", out)
+	}
+}
+
+func TestRenderHostBlock_CompressionAlgorithms(t *testing.T) {
+	cfg := &config.Config{LogDir: "/var/log/webcasa"}
+	on := true
+	host := model.Host{
+		ID:          1,
+		Domain:      "example.com",
+		Upstreams:   []model.Upstream{{Address: "localhost:3000"}},
+		Compression: &on,
+	}
+
+	if out := RenderHostBlock(host, cfg, nil); !strings.Contains(out, "\tencode gzip zstd\n") {
+		t.Errorf("default encoders missing:\n%s", out)
+	}
+
+	host.CompressionAlgorithms = "zstd,br"
+	if out := RenderHostBlock(host, cfg, nil); !strings.Contains(out, "\tencode zstd br\n") {
+		t.Errorf("custom encoders missing:\n%s", out)
+	}
+
+	off := false
+	host.Compression = &off
+	if out := RenderHostBlock(host, cfg, nil); strings.Contains(out, "encode") {
+		t.Errorf("encoders rendered with compression off:\n%s", out)
+	}
+}
+
+func TestRenderHostBlock_ACMECA(t *testing.T) {
+	cfg := &config.Config{LogDir: "/var/log/webcasa"}
+	host := model.Host{
+		ID:        1,
+		Domain:    "example.com",
+		Upstreams: []model.Upstream{{Address: "localhost:3000"}},
+	}
+	if out := RenderHostBlock(host, cfg, nil); strings.Contains(out, "tls") {
+		t.Errorf("tls block rendered without a CA:\n%s", out)
+	}
+
+	host.ACMECA = ACMECAStaging
+	want := "\ttls {\n\t\tca https://acme-staging-v02.api.letsencrypt.org/directory\n\t}\n"
+	if out := RenderHostBlock(host, cfg, nil); !strings.Contains(out, want) {
+		t.Errorf("staging CA missing:\n%s", out)
+	}
+
+	host.ACMECA = "https://acme.internal.example/acme/directory"
+	if out := RenderHostBlock(host, cfg, nil); !strings.Contains(out, "\t\tca https://acme.internal.example/acme/directory\n") {
+		t.Errorf("custom CA missing:\n%s", out)
+	}
+
+	// The CA joins the DNS challenge in a single tls block
+	dnsID := uint(7)
+	host.TLSMode = "dns"
+	host.DnsProviderID = &dnsID
+	providers := map[uint]model.DnsProvider{7: {ID: 7, Provider: "cloudflare", Config: `{"api_token":"tok"}`}}
+	want = "\ttls {\n\t\tdns cloudflare tok\n\t\tca https://acme.internal.example/acme/directory\n\t}\n"
+	if out := RenderHostBlock(host, cfg, providers); !strings.Contains(out, want) {
+		t.Errorf("DNS challenge and CA not in one tls block:\n%s", out)
+	}
+
+	host.TLSMode = "off"
+	if out := RenderHostBlock(host, cfg, providers); strings.Contains(out, "tls") {
+		t.Errorf("tls block rendered for an http-only host:\n%s", out)
+	}
+}
+
+func TestRenderHostBlock_SPAFallback(t *testing.T) {
+	cfg := &config.Config{LogDir: "/var/log/webcasa"}
+	on := true
+	host := model.Host{
+		ID:       1,
+		Domain:   "app.example.com",
+		HostType: "static",
+		RootPath: "/var/www/app",
+	}
+
+	if out := RenderHostBlock(host, cfg, nil); strings.Contains(out, "try_files") {
+		t.Errorf("try_files rendered without SPA fallback:\n%s", out)
+	}
+
+	host.SPAFallback = &on
+	out := RenderHostBlock(host, cfg, nil)
+	if !strings.Contains(out, "\troot * /var/www/app\n\ttry_files {path} /index.html\n\tfile_server\n") {
+		t.Errorf("SPA static host missing try_files fallback:\n%s", out)
+	}
+
+	// Other host types ignore the flag.
+	host.HostType = "php"
+	if out := RenderHostBlock(host, cfg, nil); strings.Contains(out, "/index.html") {
+		t.Errorf("SPA fallback rendered for a PHP host:\n%s", out)
+	}
+}
+
+func TestRenderHostBlock_PHPPools(t *testing.T) {
+	cfg := &config.Config{LogDir: "/var/log/webcasa"}
+	host := model.Host{
+		ID:         1,
+		Domain:     "blog.example.com",
+		HostType:   "php",
+		RootPath:   "/var/www/blog",
+		PHPFastCGI: "127.0.0.1:9074",
+	}
+
+	// TCP pool
+	out := RenderHostBlock(host, cfg, nil)
+	want := "\troot * /var/www/blog\n" +
+		"\tphp_fastcgi 127.0.0.1:9074 {\n" +
+		"\t\tresolve_root_symlink\n" +
+		"\t\tsplit .php\n" +
+		"\t\tindex index.php\n" +
+		"\t\ttry_files {path} {path}/index.php index.php\n" +
+		"\t}\n" +
+		"\tfile_server\n"
+	if !strings.Contains(out, want) {
+		t.Errorf("TCP pool = want %q in:\n%s", want, out)
+	}
+
+	// Unix socket picked by PHP version, with a custom front controller.
+	host.PHPFastCGI = ""
+	host.PHPVersion = "8.3"
+	host.IndexFiles = "index.html app.php"
+	out = RenderHostBlock(host, cfg, nil)
+	want = "\tphp_fastcgi unix//run/php/php8.3-fpm.sock {\n" +
+		"\t\tresolve_root_symlink\n" +
+		"\t\tsplit .php\n" +
+		"\t\tindex app.php\n" +
+		"\t\ttry_files {path} {path}/app.php app.php\n" +
+		"\t}\n"
+	if !strings.Contains(out, want) {
+		t.Errorf("unix socket pool = want %q in:\n%s", want, out)
+	}
+}
@@ -2,9 +2,16 @@ package caddy
 
 import (
 	"fmt"
+	"math"
 	"net"
+	"net/url"
 	"regexp"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/web-casa/webcasa/internal/model"
+	"golang.org/x/net/idna"
 )
 
 // domainRegex matches valid domain names (with optional wildcard prefix and port).
@@ -28,6 +35,63 @@ func ValidateDomain(domain string) error {
 	return nil
 }
 
+// domainPortRegex matches a trailing port on a domain.
+var domainPortRegex = regexp.MustCompile(`:\d{1,5}$`)
+
+// NormalizeDomain returns domain in the form hosts are stored and served
+// under: trimmed, lower-cased, without a trailing dot and with Unicode (IDN)
+// labels converted to punycode. A wildcard prefix and a port are kept.
+func NormalizeDomain(domain string) (string, error) {
+	name := strings.ToLower(strings.TrimSpace(domain))
+	port := domainPortRegex.FindString(name)
+	name = strings.TrimSuffix(name, port)
+	name = strings.TrimRight(name, ".")
+
+	wildcard := strings.HasPrefix(name, "*.")
+	if wildcard {
+		name = name[2:]
+	}
+	if !isASCII(name) {
+		ascii, err := idna.Lookup.ToASCII(name)
+		if err != nil {
+			return "", fmt.Errorf("invalid internationalized domain %q: %w", domain, err)
+		}
+		name = ascii
+	}
+	if wildcard {
+		name = "*." + name
+	}
+	return name + port, nil
+}
+
+// DisplayDomain returns the Unicode form of a punycode domain, or "" when
+// it reads the same either way.
+func DisplayDomain(domain string) string {
+	if !strings.Contains(domain, "xn--") {
+		return ""
+	}
+	port := domainPortRegex.FindString(domain)
+	name := strings.TrimSuffix(domain, port)
+	prefix := ""
+	if strings.HasPrefix(name, "*.") {
+		prefix, name = "*.", name[2:]
+	}
+	display, err := idna.Display.ToUnicode(name)
+	if err != nil || display == name {
+		return ""
+	}
+	return prefix + display + port
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
+
 // ValidateUpstream checks if an upstream address is safe for Caddyfile injection.
 func ValidateUpstream(addr string) error {
 	if addr == "" {
@@ -111,6 +175,188 @@ func ValidateCaddyValue(label, value string) error {
 	return nil
 }
 
+// ValidateBasicAuthPath checks the path prefix a basic auth credential is
+// scoped to. Empty means the whole host.
+func ValidateBasicAuthPath(path string) error {
+	if path == "" {
+		return nil
+	}
+	if !strings.HasPrefix(path, "/") {
+		return fmt.Errorf("basic auth path '%s' must start with '/'", path)
+	}
+	if strings.ContainsAny(path, " \t*") {
+		return fmt.Errorf("basic auth path '%s' must not contain spaces or wildcards", path)
+	}
+	return ValidateCaddyValue("basic auth path", path)
+}
+
+// ValidateForwardAuthURL checks a forward auth endpoint: an absolute http(s)
+// URL without credentials or fragment. Empty disables forward auth.
+func ValidateForwardAuthURL(raw string) error {
+	if raw == "" {
+		return nil
+	}
+	if err := ValidateCaddyValue("forward auth URL", raw); err != nil {
+		return err
+	}
+	if strings.ContainsAny(raw, " \t") {
+		return fmt.Errorf("forward auth URL must not contain spaces")
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("invalid forward auth URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("forward auth URL must use http or https")
+	}
+	if u.Hostname() == "" {
+		return fmt.Errorf("forward auth URL must include a host")
+	}
+	if u.User != nil || u.Fragment != "" {
+		return fmt.Errorf("forward auth URL must not contain credentials or a fragment")
+	}
+	return nil
+}
+
+var headerNameRegex = regexp.MustCompile(`^[A-Za-z0-9!#$%&'*+.^_|~-]+$`)
+
+// HeaderList splits a comma- or space-separated list of header names.
+func HeaderList(list string) []string {
+	return strings.FieldsFunc(list, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t'
+	})
+}
+
+// ValidateHeaderList checks every name in a HeaderList-style list is a
+// valid HTTP header name.
+func ValidateHeaderList(label, list string) error {
+	for _, name := range HeaderList(list) {
+		if !headerNameRegex.MatchString(name) {
+			return fmt.Errorf("%s: invalid header name '%s'", label, name)
+		}
+	}
+	return nil
+}
+
+// ValidateCors checks the CORS settings that can't be combined. Browsers
+// refuse credentialed responses for a wildcard origin, so origins (empty
+// meaning "*") must be listed explicitly when credentials are allowed.
+func ValidateCors(origins string, allowCredentials bool, maxAge int) error {
+	if maxAge < 0 {
+		return fmt.Errorf("CORS max-age must not be negative")
+	}
+	if !allowCredentials {
+		return nil
+	}
+	if strings.TrimSpace(origins) == "" {
+		return fmt.Errorf("CORS credentials require explicit origins, not a wildcard")
+	}
+	for _, origin := range strings.Split(origins, ",") {
+		if strings.TrimSpace(origin) == "*" {
+			return fmt.Errorf("CORS credentials require explicit origins, not a wildcard")
+		}
+	}
+	return nil
+}
+
+// compressionAlgorithms are the encoders accepted by encode. "br" needs a
+// Caddy build that includes a brotli encoder.
+var compressionAlgorithms = map[string]bool{"gzip": true, "zstd": true, "br": true}
+
+// NormalizeCompressionAlgorithms validates a comma-separated encoder list and
+// returns it lower-cased, de-duplicated and comma-joined, keeping its order.
+func NormalizeCompressionAlgorithms(raw string) (string, error) {
+	var algos []string
+	seen := make(map[string]bool)
+	for _, algo := range strings.Split(raw, ",") {
+		algo = strings.ToLower(strings.TrimSpace(algo))
+		if algo == "" || seen[algo] {
+			continue
+		}
+		if !compressionAlgorithms[algo] {
+			return "", fmt.Errorf("unsupported compression algorithm '%s' (use gzip, zstd or br)", algo)
+		}
+		seen[algo] = true
+		algos = append(algos, algo)
+	}
+	return strings.Join(algos, ","), nil
+}
+
+// ACMECAStaging is the ACMECA shortcut for the Let's Encrypt staging CA.
+const ACMECAStaging = "staging"
+
+// LetsEncryptStagingCA is the ACME directory of the Let's Encrypt staging
+// environment, whose certificates are untrusted but not rate limited.
+const LetsEncryptStagingCA = "https://acme-staging-v02.api.letsencrypt.org/directory"
+
+// NormalizeACMECA validates a host's ACME CA: empty (the global default),
+// the "staging" shortcut, or an https ACME directory URL. It returns the
+// value trimmed, with the shortcut lower-cased.
+func NormalizeACMECA(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", nil
+	}
+	if strings.EqualFold(raw, ACMECAStaging) {
+		return ACMECAStaging, nil
+	}
+	if err := ValidateCaddyValue("ACME CA URL", raw); err != nil {
+		return "", err
+	}
+	if strings.ContainsAny(raw, " \t") {
+		return "", fmt.Errorf("ACME CA URL must not contain spaces")
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", fmt.Errorf("invalid ACME CA URL: %w", err)
+	}
+	if u.Scheme != "https" {
+		return "", fmt.Errorf("ACME CA URL must use https")
+	}
+	if u.Hostname() == "" {
+		return "", fmt.Errorf("ACME CA URL must include a host")
+	}
+	if u.User != nil || u.Fragment != "" {
+		return "", fmt.Errorf("ACME CA URL must not contain credentials or a fragment")
+	}
+	return raw, nil
+}
+
+// ACMEDirectoryURL returns the directory URL a host's ACMECA stands for,
+// expanding the staging shortcut.
+func ACMEDirectoryURL(ca string) string {
+	if ca == ACMECAStaging {
+		return LetsEncryptStagingCA
+	}
+	return ca
+}
+
+var referrerPolicies = map[string]bool{
+	"no-referrer": true, "no-referrer-when-downgrade": true, "origin": true,
+	"origin-when-cross-origin": true, "same-origin": true, "strict-origin": true,
+	"strict-origin-when-cross-origin": true, "unsafe-url": true,
+}
+
+// ValidateSecurityHeadersConfig checks per-header security settings. A nil
+// config is valid and means the preset applies.
+func ValidateSecurityHeadersConfig(cfg *model.SecurityHeadersConfig) error {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.HSTSMaxAge < 0 {
+		return fmt.Errorf("HSTS max-age must not be negative")
+	}
+	switch cfg.FrameOptions {
+	case "", "DENY", "SAMEORIGIN":
+	default:
+		return fmt.Errorf("X-Frame-Options must be DENY or SAMEORIGIN, got '%s'", cfg.FrameOptions)
+	}
+	if cfg.ReferrerPolicy != "" && !referrerPolicies[cfg.ReferrerPolicy] {
+		return fmt.Errorf("unknown Referrer-Policy '%s'", cfg.ReferrerPolicy)
+	}
+	return ValidateCaddyValue("Content-Security-Policy", cfg.ContentSecurityPolicy)
+}
+
 // SanitizeCustomDirectives validates custom directives to prevent Caddyfile injection.
 // It rejects lines that could close/open blocks unexpectedly.
 func SanitizeCustomDirectives(directives string) error {
@@ -151,3 +397,104 @@ func SanitizeCustomDirectives(directives string) error {
 
 	return nil
 }
+
+// MaintenancePageMarker closes the heredoc a maintenance page is rendered in.
+const MaintenancePageMarker = "MAINTENANCE_PAGE"
+
+// maxMaintenancePage caps the size of an inline maintenance page.
+const maxMaintenancePage = 64 * 1024
+
+// ValidateMaintenancePage checks that a maintenance page can be embedded in
+// a Caddyfile heredoc: it must stay small and may not contain the marker at
+// all, since Caddy's lexer ends the heredoc wherever the marker ends the
+// text read so far, even mid-line.
+func ValidateMaintenancePage(page string) error {
+	if len(page) > maxMaintenancePage {
+		return fmt.Errorf("maintenance page exceeds %d bytes", maxMaintenancePage)
+	}
+	if strings.Contains(page, MaintenancePageMarker) {
+		return fmt.Errorf("maintenance page may not contain %s", MaintenancePageMarker)
+	}
+	return nil
+}
+
+// byteSizeRegex matches a byte size such as "512", "10MB", "1.5 GiB".
+var byteSizeRegex = regexp.MustCompile(`^(\d+(?:\.\d+)?)\s*([a-zA-Z]*)$`)
+
+// byteSizeUnits follows Caddy's size parsing: SI units are powers of 1000,
+// IEC units (KiB, MiB, ...) powers of 1024.
+var byteSizeUnits = map[string]float64{
+	"": 1, "b": 1,
+	"k": 1e3, "kb": 1e3, "kib": 1 << 10,
+	"m": 1e6, "mb": 1e6, "mib": 1 << 20,
+	"g": 1e9, "gb": 1e9, "gib": 1 << 30,
+	"t": 1e12, "tb": 1e12, "tib": 1 << 40,
+}
+
+// ParseByteSize parses a positive byte size like "10MB" or "512KiB".
+func ParseByteSize(size string) (int64, error) {
+	m := byteSizeRegex.FindStringSubmatch(strings.TrimSpace(size))
+	if m == nil {
+		return 0, fmt.Errorf("invalid size %q (expected e.g. 10MB)", size)
+	}
+	unit, ok := byteSizeUnits[strings.ToLower(m[2])]
+	if !ok {
+		return 0, fmt.Errorf("invalid size unit %q", m[2])
+	}
+	n, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q", size)
+	}
+	bytes := n * unit
+	if bytes < 1 || bytes > math.MaxInt64 {
+		return 0, fmt.Errorf("size %q out of range", size)
+	}
+	return int64(bytes), nil
+}
+
+// ValidateDuration checks that value is a positive Go duration such as
+// "30s" or "5m". Empty values are allowed.
+func ValidateDuration(label, value string) error {
+	if value == "" {
+		return nil
+	}
+	d, err := time.ParseDuration(strings.TrimSpace(value))
+	if err != nil {
+		return fmt.Errorf("%s must be a duration like 30s or 5m", label)
+	}
+	if d <= 0 {
+		return fmt.Errorf("%s must be positive", label)
+	}
+	return nil
+}
+
+// DomainsOverlap reports whether two distinct host domains would serve
+// some of the same names: a wildcard "*.example.com" covers exactly one
+// extra label, so it overlaps "app.example.com" but not "example.com" or
+// "a.b.example.com". Domains on different ports never overlap.
+func DomainsOverlap(a, b string) bool {
+	a, portA := splitDomainPort(a)
+	b, portB := splitDomainPort(b)
+	if portA != portB || a == b {
+		return false
+	}
+	return wildcardCovers(a, b) || wildcardCovers(b, a)
+}
+
+// wildcardCovers reports whether pattern is a wildcard matching name.
+func wildcardCovers(pattern, name string) bool {
+	suffix, ok := strings.CutPrefix(pattern, "*.")
+	if !ok {
+		return false
+	}
+	label, rest, found := strings.Cut(name, ".")
+	return found && label != "" && label != "*" && rest == suffix
+}
+
+func splitDomainPort(domain string) (string, string) {
+	domain = strings.ToLower(strings.TrimSpace(domain))
+	if i := strings.LastIndex(domain, ":"); i >= 0 {
+		return strings.TrimSuffix(domain[:i], "."), domain[i+1:]
+	}
+	return strings.TrimSuffix(domain, "."), ""
+}
@@ -3,6 +3,8 @@ package caddy
 import (
 	"strings"
 	"testing"
+
+	"github.com/web-casa/webcasa/internal/model"
 )
 
 // ---------------------------------------------------------------------------
@@ -339,3 +341,270 @@ func TestValidateIPRange_BoundaryCIDR(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateMaintenancePage(t *testing.T) {
+	if err := ValidateMaintenancePage("<h1>Back soon</h1>\n<p>{braces} are fine</p>"); err != nil {
+		t.Errorf("valid page rejected: %v", err)
+	}
+	if err := ValidateMaintenancePage("<p>hi</p>\n  MAINTENANCE_PAGE 200\nreverse_proxy evil:80"); err == nil {
+		t.Error("page that closes the heredoc should be rejected")
+	}
+	if err := ValidateMaintenancePage("<p>hi</p>\nxMAINTENANCE_PAGE 200\nreverse_proxy evil:80"); err == nil {
+		t.Error("page with the marker mid-line should be rejected")
+	}
+	if err := ValidateMaintenancePage(strings.Repeat("x", maxMaintenancePage+1)); err == nil {
+		t.Error("oversized page should be rejected")
+	}
+}
+
+func TestParseByteSize(t *testing.T) {
+	valid := map[string]int64{
+		"512":     512,
+		"10MB":    10_000_000,
+		"10mb":    10_000_000,
+		"1.5 GiB": 1610612736,
+		"64KiB":   65536,
+		"2G":      2_000_000_000,
+	}
+	for in, want := range valid {
+		got, err := ParseByteSize(in)
+		if err != nil || got != want {
+			t.Errorf("ParseByteSize(%q) = %d, %v; want %d", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "MB", "ten megs", "10XB", "-5MB", "0", "10MB\nimport evil"} {
+		if _, err := ParseByteSize(in); err == nil {
+			t.Errorf("ParseByteSize(%q) should fail", in)
+		}
+	}
+}
+
+func TestValidateDuration(t *testing.T) {
+	for _, v := range []string{"", "30s", "5m", "1h30m", "250ms"} {
+		if err := ValidateDuration("timeout", v); err != nil {
+			t.Errorf("ValidateDuration(%q): %v", v, err)
+		}
+	}
+	for _, v := range []string{"30", "five minutes", "-5s", "0s", "5m\nimport evil"} {
+		if err := ValidateDuration("timeout", v); err == nil {
+			t.Errorf("ValidateDuration(%q) should fail", v)
+		}
+	}
+}
+
+func TestValidateBasicAuthPath(t *testing.T) {
+	for _, v := range []string{"", "/", "/admin", "/admin/", "/api/v1"} {
+		if err := ValidateBasicAuthPath(v); err != nil {
+			t.Errorf("ValidateBasicAuthPath(%q): %v", v, err)
+		}
+	}
+	for _, v := range []string{"admin", "/admin/*", "/a b", "/admin\nimport evil", "/{path}"} {
+		if err := ValidateBasicAuthPath(v); err == nil {
+			t.Errorf("ValidateBasicAuthPath(%q) should fail", v)
+		}
+	}
+}
+
+func TestValidateForwardAuthURL(t *testing.T) {
+	for _, v := range []string{"", "http://authelia:9091/api/verify", "https://auth.example.com/api/authz/forward-auth?rd=x"} {
+		if err := ValidateForwardAuthURL(v); err != nil {
+			t.Errorf("ValidateForwardAuthURL(%q): %v", v, err)
+		}
+	}
+	for _, v := range []string{"authelia:9091", "ftp://auth.example.com", "http:///verify", "http://user:pw@auth", "http://auth/a b", "http://auth/{x}", "http://auth\nimport evil"} {
+		if err := ValidateForwardAuthURL(v); err == nil {
+			t.Errorf("ValidateForwardAuthURL(%q) should fail", v)
+		}
+	}
+}
+
+func TestValidateHeaderList(t *testing.T) {
+	if got := HeaderList("Remote-User, Remote-Groups  Remote-Email"); len(got) != 3 || got[2] != "Remote-Email" {
+		t.Errorf("HeaderList = %v", got)
+	}
+	if err := ValidateHeaderList("copy_headers", "Remote-User, Remote-Groups"); err != nil {
+		t.Errorf("valid header list rejected: %v", err)
+	}
+	for _, v := range []string{"Remote-User; import evil", "X-{a}", "Bad:Header"} {
+		if err := ValidateHeaderList("copy_headers", v); err == nil {
+			t.Errorf("ValidateHeaderList(%q) should fail", v)
+		}
+	}
+}
+
+func TestPHPPreset(t *testing.T) {
+	for _, tc := range []struct{ address, version, want string }{
+		{"", "", DefaultPHPFastCGI},
+		{"", "8.2", "unix//run/php/php8.2-fpm.sock"},
+		{"php:9000", "8.2", "php:9000"},
+		{"/run/php-fpm/www.sock", "", "unix//run/php-fpm/www.sock"},
+		{"unix//run/php-fpm/www.sock", "", "unix//run/php-fpm/www.sock"},
+	} {
+		if got := PHPPreset(tc.address, tc.version, "").Address; got != tc.want {
+			t.Errorf("PHPPreset(%q, %q).Address = %q, want %q", tc.address, tc.version, got, tc.want)
+		}
+	}
+
+	for _, v := range []string{"", "localhost:9000", "unix//run/php/php8.3-fpm.sock", "/run/php/php8.3-fpm.sock"} {
+		if err := ValidatePHPFastCGI(v); err != nil {
+			t.Errorf("ValidatePHPFastCGI(%q): %v", v, err)
+		}
+	}
+	for _, v := range []string{"http://localhost:9000", "localhost:9000 {", "unix/relative.sock"} {
+		if err := ValidatePHPFastCGI(v); err == nil {
+			t.Errorf("ValidatePHPFastCGI(%q) should fail", v)
+		}
+	}
+	if err := ValidatePHPVersion("8.3"); err != nil {
+		t.Errorf("ValidatePHPVersion(8.3): %v", err)
+	}
+	if err := ValidatePHPVersion("8.3; rm"); err == nil {
+		t.Error("ValidatePHPVersion should reject junk")
+	}
+}
+
+func TestNormalizeCompressionAlgorithms(t *testing.T) {
+	for in, want := range map[string]string{
+		"":                 "",
+		"gzip":             "gzip",
+		" ZSTD, gzip,zstd": "zstd,gzip",
+		"br,gzip,zstd":     "br,gzip,zstd",
+	} {
+		got, err := NormalizeCompressionAlgorithms(in)
+		if err != nil || got != want {
+			t.Errorf("NormalizeCompressionAlgorithms(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"deflate", "gzip,lz4", "gzip zstd"} {
+		if _, err := NormalizeCompressionAlgorithms(in); err == nil {
+			t.Errorf("NormalizeCompressionAlgorithms(%q) should fail", in)
+		}
+	}
+}
+
+func TestNormalizeACMECA(t *testing.T) {
+	for in, want := range map[string]string{
+		"":                                 "",
+		"staging":                          ACMECAStaging,
+		" Staging ":                        ACMECAStaging,
+		"https://acme.zerossl.com/v2/DV90": "https://acme.zerossl.com/v2/DV90",
+	} {
+		got, err := NormalizeACMECA(in)
+		if err != nil || got != want {
+			t.Errorf("NormalizeACMECA(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	for _, in := range []string{
+		"http://acme.example.com/directory",
+		"acme.example.com/directory",
+		"https:///directory",
+		"https://user:pw@acme.example.com/directory",
+		"https://acme.example.com/dir }",
+	} {
+		if _, err := NormalizeACMECA(in); err == nil {
+			t.Errorf("NormalizeACMECA(%q) should fail", in)
+		}
+	}
+	if got := ACMEDirectoryURL(ACMECAStaging); got != LetsEncryptStagingCA {
+		t.Errorf("ACMEDirectoryURL(staging) = %q", got)
+	}
+}
+
+func TestValidateCors(t *testing.T) {
+	if err := ValidateCors("*", false, 0); err != nil {
+		t.Errorf("wildcard without credentials: %v", err)
+	}
+	if err := ValidateCors("https://a.example.com, https://b.example.com", true, 3600); err != nil {
+		t.Errorf("explicit origins with credentials: %v", err)
+	}
+	for _, origins := range []string{"*", "", "https://a.example.com, *"} {
+		if err := ValidateCors(origins, true, 0); err == nil {
+			t.Errorf("credentials with wildcard origins %q should fail", origins)
+		}
+	}
+	if err := ValidateCors("https://a.example.com", false, -1); err == nil {
+		t.Error("negative max-age should fail")
+	}
+}
+
+func TestValidateSecurityHeadersConfig(t *testing.T) {
+	valid := []*model.SecurityHeadersConfig{
+		nil,
+		{},
+		{HSTSMaxAge: 31536000, HSTSPreload: true, FrameOptions: "SAMEORIGIN", ReferrerPolicy: "no-referrer", ContentSecurityPolicy: "default-src 'self'"},
+	}
+	for _, cfg := range valid {
+		if err := ValidateSecurityHeadersConfig(cfg); err != nil {
+			t.Errorf("ValidateSecurityHeadersConfig(%+v): %v", cfg, err)
+		}
+	}
+	invalid := []*model.SecurityHeadersConfig{
+		{HSTSMaxAge: -1},
+		{FrameOptions: "ALLOW-FROM https://x"},
+		{ReferrerPolicy: "sometimes"},
+		{ContentSecurityPolicy: "default-src \"self\""},
+		{ContentSecurityPolicy: "default-src 'self'\nimport evil"},
+	}
+	for _, cfg := range invalid {
+		if err := ValidateSecurityHeadersConfig(cfg); err == nil {
+			t.Errorf("ValidateSecurityHeadersConfig(%+v) should fail", cfg)
+		}
+	}
+}
+
+func TestDomainsOverlap(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"*.example.com", "app.example.com", true},
+		{"app.example.com", "*.example.com", true},
+		{"APP.example.com", "*.Example.com", true},
+		{"*.example.com", "example.com", false},
+		{"*.example.com", "a.b.example.com", false},
+		{"*.example.com", "app.example.org", false},
+		{"*.example.com", "*.example.com", false},
+		{"*.example.com", "*.app.example.com", false},
+		{"app.example.com", "api.example.com", false},
+		{"*.example.com:8443", "app.example.com:8443", true},
+		{"*.example.com:8443", "app.example.com", false},
+	}
+	for _, tt := range tests {
+		if got := DomainsOverlap(tt.a, tt.b); got != tt.want {
+			t.Errorf("DomainsOverlap(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeDomain(t *testing.T) {
+	for in, want := range map[string]string{
+		"Example.COM":        "example.com",
+		"example.com.":       "example.com",
+		" www.example.com ":  "www.example.com",
+		"münchen.de":         "xn--mnchen-3ya.de",
+		"MÜNCHEN.de.":        "xn--mnchen-3ya.de",
+		"*.münchen.de":       "*.xn--mnchen-3ya.de",
+		"example.com.:8443":  "example.com:8443",
+		"xn--mnchen-3ya.de":  "xn--mnchen-3ya.de",
+		"bücher.example.com": "xn--bcher-kva.example.com",
+	} {
+		got, err := NormalizeDomain(in)
+		if err != nil || got != want {
+			t.Errorf("NormalizeDomain(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := NormalizeDomain("exa‍mple.рф"); err == nil {
+		t.Error("NormalizeDomain accepted a label with a disallowed joiner")
+	}
+
+	for in, want := range map[string]string{
+		"xn--mnchen-3ya.de":      "münchen.de",
+		"*.xn--mnchen-3ya.de":    "*.münchen.de",
+		"xn--mnchen-3ya.de:8443": "münchen.de:8443",
+		"example.com":            "",
+	} {
+		if got := DisplayDomain(in); got != want {
+			t.Errorf("DisplayDomain(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
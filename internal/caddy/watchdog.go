@@ -0,0 +1,145 @@
+package caddy
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// Watched is the part of Manager the watchdog drives.
+type Watched interface {
+	IsRunning() bool
+	ShouldRun() bool
+	Start() error
+}
+
+// Watchdog event kinds.
+const (
+	WatchdogDown      = "down"      // Caddy should run but is not running
+	WatchdogRecovered = "recovered" // a restart brought Caddy back
+	WatchdogGaveUp    = "gave_up"   // every restart attempt of an outage failed
+)
+
+// WatchdogEvent reports a step of an outage to the watchdog's listener.
+type WatchdogEvent struct {
+	Kind     string
+	Attempts int   // restart attempts made so far in this outage
+	Err      error // last restart error, for WatchdogGaveUp
+}
+
+// Watchdog periodically checks that Caddy is running while it should be and
+// restarts it with exponential backoff when it is not. After maxRetries
+// failed attempts it gives up until Caddy is seen running again.
+type Watchdog struct {
+	proc       Watched
+	interval   time.Duration
+	maxRetries int
+	onEvent    func(WatchdogEvent)
+
+	backoff    time.Duration // delay after the first failed attempt, doubled after each
+	maxBackoff time.Duration
+
+	gaveUp   bool
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewWatchdog creates a watchdog checking proc every interval. onEvent, if
+// non-nil, is called for every outage event.
+func NewWatchdog(proc Watched, interval time.Duration, maxRetries int, onEvent func(WatchdogEvent)) *Watchdog {
+	if maxRetries < 1 {
+		maxRetries = 1
+	}
+	return &Watchdog{
+		proc:       proc,
+		interval:   interval,
+		maxRetries: maxRetries,
+		onEvent:    onEvent,
+		backoff:    2 * time.Second,
+		maxBackoff: 5 * time.Minute,
+		stop:       make(chan struct{}),
+	}
+}
+
+// Start runs the checks in the background. It does nothing when the
+// interval is not positive.
+func (w *Watchdog) Start() {
+	if w.interval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-w.stop:
+				return
+			case <-ticker.C:
+				w.check()
+			}
+		}
+	}()
+}
+
+// Stop ends the background checks.
+func (w *Watchdog) Stop() {
+	w.stopOnce.Do(func() { close(w.stop) })
+}
+
+// check runs one liveness check and, on an outage, the restart attempts.
+func (w *Watchdog) check() {
+	if !w.proc.ShouldRun() {
+		w.gaveUp = false
+		return
+	}
+	if w.proc.IsRunning() {
+		w.gaveUp = false
+		return
+	}
+	if w.gaveUp {
+		return
+	}
+
+	log.Println("⚠️  Caddy is not running, watchdog restarting it...")
+	w.emit(WatchdogEvent{Kind: WatchdogDown})
+
+	delay := w.backoff
+	var lastErr error
+	for attempt := 1; attempt <= w.maxRetries; attempt++ {
+		lastErr = w.proc.Start()
+		// Start fails when someone else started Caddy in the meantime.
+		if lastErr == nil || w.proc.IsRunning() {
+			log.Printf("Caddy restarted by watchdog after %d attempt(s)", attempt)
+			w.emit(WatchdogEvent{Kind: WatchdogRecovered, Attempts: attempt})
+			return
+		}
+		log.Printf("⚠️  Watchdog restart attempt %d/%d failed: %v", attempt, w.maxRetries, lastErr)
+		if attempt == w.maxRetries {
+			break
+		}
+
+		select {
+		case <-w.stop:
+			return
+		case <-time.After(delay):
+		}
+		delay *= 2
+		if delay > w.maxBackoff {
+			delay = w.maxBackoff
+		}
+		// Stopped through the panel while we were waiting.
+		if !w.proc.ShouldRun() {
+			return
+		}
+	}
+
+	log.Printf("⚠️  Watchdog gave up restarting Caddy after %d attempts", w.maxRetries)
+	w.gaveUp = true
+	w.emit(WatchdogEvent{Kind: WatchdogGaveUp, Attempts: w.maxRetries, Err: lastErr})
+}
+
+func (w *Watchdog) emit(e WatchdogEvent) {
+	if w.onEvent != nil {
+		w.onEvent(e)
+	}
+}
@@ -0,0 +1,157 @@
+package caddy
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeCaddy is a Watched whose Start fails failStarts times before it brings
+// the process up.
+type fakeCaddy struct {
+	mu         sync.Mutex
+	running    bool
+	shouldRun  bool
+	failStarts int
+	starts     int
+}
+
+func (f *fakeCaddy) IsRunning() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.running
+}
+
+func (f *fakeCaddy) ShouldRun() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.shouldRun
+}
+
+func (f *fakeCaddy) Start() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.starts++
+	if f.failStarts > 0 {
+		f.failStarts--
+		return errors.New("caddy start failed")
+	}
+	f.running = true
+	return nil
+}
+
+func (f *fakeCaddy) startCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.starts
+}
+
+// newTestWatchdog returns a watchdog with millisecond backoff recording its
+// events.
+func newTestWatchdog(proc Watched, maxRetries int) (*Watchdog, *[]WatchdogEvent) {
+	var events []WatchdogEvent
+	w := NewWatchdog(proc, time.Hour, maxRetries, func(e WatchdogEvent) { events = append(events, e) })
+	w.backoff = time.Millisecond
+	w.maxBackoff = 4 * time.Millisecond
+	return w, &events
+}
+
+func eventKinds(events []WatchdogEvent) []string {
+	var kinds []string
+	for _, e := range events {
+		kinds = append(kinds, e.Kind)
+	}
+	return kinds
+}
+
+func TestWatchdog_RestartsCrashedCaddy(t *testing.T) {
+	proc := &fakeCaddy{shouldRun: true}
+	w, events := newTestWatchdog(proc, 3)
+
+	w.check()
+	if proc.startCount() != 1 {
+		t.Fatalf("%d restart attempts, want 1", proc.startCount())
+	}
+	if !proc.IsRunning() {
+		t.Fatal("caddy not running after restart")
+	}
+	if got := eventKinds(*events); len(got) != 2 || got[0] != WatchdogDown || got[1] != WatchdogRecovered {
+		t.Errorf("events = %v, want [down recovered]", got)
+	}
+
+	// Up again: nothing more to do
+	w.check()
+	if proc.startCount() != 1 {
+		t.Errorf("restart attempted while caddy is running")
+	}
+}
+
+func TestWatchdog_RetriesWithBackoff(t *testing.T) {
+	proc := &fakeCaddy{shouldRun: true, failStarts: 2}
+	w, events := newTestWatchdog(proc, 5)
+
+	w.check()
+	if proc.startCount() != 3 {
+		t.Errorf("%d restart attempts, want 3", proc.startCount())
+	}
+	last := (*events)[len(*events)-1]
+	if last.Kind != WatchdogRecovered || last.Attempts != 3 {
+		t.Errorf("last event = %+v, want recovered after 3 attempts", last)
+	}
+}
+
+func TestWatchdog_GivesUpUntilCaddyIsBack(t *testing.T) {
+	proc := &fakeCaddy{shouldRun: true, failStarts: 100}
+	w, events := newTestWatchdog(proc, 3)
+
+	w.check()
+	if proc.startCount() != 3 {
+		t.Errorf("%d restart attempts, want 3", proc.startCount())
+	}
+	last := (*events)[len(*events)-1]
+	if last.Kind != WatchdogGaveUp || last.Err == nil {
+		t.Errorf("last event = %+v, want gave_up with the start error", last)
+	}
+
+	w.check()
+	if proc.startCount() != 3 {
+		t.Errorf("watchdog kept retrying after giving up")
+	}
+
+	// Once caddy has been seen up, a new outage is handled again
+	proc.mu.Lock()
+	proc.running, proc.failStarts = true, 0
+	proc.mu.Unlock()
+	w.check()
+	proc.mu.Lock()
+	proc.running = false
+	proc.mu.Unlock()
+	w.check()
+	if proc.startCount() != 4 || !proc.IsRunning() {
+		t.Errorf("new outage not recovered: %d attempts, running %v", proc.startCount(), proc.IsRunning())
+	}
+}
+
+func TestWatchdog_IgnoresDeliberateStop(t *testing.T) {
+	proc := &fakeCaddy{shouldRun: false}
+	w, events := newTestWatchdog(proc, 3)
+
+	w.check()
+	if proc.startCount() != 0 || len(*events) != 0 {
+		t.Errorf("watchdog restarted a caddy stopped on purpose (%d attempts)", proc.startCount())
+	}
+}
+
+func TestWatchdog_BackgroundLoop(t *testing.T) {
+	proc := &fakeCaddy{shouldRun: true}
+	w := NewWatchdog(proc, 5*time.Millisecond, 3, nil)
+	w.Start()
+	defer w.Stop()
+
+	for deadline := time.Now().Add(5 * time.Second); !proc.IsRunning(); time.Sleep(5 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("watchdog loop never restarted caddy")
+		}
+	}
+}
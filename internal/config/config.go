@@ -6,6 +6,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 )
 
@@ -19,6 +20,23 @@ type Config struct {
 	LogDir        string // Directory for Caddy logs
 	DataDir       string // Data directory root
 	AdminAPI      string // Caddy admin API URL
+
+	AuditRetentionDays  int // Delete audit log entries older than this many days (0 = keep forever)
+	JWTTTLMinutes       int // Lifetime of session JWTs
+	TempTokenTTLSeconds int // Lifetime of the pending-2FA temp token
+	TOTPSkewPeriods     int // 30s TOTP periods accepted either side of now at login
+
+	MetricsToken  string // Bearer token required by GET /api/metrics (empty = localhost only)
+	MetricsPublic bool   // Serve GET /api/metrics to any client when no token is set
+
+	BackupDir           string // Directory for full backup archives
+	BackupIntervalHours int    // Hours between scheduled backups (0 = disabled)
+	BackupKeep          int    // Number of backup archives to keep
+
+	ApplyDebounceMs int // Window in which host changes share one Caddy config apply (0 = apply each change)
+
+	WatchdogIntervalSeconds int // Seconds between Caddy liveness checks (0 = watchdog disabled)
+	WatchdogMaxRetries      int // Restart attempts per outage before the watchdog gives up
 }
 
 // Load reads configuration from environment variables with sensible defaults
@@ -39,12 +57,30 @@ func Load() *Config {
 		LogDir:        envOrDefault("WEBCASA_LOG_DIR", filepath.Join(dataDir, "logs")),
 		DataDir:       dataDir,
 		AdminAPI:      envOrDefault("WEBCASA_ADMIN_API", "http://localhost:2019"),
+
+		AuditRetentionDays:  envIntOrDefault("WEBCASA_AUDIT_RETENTION_DAYS", 0, 0),
+		JWTTTLMinutes:       envIntOrDefault("WEBCASA_JWT_TTL_MINUTES", 24*60, 1),
+		TempTokenTTLSeconds: envIntOrDefault("WEBCASA_TEMP_TOKEN_TTL_SECONDS", 5*60, 1),
+		TOTPSkewPeriods:     envIntOrDefault("WEBCASA_TOTP_SKEW_PERIODS", 1, 0),
+
+		MetricsToken:  os.Getenv("WEBCASA_METRICS_TOKEN"),
+		MetricsPublic: os.Getenv("WEBCASA_METRICS_PUBLIC") == "true",
+
+		BackupDir:           envOrDefault("WEBCASA_BACKUP_DIR", filepath.Join(dataDir, "backups")),
+		BackupIntervalHours: envIntOrDefault("WEBCASA_BACKUP_INTERVAL_HOURS", 0, 0),
+		BackupKeep:          envIntOrDefault("WEBCASA_BACKUP_KEEP", 7, 1),
+
+		ApplyDebounceMs: envIntOrDefault("WEBCASA_APPLY_DEBOUNCE_MS", 200, 0),
+
+		WatchdogIntervalSeconds: envIntOrDefault("WEBCASA_WATCHDOG_INTERVAL_SECONDS", 30, 0),
+		WatchdogMaxRetries:      envIntOrDefault("WEBCASA_WATCHDOG_MAX_RETRIES", 5, 1),
 	}
 
 	// Ensure directories exist (0700: not world-readable; backups may contain
 	// exported credentials).
 	os.MkdirAll(cfg.LogDir, 0700)
 	os.MkdirAll(filepath.Join(dataDir, "backups"), 0700)
+	os.MkdirAll(cfg.BackupDir, 0700)
 
 	// Best-effort tightening of the DB file permissions to owner-only. The DB is
 	// opened by the database package; if it already exists, ensure it is 0600.
@@ -104,3 +140,18 @@ func envOrDefault(key, defaultVal string) string {
 	}
 	return defaultVal
 }
+
+// envIntOrDefault reads an integer env var of at least min, falling back to
+// defaultVal when it is unset or invalid.
+func envIntOrDefault(key string, defaultVal, min int) int {
+	val := os.Getenv(key)
+	if val == "" {
+		return defaultVal
+	}
+	n, err := strconv.Atoi(val)
+	if err != nil || n < min {
+		log.Printf("⚠️  Ignoring invalid %s=%q", key, val)
+		return defaultVal
+	}
+	return n
+}
@@ -147,3 +147,61 @@ func TestResolveJWTSecret_FilePermissions(t *testing.T) {
 		t.Errorf("expected file permissions 0600, got %04o", perm)
 	}
 }
+
+// TestLoad_TokenLifetimes verifies the token TTL defaults and that
+// non-positive values are rejected in favour of the defaults.
+func TestLoad_TokenLifetimes(t *testing.T) {
+	t.Setenv("WEBCASA_DATA_DIR", t.TempDir())
+
+	cfg := Load()
+	if cfg.JWTTTLMinutes != 1440 || cfg.TempTokenTTLSeconds != 300 {
+		t.Errorf("defaults = %d min / %d s, want 1440 / 300", cfg.JWTTTLMinutes, cfg.TempTokenTTLSeconds)
+	}
+
+	t.Setenv("WEBCASA_JWT_TTL_MINUTES", "60")
+	t.Setenv("WEBCASA_TEMP_TOKEN_TTL_SECONDS", "0")
+	cfg = Load()
+	if cfg.JWTTTLMinutes != 60 {
+		t.Errorf("JWTTTLMinutes = %d, want 60", cfg.JWTTTLMinutes)
+	}
+	if cfg.TempTokenTTLSeconds != 300 {
+		t.Errorf("TempTokenTTLSeconds = %d, want default 300 for a non-positive value", cfg.TempTokenTTLSeconds)
+	}
+
+	t.Setenv("WEBCASA_JWT_TTL_MINUTES", "-5")
+	if cfg = Load(); cfg.JWTTTLMinutes != 1440 {
+		t.Errorf("JWTTTLMinutes = %d, want default for a negative value", cfg.JWTTTLMinutes)
+	}
+}
+
+func TestLoad_TOTPSkewPeriods(t *testing.T) {
+	t.Setenv("WEBCASA_DATA_DIR", t.TempDir())
+
+	if cfg := Load(); cfg.TOTPSkewPeriods != 1 {
+		t.Errorf("default TOTPSkewPeriods = %d, want 1", cfg.TOTPSkewPeriods)
+	}
+	t.Setenv("WEBCASA_TOTP_SKEW_PERIODS", "0")
+	if cfg := Load(); cfg.TOTPSkewPeriods != 0 {
+		t.Errorf("TOTPSkewPeriods = %d, want 0 to be allowed", cfg.TOTPSkewPeriods)
+	}
+}
+
+func TestLoad_Backup(t *testing.T) {
+	dataDir := t.TempDir()
+	t.Setenv("WEBCASA_DATA_DIR", dataDir)
+
+	cfg := Load()
+	if cfg.BackupDir != filepath.Join(dataDir, "backups") || cfg.BackupIntervalHours != 0 || cfg.BackupKeep != 7 {
+		t.Errorf("defaults = %q / %dh / keep %d", cfg.BackupDir, cfg.BackupIntervalHours, cfg.BackupKeep)
+	}
+
+	t.Setenv("WEBCASA_BACKUP_INTERVAL_HOURS", "24")
+	t.Setenv("WEBCASA_BACKUP_KEEP", "0")
+	cfg = Load()
+	if cfg.BackupIntervalHours != 24 {
+		t.Errorf("BackupIntervalHours = %d, want 24", cfg.BackupIntervalHours)
+	}
+	if cfg.BackupKeep != 7 {
+		t.Errorf("BackupKeep = %d, want default 7 for a value below 1", cfg.BackupKeep)
+	}
+}
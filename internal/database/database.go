@@ -1,6 +1,7 @@
 package database
 
 import (
+	"fmt"
 	"log"
 
 	"github.com/web-casa/webcasa/internal/model"
@@ -24,8 +25,20 @@ func Init(dbPath string) *gorm.DB {
 	sqlDB.Exec("PRAGMA journal_mode=WAL")
 	sqlDB.Exec("PRAGMA foreign_keys=ON")
 
+	if err := Migrate(db); err != nil {
+		log.Fatalf("Failed to migrate database: %v", err)
+	}
+
+	log.Println("Database initialized successfully")
+	return db
+}
+
+// Migrate brings db's schema up to date and seeds the default settings.
+// It is safe to run on an up-to-date database, and is also run after a
+// backup from an older version is restored over the live one.
+func Migrate(db *gorm.DB) error {
 	// Auto-migrate all models
-	err = db.AutoMigrate(
+	err := db.AutoMigrate(
 		&model.User{},
 		&model.Host{},
 		&model.Upstream{},
@@ -34,17 +47,30 @@ func Init(dbPath string) *gorm.DB {
 		&model.AccessRule{},
 		&model.BasicAuth{},
 		&model.AuditLog{},
+		&model.LoginAttempt{},
 		&model.DnsProvider{},
 		&model.Setting{},
 		&model.Certificate{},
 		&model.Group{},
 		&model.Tag{},
 		&model.HostTag{},
+		&model.TagRule{},
+		&model.HostRevision{},
+		&model.HostSchedule{},
 		&model.Template{},
 		&notify.Channel{},
 	)
 	if err != nil {
-		log.Fatalf("Failed to migrate database: %v", err)
+		return err
+	}
+
+	// Trash migration: domains are now unique among live hosts only
+	// (idx_hosts_domain_live). Drop the old table-wide index so a trashed
+	// host does not block re-creating its domain.
+	if db.Migrator().HasIndex(&model.Host{}, "idx_hosts_domain") {
+		if err := db.Migrator().DropIndex(&model.Host{}, "idx_hosts_domain"); err != nil {
+			return fmt.Errorf("hosts domain index: %w", err)
+		}
 	}
 
 	// Seed default settings
@@ -54,6 +80,7 @@ func Init(dbPath string) *gorm.DB {
 	db.Where("key = ?", "wildcard_domain").FirstOrCreate(&model.Setting{Key: "wildcard_domain", Value: ""})
 	db.Where("key = ?", "wildcard_tls_mode").FirstOrCreate(&model.Setting{Key: "wildcard_tls_mode", Value: "auto"})
 	db.Where("key = ?", "server_ipv6").FirstOrCreate(&model.Setting{Key: "server_ipv6", Value: ""})
+	db.Where("key = ?", "reject_domain_overlap").FirstOrCreate(&model.Setting{Key: "reject_domain_overlap", Value: "false"})
 
 	// RBAC migration: promote first admin to owner if no owner exists yet.
 	var ownerCount int64
@@ -73,9 +100,7 @@ func Init(dbPath string) *gorm.DB {
 			}
 		}
 	}
-
-	log.Println("Database initialized successfully")
-	return db
+	return nil
 }
 
 // SeedTemplatePresets seeds preset templates if the templates table is empty.
@@ -1,8 +1,13 @@
 package handler
 
 import (
+	"encoding/csv"
+	"fmt"
+	"log"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/web-casa/webcasa/internal/model"
 	"github.com/gin-gonic/gin"
@@ -11,50 +16,319 @@ import (
 
 // AuditHandler handles audit log queries
 type AuditHandler struct {
-	db *gorm.DB
+	db            *gorm.DB
+	retentionDays int // 0 = keep forever
 }
 
-func NewAuditHandler(db *gorm.DB) *AuditHandler {
-	return &AuditHandler{db: db}
+func NewAuditHandler(db *gorm.DB, retentionDays int) *AuditHandler {
+	return &AuditHandler{db: db, retentionDays: retentionDays}
 }
 
-// List returns audit logs with pagination
+// maxAuditLimit caps how many audit entries a single list request returns.
+const maxAuditLimit = 500
+
+// auditFilter holds the query filters shared by the audit log endpoints.
+type auditFilter struct {
+	Action   string
+	Target   string
+	Username string
+	From     *time.Time
+	To       *time.Time
+}
+
+// parseAuditFilter reads action, target, username, from and to from the
+// query string. from/to must be RFC3339 timestamps.
+func parseAuditFilter(c *gin.Context) (auditFilter, error) {
+	f := auditFilter{
+		Action:   strings.ToUpper(strings.TrimSpace(c.Query("action"))),
+		Target:   strings.TrimSpace(c.Query("target")),
+		Username: strings.TrimSpace(c.Query("username")),
+	}
+	for param, dst := range map[string]**time.Time{"from": &f.From, "to": &f.To} {
+		v := c.Query(param)
+		if v == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return f, fmt.Errorf("%s must be an RFC3339 timestamp", param)
+		}
+		*dst = &t
+	}
+	if f.From != nil && f.To != nil && f.From.After(*f.To) {
+		return f, fmt.Errorf("from must not be after to")
+	}
+	return f, nil
+}
+
+// apply narrows q to the entries matching f.
+func (f auditFilter) apply(q *gorm.DB) *gorm.DB {
+	if f.Action != "" {
+		q = q.Where("action = ?", f.Action)
+	}
+	if f.Target != "" {
+		q = q.Where("target = ?", f.Target)
+	}
+	if f.Username != "" {
+		q = q.Where("username = ?", f.Username)
+	}
+	if f.From != nil {
+		q = q.Where("created_at >= ?", *f.From)
+	}
+	if f.To != nil {
+		q = q.Where("created_at <= ?", *f.To)
+	}
+	return q
+}
+
+// List returns audit logs, newest first, filtered by action, target,
+// username and a from/to date range. Paginate with limit/offset; the older
+// page/per_page parameters are still honoured when limit/offset are absent.
 func (h *AuditHandler) List(c *gin.Context) {
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	perPage, _ := strconv.Atoi(c.DefaultQuery("per_page", "50"))
-	if page < 1 {
-		page = 1
+	filter, err := parseAuditFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "error_key": "error.invalid_date_range"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", c.DefaultQuery("per_page", "50")))
+	if err != nil || limit < 1 {
+		limit = 50
 	}
-	if perPage < 1 || perPage > 100 {
-		perPage = 50
+	if limit > maxAuditLimit {
+		limit = maxAuditLimit
+	}
+	offset, err := strconv.Atoi(c.Query("offset"))
+	if err != nil || offset < 0 {
+		offset = 0
+		if page, _ := strconv.Atoi(c.Query("page")); page > 1 {
+			offset = (page - 1) * limit
+		}
 	}
 
 	var total int64
-	h.db.Model(&model.AuditLog{}).Count(&total)
+	if err := filter.apply(h.db.Model(&model.AuditLog{})).Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
 
-	var logs []model.AuditLog
-	h.db.Order("created_at DESC").
-		Offset((page - 1) * perPage).
-		Limit(perPage).
-		Find(&logs)
+	logs := []model.AuditLog{}
+	if err := filter.apply(h.db.Model(&model.AuditLog{})).
+		Order("created_at DESC, id DESC").
+		Offset(offset).
+		Limit(limit).
+		Find(&logs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"logs":     logs,
-		"total":    total,
-		"page":     page,
-		"per_page": perPage,
+		"logs":   logs,
+		"total":  total,
+		"limit":  limit,
+		"offset": offset,
 	})
 }
 
-// WriteLog is a helper to create an audit log entry
-func WriteAuditLog(db *gorm.DB, userID uint, username, action, target, targetID, detail, ip string) {
-	db.Create(&model.AuditLog{
+// Export streams the audit logs matching the list filters as a CSV
+// attachment, newest first. Rows are written as they are read so large
+// tables are never held in memory.
+func (h *AuditHandler) Export(c *gin.Context) {
+	filter, err := parseAuditFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "error_key": "error.invalid_date_range"})
+		return
+	}
+
+	rows, err := filter.apply(h.db.Model(&model.AuditLog{})).
+		Order("created_at DESC, id DESC").
+		Rows()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	filename := fmt.Sprintf("audit_logs_%s.csv", time.Now().Format("20060102_150405"))
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", "attachment; filename="+filename)
+	c.Status(http.StatusOK)
+
+	w := csv.NewWriter(c.Writer)
+	w.Write([]string{"timestamp", "username", "action", "target", "target_id", "detail", "ip"})
+	for rows.Next() {
+		var entry model.AuditLog
+		if err := h.db.ScanRows(rows, &entry); err != nil {
+			break
+		}
+		w.Write([]string{
+			entry.CreatedAt.UTC().Format(time.RFC3339),
+			csvCell(entry.Username),
+			csvCell(entry.Action),
+			csvCell(entry.Target),
+			csvCell(entry.TargetID),
+			csvCell(entry.Detail),
+			csvCell(entry.IP),
+		})
+		if w.Error() != nil {
+			break // client went away
+		}
+	}
+	w.Flush()
+}
+
+// PruneAuditLogs deletes audit entries older than retentionDays and returns
+// how many were removed. A retention of 0 keeps everything.
+func PruneAuditLogs(db *gorm.DB, retentionDays int) (int64, error) {
+	if retentionDays <= 0 {
+		return 0, nil
+	}
+	cutoff := time.Now().AddDate(0, 0, -retentionDays)
+	res := db.Where("created_at < ?", cutoff).Delete(&model.AuditLog{})
+	return res.RowsAffected, res.Error
+}
+
+// StartRetention prunes expired audit entries now and then once a day. It
+// does nothing when retention is disabled.
+func (h *AuditHandler) StartRetention() {
+	if h.retentionDays <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(24 * time.Hour)
+		defer ticker.Stop()
+		for {
+			h.pruneScheduled()
+			<-ticker.C
+		}
+	}()
+}
+
+func (h *AuditHandler) pruneScheduled() {
+	n, err := PruneAuditLogs(h.db, h.retentionDays)
+	if err != nil {
+		log.Printf("⚠️  Audit log retention: %v", err)
+		return
+	}
+	if n > 0 {
+		WriteAuditLog(h.db, nil, 0, "system", "PRUNE", "audit", "",
+			fmt.Sprintf("Pruned %d audit log entries older than %d days", n, h.retentionDays))
+	}
+}
+
+// Prune deletes audit entries older than the retention period. The body may
+// set "days" to override the configured retention for this run.
+func (h *AuditHandler) Prune(c *gin.Context) {
+	var req struct {
+		Days *int `json:"days"`
+	}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "error_key": "error.invalid_request"})
+			return
+		}
+	}
+	days := h.retentionDays
+	if req.Days != nil {
+		days = *req.Days
+	}
+	if days <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Audit log retention is disabled; pass a positive days value", "error_key": "error.audit_retention_disabled"})
+		return
+	}
+
+	n, err := PruneAuditLogs(h.db, days)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if uid, ok := c.Get("user_id"); ok {
+		uname, _ := c.Get("username")
+		WriteAuditLog(h.db, c, uid.(uint), fmt.Sprint(uname), "PRUNE", "audit", "",
+			fmt.Sprintf("Pruned %d audit log entries older than %d days", n, days))
+	}
+	c.JSON(http.StatusOK, gin.H{"pruned": n, "days": days})
+}
+
+// csvCell prefixes values that spreadsheets would evaluate as formulas.
+func csvCell(v string) string {
+	if v != "" && strings.ContainsRune("=+-@\t\r", rune(v[0])) {
+		return "'" + v
+	}
+	return v
+}
+
+// auditWrittenKey marks a request whose handler already wrote an audit entry,
+// so AuditMutations does not add a generic one. Plugin routes set it through
+// the CoreAPI's WriteAuditLog.
+const auditWrittenKey = "audit_written"
+
+// maxUserAgentLen matches the AuditLog.UserAgent column size.
+const maxUserAgentLen = 255
+
+// WriteAuditLog creates an audit log entry. When c is non-nil the client IP
+// and user agent are taken from the request; pass nil for system events.
+func WriteAuditLog(db *gorm.DB, c *gin.Context, userID uint, username, action, target, targetID, detail string) {
+	entry := &model.AuditLog{
 		UserID:   userID,
 		Username: username,
 		Action:   action,
 		Target:   target,
 		TargetID: targetID,
 		Detail:   detail,
-		IP:       ip,
-	})
+	}
+	if c != nil {
+		entry.IP = c.ClientIP()
+		entry.UserAgent = c.Request.UserAgent()
+		if len(entry.UserAgent) > maxUserAgentLen {
+			entry.UserAgent = entry.UserAgent[:maxUserAgentLen]
+		}
+		c.Set(auditWrittenKey, true)
+	}
+	db.Create(entry)
+}
+
+// AuditMutations records a generic audit entry for every successful
+// POST/PUT/PATCH/DELETE by an authenticated user whose handler did not call
+// WriteAuditLog (or, on plugin routes, the CoreAPI's) itself, so no state
+// change goes unlogged.
+func AuditMutations(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		switch c.Request.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		default:
+			return
+		}
+		if c.GetBool(auditWrittenKey) || c.Writer.Status() >= http.StatusBadRequest {
+			return
+		}
+		uid, ok := c.Get("user_id")
+		if !ok {
+			return
+		}
+		userID, _ := uid.(uint)
+		WriteAuditLog(db, c, userID, c.GetString("username"), c.Request.Method,
+			auditTarget(c.FullPath()), c.Param("id"),
+			c.Request.Method+" "+c.Request.URL.Path)
+	}
+}
+
+// auditTarget derives the audit target from a route pattern:
+// "/api/hosts/:id" → "hosts", "/api/plugins/docker/..." → "plugins/docker".
+func auditTarget(route string) string {
+	parts := strings.Split(strings.TrimPrefix(route, "/api/"), "/")
+	target := parts[0]
+	if target == "plugins" && len(parts) > 1 && !strings.HasPrefix(parts[1], ":") {
+		target += "/" + parts[1]
+	}
+	if target == "" {
+		target = "unknown"
+	}
+	if len(target) > 64 {
+		target = target[:64]
+	}
+	return target
 }
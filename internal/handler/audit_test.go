@@ -2,17 +2,21 @@ package handler
 
 import (
 	"bytes"
+	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
 	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/web-casa/webcasa/internal/caddy"
 	"github.com/web-casa/webcasa/internal/config"
 	"github.com/web-casa/webcasa/internal/model"
+	"github.com/web-casa/webcasa/internal/plugin"
 	"github.com/web-casa/webcasa/internal/service"
 	"github.com/gin-gonic/gin"
 	"github.com/leanovate/gopter"
@@ -137,7 +141,7 @@ func TestProperty20_MutationOperationsProduceAuditLogs(t *testing.T) {
 			groupHandler := NewGroupHandler(groupSvc, db)
 
 			// Create a group first
-			group, err := groupSvc.Create(fmt.Sprintf("grp-%d", suffix), "red")
+			group, err := groupSvc.Create(fmt.Sprintf("grp-%d", suffix), "#ef4444")
 			if err != nil {
 				return false
 			}
@@ -173,7 +177,7 @@ func TestProperty20_MutationOperationsProduceAuditLogs(t *testing.T) {
 
 			body, _ := json.Marshal(map[string]string{
 				"name":  fmt.Sprintf("tag-%d", suffix),
-				"color": "blue",
+				"color": "#3b82f6",
 			})
 			w := httptest.NewRecorder()
 			c, _ := gin.CreateTestContext(w)
@@ -226,3 +230,332 @@ func TestProperty20_MutationOperationsProduceAuditLogs(t *testing.T) {
 
 	properties.TestingRun(t)
 }
+
+// listAuditLogs calls AuditHandler.List with the given query string.
+func listAuditLogs(t *testing.T, db *gorm.DB, query string) (int, []model.AuditLog, int64) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("GET", "/api/audit/logs?"+query, nil)
+	NewAuditHandler(db, 0).List(c)
+
+	var resp struct {
+		Logs  []model.AuditLog `json:"logs"`
+		Total int64            `json:"total"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	return w.Code, resp.Logs, resp.Total
+}
+
+// seedAuditLogs inserts one entry per day going back from base, alternating
+// CREATE and DELETE actions.
+func seedAuditLogs(db *gorm.DB, base time.Time, n int) {
+	for i := 0; i < n; i++ {
+		action := "CREATE"
+		if i%2 == 1 {
+			action = "DELETE"
+		}
+		db.Create(&model.AuditLog{
+			Username:  "admin",
+			Action:    action,
+			Target:    "host",
+			TargetID:  fmt.Sprint(i),
+			CreatedAt: base.AddDate(0, 0, -i),
+		})
+	}
+}
+
+func TestAuditList_FilterByAction(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupAuditTestDB(t, "audit_list_action")
+	seedAuditLogs(db, time.Now(), 6)
+	db.Create(&model.AuditLog{Username: "bob", Action: "DELETE", Target: "user", TargetID: "9"})
+
+	code, logs, total := listAuditLogs(t, db, "action=delete&target=host")
+	if code != http.StatusOK {
+		t.Fatalf("status = %d", code)
+	}
+	if total != 3 || len(logs) != 3 {
+		t.Fatalf("total = %d, len = %d, want 3", total, len(logs))
+	}
+	for _, l := range logs {
+		if l.Action != "DELETE" || l.Target != "host" {
+			t.Errorf("unexpected entry %+v", l)
+		}
+	}
+
+	_, logs, total = listAuditLogs(t, db, "username=bob")
+	if total != 1 || logs[0].Target != "user" {
+		t.Errorf("username filter: total = %d, logs = %+v", total, logs)
+	}
+}
+
+func TestAuditList_DateRange(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupAuditTestDB(t, "audit_list_range")
+	base := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	seedAuditLogs(db, base, 10) // Mar 10 back to Mar 1
+
+	from := base.AddDate(0, 0, -5).Add(-time.Hour).Format(time.RFC3339)
+	to := base.AddDate(0, 0, -3).Add(time.Hour).Format(time.RFC3339)
+	_, logs, total := listAuditLogs(t, db, "from="+from+"&to="+to)
+	if total != 3 {
+		t.Fatalf("total = %d, want 3 (days -3..-5)", total)
+	}
+	// Newest first.
+	if logs[0].TargetID != "3" || logs[2].TargetID != "5" {
+		t.Errorf("order = %s,%s,%s", logs[0].TargetID, logs[1].TargetID, logs[2].TargetID)
+	}
+
+	for _, q := range []string{"from=yesterday", "to=2026-03-01", "from=" + to + "&to=" + from} {
+		if code, _, _ := listAuditLogs(t, db, q); code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", q, code)
+		}
+	}
+}
+
+func TestAuditList_Pagination(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupAuditTestDB(t, "audit_list_page")
+	seedAuditLogs(db, time.Now(), 7)
+
+	seen := map[string]bool{}
+	for offset := 0; offset < 7; offset += 3 {
+		_, logs, total := listAuditLogs(t, db, fmt.Sprintf("limit=3&offset=%d", offset))
+		if total != 7 {
+			t.Fatalf("total = %d, want 7", total)
+		}
+		wantLen := 3
+		if offset == 6 {
+			wantLen = 1
+		}
+		if len(logs) != wantLen {
+			t.Fatalf("offset %d: len = %d, want %d", offset, len(logs), wantLen)
+		}
+		if logs[0].TargetID != fmt.Sprint(offset) {
+			t.Errorf("offset %d starts at %s", offset, logs[0].TargetID)
+		}
+		for _, l := range logs {
+			if seen[l.TargetID] {
+				t.Errorf("entry %s returned twice", l.TargetID)
+			}
+			seen[l.TargetID] = true
+		}
+	}
+
+	// Legacy page/per_page still work.
+	_, logs, _ := listAuditLogs(t, db, "page=2&per_page=2")
+	if len(logs) != 2 || logs[0].TargetID != "2" {
+		t.Errorf("page 2 = %+v", logs)
+	}
+	// Oversized limits are capped rather than rejected.
+	if code, logs, _ := listAuditLogs(t, db, "limit=100000"); code != http.StatusOK || len(logs) != 7 {
+		t.Errorf("capped limit: status %d, len %d", code, len(logs))
+	}
+}
+
+func TestAuditExport_CSV(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupAuditTestDB(t, "audit_export_csv")
+	seedAuditLogs(db, time.Now(), 4)
+	db.Create(&model.AuditLog{Username: "bob", Action: "DELETE", Target: "user", TargetID: "9", Detail: "=HYPERLINK(\"x\")", IP: "10.0.0.1"})
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("GET", "/api/audit/logs/export?action=DELETE", nil)
+	NewAuditHandler(db, 0).Export(c)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d", w.Code)
+	}
+	if cd := w.Header().Get("Content-Disposition"); !strings.HasPrefix(cd, "attachment;") || !strings.Contains(cd, ".csv") {
+		t.Errorf("Content-Disposition = %q", cd)
+	}
+
+	records, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("parse csv: %v", err)
+	}
+	want := []string{"timestamp", "username", "action", "target", "target_id", "detail", "ip"}
+	if strings.Join(records[0], ",") != strings.Join(want, ",") {
+		t.Errorf("header = %v", records[0])
+	}
+	rows := records[1:]
+	if len(rows) != 3 { // two seeded DELETEs plus bob's
+		t.Fatalf("rows = %d, want 3: %v", len(rows), rows)
+	}
+	for _, r := range rows {
+		if r[2] != "DELETE" {
+			t.Errorf("filtered export contains %v", r)
+		}
+	}
+	if rows[0][1] != "bob" || rows[0][5] != `'=HYPERLINK("x")` || rows[0][6] != "10.0.0.1" {
+		t.Errorf("newest row = %v", rows[0])
+	}
+}
+
+func TestAuditPrune_RemovesOnlyOldEntries(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupAuditTestDB(t, "audit_prune")
+	now := time.Now()
+	for _, age := range []int{0, 5, 29, 31, 90} {
+		db.Create(&model.AuditLog{Username: "admin", Action: "UPDATE", Target: "host", TargetID: fmt.Sprint(age), CreatedAt: now.AddDate(0, 0, -age)})
+	}
+
+	if n, _ := PruneAuditLogs(db, 0); n != 0 || countAuditLogs(db) != 5 {
+		t.Fatalf("retention 0 should keep everything, pruned %d", n)
+	}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("POST", "/api/audit/logs/prune", nil)
+	setAuthContext(c)
+	NewAuditHandler(db, 30).Prune(c)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+
+	var remaining []model.AuditLog
+	db.Where("action = ?", "UPDATE").Order("target_id").Find(&remaining)
+	var ids []string
+	for _, l := range remaining {
+		ids = append(ids, l.TargetID)
+	}
+	if strings.Join(ids, ",") != "0,29,5" {
+		t.Errorf("remaining entries = %v, want ages 0, 5 and 29", ids)
+	}
+
+	var note model.AuditLog
+	if err := db.Where("action = ?", "PRUNE").First(&note).Error; err != nil {
+		t.Fatal("prune should be recorded in the audit log")
+	}
+	if !strings.Contains(note.Detail, "Pruned 2 ") {
+		t.Errorf("prune detail = %q", note.Detail)
+	}
+	if countAuditLogs(db) != 4 {
+		t.Errorf("expected exactly one prune entry, total = %d", countAuditLogs(db))
+	}
+
+	// With retention disabled, a manual prune needs an explicit window.
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("POST", "/api/audit/logs/prune", nil)
+	setAuthContext(c)
+	NewAuditHandler(db, 0).Prune(c)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("disabled retention: status = %d, want 400", w.Code)
+	}
+}
+
+func TestWriteAuditLog_StoresUserAgent(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupAuditTestDB(t, "audit_user_agent")
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("DELETE", "/api/hosts/3", nil)
+	c.Request.Header.Set("User-Agent", "Mozilla/5.0 (X11; Linux x86_64) webcasa-test")
+	c.Request.RemoteAddr = "203.0.113.7:51234"
+	WriteAuditLog(db, c, 1, "admin", "DELETE", "host", "3", "Deleted host")
+
+	var entry model.AuditLog
+	if err := db.First(&entry).Error; err != nil {
+		t.Fatal(err)
+	}
+	if entry.UserAgent != "Mozilla/5.0 (X11; Linux x86_64) webcasa-test" {
+		t.Errorf("user agent = %q", entry.UserAgent)
+	}
+	if entry.IP != "203.0.113.7" {
+		t.Errorf("ip = %q", entry.IP)
+	}
+}
+
+func TestAuditMutations_FillsGaps(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupAuditTestDB(t, "audit_middleware")
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("user_id", uint(4))
+		c.Set("username", "alice")
+	})
+	r.Use(AuditMutations(db))
+	// Un-instrumented mutation.
+	r.DELETE("/api/certs/:id", func(c *gin.Context) { c.Status(http.StatusOK) })
+	// Instrumented mutation: must not be logged twice.
+	r.POST("/api/hosts", func(c *gin.Context) {
+		WriteAuditLog(db, c, 4, "alice", "CREATE", "host", "1", "Created host")
+		c.Status(http.StatusCreated)
+	})
+	r.PUT("/api/settings", func(c *gin.Context) { c.Status(http.StatusBadRequest) })
+	r.GET("/api/hosts", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	do := func(method, path string) {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("User-Agent", "curl/8.0")
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	do("DELETE", "/api/certs/12")
+	var entry model.AuditLog
+	if err := db.Where("action = ?", "DELETE").First(&entry).Error; err != nil {
+		t.Fatal("un-instrumented delete should produce an audit entry")
+	}
+	if entry.Target != "certs" || entry.TargetID != "12" || entry.Username != "alice" || entry.UserID != 4 {
+		t.Errorf("generic entry = %+v", entry)
+	}
+	if entry.Detail != "DELETE /api/certs/12" || entry.UserAgent != "curl/8.0" {
+		t.Errorf("generic entry detail/ua = %q / %q", entry.Detail, entry.UserAgent)
+	}
+
+	do("POST", "/api/hosts")
+	do("PUT", "/api/settings") // failed: nothing changed
+	do("GET", "/api/hosts")    // read-only
+	if n := countAuditLogs(db); n != 2 {
+		t.Errorf("audit entries = %d, want 2 (generic delete + handler's create)", n)
+	}
+}
+
+func TestAuditMutations_PluginAuditNotDuplicated(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupAuditTestDB(t, "audit_plugin_route")
+	api := plugin.NewCoreAPI(db, nil, nil, t.TempDir())
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("user_id", uint(4))
+		c.Set("username", "alice")
+	})
+	r.Use(AuditMutations(db))
+	r.POST("/api/plugins/docker/stacks", func(c *gin.Context) {
+		api.WriteAuditLog(c, "CREATE", "docker_stack", "7", "Created stack web")
+		c.Status(http.StatusCreated)
+	})
+
+	req := httptest.NewRequest("POST", "/api/plugins/docker/stacks", nil)
+	req.Header.Set("User-Agent", "curl/8.0")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	if n := countAuditLogs(db); n != 1 {
+		t.Fatalf("audit entries = %d, want only the plugin's own", n)
+	}
+	var entry model.AuditLog
+	db.First(&entry)
+	if entry.Action != "CREATE" || entry.Target != "docker_stack" || entry.Username != "alice" || entry.UserAgent != "curl/8.0" {
+		t.Errorf("plugin entry = %+v", entry)
+	}
+}
+
+func TestAuditTarget(t *testing.T) {
+	for route, want := range map[string]string{
+		"/api/hosts/:id/cert":         "hosts",
+		"/api/plugins/docker/stacks":  "plugins/docker",
+		"/api/plugins/:id/enable":     "plugins",
+		"/api/audit/logs/prune":       "audit",
+		"":                            "unknown",
+	} {
+		if got := auditTarget(route); got != want {
+			t.Errorf("auditTarget(%q) = %q, want %q", route, got, want)
+		}
+	}
+}
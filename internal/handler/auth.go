@@ -3,10 +3,12 @@ package handler
 import (
 	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/web-casa/webcasa/internal/auth"
 	"github.com/web-casa/webcasa/internal/config"
+	"github.com/web-casa/webcasa/internal/metrics"
 	"github.com/web-casa/webcasa/internal/model"
 	"github.com/web-casa/webcasa/internal/service"
 	"gorm.io/gorm"
@@ -119,7 +121,7 @@ func (h *AuthHandler) Setup(c *gin.Context) {
 	}
 
 	h.limiters.Login.RecordSuccess(ip)
-	token, _ := auth.GenerateToken(user.ID, user.Username, h.cfg.JWTSecret, user.TokenVersion)
+	token, _ := auth.GenerateToken(user.ID, user.Username, user.Role, h.cfg.JWTSecret, user.TokenVersion)
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Admin user created successfully",
 		"token":   token,
@@ -151,8 +153,9 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	// Primary credentials path: Login bucket applies (5/15min).
-	allowed, waitSec := h.limiters.Login.Check(ip)
+	// Primary credentials path: Login bucket applies (5/15min) both per IP
+	// and per IP and username; a success clears only the latter.
+	allowed, waitSec := h.limiters.Login.CheckIPAndUser(ip, req.Username)
 	if !allowed {
 		c.JSON(http.StatusTooManyRequests, gin.H{
 			"error":       "Too many login attempts",
@@ -163,13 +166,13 @@ func (h *AuthHandler) Login(c *gin.Context) {
 
 	// Verify ALTCHA PoW challenge
 	if req.Altcha == "" {
-		h.limiters.Login.RecordFail(ip)
+		h.loginFailed(h.limiters.Login, ip, req.Username)
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Please complete security verification first"})
 		return
 	}
 	ok, err := auth.VerifyAltchaSolution(req.Altcha, h.cfg.JWTSecret)
 	if err != nil || !ok {
-		h.limiters.Login.RecordFail(ip)
+		h.loginFailed(h.limiters.Login, ip, req.Username)
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Verification failed, please try again"})
 		return
 	}
@@ -179,13 +182,13 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		// Run a dummy bcrypt comparison so an unknown username takes the same
 		// time as a known one, defeating timing-based username enumeration.
 		auth.CheckPassword(dummyBcryptHash, req.Password)
-		h.limiters.Login.RecordFail(ip)
+		h.loginFailed(h.limiters.Login, ip, req.Username)
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
 		return
 	}
 
 	if !auth.CheckPassword(user.Password, req.Password) {
-		h.limiters.Login.RecordFail(ip)
+		h.loginFailed(h.limiters.Login, ip, req.Username)
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
 		return
 	}
@@ -201,18 +204,27 @@ func (h *AuthHandler) Login(c *gin.Context) {
 				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate temp token"})
 				return
 			}
-			h.limiters.Login.RecordSuccess(ip)
-			c.JSON(http.StatusOK, gin.H{
-				"requires_2fa": true,
-				"temp_token":   tempToken,
-			})
+			h.limiters.Login.RecordSuccessUser(ip, req.Username)
+			resp := gin.H{
+				"requires_2fa":      true,
+				"temp_token":        tempToken,
+				"two_factor_method": service.TwoFactorTOTP,
+			}
+			// Email 2FA: send the code now. If delivery fails the user can
+			// still finish with a recovery code, so the temp token is issued
+			// either way.
+			if user.TwoFactorMethod == service.TwoFactorEmail {
+				resp["two_factor_method"] = service.TwoFactorEmail
+				resp["email_sent"] = h.totpSvc.SendEmailCode(user.ID) == nil
+			}
+			c.JSON(http.StatusOK, resp)
 			return
 		}
 
 		// 2FA enabled and code provided — validate
 		valid, err := h.totpSvc.ValidateLogin(user.ID, req.TOTPCode)
 		if err != nil || !valid {
-			h.limiters.Login.RecordFail(ip)
+			h.loginFailed(h.limiters.Login, ip, req.Username)
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"error":     "Invalid TOTP code",
 				"error_key": "error.invalid_totp",
@@ -221,13 +233,14 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		}
 	}
 
-	token, err := auth.GenerateToken(user.ID, user.Username, h.cfg.JWTSecret, user.TokenVersion)
+	token, err := auth.GenerateToken(user.ID, user.Username, user.Role, h.cfg.JWTSecret, user.TokenVersion)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
 	}
 
-	h.limiters.Login.RecordSuccess(ip)
+	h.limiters.Login.RecordSuccessUser(ip, req.Username)
+	metrics.LoginAttempts.WithLabelValues("success").Inc()
 	c.JSON(http.StatusOK, gin.H{
 		"token": token,
 		"user": gin.H{
@@ -237,13 +250,28 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	})
 }
 
+// loginFailed records a failed login step against limiter, for the IP and
+// for the IP and username, and in the login metrics.
+func (h *AuthHandler) loginFailed(limiter *auth.RateLimiter, ip, username string) {
+	limiter.RecordFailIPAndUser(ip, username)
+	metrics.LoginAttempts.WithLabelValues("failure").Inc()
+}
+
 // handleTempTokenLogin handles the second step of 2FA login using a temp token.
 // Uses the dedicated TOTP limiter (10/5min) so a user mistyping their 2FA code
 // does not burn through the stricter primary-login budget. Enforces TOTP.Check
 // at entry so repeated wrong codes actually hit the 10/5min ceiling (Login
 // limiter is not incremented by TOTP failures).
 func (h *AuthHandler) handleTempTokenLogin(c *gin.Context, ip string, req loginRequest) {
-	if allowed, waitSec := h.limiters.TOTP.Check(ip); !allowed {
+	// Parse the temp token first: its signed username keys the per-user
+	// TOTP bucket, checked alongside the IP one. Invalid tokens are counted
+	// against the IP alone.
+	claims, err := auth.ParseToken(req.TempToken, h.cfg.JWTSecret)
+	username := ""
+	if err == nil && claims.Pending2FA {
+		username = claims.Username
+	}
+	if allowed, waitSec := h.limiters.TOTP.CheckIPAndUser(ip, username); !allowed {
 		c.JSON(http.StatusTooManyRequests, gin.H{
 			"error":       "Too many 2FA attempts",
 			"retry_after": waitSec,
@@ -251,10 +279,8 @@ func (h *AuthHandler) handleTempTokenLogin(c *gin.Context, ip string, req loginR
 		return
 	}
 
-	// Parse and validate the temp token
-	claims, err := auth.ParseToken(req.TempToken, h.cfg.JWTSecret)
 	if err != nil {
-		h.limiters.TOTP.RecordFail(ip)
+		h.loginFailed(h.limiters.TOTP, ip, "")
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"error":     "Temp token expired or invalid",
 			"error_key": "error.temp_token_expired",
@@ -263,7 +289,7 @@ func (h *AuthHandler) handleTempTokenLogin(c *gin.Context, ip string, req loginR
 	}
 
 	if !claims.Pending2FA {
-		h.limiters.TOTP.RecordFail(ip)
+		h.loginFailed(h.limiters.TOTP, ip, "")
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"error":     "Invalid token type",
 			"error_key": "error.invalid_token",
@@ -283,8 +309,12 @@ func (h *AuthHandler) handleTempTokenLogin(c *gin.Context, ip string, req loginR
 	// records the TOTP timestep / permanently consumes a recovery code, so
 	// checking the version afterward would burn a valid code on a session that
 	// was revoked (password/role change, logout-all) during the 2FA step.
-	var tv int
-	h.db.Model(&model.User{}).Select("token_version").Where("id = ?", claims.UserID).Scan(&tv)
+	var current struct {
+		TokenVersion int
+		Role         string
+	}
+	h.db.Model(&model.User{}).Select("token_version, role").Where("id = ?", claims.UserID).Scan(&current)
+	tv := current.TokenVersion
 	if claims.TokenVersion != tv {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"error":     "Session expired, please log in again",
@@ -296,7 +326,7 @@ func (h *AuthHandler) handleTempTokenLogin(c *gin.Context, ip string, req loginR
 	// Validate the TOTP code or recovery code
 	valid, err := h.totpSvc.ValidateLogin(claims.UserID, req.TOTPCode)
 	if err != nil || !valid {
-		h.limiters.TOTP.RecordFail(ip)
+		h.loginFailed(h.limiters.TOTP, ip, claims.Username)
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"error":     "Invalid TOTP code",
 			"error_key": "error.invalid_totp",
@@ -306,14 +336,15 @@ func (h *AuthHandler) handleTempTokenLogin(c *gin.Context, ip string, req loginR
 
 	// Issue full JWT with the (already-validated) TokenVersion so revocation
 	// continues to invalidate this session.
-	token, err := auth.GenerateToken(claims.UserID, claims.Username, h.cfg.JWTSecret, tv)
+	token, err := auth.GenerateToken(claims.UserID, claims.Username, current.Role, h.cfg.JWTSecret, tv)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
 	}
 
-	h.limiters.TOTP.RecordSuccess(ip)
-	h.limiters.Login.RecordSuccess(ip)
+	h.limiters.TOTP.RecordSuccessUser(ip, claims.Username)
+	h.limiters.Login.RecordSuccessUser(ip, claims.Username)
+	metrics.LoginAttempts.WithLabelValues("success").Inc()
 	c.JSON(http.StatusOK, gin.H{
 		"token": token,
 		"user": gin.H{
@@ -325,8 +356,9 @@ func (h *AuthHandler) handleTempTokenLogin(c *gin.Context, ip string, req loginR
 
 // LogoutAll invalidates every outstanding JWT for the calling user by bumping
 // their TokenVersion. The caller's own current token is invalidated too, so the
-// client must log in again. Route (wire in main.go): POST /auth/logout-all,
-// behind the JWT auth middleware.
+// client must log in again. Routes (wired in main.go): POST
+// /auth/revoke-sessions and its older alias POST /auth/logout-all, behind the
+// JWT auth middleware.
 func (h *AuthHandler) LogoutAll(c *gin.Context) {
 	userID, ok := c.Get("user_id")
 	if !ok {
@@ -342,15 +374,105 @@ func (h *AuthHandler) LogoutAll(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "All sessions revoked"})
 }
 
+// minPasswordLength matches the min=8 binding on setup and user creation.
+const minPasswordLength = 8
+
+// ChangePassword lets the logged-in user replace their own password after
+// re-entering the current one. All other sessions are revoked; the caller
+// receives a fresh token so their own session continues.
+func (h *AuthHandler) ChangePassword(c *gin.Context) {
+	ip := c.ClientIP()
+	username := c.GetString("username")
+	allowed, waitSec := h.limiters.Login.CheckIPAndUser(ip, username)
+	if !allowed {
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"error":       "Too many attempts",
+			"retry_after": waitSec,
+		})
+		return
+	}
+
+	var req struct {
+		OldPassword string `json:"old_password" binding:"required"`
+		NewPassword string `json:"new_password" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "error_key": "error.invalid_request"})
+		return
+	}
+	if len(req.NewPassword) < minPasswordLength {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":     fmt.Sprintf("password must be at least %d characters", minPasswordLength),
+			"error_key": "error.password_too_short",
+		})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	var user model.User
+	if err := h.db.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	// 400 rather than 401: the session is valid, and the frontend treats any
+	// 401 as a logout.
+	if !auth.CheckPassword(user.Password, req.OldPassword) {
+		h.limiters.Login.RecordFailIPAndUser(ip, username)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":     "Current password is incorrect",
+			"error_key": "error.invalid_credentials",
+		})
+		return
+	}
+
+	hashed, err := auth.HashPassword(req.NewPassword)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		return
+	}
+	user.Password = hashed
+	user.TokenVersion++
+	if err := h.db.Model(&user).Updates(map[string]interface{}{
+		"password":      user.Password,
+		"token_version": user.TokenVersion,
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update password"})
+		return
+	}
+
+	h.limiters.Login.RecordSuccessUser(ip, username)
+	h.audit(c, "UPDATE", "Changed own password")
+
+	token, err := auth.GenerateToken(user.ID, user.Username, user.Role, h.cfg.JWTSecret, user.TokenVersion)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Password changed", "token": token})
+}
+
 // Me returns the current authenticated user info
 func (h *AuthHandler) Me(c *gin.Context) {
 	userID, _ := c.Get("user_id")
 	username, _ := c.Get("username")
 
-	c.JSON(http.StatusOK, gin.H{
+	resp := gin.H{
 		"id":       userID,
 		"username": username,
-	})
+	}
+	// 2FA status, so users can regenerate recovery codes before running out.
+	// Only the count is exposed, never the stored hashes.
+	var user model.User
+	if err := h.db.Select("totp_enabled", "recovery_codes", "two_factor_method").First(&user, userID).Error; err == nil {
+		enabled := user.TOTPEnabled != nil && *user.TOTPEnabled
+		resp["totp_enabled"] = enabled
+		resp["recovery_codes_remaining"] = 0
+		if enabled {
+			resp["recovery_codes_remaining"] = service.RemainingRecoveryCodes(user.RecoveryCodes)
+			resp["two_factor_method"] = user.TwoFactorMethod
+		}
+	}
+	c.JSON(http.StatusOK, resp)
 }
 
 // NeedSetup checks if initial setup is required
@@ -363,7 +485,7 @@ func (h *AuthHandler) NeedSetup(c *gin.Context) {
 func (h *AuthHandler) audit(c *gin.Context, action, detail string) {
 	if uid, ok := c.Get("user_id"); ok {
 		uname, _ := c.Get("username")
-		WriteAuditLog(h.db, uid.(uint), fmt.Sprint(uname), action, "user", fmt.Sprint(uid), detail, c.ClientIP())
+		WriteAuditLog(h.db, c, uid.(uint), fmt.Sprint(uname), action, "user", fmt.Sprint(uid), detail)
 	}
 }
 
@@ -451,3 +573,144 @@ func (h *AuthHandler) Disable2FA(c *gin.Context) {
 		"message": "2FA disabled successfully",
 	})
 }
+
+// SetupEmail2FA stores the address for email 2FA and sends it a confirmation
+// code.
+func (h *AuthHandler) SetupEmail2FA(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req struct {
+		Email string `json:"email" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "error_key": "error.invalid_request"})
+		return
+	}
+
+	if err := h.totpSvc.SetupEmail(userID.(uint), req.Email); err != nil {
+		h.email2FAError(c, err, "error.2fa_setup_failed")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Verification code sent"})
+}
+
+// VerifyEmail2FA confirms the emailed code and enables email 2FA, returning
+// recovery codes.
+func (h *AuthHandler) VerifyEmail2FA(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req struct {
+		Code string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "error_key": "error.invalid_request"})
+		return
+	}
+
+	codes, err := h.totpSvc.VerifyEmailAndEnable(userID.(uint), req.Code)
+	if err != nil {
+		h.email2FAError(c, err, "error.2fa_verify_failed")
+		return
+	}
+
+	h.audit(c, "ENABLE_2FA", "Enabled email 2FA")
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "2FA enabled successfully",
+		"recovery_codes": codes,
+	})
+}
+
+// SendEmail2FACode emails a fresh code to a signed-in user with email 2FA,
+// for confirming Disable2FA or RegenerateRecoveryCodes.
+func (h *AuthHandler) SendEmail2FACode(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	if err := h.totpSvc.SendEmailCode(userID.(uint)); err != nil {
+		h.email2FAError(c, err, "error.email_send_failed")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Verification code sent"})
+}
+
+// email2FAError maps email 2FA service errors to a 400 with an error key.
+func (h *AuthHandler) email2FAError(c *gin.Context, err error, fallbackKey string) {
+	errMsg := err.Error()
+	errKey := fallbackKey
+	switch errMsg {
+	case "error.invalid_totp", "error.invalid_email", "error.2fa_already_enabled",
+		"error.2fa_not_enabled", "error.2fa_not_setup", "error.email_send_failed":
+		errKey = errMsg
+	}
+	c.JSON(http.StatusBadRequest, gin.H{
+		"error":     errMsg,
+		"error_key": errKey,
+	})
+}
+
+// RegenerateRecoveryCodes verifies a TOTP code and issues a fresh set of
+// recovery codes, invalidating the old ones.
+func (h *AuthHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req struct {
+		Code string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "error_key": "error.invalid_request"})
+		return
+	}
+
+	codes, err := h.totpSvc.RegenerateRecoveryCodes(userID.(uint), req.Code)
+	if err != nil {
+		errMsg := err.Error()
+		errKey := "error.2fa_recovery_regenerate_failed"
+		switch errMsg {
+		case "error.invalid_totp":
+			errKey = "error.invalid_totp"
+		case "error.2fa_not_enabled":
+			errKey = "error.2fa_not_enabled"
+		}
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":     errMsg,
+			"error_key": errKey,
+		})
+		return
+	}
+
+	h.audit(c, "REGENERATE_RECOVERY_CODES", "Regenerated 2FA recovery codes")
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "Recovery codes regenerated",
+		"recovery_codes": codes,
+	})
+}
+
+// ListLockouts returns clients with recent failed logins, per limiter.
+func (h *AuthHandler) ListLockouts(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"login": h.limiters.Login.Entries(),
+		"totp":  h.limiters.TOTP.Entries(),
+	})
+}
+
+// ClearLockout forgets the failed attempts recorded for ?ip=, unlocking it.
+// With ?username= only the attempts against that account are cleared.
+func (h *AuthHandler) ClearLockout(c *gin.Context) {
+	ip := strings.TrimSpace(c.Query("ip"))
+	if ip == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ip is required"})
+		return
+	}
+	target := ip
+	if username, ok := c.GetQuery("username"); ok {
+		h.limiters.Login.ClearUser(ip, username)
+		h.limiters.TOTP.ClearUser(ip, username)
+		target = username + "@" + ip
+	} else {
+		h.limiters.Login.Clear(ip)
+		h.limiters.TOTP.Clear(ip)
+	}
+	uid, _ := c.Get("user_id")
+	uname, _ := c.Get("username")
+	userID, _ := uid.(uint)
+	WriteAuditLog(h.db, c, userID, fmt.Sprint(uname), "DELETE", "lockout", "", "Cleared login lockout for "+target)
+	c.JSON(http.StatusOK, gin.H{"message": "Lockout cleared"})
+}
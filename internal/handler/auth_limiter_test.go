@@ -3,6 +3,7 @@ package handler
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -82,25 +83,113 @@ func TestLogin_TempTokenPath_NotBlockedByLoginLimiter(t *testing.T) {
 }
 
 // TestLogin_PrimaryPath_StillBlockedByLoginLimiter verifies the inverse:
-// primary credential requests are still gated by the Login bucket.
+// primary credential requests are still gated by the Login bucket, keyed
+// on IP and username so other accounts on the same IP can still log in.
 func TestLogin_PrimaryPath_StillBlockedByLoginLimiter(t *testing.T) {
 	r, h := setupAuthLimiterTest(t)
 
 	for i := 0; i < 6; i++ {
-		h.limiters.Login.RecordFail("198.51.100.5")
+		h.limiters.Login.RecordFailUser("198.51.100.5", "anyone")
 	}
 
-	body, _ := json.Marshal(map[string]string{
-		"username": "anyone",
-		"password": "whatever",
-	})
+	login := func(username string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(map[string]string{
+			"username": username,
+			"password": "whatever",
+		})
+		req := httptest.NewRequest("POST", "/login", bytes.NewReader(body))
+		req.RemoteAddr = "198.51.100.5:2222"
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := login("anyone"); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("primary login should be 429 when Login bucket exhausted, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := login("someone-else"); w.Code == http.StatusTooManyRequests {
+		t.Fatalf("another username on the same IP should not be locked out: %s", w.Body.String())
+	}
+}
+
+// TestLogin_IPLockedAcrossUsernames checks that the per-IP bucket still
+// applies next to the per-username one: spraying guesses over many
+// usernames from one IP locks that IP out for every username.
+func TestLogin_IPLockedAcrossUsernames(t *testing.T) {
+	r, h := setupAuthLimiterTest(t)
+	const ip = "198.51.100.9"
+	for i := 0; i < 5; i++ {
+		h.loginFailed(h.limiters.Login, ip, fmt.Sprintf("user%d", i))
+	}
+
+	body, _ := json.Marshal(map[string]string{"username": "fresh-user", "password": "whatever"})
 	req := httptest.NewRequest("POST", "/login", bytes.NewReader(body))
-	req.RemoteAddr = "198.51.100.5:2222"
+	req.RemoteAddr = ip + ":3333"
 	req.Header.Set("Content-Type", "application/json")
 	w := httptest.NewRecorder()
 	r.ServeHTTP(w, req)
-
 	if w.Code != http.StatusTooManyRequests {
-		t.Fatalf("primary login should be 429 when Login bucket exhausted, got %d: %s", w.Code, w.Body.String())
+		t.Fatalf("IP that failed across 5 usernames: status %d, want 429: %s", w.Code, w.Body.String())
+	}
+
+	// A success clears only the pair it logged in as, not the IP bucket.
+	h.limiters.Login.RecordSuccessUser(ip, "user0")
+	if allowed, _ := h.limiters.Login.CheckIPAndUser(ip, "user0"); allowed {
+		t.Error("a success for one username must not lift the IP lockout")
+	}
+}
+
+func TestLockouts_ListAndClear(t *testing.T) {
+	_, h := setupAuthLimiterTest(t)
+	if err := h.db.AutoMigrate(&model.AuditLog{}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		h.limiters.Login.RecordFailUser("203.0.113.9", "admin")
+	}
+
+	r := gin.New()
+	r.GET("/auth/lockouts", h.ListLockouts)
+	r.DELETE("/auth/lockouts", h.ClearLockout)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/auth/lockouts", nil))
+	var list struct {
+		Login []auth.LockoutEntry `json:"login"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &list)
+	if w.Code != http.StatusOK || len(list.Login) != 1 || !list.Login[0].Locked || list.Login[0].IP != "203.0.113.9" || list.Login[0].Username != "admin" {
+		t.Fatalf("list = %d %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("DELETE", "/auth/lockouts", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("clear without ip: status = %d, want 400", w.Code)
+	}
+
+	for i := 0; i < 5; i++ {
+		h.limiters.Login.RecordFailUser("203.0.113.9", "bob")
+	}
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("DELETE", "/auth/lockouts?ip=203.0.113.9&username=bob", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("clear username: status = %d", w.Code)
+	}
+	if allowed, _ := h.limiters.Login.CheckUser("203.0.113.9", "bob"); !allowed {
+		t.Error("cleared username should be able to log in again")
+	}
+	if allowed, _ := h.limiters.Login.CheckUser("203.0.113.9", "admin"); allowed {
+		t.Error("clearing one username must keep the others locked")
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("DELETE", "/auth/lockouts?ip=203.0.113.9", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("clear: status = %d", w.Code)
+	}
+	if allowed, _ := h.limiters.Login.CheckUser("203.0.113.9", "admin"); !allowed {
+		t.Error("cleared IP should be able to log in again")
 	}
 }
@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/web-casa/webcasa/internal/auth"
+	"github.com/web-casa/webcasa/internal/model"
+)
+
+// changePassword posts to ChangePassword as the given user.
+func changePassword(t *testing.T, h *AuthHandler, user model.User, oldPw, newPw string) (*httptest.ResponseRecorder, map[string]interface{}) {
+	t.Helper()
+	body, _ := json.Marshal(map[string]string{"old_password": oldPw, "new_password": newPw})
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("POST", "/auth/change-password", bytes.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Set("user_id", user.ID)
+	c.Set("username", user.Username)
+	h.ChangePassword(c)
+
+	var resp map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	return w, resp
+}
+
+func setupPasswordTest(t *testing.T) (*AuthHandler, model.User) {
+	t.Helper()
+	_, h := setupAuthLimiterTest(t)
+	if err := h.db.AutoMigrate(&model.AuditLog{}); err != nil {
+		t.Fatal(err)
+	}
+	hashed, _ := auth.HashPassword("old-password")
+	user := model.User{Username: "alice", Password: hashed, Role: auth.RoleViewer}
+	if err := h.db.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	return h, user
+}
+
+func TestChangePassword_Success(t *testing.T) {
+	h, user := setupPasswordTest(t)
+
+	w, resp := changePassword(t, h, user, "old-password", "brand-new-password")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+
+	var saved model.User
+	h.db.First(&saved, user.ID)
+	if !auth.CheckPassword(saved.Password, "brand-new-password") {
+		t.Error("new password should be stored")
+	}
+	if auth.CheckPassword(saved.Password, "old-password") {
+		t.Error("old password should no longer work")
+	}
+	if saved.TokenVersion != user.TokenVersion+1 {
+		t.Errorf("token version = %d, want bump to revoke other sessions", saved.TokenVersion)
+	}
+
+	claims, err := auth.ParseToken(resp["token"].(string), h.cfg.JWTSecret)
+	if err != nil || claims.TokenVersion != saved.TokenVersion {
+		t.Errorf("fresh token should carry the new token version: %+v, %v", claims, err)
+	}
+
+	var entry model.AuditLog
+	if err := h.db.Where("target = ? AND user_id = ?", "user", user.ID).First(&entry).Error; err != nil {
+		t.Error("password change should be audited")
+	}
+}
+
+func TestChangePassword_WrongOldPassword(t *testing.T) {
+	h, user := setupPasswordTest(t)
+
+	w, resp := changePassword(t, h, user, "not-my-password", "brand-new-password")
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", w.Code)
+	}
+	if resp["error_key"] != "error.invalid_credentials" {
+		t.Errorf("error_key = %v", resp["error_key"])
+	}
+
+	var saved model.User
+	h.db.First(&saved, user.ID)
+	if !auth.CheckPassword(saved.Password, "old-password") || saved.TokenVersion != user.TokenVersion {
+		t.Error("a rejected change must leave the account untouched")
+	}
+}
+
+func TestChangePassword_TooShort(t *testing.T) {
+	h, user := setupPasswordTest(t)
+
+	w, resp := changePassword(t, h, user, "old-password", "short")
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", w.Code)
+	}
+	if resp["error_key"] != "error.password_too_short" {
+		t.Errorf("error_key = %v", resp["error_key"])
+	}
+
+	var saved model.User
+	h.db.First(&saved, user.ID)
+	if !auth.CheckPassword(saved.Password, "old-password") {
+		t.Error("password should be unchanged")
+	}
+}
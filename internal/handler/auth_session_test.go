@@ -0,0 +1,147 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+	"github.com/web-casa/webcasa/internal/auth"
+	"github.com/web-casa/webcasa/internal/model"
+)
+
+// sessionRouter wires the session routes behind the real auth middleware.
+func sessionRouter(h *AuthHandler) *gin.Engine {
+	r := gin.New()
+	protected := r.Group("/api")
+	protected.Use(auth.Middleware(h.cfg.JWTSecret, auth.WithDB(h.db)))
+	protected.GET("/auth/me", h.Me)
+	protected.POST("/auth/revoke-sessions", h.LogoutAll)
+	protected.POST("/auth/change-password", h.ChangePassword)
+	return r
+}
+
+func sessionRequest(r *gin.Engine, method, path, token string, body []byte) int {
+	req := httptest.NewRequest(method, path, bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w.Code
+}
+
+// issueToken mints a token for the user's current stored token version.
+func issueToken(t *testing.T, h *AuthHandler, userID uint) string {
+	t.Helper()
+	var u model.User
+	h.db.First(&u, userID)
+	tok, err := auth.GenerateToken(u.ID, u.Username, u.Role, h.cfg.JWTSecret, u.TokenVersion)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tok
+}
+
+func TestRevokeSessions_StaleTokenRejected(t *testing.T) {
+	h, user := setupPasswordTest(t)
+	r := sessionRouter(h)
+
+	laptop := issueToken(t, h, user.ID)
+	phone := issueToken(t, h, user.ID)
+	if code := sessionRequest(r, "GET", "/api/auth/me", phone, nil); code != http.StatusOK {
+		t.Fatalf("token before revocation: status = %d", code)
+	}
+
+	if code := sessionRequest(r, "POST", "/api/auth/revoke-sessions", laptop, nil); code != http.StatusOK {
+		t.Fatalf("revoke-sessions: status = %d", code)
+	}
+
+	for name, tok := range map[string]string{"laptop": laptop, "phone": phone} {
+		if code := sessionRequest(r, "GET", "/api/auth/me", tok, nil); code != http.StatusUnauthorized {
+			t.Errorf("%s token after revocation: status = %d, want 401", name, code)
+		}
+	}
+
+	fresh := issueToken(t, h, user.ID)
+	if code := sessionRequest(r, "GET", "/api/auth/me", fresh, nil); code != http.StatusOK {
+		t.Errorf("fresh token: status = %d, want 200", code)
+	}
+}
+
+func TestChangePassword_RevokesOtherSessions(t *testing.T) {
+	h, user := setupPasswordTest(t)
+	r := sessionRouter(h)
+
+	other := issueToken(t, h, user.ID)
+	current := issueToken(t, h, user.ID)
+	body := []byte(`{"old_password":"old-password","new_password":"brand-new-password"}`)
+	if code := sessionRequest(r, "POST", "/api/auth/change-password", current, body); code != http.StatusOK {
+		t.Fatalf("change-password: status = %d", code)
+	}
+
+	if code := sessionRequest(r, "GET", "/api/auth/me", other, nil); code != http.StatusUnauthorized {
+		t.Errorf("token issued before the password change: status = %d, want 401", code)
+	}
+	if code := sessionRequest(r, "GET", "/api/auth/me", issueToken(t, h, user.ID), nil); code != http.StatusOK {
+		t.Errorf("fresh token: status = %d, want 200", code)
+	}
+}
+
+func TestMe_RecoveryCodesRemaining(t *testing.T) {
+	h, user := setupPasswordTest(t)
+	r := sessionRouter(h)
+
+	me := func() map[string]interface{} {
+		t.Helper()
+		req := httptest.NewRequest("GET", "/api/auth/me", nil)
+		req.Header.Set("Authorization", "Bearer "+issueToken(t, h, user.ID))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("me: status = %d", w.Code)
+		}
+		if strings.Contains(w.Body.String(), "$2a$") {
+			t.Fatalf("me leaks recovery code hashes: %s", w.Body.String())
+		}
+		var resp map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return resp
+	}
+
+	if resp := me(); resp["totp_enabled"] != false || resp["recovery_codes_remaining"] != float64(0) {
+		t.Errorf("before 2FA: %v", resp)
+	}
+
+	uri, err := h.totpSvc.GenerateSecret(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := otp.NewKeyFromURL(uri)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, _ := totp.GenerateCode(key.Secret(), time.Now())
+	codes, err := h.totpSvc.VerifyAndEnable(user.ID, code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, rc := range codes[:2] {
+		if ok, err := h.totpSvc.ValidateLogin(user.ID, rc); err != nil || !ok {
+			t.Fatalf("consume recovery code: ok=%v err=%v", ok, err)
+		}
+	}
+
+	resp := me()
+	if resp["totp_enabled"] != true {
+		t.Errorf("totp_enabled = %v, want true", resp["totp_enabled"])
+	}
+	if resp["recovery_codes_remaining"] != float64(6) {
+		t.Errorf("recovery_codes_remaining = %v, want 6", resp["recovery_codes_remaining"])
+	}
+}
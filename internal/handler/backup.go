@@ -0,0 +1,123 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/web-casa/webcasa/internal/model"
+	"github.com/web-casa/webcasa/internal/service"
+	"gorm.io/gorm"
+)
+
+// maxBackupUploadBytes caps the request body accepted by Restore.
+var maxBackupUploadBytes int64 = 1 << 30
+
+// configApplier re-renders the Caddyfile and reloads Caddy.
+type configApplier interface {
+	ApplyConfig() (model.ApplyResult, error)
+}
+
+// BackupHandler serves full panel backups (database, Caddyfile and
+// certificates).
+type BackupHandler struct {
+	svc     *service.BackupService
+	db      *gorm.DB
+	applier configApplier
+}
+
+// NewBackupHandler creates a new BackupHandler. applier reloads Caddy after
+// a restore.
+func NewBackupHandler(svc *service.BackupService, db *gorm.DB, applier configApplier) *BackupHandler {
+	return &BackupHandler{svc: svc, db: db, applier: applier}
+}
+
+// Now POST /api/backup/now
+// Writes a backup archive immediately.
+func (h *BackupHandler) Now(c *gin.Context) {
+	backup, err := h.svc.Create()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "error_key": "error.backup_failed"})
+		return
+	}
+	c.JSON(http.StatusOK, backup)
+}
+
+// List GET /api/backup/list
+// Returns the stored archives, newest first.
+func (h *BackupHandler) List(c *gin.Context) {
+	backups, err := h.svc.List()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"backups": backups})
+}
+
+// Download GET /api/backup/download/:id
+func (h *BackupHandler) Download(c *gin.Context) {
+	path, err := h.svc.Path(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Backup not found", "error_key": err.Error()})
+		return
+	}
+	c.FileAttachment(path, c.Param("id"))
+}
+
+// Restore POST /api/backup/restore (multipart: file, confirm=true)
+// Replaces the database, Caddyfile and certificates with an uploaded archive
+// and reloads Caddy. All panel data written since the backup is lost, so the
+// request must set confirm=true.
+func (h *BackupHandler) Restore(c *gin.Context) {
+	// Cap the body before anything parses the multipart form.
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBackupUploadBytes)
+	if err := c.Request.ParseMultipartForm(32 << 20); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Backup archive is too large", "error_key": "error.backup_too_large"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No file uploaded", "error_key": "error.invalid_request"})
+		return
+	}
+	if c.PostForm("confirm") != "true" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Restoring a backup overwrites all panel data; set confirm=true", "error_key": "error.backup_confirm_required"})
+		return
+	}
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No file uploaded", "error_key": "error.invalid_request"})
+		return
+	}
+	defer file.Close()
+
+	// Capture the caller before the users table is replaced.
+	uid, _ := c.Get("user_id")
+	uname, _ := c.Get("username")
+
+	if err := h.svc.Restore(file); err != nil {
+		if errors.Is(err, service.ErrBackupEntryNotAllowed) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "error_key": "error.backup_entry_not_allowed"})
+			return
+		}
+		if errors.Is(err, service.ErrInvalidBackupArchive) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "error_key": "error.backup_invalid"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "error_key": "error.backup_restore_failed"})
+		return
+	}
+
+	userID, _ := uid.(uint)
+	WriteAuditLog(h.db, c, userID, fmt.Sprint(uname), "RESTORE", "backup", "",
+		fmt.Sprintf("Restored panel backup from %s", header.Filename))
+
+	reloaded := true
+	if _, err := h.applier.ApplyConfig(); err != nil {
+		log.Printf("⚠️  Apply config after restore: %v", err)
+		reloaded = false
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Backup restored", "reloaded": reloaded})
+}
@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/web-casa/webcasa/internal/config"
+	"github.com/web-casa/webcasa/internal/model"
+	"github.com/web-casa/webcasa/internal/service"
+)
+
+type fakeApplier struct{ calls int }
+
+func (f *fakeApplier) ApplyConfig() (model.ApplyResult, error) {
+	f.calls++
+	return model.ApplyResult{Written: true, Reloaded: true}, nil
+}
+
+// gzipTar builds a tar.gz holding files.
+func gzipTar(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, body := range files {
+		tw.WriteHeader(&tar.Header{Name: name, Mode: 0600, Size: int64(len(body)), Typeflag: tar.TypeReg})
+		tw.Write([]byte(body))
+	}
+	tw.Close()
+	gz.Close()
+	return buf.Bytes()
+}
+
+func restoreRequest(t *testing.T, h *BackupHandler, confirm string, archive []byte) (int, map[string]interface{}) {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	if confirm != "" {
+		mw.WriteField("confirm", confirm)
+	}
+	fw, _ := mw.CreateFormFile("file", "backup.tar.gz")
+	fw.Write(archive)
+	mw.Close()
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("POST", "/api/backup/restore", &body)
+	c.Request.Header.Set("Content-Type", mw.FormDataContentType())
+	c.Set("user_id", uint(1))
+	c.Set("username", "admin")
+	h.Restore(c)
+
+	var resp map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	return w.Code, resp
+}
+
+func TestBackupRestore_RequiresConfirmation(t *testing.T) {
+	db := openHealthTestDB(t)
+	dir := t.TempDir()
+	applier := &fakeApplier{}
+	h := NewBackupHandler(service.NewBackupService(db, &config.Config{DataDir: dir, BackupDir: dir}), db, applier)
+
+	code, resp := restoreRequest(t, h, "", []byte("x"))
+	if code != http.StatusBadRequest || resp["error_key"] != "error.backup_confirm_required" {
+		t.Errorf("without confirm: %d %v", code, resp)
+	}
+	code, resp = restoreRequest(t, h, "true", []byte("not an archive"))
+	if code != http.StatusBadRequest || resp["error_key"] != "error.backup_invalid" {
+		t.Errorf("malformed archive: %d %v", code, resp)
+	}
+	code, resp = restoreRequest(t, h, "true", gzipTar(t, map[string]string{"webcasa.db": "x", ".jwt_secret": "x"}))
+	if code != http.StatusBadRequest || resp["error_key"] != "error.backup_entry_not_allowed" {
+		t.Errorf("archive with a disallowed file: %d %v", code, resp)
+	}
+	if applier.calls != 0 {
+		t.Error("Caddy must not be reloaded when nothing was restored")
+	}
+}
+
+func TestBackupRestore_RejectsOversizedUpload(t *testing.T) {
+	db := openHealthTestDB(t)
+	dir := t.TempDir()
+	applier := &fakeApplier{}
+	h := NewBackupHandler(service.NewBackupService(db, &config.Config{DataDir: dir, BackupDir: dir}), db, applier)
+
+	defer func(n int64) { maxBackupUploadBytes = n }(maxBackupUploadBytes)
+	maxBackupUploadBytes = 1024
+	code, resp := restoreRequest(t, h, "true", bytes.Repeat([]byte("x"), 4096))
+	if code != http.StatusRequestEntityTooLarge || resp["error_key"] != "error.backup_too_large" {
+		t.Errorf("oversized upload: %d %v", code, resp)
+	}
+	if applier.calls != 0 {
+		t.Error("Caddy must not be reloaded when nothing was restored")
+	}
+}
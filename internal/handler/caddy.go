@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 
@@ -23,7 +24,7 @@ func NewCaddyHandler(mgr *caddy.Manager, db *gorm.DB) *CaddyHandler {
 func (h *CaddyHandler) audit(c *gin.Context, action, detail string) {
 	if uid, ok := c.Get("user_id"); ok {
 		uname, _ := c.Get("username")
-		WriteAuditLog(h.db, uid.(uint), fmt.Sprint(uname), action, "caddy", "", detail, c.ClientIP())
+		WriteAuditLog(h.db, c, uid.(uint), fmt.Sprint(uname), action, "caddy", "", detail)
 	}
 }
 
@@ -33,6 +34,24 @@ func (h *CaddyHandler) Status(c *gin.Context) {
 	c.JSON(http.StatusOK, status)
 }
 
+// MetricsSummary returns request counts, latency and upstream health parsed
+// from Caddy's own Prometheus metrics.
+func (h *CaddyHandler) MetricsSummary(c *gin.Context) {
+	summary, err := h.mgr.Metrics(c.Request.Context())
+	if errors.Is(err, caddy.ErrNotRunning) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":     "Caddy is not running",
+			"error_key": "error.caddy_not_running",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, summary)
+}
+
 // Start starts the Caddy process
 func (h *CaddyHandler) Start(c *gin.Context) {
 	if err := h.mgr.Start(); err != nil {
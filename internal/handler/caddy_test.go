@@ -0,0 +1,26 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/web-casa/webcasa/internal/caddy"
+	"github.com/web-casa/webcasa/internal/config"
+)
+
+func TestCaddyMetricsSummary_NotRunning(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	adminAPI := srv.URL
+	srv.Close()
+
+	h := NewCaddyHandler(caddy.NewManager(&config.Config{AdminAPI: adminAPI}), nil)
+	r := gin.New()
+	r.GET("/caddy/metrics/summary", h.MetricsSummary)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/caddy/metrics/summary", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want 503", w.Code)
+	}
+}
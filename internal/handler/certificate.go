@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"bytes"
 	"crypto/x509"
 	"encoding/pem"
 	"fmt"
@@ -116,7 +117,14 @@ func (h *CertificateHandler) Upload(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{"message": "Certificate uploaded", "certificate": cert})
+	resp := gin.H{"message": "Certificate uploaded", "certificate": cert}
+	// A bundle without its intermediates is still accepted: some clients
+	// fetch the chain themselves, but many report the site as untrusted.
+	if certChainIncomplete(certData) {
+		resp["cert_chain_incomplete"] = true
+		resp["warning"] = "certificate chain looks incomplete: include the intermediate certificates after the leaf"
+	}
+	c.JSON(http.StatusCreated, resp)
 }
 
 // Delete removes a certificate
@@ -176,6 +184,49 @@ func parseCertInfo(certData []byte) (string, *time.Time) {
 	return strings.Join(domains, ", "), &expires
 }
 
+// certChainIncomplete reports whether a PEM bundle looks like a bare leaf:
+// no certificate in the bundle issued the leaf and the leaf does not verify
+// against the system roots on its own. Self-signed certificates and data
+// without parsable certificates are not flagged.
+func certChainIncomplete(certData []byte) bool {
+	var certs []*x509.Certificate
+	for rest := certData; ; {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		if cert, err := x509.ParseCertificate(block.Bytes); err == nil {
+			certs = append(certs, cert)
+		}
+	}
+	if len(certs) == 0 {
+		return false
+	}
+
+	leaf := certs[0]
+	if bytes.Equal(leaf.RawIssuer, leaf.RawSubject) &&
+		leaf.CheckSignature(leaf.SignatureAlgorithm, leaf.RawTBSCertificate, leaf.Signature) == nil {
+		return false
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		if leaf.CheckSignatureFrom(cert) == nil {
+			return false
+		}
+		intermediates.AddCert(cert)
+	}
+	// A leaf issued straight by a trusted root needs no intermediate.
+	_, err := leaf.Verify(x509.VerifyOptions{
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	return err != nil
+}
+
 func readMultipartFile(header *multipart.FileHeader) ([]byte, error) {
 	src, err := header.Open()
 	if err != nil {
@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/web-casa/webcasa/internal/config"
+	"github.com/web-casa/webcasa/internal/model"
+)
+
+// issueCert creates a certificate for subject signed by parent (self-signed
+// when parent is nil) and returns it PEM-encoded with its key.
+func issueCert(t *testing.T, subject string, isCA bool, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey, []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: subject},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		IsCA:                  isCA,
+		BasicConstraintsValid: true,
+	}
+	if isCA {
+		tmpl.KeyUsage = x509.KeyUsageCertSign
+	} else {
+		tmpl.DNSNames = []string{subject}
+	}
+	if parent == nil {
+		parent, parentKey = tmpl, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return cert, key, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func uploadCertificate(t *testing.T, h *CertificateHandler, certPEM []byte) (int, map[string]interface{}) {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	mw.WriteField("name", "test")
+	fw, _ := mw.CreateFormFile("cert", "cert.pem")
+	fw.Write(certPEM)
+	fw, _ = mw.CreateFormFile("key", "key.pem")
+	fw.Write([]byte("key"))
+	mw.Close()
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("POST", "/api/certificates", &body)
+	c.Request.Header.Set("Content-Type", mw.FormDataContentType())
+	h.Upload(c)
+
+	var resp map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	return w.Code, resp
+}
+
+func TestCertificateUpload_ChainWarning(t *testing.T) {
+	db := openHealthTestDB(t)
+	if err := db.AutoMigrate(&model.Certificate{}); err != nil {
+		t.Fatal(err)
+	}
+	h := NewCertificateHandler(db, &config.Config{DataDir: t.TempDir()})
+
+	root, rootKey, _ := issueCert(t, "Test Root CA", true, nil, nil)
+	inter, interKey, interPEM := issueCert(t, "Test Intermediate CA", true, root, rootKey)
+	_, _, leafPEM := issueCert(t, "shop.example.com", false, inter, interKey)
+
+	code, resp := uploadCertificate(t, h, append(append([]byte{}, leafPEM...), interPEM...))
+	if code != http.StatusCreated {
+		t.Fatalf("full chain upload: %d %v", code, resp)
+	}
+	if _, warned := resp["cert_chain_incomplete"]; warned {
+		t.Errorf("full chain flagged as incomplete: %v", resp)
+	}
+
+	code, resp = uploadCertificate(t, h, leafPEM)
+	if code != http.StatusCreated {
+		t.Fatalf("leaf-only upload must still be accepted: %d %v", code, resp)
+	}
+	if resp["cert_chain_incomplete"] != true {
+		t.Errorf("leaf-only cert not flagged: %v", resp)
+	}
+
+	_, _, selfPEM := issueCert(t, "self.example.com", false, nil, nil)
+	if _, resp = uploadCertificate(t, h, selfPEM); resp["cert_chain_incomplete"] != nil {
+		t.Errorf("self-signed cert flagged as incomplete: %v", resp)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/web-casa/webcasa/internal/caddy"
+	"github.com/web-casa/webcasa/internal/config"
 	"github.com/web-casa/webcasa/internal/service"
 	"github.com/gin-gonic/gin"
 	"github.com/shirou/gopsutil/v4/cpu"
@@ -20,10 +21,23 @@ type DashboardHandler struct {
 	hostSvc  *service.HostService
 	caddyMgr *caddy.Manager
 	version  string
+	dataDir  string
+	traffic  *trafficCache
+	system   systemCollector
 }
 
-func NewDashboardHandler(hostSvc *service.HostService, caddyMgr *caddy.Manager, version string) *DashboardHandler {
-	return &DashboardHandler{hostSvc: hostSvc, caddyMgr: caddyMgr, version: version}
+// NewDashboardHandler creates a new DashboardHandler. Traffic stats are
+// read from the access logs in cfg.LogDir; disk usage is reported for the
+// partition holding cfg.DataDir.
+func NewDashboardHandler(hostSvc *service.HostService, caddyMgr *caddy.Manager, cfg *config.Config, version string) *DashboardHandler {
+	return &DashboardHandler{
+		hostSvc:  hostSvc,
+		caddyMgr: caddyMgr,
+		version:  version,
+		dataDir:  cfg.DataDir,
+		traffic:  newTrafficCache(accessLogDir(cfg.LogDir)),
+		system:   gopsutilCollector{},
+	}
 }
 
 // Stats returns comprehensive dashboard statistics
@@ -43,6 +57,7 @@ func (h *DashboardHandler) Stats(c *gin.Context) {
 		tlsCustom     int
 		tlsNone       int
 		withAuth      int
+		configErrors  = []gin.H{}
 	)
 
 	for _, host := range hosts {
@@ -72,6 +87,11 @@ func (h *DashboardHandler) Stats(c *gin.Context) {
 		if len(host.BasicAuths) > 0 {
 			withAuth++
 		}
+
+		// Hosts left out of the Caddyfile because Caddy rejected them
+		if host.ConfigError != "" {
+			configErrors = append(configErrors, gin.H{"id": host.ID, "domain": host.Domain, "error": host.ConfigError})
+		}
 	}
 
 	// Caddy info
@@ -114,8 +134,10 @@ func (h *DashboardHandler) Stats(c *gin.Context) {
 		"security": gin.H{
 			"with_auth": withAuth,
 		},
-		"system": sysInfo,
-		"caddy":  caddyStatus,
+		"system":        sysInfo,
+		"caddy":         caddyStatus,
+		"traffic":       h.traffic.Get(),
+		"config_errors": configErrors,
 	})
 }
 
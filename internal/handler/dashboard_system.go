@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"net/http"
+	"runtime"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shirou/gopsutil/v4/cpu"
+	"github.com/shirou/gopsutil/v4/disk"
+	"github.com/shirou/gopsutil/v4/host"
+	"github.com/shirou/gopsutil/v4/load"
+	"github.com/shirou/gopsutil/v4/mem"
+)
+
+// systemCollector reads host resource usage. Each method fails
+// independently so one unsupported metric doesn't hide the others.
+type systemCollector interface {
+	CPUPercent() (float64, error)
+	LoadAvg() (load1, load5, load15 float64, err error)
+	Memory() (used, total uint64, err error)
+	Disk(path string) (used, total uint64, err error)
+	Uptime() (uint64, error)
+}
+
+// gopsutilCollector is the systemCollector backed by gopsutil, which reads
+// /proc on Linux.
+type gopsutilCollector struct{}
+
+func (gopsutilCollector) CPUPercent() (float64, error) {
+	pcts, err := cpu.Percent(0, false)
+	if err != nil || len(pcts) == 0 {
+		return 0, err
+	}
+	return pcts[0], nil
+}
+
+func (gopsutilCollector) LoadAvg() (float64, float64, float64, error) {
+	avg, err := load.Avg()
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	return avg.Load1, avg.Load5, avg.Load15, nil
+}
+
+func (gopsutilCollector) Memory() (uint64, uint64, error) {
+	vm, err := mem.VirtualMemory()
+	if err != nil {
+		return 0, 0, err
+	}
+	return vm.Used, vm.Total, nil
+}
+
+func (gopsutilCollector) Disk(path string) (uint64, uint64, error) {
+	usage, err := disk.Usage(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	return usage.Used, usage.Total, nil
+}
+
+func (gopsutilCollector) Uptime() (uint64, error) {
+	return host.Uptime()
+}
+
+// SystemCPU is CPU usage; Load* are nil where load averages don't exist.
+type SystemCPU struct {
+	Percent float64  `json:"percent"`
+	Cores   int      `json:"cores"`
+	Load1   *float64 `json:"load1"`
+	Load5   *float64 `json:"load5"`
+	Load15  *float64 `json:"load15"`
+}
+
+// SystemUsage is used/total bytes of memory or a disk.
+type SystemUsage struct {
+	Path    string  `json:"path,omitempty"`
+	Used    uint64  `json:"used"`
+	Total   uint64  `json:"total"`
+	Percent float64 `json:"percent"`
+}
+
+// SystemResources is the dashboard resource widget. Sections that can't be
+// read on this platform are null.
+type SystemResources struct {
+	OS     string       `json:"os"`
+	CPU    *SystemCPU   `json:"cpu"`
+	Memory *SystemUsage `json:"memory"`
+	Disk   *SystemUsage `json:"disk"`
+	Uptime *uint64      `json:"uptime"` // seconds
+}
+
+func newSystemUsage(path string, used, total uint64) *SystemUsage {
+	u := &SystemUsage{Path: path, Used: used, Total: total}
+	if total > 0 {
+		u.Percent = float64(used) / float64(total) * 100
+	}
+	return u
+}
+
+// collectSystemResources reads every section from c, leaving failed ones nil.
+// The disk section reports the partition holding dataDir.
+func collectSystemResources(c systemCollector, dataDir string) SystemResources {
+	res := SystemResources{OS: runtime.GOOS}
+
+	if pct, err := c.CPUPercent(); err == nil {
+		res.CPU = &SystemCPU{Percent: pct, Cores: runtime.NumCPU()}
+		if l1, l5, l15, err := c.LoadAvg(); err == nil {
+			res.CPU.Load1, res.CPU.Load5, res.CPU.Load15 = &l1, &l5, &l15
+		}
+	}
+	if used, total, err := c.Memory(); err == nil {
+		res.Memory = newSystemUsage("", used, total)
+	}
+	if used, total, err := c.Disk(dataDir); err == nil {
+		res.Disk = newSystemUsage(dataDir, used, total)
+	}
+	if up, err := c.Uptime(); err == nil {
+		res.Uptime = &up
+	}
+	return res
+}
+
+// System GET /api/dashboard/system
+// Returns CPU load, memory, data-partition disk usage and uptime.
+func (h *DashboardHandler) System(c *gin.Context) {
+	c.JSON(http.StatusOK, collectSystemResources(h.system, h.dataDir))
+}
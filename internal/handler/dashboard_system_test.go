@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fakeSystem is a systemCollector with fixed readings; a non-nil err makes
+// the load average, disk and uptime unavailable, as on platforms without
+// them.
+type fakeSystem struct {
+	err error
+}
+
+func (f fakeSystem) CPUPercent() (float64, error) { return 12.5, nil }
+
+func (f fakeSystem) LoadAvg() (float64, float64, float64, error) {
+	return 0.5, 0.25, 0.125, f.err
+}
+
+func (f fakeSystem) Memory() (uint64, uint64, error) { return 1 << 30, 4 << 30, nil }
+
+func (f fakeSystem) Disk(path string) (uint64, uint64, error) {
+	if f.err != nil {
+		return 0, 0, f.err
+	}
+	return 30 << 30, 120 << 30, nil
+}
+
+func (f fakeSystem) Uptime() (uint64, error) { return 3600, f.err }
+
+func TestCollectSystemResources(t *testing.T) {
+	res := collectSystemResources(fakeSystem{}, "/var/lib/webcasa")
+
+	if res.CPU == nil || res.CPU.Percent != 12.5 || res.CPU.Load1 == nil || *res.CPU.Load15 != 0.125 {
+		t.Errorf("cpu = %+v", res.CPU)
+	}
+	if res.Memory == nil || res.Memory.Total != 4<<30 || res.Memory.Percent != 25 {
+		t.Errorf("memory = %+v", res.Memory)
+	}
+	if res.Disk == nil || res.Disk.Path != "/var/lib/webcasa" || res.Disk.Percent != 25 {
+		t.Errorf("disk = %+v", res.Disk)
+	}
+	if res.Uptime == nil || *res.Uptime != 3600 {
+		t.Errorf("uptime = %v", res.Uptime)
+	}
+}
+
+func TestDashboardSystem_DegradesGracefully(t *testing.T) {
+	h := &DashboardHandler{system: fakeSystem{err: errors.New("not implemented yet")}, dataDir: "/data"}
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("GET", "/api/dashboard/system", nil)
+	h.System(c)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d", w.Code)
+	}
+	var resp map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp["disk"] != nil || resp["uptime"] != nil {
+		t.Errorf("unavailable sections should be null: %v", resp)
+	}
+	cpu := resp["cpu"].(map[string]interface{})
+	if cpu["percent"] != 12.5 || cpu["load1"] != nil {
+		t.Errorf("cpu = %v, want percent without load averages", cpu)
+	}
+	if resp["memory"] == nil {
+		t.Error("memory should still be reported")
+	}
+}
@@ -1,29 +1,33 @@
 package handler
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/web-casa/webcasa/internal/model"
+	"github.com/web-casa/webcasa/internal/service"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
 // DnsProviderHandler manages DNS provider CRUD
 type DnsProviderHandler struct {
-	db *gorm.DB
+	svc *service.DnsProviderService
+	db  *gorm.DB
 }
 
 // NewDnsProviderHandler creates a new DnsProviderHandler
-func NewDnsProviderHandler(db *gorm.DB) *DnsProviderHandler {
-	return &DnsProviderHandler{db: db}
+func NewDnsProviderHandler(svc *service.DnsProviderService, db *gorm.DB) *DnsProviderHandler {
+	return &DnsProviderHandler{svc: svc, db: db}
 }
 
 func (h *DnsProviderHandler) audit(c *gin.Context, action, detail string) {
 	if uid, ok := c.Get("user_id"); ok {
 		uname, _ := c.Get("username")
-		WriteAuditLog(h.db, uid.(uint), fmt.Sprint(uname), action, "dns_provider", "", detail, c.ClientIP())
+		WriteAuditLog(h.db, c, uid.(uint), fmt.Sprint(uname), action, "dns_provider", "", detail)
 	}
 }
 
@@ -36,7 +40,7 @@ func (h *DnsProviderHandler) List(c *gin.Context) {
 	}
 	// Mask config secrets in list view
 	for i := range providers {
-		providers[i].Config = "***"
+		providers[i].Config = h.svc.MaskedConfig(&providers[i])
 	}
 	c.JSON(http.StatusOK, gin.H{"providers": providers, "total": len(providers)})
 }
@@ -49,10 +53,42 @@ func (h *DnsProviderHandler) Get(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "DNS provider not found"})
 		return
 	}
-	p.Config = "***"
+	p.Config = h.svc.MaskedConfig(&p)
 	c.JSON(http.StatusOK, p)
 }
 
+// Default returns the default DNS provider (with config secrets masked),
+// used for DNS-challenge hosts that don't pick a provider
+func (h *DnsProviderHandler) Default(c *gin.Context) {
+	p, err := h.svc.Default()
+	if err != nil {
+		if err.Error() == "error.no_default_dns_provider" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "No default DNS provider", "error_key": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	p.Config = h.svc.MaskedConfig(p)
+	c.JSON(http.StatusOK, p)
+}
+
+// RenderPreview returns the tls block hosts using the provider get, with
+// secrets masked, to check the provider's Caddy config
+func (h *DnsProviderHandler) RenderPreview(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	block, err := h.svc.RenderPreview(uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "DNS provider not found"})
+			return
+		}
+		respondDnsProviderError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"block": block})
+}
+
 // Create creates a new DNS provider
 func (h *DnsProviderHandler) Create(c *gin.Context) {
 	var req struct {
@@ -83,21 +119,16 @@ func (h *DnsProviderHandler) Create(c *gin.Context) {
 	p := model.DnsProvider{
 		Name:      req.Name,
 		Provider:  req.Provider,
-		Config:    req.Config,
 		IsDefault: &isDefault,
 	}
 
-	// If set as default, unset other defaults
-	if isDefault {
-		h.db.Model(&model.DnsProvider{}).Where("is_default = ?", true).Update("is_default", false)
-	}
-
-	if err := h.db.Create(&p).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.svc.Save(&p, req.Config); err != nil {
+		respondDnsProviderError(c, err)
 		return
 	}
 
 	h.audit(c, "CREATE", fmt.Sprintf("Created DNS provider: %s (%s)", p.Name, p.Provider))
+	p.Config = h.svc.MaskedConfig(&p)
 	c.JSON(http.StatusCreated, p)
 }
 
@@ -127,25 +158,31 @@ func (h *DnsProviderHandler) Update(c *gin.Context) {
 	if req.Provider != "" {
 		p.Provider = req.Provider
 	}
-	if req.Config != "" {
-		p.Config = req.Config
-	}
 	if req.IsDefault != nil {
-		if *req.IsDefault {
-			h.db.Model(&model.DnsProvider{}).Where("is_default = ?", true).Update("is_default", false)
-		}
 		p.IsDefault = req.IsDefault
 	}
 
-	if err := h.db.Save(&p).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	// Masked config fields keep their stored values
+	if err := h.svc.Save(&p, req.Config); err != nil {
+		respondDnsProviderError(c, err)
 		return
 	}
 
 	h.audit(c, "UPDATE", fmt.Sprintf("Updated DNS provider: %s", p.Name))
+	p.Config = h.svc.MaskedConfig(&p)
 	c.JSON(http.StatusOK, p)
 }
 
+// respondDnsProviderError writes a failed save: validation errors as 400
+// with their error key, anything else as 500.
+func respondDnsProviderError(c *gin.Context, err error) {
+	if msg := err.Error(); strings.HasPrefix(msg, "error.") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg, "error_key": msg})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
+
 // Delete deletes a DNS provider
 func (h *DnsProviderHandler) Delete(c *gin.Context) {
 	id, _ := strconv.Atoi(c.Param("id"))
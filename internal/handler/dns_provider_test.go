@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/web-casa/webcasa/internal/model"
+	"github.com/web-casa/webcasa/internal/service"
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func setupDnsProviderTest(t *testing.T) (*gin.Engine, *gorm.DB) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	if err := db.AutoMigrate(&model.DnsProvider{}, &model.Host{}, &model.AuditLog{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	h := NewDnsProviderHandler(service.NewDnsProviderService(db, "test-secret-do-not-use"), db)
+	r := gin.New()
+	r.GET("/dns-providers", h.List)
+	r.GET("/dns-providers/default", h.Default)
+	r.GET("/dns-providers/:id", h.Get)
+	r.POST("/dns-providers", h.Create)
+	r.PUT("/dns-providers/:id", h.Update)
+	return r, db
+}
+
+func doDnsProviderRequest(r *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestDnsProviderHandler_MasksCredentials(t *testing.T) {
+	r, db := setupDnsProviderTest(t)
+	const token = "cf-secret-token-1234"
+
+	w := doDnsProviderRequest(r, http.MethodPost, "/dns-providers",
+		`{"name":"cf","provider":"cloudflare","config":"{\"api_token\":\"`+token+`\"}","is_default":true}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create: %d %s", w.Code, w.Body)
+	}
+
+	var stored model.DnsProvider
+	db.First(&stored)
+	if strings.Contains(stored.Config, token) {
+		t.Fatalf("token stored in plain text: %q", stored.Config)
+	}
+
+	for _, path := range []string{"/dns-providers", "/dns-providers/1", "/dns-providers/default"} {
+		w := doDnsProviderRequest(r, http.MethodGet, path, "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET %s: %d %s", path, w.Code, w.Body)
+		}
+		if strings.Contains(w.Body.String(), token) || !strings.Contains(w.Body.String(), `cf-s****1234`) {
+			t.Fatalf("GET %s: credentials not masked: %s", path, w.Body)
+		}
+	}
+
+	// Sending the masked config back keeps the stored token.
+	w = doDnsProviderRequest(r, http.MethodPut, "/dns-providers/1", `{"name":"cloudflare","config":"{\"api_token\":\"cf-s****1234\"}"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("update: %d %s", w.Code, w.Body)
+	}
+	var resp model.DnsProvider
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp.Name != "cloudflare" || resp.Config != `{"api_token":"cf-s****1234"}` {
+		t.Fatalf("unexpected update response: %+v", resp)
+	}
+
+	w = doDnsProviderRequest(r, http.MethodPut, "/dns-providers/1", `{"config":"not json"}`)
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "error.invalid_dns_config") {
+		t.Fatalf("expected 400 invalid_dns_config, got %d %s", w.Code, w.Body)
+	}
+}
@@ -40,10 +40,10 @@ func TestProperty21_ErrorResponsesContainTranslationKey(t *testing.T) {
 
 			name := fmt.Sprintf("dup-group-%d", suffix)
 			// Create first
-			groupSvc.Create(name, "red")
+			groupSvc.Create(name, "#ef4444")
 
 			// Try to create duplicate
-			body, _ := json.Marshal(map[string]string{"name": name, "color": "blue"})
+			body, _ := json.Marshal(map[string]string{"name": name, "color": "#3b82f6"})
 			w := httptest.NewRecorder()
 			c, _ := gin.CreateTestContext(w)
 			c.Request = httptest.NewRequest("POST", "/api/groups", bytes.NewReader(body))
@@ -69,9 +69,9 @@ func TestProperty21_ErrorResponsesContainTranslationKey(t *testing.T) {
 			tagHandler := NewTagHandler(tagSvc, db)
 
 			name := fmt.Sprintf("dup-tag-%d", suffix)
-			tagSvc.Create(name, "red")
+			tagSvc.Create(name, "#ef4444")
 
-			body, _ := json.Marshal(map[string]string{"name": name, "color": "blue"})
+			body, _ := json.Marshal(map[string]string{"name": name, "color": "#3b82f6"})
 			w := httptest.NewRecorder()
 			c, _ := gin.CreateTestContext(w)
 			c.Request = httptest.NewRequest("POST", "/api/tags", bytes.NewReader(body))
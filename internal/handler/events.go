@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/web-casa/webcasa/internal/auth"
+	"github.com/web-casa/webcasa/internal/plugin"
+)
+
+// eventStreamBuffer is how many events may queue for a slow client before
+// further events are dropped. Publishing never waits on a client.
+const eventStreamBuffer = 64
+
+// eventTopicRE matches one entry of the topics query: an event type
+// ("cert.expiring"), a prefix pattern ("deploy.*") or "*" for everything.
+var eventTopicRE = regexp.MustCompile(`^(\*|[a-z0-9_-]+(\.[a-z0-9_-]+)*(\.\*)?)$`)
+
+// EventsHandler streams plugin event-bus events to the frontend.
+type EventsHandler struct {
+	bus *plugin.EventBus
+}
+
+// NewEventsHandler creates a new EventsHandler
+func NewEventsHandler(bus *plugin.EventBus) *EventsHandler {
+	return &EventsHandler{bus: bus}
+}
+
+// parseEventTopics splits a comma-separated topics query. It returns false
+// when the list is empty or any entry is malformed.
+func parseEventTopics(raw string) ([]string, bool) {
+	var topics []string
+	for _, t := range strings.Split(raw, ",") {
+		t = strings.TrimSpace(t)
+		if t == "" {
+			continue
+		}
+		if !eventTopicRE.MatchString(t) {
+			return nil, false
+		}
+		topics = append(topics, t)
+	}
+	return topics, len(topics) > 0
+}
+
+// matchEventTopic reports whether eventType is selected by any of topics.
+func matchEventTopic(topics []string, eventType string) bool {
+	for _, t := range topics {
+		if t == "*" || t == eventType {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(t, "*"); ok && strings.HasPrefix(eventType, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Stream upgrades to a WebSocket and sends each event whose type matches
+// the topics query as a JSON message until the client disconnects.
+// GET /api/events/stream?topics=cert.expiring,deploy.*
+func (h *EventsHandler) Stream(c *gin.Context) {
+	topics, ok := parseEventTopics(c.Query("topics"))
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "topics must list event types such as cert.expiring or deploy.*"})
+		return
+	}
+
+	conn, err := wsUpgrader.Upgrade(c.Writer, c.Request, auth.WSUpgradeResponseHeader(c))
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	events := make(chan plugin.Event, eventStreamBuffer)
+	unsubscribe := h.bus.SubscribeCancel("*", func(e plugin.Event) {
+		if !matchEventTopic(topics, e.Type) {
+			return
+		}
+		select {
+		case events <- e:
+		default: // client is not keeping up; drop rather than block Publish
+		}
+	})
+	defer unsubscribe()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Detect client disconnect.
+	go func() {
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				cancel()
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e := <-events:
+			if err := conn.WriteJSON(e); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/web-casa/webcasa/internal/plugin"
+)
+
+func dialEventStream(t *testing.T, bus *plugin.EventBus, topics string) *websocket.Conn {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/api/events/stream", NewEventsHandler(bus).Stream)
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/api/events/stream?topics=" + topics
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// waitSubscribed publishes probe events until the stream has
+// subscribed, which happens just after the handshake completes.
+func waitSubscribed(t *testing.T, bus *plugin.EventBus, conn *websocket.Conn, probe string) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		bus.Publish(plugin.Event{Type: probe})
+		conn.SetReadDeadline(time.Now().Add(20 * time.Millisecond))
+		var e plugin.Event
+		if err := conn.ReadJSON(&e); err == nil && e.Type == probe {
+			conn.SetReadDeadline(time.Time{})
+			return
+		}
+	}
+	t.Fatal("event stream never subscribed")
+}
+
+func TestEventStream_DeliversSubscribedTopics(t *testing.T) {
+	bus := plugin.NewEventBus(slog.Default())
+	conn := dialEventStream(t, bus, "probe,cert.expiring,deploy.*")
+	waitSubscribed(t, bus, conn, "probe")
+
+	bus.Publish(plugin.Event{Type: "host.created", Source: "core"})
+	bus.Publish(plugin.Event{Type: "cert.expiring", Source: "core", Payload: map[string]interface{}{"domain": "a.com"}})
+	bus.Publish(plugin.Event{Type: "deploy.build.failed", Source: "deploy"})
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var got []string
+	for i := 0; i < 2; i++ {
+		var e plugin.Event
+		if err := conn.ReadJSON(&e); err != nil {
+			t.Fatalf("read: %v", err)
+		}
+		got = append(got, e.Type)
+		if e.Type == "cert.expiring" && e.Payload["domain"] != "a.com" {
+			t.Errorf("payload = %v", e.Payload)
+		}
+	}
+	if got[0] != "cert.expiring" || got[1] != "deploy.build.failed" {
+		t.Errorf("events = %v, want host.created filtered out", got)
+	}
+}
+
+func TestEventStream_UnsubscribesOnDisconnect(t *testing.T) {
+	bus := plugin.NewEventBus(slog.Default())
+	conn := dialEventStream(t, bus, "probe")
+	waitSubscribed(t, bus, conn, "probe")
+	if n := bus.Subscribers("*"); n != 1 {
+		t.Fatalf("subscribers while connected = %d, want 1", n)
+	}
+
+	conn.Close()
+	deadline := time.Now().Add(2 * time.Second)
+	for bus.Subscribers("*") != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("stream subscriber was not removed after disconnect")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestEventStream_RejectsBadTopics(t *testing.T) {
+	h := NewEventsHandler(plugin.NewEventBus(slog.Default()))
+	for _, q := range []string{"", ",", "Deploy", "a..b", "*.x", "deploy.*.x"} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("GET", "/api/events/stream?topics="+q, nil)
+		h.Stream(c)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("topics=%q: status = %d, want 400", q, w.Code)
+		}
+	}
+}
@@ -1,7 +1,9 @@
 package handler
 
 import (
+	"encoding/json"
 	"net/http"
+	"strings"
 
 	"github.com/web-casa/webcasa/internal/model"
 	"github.com/web-casa/webcasa/internal/service"
@@ -10,12 +12,13 @@ import (
 
 // ExportHandler manages config import/export endpoints
 type ExportHandler struct {
-	svc *service.HostService
+	svc    *service.HostService
+	config *service.ConfigService
 }
 
 // NewExportHandler creates a new ExportHandler
-func NewExportHandler(svc *service.HostService) *ExportHandler {
-	return &ExportHandler{svc: svc}
+func NewExportHandler(svc *service.HostService, config *service.ConfigService) *ExportHandler {
+	return &ExportHandler{svc: svc, config: config}
 }
 
 // Export returns all hosts as a JSON download
@@ -48,3 +51,98 @@ func (h *ExportHandler) Import(c *gin.Context) {
 		"hosts":   len(data.Hosts),
 	})
 }
+
+// ExportFull returns the full panel configuration as a JSON download.
+// Secrets are left out unless the request asks to include or encrypt them.
+func (h *ExportHandler) ExportFull(c *gin.Context) {
+	var req struct {
+		Secrets    string `json:"secrets"`
+		Passphrase string `json:"passphrase"`
+	}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "error_key": "error.invalid_request"})
+			return
+		}
+	}
+
+	data, err := h.config.Export(req.Secrets, req.Passphrase)
+	if err != nil {
+		respondConfigError(c, err)
+		return
+	}
+	c.Header("Content-Disposition", "attachment; filename=webcasa-config.json")
+	c.JSON(http.StatusOK, data)
+}
+
+// ImportFull restores a full configuration export, or a hosts-only one.
+func (h *ExportHandler) ImportFull(c *gin.Context) {
+	var req struct {
+		Config     json.RawMessage `json:"config" binding:"required"`
+		Passphrase string          `json:"passphrase"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "error_key": "error.invalid_request"})
+		return
+	}
+
+	data, err := service.ParseExport(req.Config)
+	if err == nil {
+		err = h.config.Import(data, req.Passphrase)
+	}
+	if err != nil {
+		respondConfigError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Configuration imported successfully",
+		"version":  data.Version,
+		"hosts":    len(data.Hosts),
+		"groups":   len(data.Groups),
+		"tags":     len(data.Tags),
+		"settings": len(data.Settings),
+	})
+}
+
+// respondConfigError answers a config export or import error: errors
+// carrying an error key are the request's fault, others the server's.
+func respondConfigError(c *gin.Context, err error) {
+	if msg := err.Error(); strings.HasPrefix(msg, "error.") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg, "error_key": msg})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
+
+// ImportCaddyfile creates hosts from the sites of a hand-written Caddyfile
+// and reports which blocks were imported and which were skipped.
+func (h *ExportHandler) ImportCaddyfile(c *gin.Context) {
+	var req struct {
+		Content string `json:"content" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "error_key": "error.invalid_request"})
+		return
+	}
+
+	results, err := h.svc.ImportCaddyfile(req.Content)
+	if err != nil && results == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "error_key": "error.caddyfile_import_failed"})
+		return
+	}
+
+	imported := 0
+	for _, r := range results {
+		if r.Status == "imported" {
+			imported++
+		}
+	}
+	summary := gin.H{"results": results, "imported": imported, "skipped": len(results) - imported}
+	if err != nil {
+		summary["error"] = err.Error()
+		summary["error_key"] = "error.caddyfile_import_failed"
+		c.JSON(http.StatusInternalServerError, summary)
+		return
+	}
+	c.JSON(http.StatusOK, summary)
+}
@@ -23,7 +23,7 @@ func NewGroupHandler(svc *service.GroupService, db *gorm.DB) *GroupHandler {
 func (h *GroupHandler) audit(c *gin.Context, action, targetID, detail string) {
 	if uid, ok := c.Get("user_id"); ok {
 		uname, _ := c.Get("username")
-		WriteAuditLog(h.db, uid.(uint), fmt.Sprint(uname), action, "group", targetID, detail, c.ClientIP())
+		WriteAuditLog(h.db, c, uid.(uint), fmt.Sprint(uname), action, "group", targetID, detail)
 	}
 }
 
@@ -37,6 +37,11 @@ func (h *GroupHandler) List(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"groups": groups, "total": len(groups)})
 }
 
+// Palette returns the colors suggested for groups and tags
+func (h *GroupHandler) Palette(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"palette": service.ColorPalette})
+}
+
 // Create adds a new group
 func (h *GroupHandler) Create(c *gin.Context) {
 	var req struct {
@@ -50,6 +55,13 @@ func (h *GroupHandler) Create(c *gin.Context) {
 
 	group, err := h.svc.Create(req.Name, req.Color)
 	if err != nil {
+		if err.Error() == "error.invalid_color" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":     fmt.Sprintf("invalid color '%s' (expected a hex code such as #3b82f6)", req.Color),
+				"error_key": "error.invalid_color",
+			})
+			return
+		}
 		if err.Error() == "error.group_name_exists" {
 			c.JSON(http.StatusBadRequest, gin.H{
 				"error":     fmt.Sprintf("group name '%s' already exists", req.Name),
@@ -84,6 +96,13 @@ func (h *GroupHandler) Update(c *gin.Context) {
 
 	group, err := h.svc.Update(id, req.Name, req.Color)
 	if err != nil {
+		if err.Error() == "error.invalid_color" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":     fmt.Sprintf("invalid color '%s' (expected a hex code such as #3b82f6)", req.Color),
+				"error_key": "error.invalid_color",
+			})
+			return
+		}
 		if err.Error() == "error.group_name_exists" {
 			c.JSON(http.StatusBadRequest, gin.H{
 				"error":     fmt.Sprintf("group name '%s' already exists", req.Name),
@@ -99,7 +118,30 @@ func (h *GroupHandler) Update(c *gin.Context) {
 	c.JSON(http.StatusOK, group)
 }
 
-// Delete removes a group
+// Reorder sets the custom group order from an ordered list of group IDs
+func (h *GroupHandler) Reorder(c *gin.Context) {
+	var req struct {
+		IDs []uint `json:"ids" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "error_key": "error.invalid_request"})
+		return
+	}
+
+	groups, err := h.svc.Reorder(req.IDs)
+	if err != nil {
+		if err.Error() == "error.group_not_found" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown group in order", "error_key": "error.group_not_found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "error_key": "error.group_update_failed"})
+		return
+	}
+
+	h.audit(c, "UPDATE", "", fmt.Sprintf("Reordered %d groups", len(req.IDs)))
+	c.JSON(http.StatusOK, gin.H{"groups": groups, "total": len(groups)})
+}
+
 func (h *GroupHandler) Delete(c *gin.Context) {
 	id, err := parseID(c)
 	if err != nil {
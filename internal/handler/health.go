@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// caddyRunningCacheTTL limits how often an unauthenticated health probe can
+// make the panel query the Caddy admin API.
+const caddyRunningCacheTTL = 5 * time.Second
+
+// caddyStatus is the part of caddy.Manager the health check needs.
+type caddyStatus interface {
+	IsRunning() bool
+}
+
+// HealthHandler serves the unauthenticated liveness/readiness probe.
+type HealthHandler struct {
+	db      *gorm.DB
+	caddy   caddyStatus
+	version string
+
+	mu        sync.Mutex
+	running   bool
+	checkedAt time.Time
+}
+
+// NewHealthHandler creates a new HealthHandler
+func NewHealthHandler(db *gorm.DB, caddyMgr caddyStatus, version string) *HealthHandler {
+	return &HealthHandler{db: db, caddy: caddyMgr, version: version}
+}
+
+// Check reports panel health. It returns 503 when the database is
+// unreachable; a stopped Caddy is reported but does not fail the probe,
+// since the panel itself is still usable to start it.
+func (h *HealthHandler) Check(c *gin.Context) {
+	dbOK := h.pingDB(c.Request.Context())
+	running := h.caddyRunning()
+
+	status, code := "ok", http.StatusOK
+	switch {
+	case !dbOK:
+		status, code = "unavailable", http.StatusServiceUnavailable
+	case !running:
+		status = "degraded"
+	}
+
+	c.JSON(code, gin.H{
+		"status":        status,
+		"version":       h.version,
+		"caddy_running": running,
+		"db_ok":         dbOK,
+	})
+}
+
+func (h *HealthHandler) pingDB(ctx context.Context) bool {
+	sqlDB, err := h.db.DB()
+	if err != nil {
+		return false
+	}
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	return sqlDB.PingContext(ctx) == nil
+}
+
+// caddyRunning returns the Caddy status, re-checking at most every
+// caddyRunningCacheTTL.
+func (h *HealthHandler) caddyRunning() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.checkedAt.IsZero() || time.Since(h.checkedAt) > caddyRunningCacheTTL {
+		h.running = h.caddy.IsRunning()
+		h.checkedAt = time.Now()
+	}
+	return h.running
+}
@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type fakeCaddy struct {
+	running bool
+	calls   int
+}
+
+func (f *fakeCaddy) IsRunning() bool {
+	f.calls++
+	return f.running
+}
+
+func checkHealth(t *testing.T, h *HealthHandler) (int, map[string]interface{}) {
+	t.Helper()
+	r := gin.New()
+	r.GET("/api/health", h.Check)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/api/health", nil))
+	var resp map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	return w.Code, resp
+}
+
+func openHealthTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestHealth_OK(t *testing.T) {
+	caddy := &fakeCaddy{running: true}
+	h := NewHealthHandler(openHealthTestDB(t), caddy, "1.2.3")
+
+	code, resp := checkHealth(t, h)
+	if code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", code)
+	}
+	if resp["status"] != "ok" || resp["version"] != "1.2.3" || resp["db_ok"] != true || resp["caddy_running"] != true {
+		t.Errorf("resp = %v", resp)
+	}
+
+	// The Caddy check is cached between probes.
+	checkHealth(t, h)
+	if caddy.calls != 1 {
+		t.Errorf("IsRunning called %d times, want 1", caddy.calls)
+	}
+}
+
+func TestHealth_CaddyStopped(t *testing.T) {
+	code, resp := checkHealth(t, NewHealthHandler(openHealthTestDB(t), &fakeCaddy{}, "dev"))
+	if code != http.StatusOK || resp["status"] != "degraded" || resp["caddy_running"] != false {
+		t.Errorf("got %d %v, want 200 degraded", code, resp)
+	}
+}
+
+func TestHealth_DBClosed(t *testing.T) {
+	db := openHealthTestDB(t)
+	sqlDB, _ := db.DB()
+	sqlDB.Close()
+
+	code, resp := checkHealth(t, NewHealthHandler(db, &fakeCaddy{running: true}, "dev"))
+	if code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want 503", code)
+	}
+	if resp["db_ok"] != false || resp["status"] != "unavailable" {
+		t.Errorf("resp = %v", resp)
+	}
+}
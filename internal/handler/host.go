@@ -1,11 +1,14 @@
 package handler
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/web-casa/webcasa/internal/model"
+	"github.com/web-casa/webcasa/internal/plugin"
 	"github.com/web-casa/webcasa/internal/service"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -15,6 +18,7 @@ import (
 type HostHandler struct {
 	svc *service.HostService
 	db  *gorm.DB
+	bus *plugin.EventBus // optional; receives host.* events
 }
 
 // NewHostHandler creates a new HostHandler
@@ -25,11 +29,59 @@ func NewHostHandler(svc *service.HostService, db *gorm.DB) *HostHandler {
 func (h *HostHandler) audit(c *gin.Context, action, targetID, detail string) {
 	if uid, ok := c.Get("user_id"); ok {
 		uname, _ := c.Get("username")
-		WriteAuditLog(h.db, uid.(uint), fmt.Sprint(uname), action, "host", targetID, detail, c.ClientIP())
+		WriteAuditLog(h.db, c, uid.(uint), fmt.Sprint(uname), action, "host", targetID, detail)
 	}
 }
 
-// List returns all proxy hosts
+// SetEventBus makes the handler publish host.created, host.deleted and
+// host.toggled events on bus.
+func (h *HostHandler) SetEventBus(bus *plugin.EventBus) {
+	h.bus = bus
+}
+
+// publish sends a host event on behalf of the request's user if an event
+// bus is set.
+func (h *HostHandler) publish(c *gin.Context, eventType string, host *model.Host) {
+	h.publishAs(c.GetString("username"), eventType, host)
+}
+
+// publishAs sends a host event attributed to user if an event bus is set.
+func (h *HostHandler) publishAs(user, eventType string, host *model.Host) {
+	if h.bus == nil {
+		return
+	}
+	h.bus.Publish(plugin.Event{
+		Type:    eventType,
+		Source:  "core",
+		Payload: HostEventPayload(host, user),
+	})
+}
+
+// ScheduledToggle records a host its schedule switched the way Toggle
+// records one a user switched: an audit entry and a host.toggled event,
+// both attributed to "system". It is the host scheduler's callback.
+func (h *HostHandler) ScheduledToggle(host *model.Host, action string) {
+	WriteAuditLog(h.db, nil, 0, "system", strings.ToUpper(action), "host", fmt.Sprint(host.ID),
+		fmt.Sprintf("Schedule %sd host '%s'", action, host.Domain))
+	h.publishAs("system", "host.toggled", host)
+}
+
+// HostEventPayload is the payload of a host.* event.
+func HostEventPayload(host *model.Host, user string) map[string]interface{} {
+	return map[string]interface{}{
+		"host_id":   host.ID,
+		"domain":    host.Domain,
+		"host_type": host.HostType,
+		"enabled":   host.Enabled == nil || *host.Enabled,
+		"user":      user,
+	}
+}
+
+// maxHostLimit caps the page size of a host list request. Without a limit
+// every matching host is returned.
+const maxHostLimit = 500
+
+// List returns proxy hosts, optionally filtered, sorted and paginated
 func (h *HostHandler) List(c *gin.Context) {
 	var filter service.HostListFilter
 	if gid := c.Query("group_id"); gid != "" {
@@ -45,12 +97,47 @@ func (h *HostHandler) List(c *gin.Context) {
 		}
 	}
 
-	hosts, err := h.svc.List(filter)
+	filter.Domain = c.Query("domain")
+	filter.Sort = c.Query("sort")
+	for param, dst := range map[string]*int{"limit": &filter.Limit, "offset": &filter.Offset} {
+		v := c.Query(param)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + param, "error_key": "error.invalid_pagination"})
+			return
+		}
+		*dst = n
+	}
+	if filter.Limit > maxHostLimit {
+		filter.Limit = maxHostLimit
+	}
+
+	// summary=true returns a light projection without the sub-tables.
+	var hosts interface{}
+	var total int64
+	var err error
+	if c.Query("summary") == "true" {
+		hosts, total, err = h.svc.ListSummary(filter)
+	} else {
+		hosts, total, err = h.svc.ListPage(filter)
+	}
 	if err != nil {
+		if key := err.Error(); key == "error.invalid_sort" || key == "error.invalid_pagination" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid sort or pagination", "error_key": key})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"hosts": hosts, "total": len(hosts)})
+	c.JSON(http.StatusOK, gin.H{
+		"hosts":  hosts,
+		"total":  total,
+		"limit":  filter.Limit,
+		"offset": filter.Offset,
+	})
 }
 
 // Get returns a single proxy host
@@ -77,14 +164,20 @@ func (h *HostHandler) Create(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	req.AuthorID = c.GetUint("user_id")
+	req.Author = c.GetString("username")
 
 	host, err := h.svc.Create(&req)
+	if respondDomainOverlap(c, err) || respondInvalidConfig(c, err) {
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
 	h.audit(c, "CREATE", fmt.Sprint(host.ID), fmt.Sprintf("Created %s host '%s'", host.HostType, host.Domain))
+	h.publish(c, "host.created", host)
 	c.JSON(http.StatusCreated, host)
 }
 
@@ -101,8 +194,17 @@ func (h *HostHandler) Update(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	req.AuthorID = c.GetUint("user_id")
+	req.Author = c.GetString("username")
 
 	host, err := h.svc.Update(id, &req)
+	if respondDomainOverlap(c, err) || respondInvalidConfig(c, err) {
+		return
+	}
+	if errors.Is(err, service.ErrStaleHost) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "error_key": "error.stale_host"})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -112,7 +214,7 @@ func (h *HostHandler) Update(c *gin.Context) {
 	c.JSON(http.StatusOK, host)
 }
 
-// Delete removes a proxy host
+// Delete moves a proxy host to the trash
 func (h *HostHandler) Delete(c *gin.Context) {
 	id, err := parseID(c)
 	if err != nil {
@@ -120,13 +222,233 @@ func (h *HostHandler) Delete(c *gin.Context) {
 		return
 	}
 
+	host, err := h.svc.Get(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Host not found", "error_key": "error.host_not_found"})
+		return
+	}
 	if err := h.svc.Delete(id); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	h.audit(c, "DELETE", fmt.Sprint(id), "Deleted host")
-	c.JSON(http.StatusOK, gin.H{"message": "Host deleted successfully"})
+	h.audit(c, "DELETE", fmt.Sprint(id), "Moved host to trash")
+	h.publish(c, "host.deleted", host)
+	c.JSON(http.StatusOK, gin.H{"message": "Host moved to trash"})
+}
+
+// Revisions lists a host's configuration history
+func (h *HostHandler) Revisions(c *gin.Context) {
+	id, err := parseID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID", "error_key": "error.invalid_id"})
+		return
+	}
+
+	revs, err := h.svc.Revisions(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Host not found", "error_key": "error.host_not_found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"revisions": revs, "total": len(revs)})
+}
+
+// CertStatus reports the certificate Caddy manages for a host
+func (h *HostHandler) CertStatus(c *gin.Context) {
+	id, err := parseID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID", "error_key": "error.invalid_id"})
+		return
+	}
+
+	status, err := h.svc.CertStatus(id)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Host not found", "error_key": "error.host_not_found"})
+		return
+	case errors.Is(err, service.ErrCertNotManaged):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "error_key": "error.cert_not_managed"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// Curl returns ready-made curl and HTTPie commands for testing a host
+func (h *HostHandler) Curl(c *gin.Context) {
+	id, err := parseID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID", "error_key": "error.invalid_id"})
+		return
+	}
+
+	cmds, err := h.svc.TestCommands(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Host not found", "error_key": "error.host_not_found"})
+		return
+	}
+	c.JSON(http.StatusOK, cmds)
+}
+
+// Lint returns advisory warnings about a host's configuration
+func (h *HostHandler) Lint(c *gin.Context) {
+	id, err := parseID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID", "error_key": "error.invalid_id"})
+		return
+	}
+
+	warnings, err := h.svc.LintHost(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Host not found", "error_key": "error.host_not_found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"warnings": warnings, "total": len(warnings)})
+}
+
+// Metadata returns the title and favicon of a host's upstream app
+func (h *HostHandler) Metadata(c *gin.Context) {
+	id, err := parseID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID", "error_key": "error.invalid_id"})
+		return
+	}
+
+	meta, err := h.svc.Metadata(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Host not found", "error_key": "error.host_not_found"})
+		return
+	}
+	c.JSON(http.StatusOK, meta)
+}
+
+// RestoreRevision re-applies an earlier revision of a host's configuration
+func (h *HostHandler) RestoreRevision(c *gin.Context) {
+	id, err := parseID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID", "error_key": "error.invalid_id"})
+		return
+	}
+	rev, err := strconv.ParseUint(c.Param("rev"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid revision", "error_key": "error.invalid_id"})
+		return
+	}
+
+	host, err := h.svc.RestoreRevision(id, uint(rev), c.GetUint("user_id"), c.GetString("username"))
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Host not found", "error_key": "error.host_not_found"})
+		return
+	case errors.Is(err, service.ErrRevisionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error(), "error_key": "error.revision_not_found"})
+		return
+	case errors.Is(err, service.ErrStaleHost):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "error_key": "error.stale_host"})
+		return
+	case respondInvalidConfig(c, err):
+		return
+	case err != nil:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.audit(c, "REVERT", fmt.Sprint(id), fmt.Sprintf("Restored host '%s' to revision %d", host.Domain, rev))
+	c.JSON(http.StatusOK, host)
+}
+
+// Trash lists soft-deleted hosts
+func (h *HostHandler) Trash(c *gin.Context) {
+	hosts, err := h.svc.Trash()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"hosts": hosts, "total": len(hosts)})
+}
+
+// Restore brings a host back from the trash
+func (h *HostHandler) Restore(c *gin.Context) {
+	id, err := parseID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID", "error_key": "error.invalid_id"})
+		return
+	}
+
+	host, err := h.svc.Restore(id)
+	switch {
+	case errors.Is(err, service.ErrNotInTrash):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error(), "error_key": "error.host_not_in_trash"})
+		return
+	case errors.Is(err, service.ErrDomainTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "error_key": "error.restore_domain_taken"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.audit(c, "RESTORE", fmt.Sprint(id), fmt.Sprintf("Restored host '%s' from trash", host.Domain))
+	c.JSON(http.StatusOK, host)
+}
+
+// Purge permanently removes a host from the trash
+func (h *HostHandler) Purge(c *gin.Context) {
+	id, err := parseID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID", "error_key": "error.invalid_id"})
+		return
+	}
+
+	if err := h.svc.Purge(id); err != nil {
+		if errors.Is(err, service.ErrNotInTrash) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error(), "error_key": "error.host_not_in_trash"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.audit(c, "PURGE", fmt.Sprint(id), "Permanently deleted host")
+	c.JSON(http.StatusOK, gin.H{"message": "Host permanently deleted"})
+}
+
+// Batch enables, disables or deletes a selection of hosts with a single
+// config reload and reports the outcome for each ID.
+func (h *HostHandler) Batch(c *gin.Context) {
+	var req struct {
+		IDs    []uint `json:"ids" binding:"required"`
+		Action string `json:"action" binding:"required,oneof=enable disable delete"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "error_key": "error.invalid_request"})
+		return
+	}
+
+	results, err := h.svc.Batch(req.IDs, req.Action)
+	if err != nil && results == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "error_key": "error.batch_failed"})
+		return
+	}
+
+	var done []string
+	for _, r := range results {
+		if r.OK {
+			done = append(done, fmt.Sprint(r.ID))
+		}
+	}
+	if len(done) > 0 {
+		action := "BATCH_" + strings.ToUpper(req.Action)
+		h.audit(c, action, "", fmt.Sprintf("Batch %s of %d host(s): %s", req.Action, len(done), strings.Join(done, ", ")))
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "error_key": "error.batch_failed", "results": results})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"results": results})
 }
 
 // Toggle enables/disables a proxy host
@@ -149,8 +471,92 @@ func (h *HostHandler) Toggle(c *gin.Context) {
 		action = "ENABLE"
 	}
 	h.audit(c, action, fmt.Sprint(host.ID), fmt.Sprintf("Toggled host '%s' → %s", host.Domain, action))
+	h.publish(c, "host.toggled", host)
 	c.JSON(http.StatusOK, host)
 }
+
+// SetMaintenance toggles maintenance mode for a host, optionally replacing
+// its maintenance page.
+func (h *HostHandler) SetMaintenance(c *gin.Context) {
+	id, err := parseID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID", "error_key": "error.invalid_id"})
+		return
+	}
+
+	var req struct {
+		Enabled *bool   `json:"enabled" binding:"required"`
+		Page    *string `json:"page"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "error_key": "error.invalid_request"})
+		return
+	}
+
+	host, err := h.svc.SetMaintenance(id, *req.Enabled, req.Page)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	action := "MAINTENANCE_OFF"
+	if *req.Enabled {
+		action = "MAINTENANCE_ON"
+	}
+	h.audit(c, action, fmt.Sprint(host.ID), fmt.Sprintf("Set maintenance mode for host '%s' → %s", host.Domain, action))
+	c.JSON(http.StatusOK, host)
+}
+
+// GetSchedule returns a host's enable/disable schedule
+func (h *HostHandler) GetSchedule(c *gin.Context) {
+	id, err := parseID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID", "error_key": "error.invalid_id"})
+		return
+	}
+
+	sched, err := h.svc.GetSchedule(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Host not found", "error_key": "error.host_not_found"})
+		return
+	}
+	c.JSON(http.StatusOK, sched)
+}
+
+// SetSchedule sets the cron expressions that enable and disable a host
+func (h *HostHandler) SetSchedule(c *gin.Context) {
+	id, err := parseID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID", "error_key": "error.invalid_id"})
+		return
+	}
+
+	var req struct {
+		EnableCron  string `json:"enable_cron"`
+		DisableCron string `json:"disable_cron"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "error_key": "error.invalid_request"})
+		return
+	}
+
+	sched, err := h.svc.SetSchedule(id, req.EnableCron, req.DisableCron)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Host not found", "error_key": "error.host_not_found"})
+		return
+	case errors.Is(err, service.ErrInvalidCron):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "error_key": "error.invalid_cron"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.audit(c, "UPDATE", fmt.Sprint(id), fmt.Sprintf("Set schedule for host %d: enable '%s', disable '%s'", id, sched.EnableCron, sched.DisableCron))
+	c.JSON(http.StatusOK, sched)
+}
+
 // Clone creates a deep copy of an existing host with a new domain
 func (h *HostHandler) Clone(c *gin.Context) {
 	id, err := parseID(c)
@@ -161,6 +567,9 @@ func (h *HostHandler) Clone(c *gin.Context) {
 
 	var req struct {
 		Domain string `json:"domain" binding:"required"`
+		// Optional filing for the clone; omitted fields copy the source's.
+		GroupID *uint  `json:"group_id"`
+		TagIDs  []uint `json:"tag_ids"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "error_key": "error.invalid_request"})
@@ -174,7 +583,10 @@ func (h *HostHandler) Clone(c *gin.Context) {
 		return
 	}
 
-	newHost, err := h.svc.CloneHost(id, req.Domain)
+	newHost, err := h.svc.CloneHost(id, req.Domain, service.CloneOptions{GroupID: req.GroupID, TagIDs: req.TagIDs})
+	if respondDomainOverlap(c, err) {
+		return
+	}
 	if err != nil {
 		errMsg := err.Error()
 		switch errMsg {
@@ -202,6 +614,32 @@ func (h *HostHandler) Clone(c *gin.Context) {
 	c.JSON(http.StatusCreated, newHost)
 }
 
+// respondInvalidConfig answers 400 with error.invalid_generated_config when
+// the change was saved but Caddy rejected the resulting Caddyfile, and
+// reports whether it did.
+func respondInvalidConfig(c *gin.Context, err error) bool {
+	if !errors.Is(err, service.ErrInvalidGeneratedConfig) {
+		return false
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "error_key": "error.invalid_generated_config"})
+	return true
+}
+
+// respondDomainOverlap answers 409 with the conflicting host when err is a
+// *service.DomainOverlapError, and reports whether it did.
+func respondDomainOverlap(c *gin.Context, err error) bool {
+	var overlap *service.DomainOverlapError
+	if !errors.As(err, &overlap) {
+		return false
+	}
+	c.JSON(http.StatusConflict, gin.H{
+		"error":     err.Error(),
+		"error_key": "error.domain_overlap",
+		"conflict":  gin.H{"id": overlap.HostID, "domain": overlap.HostDomain},
+	})
+	return true
+}
+
 func parseID(c *gin.Context) (uint, error) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	return uint(id), err
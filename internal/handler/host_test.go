@@ -0,0 +1,39 @@
+package handler
+
+import (
+	"log/slog"
+	"testing"
+
+	"github.com/web-casa/webcasa/internal/model"
+	"github.com/web-casa/webcasa/internal/plugin"
+)
+
+func TestHostScheduledToggle_PublishesAndAudits(t *testing.T) {
+	db := openHealthTestDB(t)
+	if err := db.AutoMigrate(&model.AuditLog{}); err != nil {
+		t.Fatal(err)
+	}
+	bus := plugin.NewEventBus(slog.Default())
+	h := NewHostHandler(nil, db)
+	h.SetEventBus(bus)
+
+	var events []plugin.Event
+	bus.Subscribe("host.*", func(e plugin.Event) { events = append(events, e) })
+
+	disabled := false
+	h.ScheduledToggle(&model.Host{ID: 7, Domain: "shop.example.com", HostType: "proxy", Enabled: &disabled}, "disable")
+
+	if len(events) != 1 || events[0].Type != "host.toggled" {
+		t.Fatalf("events = %+v, want one host.toggled", events)
+	}
+	if p := events[0].Payload; p["host_id"] != uint(7) || p["enabled"] != false || p["user"] != "system" {
+		t.Errorf("payload = %v", p)
+	}
+	var entry model.AuditLog
+	if err := db.First(&entry).Error; err != nil {
+		t.Fatalf("no audit entry: %v", err)
+	}
+	if entry.Action != "DISABLE" || entry.Username != "system" || entry.TargetID != "7" {
+		t.Errorf("audit entry = %+v", entry)
+	}
+}
@@ -2,18 +2,47 @@ package handler
 
 import (
 	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"log"
 	"net/http"
+	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
-	"github.com/web-casa/webcasa/internal/config"
 	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/web-casa/webcasa/internal/auth"
+	"github.com/web-casa/webcasa/internal/caddy"
+	"github.com/web-casa/webcasa/internal/config"
 )
 
+// logStreamPoll is how often Stream checks the tailed file for new data.
+var logStreamPoll = 500 * time.Millisecond
+
+// wsUpgrader accepts same-origin WebSocket upgrades (or clients sending no Origin).
+var wsUpgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			return true
+		}
+		u, err := url.Parse(origin)
+		if err != nil {
+			return false
+		}
+		return u.Host == r.Host
+	},
+}
+
 // LogHandler manages log viewing endpoints
 type LogHandler struct {
 	cfg *config.Config
@@ -24,9 +53,15 @@ func NewLogHandler(cfg *config.Config) *LogHandler {
 	return &LogHandler{cfg: cfg}
 }
 
-// GetLogs returns the last N lines from a log file
+// GetLogs returns the last N lines from a log file. When any of contains,
+// level, since or limit is given the lines are filtered server-side and
+// returned newest first (see filterLogFile).
 func (h *LogHandler) GetLogs(c *gin.Context) {
 	logType := c.DefaultQuery("type", "caddy")
+	if hasLogFilter(c) {
+		h.getFilteredLogs(c, logType)
+		return
+	}
 	linesStr := c.DefaultQuery("lines", "100")
 	search := c.DefaultQuery("search", "")
 
@@ -54,7 +89,220 @@ func (h *LogHandler) GetLogs(c *gin.Context) {
 	})
 }
 
-// ListLogFiles returns available log files
+// maxLogLimit caps how many lines a filtered GetLogs returns.
+const maxLogLimit = 5000
+
+// logFilter holds the server-side filters for GetLogs.
+type logFilter struct {
+	Contains string     // case-insensitive substring of the raw line
+	Level    string     // case-insensitive log level, e.g. "error"
+	Since    *time.Time // drop JSON entries logged before this time
+}
+
+func hasLogFilter(c *gin.Context) bool {
+	for _, p := range []string{"contains", "level", "since", "limit"} {
+		if _, ok := c.GetQuery(p); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// parseLogFilter reads contains, level and since from the query string.
+// since must be an RFC3339 timestamp.
+func parseLogFilter(c *gin.Context) (logFilter, error) {
+	f := logFilter{
+		Contains: strings.ToLower(strings.TrimSpace(c.Query("contains"))),
+		Level:    strings.ToLower(strings.TrimSpace(c.Query("level"))),
+	}
+	if v := c.Query("since"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return f, fmt.Errorf("since must be an RFC3339 timestamp")
+		}
+		f.Since = &t
+	}
+	return f, nil
+}
+
+// jsonLogLine is the subset of a Caddy JSON log entry used for filtering.
+// ts is Unix seconds as a float by default, or a string when Caddy's
+// time_format is set to an ISO layout.
+type jsonLogLine struct {
+	Level string          `json:"level"`
+	TS    json.RawMessage `json:"ts"`
+}
+
+func (e jsonLogLine) time() (time.Time, bool) {
+	var secs float64
+	if err := json.Unmarshal(e.TS, &secs); err == nil {
+		whole := int64(secs)
+		return time.Unix(whole, int64((secs-float64(whole))*1e9)), true
+	}
+	var str string
+	if err := json.Unmarshal(e.TS, &str); err == nil {
+		if t, err := time.Parse(time.RFC3339Nano, str); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// match reports whether line passes f. JSON lines are matched on their
+// level and ts fields; plain-text lines fall back to substring matching
+// for the level, and cannot be filtered by time.
+func (f logFilter) match(line string) bool {
+	lower := strings.ToLower(line)
+	if f.Contains != "" && !strings.Contains(lower, f.Contains) {
+		return false
+	}
+	if f.Level == "" && f.Since == nil {
+		return true
+	}
+
+	var entry jsonLogLine
+	if strings.HasPrefix(strings.TrimSpace(line), "{") && json.Unmarshal([]byte(line), &entry) == nil {
+		if f.Level != "" && !strings.EqualFold(entry.Level, f.Level) {
+			return false
+		}
+		if f.Since != nil {
+			if ts, ok := entry.time(); ok && ts.Before(*f.Since) {
+				return false
+			}
+		}
+		return true
+	}
+	return f.Level == "" || strings.Contains(lower, f.Level)
+}
+
+// filterLogFile returns up to limit lines of filePath matching f, newest
+// first.
+func filterLogFile(filePath string, f logFilter, limit int) ([]string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	// Keep only the last limit matches while scanning forward.
+	ring := make([]string, 0, limit)
+	next := 0
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !f.match(line) {
+			continue
+		}
+		if len(ring) < limit {
+			ring = append(ring, line)
+		} else {
+			ring[next] = line
+		}
+		next = (next + 1) % limit
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading file: %w", err)
+	}
+
+	out := make([]string, 0, len(ring))
+	for i := 1; i <= len(ring); i++ {
+		out = append(out, ring[(next-i+len(ring))%len(ring)])
+	}
+	return out, nil
+}
+
+// getFilteredLogs serves GetLogs when filter parameters are present.
+func (h *LogHandler) getFilteredLogs(c *gin.Context, logType string) {
+	filter, err := parseLogFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "error_key": "error.invalid_date_range"})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit < 1 {
+		limit = 100
+	}
+	if limit > maxLogLimit {
+		limit = maxLogLimit
+	}
+
+	logFile := h.resolveLogFile(logType)
+	if logFile == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid log type"})
+		return
+	}
+
+	content, err := filterLogFile(logFile, filter, limit)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"lines": []string{}, "file": logFile, "error": "Log file not found or empty"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"lines": content,
+		"file":  logFile,
+		"total": len(content),
+		"order": "desc",
+	})
+}
+
+// accessTopPaths is how many paths AccessLog lists in top_paths.
+const accessTopPaths = 10
+
+// AccessLog GET /api/logs/access?host=&status=&limit=
+// Parses the per-host Caddy access logs and returns aggregates (request
+// count, status breakdown, top paths) plus the most recent matching
+// entries, newest first. With host set only that host's log is read.
+func (h *LogHandler) AccessLog(c *gin.Context) {
+	filter, err := caddy.ParseAccessFilter(c.Query("host"), c.Query("status"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "error_key": "error.invalid_request"})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 0 {
+		limit = 50
+	}
+	if limit > maxLogLimit {
+		limit = maxLogLimit
+	}
+
+	var entries []caddy.AccessEntry
+	for _, path := range caddy.AccessLogFiles(h.cfg.LogDir, filter.Host) {
+		f, err := os.Open(path)
+		if err != nil {
+			continue
+		}
+		parsed, err := caddy.ParseAccessLog(f)
+		f.Close()
+		if err != nil {
+			log.Printf("Warning: access log %s: %v", path, err)
+		}
+		for _, e := range parsed {
+			if filter.Match(e) {
+				entries = append(entries, e)
+			}
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].TS.After(entries[j].TS) })
+	recent := entries
+	if len(recent) > limit {
+		recent = recent[:limit]
+	}
+	if recent == nil {
+		recent = []caddy.AccessEntry{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"summary": caddy.SummarizeAccess(entries, accessTopPaths),
+		"entries": recent,
+	})
+}
+
+// ListLogFiles returns available log files. Per-host access logs
+// (access-<domain>.log) carry the domain in "host".
 func (h *LogHandler) ListLogFiles(c *gin.Context) {
 	entries, err := os.ReadDir(h.cfg.LogDir)
 	if err != nil {
@@ -68,10 +316,14 @@ func (h *LogHandler) ListLogFiles(c *gin.Context) {
 			continue
 		}
 		info, _ := e.Info()
-		files = append(files, map[string]interface{}{
+		file := map[string]interface{}{
 			"name": e.Name(),
 			"size": info.Size(),
-		})
+		}
+		if strings.HasPrefix(e.Name(), "access-") && strings.HasSuffix(e.Name(), ".log") {
+			file["host"] = strings.TrimSuffix(strings.TrimPrefix(e.Name(), "access-"), ".log")
+		}
+		files = append(files, file)
 	}
 
 	c.JSON(http.StatusOK, gin.H{"files": files})
@@ -111,6 +363,139 @@ func (h *LogHandler) resolveLogFile(logType string) string {
 	}
 }
 
+// resolveStreamFile maps a file name from the client to a path inside
+// cfg.LogDir. Anything that escapes the directory, directly or through a
+// symlink, is rejected.
+func (h *LogHandler) resolveStreamFile(name string) (string, bool) {
+	if name == "" {
+		return "", false
+	}
+	dir, err := filepath.Abs(h.cfg.LogDir)
+	if err != nil {
+		return "", false
+	}
+	path := filepath.Join(dir, name)
+	if !withinDir(dir, path) {
+		return "", false
+	}
+
+	// Resolve symlinks once the file exists; a missing file is reported
+	// by the caller.
+	realDir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return path, true
+	}
+	realPath, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return path, true
+	}
+	if !withinDir(realDir, realPath) {
+		return "", false
+	}
+	return path, true
+}
+
+// withinDir reports whether path is strictly below dir.
+func withinDir(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil || rel == "." || filepath.IsAbs(rel) {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// Stream GET /api/logs/stream?file=
+// Tails a log file under cfg.LogDir over WebSocket: starting from the
+// current end of the file, each appended line is sent as one text message
+// until the client disconnects. Truncation and rotation are followed.
+func (h *LogHandler) Stream(c *gin.Context) {
+	path, ok := h.resolveStreamFile(c.Query("file"))
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid log file"})
+		return
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Log file not found"})
+		return
+	}
+	defer func() { f.Close() }()
+
+	// Seek before upgrading so that everything written after the
+	// handshake completes is streamed.
+	offset, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read log file"})
+		return
+	}
+
+	conn, err := wsUpgrader.Upgrade(c.Writer, c.Request, auth.WSUpgradeResponseHeader(c))
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Detect client disconnect.
+	go func() {
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				cancel()
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(logStreamPoll)
+	defer ticker.Stop()
+
+	var pending []byte
+	buf := make([]byte, 32*1024)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		// A file replaced at the same path (rotation) is reopened from the
+		// start; a file that shrank (truncation) is reread from the start.
+		if cur, err := os.Stat(path); err == nil {
+			if old, err := f.Stat(); err == nil && !os.SameFile(old, cur) {
+				if nf, err := os.Open(path); err == nil {
+					f.Close()
+					f, offset, pending = nf, 0, nil
+				}
+			} else if cur.Size() < offset {
+				offset, pending = 0, nil
+			}
+		}
+
+		for {
+			n, err := f.ReadAt(buf, offset)
+			offset += int64(n)
+			pending = append(pending, buf[:n]...)
+			if err != nil || n == 0 {
+				break
+			}
+		}
+
+		for {
+			i := bytes.IndexByte(pending, '\n')
+			if i < 0 {
+				break
+			}
+			line := bytes.TrimSuffix(pending[:i], []byte("\r"))
+			if err := conn.WriteMessage(websocket.TextMessage, line); err != nil {
+				return
+			}
+			pending = pending[i+1:]
+		}
+	}
+}
+
 // tailFile reads the last N lines from a file, optionally filtering by search term
 func tailFile(filePath string, n int, search string) ([]string, error) {
 	f, err := os.Open(filePath)
@@ -0,0 +1,229 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/web-casa/webcasa/internal/config"
+)
+
+func setupLogStream(t *testing.T) (string, *httptest.Server) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	old := logStreamPoll
+	logStreamPoll = 20 * time.Millisecond
+	t.Cleanup(func() { logStreamPoll = old })
+
+	dir := t.TempDir()
+	h := NewLogHandler(&config.Config{LogDir: dir})
+	r := gin.New()
+	r.GET("/api/logs/stream", h.Stream)
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+	return dir, srv
+}
+
+func dialLogStream(t *testing.T, srv *httptest.Server, file string) *websocket.Conn {
+	t.Helper()
+	u := "ws" + strings.TrimPrefix(srv.URL, "http") + "/api/logs/stream?file=" + file
+	conn, _, err := websocket.DefaultDialer.Dial(u, nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func appendLog(t *testing.T, path, data string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readLines(t *testing.T, conn *websocket.Conn, n int) []string {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var lines []string
+	for len(lines) < n {
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("read after %v: %v", lines, err)
+		}
+		lines = append(lines, string(msg))
+	}
+	return lines
+}
+
+func TestLogStream_AppendedLines(t *testing.T) {
+	dir, srv := setupLogStream(t)
+	path := filepath.Join(dir, "caddy.log")
+	if err := os.WriteFile(path, []byte("old line\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	conn := dialLogStream(t, srv, "caddy.log")
+	appendLog(t, path, "first\nsecond\npart")
+	if got := readLines(t, conn, 2); got[0] != "first" || got[1] != "second" {
+		t.Errorf("lines = %q, want only lines written after connecting", got)
+	}
+
+	// A partial line is held back until it is completed.
+	appendLog(t, path, "ial\n")
+	if got := readLines(t, conn, 1); got[0] != "partial" {
+		t.Errorf("line = %q, want partial", got[0])
+	}
+
+	// After truncation the file is read again from the start.
+	if err := os.WriteFile(path, []byte("rotated\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := readLines(t, conn, 1); got[0] != "rotated" {
+		t.Errorf("line after truncation = %q", got[0])
+	}
+}
+
+func TestLogStream_RejectsPathsOutsideLogDir(t *testing.T) {
+	dir, srv := setupLogStream(t)
+	secret := filepath.Join(t.TempDir(), "secret.log")
+	os.WriteFile(secret, []byte("secret\n"), 0644)
+	os.Symlink(secret, filepath.Join(dir, "link.log"))
+
+	for name, want := range map[string]int{
+		"":                  http.StatusBadRequest,
+		"..%2Fsecret.log":   http.StatusBadRequest,
+		"..":                http.StatusBadRequest,
+		"%2Fetc%2Fpasswd":   http.StatusNotFound, // joined under LogDir
+		"link.log":          http.StatusBadRequest,
+		"missing.log":       http.StatusNotFound,
+		"sub%2F..%2F..%2Fx": http.StatusBadRequest,
+	} {
+		resp, err := http.Get(srv.URL + "/api/logs/stream?file=" + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Errorf("file=%q: status = %d, want %d", name, resp.StatusCode, want)
+		}
+	}
+}
+
+// getLogs calls GetLogs with the given query and decodes the lines.
+func getLogs(t *testing.T, dir, query string) (int, []string) {
+	t.Helper()
+	h := NewLogHandler(&config.Config{LogDir: dir})
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("GET", "/api/logs?"+query, nil)
+	h.GetLogs(c)
+
+	var resp struct {
+		Lines []string `json:"lines"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	return w.Code, resp.Lines
+}
+
+func TestGetLogs_FilterJSONByLevel(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "caddy.log"), []byte(strings.Join([]string{
+		`{"level":"info","ts":1700000000.5,"msg":"handled request"}`,
+		`{"level":"error","ts":1700000100.25,"msg":"dial tcp: connection refused"}`,
+		`{"level":"info","ts":1700000200,"msg":"handled request"}`,
+		`{"level":"error","ts":1700000300,"msg":"tls handshake error"}`,
+	}, "\n")+"\n"), 0644)
+
+	code, lines := getLogs(t, dir, "type=caddy&level=ERROR")
+	if code != http.StatusOK {
+		t.Fatalf("status = %d", code)
+	}
+	if len(lines) != 2 || !strings.Contains(lines[0], "tls handshake") || !strings.Contains(lines[1], "connection refused") {
+		t.Errorf("lines = %q, want the two errors newest first", lines)
+	}
+
+	since := time.Unix(1700000150, 0).UTC().Format(time.RFC3339)
+	if _, lines := getLogs(t, dir, "type=caddy&level=error&since="+since); len(lines) != 1 || !strings.Contains(lines[0], "tls handshake") {
+		t.Errorf("since: lines = %q", lines)
+	}
+	if _, lines := getLogs(t, dir, "type=caddy&limit=1"); len(lines) != 1 || !strings.Contains(lines[0], "tls handshake") {
+		t.Errorf("limit: lines = %q", lines)
+	}
+	if code, _ := getLogs(t, dir, "type=caddy&since=yesterday"); code != http.StatusBadRequest {
+		t.Errorf("invalid since: status = %d, want 400", code)
+	}
+}
+
+func TestGetLogs_FilterPlainBySubstring(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "app.log"), []byte(
+		"2024/01/01 10:00:00 INFO server started\n"+
+			"2024/01/01 10:00:01 WARN slow upstream shop.example.com\n"+
+			"2024/01/01 10:00:02 INFO proxying shop.example.com\n"+
+			"2024/01/01 10:00:03 ERROR upstream shop.example.com down\n"), 0644)
+
+	_, lines := getLogs(t, dir, "type=app.log&contains=SHOP.example")
+	if len(lines) != 3 || !strings.Contains(lines[0], "ERROR") || !strings.Contains(lines[2], "WARN") {
+		t.Errorf("contains: lines = %q, want 3 matches newest first", lines)
+	}
+	if _, lines := getLogs(t, dir, "type=app.log&level=error"); len(lines) != 1 || !strings.Contains(lines[0], "down") {
+		t.Errorf("level on plain text: lines = %q", lines)
+	}
+
+	// Without filter parameters the original oldest-first tail is kept.
+	if _, lines := getLogs(t, dir, "type=app.log&lines=2"); len(lines) != 2 || !strings.Contains(lines[1], "ERROR") {
+		t.Errorf("unfiltered: lines = %q", lines)
+	}
+}
+
+func TestAccessLog_FilterByHost(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "access-shop.example.com.log"), []byte(
+		`{"ts":1700000000,"request":{"method":"GET","host":"shop.example.com","uri":"/"},"status":200}`+"\n"+
+			`{"ts":1700000002,"request":{"method":"GET","host":"shop.example.com","uri":"/missing"},"status":404}`+"\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "access-blog.example.com.log"), []byte(
+		`{"ts":1700000001,"request":{"method":"GET","host":"blog.example.com","uri":"/"},"status":200}`+"\n"), 0644)
+	h := NewLogHandler(&config.Config{LogDir: dir})
+
+	get := func(query string) (int, map[string]interface{}) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("GET", "/api/logs/access?"+query, nil)
+		h.AccessLog(c)
+		var resp map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp
+	}
+
+	_, all := get("")
+	if n := all["summary"].(map[string]interface{})["requests"]; n != float64(3) {
+		t.Errorf("all hosts: requests = %v, want 3", n)
+	}
+	entries := all["entries"].([]interface{})
+	if first := entries[0].(map[string]interface{}); first["uri"] != "/missing" {
+		t.Errorf("entries should be newest first, got %v", first)
+	}
+
+	_, shop := get("host=shop.example.com&status=4xx")
+	sum := shop["summary"].(map[string]interface{})
+	if sum["requests"] != float64(1) || sum["statuses"].(map[string]interface{})["404"] != float64(1) {
+		t.Errorf("shop 4xx summary = %v", sum)
+	}
+
+	if code, _ := get("status=teapot"); code != http.StatusBadRequest {
+		t.Errorf("invalid status: code = %d, want 400", code)
+	}
+}
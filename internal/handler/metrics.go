@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/web-casa/webcasa/internal/metrics"
+)
+
+// MetricsHandler serves Prometheus metrics behind a static bearer token so
+// scrapers don't need a panel login. Without a token they are served to
+// local scrapers only, unless public is set.
+type MetricsHandler struct {
+	token   string
+	public  bool
+	handler http.Handler
+}
+
+// NewMetricsHandler creates a new MetricsHandler. With an empty token the
+// endpoint is open to every client if public is set, and to direct
+// loopback connections otherwise.
+func NewMetricsHandler(token string, public bool) *MetricsHandler {
+	return &MetricsHandler{token: token, public: public, handler: metrics.Handler()}
+}
+
+// Serve writes the metrics exposition.
+func (h *MetricsHandler) Serve(c *gin.Context) {
+	switch {
+	case h.token != "":
+		got := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(got), []byte(h.token)) != 1 {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid metrics token"})
+			return
+		}
+	case !h.public && !directLoopback(c.Request):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Metrics are only served to localhost unless WEBCASA_METRICS_TOKEN is set"})
+		return
+	}
+	h.handler.ServeHTTP(c.Writer, c.Request)
+}
+
+// directLoopback reports whether r comes straight from a loopback address.
+// The peer address is used rather than ClientIP, and requests relayed by a
+// local reverse proxy (which set forwarding headers) do not count.
+func directLoopback(r *http.Request) bool {
+	if r.Header.Get("X-Forwarded-For") != "" || r.Header.Get("X-Real-IP") != "" || r.Header.Get("Forwarded") != "" {
+		return false
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return false
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func scrapeMetrics(h *MetricsHandler, auth string, headers ...string) *httptest.ResponseRecorder {
+	r := gin.New()
+	r.GET("/api/metrics", h.Serve)
+	req := httptest.NewRequest("GET", "/api/metrics", nil)
+	if auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		if headers[i] == "RemoteAddr" {
+			req.RemoteAddr = headers[i+1]
+		} else {
+			req.Header.Set(headers[i], headers[i+1])
+		}
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestMetrics_TokenGate(t *testing.T) {
+	h := NewMetricsHandler("scrape-secret", false)
+
+	if w := scrapeMetrics(h, ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("no token: status = %d, want 401", w.Code)
+	}
+	if w := scrapeMetrics(h, "Bearer wrong"); w.Code != http.StatusUnauthorized {
+		t.Errorf("wrong token: status = %d, want 401", w.Code)
+	}
+	w := scrapeMetrics(h, "Bearer scrape-secret")
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/plain") {
+		t.Errorf("valid token: status = %d content-type = %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	if w := scrapeMetrics(h, "", "RemoteAddr", "127.0.0.1:5000"); w.Code != http.StatusUnauthorized {
+		t.Errorf("localhost without token: status = %d, want 401", w.Code)
+	}
+}
+
+func TestMetrics_LocalhostOnlyWithoutToken(t *testing.T) {
+	h := NewMetricsHandler("", false)
+
+	if w := scrapeMetrics(h, ""); w.Code != http.StatusForbidden {
+		t.Errorf("remote client: status = %d, want 403", w.Code)
+	}
+	if w := scrapeMetrics(h, "", "RemoteAddr", "127.0.0.1:5000", "X-Forwarded-For", "203.0.113.9"); w.Code != http.StatusForbidden {
+		t.Errorf("client relayed by a local proxy: status = %d, want 403", w.Code)
+	}
+	for _, addr := range []string{"127.0.0.1:5000", "[::1]:5000"} {
+		if w := scrapeMetrics(h, "", "RemoteAddr", addr); w.Code != http.StatusOK {
+			t.Errorf("local scraper %s: status = %d, want 200", addr, w.Code)
+		}
+	}
+
+	if w := scrapeMetrics(NewMetricsHandler("", true), ""); w.Code != http.StatusOK {
+		t.Errorf("public without token: status = %d, want 200", w.Code)
+	}
+}
@@ -19,19 +19,22 @@ func NewPluginHandler(mgr *plugin.Manager) *PluginHandler {
 	return &PluginHandler{mgr: mgr}
 }
 
-// List returns all registered plugins with their enabled state.
+// List returns all registered plugins with their enabled state and, for
+// enabled plugins that support it, their health.
 func (h *PluginHandler) List(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"plugins": h.mgr.List()})
+	c.JSON(http.StatusOK, gin.H{"plugins": h.mgr.Health()})
 }
 
-// Enable enables a plugin by ID.
+// Enable enables a plugin by ID. Reloadable plugins are re-initialised
+// live; the response sets restart_required only for a plugin whose Init
+// failed at startup and so needs a panel restart.
 func (h *PluginHandler) Enable(c *gin.Context) {
 	id := c.Param("id")
 	if err := h.mgr.Enable(id); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "Plugin enabled"})
+	c.JSON(http.StatusOK, h.enableResponse(id, "Plugin enabled"))
 }
 
 // Disable disables a plugin by ID.
@@ -44,6 +47,21 @@ func (h *PluginHandler) Disable(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Plugin disabled"})
 }
 
+// enableResponse reports whether enabling was fully applied or needs a panel
+// restart to pick up route and Init-time changes.
+func (h *PluginHandler) enableResponse(id, message string) gin.H {
+	switch {
+	case h.mgr.CanReload(id):
+		return gin.H{"message": message, "reloaded": true}
+	case h.mgr.RestartRequired(id):
+		return gin.H{
+			"message":          message + "; restart WebCasa to apply route and configuration changes",
+			"restart_required": true,
+		}
+	}
+	return gin.H{"message": message}
+}
+
 // FrontendManifests returns the combined frontend manifests for all enabled plugins.
 func (h *PluginHandler) FrontendManifests(c *gin.Context) {
 	c.JSON(http.StatusOK, h.mgr.FrontendManifests())
@@ -0,0 +1,32 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/web-casa/webcasa/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// SearchHandler serves the global search
+type SearchHandler struct {
+	svc *service.SearchService
+}
+
+// NewSearchHandler creates a new SearchHandler
+func NewSearchHandler(svc *service.SearchService) *SearchHandler {
+	return &SearchHandler{svc: svc}
+}
+
+// Search finds hosts, templates, groups, tags and certificates by partial name
+func (h *SearchHandler) Search(c *gin.Context) {
+	results, err := h.svc.Search(c.Query("q"))
+	if err != nil {
+		if err.Error() == "error.search_query_required" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Search query is required", "error_key": "error.search_query_required"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"query": c.Query("q"), "results": results, "total": results.Total()})
+}
@@ -2,25 +2,34 @@ package handler
 
 import (
 	"net/http"
+	"net/mail"
 	"regexp"
 	"strconv"
 	"strings"
 
+	"github.com/web-casa/webcasa/internal/crypto"
 	"github.com/web-casa/webcasa/internal/model"
+	"github.com/web-casa/webcasa/internal/notify"
+	"github.com/web-casa/webcasa/internal/service"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
 // SettingHandler manages panel settings
 type SettingHandler struct {
-	db *gorm.DB
+	db        *gorm.DB
+	jwtSecret string // encrypts secret settings (smtp_password)
 }
 
 // NewSettingHandler creates a new SettingHandler
-func NewSettingHandler(db *gorm.DB) *SettingHandler {
-	return &SettingHandler{db: db}
+func NewSettingHandler(db *gorm.DB, jwtSecret string) *SettingHandler {
+	return &SettingHandler{db: db, jwtSecret: jwtSecret}
 }
 
+// maskedSecret is returned in place of secret settings; writing it back
+// leaves the stored value unchanged.
+const maskedSecret = "********"
+
 // GetAll returns all settings as a key-value map
 func (h *SettingHandler) GetAll(c *gin.Context) {
 	var settings []model.Setting
@@ -28,6 +37,9 @@ func (h *SettingHandler) GetAll(c *gin.Context) {
 	result := make(map[string]string, len(settings))
 	for _, s := range settings {
 		result[s.Key] = s.Value
+		if s.Key == "smtp_password" && s.Value != "" {
+			result[s.Key] = maskedSecret
+		}
 	}
 	c.JSON(http.StatusOK, gin.H{"settings": result})
 }
@@ -62,6 +74,14 @@ func (h *SettingHandler) Update(c *gin.Context) {
 		"server_ipv6":            true,
 		"wildcard_domain":        true, // PB-R2-H2: required by Preview Deploy (v0.14+)
 		"max_concurrent_builds":  true, // v0.17-A1: panel-wide build concurrency cap
+		"reject_domain_overlap":  true, // refuse hosts whose domain overlaps another host's wildcard
+		// SMTP server for panel mail (email 2FA codes, email notifications)
+		"smtp_host":     true,
+		"smtp_port":     true,
+		"smtp_username": true,
+		"smtp_password": true,
+		"smtp_from":     true,
+		"smtp_use_tls":  true,
 	}
 	if !allowed[req.Key] {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "unknown setting: " + req.Key})
@@ -82,11 +102,11 @@ func (h *SettingHandler) Update(c *gin.Context) {
 			}
 			value = v
 		}
-	case "auto_reload":
+	case "auto_reload", "reject_domain_overlap":
 		// Strict boolean string. Anything else (including "") could
 		// silently flip the read-side `!= "false"` default check.
 		if value != "true" && value != "false" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "auto_reload must be 'true' or 'false'"})
+			c.JSON(http.StatusBadRequest, gin.H{"error": req.Key + " must be 'true' or 'false'"})
 			return
 		}
 	case "max_concurrent_builds":
@@ -101,12 +121,85 @@ func (h *SettingHandler) Update(c *gin.Context) {
 			}
 			value = strconv.Itoa(n)
 		}
+	case "smtp_host", "smtp_username", "smtp_from":
+		value = strings.TrimSpace(value)
+		if strings.ContainsAny(value, "\r\n") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": req.Key + " must be a single line"})
+			return
+		}
+	case "smtp_port":
+		if value != "" {
+			n, err := strconv.Atoi(strings.TrimSpace(value))
+			if err != nil || n <= 0 || n > 65535 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "smtp_port must be an integer between 1 and 65535"})
+				return
+			}
+			value = strconv.Itoa(n)
+		}
+	case "smtp_use_tls":
+		if value != "true" && value != "false" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "smtp_use_tls must be 'true' or 'false'"})
+			return
+		}
+	case "smtp_password":
+		if value == maskedSecret {
+			c.JSON(http.StatusOK, gin.H{"message": "Setting updated"})
+			return
+		}
+		enc, err := crypto.Encrypt(value, h.jwtSecret)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to encrypt smtp_password"})
+			return
+		}
+		value = enc
 	}
 
 	h.db.Where("key = ?", req.Key).Assign(model.Setting{Value: value}).FirstOrCreate(&model.Setting{Key: req.Key})
 	c.JSON(http.StatusOK, gin.H{"message": "Setting updated"})
 }
 
+// TestSMTP sends a test message through the panel SMTP settings, to the
+// given address or else the requesting user's email.
+func (h *SettingHandler) TestSMTP(c *gin.Context) {
+	var req struct {
+		To string `json:"to"`
+	}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "error_key": "error.invalid_request"})
+			return
+		}
+	}
+	to := strings.TrimSpace(req.To)
+	if to == "" {
+		var user model.User
+		if err := h.db.Select("email").First(&user, c.GetUint("user_id")).Error; err == nil {
+			to = user.Email
+		}
+	}
+	if to == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "recipient is required", "error_key": "error.smtp_recipient_required"})
+		return
+	}
+	if addr, err := mail.ParseAddress(to); err != nil || addr.Address != to {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid recipient address", "error_key": "error.invalid_email"})
+		return
+	}
+
+	err := service.NewSMTPMailer(h.db, h.jwtSecret).Send(to, "[Web.Casa] Test email",
+		"This is a test email from Web.Casa. If you received this, your SMTP settings are configured correctly.")
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, gin.H{"message": "Test email sent", "to": to})
+	case err.Error() == "error.smtp_not_configured":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "SMTP is not configured", "error_key": "error.smtp_not_configured"})
+	case notify.IsAuthError(err):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "error_key": "error.smtp_auth_failed"})
+	default:
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error(), "error_key": "error.smtp_send_failed"})
+	}
+}
+
 // validWildcardDomain matches a bare DNS suffix: at least two labels,
 // each label `a-z0-9` with optional `-` (not at edges) AND ≤63 chars
 // per RFC 1035, total ≤253. PB-R3-L2 fix.
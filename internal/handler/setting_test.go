@@ -0,0 +1,135 @@
+package handler
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/web-casa/webcasa/internal/crypto"
+	"github.com/web-casa/webcasa/internal/model"
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const settingTestSecret = "test-secret-do-not-use"
+
+func setupSettingTest(t *testing.T) (*gin.Engine, *gorm.DB) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	if err := db.AutoMigrate(&model.Setting{}, &model.User{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	h := NewSettingHandler(db, settingTestSecret)
+	r := gin.New()
+	r.POST("/settings/smtp/test", func(c *gin.Context) {
+		c.Set("user_id", uint(1))
+		h.TestSMTP(c)
+	})
+	return r, db
+}
+
+// rejectingSMTP starts an SMTP server on 127.0.0.1 that refuses every login
+// and returns its port.
+func rejectingSMTP(t *testing.T) int {
+	t.Helper()
+	l, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				conn.Write([]byte("220 mock ESMTP\r\n"))
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					switch cmd := strings.ToUpper(strings.TrimSpace(line)); {
+					case strings.HasPrefix(cmd, "EHLO"):
+						conn.Write([]byte("250-mock\r\n250 AUTH PLAIN\r\n"))
+					case strings.HasPrefix(cmd, "AUTH"):
+						conn.Write([]byte("535 5.7.8 Authentication credentials invalid\r\n"))
+					case cmd == "QUIT":
+						conn.Write([]byte("221 Bye\r\n"))
+						return
+					default:
+						conn.Write([]byte("250 OK\r\n"))
+					}
+				}
+			}()
+		}
+	}()
+	return l.Addr().(*net.TCPAddr).Port
+}
+
+func postSMTPTest(r *gin.Engine, body string) (int, map[string]interface{}) {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/settings/smtp/test", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+	var resp map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	return w.Code, resp
+}
+
+func TestTestSMTP_AuthFailure(t *testing.T) {
+	r, db := setupSettingTest(t)
+	password, err := crypto.Encrypt("wrong", settingTestSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, value := range map[string]string{
+		"smtp_host":     "127.0.0.1",
+		"smtp_port":     strconv.Itoa(rejectingSMTP(t)),
+		"smtp_username": "panel",
+		"smtp_password": password,
+		"smtp_use_tls":  "false",
+	} {
+		db.Create(&model.Setting{Key: key, Value: value})
+	}
+
+	code, resp := postSMTPTest(r, `{"to":"ops@example.com"}`)
+	if code != http.StatusBadRequest || resp["error_key"] != "error.smtp_auth_failed" {
+		t.Fatalf("expected 400 smtp_auth_failed, got %d %v", code, resp)
+	}
+	if msg, _ := resp["error"].(string); !strings.Contains(msg, "535") {
+		t.Fatalf("expected the server's reply in the error, got %q", msg)
+	}
+}
+
+func TestTestSMTP_NotConfigured(t *testing.T) {
+	r, db := setupSettingTest(t)
+	db.Create(&model.User{Username: "admin", Password: "x", Email: "admin@example.com"})
+
+	// No recipient given: the requesting user's email is used.
+	code, resp := postSMTPTest(r, "")
+	if code != http.StatusBadRequest || resp["error_key"] != "error.smtp_not_configured" {
+		t.Fatalf("expected 400 smtp_not_configured, got %d %v", code, resp)
+	}
+
+	code, resp = postSMTPTest(r, `{"to":"not an address"}`)
+	if code != http.StatusBadRequest || resp["error_key"] != "error.invalid_email" {
+		t.Fatalf("expected 400 invalid_email, got %d %v", code, resp)
+	}
+}
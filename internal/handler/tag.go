@@ -23,7 +23,7 @@ func NewTagHandler(svc *service.TagService, db *gorm.DB) *TagHandler {
 func (h *TagHandler) audit(c *gin.Context, action, targetID, detail string) {
 	if uid, ok := c.Get("user_id"); ok {
 		uname, _ := c.Get("username")
-		WriteAuditLog(h.db, uid.(uint), fmt.Sprint(uname), action, "tag", targetID, detail, c.ClientIP())
+		WriteAuditLog(h.db, c, uid.(uint), fmt.Sprint(uname), action, "tag", targetID, detail)
 	}
 }
 
@@ -50,6 +50,13 @@ func (h *TagHandler) Create(c *gin.Context) {
 
 	tag, err := h.svc.Create(req.Name, req.Color)
 	if err != nil {
+		if err.Error() == "error.invalid_color" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":     fmt.Sprintf("invalid color '%s' (expected a hex code such as #3b82f6)", req.Color),
+				"error_key": "error.invalid_color",
+			})
+			return
+		}
 		if err.Error() == "error.tag_name_exists" {
 			c.JSON(http.StatusBadRequest, gin.H{
 				"error":     fmt.Sprintf("tag name '%s' already exists", req.Name),
@@ -84,6 +91,13 @@ func (h *TagHandler) Update(c *gin.Context) {
 
 	tag, err := h.svc.Update(id, req.Name, req.Color)
 	if err != nil {
+		if err.Error() == "error.invalid_color" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":     fmt.Sprintf("invalid color '%s' (expected a hex code such as #3b82f6)", req.Color),
+				"error_key": "error.invalid_color",
+			})
+			return
+		}
 		if err.Error() == "error.tag_name_exists" {
 			c.JSON(http.StatusBadRequest, gin.H{
 				"error":     fmt.Sprintf("tag name '%s' already exists", req.Name),
@@ -119,3 +133,78 @@ func (h *TagHandler) Delete(c *gin.Context) {
 	h.audit(c, "DELETE", fmt.Sprint(id), "Deleted tag")
 	c.JSON(http.StatusOK, gin.H{"message": "Tag deleted successfully"})
 }
+
+// ListRules returns all tag auto-assignment rules
+func (h *TagHandler) ListRules(c *gin.Context) {
+	rules, err := h.svc.ListRules()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "error_key": "error.tag_rule_list_failed"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"rules": rules, "total": len(rules)})
+}
+
+// CreateRule adds a tag auto-assignment rule
+func (h *TagHandler) CreateRule(c *gin.Context) {
+	var req struct {
+		TagID      uint   `json:"tag_id" binding:"required"`
+		MatchField string `json:"match_field" binding:"required"`
+		Pattern    string `json:"pattern" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "error_key": "error.invalid_request"})
+		return
+	}
+
+	rule, err := h.svc.CreateRule(req.TagID, req.MatchField, req.Pattern)
+	if err != nil {
+		switch err.Error() {
+		case "error.tag_not_found":
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Tag not found", "error_key": "error.tag_not_found"})
+		case "error.invalid_tag_rule":
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":     fmt.Sprintf("invalid rule: match_field must be domain or host_type and pattern a valid glob, got %s '%s'", req.MatchField, req.Pattern),
+				"error_key": "error.invalid_tag_rule",
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "error_key": "error.tag_rule_create_failed"})
+		}
+		return
+	}
+
+	h.audit(c, "CREATE", fmt.Sprint(rule.TagID), fmt.Sprintf("Created tag rule %s matches '%s'", rule.MatchField, rule.Pattern))
+	c.JSON(http.StatusCreated, rule)
+}
+
+// DeleteRule removes a tag auto-assignment rule
+func (h *TagHandler) DeleteRule(c *gin.Context) {
+	id, err := parseID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID", "error_key": "error.invalid_id"})
+		return
+	}
+
+	if err := h.svc.DeleteRule(id); err != nil {
+		if err.Error() == "error.tag_rule_not_found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Tag rule not found", "error_key": "error.tag_rule_not_found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "error_key": "error.tag_rule_delete_failed"})
+		return
+	}
+
+	h.audit(c, "DELETE", "", fmt.Sprintf("Deleted tag rule %d", id))
+	c.JSON(http.StatusOK, gin.H{"message": "Tag rule deleted successfully"})
+}
+
+// ApplyRules tags all existing hosts matching the tag rules
+func (h *TagHandler) ApplyRules(c *gin.Context) {
+	added, err := h.svc.ApplyRules()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "error_key": "error.tag_rule_apply_failed"})
+		return
+	}
+
+	h.audit(c, "UPDATE", "", fmt.Sprintf("Applied tag rules, %d tags added", added))
+	c.JSON(http.StatusOK, gin.H{"added": added})
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 
 	"github.com/web-casa/webcasa/internal/service"
 	"github.com/gin-gonic/gin"
@@ -24,13 +25,16 @@ func NewTemplateHandler(svc *service.TemplateService, db *gorm.DB) *TemplateHand
 func (h *TemplateHandler) audit(c *gin.Context, action, targetID, detail string) {
 	if uid, ok := c.Get("user_id"); ok {
 		uname, _ := c.Get("username")
-		WriteAuditLog(h.db, uid.(uint), fmt.Sprint(uname), action, "template", targetID, detail, c.ClientIP())
+		WriteAuditLog(h.db, c, uid.(uint), fmt.Sprint(uname), action, "template", targetID, detail)
 	}
 }
 
-// List returns all templates.
+// List returns all templates, optionally filtered by ?category= and ?type=.
 func (h *TemplateHandler) List(c *gin.Context) {
-	templates, err := h.svc.List()
+	templates, err := h.svc.List(service.TemplateListFilter{
+		Category: strings.TrimSpace(c.Query("category")),
+		Type:     strings.TrimSpace(c.Query("type")),
+	})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "error_key": "error.template_list_failed"})
 		return
@@ -43,6 +47,7 @@ func (h *TemplateHandler) Create(c *gin.Context) {
 	var req struct {
 		Name        string `json:"name" binding:"required"`
 		Description string `json:"description"`
+		Category    string `json:"category"`
 		Config      string `json:"config" binding:"required"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -50,7 +55,7 @@ func (h *TemplateHandler) Create(c *gin.Context) {
 		return
 	}
 
-	tpl, err := h.svc.Create(req.Name, req.Description, req.Config)
+	tpl, err := h.svc.Create(req.Name, req.Description, req.Category, req.Config)
 	if err != nil {
 		errMsg := err.Error()
 		switch errMsg {
@@ -58,6 +63,8 @@ func (h *TemplateHandler) Create(c *gin.Context) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template config JSON", "error_key": errMsg})
 		case "error.template_missing_fields":
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Template config missing required fields (host_type)", "error_key": errMsg})
+		case "error.invalid_template_category":
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template category", "error_key": errMsg})
 		default:
 			c.JSON(http.StatusInternalServerError, gin.H{"error": errMsg, "error_key": "error.template_create_failed"})
 		}
@@ -79,6 +86,7 @@ func (h *TemplateHandler) Update(c *gin.Context) {
 	var req struct {
 		Name        string `json:"name" binding:"required"`
 		Description string `json:"description"`
+		Category    string `json:"category"`
 		Config      string `json:"config"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -86,7 +94,7 @@ func (h *TemplateHandler) Update(c *gin.Context) {
 		return
 	}
 
-	tpl, err := h.svc.Update(id, req.Name, req.Description, req.Config)
+	tpl, err := h.svc.Update(id, req.Name, req.Description, req.Category, req.Config)
 	if err != nil {
 		errMsg := err.Error()
 		switch errMsg {
@@ -98,6 +106,8 @@ func (h *TemplateHandler) Update(c *gin.Context) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template config JSON", "error_key": errMsg})
 		case "error.template_missing_fields":
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Template config missing required fields", "error_key": errMsg})
+		case "error.invalid_template_category":
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template category", "error_key": errMsg})
 		default:
 			c.JSON(http.StatusBadRequest, gin.H{"error": errMsg, "error_key": "error.template_update_failed"})
 		}
@@ -177,6 +187,8 @@ func (h *TemplateHandler) handleImportError(c *gin.Context, err error) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template JSON format", "error_key": errMsg})
 	case "error.template_missing_fields":
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Template JSON missing required fields", "error_key": errMsg})
+	case "error.invalid_template_category":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template category", "error_key": errMsg})
 	default:
 		c.JSON(http.StatusBadRequest, gin.H{"error": errMsg, "error_key": "error.template_import_failed"})
 	}
@@ -222,6 +234,9 @@ func (h *TemplateHandler) CreateHost(c *gin.Context) {
 	}
 
 	host, err := h.svc.CreateFromTemplate(id, req.Domain)
+	if respondDomainOverlap(c, err) {
+		return
+	}
 	if err != nil {
 		errMsg := err.Error()
 		switch errMsg {
@@ -245,6 +260,50 @@ func (h *TemplateHandler) CreateHost(c *gin.Context) {
 	c.JSON(http.StatusCreated, host)
 }
 
+// CreateHosts creates a host per domain from a template with a single config
+// reload and reports the outcome for each domain.
+func (h *TemplateHandler) CreateHosts(c *gin.Context) {
+	id, err := parseID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID", "error_key": "error.invalid_id"})
+		return
+	}
+
+	var req struct {
+		Domains []string `json:"domains" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "error_key": "error.invalid_request"})
+		return
+	}
+
+	results, err := h.svc.CreateHostsFromTemplate(id, req.Domains)
+	if err != nil {
+		errMsg := err.Error()
+		switch errMsg {
+		case "error.template_not_found":
+			c.JSON(http.StatusNotFound, gin.H{"error": "Template not found", "error_key": errMsg})
+		case "error.invalid_template_json":
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Template config is invalid", "error_key": errMsg})
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": errMsg, "error_key": "error.template_create_host_failed"})
+		}
+		return
+	}
+
+	var created []string
+	for _, r := range results {
+		if r.Status == "created" {
+			created = append(created, r.Domain)
+		}
+	}
+	if len(created) > 0 {
+		h.audit(c, "CREATE_FROM_TEMPLATE", "",
+			fmt.Sprintf("Created %d host(s) from template #%d: %s", len(created), id, strings.Join(created, ", ")))
+	}
+	c.JSON(http.StatusOK, gin.H{"results": results})
+}
+
 // SaveAsTemplate creates a template from an existing host (called from host context).
 func (h *TemplateHandler) SaveAsTemplate(c *gin.Context) {
 	id, err := parseID(c)
@@ -256,18 +315,23 @@ func (h *TemplateHandler) SaveAsTemplate(c *gin.Context) {
 	var req struct {
 		Name        string `json:"name" binding:"required"`
 		Description string `json:"description"`
+		Category    string `json:"category"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "error_key": "error.invalid_request"})
 		return
 	}
 
-	tpl, err := h.svc.SaveAsTemplate(id, req.Name, req.Description)
+	tpl, err := h.svc.SaveAsTemplate(id, req.Name, req.Description, req.Category)
 	if err != nil {
 		if err.Error() == "error.host_not_found" {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Host not found", "error_key": "error.host_not_found"})
 			return
 		}
+		if err.Error() == "error.invalid_template_category" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template category", "error_key": "error.invalid_template_category"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "error_key": "error.template_save_failed"})
 		return
 	}
@@ -0,0 +1,167 @@
+package handler
+
+import (
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/web-casa/webcasa/internal/caddy"
+)
+
+// trafficCacheTTL is how long dashboard traffic stats are reused before the
+// access logs are parsed again.
+const trafficCacheTTL = time.Minute
+
+// trafficWindow and trafficBuckets shape the dashboard traffic stats: totals
+// over the window and a series of equal buckets for the sparkline.
+const (
+	trafficWindow  = 24 * time.Hour
+	trafficBuckets = 24
+)
+
+// TrafficStats summarizes request volume over the last 24 hours.
+type TrafficStats struct {
+	Requests24h int64          `json:"requests_24h"`
+	Bytes24h    int64          `json:"bytes_24h"`
+	Series      []TrafficPoint `json:"series"` // oldest first
+}
+
+// TrafficPoint is one sparkline bucket starting at Time.
+type TrafficPoint struct {
+	Time     time.Time `json:"time"`
+	Requests int64     `json:"requests"`
+	Bytes    int64     `json:"bytes"`
+}
+
+// accessLogSource supplies access log entries for traffic stats.
+type accessLogSource interface {
+	// EachAccessEntry calls fn for every entry that may be newer than
+	// since. Entries older than since may still be passed.
+	EachAccessEntry(since time.Time, fn func(caddy.AccessEntry)) error
+}
+
+// accessLogDir reads every access log in a directory.
+type accessLogDir string
+
+// EachAccessEntry streams the entries of every access log in the directory,
+// skipping files, such as old rotated logs, last written before since.
+func (d accessLogDir) EachAccessEntry(since time.Time, fn func(caddy.AccessEntry)) error {
+	for _, path := range caddy.AccessLogFiles(string(d), "") {
+		if info, err := os.Stat(path); err != nil || info.ModTime().Before(since) {
+			continue
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			continue
+		}
+		err = caddy.ScanAccessLog(f, fn)
+		f.Close()
+		if err != nil {
+			log.Printf("Warning: access log %s: %v", path, err)
+		}
+	}
+	return nil
+}
+
+// trafficAccumulator totals entries in the trafficWindow ending at now, one
+// entry at a time.
+type trafficAccumulator struct {
+	start, now time.Time
+	step       time.Duration
+	stats      TrafficStats
+}
+
+func newTrafficAccumulator(now time.Time) *trafficAccumulator {
+	a := &trafficAccumulator{
+		start: now.Add(-trafficWindow),
+		now:   now,
+		step:  trafficWindow / trafficBuckets,
+		stats: TrafficStats{Series: make([]TrafficPoint, trafficBuckets)},
+	}
+	for i := range a.stats.Series {
+		a.stats.Series[i].Time = a.start.Add(time.Duration(i) * a.step)
+	}
+	return a
+}
+
+func (a *trafficAccumulator) add(e caddy.AccessEntry) {
+	if e.TS.Before(a.start) || e.TS.After(a.now) {
+		return
+	}
+	i := int(e.TS.Sub(a.start) / a.step)
+	if i >= trafficBuckets {
+		i = trafficBuckets - 1
+	}
+	a.stats.Requests24h++
+	a.stats.Bytes24h += e.Size
+	a.stats.Series[i].Requests++
+	a.stats.Series[i].Bytes += e.Size
+}
+
+// aggregateTraffic totals entries in the trafficWindow ending at now.
+func aggregateTraffic(entries []caddy.AccessEntry, now time.Time) TrafficStats {
+	a := newTrafficAccumulator(now)
+	for _, e := range entries {
+		a.add(e)
+	}
+	return a.stats
+}
+
+// trafficCache recomputes TrafficStats at most once per trafficCacheTTL.
+// The logs are read outside mu, and only one refresh runs at a time;
+// callers arriving during a refresh get the previous stats.
+type trafficCache struct {
+	src accessLogSource
+	now func() time.Time
+
+	refresh sync.Mutex // held while the logs are read
+
+	mu         sync.Mutex
+	stats      TrafficStats
+	computedAt time.Time
+}
+
+func newTrafficCache(src accessLogSource) *trafficCache {
+	return &trafficCache{src: src, now: time.Now}
+}
+
+// cached returns the stored stats, whether there are any, and whether they
+// are still fresh at now.
+func (c *trafficCache) cached(now time.Time) (TrafficStats, bool, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ok := !c.computedAt.IsZero()
+	return c.stats, ok, ok && now.Sub(c.computedAt) < trafficCacheTTL
+}
+
+func (c *trafficCache) Get() TrafficStats {
+	stats, ok, fresh := c.cached(c.now())
+	if fresh {
+		return stats
+	}
+	if ok {
+		if !c.refresh.TryLock() {
+			return stats
+		}
+	} else {
+		c.refresh.Lock()
+	}
+	defer c.refresh.Unlock()
+
+	// Another caller may have refreshed while this one waited.
+	now := c.now()
+	if stats, _, fresh := c.cached(now); fresh {
+		return stats
+	}
+	acc := newTrafficAccumulator(now)
+	if err := c.src.EachAccessEntry(now.Add(-trafficWindow), acc.add); err != nil {
+		log.Printf("Warning: traffic stats: %v", err)
+	}
+
+	c.mu.Lock()
+	c.stats = acc.stats
+	c.computedAt = now
+	c.mu.Unlock()
+	return acc.stats
+}
@@ -0,0 +1,147 @@
+package handler
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/web-casa/webcasa/internal/caddy"
+)
+
+type stubAccessLogs struct {
+	entries []caddy.AccessEntry
+	calls   int
+
+	started chan struct{} // when set, signalled once reading begins
+	release chan struct{} // when set, reading waits for it
+}
+
+func (s *stubAccessLogs) EachAccessEntry(since time.Time, fn func(caddy.AccessEntry)) error {
+	s.calls++
+	if s.started != nil {
+		s.started <- struct{}{}
+	}
+	if s.release != nil {
+		<-s.release
+	}
+	for _, e := range s.entries {
+		fn(e)
+	}
+	return nil
+}
+
+func TestAggregateTraffic(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	stats := aggregateTraffic([]caddy.AccessEntry{
+		{TS: now.Add(-25 * time.Hour), Size: 1000}, // outside the window
+		{TS: now.Add(-23*time.Hour - 30*time.Minute), Size: 100},
+		{TS: now.Add(-10 * time.Minute), Size: 200},
+		{TS: now.Add(-5 * time.Minute), Size: 300},
+		{TS: now, Size: 400},
+	}, now)
+
+	if stats.Requests24h != 4 || stats.Bytes24h != 1000 {
+		t.Errorf("totals = %d requests, %d bytes; want 4, 1000", stats.Requests24h, stats.Bytes24h)
+	}
+	if len(stats.Series) != trafficBuckets {
+		t.Fatalf("series has %d points, want %d", len(stats.Series), trafficBuckets)
+	}
+	if p := stats.Series[0]; p.Requests != 1 || p.Bytes != 100 || !p.Time.Equal(now.Add(-24*time.Hour)) {
+		t.Errorf("first bucket = %+v", p)
+	}
+	if p := stats.Series[trafficBuckets-1]; p.Requests != 3 || p.Bytes != 900 {
+		t.Errorf("last bucket = %+v", p)
+	}
+}
+
+func TestTrafficCache_ReusesWithinTTL(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	src := &stubAccessLogs{entries: []caddy.AccessEntry{{TS: now.Add(-time.Hour), Size: 50}}}
+	cache := newTrafficCache(src)
+	cache.now = func() time.Time { return now }
+
+	first := cache.Get()
+	src.entries = append(src.entries, caddy.AccessEntry{TS: now.Add(-time.Minute), Size: 70})
+	now = now.Add(trafficCacheTTL / 2)
+	if second := cache.Get(); second.Requests24h != first.Requests24h || src.calls != 1 {
+		t.Errorf("within TTL: requests = %d, source calls = %d; want cached value", second.Requests24h, src.calls)
+	}
+
+	now = now.Add(trafficCacheTTL)
+	if third := cache.Get(); third.Requests24h != 2 || third.Bytes24h != 120 || src.calls != 2 {
+		t.Errorf("after TTL: %+v, source calls = %d", third, src.calls)
+	}
+}
+
+func TestTrafficCache_ServesStaleDuringRefresh(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	src := &stubAccessLogs{entries: []caddy.AccessEntry{{TS: now.Add(-time.Hour), Size: 50}}}
+	cache := newTrafficCache(src)
+	var mu sync.Mutex
+	cache.now = func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	}
+	cache.Get()
+
+	mu.Lock()
+	now = now.Add(2 * trafficCacheTTL)
+	mu.Unlock()
+	src.entries = append(src.entries, caddy.AccessEntry{TS: now.Add(-time.Minute), Size: 70})
+	src.started, src.release = make(chan struct{}), make(chan struct{})
+	done := make(chan TrafficStats)
+	go func() { done <- cache.Get() }()
+	<-src.started
+
+	// The slow refresh holds no lock other callers need.
+	if stale := cache.Get(); stale.Requests24h != 1 {
+		t.Errorf("during refresh: requests = %d, want the previous 1", stale.Requests24h)
+	}
+	close(src.release)
+	if fresh := <-done; fresh.Requests24h != 2 || src.calls != 2 {
+		t.Errorf("after refresh: requests = %d, source calls = %d", fresh.Requests24h, src.calls)
+	}
+}
+
+func collectAccessEntries(t *testing.T, d accessLogDir, since time.Time) []caddy.AccessEntry {
+	t.Helper()
+	var entries []caddy.AccessEntry
+	if err := d.EachAccessEntry(since, func(e caddy.AccessEntry) { entries = append(entries, e) }); err != nil {
+		t.Fatal(err)
+	}
+	return entries
+}
+
+func TestAccessLogDir_ReadsAllLogs(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "access-a.example.com.log"), []byte(
+		`{"ts":1700000000,"request":{"host":"a.example.com","uri":"/"},"size":10,"status":200}`+"\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "access.log"), []byte(
+		`{"ts":1700000001,"request":{"host":"b.example.com","uri":"/"},"size":20,"status":200}`+"\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "caddy.log"), []byte(
+		`{"ts":1700000002,"request":{"host":"c.example.com","uri":"/"},"size":30,"status":200}`+"\n"), 0644)
+
+	entries := collectAccessEntries(t, accessLogDir(dir), time.Time{})
+	if len(entries) != 2 || entries[0].Size+entries[1].Size != 30 {
+		t.Errorf("entries = %+v, want the per-host and shared access logs only", entries)
+	}
+}
+
+func TestAccessLogDir_SkipsLogsOlderThanWindow(t *testing.T) {
+	dir := t.TempDir()
+	rotated := filepath.Join(dir, "access-a.example.com-2024-01-01T00-00-00.000.log")
+	os.WriteFile(rotated, []byte(
+		`{"ts":1700000000,"request":{"host":"a.example.com","uri":"/"},"size":10,"status":200}`+"\n"), 0644)
+	old := time.Now().Add(-2 * trafficWindow)
+	os.Chtimes(rotated, old, old)
+	os.WriteFile(filepath.Join(dir, "access.log"), []byte(
+		`{"ts":1700000001,"request":{"host":"b.example.com","uri":"/"},"size":20,"status":200}`+"\n"), 0644)
+
+	entries := collectAccessEntries(t, accessLogDir(dir), time.Now().Add(-trafficWindow))
+	if len(entries) != 1 || entries[0].Size != 20 {
+		t.Errorf("entries = %+v, want only the log written within the window", entries)
+	}
+}
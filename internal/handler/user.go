@@ -88,8 +88,8 @@ func (h *UserHandler) Create(c *gin.Context) {
 	// Audit
 	if uid, ok := c.Get("user_id"); ok {
 		uname, _ := c.Get("username")
-		WriteAuditLog(h.db, uid.(uint), fmt.Sprint(uname), "CREATE", "user", fmt.Sprint(user.ID),
-			fmt.Sprintf("Created user '%s' with role '%s'", user.Username, user.Role), c.ClientIP())
+		WriteAuditLog(h.db, c, uid.(uint), fmt.Sprint(uname), "CREATE", "user", fmt.Sprint(user.ID),
+			fmt.Sprintf("Created user '%s' with role '%s'", user.Username, user.Role))
 	}
 
 	c.JSON(http.StatusCreated, user)
@@ -163,8 +163,8 @@ func (h *UserHandler) Update(c *gin.Context) {
 
 	if uid, ok := c.Get("user_id"); ok {
 		uname, _ := c.Get("username")
-		WriteAuditLog(h.db, uid.(uint), fmt.Sprint(uname), "UPDATE", "user", fmt.Sprint(user.ID),
-			fmt.Sprintf("Updated user '%s'", user.Username), c.ClientIP())
+		WriteAuditLog(h.db, c, uid.(uint), fmt.Sprint(uname), "UPDATE", "user", fmt.Sprint(user.ID),
+			fmt.Sprintf("Updated user '%s'", user.Username))
 	}
 
 	c.JSON(http.StatusOK, user)
@@ -200,8 +200,8 @@ func (h *UserHandler) Delete(c *gin.Context) {
 
 	if uid, ok := c.Get("user_id"); ok {
 		uname, _ := c.Get("username")
-		WriteAuditLog(h.db, uid.(uint), fmt.Sprint(uname), "DELETE", "user", fmt.Sprint(id),
-			fmt.Sprintf("Deleted user '%s'", user.Username), c.ClientIP())
+		WriteAuditLog(h.db, c, uid.(uint), fmt.Sprint(uname), "DELETE", "user", fmt.Sprint(id),
+			fmt.Sprintf("Deleted user '%s'", user.Username))
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "user deleted"})
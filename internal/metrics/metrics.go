@@ -0,0 +1,117 @@
+// Package metrics exposes panel metrics in the Prometheus text format.
+//
+// Collectors are registered on a dedicated Registry at startup (core
+// collectors in main.go, plugin collectors from their Init) and served by
+// Handler on GET /api/metrics.
+package metrics
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/web-casa/webcasa/internal/model"
+	"gorm.io/gorm"
+)
+
+// Registry holds every panel collector. A private registry (rather than the
+// client library's global one) keeps the exposition limited to panel metrics.
+var Registry = prometheus.NewRegistry()
+
+// LoginAttempts counts primary and 2FA login outcomes, labelled by result
+// ("success" or "failure").
+var LoginAttempts = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "webcasa_login_attempts_total",
+	Help: "Login attempts by result.",
+}, []string{"result"})
+
+func init() {
+	Registry.MustRegister(LoginAttempts)
+}
+
+// Register adds c to Registry. Registering the same collector twice (e.g. a
+// plugin re-initialised in tests) is not an error.
+func Register(c prometheus.Collector) error {
+	err := Registry.Register(c)
+	var already prometheus.AlreadyRegisteredError
+	if errors.As(err, &already) {
+		return nil
+	}
+	return err
+}
+
+// Handler serves Registry in the Prometheus exposition format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// caddyStatus is the part of caddy.Manager the panel collector needs.
+type caddyStatus interface {
+	IsRunning() bool
+}
+
+var (
+	hostsDesc = prometheus.NewDesc(
+		"webcasa_hosts",
+		"Configured hosts by type and enabled state.",
+		[]string{"type", "enabled"}, nil,
+	)
+	caddyUpDesc = prometheus.NewDesc(
+		"webcasa_caddy_up",
+		"Whether the Caddy admin API is reachable (1) or not (0).",
+		nil, nil,
+	)
+)
+
+// PanelCollector reports host counts and Caddy status, read fresh on each
+// scrape.
+type PanelCollector struct {
+	db    *gorm.DB
+	caddy caddyStatus
+}
+
+// NewPanelCollector creates a new PanelCollector
+func NewPanelCollector(db *gorm.DB, caddyMgr caddyStatus) *PanelCollector {
+	return &PanelCollector{db: db, caddy: caddyMgr}
+}
+
+// Describe implements prometheus.Collector.
+func (pc *PanelCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- hostsDesc
+	ch <- caddyUpDesc
+}
+
+// Collect implements prometheus.Collector.
+func (pc *PanelCollector) Collect(ch chan<- prometheus.Metric) {
+	var rows []struct {
+		HostType string
+		Enabled  *bool
+		Count    int64
+	}
+	if err := pc.db.Model(&model.Host{}).
+		Select("host_type, enabled, COUNT(*) AS count").
+		Group("host_type, enabled").
+		Scan(&rows).Error; err != nil {
+		ch <- prometheus.NewInvalidMetric(hostsDesc, err)
+	} else {
+		// NULL enabled means the default (enabled), so fold it into "true".
+		counts := make(map[[2]string]int64)
+		for _, r := range rows {
+			enabled := "true"
+			if r.Enabled != nil && !*r.Enabled {
+				enabled = "false"
+			}
+			counts[[2]string{r.HostType, enabled}] += r.Count
+		}
+		for k, n := range counts {
+			ch <- prometheus.MustNewConstMetric(hostsDesc, prometheus.GaugeValue, float64(n), k[0], k[1])
+		}
+	}
+
+	up := 0.0
+	if pc.caddy.IsRunning() {
+		up = 1
+	}
+	ch <- prometheus.MustNewConstMetric(caddyUpDesc, prometheus.GaugeValue, up)
+}
@@ -0,0 +1,80 @@
+package metrics
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/web-casa/webcasa/internal/model"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type fakeCaddy bool
+
+func (f fakeCaddy) IsRunning() bool { return bool(f) }
+
+func boolPtr(b bool) *bool { return &b }
+
+// scrape serves reg and returns the exposition text.
+func scrape(t *testing.T, reg *prometheus.Registry) string {
+	t.Helper()
+	w := httptest.NewRecorder()
+	promhttp.HandlerFor(reg, promhttp.HandlerOpts{}).ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := io.ReadAll(w.Body)
+	return string(body)
+}
+
+func TestPanelCollector_Exposition(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&model.Host{}); err != nil {
+		t.Fatal(err)
+	}
+	for i, h := range []model.Host{
+		{Domain: "a.example.com", HostType: "proxy", Enabled: boolPtr(true)},
+		{Domain: "b.example.com", HostType: "proxy", Enabled: boolPtr(true)},
+		{Domain: "c.example.com", HostType: "proxy", Enabled: boolPtr(false)},
+		{Domain: "d.example.com", HostType: "redirect", Enabled: boolPtr(true)},
+	} {
+		if err := db.Create(&h).Error; err != nil {
+			t.Fatalf("host %d: %v", i, err)
+		}
+	}
+
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(NewPanelCollector(db, fakeCaddy(true)), LoginAttempts)
+	LoginAttempts.WithLabelValues("failure").Inc()
+
+	out := scrape(t, reg)
+	for _, want := range []string{
+		"# TYPE webcasa_hosts gauge",
+		`webcasa_hosts{enabled="true",type="proxy"} 2`,
+		`webcasa_hosts{enabled="false",type="proxy"} 1`,
+		`webcasa_hosts{enabled="true",type="redirect"} 1`,
+		"webcasa_caddy_up 1",
+		"# TYPE webcasa_login_attempts_total counter",
+		`webcasa_login_attempts_total{result="failure"}`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("exposition missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestRegister_Idempotent(t *testing.T) {
+	c := prometheus.NewCounter(prometheus.CounterOpts{Name: "webcasa_test_register_total", Help: "test"})
+	if err := Register(c); err != nil {
+		t.Fatal(err)
+	}
+	if err := Register(c); err != nil {
+		t.Errorf("second Register should be a no-op, got %v", err)
+	}
+	Registry.Unregister(c)
+}
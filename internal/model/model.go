@@ -2,19 +2,25 @@ package model
 
 import (
 	"time"
+
+	"gorm.io/gorm"
 )
 
 // User represents a panel administrator
 type User struct {
 	ID               uint      `gorm:"primaryKey" json:"id"`
 	Username         string    `gorm:"uniqueIndex;not null;size:64" json:"username"`
-	Password         string    `gorm:"not null" json:"-"`                          // bcrypt hash, never exposed in JSON
-	Role             string    `gorm:"not null;size:16;default:admin" json:"role"` // owner, admin, operator, viewer
-	TOTPSecret       string    `gorm:"size:512" json:"-"`                          // AES-GCM encrypted TOTP secret
-	TOTPEnabled      *bool     `gorm:"default:false" json:"totp_enabled"`          // whether 2FA is enabled
-	LastTOTPTimestep int64     `gorm:"default:0" json:"-"`                         // last accepted TOTP timestep (replay protection)
-	RecoveryCodes    string    `gorm:"type:text" json:"-"`                         // JSON array of recovery code hashes
-	TokenVersion     int       `gorm:"default:0" json:"-"`                         // bumped on password/role change to revoke outstanding JWTs
+	Password         string    `gorm:"not null" json:"-"`                             // bcrypt hash, never exposed in JSON
+	Role             string    `gorm:"not null;size:16;default:admin" json:"role"`    // owner, admin, operator, viewer
+	TOTPSecret       string    `gorm:"size:512" json:"-"`                             // AES-GCM encrypted TOTP secret
+	TOTPEnabled      *bool     `gorm:"default:false" json:"totp_enabled"`             // whether 2FA is enabled
+	LastTOTPTimestep int64     `gorm:"default:0" json:"-"`                            // last accepted TOTP timestep (replay protection)
+	RecoveryCodes    string    `gorm:"type:text" json:"-"`                            // JSON array of recovery code hashes
+	TwoFactorMethod  string    `gorm:"size:16;default:totp" json:"two_factor_method"` // "totp" or "email"
+	Email            string    `gorm:"size:255" json:"email"`                         // destination for email 2FA codes
+	EmailOTPHash     string    `gorm:"size:128" json:"-"`                             // bcrypt hash of the outstanding email code
+	EmailOTPExpires  time.Time `json:"-"`                                             // when the outstanding email code expires
+	TokenVersion     int       `gorm:"default:0" json:"-"`                            // bumped on password/role change to revoke outstanding JWTs
 	CreatedAt        time.Time `json:"created_at"`
 	UpdatedAt        time.Time `json:"updated_at"`
 }
@@ -51,7 +57,7 @@ type Certificate struct {
 // Host represents a reverse proxy or redirect host configuration
 type Host struct {
 	ID             uint   `gorm:"primaryKey" json:"id"`
-	Domain         string `gorm:"not null;uniqueIndex;size:255" json:"domain"`
+	Domain         string `gorm:"not null;size:255;uniqueIndex:idx_hosts_domain_live,where:deleted_at IS NULL" json:"domain"`
 	HostType       string `gorm:"not null;size:16;default:proxy" json:"host_type"` // "proxy", "redirect", "static", "php"
 	Enabled        *bool  `gorm:"default:true" json:"enabled"`
 	TLSEnabled     *bool  `gorm:"default:true" json:"tls_enabled"`
@@ -79,8 +85,70 @@ type Host struct {
 	// Phase 4 batch 3: new host types
 	RootPath        string `gorm:"size:512" json:"root_path"`             // root directory for static/PHP hosts
 	DirectoryBrowse *bool  `gorm:"default:false" json:"directory_browse"` // enable directory listing
+	SPAFallback     *bool  `gorm:"default:false" json:"spa_fallback"`     // serve /index.html for unknown paths (static only)
 	PHPFastCGI      string `gorm:"size:255" json:"php_fastcgi"`           // PHP-FPM address e.g. "localhost:9000"
+	PHPVersion      string `gorm:"size:16" json:"php_version"`            // picks the FPM socket when PHPFastCGI is empty
 	IndexFiles      string `gorm:"size:255" json:"index_files"`           // custom index files e.g. "index.html index.php"
+	// SeparateAccessLog writes this host's access log to its own file
+	// (access-<domain>.log) instead of the shared access.log.
+	SeparateAccessLog *bool `gorm:"default:true" json:"separate_access_log"`
+	// MaxRequestBodySize caps request bodies (e.g. "10MB"); empty means no limit.
+	MaxRequestBodySize string `gorm:"size:32" json:"max_request_body_size"`
+	// Reverse proxy transport timeouts as Go durations (e.g. "30s", "5m");
+	// empty keeps Caddy's default.
+	ProxyReadTimeout  string `gorm:"size:16" json:"proxy_read_timeout"`
+	ProxyWriteTimeout string `gorm:"size:16" json:"proxy_write_timeout"`
+	ProxyDialTimeout  string `gorm:"size:16" json:"proxy_dial_timeout"`
+	// MaintenanceMode answers every request with 503 and MaintenancePage
+	// (inline HTML, or a default message when empty) while keeping TLS.
+	MaintenanceMode *bool  `gorm:"default:false" json:"maintenance_mode"`
+	MaintenancePage string `gorm:"type:text" json:"maintenance_page"`
+	// DisplayDomain is the Unicode form of an internationalized Domain,
+	// which is stored as punycode; empty for plain ASCII domains.
+	DisplayDomain string `gorm:"size:255" json:"display_domain,omitempty"`
+	// Aliases are extra comma-separated domains served by this host; Domain
+	// stays the primary name.
+	Aliases string `gorm:"type:text" json:"aliases"`
+	// BasicAuthRealm is shown in the browser's login prompt; empty keeps
+	// Caddy's default.
+	BasicAuthRealm string `gorm:"size:255" json:"basic_auth_realm"`
+	// ForwardAuthURL delegates authentication to an external server (e.g.
+	// Authelia) via Caddy's forward_auth; empty disables it.
+	// ForwardAuthCopyHeaders lists the response headers (comma-separated)
+	// copied from the auth server into the proxied request.
+	ForwardAuthURL         string `gorm:"size:512" json:"forward_auth_url"`
+	ForwardAuthCopyHeaders string `gorm:"size:512" json:"forward_auth_copy_headers"`
+	// SecurityHeadersConfig tunes each security header individually. When
+	// set it replaces the SecurityHeaders preset; nil keeps the preset.
+	SecurityHeadersConfig *SecurityHeadersConfig `gorm:"type:text;serializer:json" json:"security_headers_config"`
+	// CorsAllowCredentials lets browsers send cookies and auth headers on
+	// cross-origin requests (not allowed with a wildcard origin).
+	// CorsMaxAge is how long preflight answers may be cached in seconds;
+	// 0 uses the default of 86400.
+	CorsAllowCredentials *bool `gorm:"default:false" json:"cors_allow_credentials"`
+	CorsMaxAge           int   `json:"cors_max_age"`
+	// CompressionAlgorithms picks the encoders used when Compression is on,
+	// comma-separated in order of preference (gzip, zstd, br); empty keeps
+	// the default "gzip, zstd".
+	CompressionAlgorithms string `gorm:"size:32" json:"compression_algorithms"`
+	// ACMECA is the ACME directory the host's certificate is requested from:
+	// empty for Caddy's default, "staging" for the Let's Encrypt staging CA,
+	// or an https directory URL.
+	ACMECA string `gorm:"size:512" json:"acme_ca"`
+	// Version increments on every Update; edits must carry the version they
+	// were based on so concurrent edits cannot silently overwrite each other.
+	Version uint `gorm:"not null;default:1" json:"version"`
+	// ConfigError holds Caddy's validation output when this host's block was
+	// found to break the Caddyfile; the host is left out of the rendered
+	// config until it is edited.
+	ConfigError string `gorm:"type:text" json:"config_error,omitempty"`
+	// Apply reports how the config apply run by the request returning this
+	// host went, so a saved change Caddy did not pick up can be flagged. It
+	// is not stored.
+	Apply *ApplyResult `gorm:"-" json:"apply,omitempty"`
+	// Warning notes a problem the request returning this host saved despite,
+	// such as a domain overlapping another host's. It is not stored.
+	Warning *HostWarning `gorm:"-" json:"warning,omitempty"`
 	// Phase 6: group and tag associations
 	// Per-host configuration overrides (JSON map, 3-tier: host → global → default)
 	ConfigOverrides string         `gorm:"type:text" json:"config_overrides,omitempty"`
@@ -94,6 +162,24 @@ type Host struct {
 	BasicAuths      []BasicAuth    `gorm:"foreignKey:HostID;constraint:OnDelete:CASCADE" json:"basic_auths"`
 	CreatedAt       time.Time      `json:"created_at"`
 	UpdatedAt       time.Time      `json:"updated_at"`
+	DeletedAt       gorm.DeletedAt `gorm:"index" json:"deleted_at"` // set while the host is in the trash
+}
+
+// ApplyResult describes what a config apply did.
+type ApplyResult struct {
+	Written         bool   `json:"written"`                    // the new Caddyfile is the live file
+	Reloaded        bool   `json:"reloaded"`                   // Caddy reloaded or started with it
+	ValidationError string `json:"validation_error,omitempty"` // why Caddy rejected the Caddyfile
+	ReloadError     string `json:"reload_error,omitempty"`     // why the reload or start failed
+}
+
+// HostWarning describes a problem a host was saved despite. Key is the
+// warning's i18n key; HostID and Domain name the other host involved.
+type HostWarning struct {
+	Key     string `json:"key"`
+	Message string `json:"message"`
+	HostID  uint   `json:"host_id,omitempty"`
+	Domain  string `json:"domain,omitempty"`
 }
 
 // Upstream represents a backend server for reverse proxying
@@ -125,6 +211,17 @@ type CustomHeader struct {
 	SortOrder int    `gorm:"default:0" json:"sort_order"`
 }
 
+// SecurityHeadersConfig holds per-header security settings. Only the headers
+// that are configured are sent.
+type SecurityHeadersConfig struct {
+	HSTSMaxAge            int    `json:"hsts_max_age"` // seconds; 0 omits Strict-Transport-Security
+	HSTSIncludeSubdomains bool   `json:"hsts_include_subdomains"`
+	HSTSPreload           bool   `json:"hsts_preload"`
+	FrameOptions          string `json:"frame_options"` // "DENY" or "SAMEORIGIN"
+	ContentSecurityPolicy string `json:"content_security_policy"`
+	ReferrerPolicy        string `json:"referrer_policy"`
+}
+
 // AccessRule represents an IP allow/deny rule
 type AccessRule struct {
 	ID        uint   `gorm:"primaryKey" json:"id"`
@@ -140,6 +237,9 @@ type BasicAuth struct {
 	HostID       uint   `gorm:"index;not null" json:"host_id"`
 	Username     string `gorm:"not null;size:64" json:"username"`
 	PasswordHash string `gorm:"not null;size:255" json:"-"` // bcrypt hash, never exposed
+	// Path limits the credential to a path prefix (e.g. "/admin"); empty
+	// protects the whole host.
+	Path string `gorm:"size:255" json:"path"`
 }
 
 // HostCreateRequest is the request body for creating/updating a host
@@ -163,20 +263,47 @@ type HostCreateRequest struct {
 	SecurityHeaders *bool  `json:"security_headers"`
 	ErrorPagePath   string `json:"error_page_path"`
 	// Batch 3
-	RootPath         string           `json:"root_path"`
-	DirectoryBrowse  *bool            `json:"directory_browse"`
-	PHPFastCGI       string           `json:"php_fastcgi"`
-	IndexFiles       string           `json:"index_files"`
-	TLSMode          string           `json:"tls_mode"`
-	DnsProviderID    *uint            `json:"dns_provider_id"`
-	CustomDirectives string           `json:"custom_directives"`
-	Upstreams        []UpstreamInput  `json:"upstreams"`
-	CustomHeaders    []HeaderInput    `json:"custom_headers"`
-	AccessRules      []AccessInput    `json:"access_rules"`
-	BasicAuths       []BasicAuthInput `json:"basic_auths"`
+	RootPath           string           `json:"root_path"`
+	DirectoryBrowse    *bool            `json:"directory_browse"`
+	SPAFallback        *bool            `json:"spa_fallback"`
+	PHPFastCGI         string           `json:"php_fastcgi"`
+	PHPVersion         string           `json:"php_version"`
+	IndexFiles         string           `json:"index_files"`
+	SeparateAccessLog  *bool            `json:"separate_access_log"`
+	MaxRequestBodySize string           `json:"max_request_body_size"`
+	ProxyReadTimeout   string           `json:"proxy_read_timeout"`
+	ProxyWriteTimeout  string           `json:"proxy_write_timeout"`
+	ProxyDialTimeout   string           `json:"proxy_dial_timeout"`
+	Aliases            string           `json:"aliases"`
+	BasicAuthRealm     string           `json:"basic_auth_realm"`
+	TLSMode            string           `json:"tls_mode"`
+	DnsProviderID      *uint            `json:"dns_provider_id"`
+	CustomDirectives   string           `json:"custom_directives"`
+	Upstreams          []UpstreamInput  `json:"upstreams"`
+	CustomHeaders      []HeaderInput    `json:"custom_headers"`
+	AccessRules        []AccessInput    `json:"access_rules"`
+	BasicAuths         []BasicAuthInput `json:"basic_auths"`
+	// Forward auth (external SSO)
+	ForwardAuthURL         string `json:"forward_auth_url"`
+	ForwardAuthCopyHeaders string `json:"forward_auth_copy_headers"`
+	// Per-header security settings; overrides the SecurityHeaders preset
+	SecurityHeadersConfig *SecurityHeadersConfig `json:"security_headers_config"`
+	// CORS credentials and preflight caching
+	CorsAllowCredentials *bool `json:"cors_allow_credentials"`
+	CorsMaxAge           int   `json:"cors_max_age"`
+	// Encoders used when Compression is on
+	CompressionAlgorithms string `json:"compression_algorithms"`
+	// ACME directory for the host's certificate ("staging" or an https URL)
+	ACMECA string `json:"acme_ca"`
 	// Phase 6: group and tag associations
 	GroupID *uint  `json:"group_id"`
 	TagIDs  []uint `json:"tag_ids"`
+	// Version is the host version the edit is based on (updates only).
+	Version uint `json:"version"`
+	// Author is recorded in the host's revision history. Set by the
+	// handler from the session, never bound from the request body.
+	AuthorID uint   `json:"-"`
+	Author   string `json:"-"`
 }
 
 // UpstreamInput is input for creating an upstream
@@ -203,6 +330,10 @@ type AccessInput struct {
 type BasicAuthInput struct {
 	Username string `json:"username" binding:"required"`
 	Password string `json:"password" binding:"required"` // plain text, will be hashed
+	Path     string `json:"path"`                        // optional path prefix
+	// PasswordHash reuses an existing bcrypt hash instead of hashing
+	// Password; only set internally when restoring a revision.
+	PasswordHash string `json:"-"`
 }
 
 // ExportData represents the full export of all hosts
@@ -212,6 +343,42 @@ type ExportData struct {
 	Hosts      []Host `json:"hosts"`
 }
 
+// ConfigExportVersion is the schema version of a full configuration
+// export. Version 1 is the hosts-only ExportData.
+const ConfigExportVersion = 2
+
+// How secrets (DNS provider credentials, the SMTP password) are stored in a
+// configuration export.
+const (
+	SecretsIncluded  = "included"  // in plain text
+	SecretsEncrypted = "encrypted" // encrypted with a passphrase
+	SecretsExcluded  = "excluded"  // left out
+)
+
+// ConfigExport is a full export of the panel configuration. On import each
+// section present replaces the current one; a missing (null) section is
+// left as it is.
+type ConfigExport struct {
+	Version      int             `json:"version"`
+	ExportedAt   string          `json:"exported_at"`
+	Secrets      string          `json:"secrets"`
+	Settings     []Setting       `json:"settings"`
+	Groups       []Group         `json:"groups"`
+	Tags         []Tag           `json:"tags"`
+	TagRules     []ExportTagRule `json:"tag_rules"`
+	Templates    []Template      `json:"templates"` // custom templates only
+	DnsProviders []DnsProvider   `json:"dns_providers"`
+	Certificates []Certificate   `json:"certificates"` // metadata; the PEM files are not included
+	Hosts        []Host          `json:"hosts"`
+}
+
+// ExportTagRule is a tag rule referring to its tag by name.
+type ExportTagRule struct {
+	Tag        string `json:"tag"`
+	MatchField string `json:"match_field"`
+	Pattern    string `json:"pattern"`
+}
+
 // AuditLog records admin actions for auditing
 type AuditLog struct {
 	ID        uint      `gorm:"primaryKey" json:"id"`
@@ -222,14 +389,27 @@ type AuditLog struct {
 	TargetID  string    `gorm:"size:32" json:"target_id"`       // ID of the affected resource
 	Detail    string    `gorm:"type:text" json:"detail"`        // human-readable description
 	IP        string    `gorm:"size:45" json:"ip"`
+	UserAgent string    `gorm:"size:255" json:"user_agent"`
 	CreatedAt time.Time `json:"created_at"`
 }
 
+// LoginAttempt is one failed authentication attempt, persisted so rate-limit
+// lockouts survive a restart.
+type LoginAttempt struct {
+	ID       uint      `gorm:"primaryKey" json:"id"`
+	Scope    string    `gorm:"not null;size:16;index:idx_login_attempt_scope_ip" json:"scope"` // limiter name: "login", "totp"
+	IP       string    `gorm:"not null;size:45;index:idx_login_attempt_scope_ip" json:"ip"`
+	Username string    `gorm:"size:64;index" json:"username"`
+	FailedAt time.Time `gorm:"index" json:"failed_at"`
+}
+
 // Group represents a host group for organizing sites
 type Group struct {
 	ID        uint      `gorm:"primaryKey" json:"id"`
 	Name      string    `gorm:"uniqueIndex;not null;size:64" json:"name"`
 	Color     string    `gorm:"size:16" json:"color"`
+	SortOrder int       `gorm:"default:0" json:"sort_order"`
+	HostCount int64     `gorm:"-" json:"host_count,omitempty"` // filled by GroupService.List
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
@@ -249,13 +429,52 @@ type HostTag struct {
 	TagID  uint `gorm:"primaryKey" json:"tag_id"`
 }
 
+// TagRule applies a tag automatically to hosts whose MatchField ("domain" or
+// "host_type") matches Pattern, a case-insensitive glob such as
+// "*.example.com".
+type TagRule struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	TagID      uint      `gorm:"not null;index" json:"tag_id"`
+	MatchField string    `gorm:"size:16;not null" json:"match_field"`
+	Pattern    string    `gorm:"size:255;not null" json:"pattern"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// HostSchedule turns a host on and off on cron schedules, e.g. to serve it
+// only during business hours. Either expression may be empty.
+type HostSchedule struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	HostID      uint      `gorm:"not null;uniqueIndex" json:"host_id"`
+	EnableCron  string    `gorm:"size:128" json:"enable_cron"`
+	DisableCron string    `gorm:"size:128" json:"disable_cron"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// HostRevision is a snapshot of a host's configuration taken after each
+// create or update. Revision matches the host Version it captures.
+type HostRevision struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	HostID      uint      `gorm:"not null;uniqueIndex:idx_host_revision" json:"host_id"`
+	Revision    uint      `gorm:"not null;uniqueIndex:idx_host_revision" json:"revision"`
+	Snapshot    string    `gorm:"type:text;not null" json:"snapshot"` // JSON of the host and its sub-tables
+	Credentials string    `gorm:"type:text" json:"-"`                 // JSON basic auth usernames and bcrypt hashes, never exposed
+	UserID      uint      `json:"user_id"`
+	Username    string    `gorm:"size:64" json:"username"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
 // Template represents a reusable host configuration template
 type Template struct {
 	ID          uint      `gorm:"primaryKey" json:"id"`
 	Name        string    `gorm:"not null;size:128" json:"name"`
 	Description string    `gorm:"size:512" json:"description"`
 	Type        string    `gorm:"not null;size:16;default:custom" json:"type"` // "preset" or "custom"
+	Category    string    `gorm:"size:32;index" json:"category"`               // e.g. "proxy", "static", "cms"
 	Config      string    `gorm:"type:text;not null" json:"config"`            // JSON snapshot
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
+	// Revision of a built-in preset's definition; bumped when a release
+	// changes it so existing rows are upgraded. 0 for custom templates.
+	PresetVersion int `gorm:"default:0" json:"preset_version,omitempty"`
 }
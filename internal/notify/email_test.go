@@ -0,0 +1,165 @@
+package notify
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// mockSMTP is a minimal SMTP server accepting PLAIN auth. Each delivered
+// message's DATA is sent on messages.
+type mockSMTP struct {
+	addr     *net.TCPAddr
+	messages chan string
+}
+
+// newMockSMTP starts a mock SMTP server on 127.0.0.1; with rejectAuth every
+// login fails with 535.
+func newMockSMTP(t *testing.T, rejectAuth bool) *mockSMTP {
+	t.Helper()
+	l, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { l.Close() })
+	m := &mockSMTP{addr: l.Addr().(*net.TCPAddr), messages: make(chan string, 4)}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go m.serve(conn, rejectAuth)
+		}
+	}()
+	return m
+}
+
+func (m *mockSMTP) serve(conn net.Conn, rejectAuth bool) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(s string) { conn.Write([]byte(s + "\r\n")) }
+	reply("220 mock ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"):
+			reply("250-mock")
+			reply("250 AUTH PLAIN")
+		case strings.HasPrefix(cmd, "AUTH"):
+			if rejectAuth {
+				reply("535 5.7.8 Authentication credentials invalid")
+			} else {
+				reply("235 2.7.0 Authentication successful")
+			}
+		case strings.HasPrefix(cmd, "MAIL"), strings.HasPrefix(cmd, "RCPT"):
+			reply("250 OK")
+		case cmd == "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				data.WriteString(l)
+			}
+			m.messages <- data.String()
+			reply("250 OK")
+		case cmd == "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+func (m *mockSMTP) emailConfig() EmailConfig {
+	return EmailConfig{
+		SMTPHost: "127.0.0.1",
+		SMTPPort: m.addr.Port,
+		Username: "panel",
+		Password: "secret",
+		From:     "panel@example.com",
+		To:       "ops@example.com",
+	}
+}
+
+func TestNotifier_Send_EmailForSubscribedEvent(t *testing.T) {
+	server := newMockSMTP(t, false)
+	n, db := newTestNotifier(t)
+
+	cfg, _ := json.Marshal(server.emailConfig())
+	db.Create(&Channel{Type: "email", Name: "Ops", Config: string(cfg), Enabled: true, Events: `["host.*"]`})
+
+	n.Send(NotifyEvent{Type: "backup.completed", Title: "Backup Done", Time: time.Now()})
+	n.Send(NotifyEvent{Type: "host.deleted", Title: "Host Deleted: app.example.com", Message: "domain: app.example.com", Time: time.Now()})
+
+	select {
+	case msg := <-server.messages:
+		if !strings.Contains(msg, "Subject: [Web.Casa] Host Deleted: app.example.com") {
+			t.Fatalf("unexpected subject in message:\n%s", msg)
+		}
+		if !strings.Contains(msg, "To: ops@example.com") || !strings.Contains(msg, "Event: host.deleted") {
+			t.Fatalf("unexpected message:\n%s", msg)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("host.deleted email was not delivered")
+	}
+
+	select {
+	case msg := <-server.messages:
+		t.Fatalf("unsubscribed event was mailed:\n%s", msg)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestSendMail_AuthFailure(t *testing.T) {
+	server := newMockSMTP(t, true)
+
+	err := SendMail(server.emailConfig(), "subject", "body")
+	if err == nil {
+		t.Fatal("expected auth error")
+	}
+	if !IsAuthError(err) {
+		t.Fatalf("expected IsAuthError for %v", err)
+	}
+	if retryable(err) {
+		t.Fatal("auth failure should not be retried")
+	}
+}
+
+// recordingMailer records the messages it is asked to send.
+type recordingMailer struct {
+	to, subject string
+}
+
+func (m *recordingMailer) Send(to, subject, body string) error {
+	m.to, m.subject = to, subject
+	return nil
+}
+
+func TestNotifier_SendEmail_UsesPanelMailer(t *testing.T) {
+	n, _ := newTestNotifier(t)
+	mailer := &recordingMailer{}
+	n.SetMailer(mailer)
+
+	ch := Channel{Type: "email", Config: `{"to":"ops@example.com"}`}
+	if err := n.sendEmail(ch, NotifyEvent{Type: "cert.expiring", Title: "Certificate Expiring: example.com", Time: time.Now()}); err != nil {
+		t.Fatalf("sendEmail: %v", err)
+	}
+	if mailer.to != "ops@example.com" || mailer.subject != "[Web.Casa] Certificate Expiring: example.com" {
+		t.Fatalf("unexpected mail to %q subject %q", mailer.to, mailer.subject)
+	}
+}
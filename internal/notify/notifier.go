@@ -4,26 +4,73 @@ import (
 	"bytes"
 	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
 	"net/smtp"
+	"net/textproto"
 	"strings"
 	"time"
 
 	"gorm.io/gorm"
 )
 
+// sendAttempts is how many times a notification is tried before it is
+// given up.
+const sendAttempts = 3
+
 // Notifier dispatches notifications to configured channels.
 type Notifier struct {
 	db       *gorm.DB
 	logger   *slog.Logger
 	skipSSRF bool // for testing only — skips SSRF validation on webhook URLs
+
+	backoff time.Duration // delay before the first retry, doubled after each
+	mailer  Mailer        // panel SMTP server, for email channels without their own
+}
+
+// Mailer delivers a plain-text message to comma-separated recipients.
+type Mailer interface {
+	Send(to, subject, body string) error
 }
 
 // NewNotifier creates a new Notifier.
 func NewNotifier(db *gorm.DB, logger *slog.Logger) *Notifier {
-	return &Notifier{db: db, logger: logger}
+	return &Notifier{db: db, logger: logger, backoff: 2 * time.Second}
+}
+
+// SetMailer sets the mailer used by email channels that name recipients
+// but no SMTP server of their own.
+func (n *Notifier) SetMailer(m Mailer) {
+	n.mailer = m
+}
+
+// statusError is a non-success HTTP response from a channel endpoint.
+type statusError struct {
+	service string
+	code    int
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("%s returned status %d", e.service, e.code)
+}
+
+// retryable reports whether a failed send may succeed when tried again:
+// network errors, rate limiting and server errors may; configuration and
+// other client errors (a wrong URL or token) will not.
+func retryable(err error) bool {
+	var se *statusError
+	if errors.As(err, &se) {
+		return se.code == http.StatusTooManyRequests || se.code >= 500
+	}
+	var te *textproto.Error
+	if errors.As(err, &te) {
+		return te.Code >= 400 && te.Code < 500 // transient SMTP failure
+	}
+	var ne net.Error
+	return errors.As(err, &ne)
 }
 
 // Send dispatches a notification event to all enabled channels that match the event type.
@@ -40,17 +87,7 @@ func (n *Notifier) Send(event NotifyEvent) {
 		}
 
 		go func(ch Channel) {
-			var err error
-			switch ch.Type {
-			case "webhook":
-				err = n.sendWebhook(ch, event)
-			case "email":
-				err = n.sendEmail(ch, event)
-			case "discord":
-				err = n.sendDiscord(ch, event)
-			case "telegram":
-				err = n.sendTelegram(ch, event)
-			}
+			err := n.sendWithRetry(ch, event)
 			if err != nil {
 				n.logger.Error("notification failed", "channel", ch.Name, "type", ch.Type, "err", err)
 			} else {
@@ -60,6 +97,40 @@ func (n *Notifier) Send(event NotifyEvent) {
 	}
 }
 
+// sendWithRetry sends event to ch, retrying failures that may be temporary
+// with exponential backoff.
+func (n *Notifier) sendWithRetry(ch Channel, event NotifyEvent) error {
+	delay := n.backoff
+	var err error
+	for attempt := 1; attempt <= sendAttempts; attempt++ {
+		if err = n.send(ch, event); err == nil || !retryable(err) {
+			return err
+		}
+		if attempt < sendAttempts {
+			n.logger.Warn("notification failed, retrying", "channel", ch.Name, "attempt", attempt, "err", err)
+			time.Sleep(delay)
+			delay *= 2
+		}
+	}
+	return err
+}
+
+// send delivers event to ch once.
+func (n *Notifier) send(ch Channel, event NotifyEvent) error {
+	switch ch.Type {
+	case "webhook":
+		return n.sendWebhook(ch, event)
+	case "email":
+		return n.sendEmail(ch, event)
+	case "discord":
+		return n.sendDiscord(ch, event)
+	case "telegram":
+		return n.sendTelegram(ch, event)
+	default:
+		return fmt.Errorf("unknown channel type: %s", ch.Type)
+	}
+}
+
 // matchesEvent checks if a channel's event patterns match the given event type.
 func (n *Notifier) matchesEvent(ch Channel, eventType string) bool {
 	if ch.Events == "" {
@@ -132,7 +203,7 @@ func (n *Notifier) sendWebhook(ch Channel, event NotifyEvent) error {
 	defer resp.Body.Close()
 
 	if resp.StatusCode >= 400 {
-		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+		return &statusError{service: "webhook", code: resp.StatusCode}
 	}
 	return nil
 }
@@ -143,6 +214,25 @@ func (n *Notifier) sendEmail(ch Channel, event NotifyEvent) error {
 	if err := json.Unmarshal([]byte(ch.Config), &cfg); err != nil {
 		return fmt.Errorf("parse email config: %w", err)
 	}
+
+	subject := fmt.Sprintf("[Web.Casa] %s", event.Title)
+	body := fmt.Sprintf("Event: %s\nTime: %s\n\n%s",
+		event.Type,
+		event.Time.Format("2006-01-02 15:04:05"),
+		event.Message,
+	)
+	if cfg.SMTPHost == "" && n.mailer != nil {
+		if cfg.To == "" {
+			return fmt.Errorf("email config incomplete")
+		}
+		return n.mailer.Send(cfg.To, subject, body)
+	}
+	return SendMail(cfg, subject, body)
+}
+
+// SendMail sends a plain-text message to cfg.To through cfg's SMTP server.
+// It is shared by email channels and other panel mail such as 2FA codes.
+func SendMail(cfg EmailConfig, subject, body string) error {
 	if cfg.SMTPHost == "" || cfg.To == "" {
 		return fmt.Errorf("email config incomplete")
 	}
@@ -152,13 +242,6 @@ func (n *Notifier) sendEmail(ch Channel, event NotifyEvent) error {
 		from = cfg.Username
 	}
 
-	subject := fmt.Sprintf("[Web.Casa] %s", event.Title)
-	body := fmt.Sprintf("Event: %s\nTime: %s\n\n%s",
-		event.Type,
-		event.Time.Format("2006-01-02 15:04:05"),
-		event.Message,
-	)
-
 	msg := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n%s",
 		from, cfg.To, subject, body)
 
@@ -175,13 +258,22 @@ func (n *Notifier) sendEmail(ch Channel, event NotifyEvent) error {
 	}
 
 	if cfg.UseTLS {
-		return n.sendEmailTLS(addr, auth, from, recipients, []byte(msg), cfg.SMTPHost)
+		return sendEmailTLS(addr, auth, from, recipients, []byte(msg), cfg.SMTPHost)
 	}
 	return smtp.SendMail(addr, auth, from, recipients, []byte(msg))
 }
 
+// IsAuthError reports whether err is an SMTP server rejecting the login.
+func IsAuthError(err error) bool {
+	var te *textproto.Error
+	if errors.As(err, &te) {
+		return te.Code == 530 || te.Code == 534 || te.Code == 535
+	}
+	return false
+}
+
 // sendEmailTLS sends email over TLS.
-func (n *Notifier) sendEmailTLS(addr string, auth smtp.Auth, from string, to []string, msg []byte, host string) error {
+func sendEmailTLS(addr string, auth smtp.Auth, from string, to []string, msg []byte, host string) error {
 	tlsConfig := &tls.Config{ServerName: host}
 	conn, err := tls.Dial("tcp", addr, tlsConfig)
 	if err != nil {
@@ -293,7 +385,7 @@ func (n *Notifier) sendDiscord(ch Channel, event NotifyEvent) error {
 	defer resp.Body.Close()
 
 	if resp.StatusCode >= 400 {
-		return fmt.Errorf("discord webhook returned status %d", resp.StatusCode)
+		return &statusError{service: "discord webhook", code: resp.StatusCode}
 	}
 	return nil
 }
@@ -347,7 +439,7 @@ func (n *Notifier) sendTelegram(ch Channel, event NotifyEvent) error {
 	defer resp.Body.Close()
 
 	if resp.StatusCode >= 400 {
-		return fmt.Errorf("telegram API returned status %d", resp.StatusCode)
+		return &statusError{service: "telegram API", code: resp.StatusCode}
 	}
 	return nil
 }
@@ -373,18 +465,7 @@ func (n *Notifier) TestChannel(ch Channel) error {
 		Time:    time.Now(),
 	}
 
-	switch ch.Type {
-	case "webhook":
-		return n.sendWebhook(ch, event)
-	case "email":
-		return n.sendEmail(ch, event)
-	case "discord":
-		return n.sendDiscord(ch, event)
-	case "telegram":
-		return n.sendTelegram(ch, event)
-	default:
-		return fmt.Errorf("unknown channel type: %s", ch.Type)
-	}
+	return n.send(ch, event)
 }
 
 // --- CRUD helpers ---
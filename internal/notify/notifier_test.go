@@ -8,6 +8,7 @@ import (
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -329,6 +330,102 @@ func TestNotifier_Send_DisabledChannel(t *testing.T) {
 	}
 }
 
+func TestNotifier_Send_HostDeletedPayload(t *testing.T) {
+	received := make(chan NotifyEvent, 4)
+	server := newTestServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event NotifyEvent
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			t.Errorf("decode payload: %v", err)
+		}
+		received <- event
+		w.WriteHeader(200)
+	}))
+	defer server.Close()
+
+	n, db := newTestNotifier(t)
+	cfg, _ := json.Marshal(WebhookConfig{URL: server.URL})
+	db.Create(&Channel{Type: "webhook", Name: "Hosts", Config: string(cfg), Enabled: true, Events: `["host.*"]`})
+
+	// Not subscribed: must not reach the server.
+	n.Send(NotifyEvent{Type: "cert.expiring", Title: "Certificate Expiring: example.com", Time: time.Now()})
+	n.Send(NotifyEvent{
+		Type:    "host.deleted",
+		Title:   "Host Deleted: app.example.com",
+		Message: "domain: app.example.com",
+		Data:    map[string]interface{}{"host_id": 7, "domain": "app.example.com", "user": "admin"},
+		Time:    time.Now(),
+	})
+
+	select {
+	case event := <-received:
+		if event.Type != "host.deleted" || event.Title != "Host Deleted: app.example.com" {
+			t.Fatalf("unexpected event %q titled %q", event.Type, event.Title)
+		}
+		if event.Data["domain"] != "app.example.com" || event.Data["host_id"] != float64(7) || event.Data["user"] != "admin" {
+			t.Fatalf("unexpected data %v", event.Data)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("host.deleted was not delivered")
+	}
+
+	select {
+	case event := <-received:
+		t.Fatalf("unsubscribed event %q was delivered", event.Type)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestNotifier_Send_RetriesWithBackoff(t *testing.T) {
+	var calls int32
+	done := make(chan struct{})
+	server := newTestServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch atomic.AddInt32(&calls, 1) {
+		case 1, 2:
+			w.WriteHeader(http.StatusBadGateway)
+		default:
+			w.WriteHeader(200)
+			close(done)
+		}
+	}))
+	defer server.Close()
+
+	n, db := newTestNotifier(t)
+	n.backoff = 10 * time.Millisecond
+	cfg, _ := json.Marshal(WebhookConfig{URL: server.URL})
+	db.Create(&Channel{Type: "webhook", Name: "Flaky", Config: string(cfg), Enabled: true, Events: `["*"]`})
+
+	n.Send(NotifyEvent{Type: "system.caddy.reload_failed", Time: time.Now()})
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("webhook not delivered after retries, %d calls", atomic.LoadInt32(&calls))
+	}
+	if got := atomic.LoadInt32(&calls); got != sendAttempts {
+		t.Fatalf("expected %d calls, got %d", sendAttempts, got)
+	}
+}
+
+func TestNotifier_SendWithRetry_ClientErrorNotRetried(t *testing.T) {
+	var calls int32
+	server := newTestServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	n, _ := newTestNotifier(t)
+	n.backoff = 10 * time.Millisecond
+	cfg, _ := json.Marshal(WebhookConfig{URL: server.URL})
+	ch := Channel{Type: "webhook", Config: string(cfg)}
+
+	if err := n.sendWithRetry(ch, NotifyEvent{Type: "test", Time: time.Now()}); err == nil {
+		t.Fatal("expected error for 404 response")
+	}
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Fatalf("expected 1 call, got %d", got)
+	}
+}
+
 // ── Model tests ──
 
 func TestChannel_TableName(t *testing.T) {
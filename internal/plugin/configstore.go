@@ -1,7 +1,9 @@
 package plugin
 
 import (
+	"encoding/json"
 	"fmt"
+	"strconv"
 
 	"github.com/web-casa/webcasa/internal/model"
 	"gorm.io/gorm"
@@ -31,6 +33,48 @@ func (cs *ConfigStore) Get(key string) string {
 	return s.Value
 }
 
+// GetInt reads an integer value, returning def when the key is missing or
+// the value is not an integer.
+func (cs *ConfigStore) GetInt(key string, def int) int {
+	n, err := strconv.Atoi(cs.Get(key))
+	if err != nil {
+		return def
+	}
+	return n
+}
+
+// GetBool reads a boolean value ("true", "false", "1", "0", ...), returning
+// def when the key is missing or the value is not a boolean.
+func (cs *ConfigStore) GetBool(key string, def bool) bool {
+	b, err := strconv.ParseBool(cs.Get(key))
+	if err != nil {
+		return def
+	}
+	return b
+}
+
+// GetJSON decodes a JSON value into out. A missing key leaves out untouched,
+// so callers can pre-fill defaults; a malformed value returns an error.
+func (cs *ConfigStore) GetJSON(key string, out any) error {
+	v := cs.Get(key)
+	if v == "" {
+		return nil
+	}
+	if err := json.Unmarshal([]byte(v), out); err != nil {
+		return fmt.Errorf("config %s: %w", key, err)
+	}
+	return nil
+}
+
+// SetJSON encodes v as JSON and writes it.
+func (cs *ConfigStore) SetJSON(key string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("config %s: %w", key, err)
+	}
+	return cs.Set(key, string(data))
+}
+
 // Set writes a configuration value (upsert).
 func (cs *ConfigStore) Set(key, value string) error {
 	fullKey := cs.prefix + key
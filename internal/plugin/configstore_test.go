@@ -76,3 +76,69 @@ func TestConfigStoreAll(t *testing.T) {
 		t.Fatalf("unexpected values: %v", all)
 	}
 }
+
+func TestConfigStoreTyped(t *testing.T) {
+	db := setupTestDB(t)
+	db.AutoMigrate(&model.Setting{})
+	cs := NewConfigStore(db, "typed")
+
+	// Missing keys fall back to the default.
+	if v := cs.GetInt("port", 8080); v != 8080 {
+		t.Fatalf("GetInt(missing) = %d, want 8080", v)
+	}
+	if v := cs.GetBool("enabled", true); !v {
+		t.Fatal("GetBool(missing) = false, want default true")
+	}
+
+	cs.Set("port", "9000")
+	cs.Set("enabled", "false")
+	if v := cs.GetInt("port", 8080); v != 9000 {
+		t.Fatalf("GetInt = %d, want 9000", v)
+	}
+	if v := cs.GetBool("enabled", true); v {
+		t.Fatal("GetBool = true, want false")
+	}
+
+	// Malformed values also fall back to the default.
+	cs.Set("port", "90x")
+	cs.Set("enabled", "maybe")
+	if v := cs.GetInt("port", 8080); v != 8080 {
+		t.Fatalf("GetInt(malformed) = %d, want 8080", v)
+	}
+	if v := cs.GetBool("enabled", true); !v {
+		t.Fatal("GetBool(malformed) = false, want default true")
+	}
+}
+
+func TestConfigStoreJSON(t *testing.T) {
+	db := setupTestDB(t)
+	db.AutoMigrate(&model.Setting{})
+	cs := NewConfigStore(db, "json")
+
+	type limits struct {
+		Hosts []string `json:"hosts"`
+		Max   int      `json:"max"`
+	}
+
+	// A missing key leaves the pre-filled defaults in place.
+	got := limits{Max: 5}
+	if err := cs.GetJSON("limits", &got); err != nil || got.Max != 5 {
+		t.Fatalf("GetJSON(missing) = %+v, %v; want defaults kept", got, err)
+	}
+
+	if err := cs.SetJSON("limits", limits{Hosts: []string{"a.example.com"}, Max: 10}); err != nil {
+		t.Fatal(err)
+	}
+	if v := cs.Get("limits"); v != `{"hosts":["a.example.com"],"max":10}` {
+		t.Fatalf("stored %q", v)
+	}
+	got = limits{}
+	if err := cs.GetJSON("limits", &got); err != nil || got.Max != 10 || len(got.Hosts) != 1 {
+		t.Fatalf("GetJSON = %+v, %v", got, err)
+	}
+
+	cs.Set("limits", "{not json")
+	if err := cs.GetJSON("limits", &got); err == nil {
+		t.Fatal("GetJSON(malformed) should return an error")
+	}
+}
@@ -6,6 +6,7 @@ import (
 	cryptorand "crypto/rand"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
@@ -16,6 +17,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/gin-gonic/gin"
 	"github.com/robfig/cron/v3"
 	"github.com/web-casa/webcasa/internal/caddy"
 	"github.com/web-casa/webcasa/internal/crypto"
@@ -181,6 +183,30 @@ func (a *CoreAPIImpl) GetHost(id uint) (map[string]interface{}, error) {
 	}, nil
 }
 
+func (a *CoreAPIImpl) GetHostByDomain(domain string) (*HostInfo, error) {
+	var h model.Host
+	err := a.db.Preload("Upstreams").Where("LOWER(domain) = ?", strings.ToLower(strings.TrimSpace(domain))).First(&h).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	upstreams := make([]string, len(h.Upstreams))
+	for i, u := range h.Upstreams {
+		upstreams[i] = u.Address
+	}
+	return &HostInfo{
+		ID:         h.ID,
+		Domain:     h.Domain,
+		HostType:   h.HostType,
+		Enabled:    h.Enabled != nil && *h.Enabled,
+		TLSEnabled: h.TLSEnabled != nil && *h.TLSEnabled,
+		Upstreams:  upstreams,
+	}, nil
+}
+
 func (a *CoreAPIImpl) ReloadCaddy() error {
 	return a.caddyMgr.Reload()
 }
@@ -189,15 +215,7 @@ func (a *CoreAPIImpl) UpdateHostUpstream(hostID uint, newUpstream string) error
 	if err := caddy.ValidateUpstream(newUpstream); err != nil {
 		return fmt.Errorf("invalid upstream: %w", err)
 	}
-	if err := a.db.Model(&model.Upstream{}).
-		Where("host_id = ?", hostID).
-		Order("sort_order ASC").
-		Limit(1).
-		Update("address", newUpstream).Error; err != nil {
-		return fmt.Errorf("update upstream: %w", err)
-	}
-	// Regenerate Caddyfile and reload so traffic actually switches.
-	return a.hostSvc.ApplyConfig()
+	return a.UpdateHost(hostID, UpdateHostRequest{Upstream: newUpstream})
 }
 
 // ──────────────────────────────────────────────────
@@ -396,6 +414,7 @@ func (a *CoreAPIImpl) TriggerBuild(projectID uint) error {
 	}
 	a.eventBus.Publish(Event{
 		Type:    "deploy.trigger_build",
+		Command: true,
 		Payload: map[string]interface{}{"project_id": projectID},
 		Source:  "core",
 	})
@@ -600,70 +619,55 @@ func (a *CoreAPIImpl) TriggerBackup() error {
 	}
 	a.eventBus.Publish(Event{
 		Type:    "backup.trigger",
+		Command: true,
 		Payload: map[string]interface{}{},
 		Source:  "core",
 	})
 	return nil
 }
 
+// UpdateHost changes the given fields of a host through HostService.Update,
+// so plugin edits are validated, versioned and recorded like panel edits.
+// An edit racing another one is retried on the newer version.
 func (a *CoreAPIImpl) UpdateHost(id uint, req UpdateHostRequest) error {
-	var h model.Host
-	if err := a.db.First(&h, id).Error; err != nil {
-		return fmt.Errorf("host not found: %w", err)
-	}
-
-	updates := map[string]interface{}{}
-	if req.Domain != "" {
-		if err := caddy.ValidateDomain(req.Domain); err != nil {
-			return fmt.Errorf("invalid domain: %w", err)
+	for attempt := 0; ; attempt++ {
+		update, err := a.hostSvc.CurrentRequest(id)
+		if err != nil {
+			return fmt.Errorf("host not found: %w", err)
 		}
-		// Check uniqueness.
-		var count int64
-		a.db.Model(&model.Host{}).Where("domain = ? AND id != ?", req.Domain, id).Count(&count)
-		if count > 0 {
-			return fmt.Errorf("domain %q already exists", req.Domain)
+		if req.Domain != "" {
+			update.Domain = req.Domain
 		}
-		updates["domain"] = req.Domain
-	}
-	if req.Upstream != "" {
-		if err := caddy.ValidateUpstream(req.Upstream); err != nil {
-			return fmt.Errorf("invalid upstream: %w", err)
+		if req.Upstream != "" {
+			if len(update.Upstreams) == 0 {
+				update.Upstreams = []model.UpstreamInput{{Address: req.Upstream, Weight: 1}}
+			} else {
+				update.Upstreams[0].Address = req.Upstream
+			}
 		}
-		a.db.Model(&model.Upstream{}).
-			Where("host_id = ?", id).
-			Order("sort_order ASC").
-			Limit(1).
-			Update("address", req.Upstream)
-	}
-	if req.TLSMode != "" {
-		updates["tls_mode"] = req.TLSMode
-		if req.TLSMode == "off" {
-			updates["tls_enabled"] = false
-		} else {
-			updates["tls_enabled"] = true
+		if req.TLSMode != "" {
+			update.TLSMode = req.TLSMode
+			update.TLSEnabled = boolPtr(req.TLSMode != "off")
+		}
+		if req.ForceHTTPS != nil {
+			update.HTTPRedirect = boolPtr(*req.ForceHTTPS)
+		}
+		if req.WebSocket != nil {
+			update.WebSocket = boolPtr(*req.WebSocket)
+		}
+		if req.Compression != nil {
+			update.Compression = boolPtr(*req.Compression)
+		}
+		if req.Enabled != nil {
+			update.Enabled = boolPtr(*req.Enabled)
 		}
-	}
-	if req.ForceHTTPS != nil {
-		updates["http_redirect"] = *req.ForceHTTPS
-	}
-	if req.WebSocket != nil {
-		updates["websocket"] = *req.WebSocket
-	}
-	if req.Compression != nil {
-		updates["compression"] = *req.Compression
-	}
-	if req.Enabled != nil {
-		updates["enabled"] = *req.Enabled
-	}
 
-	if len(updates) > 0 {
-		if err := a.db.Model(&h).Updates(updates).Error; err != nil {
-			return fmt.Errorf("update host: %w", err)
+		_, err = a.hostSvc.Update(id, update)
+		if errors.Is(err, service.ErrStaleHost) && attempt < 2 {
+			continue
 		}
+		return err
 	}
-
-	// Regenerate Caddyfile and reload Caddy to apply changes.
-	return a.hostSvc.ApplyConfig()
 }
 
 func (a *CoreAPIImpl) GetRecentAlerts() ([]map[string]interface{}, error) {
@@ -714,7 +718,8 @@ func (a *CoreAPIImpl) DatabaseCreateInstance(req DatabaseCreateInstanceRequest)
 		return 0, fmt.Errorf("event bus not available")
 	}
 	a.eventBus.Publish(Event{
-		Type: "database.create_instance",
+		Type:    "database.create_instance",
+		Command: true,
 		Payload: map[string]interface{}{
 			"engine":        req.Engine,
 			"version":       req.Version,
@@ -1094,7 +1099,8 @@ func (a *CoreAPIImpl) AppStoreInstallApp(appID string, config map[string]interfa
 		return 0, fmt.Errorf("event bus not available")
 	}
 	a.eventBus.Publish(Event{
-		Type: "appstore.install",
+		Type:    "appstore.install",
+		Command: true,
 		Payload: map[string]interface{}{
 			"app_id": appID,
 			"config": config,
@@ -1480,7 +1486,8 @@ func (a *CoreAPIImpl) ToggleHost(id uint) error {
 	if err := a.db.Model(&h).Update("enabled", newVal).Error; err != nil {
 		return fmt.Errorf("toggle host: %w", err)
 	}
-	return a.hostSvc.ApplyConfig()
+	_, err := a.hostSvc.ApplyConfig()
+	return err
 }
 
 func (a *CoreAPIImpl) CloneHost(id uint, newDomain string) (uint, error) {
@@ -1512,6 +1519,29 @@ func (a *CoreAPIImpl) CloneHost(id uint, newDomain string) (uint, error) {
 	})
 }
 
+func (a *CoreAPIImpl) GetHostDiagnostics(id uint, lines int) (map[string]interface{}, error) {
+	diag, err := a.hostSvc.Diagnostics(id, lines)
+	if err != nil {
+		return nil, fmt.Errorf("host not found: %w", err)
+	}
+	upstreams := make([]map[string]interface{}, len(diag.Upstreams))
+	for i, u := range diag.Upstreams {
+		upstreams[i] = map[string]interface{}{
+			"address":    u.Address,
+			"reachable":  u.Reachable,
+			"latency_ms": u.LatencyMs,
+			"error":      u.Error,
+		}
+	}
+	return map[string]interface{}{
+		"domain":     diag.Domain,
+		"host_block": diag.HostBlock,
+		"access_log": diag.AccessLog,
+		"error_log":  diag.ErrorLog,
+		"upstreams":  upstreams,
+	}, nil
+}
+
 // ──────────────────────────────────────────────────
 // NLOps: Caddy management
 // ──────────────────────────────────────────────────
@@ -1537,6 +1567,7 @@ func (a *CoreAPIImpl) StartProject(id uint) error {
 	}
 	a.eventBus.Publish(Event{
 		Type:    "deploy.start_project",
+		Command: true,
 		Payload: map[string]interface{}{"project_id": id},
 		Source:  "core",
 	})
@@ -1549,6 +1580,7 @@ func (a *CoreAPIImpl) StopProject(id uint) error {
 	}
 	a.eventBus.Publish(Event{
 		Type:    "deploy.stop_project",
+		Command: true,
 		Payload: map[string]interface{}{"project_id": id},
 		Source:  "core",
 	})
@@ -1560,7 +1592,8 @@ func (a *CoreAPIImpl) RollbackProject(projectID uint, buildNum int) error {
 		return fmt.Errorf("event bus not available")
 	}
 	a.eventBus.Publish(Event{
-		Type: "deploy.rollback",
+		Type:    "deploy.rollback",
+		Command: true,
 		Payload: map[string]interface{}{
 			"project_id":   projectID,
 			"build_number": buildNum,
@@ -1650,6 +1683,7 @@ func (a *CoreAPIImpl) TestNotifyChannel(id uint) error {
 	}
 	a.eventBus.Publish(Event{
 		Type:    "notify.test_channel",
+		Command: true,
 		Payload: map[string]interface{}{"channel_id": id},
 		Source:  "core",
 	})
@@ -1822,6 +1856,7 @@ func (a *CoreAPIImpl) CronJobCreate(name, expression, command, workingDir string
 	if a.eventBus != nil {
 		a.eventBus.Publish(Event{
 			Type:    "cronjob.reload",
+			Command: true,
 			Source:  "core",
 			Payload: map[string]interface{}{"task_id": float64(row.ID), "action": "create"},
 		})
@@ -1862,6 +1897,7 @@ func (a *CoreAPIImpl) CronJobUpdate(id uint, updates map[string]interface{}) err
 	if a.eventBus != nil {
 		a.eventBus.Publish(Event{
 			Type:    "cronjob.reload",
+			Command: true,
 			Source:  "core",
 			Payload: map[string]interface{}{"task_id": float64(id), "action": "update"},
 		})
@@ -1875,6 +1911,7 @@ func (a *CoreAPIImpl) CronJobDelete(id uint) error {
 	if a.eventBus != nil {
 		a.eventBus.Publish(Event{
 			Type:    "cronjob.reload",
+			Command: true,
 			Source:  "core",
 			Payload: map[string]interface{}{"task_id": float64(id), "action": "delete"},
 		})
@@ -1920,6 +1957,7 @@ func (a *CoreAPIImpl) CronJobTrigger(id uint) error {
 	if a.eventBus != nil {
 		a.eventBus.Publish(Event{
 			Type:    "cronjob.trigger",
+			Command: true,
 			Source:  "core",
 			Payload: map[string]interface{}{"task_id": float64(id)},
 		})
@@ -1945,6 +1983,36 @@ func (a *CoreAPIImpl) DecryptSecret(ciphertext string) (string, error) {
 	return crypto.Decrypt(ciphertext, a.jwtSecret)
 }
 
+// ──────────────────────────────────────────────────
+// Audit logging
+// ──────────────────────────────────────────────────
+
+// auditWrittenKey must match the key handler.AuditMutations checks.
+const auditWrittenKey = "audit_written"
+
+// maxAuditUserAgentLen matches the AuditLog.UserAgent column size.
+const maxAuditUserAgentLen = 255
+
+func (a *CoreAPIImpl) WriteAuditLog(c *gin.Context, action, target, targetID, detail string) {
+	userID, _ := c.Get("user_id")
+	uid, _ := userID.(uint)
+	entry := &model.AuditLog{
+		UserID:    uid,
+		Username:  c.GetString("username"),
+		Action:    action,
+		Target:    target,
+		TargetID:  targetID,
+		Detail:    detail,
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}
+	if len(entry.UserAgent) > maxAuditUserAgentLen {
+		entry.UserAgent = entry.UserAgent[:maxAuditUserAgentLen]
+	}
+	c.Set(auditWrittenKey, true)
+	a.db.Create(entry)
+}
+
 // ──────────────────────────────────────────────────
 // Internal helpers
 // ──────────────────────────────────────────────────
@@ -0,0 +1,173 @@
+package plugin
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/web-casa/webcasa/internal/caddy"
+	"github.com/web-casa/webcasa/internal/config"
+	"github.com/web-casa/webcasa/internal/model"
+	"github.com/web-casa/webcasa/internal/service"
+)
+
+// setupTestCoreAPI returns a CoreAPIImpl over a real HostService whose
+// config changes are written to a temp dir without reloading Caddy.
+func setupTestCoreAPI(t *testing.T) *CoreAPIImpl {
+	t.Helper()
+	db := setupTestDB(t)
+	if err := db.AutoMigrate(&model.Host{}, &model.Upstream{}, &model.Route{}, &model.CustomHeader{},
+		&model.AccessRule{}, &model.BasicAuth{}, &model.Setting{}, &model.Group{}, &model.Tag{}, &model.HostTag{}, &model.HostRevision{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	db.Create(&model.Setting{Key: "auto_reload", Value: "false"})
+
+	dir := t.TempDir()
+	cfg := &config.Config{
+		DataDir:       dir,
+		CaddyfilePath: filepath.Join(dir, "Caddyfile"),
+		CaddyBin:      "echo",
+		LogDir:        dir,
+		AdminAPI:      "http://localhost:2019",
+	}
+	caddyMgr := caddy.NewManager(cfg)
+	return NewCoreAPI(db, service.NewHostService(db, caddyMgr, cfg), caddyMgr, dir)
+}
+
+func TestCoreAPI_UpdateHostKeepsIDAndSettings(t *testing.T) {
+	api := setupTestCoreAPI(t)
+	id, err := api.CreateHost(CreateHostRequest{
+		Domain:       "app.example.com",
+		UpstreamAddr: "localhost:10001",
+		TLSEnabled:   true,
+		HTTPRedirect: true,
+		WebSocket:    true,
+	})
+	if err != nil {
+		t.Fatalf("CreateHost: %v", err)
+	}
+	// A header added in the panel after the plugin created the host.
+	api.db.Create(&model.CustomHeader{HostID: id, Direction: "response", Operation: "set", Name: "X-Team", Value: "web"})
+
+	if err := api.UpdateHost(id, UpdateHostRequest{Upstream: "localhost:10002"}); err != nil {
+		t.Fatalf("UpdateHost: %v", err)
+	}
+
+	var hosts []model.Host
+	api.db.Preload("Upstreams").Preload("CustomHeaders").Find(&hosts)
+	if len(hosts) != 1 || hosts[0].ID != id {
+		t.Fatalf("hosts = %+v, want the original host %d only", hosts, id)
+	}
+	h := hosts[0]
+	if len(h.Upstreams) != 1 || h.Upstreams[0].Address != "localhost:10002" {
+		t.Errorf("upstreams = %+v", h.Upstreams)
+	}
+	if h.Domain != "app.example.com" || !*h.TLSEnabled || !*h.HTTPRedirect || !*h.WebSocket {
+		t.Errorf("settings changed: domain=%s tls=%v redirect=%v ws=%v", h.Domain, *h.TLSEnabled, *h.HTTPRedirect, *h.WebSocket)
+	}
+	if len(h.CustomHeaders) != 1 || h.CustomHeaders[0].Value != "web" {
+		t.Errorf("custom headers = %+v", h.CustomHeaders)
+	}
+
+	if err := api.UpdateHost(id, UpdateHostRequest{Upstream: "bad upstream;"}); err == nil {
+		t.Error("invalid upstream should be rejected")
+	}
+}
+
+func TestCoreAPI_UpdateHostGoesThroughHostService(t *testing.T) {
+	api := setupTestCoreAPI(t)
+	id, err := api.CreateHost(CreateHostRequest{Domain: "app.example.com", UpstreamAddr: "localhost:10001"})
+	if err != nil {
+		t.Fatalf("CreateHost: %v", err)
+	}
+	var before model.Host
+	api.db.First(&before, id)
+
+	if err := api.UpdateHost(id, UpdateHostRequest{Upstream: "localhost:10002", WebSocket: boolPtr(false)}); err != nil {
+		t.Fatalf("UpdateHost: %v", err)
+	}
+	var after model.Host
+	api.db.First(&after, id)
+	if after.Version != before.Version+1 {
+		t.Errorf("version = %d, want %d after a plugin edit", after.Version, before.Version+1)
+	}
+	if after.WebSocket == nil || *after.WebSocket {
+		t.Errorf("websocket = %v, want false", after.WebSocket)
+	}
+	var revisions int64
+	api.db.Model(&model.HostRevision{}).Where("host_id = ? AND revision = ?", id, after.Version).Count(&revisions)
+	if revisions != 1 {
+		t.Errorf("no revision recorded for the plugin edit")
+	}
+}
+
+func TestCoreAPI_UpdateHostRejectsTakenDomain(t *testing.T) {
+	api := setupTestCoreAPI(t)
+	if _, err := api.CreateHost(CreateHostRequest{Domain: "shop.example.com", UpstreamAddr: "localhost:10001"}); err != nil {
+		t.Fatalf("CreateHost: %v", err)
+	}
+	api.db.Model(&model.Host{}).Where("domain = ?", "shop.example.com").Update("aliases", "store.example.com")
+	id, err := api.CreateHost(CreateHostRequest{Domain: "app.example.com", UpstreamAddr: "localhost:10002"})
+	if err != nil {
+		t.Fatalf("CreateHost: %v", err)
+	}
+
+	for _, domain := range []string{"SHOP.example.com", "store.example.com"} {
+		if err := api.UpdateHost(id, UpdateHostRequest{Domain: domain}); err == nil || !strings.Contains(err.Error(), "already exists") {
+			t.Errorf("rename to %s: err = %v, want already exists", domain, err)
+		}
+	}
+	var h model.Host
+	api.db.First(&h, id)
+	if h.Domain != "app.example.com" {
+		t.Errorf("domain = %s after rejected renames", h.Domain)
+	}
+	if err := api.UpdateHost(id, UpdateHostRequest{Domain: "API.Example.com."}); err != nil {
+		t.Errorf("rename to a free domain: %v", err)
+	}
+	api.db.First(&h, id)
+	if h.Domain != "api.example.com" {
+		t.Errorf("domain = %q, want the normalized api.example.com", h.Domain)
+	}
+}
+
+func TestCoreAPI_GetHostByDomain(t *testing.T) {
+	api := setupTestCoreAPI(t)
+	id, err := api.CreateHost(CreateHostRequest{Domain: "app.example.com", UpstreamAddr: "localhost:10001", TLSEnabled: true})
+	if err != nil {
+		t.Fatalf("CreateHost: %v", err)
+	}
+	api.db.Create(&model.BasicAuth{HostID: id, Username: "admin", PasswordHash: "$2a$10$secrethash"})
+	api.db.Model(&model.Host{}).Where("id = ?", id).Update("custom_cert_path", "/etc/certs/app.pem")
+
+	h, err := api.GetHostByDomain("  App.Example.COM ")
+	if err != nil {
+		t.Fatalf("GetHostByDomain: %v", err)
+	}
+	if h == nil || h.ID != id || h.HostType != "proxy" || !h.TLSEnabled {
+		t.Fatalf("host = %+v, want host %d", h, id)
+	}
+	if len(h.Upstreams) != 1 || h.Upstreams[0] != "localhost:10001" {
+		t.Errorf("upstreams = %v", h.Upstreams)
+	}
+
+	// Neither lookup hands plugins credentials or certificate paths.
+	single, _ := json.Marshal(h)
+	list, err := api.ListHosts()
+	if err != nil {
+		t.Fatalf("ListHosts: %v", err)
+	}
+	all, _ := json.Marshal(list)
+	for _, out := range []string{string(single), string(all)} {
+		for _, secret := range []string{"secrethash", "admin", "app.pem", "basic_auth", "custom_cert"} {
+			if strings.Contains(out, secret) {
+				t.Errorf("host info exposes %q: %s", secret, out)
+			}
+		}
+	}
+
+	if h, err := api.GetHostByDomain("missing.example.com"); err != nil || h != nil {
+		t.Errorf("GetHostByDomain(missing) = %+v, %v; want nil, nil", h, err)
+	}
+}
@@ -2,6 +2,7 @@ package plugin
 
 import (
 	"log/slog"
+	"strings"
 	"sync"
 	"time"
 )
@@ -12,6 +13,11 @@ type Event struct {
 	Payload map[string]interface{} `json:"payload"` // event-specific data
 	Source  string                 `json:"source"`  // originating plugin ID or "core"
 	Time    time.Time              `json:"time"`
+	// Command marks an event that asks a plugin to do something (such as
+	// "backup.trigger") rather than reporting that something happened.
+	// Commands reach exact-type and "*" subscribers only, never "prefix.*"
+	// ones, so notification subscriptions do not pick them up.
+	Command bool `json:"command,omitempty"`
 }
 
 // EventHandler is a callback that processes an event.
@@ -20,24 +26,61 @@ type EventHandler func(event Event)
 // EventBus is an in-memory publish/subscribe event bus.
 type EventBus struct {
 	mu       sync.RWMutex
-	handlers map[string][]EventHandler
+	handlers map[string][]subscription
+	nextID   uint64
 	logger   *slog.Logger
 }
 
+// subscription is a registered handler; id lets Unsubscribe find it.
+type subscription struct {
+	id uint64
+	fn EventHandler
+}
+
 // NewEventBus creates a new EventBus.
 func NewEventBus(logger *slog.Logger) *EventBus {
 	return &EventBus{
-		handlers: make(map[string][]EventHandler),
+		handlers: make(map[string][]subscription),
 		logger:   logger,
 	}
 }
 
 // Subscribe registers a handler for the given event type.
-// Use "*" to subscribe to all events.
+// Use "*" to subscribe to all events, or "prefix.*" to subscribe to every
+// non-command event type under prefix (e.g. "host.*" receives
+// "host.created").
 func (eb *EventBus) Subscribe(eventType string, handler EventHandler) {
+	eb.SubscribeCancel(eventType, handler)
+}
+
+// SubscribeCancel is like Subscribe but returns a function that removes the
+// handler again, for subscribers that do not live as long as the process
+// (such as a client connection). Calling it more than once is harmless.
+func (eb *EventBus) SubscribeCancel(eventType string, handler EventHandler) (unsubscribe func()) {
 	eb.mu.Lock()
 	defer eb.mu.Unlock()
-	eb.handlers[eventType] = append(eb.handlers[eventType], handler)
+	eb.nextID++
+	id := eb.nextID
+	eb.handlers[eventType] = append(eb.handlers[eventType], subscription{id: id, fn: handler})
+
+	return func() {
+		eb.mu.Lock()
+		defer eb.mu.Unlock()
+		subs := eb.handlers[eventType]
+		for i, sub := range subs {
+			if sub.id == id {
+				eb.handlers[eventType] = append(subs[:i:i], subs[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Subscribers returns the number of handlers registered for eventType.
+func (eb *EventBus) Subscribers(eventType string) int {
+	eb.mu.RLock()
+	defer eb.mu.RUnlock()
+	return len(eb.handlers[eventType])
 }
 
 // Publish dispatches an event to all matching subscribers.
@@ -49,9 +92,18 @@ func (eb *EventBus) Publish(event Event) {
 	}
 
 	eb.mu.RLock()
-	// Collect handlers: specific + wildcard.
-	handlers := make([]EventHandler, 0, len(eb.handlers[event.Type])+len(eb.handlers["*"]))
+	// Collect handlers: specific, then prefix patterns (not for commands),
+	// then wildcard.
+	handlers := make([]subscription, 0, len(eb.handlers[event.Type])+len(eb.handlers["*"]))
 	handlers = append(handlers, eb.handlers[event.Type]...)
+	for i := strings.IndexByte(event.Type, '.'); i >= 0 && !event.Command; {
+		handlers = append(handlers, eb.handlers[event.Type[:i]+".*"]...)
+		next := strings.IndexByte(event.Type[i+1:], '.')
+		if next < 0 {
+			break
+		}
+		i += next + 1
+	}
 	handlers = append(handlers, eb.handlers["*"]...)
 	eb.mu.RUnlock()
 
@@ -66,7 +118,7 @@ func (eb *EventBus) Publish(event Event) {
 					)
 				}
 			}()
-			h(event)
+			h.fn(event)
 		}()
 	}
 }
@@ -75,3 +75,56 @@ func TestEventBusNoSubscribers(t *testing.T) {
 	// Should not panic.
 	eb.Publish(Event{Type: "nobody.listens"})
 }
+
+func TestEventBusUnsubscribe(t *testing.T) {
+	eb := NewEventBus(slog.Default())
+
+	var a, b int32
+	cancelA := eb.SubscribeCancel("x", func(e Event) { atomic.AddInt32(&a, 1) })
+	eb.Subscribe("x", func(e Event) { atomic.AddInt32(&b, 1) })
+
+	eb.Publish(Event{Type: "x"})
+	cancelA()
+	cancelA()
+	eb.Publish(Event{Type: "x"})
+
+	if atomic.LoadInt32(&a) != 1 || atomic.LoadInt32(&b) != 2 {
+		t.Fatalf("a=%d b=%d, want 1 and 2", a, b)
+	}
+}
+
+func TestEventBusPrefixPattern(t *testing.T) {
+	eb := NewEventBus(slog.Default())
+
+	var host, system, caddy int32
+	eb.Subscribe("host.*", func(e Event) { atomic.AddInt32(&host, 1) })
+	eb.Subscribe("system.*", func(e Event) { atomic.AddInt32(&system, 1) })
+	eb.Subscribe("system.caddy.*", func(e Event) { atomic.AddInt32(&caddy, 1) })
+
+	eb.Publish(Event{Type: "host.deleted"})
+	eb.Publish(Event{Type: "system.caddy.down"})
+	eb.Publish(Event{Type: "hostile"})
+	eb.Publish(Event{Type: "host"})
+
+	if host != 1 || system != 1 || caddy != 1 {
+		t.Fatalf("host=%d system=%d caddy=%d, want 1 each", host, system, caddy)
+	}
+}
+
+func TestEventBusCommandsSkipPrefixPatterns(t *testing.T) {
+	eb := NewEventBus(slog.Default())
+
+	var exact, prefix, all int32
+	eb.Subscribe("backup.trigger", func(e Event) { atomic.AddInt32(&exact, 1) })
+	eb.Subscribe("backup.*", func(e Event) { atomic.AddInt32(&prefix, 1) })
+	eb.Subscribe("*", func(e Event) { atomic.AddInt32(&all, 1) })
+
+	eb.Publish(Event{Type: "backup.trigger", Command: true})
+	if exact != 1 || prefix != 0 || all != 1 {
+		t.Fatalf("command: exact=%d prefix=%d all=%d, want 1, 0, 1", exact, prefix, all)
+	}
+	eb.Publish(Event{Type: "backup.completed"})
+	if prefix != 1 {
+		t.Fatalf("prefix=%d after a non-command event, want 1", prefix)
+	}
+}
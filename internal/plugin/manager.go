@@ -17,10 +17,14 @@ import (
 // Manager manages the lifecycle of all registered plugins.
 type Manager struct {
 	mu       sync.RWMutex
-	plugins  map[string]Plugin   // id → plugin
-	contexts map[string]*Context // id → context
-	order    []string            // topological load order
-	disabled sync.Map            // id → true for disabled plugins (runtime guard)
+	plugins  map[string]Plugin     // id → plugin
+	contexts map[string]*Context   // id → context
+	order    []string              // topological load order
+	disabled sync.Map              // id → true for disabled plugins (runtime guard)
+	muxes    map[string]*pluginMux // id → route mux for Reloadable plugins
+	// initFailed marks disabled plugins whose Init failed at startup, so
+	// their routes and Init-time state are missing until a restart.
+	initFailed map[string]bool
 
 	db             *gorm.DB
 	router         *gin.RouterGroup // /api/plugins (protected, any role)
@@ -39,15 +43,17 @@ func NewManager(db *gorm.DB, router *gin.RouterGroup, operatorRouter *gin.Router
 	return &Manager{
 		plugins:        make(map[string]Plugin),
 		contexts:       make(map[string]*Context),
+		muxes:          make(map[string]*pluginMux),
+		initFailed:     make(map[string]bool),
 		db:             db,
 		router:         router,
 		operatorRouter: operatorRouter,
 		adminRouter:    adminRouter,
 		publicRouter:   publicRouter,
-		eventBus:     NewEventBus(logger),
-		coreAPI:      coreAPI,
-		dataDir:      dataDir,
-		logger:       logger,
+		eventBus:       NewEventBus(logger),
+		coreAPI:        coreAPI,
+		dataDir:        dataDir,
+		logger:         logger,
 	}
 }
 
@@ -118,37 +124,36 @@ func (m *Manager) InitAll() error {
 		}
 
 		// Prepare plugin data directory.
-		pluginDataDir := filepath.Join(m.dataDir, "plugins", id)
-		if err := os.MkdirAll(pluginDataDir, 0755); err != nil {
+		if err := os.MkdirAll(m.pluginDataDir(id), 0755); err != nil {
 			return fmt.Errorf("create data dir for plugin %q: %w", id, err)
 		}
 
-		// Create a sub-router under /api/plugins/{id}
-		pluginRouter := m.router.Group("/" + id)
-		operatorPluginRouter := m.operatorRouter.Group("/" + id)
-		adminPluginRouter := m.adminRouter.Group("/" + id)
-		publicPluginRouter := m.publicRouter.Group("/" + id)
-
-		ctx := &Context{
-			DB:             m.db,
-			Router:         pluginRouter,
-			OperatorRouter: operatorPluginRouter,
-			AdminRouter:    adminPluginRouter,
-			PublicRouter:   publicPluginRouter,
-			EventBus:     m.eventBus,
-			Logger:       m.logger.With("plugin", id),
-			DataDir:      pluginDataDir,
-			ConfigStore:  NewConfigStore(m.db, id),
-			CoreAPI:      m.coreAPI,
+		// Create a sub-router under /api/plugins/{id}. Reloadable plugins
+		// get theirs on inner engines that Reload can replace.
+		var groups [4]*gin.RouterGroup
+		var engines [4]*gin.Engine
+		_, reloadable := p.(Reloadable)
+		if reloadable {
+			engines, groups = m.newMuxEngines(id)
+		} else {
+			for i, rg := range m.tierRouters() {
+				groups[i] = rg.Group("/" + id)
+			}
 		}
-		m.contexts[id] = ctx
 
-		if err := p.Init(ctx); err != nil {
+		err := p.Init(m.newContext(id, groups))
+		if reloadable {
+			x := &pluginMux{mounted: make(map[string]bool)}
+			m.swapMux(id, x, engines, false)
+			m.muxes[id] = x
+		}
+		if err != nil {
 			if enabled {
 				return fmt.Errorf("init plugin %q (v%s): %w", id, meta.Version, err)
 			}
 			// Disabled plugins failing Init is non-fatal; just log and skip.
 			m.logger.Warn("init failed for disabled plugin", "id", id, "err", err)
+			m.initFailed[id] = true
 			continue
 		}
 		if enabled {
@@ -161,6 +166,30 @@ func (m *Manager) InitAll() error {
 	return nil
 }
 
+// pluginDataDir returns the data directory of plugin id.
+func (m *Manager) pluginDataDir(id string) string {
+	return filepath.Join(m.dataDir, "plugins", id)
+}
+
+// newContext builds the Context passed to a plugin's Init, with groups as its
+// Router, OperatorRouter, AdminRouter and PublicRouter.
+func (m *Manager) newContext(id string, groups [4]*gin.RouterGroup) *Context {
+	ctx := &Context{
+		DB:             m.db,
+		Router:         groups[0],
+		OperatorRouter: groups[1],
+		AdminRouter:    groups[2],
+		PublicRouter:   groups[3],
+		EventBus:       m.eventBus,
+		Logger:         m.logger.With("plugin", id),
+		DataDir:        m.pluginDataDir(id),
+		ConfigStore:    NewConfigStore(m.db, id),
+		CoreAPI:        m.coreAPI,
+	}
+	m.contexts[id] = ctx
+	return ctx
+}
+
 // StartAll calls Start on every enabled plugin in load order.
 func (m *Manager) StartAll() error {
 	m.mu.RLock()
@@ -214,8 +243,39 @@ func (m *Manager) List() []PluginInfo {
 	return list
 }
 
+// Health returns List with the health of every enabled plugin that
+// implements HealthChecker. Checks run concurrently.
+func (m *Manager) Health() []PluginInfo {
+	list := m.List()
+
+	m.mu.RLock()
+	checkers := make(map[int]HealthChecker)
+	for i, info := range list {
+		if hc, ok := m.plugins[info.ID].(HealthChecker); ok && info.Enabled {
+			checkers[i] = hc
+		}
+	}
+	m.mu.RUnlock()
+
+	var wg sync.WaitGroup
+	for i, hc := range checkers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			health := &PluginHealth{Status: HealthHealthy}
+			if err := hc.Health(); err != nil {
+				health = &PluginHealth{Status: HealthUnhealthy, Error: err.Error()}
+			}
+			list[i].Health = health
+		}()
+	}
+	wg.Wait()
+	return list
+}
+
 // Enable enables a plugin. Takes effect immediately: updates the disabled map
-// and starts the plugin's background tasks. Idempotent: no-op if already enabled.
+// and starts the plugin's background tasks; Reloadable plugins are also
+// re-initialised. Idempotent: no-op if already enabled.
 func (m *Manager) Enable(id string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -241,6 +301,15 @@ func (m *Manager) Enable(id string) error {
 		return err
 	}
 
+	// Reloadable plugins are re-initialised so they pick up any changes
+	// made while disabled, and recover if Init failed at startup.
+	if rp, ok := p.(Reloadable); ok {
+		if err := m.reinit(id, rp); err != nil {
+			m.setState(id, false)
+			return err
+		}
+	}
+
 	// Remove from disabled map so guard middleware allows requests.
 	m.disabled.Delete(id)
 
@@ -254,8 +323,9 @@ func (m *Manager) Enable(id string) error {
 	return nil
 }
 
-// Disable disables a plugin. Takes effect immediately: stops the plugin and
-// blocks its API routes via the guard middleware. Idempotent: no-op if already disabled.
+// Disable disables a plugin. Takes effect immediately: stops the plugin (and
+// tears down Reloadable ones) and blocks its API routes via the guard
+// middleware. Idempotent: no-op if already disabled.
 func (m *Manager) Disable(id string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -290,6 +360,12 @@ func (m *Manager) Disable(id string) error {
 		m.logger.Error("failed to stop plugin on disable", "id", id, "err", err)
 	}
 
+	if rp, ok := p.(Reloadable); ok {
+		if err := rp.Teardown(); err != nil {
+			m.logger.Error("failed to tear down plugin on disable", "id", id, "err", err)
+		}
+	}
+
 	// Mark as disabled in memory (guard middleware blocks immediately).
 	m.disabled.Store(id, true)
 
@@ -1,6 +1,7 @@
 package plugin
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
@@ -59,6 +60,7 @@ func (s *stubCoreAPI) CreateHost(req CreateHostRequest) (uint, error)          {
 func (s *stubCoreAPI) DeleteHost(id uint) error                                { return nil }
 func (s *stubCoreAPI) ListHosts() ([]map[string]interface{}, error)            { return nil, nil }
 func (s *stubCoreAPI) GetHost(id uint) (map[string]interface{}, error)         { return nil, nil }
+func (s *stubCoreAPI) GetHostByDomain(domain string) (*HostInfo, error)       { return nil, nil }
 func (s *stubCoreAPI) UpdateHostUpstream(hostID uint, newUpstream string) error { return nil }
 func (s *stubCoreAPI) ReloadCaddy() error                                      { return nil }
 func (s *stubCoreAPI) GetSetting(key string) (string, error)                   { return "", nil }
@@ -118,6 +120,7 @@ func (s *stubCoreAPI) PHPListSites() ([]map[string]interface{}, error)
 // NLOps stubs
 func (s *stubCoreAPI) ToggleHost(id uint) error                                          { return nil }
 func (s *stubCoreAPI) CloneHost(id uint, newDomain string) (uint, error)                 { return 1, nil }
+func (s *stubCoreAPI) GetHostDiagnostics(id uint, lines int) (map[string]interface{}, error) { return nil, nil }
 func (s *stubCoreAPI) GetCaddyStatus() (map[string]interface{}, error)                   { return nil, nil }
 func (s *stubCoreAPI) RestartCaddy() error                                               { return nil }
 func (s *stubCoreAPI) StartProject(id uint) error                                        { return nil }
@@ -143,6 +146,7 @@ func (s *stubCoreAPI) CronJobLogs(taskID uint, limit int) ([]map[string]interfac
 func (s *stubCoreAPI) CronJobTrigger(id uint) error                                      { return nil }
 func (s *stubCoreAPI) EncryptSecret(plaintext string) (string, error)                    { return plaintext, nil }
 func (s *stubCoreAPI) DecryptSecret(ciphertext string) (string, error)                   { return ciphertext, nil }
+func (s *stubCoreAPI) WriteAuditLog(c *gin.Context, action, target, targetID, detail string) {}
 
 // ── stub plugin ──
 
@@ -337,3 +341,43 @@ func TestPluginDataDir(t *testing.T) {
 		t.Fatalf("plugin data dir was not created: %s", expectedDir)
 	}
 }
+
+// healthPlugin is a stub plugin whose Health returns err.
+type healthPlugin struct {
+	stubPlugin
+	err error
+}
+
+func (p *healthPlugin) Health() error { return p.err }
+
+func TestHealth(t *testing.T) {
+	mgr, db := setupTestManager(t)
+
+	mgr.Register(&healthPlugin{stubPlugin: *newStubPlugin("good", nil, 0)})
+	mgr.Register(&healthPlugin{stubPlugin: *newStubPlugin("bad", nil, 1), err: errors.New("daemon unreachable")})
+	mgr.Register(&healthPlugin{stubPlugin: *newStubPlugin("off", nil, 2), err: errors.New("should not run")})
+	mgr.Register(newStubPlugin("plain", nil, 3))
+
+	enabled, disabled := true, false
+	db.Create(&PluginState{ID: "good", Enabled: &enabled})
+	db.Create(&PluginState{ID: "bad", Enabled: &enabled})
+	db.Create(&PluginState{ID: "off", Enabled: &disabled})
+	db.Create(&PluginState{ID: "plain", Enabled: &enabled})
+
+	got := map[string]*PluginHealth{}
+	for _, info := range mgr.Health() {
+		got[info.ID] = info.Health
+	}
+	if h := got["good"]; h == nil || h.Status != HealthHealthy || h.Error != "" {
+		t.Errorf("good: %+v", h)
+	}
+	if h := got["bad"]; h == nil || h.Status != HealthUnhealthy || h.Error != "daemon unreachable" {
+		t.Errorf("bad: %+v", h)
+	}
+	if got["off"] != nil {
+		t.Errorf("disabled plugins are not checked: %+v", got["off"])
+	}
+	if got["plain"] != nil {
+		t.Errorf("plugins without HealthChecker report no health: %+v", got["plain"])
+	}
+}
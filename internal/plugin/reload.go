@@ -0,0 +1,158 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ErrRestartRequired is returned by Reload for plugins that do not implement
+// Reloadable: their routes and Init-time state only change on restart.
+var ErrRestartRequired = errors.New("plugin does not support live reload; restart required")
+
+// pluginMux serves a Reloadable plugin's routes. Gin cannot remove or replace
+// routes, so Init registers them on inner engines (one per router tier) and
+// the panel router holds forwarding routes; a reload swaps in fresh engines.
+type pluginMux struct {
+	engines [4]atomic.Pointer[gin.Engine]
+	mounted map[string]bool // "METHOD /path" with a forwarding route
+}
+
+// muxKeys carries the outer request's context keys (user_id, username, ...)
+// set by the tier middleware into the inner engine.
+type muxKeys struct{}
+
+func (x *pluginMux) forward(tier int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := context.WithValue(c.Request.Context(), muxKeys{}, c.Keys)
+		x.engines[tier].Load().ServeHTTP(c.Writer, c.Request.WithContext(ctx))
+	}
+}
+
+func copyMuxKeys(c *gin.Context) {
+	if keys, ok := c.Request.Context().Value(muxKeys{}).(map[string]any); ok {
+		for k, v := range keys {
+			c.Set(k, v)
+		}
+	}
+}
+
+// tierRouters returns the four plugin routers in Context order.
+func (m *Manager) tierRouters() [4]*gin.RouterGroup {
+	return [4]*gin.RouterGroup{m.router, m.operatorRouter, m.adminRouter, m.publicRouter}
+}
+
+// newMuxEngines builds the inner engines for a Reloadable plugin along with
+// the router groups for its Context.
+func (m *Manager) newMuxEngines(id string) ([4]*gin.Engine, [4]*gin.RouterGroup) {
+	var engines [4]*gin.Engine
+	var groups [4]*gin.RouterGroup
+	for i, rg := range m.tierRouters() {
+		engines[i] = gin.New()
+		engines[i].Use(copyMuxKeys)
+		groups[i] = engines[i].Group(rg.BasePath() + "/" + id)
+	}
+	return engines, groups
+}
+
+// swapMux installs engines and forwards any routes they define that the
+// panel router does not know yet. Routes can only be added to the panel
+// router before it starts serving, so later additions wait for a restart.
+func (m *Manager) swapMux(id string, x *pluginMux, engines [4]*gin.Engine, serving bool) {
+	for i, rg := range m.tierRouters() {
+		for _, r := range engines[i].Routes() {
+			key := r.Method + " " + r.Path
+			if x.mounted[key] {
+				continue
+			}
+			if serving {
+				m.logger.Warn("route added on reload is served after restart", "id", id, "route", key)
+				continue
+			}
+			rg.Handle(r.Method, strings.TrimPrefix(r.Path, rg.BasePath()), x.forward(i))
+			x.mounted[key] = true
+		}
+		x.engines[i].Store(engines[i])
+	}
+}
+
+// CanReload reports whether the plugin implements Reloadable.
+func (m *Manager) CanReload(id string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	_, ok := m.plugins[id].(Reloadable)
+	return ok
+}
+
+// RestartRequired reports whether enabling the plugin only takes full
+// effect after a panel restart: it is not Reloadable and its Init failed at
+// startup, so its routes were never registered. Other plugins are Init'd
+// at startup even when disabled, and enabling them just unblocks their
+// routes and starts them.
+func (m *Manager) RestartRequired(id string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	_, ok := m.plugins[id].(Reloadable)
+	return !ok && m.initFailed[id]
+}
+
+// Reload tears down a Reloadable plugin and runs Init again with fresh router
+// groups, restarting it if it is enabled. Plugins that do not implement
+// Reloadable return ErrRestartRequired.
+func (m *Manager) Reload(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p, ok := m.plugins[id]
+	if !ok {
+		return fmt.Errorf("plugin %q not found", id)
+	}
+	rp, ok := p.(Reloadable)
+	if !ok {
+		return ErrRestartRequired
+	}
+
+	enabled := m.isEnabled(id)
+	if enabled {
+		if err := rp.Stop(); err != nil {
+			m.logger.Error("failed to stop plugin on reload", "id", id, "err", err)
+		}
+	}
+	if err := m.reinit(id, rp); err != nil {
+		return err
+	}
+	if enabled {
+		if err := rp.Start(); err != nil {
+			return fmt.Errorf("start plugin %q: %w", id, err)
+		}
+	}
+	m.logger.Info("plugin reloaded", "id", id)
+	return nil
+}
+
+// reinit runs Teardown and Init on a stopped Reloadable plugin and swaps in
+// the routes Init registered. If Init fails the plugin is left serving no
+// routes. The caller holds m.mu.
+func (m *Manager) reinit(id string, rp Reloadable) error {
+	x := m.muxes[id]
+	if x == nil {
+		return nil // InitAll has not run yet; it will call Init.
+	}
+	if err := rp.Teardown(); err != nil {
+		return fmt.Errorf("teardown plugin %q: %w", id, err)
+	}
+
+	engines, groups := m.newMuxEngines(id)
+	if err := rp.Init(m.newContext(id, groups)); err != nil {
+		engines, _ = m.newMuxEngines(id)
+		m.swapMux(id, x, engines, true)
+		return fmt.Errorf("init plugin %q: %w", id, err)
+	}
+	m.swapMux(id, x, engines, true)
+	delete(m.initFailed, id)
+	return nil
+}
@@ -0,0 +1,145 @@
+package plugin
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// reloadablePlugin registers a route whose response depends on how many
+// times Init has run, plus a route only the first Init registers.
+type reloadablePlugin struct {
+	stubPlugin
+	inits, teardowns int
+}
+
+func (p *reloadablePlugin) Init(ctx *Context) error {
+	p.inits++
+	gen := p.inits
+	ctx.Router.GET("/gen", func(c *gin.Context) {
+		c.String(http.StatusOK, "%d user=%d", gen, c.GetUint("user_id"))
+	})
+	if gen == 1 {
+		ctx.Router.GET("/first", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	}
+	return nil
+}
+
+func (p *reloadablePlugin) Teardown() error { p.teardowns++; return nil }
+
+func setupReloadManager(t *testing.T) (*Manager, *gin.Engine) {
+	t.Helper()
+	db := setupTestDB(t)
+	db.AutoMigrate(&PluginState{})
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	// The protected tier's auth middleware sets user_id.
+	rg := r.Group("/api/plugins", func(c *gin.Context) { c.Set("user_id", uint(7)) })
+	mgr := NewManager(db, rg, r.Group("/api/plugins"), r.Group("/api/plugins"), r.Group("/api/plugins"), &stubCoreAPI{}, t.TempDir())
+	// Management routes share the /api/plugins/:id prefix with plugin routes.
+	r.POST("/api/plugins/:id/enable", func(c *gin.Context) { c.String(http.StatusOK, "enable "+c.Param("id")) })
+	return mgr, r
+}
+
+func get(r *gin.Engine, path string) (int, string) {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+	return w.Code, w.Body.String()
+}
+
+func TestReload_ReinitsAndReregistersRoutes(t *testing.T) {
+	mgr, r := setupReloadManager(t)
+	p := &reloadablePlugin{stubPlugin: *newStubPlugin("live", nil, 0)}
+	mgr.Register(p)
+	mgr.Register(newStubPlugin("static", nil, 1))
+	mgr.setState("live", true)
+
+	if err := mgr.InitAll(); err != nil {
+		t.Fatal(err)
+	}
+	if code, body := get(r, "/api/plugins/live/gen"); code != http.StatusOK || body != "1 user=7" {
+		t.Fatalf("before reload: %d %q", code, body)
+	}
+
+	if err := mgr.Reload("live"); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if p.inits != 2 || p.teardowns != 1 {
+		t.Errorf("inits=%d teardowns=%d, want 2 and 1", p.inits, p.teardowns)
+	}
+	if !p.stopCalled || !p.startCalled {
+		t.Error("an enabled plugin should be stopped and started again")
+	}
+	if code, body := get(r, "/api/plugins/live/gen"); code != http.StatusOK || body != "2 user=7" {
+		t.Errorf("after reload: %d %q", code, body)
+	}
+	if code, _ := get(r, "/api/plugins/live/first"); code != http.StatusNotFound {
+		t.Errorf("route dropped by the new Init should be gone: %d", code)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/api/plugins/live/enable", nil))
+	if w.Body.String() != "enable live" {
+		t.Errorf("management route shadowed by plugin routes: %d %q", w.Code, w.Body.String())
+	}
+
+	if err := mgr.Reload("static"); !errors.Is(err, ErrRestartRequired) {
+		t.Errorf("Reload of a non-reloadable plugin: err = %v", err)
+	}
+	if mgr.CanReload("static") || !mgr.CanReload("live") {
+		t.Error("CanReload mismatch")
+	}
+}
+
+func TestReload_EnableDisable(t *testing.T) {
+	mgr, r := setupReloadManager(t)
+	p := &reloadablePlugin{stubPlugin: *newStubPlugin("live", nil, 0)}
+	mgr.Register(p)
+
+	if err := mgr.InitAll(); err != nil {
+		t.Fatal(err)
+	}
+	if code, _ := get(r, "/api/plugins/live/gen"); code != http.StatusNotFound {
+		t.Fatalf("disabled plugin should be blocked: %d", code)
+	}
+
+	if err := mgr.Enable("live"); err != nil {
+		t.Fatal(err)
+	}
+	if code, body := get(r, "/api/plugins/live/gen"); code != http.StatusOK || body != "2 user=7" {
+		t.Errorf("Enable should re-run Init: %d %q", code, body)
+	}
+
+	if err := mgr.Disable("live"); err != nil {
+		t.Fatal(err)
+	}
+	if p.teardowns != 2 {
+		t.Errorf("teardowns = %d, want 2 (enable + disable)", p.teardowns)
+	}
+	if code, _ := get(r, "/api/plugins/live/gen"); code != http.StatusNotFound {
+		t.Errorf("disabled plugin should be blocked again: %d", code)
+	}
+}
+
+// failingPlugin fails Init, as a plugin with a broken dependency would.
+type failingPlugin struct{ stubPlugin }
+
+func (p *failingPlugin) Init(*Context) error { return errors.New("missing binary") }
+
+func TestRestartRequired_OnlyAfterFailedInit(t *testing.T) {
+	mgr, _ := setupReloadManager(t)
+	mgr.Register(newStubPlugin("static", nil, 0))
+	mgr.Register(&failingPlugin{stubPlugin: *newStubPlugin("broken", nil, 1)})
+	mgr.Register(&reloadablePlugin{stubPlugin: *newStubPlugin("live", nil, 2)})
+
+	if err := mgr.InitAll(); err != nil {
+		t.Fatal(err)
+	}
+	for id, want := range map[string]bool{"static": false, "broken": true, "live": false} {
+		if got := mgr.RestartRequired(id); got != want {
+			t.Errorf("RestartRequired(%q) = %v, want %v", id, got, want)
+		}
+	}
+}
@@ -57,6 +57,9 @@ type CoreAPI interface {
 	DeleteHost(id uint) error
 	ListHosts() ([]map[string]interface{}, error)
 	GetHost(id uint) (map[string]interface{}, error)
+	// GetHostByDomain looks a host up by domain (case-insensitive). It
+	// returns nil and no error when no host has that domain.
+	GetHostByDomain(domain string) (*HostInfo, error)
 	UpdateHostUpstream(hostID uint, newUpstream string) error
 	ReloadCaddy() error
 
@@ -125,6 +128,9 @@ type CoreAPI interface {
 	// ── NLOps: Host management additions ──
 	ToggleHost(id uint) error
 	CloneHost(id uint, newDomain string) (uint, error)
+	// GetHostDiagnostics returns the rendered site block, recent access and
+	// error log lines, and upstream reachability for a host.
+	GetHostDiagnostics(id uint, lines int) (map[string]interface{}, error)
 
 	// ── NLOps: Caddy management ──
 	GetCaddyStatus() (map[string]interface{}, error)
@@ -162,6 +168,12 @@ type CoreAPI interface {
 	// ── Credential encryption for plugins ──
 	EncryptSecret(plaintext string) (string, error)
 	DecryptSecret(ciphertext string) (string, error)
+
+	// ── Audit logging ──
+	// WriteAuditLog records an audit entry for the user of request c and
+	// marks the request as logged, so the generic entry the core adds for
+	// plugin mutations is not written on top.
+	WriteAuditLog(c *gin.Context, action, target, targetID, detail string)
 }
 
 // UpdateHostRequest describes fields that can be changed on an existing host via AI.
@@ -189,6 +201,17 @@ type CreateProjectRequest struct {
 	StartCommand    string `json:"start_command"`
 }
 
+// HostInfo is the plugin-facing summary of a host. It leaves out
+// credentials (basic auth), certificate paths and DNS provider settings.
+type HostInfo struct {
+	ID         uint     `json:"id"`
+	Domain     string   `json:"domain"`
+	HostType   string   `json:"host_type"`
+	Enabled    bool     `json:"enabled"`
+	TLSEnabled bool     `json:"tls_enabled"`
+	Upstreams  []string `json:"upstreams"`
+}
+
 // CreateHostRequest is the minimal set of fields a plugin needs to create a
 // host entry. Supports proxy, php, and static host types.
 type CreateHostRequest struct {
@@ -206,8 +229,21 @@ type CreateHostRequest struct {
 // PluginInfo is the serialisable representation returned by the management API.
 type PluginInfo struct {
 	Metadata
-	Enabled       bool `json:"enabled"`
-	ShowInSidebar bool `json:"show_in_sidebar"`
+	Enabled       bool          `json:"enabled"`
+	ShowInSidebar bool          `json:"show_in_sidebar"`
+	Health        *PluginHealth `json:"health,omitempty"` // enabled HealthCheckers only
+}
+
+// Plugin health states reported in PluginHealth.Status.
+const (
+	HealthHealthy   = "healthy"
+	HealthUnhealthy = "unhealthy"
+)
+
+// PluginHealth is the result of a plugin's most recent health check.
+type PluginHealth struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
 }
 
 // FrontendManifest declares the routes and menu items a plugin contributes to
@@ -235,6 +271,25 @@ type FrontendProvider interface {
 	FrontendManifest() FrontendManifest
 }
 
+// HealthChecker is an optional interface for plugins that can report whether
+// their runtime dependencies (a daemon, credentials, ...) are usable. Health
+// should return quickly; it is called on every plugin list request.
+type HealthChecker interface {
+	Health() error
+}
+
+// Reloadable is an optional interface for plugins that can be re-initialised
+// while the panel is running. Their routes are served from a router that the
+// manager rebuilds on every reload, so Init may run more than once.
+type Reloadable interface {
+	Plugin
+
+	// Teardown releases the state set up by Init (after Stop, if the plugin
+	// was running) so that Init can run again with a fresh Context. It must
+	// be safe to call on a plugin that is already torn down.
+	Teardown() error
+}
+
 // DatabaseCreateInstanceRequest holds parameters for creating a database instance.
 type DatabaseCreateInstanceRequest struct {
 	Engine       string `json:"engine"`        // mysql, postgres, mariadb, redis
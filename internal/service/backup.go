@@ -0,0 +1,512 @@
+package service
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+	"github.com/web-casa/webcasa/internal/config"
+	"github.com/web-casa/webcasa/internal/database"
+	"gorm.io/gorm"
+)
+
+// ErrInvalidBackupArchive is returned by Restore for an archive that fails
+// validation before anything live is touched.
+var ErrInvalidBackupArchive = errors.New("invalid backup archive")
+
+// ErrBackupEntryNotAllowed marks an archive rejected for an entry outside the
+// files a backup may hold: unsafe paths, links or unexpected files. It is
+// always wrapped together with ErrInvalidBackupArchive.
+var ErrBackupEntryNotAllowed = errors.New("backup entry not allowed")
+
+// maxRestoreBytes caps the unpacked size of an archive passed to Restore.
+const maxRestoreBytes = 2 << 30
+
+// backupNameRE matches archive names created by BackupService; anything else
+// in the backup directory (e.g. Caddyfile.*.bak) is left alone.
+// The optional -N suffix orders archives created within the same second.
+var backupNameRE = regexp.MustCompile(`^webcasa-backup-(\d{8}-\d{6})(?:-(\d+))?\.tar\.gz$`)
+
+// backupOrder splits an archive name into its timestamp and sequence.
+func backupOrder(id string) (string, int) {
+	m := backupNameRE.FindStringSubmatch(id)
+	if m == nil {
+		return "", 0
+	}
+	seq, _ := strconv.Atoi(m[2])
+	return m[1], seq
+}
+
+// BackupInfo describes one backup archive. ID is the archive file name.
+type BackupInfo struct {
+	ID        string    `json:"id"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// BackupService writes full panel backups: a tar.gz holding a consistent
+// copy of the SQLite database, the current Caddyfile and the uploaded
+// certificates under DataDir/certs.
+type BackupService struct {
+	db  *gorm.DB
+	cfg *config.Config
+	mu  sync.Mutex
+}
+
+// NewBackupService creates a new BackupService
+func NewBackupService(db *gorm.DB, cfg *config.Config) *BackupService {
+	return &BackupService{db: db, cfg: cfg}
+}
+
+// Create writes a new archive to cfg.BackupDir and prunes the oldest ones
+// beyond cfg.BackupKeep.
+func (s *BackupService) Create() (*BackupInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(s.cfg.BackupDir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	// VACUUM INTO gives a consistent copy without stopping writers.
+	tmpDir, err := os.MkdirTemp(s.cfg.BackupDir, ".backup-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	dbCopy := filepath.Join(tmpDir, "webcasa.db")
+	if err := s.db.Exec("VACUUM INTO ?", dbCopy).Error; err != nil {
+		return nil, fmt.Errorf("failed to snapshot database: %w", err)
+	}
+
+	name := s.archiveName(time.Now())
+	path := filepath.Join(s.cfg.BackupDir, name)
+	if err := s.writeArchive(path+".tmp", dbCopy); err != nil {
+		os.Remove(path + ".tmp")
+		return nil, err
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		os.Remove(path + ".tmp")
+		return nil, fmt.Errorf("failed to save backup: %w", err)
+	}
+
+	s.prune()
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat backup: %w", err)
+	}
+	return &BackupInfo{ID: name, Size: info.Size(), CreatedAt: info.ModTime()}, nil
+}
+
+// archiveName returns an archive name for t that sorts after every
+// existing archive from the same second.
+func (s *BackupService) archiveName(t time.Time) string {
+	stamp := t.Format("20060102-150405")
+	next := 0
+	existing, _ := s.List()
+	for _, b := range existing {
+		if ts, seq := backupOrder(b.ID); ts == stamp && seq+1 > next {
+			next = seq + 1
+		}
+	}
+	if next == 0 {
+		return "webcasa-backup-" + stamp + ".tar.gz"
+	}
+	return fmt.Sprintf("webcasa-backup-%s-%d.tar.gz", stamp, next)
+}
+
+func (s *BackupService) writeArchive(path, dbCopy string) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create backup: %w", err)
+	}
+	defer f.Close()
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+
+	if err := addFileToTar(tw, dbCopy, "webcasa.db"); err != nil {
+		return err
+	}
+	if _, err := os.Stat(s.cfg.CaddyfilePath); err == nil {
+		if err := addFileToTar(tw, s.cfg.CaddyfilePath, "Caddyfile"); err != nil {
+			return err
+		}
+	}
+	certDir := filepath.Join(s.cfg.DataDir, "certs")
+	if _, err := os.Stat(certDir); err == nil {
+		err := filepath.WalkDir(certDir, func(p string, d fs.DirEntry, err error) error {
+			if err != nil || !d.Type().IsRegular() {
+				return err
+			}
+			rel, err := filepath.Rel(s.cfg.DataDir, p)
+			if err != nil {
+				return err
+			}
+			return addFileToTar(tw, p, filepath.ToSlash(rel))
+		})
+		if err != nil {
+			return fmt.Errorf("failed to archive certificates: %w", err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("failed to write backup: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("failed to write backup: %w", err)
+	}
+	return f.Close()
+}
+
+func addFileToTar(tw *tar.Writer, src, name string) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	hdr := &tar.Header{Name: name, Mode: 0600, Size: info.Size(), ModTime: info.ModTime()}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return fmt.Errorf("failed to archive %s: %w", name, err)
+	}
+	if _, err := io.Copy(tw, f); err != nil {
+		return fmt.Errorf("failed to archive %s: %w", name, err)
+	}
+	return nil
+}
+
+// prune deletes the oldest archives beyond cfg.BackupKeep.
+func (s *BackupService) prune() {
+	backups, err := s.List()
+	if err != nil || s.cfg.BackupKeep <= 0 || len(backups) <= s.cfg.BackupKeep {
+		return
+	}
+	for _, b := range backups[s.cfg.BackupKeep:] {
+		if err := os.Remove(filepath.Join(s.cfg.BackupDir, b.ID)); err != nil {
+			log.Printf("Warning: failed to prune backup %s: %v", b.ID, err)
+		}
+	}
+}
+
+// List returns the archives in cfg.BackupDir, newest first.
+func (s *BackupService) List() ([]BackupInfo, error) {
+	entries, err := os.ReadDir(s.cfg.BackupDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []BackupInfo{}, nil
+		}
+		return nil, fmt.Errorf("failed to list backups: %w", err)
+	}
+
+	backups := []BackupInfo{}
+	for _, e := range entries {
+		if e.IsDir() || !backupNameRE.MatchString(e.Name()) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		backups = append(backups, BackupInfo{ID: e.Name(), Size: info.Size(), CreatedAt: info.ModTime()})
+	}
+	// Names embed the creation time, so they sort chronologically.
+	sort.Slice(backups, func(i, j int) bool {
+		ti, si := backupOrder(backups[i].ID)
+		tj, sj := backupOrder(backups[j].ID)
+		if ti != tj {
+			return ti > tj
+		}
+		return si > sj
+	})
+	return backups, nil
+}
+
+// Path returns the file path of archive id, or error.backup_not_found when
+// id is not an existing archive name.
+func (s *BackupService) Path(id string) (string, error) {
+	if !backupNameRE.MatchString(id) {
+		return "", fmt.Errorf("error.backup_not_found")
+	}
+	path := filepath.Join(s.cfg.BackupDir, id)
+	if _, err := os.Stat(path); err != nil {
+		return "", fmt.Errorf("error.backup_not_found")
+	}
+	return path, nil
+}
+
+// Restore replaces the database, Caddyfile and certificates with the
+// contents of a backup archive produced by Create. The archive is fully
+// extracted and the database checked before anything live is touched;
+// ErrInvalidBackupArchive is returned for archives that fail validation.
+// The caller is responsible for re-applying the Caddy config afterwards.
+func (s *BackupService) Restore(r io.Reader) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(s.cfg.BackupDir, 0700); err != nil {
+		return fmt.Errorf("failed to create backup directory: %w", err)
+	}
+	tmpDir, err := os.MkdirTemp(s.cfg.BackupDir, ".restore-")
+	if err != nil {
+		return fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	if err := extractBackup(r, tmpDir); err != nil {
+		log.Printf("Warning: rejected backup archive: %v", err)
+		return fmt.Errorf("%w: %w", ErrInvalidBackupArchive, err)
+	}
+	dbCopy := filepath.Join(tmpDir, "webcasa.db")
+	if err := checkBackupDB(dbCopy); err != nil {
+		log.Printf("Warning: rejected backup archive: %v", err)
+		return fmt.Errorf("%w: %w", ErrInvalidBackupArchive, err)
+	}
+
+	if err := s.restoreDB(dbCopy); err != nil {
+		return err
+	}
+	// A backup taken by an older version lacks newer tables and columns.
+	if err := database.Migrate(s.db); err != nil {
+		return fmt.Errorf("failed to migrate restored database: %w", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(tmpDir, "Caddyfile")); err == nil {
+		tmp := s.cfg.CaddyfilePath + ".restore"
+		if err := os.WriteFile(tmp, data, 0600); err != nil {
+			return fmt.Errorf("failed to restore Caddyfile: %w", err)
+		}
+		if err := os.Rename(tmp, s.cfg.CaddyfilePath); err != nil {
+			os.Remove(tmp)
+			return fmt.Errorf("failed to restore Caddyfile: %w", err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "certs")); err == nil {
+		if err := s.restoreCerts(filepath.Join(tmpDir, "certs")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// extractBackup unpacks a backup archive into dir. Only webcasa.db,
+// Caddyfile and regular files under certs/ are accepted; absolute paths,
+// ".." components, links and other entry types fail the whole archive.
+func extractBackup(r io.Reader, dir string) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("not a gzip archive: %w", err)
+	}
+	tr := tar.NewReader(gz)
+	var total int64
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("read archive: %w", err)
+		}
+
+		name := hdr.Name
+		if strings.Contains(name, "\\") || strings.HasPrefix(name, "/") {
+			return fmt.Errorf("%w: unsafe path %q", ErrBackupEntryNotAllowed, name)
+		}
+		for _, part := range strings.Split(name, "/") {
+			if part == ".." {
+				return fmt.Errorf("%w: unsafe path %q", ErrBackupEntryNotAllowed, name)
+			}
+		}
+		name = filepath.Clean(filepath.FromSlash(name))
+		if hdr.Typeflag == tar.TypeDir {
+			continue
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			return fmt.Errorf("%w: unsupported entry %q", ErrBackupEntryNotAllowed, hdr.Name)
+		}
+		if name != "webcasa.db" && name != "Caddyfile" && !strings.HasPrefix(name, "certs"+string(filepath.Separator)) {
+			return fmt.Errorf("%w: unexpected file %q", ErrBackupEntryNotAllowed, hdr.Name)
+		}
+
+		total += hdr.Size
+		if total > maxRestoreBytes {
+			return fmt.Errorf("archive exceeds %d bytes", int64(maxRestoreBytes))
+		}
+		dest := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(dest), 0700); err != nil {
+			return err
+		}
+		f, err := os.OpenFile(dest, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0600)
+		if err != nil {
+			return fmt.Errorf("extract %q: %w", hdr.Name, err)
+		}
+		_, err = io.Copy(f, io.LimitReader(tr, hdr.Size))
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("extract %q: %w", hdr.Name, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "webcasa.db")); err != nil {
+		return fmt.Errorf("archive has no webcasa.db")
+	}
+	return nil
+}
+
+// checkBackupDB verifies that path is an intact panel database.
+func checkBackupDB(path string) error {
+	db, err := sql.Open("sqlite3", "file:"+path+"?mode=ro")
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	var result string
+	if err := db.QueryRow("PRAGMA quick_check").Scan(&result); err != nil {
+		return fmt.Errorf("database check: %w", err)
+	}
+	if result != "ok" {
+		return fmt.Errorf("database check: %s", result)
+	}
+	var n int
+	if err := db.QueryRow("SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name = 'hosts'").Scan(&n); err != nil || n != 1 {
+		return fmt.Errorf("not a Web.Casa database")
+	}
+	return nil
+}
+
+// restoreDB copies src over the live database with SQLite's online backup
+// API, so every open connection sees the restored data at once.
+func (s *BackupService) restoreDB(src string) error {
+	srcDB, err := sql.Open("sqlite3", "file:"+src+"?mode=ro")
+	if err != nil {
+		return fmt.Errorf("failed to open backup database: %w", err)
+	}
+	defer srcDB.Close()
+
+	ctx := context.Background()
+	srcConn, err := srcDB.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to open backup database: %w", err)
+	}
+	defer srcConn.Close()
+
+	liveDB, err := s.db.DB()
+	if err != nil {
+		return fmt.Errorf("failed to access database: %w", err)
+	}
+	dstConn, err := liveDB.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to access database: %w", err)
+	}
+	defer dstConn.Close()
+
+	err = dstConn.Raw(func(dst any) error {
+		return srcConn.Raw(func(src any) error {
+			dstSQLite, ok1 := dst.(*sqlite3.SQLiteConn)
+			srcSQLite, ok2 := src.(*sqlite3.SQLiteConn)
+			if !ok1 || !ok2 {
+				return fmt.Errorf("database driver does not support online restore")
+			}
+			b, err := dstSQLite.Backup("main", srcSQLite, "main")
+			if err != nil {
+				return err
+			}
+			if _, err := b.Step(-1); err != nil {
+				b.Finish()
+				return err
+			}
+			return b.Finish()
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to restore database: %w", err)
+	}
+	return nil
+}
+
+// restoreCerts swaps DataDir/certs for the extracted certificate tree,
+// putting the old tree back if the swap fails.
+func (s *BackupService) restoreCerts(extracted string) error {
+	certDir := filepath.Join(s.cfg.DataDir, "certs")
+	old := certDir + ".before-restore"
+	os.RemoveAll(old)
+
+	hadCerts := false
+	if _, err := os.Stat(certDir); err == nil {
+		if err := os.Rename(certDir, old); err != nil {
+			return fmt.Errorf("failed to restore certificates: %w", err)
+		}
+		hadCerts = true
+	}
+	if err := moveDir(extracted, certDir); err != nil {
+		if hadCerts {
+			os.RemoveAll(certDir)
+			os.Rename(old, certDir)
+		}
+		return fmt.Errorf("failed to restore certificates: %w", err)
+	}
+	os.RemoveAll(old)
+	return nil
+}
+
+// moveDir renames src to dst, copying when they are on different
+// filesystems (BackupDir may live on another mount than DataDir).
+func moveDir(src, dst string) error {
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+	return filepath.WalkDir(src, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if d.IsDir() {
+			return os.MkdirAll(target, 0700)
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(target, data, 0600)
+	})
+}
+
+// StartSchedule creates a backup every cfg.BackupIntervalHours. It does
+// nothing when the interval is 0.
+func (s *BackupService) StartSchedule() {
+	if s.cfg.BackupIntervalHours <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(time.Duration(s.cfg.BackupIntervalHours) * time.Hour)
+		defer ticker.Stop()
+		for range ticker.C {
+			if b, err := s.Create(); err != nil {
+				log.Printf("Warning: scheduled backup failed: %v", err)
+			} else {
+				log.Printf("Scheduled backup written: %s", b.ID)
+			}
+		}
+	}()
+}
@@ -0,0 +1,253 @@
+package service
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"database/sql"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/web-casa/webcasa/internal/config"
+	"github.com/web-casa/webcasa/internal/model"
+)
+
+func setupBackupService(t *testing.T, keep int) *BackupService {
+	t.Helper()
+	db := setupTestDB(t)
+	dataDir := t.TempDir()
+	cfg := &config.Config{
+		DataDir:       dataDir,
+		CaddyfilePath: filepath.Join(dataDir, "Caddyfile"),
+		BackupDir:     filepath.Join(dataDir, "backups"),
+		BackupKeep:    keep,
+	}
+	os.WriteFile(cfg.CaddyfilePath, []byte("shop.example.com {\n}\n"), 0600)
+	os.MkdirAll(filepath.Join(dataDir, "certs", "shop.example.com"), 0700)
+	os.WriteFile(filepath.Join(dataDir, "certs", "shop.example.com", "cert.pem"), []byte("CERT"), 0600)
+	db.Create(&model.Host{Domain: "shop.example.com"})
+	return NewBackupService(db, cfg)
+}
+
+// readArchive returns the archive's files keyed by name.
+func readArchive(t *testing.T, path string) map[string][]byte {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string][]byte{}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(tr)
+		files[hdr.Name] = data
+	}
+	return files
+}
+
+func TestBackup_ArchiveContents(t *testing.T) {
+	svc := setupBackupService(t, 5)
+
+	backup, err := svc.Create()
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if !backupNameRE.MatchString(backup.ID) || backup.Size == 0 {
+		t.Errorf("backup = %+v", backup)
+	}
+	path, err := svc.Path(backup.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := readArchive(t, path)
+	if string(files["Caddyfile"]) != "shop.example.com {\n}\n" {
+		t.Errorf("Caddyfile = %q", files["Caddyfile"])
+	}
+	if string(files["certs/shop.example.com/cert.pem"]) != "CERT" {
+		t.Errorf("certificate missing from archive: %v", files)
+	}
+	if db := files["webcasa.db"]; len(db) < 16 || string(db[:15]) != "SQLite format 3" {
+		t.Errorf("webcasa.db is not a SQLite database (%d bytes)", len(db))
+	}
+
+	entries, _ := os.ReadDir(svc.cfg.BackupDir)
+	if len(entries) != 1 {
+		t.Errorf("backup dir should only hold the archive, got %d entries", len(entries))
+	}
+}
+
+func TestBackup_PrunesOldest(t *testing.T) {
+	svc := setupBackupService(t, 2)
+	// Files that are not ours are never pruned.
+	os.MkdirAll(svc.cfg.BackupDir, 0700)
+	os.WriteFile(filepath.Join(svc.cfg.BackupDir, "Caddyfile.20240101-000000.bak"), []byte("x"), 0600)
+
+	var ids []string
+	for i := 0; i < 4; i++ {
+		b, err := svc.Create()
+		if err != nil {
+			t.Fatalf("Create %d: %v", i, err)
+		}
+		ids = append(ids, b.ID)
+	}
+
+	list, err := svc.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 || list[0].ID != ids[3] || list[1].ID != ids[2] {
+		t.Errorf("list = %+v, want the 2 newest of %v", list, ids)
+	}
+	if _, err := svc.Path(ids[0]); err == nil {
+		t.Error("oldest backup should have been pruned")
+	}
+	if _, err := os.Stat(filepath.Join(svc.cfg.BackupDir, "Caddyfile.20240101-000000.bak")); err != nil {
+		t.Error("unrelated files must be kept")
+	}
+}
+
+func TestBackup_PathRejectsTraversal(t *testing.T) {
+	svc := setupBackupService(t, 2)
+	for _, id := range []string{"../webcasa.db", "Caddyfile", "webcasa-backup-20240101-000000.tar.gz"} {
+		if _, err := svc.Path(id); err == nil || err.Error() != "error.backup_not_found" {
+			t.Errorf("Path(%q) err = %v", id, err)
+		}
+	}
+}
+
+func TestBackup_RestoreRoundTrip(t *testing.T) {
+	svc := setupBackupService(t, 5)
+	backup, err := svc.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Diverge from the backup: new host, Caddyfile and certificates.
+	svc.db.Where("domain = ?", "shop.example.com").Delete(&model.Host{})
+	svc.db.Create(&model.Host{Domain: "new.example.com"})
+	os.WriteFile(svc.cfg.CaddyfilePath, []byte("new.example.com {\n}\n"), 0600)
+	os.RemoveAll(filepath.Join(svc.cfg.DataDir, "certs"))
+	os.MkdirAll(filepath.Join(svc.cfg.DataDir, "certs", "new.example.com"), 0700)
+
+	path, _ := svc.Path(backup.ID)
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := svc.Restore(f); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+
+	var domains []string
+	svc.db.Model(&model.Host{}).Pluck("domain", &domains)
+	if len(domains) != 1 || domains[0] != "shop.example.com" {
+		t.Errorf("hosts after restore = %v, want [shop.example.com]", domains)
+	}
+	if data, _ := os.ReadFile(svc.cfg.CaddyfilePath); string(data) != "shop.example.com {\n}\n" {
+		t.Errorf("Caddyfile after restore = %q", data)
+	}
+	if data, _ := os.ReadFile(filepath.Join(svc.cfg.DataDir, "certs", "shop.example.com", "cert.pem")); string(data) != "CERT" {
+		t.Errorf("certificate after restore = %q", data)
+	}
+	if _, err := os.Stat(filepath.Join(svc.cfg.DataDir, "certs", "new.example.com")); !os.IsNotExist(err) {
+		t.Error("certificates added after the backup should be replaced")
+	}
+}
+
+func TestBackup_RestoreMigratesOlderDatabase(t *testing.T) {
+	svc := setupBackupService(t, 5)
+
+	// A database from a version whose hosts table had only a domain.
+	old := filepath.Join(t.TempDir(), "old.db")
+	oldDB, err := sql.Open("sqlite3", old)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := oldDB.Exec(`CREATE TABLE hosts (id integer PRIMARY KEY AUTOINCREMENT, domain text);
+		INSERT INTO hosts (domain) VALUES ('old.example.com');`); err != nil {
+		t.Fatal(err)
+	}
+	oldDB.Close()
+	data, err := os.ReadFile(old)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := svc.Restore(buildArchive(t, map[string]string{"webcasa.db": string(data)})); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	if !svc.db.Migrator().HasColumn(&model.Host{}, "tls_mode") || !svc.db.Migrator().HasTable(&model.Template{}) {
+		t.Fatal("restored database was not migrated to the current schema")
+	}
+	var host model.Host
+	if err := svc.db.Where("domain = ?", "old.example.com").First(&host).Error; err != nil {
+		t.Fatalf("restored host not readable: %v", err)
+	}
+}
+
+// buildArchive writes a tar.gz with the given files.
+func buildArchive(t *testing.T, files map[string]string) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, body := range files {
+		tw.WriteHeader(&tar.Header{Name: name, Mode: 0600, Size: int64(len(body)), Typeflag: tar.TypeReg})
+		tw.Write([]byte(body))
+	}
+	tw.Close()
+	gz.Close()
+	return &buf
+}
+
+func TestBackup_RestoreRejectsMalformed(t *testing.T) {
+	svc := setupBackupService(t, 5)
+	tests := map[string]struct {
+		archive    io.Reader
+		notAllowed bool
+	}{
+		"not gzip":      {bytes.NewBufferString("definitely not an archive"), false},
+		"no database":   {buildArchive(t, map[string]string{"Caddyfile": "x"}), false},
+		"not sqlite":    {buildArchive(t, map[string]string{"webcasa.db": "garbage"}), false},
+		"path escape":   {buildArchive(t, map[string]string{"webcasa.db": "x", "certs/../../evil": "x"}), true},
+		"absolute path": {buildArchive(t, map[string]string{"/etc/cron.d/evil": "x"}), true},
+		"unknown file":  {buildArchive(t, map[string]string{"webcasa.db": "x", ".jwt_secret": "x"}), true},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := svc.Restore(tt.archive)
+			if !errors.Is(err, ErrInvalidBackupArchive) {
+				t.Errorf("err = %v, want ErrInvalidBackupArchive", err)
+			}
+			if errors.Is(err, ErrBackupEntryNotAllowed) != tt.notAllowed {
+				t.Errorf("err = %v, ErrBackupEntryNotAllowed want %v", err, tt.notAllowed)
+			}
+		})
+	}
+
+	var count int64
+	svc.db.Model(&model.Host{}).Where("domain = ?", "shop.example.com").Count(&count)
+	if count != 1 {
+		t.Error("a rejected archive must leave the database untouched")
+	}
+	if _, err := os.Stat(filepath.Join(svc.cfg.DataDir, "evil")); !os.IsNotExist(err) {
+		t.Error("nothing may be written outside the extraction directory")
+	}
+}
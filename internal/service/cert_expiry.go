@@ -0,0 +1,129 @@
+package service
+
+import (
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/web-casa/webcasa/internal/model"
+)
+
+const (
+	// CertExpiryWarning is how close to expiry a certificate is reported.
+	// Caddy renews managed certificates well before this, so a managed one
+	// reaching it means renewal keeps failing.
+	CertExpiryWarning = 14 * 24 * time.Hour
+	// certExpiryInterval is how often the checker looks for expiring
+	// certificates, and so how often each one is reported.
+	certExpiryInterval = 24 * time.Hour
+)
+
+// Expiring certificate sources.
+const (
+	CertSourceUploaded = "uploaded" // a certificate uploaded to the panel
+	CertSourceManaged  = "managed"  // a certificate Caddy obtained for a host
+)
+
+// ExpiringCert is a certificate close to or past its expiry.
+type ExpiringCert struct {
+	Source    string    `json:"source"`
+	CertID    uint      `json:"cert_id,omitempty"` // uploaded certificates
+	HostID    uint      `json:"host_id,omitempty"` // managed certificates
+	Name      string    `json:"name"`
+	Domains   string    `json:"domains"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// ExpiringCertificates returns the uploaded certificates, and the managed
+// certificates of enabled hosts, that expire before now+within, soonest
+// first.
+func (s *HostService) ExpiringCertificates(now time.Time, within time.Duration) ([]ExpiringCert, error) {
+	cutoff := now.Add(within)
+	expiring := []ExpiringCert{}
+
+	var certs []model.Certificate
+	if err := s.db.Where("expires_at IS NOT NULL AND expires_at <= ?", cutoff).Find(&certs).Error; err != nil {
+		return nil, err
+	}
+	for _, c := range certs {
+		expiring = append(expiring, ExpiringCert{
+			Source: CertSourceUploaded, CertID: c.ID, Name: c.Name, Domains: c.Domains, ExpiresAt: *c.ExpiresAt,
+		})
+	}
+
+	var hosts []model.Host
+	if err := s.db.Select("id", "domain", "tls_mode", "tls_enabled").
+		Where("enabled = ?", true).Find(&hosts).Error; err != nil {
+		return nil, err
+	}
+	for i := range hosts {
+		host := &hosts[i]
+		if !certManaged(host) {
+			continue
+		}
+		status, err := s.caddyMgr.ManagedCertificate(host.Domain)
+		if err != nil || status.NotAfter == nil || status.NotAfter.After(cutoff) {
+			continue
+		}
+		expiring = append(expiring, ExpiringCert{
+			Source: CertSourceManaged, HostID: host.ID, Name: host.Domain, Domains: host.Domain, ExpiresAt: *status.NotAfter,
+		})
+	}
+
+	sort.SliceStable(expiring, func(i, j int) bool { return expiring[i].ExpiresAt.Before(expiring[j].ExpiresAt) })
+	return expiring, nil
+}
+
+// CertExpiryChecker reports expiring certificates once at start and then
+// daily.
+type CertExpiryChecker struct {
+	svc        *HostService
+	onExpiring func(cert ExpiringCert)
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewCertExpiryChecker creates a checker for svc's certificates. onExpiring
+// is called for every certificate found within CertExpiryWarning of expiry.
+func NewCertExpiryChecker(svc *HostService, onExpiring func(cert ExpiringCert)) *CertExpiryChecker {
+	return &CertExpiryChecker{
+		svc:        svc,
+		onExpiring: onExpiring,
+		stop:       make(chan struct{}),
+	}
+}
+
+// Start runs the checks in the background.
+func (cc *CertExpiryChecker) Start() {
+	go func() {
+		cc.check()
+		ticker := time.NewTicker(certExpiryInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-cc.stop:
+				return
+			case <-ticker.C:
+				cc.check()
+			}
+		}
+	}()
+}
+
+// Stop ends the background checks.
+func (cc *CertExpiryChecker) Stop() {
+	cc.stopOnce.Do(func() { close(cc.stop) })
+}
+
+func (cc *CertExpiryChecker) check() {
+	certs, err := cc.svc.ExpiringCertificates(time.Now(), CertExpiryWarning)
+	if err != nil {
+		log.Printf("Warning: failed to check certificate expiry: %v", err)
+		return
+	}
+	for _, cert := range certs {
+		cc.onExpiring(cert)
+	}
+}
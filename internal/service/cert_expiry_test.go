@@ -0,0 +1,42 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/web-casa/webcasa/internal/model"
+)
+
+func TestExpiringCertificates(t *testing.T) {
+	db := setupTestDB(t)
+	svc := setupTestHostService(t, db)
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	at := func(d time.Duration) *time.Time { tm := now.Add(d); return &tm }
+	for _, c := range []model.Certificate{
+		{Name: "soon", Domains: "a.example.com", ExpiresAt: at(10 * 24 * time.Hour)},
+		{Name: "expired", Domains: "b.example.com", ExpiresAt: at(-24 * time.Hour)},
+		{Name: "later", Domains: "c.example.com", ExpiresAt: at(60 * 24 * time.Hour)},
+		{Name: "unknown", Domains: "d.example.com"},
+	} {
+		if err := db.Create(&c).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A managed host without an issued certificate is not reported.
+	createTestHost(t, svc, "e.example.com", 1, 0, 0, 0, 0)
+
+	certs, err := svc.ExpiringCertificates(now, CertExpiryWarning)
+	if err != nil {
+		t.Fatalf("ExpiringCertificates: %v", err)
+	}
+	if len(certs) != 2 {
+		t.Fatalf("expected 2 expiring certificates, got %+v", certs)
+	}
+	if certs[0].Name != "expired" || certs[1].Name != "soon" {
+		t.Fatalf("expected expired then soon, got %s then %s", certs[0].Name, certs[1].Name)
+	}
+	if certs[1].Source != CertSourceUploaded || certs[1].CertID == 0 || certs[1].Domains != "a.example.com" {
+		t.Fatalf("unexpected entry %+v", certs[1])
+	}
+}
@@ -0,0 +1,383 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/web-casa/webcasa/internal/crypto"
+	"github.com/web-casa/webcasa/internal/model"
+	"gorm.io/gorm"
+)
+
+// secretSettings are the settings holding a secret, stored encrypted with
+// the panel's own key.
+var secretSettings = map[string]bool{"smtp_password": true}
+
+// exportMigrations upgrade an export one version: exportMigrations[v] turns
+// a version v document into version v+1.
+var exportMigrations = map[int]func(doc map[string]json.RawMessage) error{
+	// Version 1 is the hosts-only export; its hosts carry over unchanged
+	// and every other section stays as it is on import.
+	1: func(doc map[string]json.RawMessage) error {
+		doc["secrets"] = json.RawMessage(`"` + model.SecretsExcluded + `"`)
+		return nil
+	},
+}
+
+// ConfigService exports and imports the full panel configuration.
+type ConfigService struct {
+	db     *gorm.DB
+	hosts  *HostService
+	secret string // key the panel encrypts stored secrets with
+}
+
+// NewConfigService creates a new ConfigService
+func NewConfigService(db *gorm.DB, hosts *HostService, jwtSecret string) *ConfigService {
+	return &ConfigService{db: db, hosts: hosts, secret: jwtSecret}
+}
+
+// Export returns the panel configuration with secrets stored as secrets
+// says (one of the model.Secrets* modes); passphrase encrypts them for
+// model.SecretsEncrypted.
+func (s *ConfigService) Export(secrets, passphrase string) (*model.ConfigExport, error) {
+	if secrets == "" {
+		secrets = model.SecretsExcluded
+	}
+	switch secrets {
+	case model.SecretsIncluded, model.SecretsExcluded:
+	case model.SecretsEncrypted:
+		if passphrase == "" {
+			return nil, fmt.Errorf("error.passphrase_required")
+		}
+	default:
+		return nil, fmt.Errorf("error.invalid_secrets_mode")
+	}
+	// seal turns a plain-text secret into its exported form.
+	seal := func(plain string) (string, error) {
+		switch secrets {
+		case model.SecretsIncluded:
+			return plain, nil
+		case model.SecretsEncrypted:
+			return crypto.Encrypt(plain, passphrase)
+		default:
+			return "", nil
+		}
+	}
+
+	data := &model.ConfigExport{
+		Version:    model.ConfigExportVersion,
+		ExportedAt: time.Now().Format(time.RFC3339),
+		Secrets:    secrets,
+	}
+
+	// Plugin settings (plugin.<id>.*) belong to the plugins, not the panel.
+	var settings []model.Setting
+	if err := s.db.Where("key NOT LIKE ?", "plugin.%").Order("key").Find(&settings).Error; err != nil {
+		return nil, err
+	}
+	data.Settings = make([]model.Setting, 0, len(settings))
+	for _, st := range settings {
+		if secretSettings[st.Key] {
+			if secrets == model.SecretsExcluded || st.Value == "" {
+				continue
+			}
+			plain, err := crypto.Decrypt(st.Value, s.secret)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decrypt %s: %w", st.Key, err)
+			}
+			if st.Value, err = seal(plain); err != nil {
+				return nil, err
+			}
+		}
+		data.Settings = append(data.Settings, st)
+	}
+
+	if err := s.db.Order("id").Find(&data.Groups).Error; err != nil {
+		return nil, err
+	}
+	if err := s.db.Order("id").Find(&data.Tags).Error; err != nil {
+		return nil, err
+	}
+	tagNames := make(map[uint]string, len(data.Tags))
+	for _, tag := range data.Tags {
+		tagNames[tag.ID] = tag.Name
+	}
+	var rules []model.TagRule
+	if err := s.db.Order("id").Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	data.TagRules = make([]model.ExportTagRule, 0, len(rules))
+	for _, r := range rules {
+		if name, ok := tagNames[r.TagID]; ok {
+			data.TagRules = append(data.TagRules, model.ExportTagRule{Tag: name, MatchField: r.MatchField, Pattern: r.Pattern})
+		}
+	}
+	if err := s.db.Where("type <> ?", "preset").Order("id").Find(&data.Templates).Error; err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Order("id").Find(&data.DnsProviders).Error; err != nil {
+		return nil, err
+	}
+	for i := range data.DnsProviders {
+		p := &data.DnsProviders[i]
+		plain, err := decryptDnsConfig(p.Config, s.secret)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt DNS provider %s: %w", p.Name, err)
+		}
+		if p.Config, err = seal(plain); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.db.Order("id").Find(&data.Certificates).Error; err != nil {
+		return nil, err
+	}
+	hosts, err := s.hosts.List()
+	if err != nil {
+		return nil, err
+	}
+	data.Hosts = hosts
+	return data, nil
+}
+
+// ParseExport decodes an export of any supported version and upgrades it
+// to the current one.
+func ParseExport(raw []byte) (*model.ConfigExport, error) {
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, fmt.Errorf("error.invalid_export")
+	}
+	version, err := exportVersion(doc["version"])
+	if err != nil {
+		return nil, err
+	}
+	for ; version < model.ConfigExportVersion; version++ {
+		if err := exportMigrations[version](doc); err != nil {
+			return nil, err
+		}
+		doc["version"] = json.RawMessage(fmt.Sprint(version + 1))
+	}
+
+	upgraded, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	var data model.ConfigExport
+	if err := json.Unmarshal(upgraded, &data); err != nil {
+		return nil, fmt.Errorf("error.invalid_export")
+	}
+	return &data, nil
+}
+
+// exportVersion reads an export's version: a number, or the "1.0" string
+// of the hosts-only export.
+func exportVersion(raw json.RawMessage) (int, error) {
+	var version int
+	if err := json.Unmarshal(raw, &version); err != nil {
+		var legacy string
+		if json.Unmarshal(raw, &legacy) != nil || !strings.HasPrefix(legacy, "1.") {
+			return 0, fmt.Errorf("error.invalid_export")
+		}
+		version = 1
+	}
+	if version < 1 || version > model.ConfigExportVersion {
+		return 0, fmt.Errorf("error.unsupported_export_version")
+	}
+	return version, nil
+}
+
+// Import restores data, an export parsed by ParseExport. Sections are
+// restored in dependency order (settings, groups, tags, templates, DNS
+// providers, certificates, then hosts) in one transaction, after which the
+// Caddy config is applied. passphrase decrypts the secrets of an encrypted
+// export. Secrets missing from an export keep their current values where a
+// setting or same-named DNS provider has one.
+func (s *ConfigService) Import(data *model.ConfigExport, passphrase string) error {
+	if data.Hosts == nil && (data.Groups != nil || data.Tags != nil || data.DnsProviders != nil || data.Certificates != nil) {
+		// Hosts refer to these sections; replacing them alone would leave
+		// the hosts' references dangling.
+		return fmt.Errorf("error.export_incomplete")
+	}
+	// open turns an exported secret back into plain text.
+	open := func(sealed string) (string, error) {
+		if data.Secrets != model.SecretsEncrypted || sealed == "" {
+			return sealed, nil
+		}
+		if passphrase == "" {
+			return "", fmt.Errorf("error.passphrase_required")
+		}
+		plain, err := crypto.Decrypt(sealed, passphrase)
+		if err != nil {
+			return "", fmt.Errorf("error.invalid_passphrase")
+		}
+		return plain, nil
+	}
+
+	if err := validateImportHosts(data.Hosts); err != nil {
+		return err
+	}
+	// Decrypt and re-encrypt every secret before changing anything.
+	for i := range data.Settings {
+		st := &data.Settings[i]
+		if !secretSettings[st.Key] {
+			continue
+		}
+		plain, err := open(st.Value)
+		if err != nil {
+			return err
+		}
+		if st.Value, err = crypto.Encrypt(plain, s.secret); err != nil {
+			return err
+		}
+	}
+	for i := range data.DnsProviders {
+		plain, err := open(data.DnsProviders[i].Config)
+		if err != nil {
+			return err
+		}
+		data.DnsProviders[i].Config = plain
+	}
+
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		for _, st := range data.Settings {
+			if strings.HasPrefix(st.Key, "plugin.") {
+				continue
+			}
+			if err := tx.Save(&model.Setting{Key: st.Key, Value: st.Value}).Error; err != nil {
+				return fmt.Errorf("failed to import setting %s: %w", st.Key, err)
+			}
+		}
+
+		if data.Hosts != nil {
+			// The hosts are replaced last; until then they must not hold on
+			// to the rows replaced before them.
+			if err := tx.Exec("UPDATE hosts SET group_id = NULL, dns_provider_id = NULL, certificate_id = NULL").Error; err != nil {
+				return err
+			}
+		}
+
+		refs := &importRefs{}
+		if data.Groups != nil {
+			if err := deleteAll(tx, &model.Group{}); err != nil {
+				return err
+			}
+			refs.groups = make(map[uint]uint, len(data.Groups))
+			for _, g := range data.Groups {
+				oldID := g.ID
+				g.ID = 0
+				if err := tx.Create(&g).Error; err != nil {
+					return fmt.Errorf("failed to import group %s: %w", g.Name, err)
+				}
+				refs.groups[oldID] = g.ID
+			}
+		}
+
+		if data.Tags != nil {
+			if err := deleteAll(tx, &model.HostTag{}, &model.TagRule{}, &model.Tag{}); err != nil {
+				return err
+			}
+			tagIDs := make(map[string]uint, len(data.Tags))
+			for _, tag := range data.Tags {
+				tag.ID = 0
+				if err := tx.Create(&tag).Error; err != nil {
+					return fmt.Errorf("failed to import tag %s: %w", tag.Name, err)
+				}
+				tagIDs[tag.Name] = tag.ID
+			}
+			for _, r := range data.TagRules {
+				tagID, ok := tagIDs[r.Tag]
+				if !ok {
+					continue
+				}
+				if err := tx.Create(&model.TagRule{TagID: tagID, MatchField: r.MatchField, Pattern: r.Pattern}).Error; err != nil {
+					return fmt.Errorf("failed to import rule for tag %s: %w", r.Tag, err)
+				}
+			}
+		}
+
+		if data.Templates != nil {
+			if err := tx.Where("type <> ?", "preset").Delete(&model.Template{}).Error; err != nil {
+				return err
+			}
+			for _, tpl := range data.Templates {
+				tpl.ID = 0
+				if err := tx.Create(&tpl).Error; err != nil {
+					return fmt.Errorf("failed to import template %s: %w", tpl.Name, err)
+				}
+			}
+		}
+
+		if data.DnsProviders != nil {
+			var current []model.DnsProvider
+			if err := tx.Find(&current).Error; err != nil {
+				return err
+			}
+			kept := make(map[string]string, len(current))
+			for _, p := range current {
+				kept[p.Provider+"/"+p.Name] = p.Config
+			}
+			if err := deleteAll(tx, &model.DnsProvider{}); err != nil {
+				return err
+			}
+			refs.dnsProviders = make(map[uint]uint, len(data.DnsProviders))
+			for _, p := range data.DnsProviders {
+				oldID := p.ID
+				p.ID = 0
+				stored := kept[p.Provider+"/"+p.Name]
+				if p.Config != "" || stored == "" {
+					plain := p.Config
+					if plain == "" {
+						plain = "{}"
+					}
+					var err error
+					if stored, err = crypto.Encrypt(plain, s.secret); err != nil {
+						return fmt.Errorf("failed to encrypt DNS provider %s: %w", p.Name, err)
+					}
+				}
+				p.Config = stored
+				if err := tx.Create(&p).Error; err != nil {
+					return fmt.Errorf("failed to import DNS provider %s: %w", p.Name, err)
+				}
+				refs.dnsProviders[oldID] = p.ID
+			}
+		}
+
+		if data.Certificates != nil {
+			if err := deleteAll(tx, &model.Certificate{}); err != nil {
+				return err
+			}
+			refs.certificates = make(map[uint]uint, len(data.Certificates))
+			for _, c := range data.Certificates {
+				oldID := c.ID
+				c.ID = 0
+				if err := tx.Create(&c).Error; err != nil {
+					return fmt.Errorf("failed to import certificate %s: %w", c.Name, err)
+				}
+				refs.certificates[oldID] = c.ID
+			}
+		}
+
+		if data.Hosts != nil {
+			return replaceHosts(tx, data.Hosts, refs)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	_, err := s.hosts.ApplyConfig()
+	return err
+}
+
+// deleteAll deletes every row of each model's table.
+func deleteAll(tx *gorm.DB, models ...interface{}) error {
+	for _, m := range models {
+		if err := tx.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(m).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,198 @@
+package service
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/web-casa/webcasa/internal/crypto"
+	"github.com/web-casa/webcasa/internal/model"
+	"gorm.io/gorm"
+)
+
+const configTestSecret = "panel-secret"
+
+// seedConfig fills db with a group, two tags (one with a rule), a custom
+// template, a DNS provider, an SMTP password and a host using all of them.
+func seedConfig(t *testing.T, db *gorm.DB, svc *HostService) {
+	t.Helper()
+	group := model.Group{Name: "prod", Color: "#ff0000"}
+	web := model.Tag{Name: "web", Color: "#00ff00"}
+	api := model.Tag{Name: "api"}
+	for _, row := range []interface{}{&group, &web, &api} {
+		if err := db.Create(row).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	db.Create(&model.TagRule{TagID: web.ID, MatchField: "domain", Pattern: "*.example.com"})
+	db.Create(&model.Template{Name: "My proxy", Type: "custom", Config: "{}"})
+	db.Create(&model.Template{Name: "Preset", Type: "preset", Config: "{}"})
+	provider := model.DnsProvider{Name: "cf", Provider: "cloudflare"}
+	if err := NewDnsProviderService(db, configTestSecret).Save(&provider, `{"api_token":"cf-token-123"}`); err != nil {
+		t.Fatal(err)
+	}
+	password, _ := crypto.Encrypt("smtp-pass-456", configTestSecret)
+	db.Create(&model.Setting{Key: "smtp_password", Value: password})
+	db.Create(&model.Setting{Key: "server_ipv4", Value: "203.0.113.7"})
+	db.Create(&model.Setting{Key: "plugin.docker.token", Value: "plugin-secret"})
+
+	host := createTestHost(t, svc, "app.example.com", 1, 0, 0, 0, 0)
+	db.Model(host).Updates(map[string]interface{}{"group_id": group.ID, "dns_provider_id": provider.ID, "tls_mode": "dns"})
+	if err := db.Model(host).Association("Tags").Append(&web, &api); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// roundTrip exports src with secrets, marshals and parses the result, and
+// imports it into a fresh panel whose IDs are offset from src's.
+func roundTrip(t *testing.T, src *ConfigService, secrets, exportPass, importPass string) (*gorm.DB, string, error) {
+	t.Helper()
+	data, err := src.Export(secrets, exportPass)
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db := setupTestDB(t)
+	svc := setupTestHostService(t, db)
+	// Occupy the first IDs so restored rows get different ones.
+	db.Create(&model.Group{Name: "stale"})
+	db.Create(&model.Tag{Name: "stale"})
+	db.Create(&model.DnsProvider{Name: "stale", Provider: "route53", Config: "{}"})
+	createTestHost(t, svc, "stale.example.com", 1, 0, 0, 0, 0)
+
+	parsed, err := ParseExport(raw)
+	if err != nil {
+		t.Fatalf("ParseExport: %v", err)
+	}
+	return db, string(raw), NewConfigService(db, svc, configTestSecret).Import(parsed, importPass)
+}
+
+func TestConfigExport_RoundTripExcludingSecrets(t *testing.T) {
+	db := setupTestDB(t)
+	svc := setupTestHostService(t, db)
+	seedConfig(t, db, svc)
+
+	dst, raw, err := roundTrip(t, NewConfigService(db, svc, configTestSecret), "", "", "")
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	for _, secret := range []string{"cf-token-123", "smtp-pass-456", "smtp_password", "plugin-secret"} {
+		if strings.Contains(raw, secret) {
+			t.Errorf("export contains %q", secret)
+		}
+	}
+
+	var hosts []model.Host
+	dst.Preload("Group").Preload("Tags").Find(&hosts)
+	if len(hosts) != 1 || hosts[0].Domain != "app.example.com" {
+		t.Fatalf("expected only app.example.com, got %+v", hosts)
+	}
+	host := hosts[0]
+	if host.Group == nil || host.Group.Name != "prod" || host.Group.Color != "#ff0000" {
+		t.Fatalf("host group not preserved: %+v", host.Group)
+	}
+	var tags []string
+	for _, tag := range host.Tags {
+		tags = append(tags, tag.Name)
+	}
+	sort.Strings(tags)
+	if strings.Join(tags, ",") != "api,web" {
+		t.Fatalf("host tags not preserved: %v", tags)
+	}
+
+	var groups, staleTags int64
+	dst.Model(&model.Group{}).Count(&groups)
+	dst.Model(&model.Tag{}).Where("name = ?", "stale").Count(&staleTags)
+	if groups != 1 || staleTags != 0 {
+		t.Fatalf("expected groups and tags replaced, got %d groups and %d stale tags", groups, staleTags)
+	}
+	var rule model.TagRule
+	if err := dst.First(&rule).Error; err != nil || rule.Pattern != "*.example.com" {
+		t.Fatalf("tag rule not restored: %+v %v", rule, err)
+	}
+	var webTag model.Tag
+	dst.Where("name = ?", "web").First(&webTag)
+	if rule.TagID != webTag.ID {
+		t.Fatalf("tag rule points to tag %d, want %d", rule.TagID, webTag.ID)
+	}
+
+	var provider model.DnsProvider
+	if err := dst.First(&provider, host.DnsProviderID).Error; err != nil || provider.Name != "cf" {
+		t.Fatalf("host DNS provider not preserved: %+v %v", provider, err)
+	}
+	if plain, err := decryptDnsConfig(provider.Config, configTestSecret); err != nil || plain != "{}" {
+		t.Fatalf("expected excluded DNS credentials, got %q %v", plain, err)
+	}
+
+	var templates []model.Template
+	dst.Find(&templates)
+	if len(templates) != 1 || templates[0].Name != "My proxy" {
+		t.Fatalf("expected only the custom template, got %+v", templates)
+	}
+	var settings []model.Setting
+	dst.Find(&settings)
+	for _, st := range settings {
+		if st.Key == "smtp_password" || strings.HasPrefix(st.Key, "plugin.") {
+			t.Fatalf("unexpected setting %s imported", st.Key)
+		}
+	}
+	var ipv4 model.Setting
+	if dst.Where("key = ?", "server_ipv4").First(&ipv4).Error != nil || ipv4.Value != "203.0.113.7" {
+		t.Fatalf("server_ipv4 not restored: %+v", ipv4)
+	}
+}
+
+func TestConfigExport_EncryptedSecrets(t *testing.T) {
+	db := setupTestDB(t)
+	svc := setupTestHostService(t, db)
+	seedConfig(t, db, svc)
+	src := NewConfigService(db, svc, configTestSecret)
+
+	if _, _, err := roundTrip(t, src, model.SecretsEncrypted, "correct horse", "wrong"); err == nil || err.Error() != "error.invalid_passphrase" {
+		t.Fatalf("expected error.invalid_passphrase, got %v", err)
+	}
+
+	dst, raw, err := roundTrip(t, src, model.SecretsEncrypted, "correct horse", "correct horse")
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	if strings.Contains(raw, "cf-token-123") || strings.Contains(raw, "smtp-pass-456") {
+		t.Fatal("encrypted export contains a plain-text secret")
+	}
+	var provider model.DnsProvider
+	dst.Where("name = ?", "cf").First(&provider)
+	if plain, err := decryptDnsConfig(provider.Config, configTestSecret); err != nil || plain != `{"api_token":"cf-token-123"}` {
+		t.Fatalf("DNS credentials not restored: %q %v", plain, err)
+	}
+	var password model.Setting
+	dst.Where("key = ?", "smtp_password").First(&password)
+	if plain, err := crypto.Decrypt(password.Value, configTestSecret); err != nil || plain != "smtp-pass-456" {
+		t.Fatalf("SMTP password not restored: %q %v", plain, err)
+	}
+}
+
+func TestParseExport_Versions(t *testing.T) {
+	legacy, err := ParseExport([]byte(`{"version":"1.0","exported_at":"2025-01-01T00:00:00Z","hosts":[]}`))
+	if err != nil {
+		t.Fatalf("legacy export: %v", err)
+	}
+	if legacy.Version != model.ConfigExportVersion || legacy.Hosts == nil || legacy.Groups != nil || legacy.Settings != nil {
+		t.Fatalf("legacy export not upgraded to a hosts-only import: %+v", legacy)
+	}
+
+	for raw, want := range map[string]string{
+		`{"version":99,"hosts":[]}`: "error.unsupported_export_version",
+		`{"version":"2.0"}`:         "error.invalid_export",
+		`{"hosts":[]}`:              "error.invalid_export",
+		`not json`:                  "error.invalid_export",
+	} {
+		if _, err := ParseExport([]byte(raw)); err == nil || err.Error() != want {
+			t.Errorf("ParseExport(%s): got %v, want %s", raw, err, want)
+		}
+	}
+}
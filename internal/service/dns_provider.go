@@ -0,0 +1,177 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/web-casa/webcasa/internal/caddy"
+	"github.com/web-casa/webcasa/internal/crypto"
+	"github.com/web-casa/webcasa/internal/model"
+	"gorm.io/gorm"
+)
+
+// dnsConfigPublic are the DNS provider config fields that are not secret and
+// so are returned unmasked.
+var dnsConfigPublic = map[string]bool{"region": true}
+
+// DnsProviderService handles business logic for DNS providers. Provider
+// configs hold API credentials and are stored encrypted with the panel's key.
+type DnsProviderService struct {
+	db     *gorm.DB
+	secret string
+}
+
+// NewDnsProviderService creates a new DnsProviderService
+func NewDnsProviderService(db *gorm.DB, jwtSecret string) *DnsProviderService {
+	return &DnsProviderService{db: db, secret: jwtSecret}
+}
+
+// Default returns the default DNS provider, or error.no_default_dns_provider
+// when none is set.
+func (s *DnsProviderService) Default() (*model.DnsProvider, error) {
+	var p model.DnsProvider
+	if err := s.db.Where("is_default = ?", true).Order("id ASC").First(&p).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("error.no_default_dns_provider")
+		}
+		return nil, err
+	}
+	return &p, nil
+}
+
+// Save creates or updates p with config, a plain-text JSON config. Fields
+// of config holding a masked value, and an empty config, keep what p has
+// stored. When p is the default, every other provider stops being one in
+// the same transaction, so at most one default exists.
+func (s *DnsProviderService) Save(p *model.DnsProvider, config string) error {
+	if config != "" {
+		stored, err := s.mergeConfig(p.Config, config)
+		if err != nil {
+			return err
+		}
+		if p.Config, err = crypto.Encrypt(stored, s.secret); err != nil {
+			return fmt.Errorf("failed to encrypt DNS provider config: %w", err)
+		}
+	}
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if p.IsDefault != nil && *p.IsDefault {
+			if err := tx.Model(&model.DnsProvider{}).
+				Where("is_default = ? AND id <> ?", true, p.ID).
+				Update("is_default", false).Error; err != nil {
+				return fmt.Errorf("failed to unset default DNS provider: %w", err)
+			}
+		}
+		return tx.Save(p).Error
+	})
+}
+
+// mergeConfig returns config with its masked fields taken from the stored
+// config.
+func (s *DnsProviderService) mergeConfig(stored, config string) (string, error) {
+	var fields map[string]string
+	if err := json.Unmarshal([]byte(config), &fields); err != nil {
+		return "", fmt.Errorf("error.invalid_dns_config")
+	}
+	var current map[string]string
+	if plain, err := decryptDnsConfig(stored, s.secret); err == nil && plain != "" {
+		json.Unmarshal([]byte(plain), &current)
+	}
+	for k, v := range fields {
+		if strings.Contains(v, "****") {
+			fields[k] = current[k]
+		}
+	}
+	merged, err := json.Marshal(fields)
+	if err != nil {
+		return "", err
+	}
+	return string(merged), nil
+}
+
+// MaskedConfig returns p's config with its secret fields masked for
+// display.
+func (s *DnsProviderService) MaskedConfig(p *model.DnsProvider) string {
+	plain, err := decryptDnsConfig(p.Config, s.secret)
+	if err != nil {
+		return "{}"
+	}
+	return maskDnsConfig(plain)
+}
+
+// maskDnsConfig masks the secret fields of a plain-text JSON config.
+func maskDnsConfig(plain string) string {
+	var fields map[string]string
+	if json.Unmarshal([]byte(plain), &fields) != nil {
+		return "{}"
+	}
+	for k, v := range fields {
+		if v != "" && !dnsConfigPublic[k] {
+			fields[k] = crypto.MaskAPIKey(v)
+		}
+	}
+	masked, _ := json.Marshal(fields)
+	return string(masked)
+}
+
+// RenderPreview returns the tls block hosts using provider id get, with
+// the config's secrets masked. A config Caddy could not use (missing or
+// unsafe values) is error.dns_config_incomplete.
+func (s *DnsProviderService) RenderPreview(id uint) (string, error) {
+	var p model.DnsProvider
+	if err := s.db.First(&p, id).Error; err != nil {
+		return "", err
+	}
+	masked := s.MaskedConfig(&p)
+	plain, err := decryptDnsConfig(p.Config, s.secret)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt DNS provider %s: %w", p.Name, err)
+	}
+	// Render the real config to check it, then the masked one to show.
+	p.Config = plain
+	if caddy.RenderDnsTLS(p) == "" {
+		return "", fmt.Errorf("error.dns_config_incomplete")
+	}
+	p.Config = masked
+	return caddy.RenderDnsTLS(p), nil
+}
+
+// EncryptPlaintextConfigs encrypts the configs stored in plain text before
+// they were encrypted at rest, returning how many were migrated.
+func (s *DnsProviderService) EncryptPlaintextConfigs() (int, error) {
+	var providers []model.DnsProvider
+	if err := s.db.Find(&providers).Error; err != nil {
+		return 0, err
+	}
+	migrated := 0
+	for _, p := range providers {
+		if !plainDnsConfig(p.Config) {
+			continue
+		}
+		enc, err := crypto.Encrypt(p.Config, s.secret)
+		if err != nil {
+			return migrated, fmt.Errorf("failed to encrypt DNS provider %s: %w", p.Name, err)
+		}
+		if err := s.db.Model(&model.DnsProvider{}).Where("id = ?", p.ID).Update("config", enc).Error; err != nil {
+			return migrated, err
+		}
+		migrated++
+	}
+	return migrated, nil
+}
+
+// plainDnsConfig reports whether a stored config is plain-text JSON rather
+// than ciphertext, whose base64 never starts with a brace.
+func plainDnsConfig(stored string) bool {
+	return strings.HasPrefix(strings.TrimSpace(stored), "{")
+}
+
+// decryptDnsConfig returns the plain-text JSON of a stored config. Configs
+// not yet migrated to encryption are returned as they are.
+func decryptDnsConfig(stored, secret string) (string, error) {
+	if plainDnsConfig(stored) {
+		return stored, nil
+	}
+	return crypto.Decrypt(stored, secret)
+}
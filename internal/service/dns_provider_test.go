@@ -0,0 +1,176 @@
+package service
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/web-casa/webcasa/internal/model"
+)
+
+const dnsTestSecret = "dns-test-secret"
+
+func newTestDnsProvider(t *testing.T, svc *DnsProviderService, name, token string, isDefault bool) *model.DnsProvider {
+	t.Helper()
+	p := &model.DnsProvider{Name: name, Provider: "cloudflare", IsDefault: &isDefault}
+	if err := svc.Save(p, `{"api_token":"`+token+`"}`); err != nil {
+		t.Fatalf("Save %s: %v", name, err)
+	}
+	return p
+}
+
+func TestDnsProviderService_SecondDefaultUnsetsFirst(t *testing.T) {
+	svc := NewDnsProviderService(setupTestDB(t), dnsTestSecret)
+
+	if _, err := svc.Default(); err == nil || err.Error() != "error.no_default_dns_provider" {
+		t.Fatalf("expected error.no_default_dns_provider, got %v", err)
+	}
+
+	first := newTestDnsProvider(t, svc, "first", "tok-1", true)
+	second := newTestDnsProvider(t, svc, "second", "tok-2", false)
+	isDefault := true
+	second.IsDefault = &isDefault
+	if err := svc.Save(second, ""); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	var reloaded model.DnsProvider
+	svc.db.First(&reloaded, first.ID)
+	if reloaded.IsDefault == nil || *reloaded.IsDefault {
+		t.Fatal("first provider still default after marking the second")
+	}
+	def, err := svc.Default()
+	if err != nil || def.ID != second.ID {
+		t.Fatalf("expected provider %d as default, got %+v %v", second.ID, def, err)
+	}
+	var count int64
+	svc.db.Model(&model.DnsProvider{}).Where("is_default = ?", true).Count(&count)
+	if count != 1 {
+		t.Fatalf("expected one default provider, got %d", count)
+	}
+}
+
+func TestApplyConfig_DnsHostFallsBackToDefaultProvider(t *testing.T) {
+	db := setupTestDB(t)
+	svc := setupTestHostService(t, db)
+	providers := NewDnsProviderService(db, svc.cfg.JWTSecret)
+	newTestDnsProvider(t, providers, "other", "tok-other", false)
+	newTestDnsProvider(t, providers, "main", "tok-main", true)
+
+	host := createTestHost(t, svc, "dns.example.com", 1, 0, 0, 0, 0)
+	db.Model(host).Update("tls_mode", "dns")
+	if _, err := svc.ApplyConfig(); err != nil {
+		t.Fatalf("ApplyConfig: %v", err)
+	}
+
+	content, _ := os.ReadFile(svc.cfg.CaddyfilePath)
+	if !strings.Contains(string(content), "dns cloudflare tok-main") {
+		t.Fatalf("dns-mode host without a provider did not use the default:\n%s", content)
+	}
+}
+
+func TestDnsProviderService_ConfigEncryptedAtRest(t *testing.T) {
+	svc := NewDnsProviderService(setupTestDB(t), dnsTestSecret)
+	p := &model.DnsProvider{Name: "aws", Provider: "route53"}
+	config := `{"access_key_id":"AKIAEXAMPLE1234","region":"eu-west-1","secret_access_key":"secret-key-5678"}`
+	if err := svc.Save(p, config); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	var stored model.DnsProvider
+	svc.db.First(&stored, p.ID)
+	if strings.Contains(stored.Config, "secret-key-5678") || plainDnsConfig(stored.Config) {
+		t.Fatalf("config stored in plain text: %q", stored.Config)
+	}
+	if plain, err := decryptDnsConfig(stored.Config, dnsTestSecret); err != nil || plain != config {
+		t.Fatalf("round trip: got %q %v", plain, err)
+	}
+
+	masked := svc.MaskedConfig(&stored)
+	var fields map[string]string
+	if err := json.Unmarshal([]byte(masked), &fields); err != nil {
+		t.Fatalf("masked config is not JSON: %q", masked)
+	}
+	if fields["secret_access_key"] != "secr****5678" || fields["access_key_id"] != "AKIA****1234" || fields["region"] != "eu-west-1" {
+		t.Fatalf("unexpected masked config: %v", fields)
+	}
+
+	// Saving the masked config back, with a new region, keeps the secrets.
+	fields["region"] = "us-west-2"
+	edited, _ := json.Marshal(fields)
+	if err := svc.Save(&stored, string(edited)); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	want := `{"access_key_id":"AKIAEXAMPLE1234","region":"us-west-2","secret_access_key":"secret-key-5678"}`
+	if plain, _ := decryptDnsConfig(stored.Config, dnsTestSecret); plain != want {
+		t.Fatalf("masked fields not kept: got %q", plain)
+	}
+
+	if err := svc.Save(&stored, "not json"); err == nil || err.Error() != "error.invalid_dns_config" {
+		t.Fatalf("expected error.invalid_dns_config, got %v", err)
+	}
+}
+
+func TestDnsProviderService_EncryptPlaintextConfigs(t *testing.T) {
+	db := setupTestDB(t)
+	svc := NewDnsProviderService(db, dnsTestSecret)
+	legacy := model.DnsProvider{Name: "legacy", Provider: "cloudflare", Config: `{"api_token":"legacy-token"}`}
+	db.Create(&legacy)
+	newTestDnsProvider(t, svc, "current", "current-token", false)
+
+	n, err := svc.EncryptPlaintextConfigs()
+	if err != nil || n != 1 {
+		t.Fatalf("expected 1 config migrated, got %d %v", n, err)
+	}
+	var stored model.DnsProvider
+	db.First(&stored, legacy.ID)
+	if plainDnsConfig(stored.Config) {
+		t.Fatalf("legacy config still plain text: %q", stored.Config)
+	}
+	if plain, err := decryptDnsConfig(stored.Config, dnsTestSecret); err != nil || plain != `{"api_token":"legacy-token"}` {
+		t.Fatalf("migrated config: got %q %v", plain, err)
+	}
+
+	if n, err := svc.EncryptPlaintextConfigs(); err != nil || n != 0 {
+		t.Fatalf("expected nothing left to migrate, got %d %v", n, err)
+	}
+}
+
+func TestDnsProviderService_RenderPreview(t *testing.T) {
+	svc := NewDnsProviderService(setupTestDB(t), dnsTestSecret)
+	for _, tc := range []struct {
+		provider, config, want string
+	}{
+		{
+			provider: "cloudflare",
+			config:   `{"api_token":"cf-token-abcd1234"}`,
+			want:     "\ttls {\n\t\tdns cloudflare cf-t****1234\n\t}\n",
+		},
+		{
+			provider: "route53",
+			config:   `{"access_key_id":"AKIAEXAMPLE1234","region":"eu-west-1","secret_access_key":"secret-key-5678"}`,
+			want:     "\ttls {\n\t\tdns route53 {\n\t\t\tregion eu-west-1\n\t\t\taccess_key_id AKIA****1234\n\t\t\tsecret_access_key secr****5678\n\t\t}\n\t}\n",
+		},
+	} {
+		p := &model.DnsProvider{Name: tc.provider, Provider: tc.provider}
+		if err := svc.Save(p, tc.config); err != nil {
+			t.Fatalf("Save %s: %v", tc.provider, err)
+		}
+		block, err := svc.RenderPreview(p.ID)
+		if err != nil {
+			t.Fatalf("RenderPreview %s: %v", tc.provider, err)
+		}
+		if block != tc.want {
+			t.Errorf("%s preview:\ngot  %q\nwant %q", tc.provider, block, tc.want)
+		}
+	}
+
+	incomplete := &model.DnsProvider{Name: "empty", Provider: "route53"}
+	if err := svc.Save(incomplete, `{"region":"eu-west-1"}`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.RenderPreview(incomplete.ID); err == nil || err.Error() != "error.dns_config_incomplete" {
+		t.Fatalf("expected error.dns_config_incomplete, got %v", err)
+	}
+}
@@ -0,0 +1,214 @@
+package service
+
+import (
+	"crypto/rand"
+	"fmt"
+	"log"
+	"math/big"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/web-casa/webcasa/internal/crypto"
+	"github.com/web-casa/webcasa/internal/model"
+	"github.com/web-casa/webcasa/internal/notify"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// Second-factor methods stored in User.TwoFactorMethod.
+const (
+	TwoFactorTOTP  = "totp"
+	TwoFactorEmail = "email"
+)
+
+// emailOTPTTL is how long an emailed login code stays valid.
+const emailOTPTTL = 5 * time.Minute
+
+// Mailer delivers a plain-text message to a single recipient.
+type Mailer interface {
+	Send(to, subject, body string) error
+}
+
+// SMTPMailer sends mail through the SMTP server configured in panel settings
+// (smtp_host, smtp_port, smtp_username, smtp_password, smtp_from,
+// smtp_use_tls). The password setting is stored encrypted.
+type SMTPMailer struct {
+	db     *gorm.DB
+	secret string
+}
+
+// NewSMTPMailer creates a new SMTPMailer
+func NewSMTPMailer(db *gorm.DB, jwtSecret string) *SMTPMailer {
+	return &SMTPMailer{db: db, secret: jwtSecret}
+}
+
+// Send reads the SMTP settings and delivers the message.
+func (m *SMTPMailer) Send(to, subject, body string) error {
+	var rows []model.Setting
+	m.db.Where("key LIKE ?", "smtp_%").Find(&rows)
+	settings := make(map[string]string, len(rows))
+	for _, r := range rows {
+		settings[r.Key] = r.Value
+	}
+	if settings["smtp_host"] == "" {
+		return fmt.Errorf("error.smtp_not_configured")
+	}
+
+	port, err := strconv.Atoi(settings["smtp_port"])
+	if err != nil || port <= 0 {
+		port = 587
+	}
+	password, err := crypto.Decrypt(settings["smtp_password"], m.secret)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt SMTP password: %w", err)
+	}
+
+	return notify.SendMail(notify.EmailConfig{
+		SMTPHost: settings["smtp_host"],
+		SMTPPort: port,
+		Username: settings["smtp_username"],
+		Password: password,
+		From:     settings["smtp_from"],
+		To:       to,
+		UseTLS:   settings["smtp_use_tls"] == "true",
+	}, subject, body)
+}
+
+// generateEmailCode returns a random 6-digit code.
+func generateEmailCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
+
+// validEmailAddress accepts a bare address (no display name), which also
+// rules out header injection through the To line.
+func validEmailAddress(email string) bool {
+	addr, err := mail.ParseAddress(email)
+	return err == nil && addr.Address == email
+}
+
+// issueEmailCode generates a new code for user, stores its hash with a short
+// expiry (replacing any outstanding code) and emails it.
+func (s *TOTPService) issueEmailCode(user *model.User) error {
+	if user.Email == "" {
+		return fmt.Errorf("error.email_not_set")
+	}
+	code, err := generateEmailCode()
+	if err != nil {
+		return fmt.Errorf("failed to generate email code: %w", err)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(code), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("failed to hash email code: %w", err)
+	}
+
+	if err := s.db.Model(&model.User{}).Where("id = ?", user.ID).Updates(map[string]interface{}{
+		"email_otp_hash":    string(hash),
+		"email_otp_expires": time.Now().Add(emailOTPTTL),
+	}).Error; err != nil {
+		return fmt.Errorf("failed to store email code: %w", err)
+	}
+
+	body := fmt.Sprintf("Your Web.Casa verification code is: %s\n\nIt expires in %d minutes. If you did not try to sign in, change your password.",
+		code, int(emailOTPTTL.Minutes()))
+	if err := s.mailer.Send(user.Email, "[Web.Casa] Verification code", body); err != nil {
+		log.Printf("Warning: failed to send 2FA email to user %d: %v", user.ID, err)
+		return fmt.Errorf("error.email_send_failed")
+	}
+	return nil
+}
+
+// consumeEmailCode checks code against the user's outstanding email code. A
+// matching, unexpired code is cleared so it cannot be used again.
+func (s *TOTPService) consumeEmailCode(user *model.User, code string) (bool, error) {
+	if user.EmailOTPHash == "" || time.Now().After(user.EmailOTPExpires) {
+		return false, nil
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.EmailOTPHash), []byte(strings.TrimSpace(code))) != nil {
+		return false, nil
+	}
+
+	// Clear only the hash we matched, so two concurrent requests with the
+	// same code cannot both succeed.
+	res := s.db.Model(&model.User{}).
+		Where("id = ? AND email_otp_hash = ?", user.ID, user.EmailOTPHash).
+		Update("email_otp_hash", "")
+	if res.Error != nil {
+		return false, fmt.Errorf("failed to consume email code: %w", res.Error)
+	}
+	user.EmailOTPHash = ""
+	return res.RowsAffected == 1, nil
+}
+
+// SetupEmail records the address for email 2FA and sends it a code. 2FA is
+// enabled once that code is confirmed with VerifyEmailAndEnable.
+func (s *TOTPService) SetupEmail(userID uint, email string) error {
+	var user model.User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		return fmt.Errorf("error.user_not_found")
+	}
+	if user.TOTPEnabled != nil && *user.TOTPEnabled {
+		return fmt.Errorf("error.2fa_already_enabled")
+	}
+
+	email = strings.TrimSpace(email)
+	if !validEmailAddress(email) {
+		return fmt.Errorf("error.invalid_email")
+	}
+	if err := s.db.Model(&user).Update("email", email).Error; err != nil {
+		return fmt.Errorf("failed to save email: %w", err)
+	}
+	user.Email = email
+	return s.issueEmailCode(&user)
+}
+
+// VerifyEmailAndEnable confirms the code sent by SetupEmail and enables email
+// 2FA. Returns the 8 plaintext recovery codes on success.
+func (s *TOTPService) VerifyEmailAndEnable(userID uint, code string) ([]string, error) {
+	var user model.User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		return nil, fmt.Errorf("error.user_not_found")
+	}
+	if user.Email == "" {
+		return nil, fmt.Errorf("error.2fa_not_setup")
+	}
+
+	ok, err := s.consumeEmailCode(&user, code)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("error.invalid_totp")
+	}
+
+	plainCodes, codesJSON, err := newRecoveryCodeSet()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.db.Model(&model.User{}).Where("id = ?", user.ID).Updates(map[string]interface{}{
+		"totp_enabled":      true,
+		"totp_secret":       "",
+		"two_factor_method": TwoFactorEmail,
+		"recovery_codes":    codesJSON,
+	}).Error; err != nil {
+		return nil, fmt.Errorf("failed to enable 2FA: %w", err)
+	}
+	return plainCodes, nil
+}
+
+// SendEmailCode emails a fresh login code to a user with email 2FA enabled.
+func (s *TOTPService) SendEmailCode(userID uint) error {
+	var user model.User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		return fmt.Errorf("error.user_not_found")
+	}
+	if user.TOTPEnabled == nil || !*user.TOTPEnabled || user.TwoFactorMethod != TwoFactorEmail {
+		return fmt.Errorf("error.2fa_not_enabled")
+	}
+	return s.issueEmailCode(&user)
+}
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/web-casa/webcasa/internal/model"
+)
+
+// mockMailer records sent messages instead of talking to an SMTP server.
+type mockMailer struct {
+	to, bodies []string
+}
+
+func (m *mockMailer) Send(to, subject, body string) error {
+	m.to = append(m.to, to)
+	m.bodies = append(m.bodies, body)
+	return nil
+}
+
+var emailCodeRE = regexp.MustCompile(`\b(\d{6})\b`)
+
+// lastCode extracts the 6-digit code from the most recent message.
+func (m *mockMailer) lastCode(t *testing.T) string {
+	t.Helper()
+	if len(m.bodies) == 0 {
+		t.Fatal("no email sent")
+	}
+	match := emailCodeRE.FindStringSubmatch(m.bodies[len(m.bodies)-1])
+	if match == nil {
+		t.Fatalf("no 6-digit code in email: %q", m.bodies[len(m.bodies)-1])
+	}
+	return match[1]
+}
+
+// setupEmail2FAUser creates a user with email 2FA enabled and returns it
+// along with the mock mailer.
+func setupEmail2FAUser(t *testing.T) (*TOTPService, *mockMailer, *model.User) {
+	t.Helper()
+	svc, _ := setupTOTPTestDB(t)
+	mailer := &mockMailer{}
+	svc.SetMailer(mailer)
+	user := createTestUser(t, svc, "mailuser", "password123")
+
+	if err := svc.SetupEmail(user.ID, "ops@example.com"); err != nil {
+		t.Fatalf("SetupEmail: %v", err)
+	}
+	codes, err := svc.VerifyEmailAndEnable(user.ID, mailer.lastCode(t))
+	if err != nil {
+		t.Fatalf("VerifyEmailAndEnable: %v", err)
+	}
+	if len(codes) != 8 {
+		t.Fatalf("got %d recovery codes, want 8", len(codes))
+	}
+	return svc, mailer, user
+}
+
+func TestEmailOTP_CodeGeneration(t *testing.T) {
+	svc, mailer, user := setupEmail2FAUser(t)
+
+	if err := svc.SendEmailCode(user.ID); err != nil {
+		t.Fatalf("SendEmailCode: %v", err)
+	}
+	if got := mailer.to[len(mailer.to)-1]; got != "ops@example.com" {
+		t.Errorf("sent to %q", got)
+	}
+	code := mailer.lastCode(t)
+
+	var stored model.User
+	svc.db.First(&stored, user.ID)
+	if stored.TwoFactorMethod != TwoFactorEmail {
+		t.Errorf("method = %q, want email", stored.TwoFactorMethod)
+	}
+	if stored.EmailOTPHash == "" || strings.Contains(stored.EmailOTPHash, code) {
+		t.Errorf("code should be stored hashed, got %q", stored.EmailOTPHash)
+	}
+	if ttl := time.Until(stored.EmailOTPExpires); ttl <= 0 || ttl > emailOTPTTL {
+		t.Errorf("expiry in %v, want within %v", ttl, emailOTPTTL)
+	}
+
+	if err := svc.SetupEmail(user.ID, "other@example.com"); err == nil || err.Error() != "error.2fa_already_enabled" {
+		t.Errorf("SetupEmail while enabled: err = %v", err)
+	}
+}
+
+func TestEmailOTP_SingleUse(t *testing.T) {
+	svc, mailer, user := setupEmail2FAUser(t)
+	svc.SendEmailCode(user.ID)
+	code := mailer.lastCode(t)
+
+	if valid, err := svc.ValidateLogin(user.ID, code); err != nil || !valid {
+		t.Fatalf("first use: valid=%v err=%v", valid, err)
+	}
+	if valid, _ := svc.ValidateLogin(user.ID, code); valid {
+		t.Error("an email code must not be accepted twice")
+	}
+}
+
+func TestEmailOTP_Expiry(t *testing.T) {
+	svc, mailer, user := setupEmail2FAUser(t)
+	svc.SendEmailCode(user.ID)
+	code := mailer.lastCode(t)
+
+	svc.db.Model(&model.User{}).Where("id = ?", user.ID).Update("email_otp_expires", time.Now().Add(-time.Second))
+	if valid, _ := svc.ValidateLogin(user.ID, code); valid {
+		t.Error("expired email code should be rejected")
+	}
+}
+
+func TestEmailOTP_WrongCodeRejected(t *testing.T) {
+	svc, mailer, user := setupEmail2FAUser(t)
+	svc.SendEmailCode(user.ID)
+	code := mailer.lastCode(t)
+
+	wrong := "000000"
+	if code == wrong {
+		wrong = "111111"
+	}
+	if valid, _ := svc.ValidateLogin(user.ID, wrong); valid {
+		t.Error("wrong email code should be rejected")
+	}
+	// A wrong guess does not burn the outstanding code.
+	if valid, err := svc.ValidateLogin(user.ID, code); err != nil || !valid {
+		t.Errorf("correct code after a wrong guess: valid=%v err=%v", valid, err)
+	}
+
+	// A newer code replaces the previous one.
+	svc.SendEmailCode(user.ID)
+	first := mailer.lastCode(t)
+	svc.SendEmailCode(user.ID)
+	if mailer.lastCode(t) != first {
+		if valid, _ := svc.ValidateLogin(user.ID, first); valid {
+			t.Error("superseded code should be rejected")
+		}
+	}
+}
+
+func TestEmailOTP_Disable(t *testing.T) {
+	svc, mailer, user := setupEmail2FAUser(t)
+	svc.SendEmailCode(user.ID)
+
+	if err := svc.Disable(user.ID, mailer.lastCode(t)); err != nil {
+		t.Fatalf("Disable: %v", err)
+	}
+	var stored model.User
+	svc.db.First(&stored, user.ID)
+	if *stored.TOTPEnabled || stored.TwoFactorMethod != TwoFactorTOTP || stored.EmailOTPHash != "" {
+		t.Errorf("after disable: enabled=%v method=%q", *stored.TOTPEnabled, stored.TwoFactorMethod)
+	}
+}
@@ -3,6 +3,8 @@ package service
 import (
 	"fmt"
 	"log"
+	"regexp"
+	"strings"
 
 	"github.com/web-casa/webcasa/internal/caddy"
 	"github.com/web-casa/webcasa/internal/config"
@@ -18,16 +20,117 @@ type GroupService struct {
 	hostSvc  *HostService
 }
 
+// PaletteColor is a color suggested for groups and tags.
+type PaletteColor struct {
+	Name  string `json:"name"`
+	Color string `json:"color"`
+}
+
+// ColorPalette is the palette suggested for groups and tags: the solid shades
+// of the UI theme's named colors, so badges can keep their themed styling.
+var ColorPalette = []PaletteColor{
+	{Name: "red", Color: "#e5484d"},
+	{Name: "orange", Color: "#f76b15"},
+	{Name: "yellow", Color: "#ffe629"},
+	{Name: "green", Color: "#30a46c"},
+	{Name: "blue", Color: "#0090ff"},
+	{Name: "purple", Color: "#8e4ec6"},
+	{Name: "pink", Color: "#d6409f"},
+	{Name: "gray", Color: "#8d8d8d"},
+}
+
+var hexColorRegex = regexp.MustCompile(`^#([0-9a-f]{3}|[0-9a-f]{6})$`)
+
+// normalizeColor validates a group or tag color, which is either empty or a
+// hex code. Hex digits are lowercased and #abc shorthand becomes #aabbcc.
+func normalizeColor(color string) (string, error) {
+	color = strings.ToLower(strings.TrimSpace(color))
+	if color == "" {
+		return "", nil
+	}
+	if !hexColorRegex.MatchString(color) {
+		return "", fmt.Errorf("error.invalid_color")
+	}
+	if len(color) == 4 {
+		color = string([]byte{'#', color[1], color[1], color[2], color[2], color[3], color[3]})
+	}
+	return color, nil
+}
+
 // NewGroupService creates a new GroupService
 func NewGroupService(db *gorm.DB, caddyMgr *caddy.Manager, cfg *config.Config, hostSvc *HostService) *GroupService {
 	return &GroupService{db: db, caddyMgr: caddyMgr, cfg: cfg, hostSvc: hostSvc}
 }
 
-// List returns all groups
+// List returns all groups in their sort order, each with the number of live
+// hosts assigned to it
 func (s *GroupService) List() ([]model.Group, error) {
 	var groups []model.Group
-	err := s.db.Order("id ASC").Find(&groups).Error
-	return groups, err
+	if err := s.db.Order("sort_order ASC, id ASC").Find(&groups).Error; err != nil {
+		return nil, err
+	}
+
+	var counts []struct {
+		GroupID uint
+		Count   int64
+	}
+	err := s.db.Model(&model.Host{}).Select("group_id, COUNT(*) AS count").
+		Where("group_id IS NOT NULL").Group("group_id").Scan(&counts).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to count group hosts: %w", err)
+	}
+	byGroup := make(map[uint]int64, len(counts))
+	for _, c := range counts {
+		byGroup[c.GroupID] = c.Count
+	}
+	for i := range groups {
+		groups[i].HostCount = byGroup[groups[i].ID]
+	}
+	return groups, nil
+}
+
+// Reorder sets the sort order of groups to the order of ids. Groups not
+// listed keep their relative order after the listed ones; repeated IDs count
+// at their first position.
+func (s *GroupService) Reorder(ids []uint) ([]model.Group, error) {
+	var groups []model.Group
+	if err := s.db.Order("sort_order ASC, id ASC").Find(&groups).Error; err != nil {
+		return nil, err
+	}
+	known := make(map[uint]bool, len(groups))
+	for _, g := range groups {
+		known[g.ID] = true
+	}
+
+	order := make([]uint, 0, len(groups))
+	placed := make(map[uint]bool, len(groups))
+	for _, id := range ids {
+		if !known[id] {
+			return nil, fmt.Errorf("error.group_not_found")
+		}
+		if !placed[id] {
+			placed[id] = true
+			order = append(order, id)
+		}
+	}
+	for _, g := range groups {
+		if !placed[g.ID] {
+			order = append(order, g.ID)
+		}
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		for i, id := range order {
+			if err := tx.Model(&model.Group{}).Where("id = ?", id).Update("sort_order", i).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to reorder groups: %w", err)
+	}
+	return s.List()
 }
 
 // Get returns a single group by ID
@@ -41,15 +144,25 @@ func (s *GroupService) Get(id uint) (*model.Group, error) {
 
 // Create creates a new group
 func (s *GroupService) Create(name, color string) (*model.Group, error) {
+	color, err := normalizeColor(color)
+	if err != nil {
+		return nil, err
+	}
+
 	var count int64
 	s.db.Model(&model.Group{}).Where("name = ?", name).Count(&count)
 	if count > 0 {
 		return nil, fmt.Errorf("error.group_name_exists")
 	}
 
+	// New groups go to the end of the custom order
+	var maxOrder int
+	s.db.Model(&model.Group{}).Select("COALESCE(MAX(sort_order), -1)").Scan(&maxOrder)
+
 	group := &model.Group{
-		Name:  name,
-		Color: color,
+		Name:      name,
+		Color:     color,
+		SortOrder: maxOrder + 1,
 	}
 	if err := s.db.Create(group).Error; err != nil {
 		return nil, fmt.Errorf("failed to create group: %w", err)
@@ -63,6 +176,9 @@ func (s *GroupService) Update(id uint, name, color string) (*model.Group, error)
 	if err != nil {
 		return nil, err
 	}
+	if color, err = normalizeColor(color); err != nil {
+		return nil, err
+	}
 
 	var count int64
 	s.db.Model(&model.Group{}).Where("name = ? AND id != ?", name, id).Count(&count)
@@ -85,8 +201,8 @@ func (s *GroupService) Delete(id uint) error {
 		return fmt.Errorf("error.group_not_found")
 	}
 
-	// Set associated hosts' group_id to NULL
-	s.db.Model(&model.Host{}).Where("group_id = ?", id).Update("group_id", nil)
+	// Set associated hosts' group_id to NULL, including hosts in the trash
+	s.db.Unscoped().Model(&model.Host{}).Where("group_id = ?", id).Update("group_id", nil)
 
 	if err := s.db.Delete(&model.Group{}, id).Error; err != nil {
 		return fmt.Errorf("failed to delete group: %w", err)
@@ -106,7 +222,7 @@ func (s *GroupService) BatchEnable(groupID uint) error {
 		return fmt.Errorf("failed to batch enable hosts: %w", err)
 	}
 
-	if err := s.hostSvc.ApplyConfig(); err != nil {
+	if _, err := s.hostSvc.ApplyConfig(); err != nil {
 		log.Printf("Warning: failed to apply config after batch enable: %v", err)
 	}
 	return nil
@@ -124,7 +240,7 @@ func (s *GroupService) BatchDisable(groupID uint) error {
 		return fmt.Errorf("failed to batch disable hosts: %w", err)
 	}
 
-	if err := s.hostSvc.ApplyConfig(); err != nil {
+	if _, err := s.hostSvc.ApplyConfig(); err != nil {
 		log.Printf("Warning: failed to apply config after batch disable: %v", err)
 	}
 	return nil
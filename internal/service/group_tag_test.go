@@ -274,6 +274,7 @@ func TestProperty11_HostGroupAssociation(t *testing.T) {
 				HostType: "proxy",
 				Enabled:  &enabled,
 				GroupID:  nil,
+				Version:  host.Version,
 				Upstreams: []model.UpstreamInput{
 					{Address: "localhost:8080", Weight: 1},
 				},
@@ -581,3 +582,170 @@ func TestProperty15_DeleteGroupUnlinksHosts(t *testing.T) {
 
 	properties.TestingRun(t)
 }
+
+func TestGroupTagColor_NormalizesHex(t *testing.T) {
+	db := setupTestDB(t)
+	groupSvc := NewGroupService(db, nil, nil, setupTestHostService(t, db))
+	tagSvc := NewTagService(db)
+
+	group, err := groupSvc.Create("shorthand", "#ABC")
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if group.Color != "#aabbcc" {
+		t.Errorf("group color = %q, want #aabbcc", group.Color)
+	}
+	group, err = groupSvc.Update(group.ID, "shorthand", " #10B981 ")
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if group.Color != "#10b981" {
+		t.Errorf("updated group color = %q, want #10b981", group.Color)
+	}
+
+	tag, err := tagSvc.Create("shorthand", "#f0f")
+	if err != nil {
+		t.Fatalf("tag Create: %v", err)
+	}
+	if tag.Color != "#ff00ff" {
+		t.Errorf("tag color = %q, want #ff00ff", tag.Color)
+	}
+}
+
+func TestGroupTagColor_RejectsNonHex(t *testing.T) {
+	db := setupTestDB(t)
+	groupSvc := NewGroupService(db, nil, nil, setupTestHostService(t, db))
+	tagSvc := NewTagService(db)
+
+	for _, color := range []string{"red", "#12345", "#ggg", "10b981", "#10b98100"} {
+		if _, err := groupSvc.Create("g-"+color, color); err == nil || err.Error() != "error.invalid_color" {
+			t.Errorf("group color %q: err = %v, want error.invalid_color", color, err)
+		}
+		if _, err := tagSvc.Create("t-"+color, color); err == nil || err.Error() != "error.invalid_color" {
+			t.Errorf("tag color %q: err = %v, want error.invalid_color", color, err)
+		}
+	}
+
+	group, err := groupSvc.Create("valid", "#3b82f6")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := groupSvc.Update(group.ID, "valid", "red"); err == nil || err.Error() != "error.invalid_color" {
+		t.Errorf("Update with red: err = %v, want error.invalid_color", err)
+	}
+	if fetched, _ := groupSvc.Get(group.ID); fetched.Color != "#3b82f6" {
+		t.Errorf("rejected update changed color to %q", fetched.Color)
+	}
+}
+
+func TestGroupTagColor_EmptyAllowed(t *testing.T) {
+	db := setupTestDB(t)
+	groupSvc := NewGroupService(db, nil, nil, setupTestHostService(t, db))
+	tagSvc := NewTagService(db)
+
+	group, err := groupSvc.Create("plain", "")
+	if err != nil {
+		t.Fatalf("group Create: %v", err)
+	}
+	if group.Color != "" {
+		t.Errorf("group color = %q, want empty", group.Color)
+	}
+	tag, err := tagSvc.Create("plain", "  ")
+	if err != nil {
+		t.Fatalf("tag Create: %v", err)
+	}
+	if tag.Color != "" {
+		t.Errorf("tag color = %q, want empty", tag.Color)
+	}
+}
+
+func TestGroupReorder_Persists(t *testing.T) {
+	db := setupTestDB(t)
+	groupSvc := NewGroupService(db, nil, nil, setupTestHostService(t, db))
+
+	var ids []uint
+	for _, name := range []string{"alpha", "beta", "gamma", "delta"} {
+		g, err := groupSvc.Create(name, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, g.ID)
+	}
+
+	// Unlisted groups (delta) keep their place after the listed ones
+	if _, err := groupSvc.Reorder([]uint{ids[2], ids[0], ids[1]}); err != nil {
+		t.Fatalf("Reorder: %v", err)
+	}
+	groups, err := groupSvc.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, g := range groups {
+		names = append(names, g.Name)
+	}
+	if got := fmt.Sprint(names); got != "[gamma alpha beta delta]" {
+		t.Errorf("order after reorder = %s, want [gamma alpha beta delta]", got)
+	}
+
+	// Groups created later are appended
+	if _, err := groupSvc.Create("epsilon", ""); err != nil {
+		t.Fatal(err)
+	}
+	groups, _ = groupSvc.List()
+	if last := groups[len(groups)-1].Name; last != "epsilon" {
+		t.Errorf("last group = %s, want epsilon", last)
+	}
+
+	if _, err := groupSvc.Reorder([]uint{ids[0], 9999}); err == nil || err.Error() != "error.group_not_found" {
+		t.Errorf("Reorder with unknown id: err = %v, want error.group_not_found", err)
+	}
+	groups, _ = groupSvc.List()
+	if groups[0].Name != "gamma" {
+		t.Errorf("failed reorder changed the order: first group = %s", groups[0].Name)
+	}
+}
+
+func TestGroupList_HostCounts(t *testing.T) {
+	db := setupTestDB(t)
+	hostSvc := setupTestHostService(t, db)
+	groupSvc := NewGroupService(db, nil, nil, hostSvc)
+
+	web, _ := groupSvc.Create("web", "")
+	api, _ := groupSvc.Create("api", "")
+	empty, _ := groupSvc.Create("empty", "")
+
+	a := createTestHost(t, hostSvc, "a.example.com", 1, 0, 0, 0, 0)
+	b := createTestHost(t, hostSvc, "b.example.com", 1, 0, 0, 0, 0)
+	c := createTestHost(t, hostSvc, "c.example.com", 1, 0, 0, 0, 0)
+	createTestHost(t, hostSvc, "ungrouped.example.com", 1, 0, 0, 0, 0)
+	db.Model(&model.Host{}).Where("id IN ?", []uint{a.ID, b.ID}).Update("group_id", web.ID)
+	db.Model(&model.Host{}).Where("id = ?", c.ID).Update("group_id", api.ID)
+
+	counts := func() map[uint]int64 {
+		groups, err := groupSvc.List()
+		if err != nil {
+			t.Fatal(err)
+		}
+		m := make(map[uint]int64)
+		for _, g := range groups {
+			m[g.ID] = g.HostCount
+		}
+		return m
+	}
+
+	got := counts()
+	if got[web.ID] != 2 || got[api.ID] != 1 || got[empty.ID] != 0 {
+		t.Errorf("counts = web %d, api %d, empty %d; want 2, 1, 0", got[web.ID], got[api.ID], got[empty.ID])
+	}
+
+	// Moving a host and trashing another are reflected
+	db.Model(&model.Host{}).Where("id = ?", b.ID).Update("group_id", api.ID)
+	if err := hostSvc.Delete(c.ID); err != nil {
+		t.Fatal(err)
+	}
+	got = counts()
+	if got[web.ID] != 1 || got[api.ID] != 1 {
+		t.Errorf("counts after changes = web %d, api %d; want 1, 1", got[web.ID], got[api.ID])
+	}
+}
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"strings"
@@ -11,6 +12,7 @@ import (
 	"github.com/web-casa/webcasa/internal/model"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // HostService handles business logic for proxy hosts
@@ -18,41 +20,124 @@ type HostService struct {
 	db       *gorm.DB
 	caddyMgr *caddy.Manager
 	cfg      *config.Config
+	applier  configApplier
+	metadata metadataCache
 }
 
+// ErrStaleHost is returned by Update when the host changed since the
+// version the edit was based on.
+var ErrStaleHost = errors.New("host was modified by someone else; reload it and try again")
+
+// ErrInvalidGeneratedConfig is returned by ApplyConfig when Caddy rejects the
+// rendered Caddyfile. The previous Caddyfile stays live.
+var ErrInvalidGeneratedConfig = errors.New("generated Caddyfile is invalid, the previous config was kept")
+
+// ErrNotInTrash is returned by Restore and Purge for hosts that are not in
+// the trash.
+var ErrNotInTrash = errors.New("host not found in trash")
+
+// ErrDomainTaken is returned by Restore when a live host now uses the
+// trashed host's domain.
+var ErrDomainTaken = errors.New("domain is already used by another host")
+
 // NewHostService creates a new HostService
 func NewHostService(db *gorm.DB, caddyMgr *caddy.Manager, cfg *config.Config) *HostService {
-	return &HostService{db: db, caddyMgr: caddyMgr, cfg: cfg}
+	s := &HostService{db: db, caddyMgr: caddyMgr, cfg: cfg}
+	s.applier.delay = time.Duration(cfg.ApplyDebounceMs) * time.Millisecond
+	s.applier.apply = s.writeConfig
+	return s
 }
 
 // HostListFilter holds optional filter parameters for listing hosts
 type HostListFilter struct {
 	GroupID *uint
 	TagID   *uint
+	Domain  string // case-insensitive substring of the domain
+	Sort    string // one of hostSortOrders; defaults to "id"
+	Limit   int    // page size; 0 means no limit
+	Offset  int
 }
 
-// List returns all hosts with their associations, optionally filtered by group_id and/or tag_id
-func (s *HostService) List(filters ...HostListFilter) ([]model.Host, error) {
-	var hosts []model.Host
-	query := s.db.Preload("Upstreams").Preload("CustomHeaders").Preload("AccessRules").Preload("Routes").Preload("BasicAuths").
-		Preload("Group").Preload("Tags")
+// hostSortOrders maps the accepted sort keys to their ORDER BY clauses. A
+// leading "-" sorts descending; ties fall back to the host ID.
+var hostSortOrders = map[string]string{
+	"id":          "hosts.id ASC",
+	"-id":         "hosts.id DESC",
+	"domain":      "hosts.domain ASC, hosts.id ASC",
+	"-domain":     "hosts.domain DESC, hosts.id ASC",
+	"created_at":  "hosts.created_at ASC, hosts.id ASC",
+	"-created_at": "hosts.created_at DESC, hosts.id ASC",
+}
 
+// List returns all hosts with their associations, optionally filtered by
+// group_id, tag_id and domain. Limit and Offset are ignored; use ListPage
+// for a single page.
+func (s *HostService) List(filters ...HostListFilter) ([]model.Host, error) {
 	var filter HostListFilter
 	if len(filters) > 0 {
 		filter = filters[0]
 	}
+	filter.Limit, filter.Offset = 0, 0
+	hosts, _, err := s.ListPage(filter)
+	return hosts, err
+}
+
+// ListPage returns one page of hosts matching filter along with the number
+// of hosts matching it across all pages.
+func (s *HostService) ListPage(filter HostListFilter) ([]model.Host, int64, error) {
+	query, total, err := s.pageQuery(filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var hosts []model.Host
+	err = query.Preload("Upstreams").Preload("CustomHeaders").Preload("AccessRules").Preload("Routes").Preload("BasicAuths").
+		Preload("Group").Preload("Tags").Find(&hosts).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return hosts, total, nil
+}
+
+// pageQuery builds the sorted, paginated host query for filter and counts
+// the hosts it matches across all pages.
+func (s *HostService) pageQuery(filter HostListFilter) (*gorm.DB, int64, error) {
+	if filter.Sort == "" {
+		filter.Sort = "id"
+	}
+	order, ok := hostSortOrders[filter.Sort]
+	if !ok {
+		return nil, 0, fmt.Errorf("error.invalid_sort")
+	}
+	if filter.Limit < 0 || filter.Offset < 0 {
+		return nil, 0, fmt.Errorf("error.invalid_pagination")
+	}
 
+	query := s.db.Model(&model.Host{})
 	if filter.GroupID != nil {
 		query = query.Where("hosts.group_id = ?", *filter.GroupID)
 	}
-
 	if filter.TagID != nil {
 		query = query.Joins("JOIN host_tags ON host_tags.host_id = hosts.id").
 			Where("host_tags.tag_id = ?", *filter.TagID)
 	}
+	if domain := strings.TrimSpace(filter.Domain); domain != "" {
+		query = query.Where(`LOWER(hosts.domain) LIKE ? ESCAPE '\'`, "%"+escapeLike(strings.ToLower(domain))+"%")
+	}
 
-	err := query.Order("hosts.id ASC").Find(&hosts).Error
-	return hosts, err
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	query = query.Order(order)
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+	if filter.Offset > 0 {
+		query = query.Offset(filter.Offset)
+	}
+	return query, total, nil
 }
 
 // Get returns a single host by ID
@@ -69,6 +154,49 @@ func (s *HostService) Get(id uint) (*model.Host, error) {
 
 // Create creates a new host and applies the configuration
 func (s *HostService) Create(req *model.HostCreateRequest) (*model.Host, error) {
+	host, err := s.create(req)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.ApplyConfig()
+	if errors.Is(err, ErrInvalidGeneratedConfig) && s.flagRejectedHosts(host.ID) {
+		// Another change merged into the same apply broke the config. It
+		// is flagged now, so apply this one again.
+		result, err = s.ApplyConfig()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("host created but Caddy config failed: %w", err)
+	}
+
+	applied, err := s.getApplied(host.ID, result)
+	if err != nil {
+		return nil, err
+	}
+	applied.Warning = host.Warning
+	return applied, nil
+}
+
+// getApplied loads a host and attaches the result of the config apply that
+// followed its change.
+func (s *HostService) getApplied(id uint, result model.ApplyResult) (*model.Host, error) {
+	host, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	host.Apply = &result
+	return host, nil
+}
+
+// create validates and saves a new host without applying the configuration.
+func (s *HostService) create(req *model.HostCreateRequest) (*model.Host, error) {
+	// Store one canonical form so uniqueness checks and rendering agree
+	domain, err := caddy.NormalizeDomain(req.Domain)
+	if err != nil {
+		return nil, fmt.Errorf("invalid domain: %w", err)
+	}
+	req.Domain = domain
+
 	// Validate domain for Caddyfile safety
 	if err := caddy.ValidateDomain(req.Domain); err != nil {
 		return nil, fmt.Errorf("invalid domain: %w", err)
@@ -93,16 +221,62 @@ func (s *HostService) Create(req *model.HostCreateRequest) (*model.Host, error)
 		return nil, fmt.Errorf("invalid custom directives: %w", err)
 	}
 
+	// Validate request body limit
+	if req.MaxRequestBodySize != "" {
+		if _, err := caddy.ParseByteSize(req.MaxRequestBodySize); err != nil {
+			return nil, fmt.Errorf("invalid max_request_body_size: %w", err)
+		}
+	}
+
+	// Validate proxy timeouts
+	for label, val := range map[string]string{
+		"proxy_read_timeout":  req.ProxyReadTimeout,
+		"proxy_write_timeout": req.ProxyWriteTimeout,
+		"proxy_dial_timeout":  req.ProxyDialTimeout,
+	} {
+		if err := caddy.ValidateDuration(label, val); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := caddy.ValidateForwardAuthURL(req.ForwardAuthURL); err != nil {
+		return nil, err
+	}
+	if err := caddy.ValidateHeaderList("forward_auth_copy_headers", req.ForwardAuthCopyHeaders); err != nil {
+		return nil, err
+	}
+	if err := caddy.ValidateSecurityHeadersConfig(req.SecurityHeadersConfig); err != nil {
+		return nil, err
+	}
+	if err := caddy.ValidatePHPFastCGI(req.PHPFastCGI); err != nil {
+		return nil, err
+	}
+	if err := caddy.ValidatePHPVersion(req.PHPVersion); err != nil {
+		return nil, err
+	}
+	compressionAlgorithms, err := caddy.NormalizeCompressionAlgorithms(req.CompressionAlgorithms)
+	if err != nil {
+		return nil, err
+	}
+	acmeCA, err := caddy.NormalizeACMECA(req.ACMECA)
+	if err != nil {
+		return nil, err
+	}
+	if err := caddy.ValidateCors(req.CorsOrigins, boolVal(req.CorsAllowCredentials), req.CorsMaxAge); err != nil {
+		return nil, err
+	}
+
 	// Validate all string fields that get embedded in Caddyfile
 	for label, val := range map[string]string{
-		"redirect_url":   req.RedirectURL,
-		"root_path":      req.RootPath,
-		"error_page_path": req.ErrorPagePath,
-		"php_fastcgi":    req.PHPFastCGI,
-		"index_files":    req.IndexFiles,
-		"cors_origins":   req.CorsOrigins,
-		"cors_methods":   req.CorsMethods,
-		"cors_headers":   req.CorsHeaders,
+		"redirect_url":     req.RedirectURL,
+		"root_path":        req.RootPath,
+		"error_page_path":  req.ErrorPagePath,
+		"php_fastcgi":      req.PHPFastCGI,
+		"index_files":      req.IndexFiles,
+		"cors_origins":     req.CorsOrigins,
+		"cors_methods":     req.CorsMethods,
+		"cors_headers":     req.CorsHeaders,
+		"basic_auth_realm": req.BasicAuthRealm,
 	} {
 		if err := caddy.ValidateCaddyValue(label, val); err != nil {
 			return nil, err
@@ -117,10 +291,21 @@ func (s *HostService) Create(req *model.HostCreateRequest) (*model.Host, error)
 		}
 	}
 
-	var count int64
-	s.db.Model(&model.Host{}).Where("domain = ?", req.Domain).Count(&count)
-	if count > 0 {
-		return nil, fmt.Errorf("domain '%s' already exists", req.Domain)
+	aliases, err := normalizeAliases(req.Domain, req.Aliases)
+	if err != nil {
+		return nil, err
+	}
+	names := caddy.HostDomains(model.Host{Domain: req.Domain, Aliases: aliases})
+	taken, err := s.findNameConflict(names, 0)
+	if err != nil {
+		return nil, err
+	}
+	if taken != "" {
+		return nil, fmt.Errorf("domain '%s' already exists", taken)
+	}
+	warning, err := s.checkDomainOverlap(names, 0)
+	if err != nil {
+		return nil, err
 	}
 
 	// Optional DNS pre-validation: warn if domain doesn't resolve to this server.
@@ -160,33 +345,54 @@ func (s *HostService) Create(req *model.HostCreateRequest) (*model.Host, error)
 			return nil, fmt.Errorf("root_path is required for PHP hosts")
 		}
 	}
+	if boolVal(req.SPAFallback) && hostType != "static" {
+		return nil, fmt.Errorf("spa_fallback is only supported for static hosts")
+	}
 
 	host := &model.Host{
-		Domain:           req.Domain,
-		HostType:         hostType,
-		Enabled:          boolPtr(boolOrDefault(req.Enabled, true)),
-		TLSEnabled:       boolPtr(boolOrDefault(req.TLSEnabled, true)),
-		HTTPRedirect:     boolPtr(boolOrDefault(req.HTTPRedirect, true)),
-		WebSocket:        boolPtr(boolOrDefault(req.WebSocket, false)),
-		RedirectURL:      req.RedirectURL,
-		RedirectCode:     intOrDefault(req.RedirectCode, 301),
-		Compression:      boolPtr(boolOrDefault(req.Compression, false)),
-		CacheEnabled:     boolPtr(boolOrDefault(req.CacheEnabled, false)),
-		CacheTTL:         intOrDefault(req.CacheTTL, 300),
-		CorsEnabled:      boolPtr(boolOrDefault(req.CorsEnabled, false)),
-		CorsOrigins:      req.CorsOrigins,
-		CorsMethods:      req.CorsMethods,
-		CorsHeaders:      req.CorsHeaders,
-		SecurityHeaders:  boolPtr(boolOrDefault(req.SecurityHeaders, false)),
-		ErrorPagePath:    req.ErrorPagePath,
-		RootPath:         req.RootPath,
-		DirectoryBrowse:  boolPtr(boolOrDefault(req.DirectoryBrowse, false)),
-		PHPFastCGI:       req.PHPFastCGI,
-		IndexFiles:       req.IndexFiles,
-		TLSMode:          stringOrDefault(req.TLSMode, "auto"),
-		DnsProviderID:    uintPtrOrNil(req.DnsProviderID),
-		CustomDirectives: req.CustomDirectives,
-		GroupID:          uintPtrOrNil(req.GroupID),
+		Domain:                 req.Domain,
+		DisplayDomain:          caddy.DisplayDomain(req.Domain),
+		Aliases:                aliases,
+		BasicAuthRealm:         req.BasicAuthRealm,
+		ForwardAuthURL:         req.ForwardAuthURL,
+		ForwardAuthCopyHeaders: req.ForwardAuthCopyHeaders,
+		SecurityHeadersConfig:  copySecurityHeadersConfig(req.SecurityHeadersConfig),
+		CorsAllowCredentials:   boolPtr(boolOrDefault(req.CorsAllowCredentials, false)),
+		CorsMaxAge:             req.CorsMaxAge,
+		CompressionAlgorithms:  compressionAlgorithms,
+		ACMECA:                 acmeCA,
+		HostType:               hostType,
+		Enabled:                boolPtr(boolOrDefault(req.Enabled, true)),
+		TLSEnabled:             boolPtr(boolOrDefault(req.TLSEnabled, true)),
+		HTTPRedirect:           boolPtr(boolOrDefault(req.HTTPRedirect, true)),
+		WebSocket:              boolPtr(boolOrDefault(req.WebSocket, false)),
+		RedirectURL:            req.RedirectURL,
+		RedirectCode:           intOrDefault(req.RedirectCode, 301),
+		Compression:            boolPtr(boolOrDefault(req.Compression, false)),
+		CacheEnabled:           boolPtr(boolOrDefault(req.CacheEnabled, false)),
+		CacheTTL:               intOrDefault(req.CacheTTL, 300),
+		CorsEnabled:            boolPtr(boolOrDefault(req.CorsEnabled, false)),
+		CorsOrigins:            req.CorsOrigins,
+		CorsMethods:            req.CorsMethods,
+		CorsHeaders:            req.CorsHeaders,
+		SecurityHeaders:        boolPtr(boolOrDefault(req.SecurityHeaders, false)),
+		ErrorPagePath:          req.ErrorPagePath,
+		RootPath:               req.RootPath,
+		DirectoryBrowse:        boolPtr(boolOrDefault(req.DirectoryBrowse, false)),
+		SPAFallback:            boolPtr(boolOrDefault(req.SPAFallback, false)),
+		PHPFastCGI:             req.PHPFastCGI,
+		PHPVersion:             req.PHPVersion,
+		IndexFiles:             req.IndexFiles,
+		SeparateAccessLog:      boolPtr(boolOrDefault(req.SeparateAccessLog, true)),
+		MaxRequestBodySize:     strings.TrimSpace(req.MaxRequestBodySize),
+		ProxyReadTimeout:       strings.TrimSpace(req.ProxyReadTimeout),
+		ProxyWriteTimeout:      strings.TrimSpace(req.ProxyWriteTimeout),
+		ProxyDialTimeout:       strings.TrimSpace(req.ProxyDialTimeout),
+		TLSMode:                stringOrDefault(req.TLSMode, "auto"),
+		DnsProviderID:          uintPtrOrNil(req.DnsProviderID),
+		CustomDirectives:       req.CustomDirectives,
+		GroupID:                uintPtrOrNil(req.GroupID),
+		Version:                1,
 	}
 
 	for i, u := range req.Upstreams {
@@ -224,13 +430,17 @@ func (s *HostService) Create(req *model.HostCreateRequest) (*model.Host, error)
 		if err := caddy.ValidateCaddyValue("basicauth username", ba.Username); err != nil {
 			return nil, err
 		}
-		hash, err := bcrypt.GenerateFromPassword([]byte(ba.Password), bcrypt.DefaultCost)
+		if err := caddy.ValidateBasicAuthPath(ba.Path); err != nil {
+			return nil, err
+		}
+		hash, err := basicAuthHash(ba)
 		if err != nil {
 			return nil, fmt.Errorf("failed to hash password for user '%s': %w", ba.Username, err)
 		}
 		host.BasicAuths = append(host.BasicAuths, model.BasicAuth{
 			Username:     ba.Username,
-			PasswordHash: string(hash),
+			PasswordHash: hash,
+			Path:         ba.Path,
 		})
 	}
 
@@ -244,12 +454,11 @@ func (s *HostService) Create(req *model.HostCreateRequest) (*model.Host, error)
 			s.db.Create(&model.HostTag{HostID: host.ID, TagID: tagID})
 		}
 	}
+	s.applyTagRules(host)
 
-	if err := s.ApplyConfig(); err != nil {
-		return nil, fmt.Errorf("host created but Caddy config failed: %w", err)
-	}
-
-	return s.Get(host.ID)
+	s.recordRevision(host.ID, req.AuthorID, req.Author)
+	host.Warning = warning
+	return host, nil
 }
 
 // Update modifies an existing host
@@ -258,6 +467,16 @@ func (s *HostService) Update(id uint, req *model.HostCreateRequest) (*model.Host
 	if err != nil {
 		return nil, err
 	}
+	if req.Version != host.Version {
+		return nil, ErrStaleHost
+	}
+
+	// Store one canonical form so uniqueness checks and rendering agree
+	domain, err := caddy.NormalizeDomain(req.Domain)
+	if err != nil {
+		return nil, fmt.Errorf("invalid domain: %w", err)
+	}
+	req.Domain = domain
 
 	// Validate domain for Caddyfile safety
 	if err := caddy.ValidateDomain(req.Domain); err != nil {
@@ -283,16 +502,62 @@ func (s *HostService) Update(id uint, req *model.HostCreateRequest) (*model.Host
 		return nil, fmt.Errorf("invalid custom directives: %w", err)
 	}
 
+	// Validate request body limit
+	if req.MaxRequestBodySize != "" {
+		if _, err := caddy.ParseByteSize(req.MaxRequestBodySize); err != nil {
+			return nil, fmt.Errorf("invalid max_request_body_size: %w", err)
+		}
+	}
+
+	// Validate proxy timeouts
+	for label, val := range map[string]string{
+		"proxy_read_timeout":  req.ProxyReadTimeout,
+		"proxy_write_timeout": req.ProxyWriteTimeout,
+		"proxy_dial_timeout":  req.ProxyDialTimeout,
+	} {
+		if err := caddy.ValidateDuration(label, val); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := caddy.ValidateForwardAuthURL(req.ForwardAuthURL); err != nil {
+		return nil, err
+	}
+	if err := caddy.ValidateHeaderList("forward_auth_copy_headers", req.ForwardAuthCopyHeaders); err != nil {
+		return nil, err
+	}
+	if err := caddy.ValidateSecurityHeadersConfig(req.SecurityHeadersConfig); err != nil {
+		return nil, err
+	}
+	if err := caddy.ValidatePHPFastCGI(req.PHPFastCGI); err != nil {
+		return nil, err
+	}
+	if err := caddy.ValidatePHPVersion(req.PHPVersion); err != nil {
+		return nil, err
+	}
+	compressionAlgorithms, err := caddy.NormalizeCompressionAlgorithms(req.CompressionAlgorithms)
+	if err != nil {
+		return nil, err
+	}
+	acmeCA, err := caddy.NormalizeACMECA(req.ACMECA)
+	if err != nil {
+		return nil, err
+	}
+	if err := caddy.ValidateCors(req.CorsOrigins, boolOrDefault(req.CorsAllowCredentials, boolVal(host.CorsAllowCredentials)), req.CorsMaxAge); err != nil {
+		return nil, err
+	}
+
 	// Validate all string fields that get embedded in Caddyfile
 	for label, val := range map[string]string{
-		"redirect_url":   req.RedirectURL,
-		"root_path":      req.RootPath,
-		"error_page_path": req.ErrorPagePath,
-		"php_fastcgi":    req.PHPFastCGI,
-		"index_files":    req.IndexFiles,
-		"cors_origins":   req.CorsOrigins,
-		"cors_methods":   req.CorsMethods,
-		"cors_headers":   req.CorsHeaders,
+		"redirect_url":     req.RedirectURL,
+		"root_path":        req.RootPath,
+		"error_page_path":  req.ErrorPagePath,
+		"php_fastcgi":      req.PHPFastCGI,
+		"index_files":      req.IndexFiles,
+		"cors_origins":     req.CorsOrigins,
+		"cors_methods":     req.CorsMethods,
+		"cors_headers":     req.CorsHeaders,
+		"basic_auth_realm": req.BasicAuthRealm,
 	} {
 		if err := caddy.ValidateCaddyValue(label, val); err != nil {
 			return nil, err
@@ -307,10 +572,23 @@ func (s *HostService) Update(id uint, req *model.HostCreateRequest) (*model.Host
 		}
 	}
 
-	var count int64
-	s.db.Model(&model.Host{}).Where("domain = ? AND id != ?", req.Domain, id).Count(&count)
-	if count > 0 {
-		return nil, fmt.Errorf("domain '%s' already exists", req.Domain)
+	aliases, err := normalizeAliases(req.Domain, req.Aliases)
+	if err != nil {
+		return nil, err
+	}
+	names := caddy.HostDomains(model.Host{Domain: req.Domain, Aliases: aliases})
+	taken, err := s.findNameConflict(names, id)
+	if err != nil {
+		return nil, err
+	}
+	if taken != "" {
+		return nil, fmt.Errorf("domain '%s' already exists", taken)
+	}
+	var warning *model.HostWarning
+	if req.Domain != host.Domain || aliases != host.Aliases {
+		if warning, err = s.checkDomainOverlap(names, id); err != nil {
+			return nil, err
+		}
 	}
 
 	hostType := stringOrDefault(req.HostType, host.HostType)
@@ -345,8 +623,17 @@ func (s *HostService) Update(id uint, req *model.HostCreateRequest) (*model.Host
 			return nil, fmt.Errorf("root_path is required for PHP hosts")
 		}
 	}
+	if boolVal(req.SPAFallback) && hostType != "static" {
+		return nil, fmt.Errorf("spa_fallback is only supported for static hosts")
+	}
 
 	host.Domain = req.Domain
+	host.DisplayDomain = caddy.DisplayDomain(req.Domain)
+	host.Aliases = aliases
+	host.BasicAuthRealm = req.BasicAuthRealm
+	host.ForwardAuthURL = req.ForwardAuthURL
+	host.ForwardAuthCopyHeaders = req.ForwardAuthCopyHeaders
+	host.SecurityHeadersConfig = copySecurityHeadersConfig(req.SecurityHeadersConfig)
 	host.HostType = hostType
 	host.Enabled = boolPtr(boolOrDefault(req.Enabled, boolVal(host.Enabled)))
 	host.TLSEnabled = boolPtr(boolOrDefault(req.TLSEnabled, boolVal(host.TLSEnabled)))
@@ -368,27 +655,31 @@ func (s *HostService) Update(id uint, req *model.HostCreateRequest) (*model.Host
 	host.CorsOrigins = req.CorsOrigins
 	host.CorsMethods = req.CorsMethods
 	host.CorsHeaders = req.CorsHeaders
+	host.CorsAllowCredentials = boolPtr(boolOrDefault(req.CorsAllowCredentials, boolVal(host.CorsAllowCredentials)))
+	host.CorsMaxAge = req.CorsMaxAge
+	host.CompressionAlgorithms = compressionAlgorithms
+	host.ACMECA = acmeCA
 	host.SecurityHeaders = boolPtr(boolOrDefault(req.SecurityHeaders, boolVal(host.SecurityHeaders)))
 	host.ErrorPagePath = req.ErrorPagePath
 	host.RootPath = req.RootPath
 	host.DirectoryBrowse = boolPtr(boolOrDefault(req.DirectoryBrowse, boolVal(host.DirectoryBrowse)))
+	// A host that stops being static drops its SPA fallback.
+	host.SPAFallback = boolPtr(hostType == "static" && boolOrDefault(req.SPAFallback, boolVal(host.SPAFallback)))
 	host.PHPFastCGI = req.PHPFastCGI
+	host.PHPVersion = req.PHPVersion
 	host.IndexFiles = req.IndexFiles
+	host.SeparateAccessLog = boolPtr(boolOrDefault(req.SeparateAccessLog, boolOrDefault(host.SeparateAccessLog, true)))
+	host.MaxRequestBodySize = strings.TrimSpace(req.MaxRequestBodySize)
+	host.ProxyReadTimeout = strings.TrimSpace(req.ProxyReadTimeout)
+	host.ProxyWriteTimeout = strings.TrimSpace(req.ProxyWriteTimeout)
+	host.ProxyDialTimeout = strings.TrimSpace(req.ProxyDialTimeout)
 	if req.TLSMode != "" {
 		host.TLSMode = req.TLSMode
 	}
 	host.DnsProviderID = uintPtrOrNil(req.DnsProviderID)
 	host.GroupID = uintPtrOrNil(req.GroupID)
-
-	// Save old upstream IDs before deletion (for route remapping).
-	var oldUpstreams []model.Upstream
-	s.db.Where("host_id = ?", id).Order("sort_order ASC").Find(&oldUpstreams)
-
-	// Replace associations
-	s.db.Where("host_id = ?", id).Delete(&model.Upstream{})
-	s.db.Where("host_id = ?", id).Delete(&model.CustomHeader{})
-	s.db.Where("host_id = ?", id).Delete(&model.AccessRule{})
-	s.db.Where("host_id = ?", id).Delete(&model.BasicAuth{})
+	// An edit may fix a host left out for breaking the config; try it again.
+	host.ConfigError = ""
 
 	host.Upstreams = nil
 	host.CustomHeaders = nil
@@ -433,28 +724,100 @@ func (s *HostService) Update(id uint, req *model.HostCreateRequest) (*model.Host
 		if err := caddy.ValidateCaddyValue("basicauth username", ba.Username); err != nil {
 			return nil, err
 		}
-		hash, err := bcrypt.GenerateFromPassword([]byte(ba.Password), bcrypt.DefaultCost)
+		if err := caddy.ValidateBasicAuthPath(ba.Path); err != nil {
+			return nil, err
+		}
+		hash, err := basicAuthHash(ba)
 		if err != nil {
 			return nil, fmt.Errorf("failed to hash password for user '%s': %w", ba.Username, err)
 		}
 		host.BasicAuths = append(host.BasicAuths, model.BasicAuth{
 			HostID:       id,
 			Username:     ba.Username,
-			PasswordHash: string(hash),
+			PasswordHash: hash,
+			Path:         ba.Path,
 		})
 	}
 
-	if err := s.db.Save(host).Error; err != nil {
+	// Claim the next version and replace the host with its sub-tables in
+	// one transaction, so a failed write cannot leave the version bumped
+	// over half-replaced upstreams, headers or rules.
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		// The claim fails if another update won the race since the check
+		// above.
+		claim := tx.Model(&model.Host{}).Where("id = ? AND version = ?", id, req.Version).
+			UpdateColumn("version", gorm.Expr("version + 1"))
+		if claim.Error != nil {
+			return claim.Error
+		}
+		if claim.RowsAffected == 0 {
+			return ErrStaleHost
+		}
+		host.Version = req.Version + 1
+		return replaceHostRows(tx, host, req)
+	}); err != nil {
+		if errors.Is(err, ErrStaleHost) {
+			return nil, err
+		}
 		return nil, fmt.Errorf("failed to update host: %w", err)
 	}
 
+	s.applyTagRules(host)
+
+	s.recordRevision(id, req.AuthorID, req.Author)
+
+	result, err := s.ApplyConfig()
+	if errors.Is(err, ErrInvalidGeneratedConfig) && s.flagRejectedHosts(id) {
+		// Another change merged into the same apply broke the config. It
+		// is flagged now, so apply this one again.
+		result, err = s.ApplyConfig()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("host updated but Caddy config failed: %w", err)
+	}
+
+	updated, err := s.getApplied(id, result)
+	if err != nil {
+		return nil, err
+	}
+	updated.Warning = warning
+	return updated, nil
+}
+
+// replaceHostRows saves host and replaces its upstreams, headers, access
+// rules, basic auths and tags with the ones built from req, remapping routes
+// that pointed at a replaced upstream.
+func replaceHostRows(tx *gorm.DB, host *model.Host, req *model.HostCreateRequest) error {
+	id := host.ID
+
+	// Save old upstream IDs before deletion (for route remapping).
+	var oldUpstreams []model.Upstream
+	if err := tx.Where("host_id = ?", id).Order("sort_order ASC").Find(&oldUpstreams).Error; err != nil {
+		return err
+	}
+
+	// Replace associations
+	for _, table := range []interface{}{&model.Upstream{}, &model.CustomHeader{}, &model.AccessRule{}, &model.BasicAuth{}, &model.HostTag{}} {
+		if err := tx.Where("host_id = ?", id).Delete(table).Error; err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Omit(clause.Associations).Save(host).Error; err != nil {
+		return err
+	}
+
 	// Explicitly clear group_id if nil (GORM Save ignores nil pointer fields)
 	if req.GroupID == nil {
-		s.db.Model(&model.Host{}).Where("id = ?", id).Update("group_id", nil)
+		if err := tx.Model(&model.Host{}).Where("id = ?", id).Update("group_id", nil).Error; err != nil {
+			return err
+		}
 	}
 
 	for i := range host.Upstreams {
-		s.db.Create(&host.Upstreams[i])
+		if err := tx.Create(&host.Upstreams[i]).Error; err != nil {
+			return err
+		}
 	}
 
 	// Remap route UpstreamIDs: old upstream at sort_order N → new upstream at sort_order N.
@@ -464,41 +827,48 @@ func (s *HostService) Update(id uint, req *model.HostCreateRequest) (*model.Host
 			oldIDMap[u.ID] = i
 		}
 		var routes []model.Route
-		s.db.Where("host_id = ?", id).Find(&routes)
+		if err := tx.Where("host_id = ?", id).Find(&routes).Error; err != nil {
+			return err
+		}
 		for _, r := range routes {
 			if r.UpstreamID != nil {
 				if idx, ok := oldIDMap[*r.UpstreamID]; ok && idx < len(host.Upstreams) {
 					newID := host.Upstreams[idx].ID
-					s.db.Model(&r).Update("upstream_id", newID)
+					if err := tx.Model(&r).Update("upstream_id", newID).Error; err != nil {
+						return err
+					}
 				}
 			}
 		}
 	}
 
 	for i := range host.CustomHeaders {
-		s.db.Create(&host.CustomHeaders[i])
+		if err := tx.Create(&host.CustomHeaders[i]).Error; err != nil {
+			return err
+		}
 	}
 	for i := range host.AccessRules {
-		s.db.Create(&host.AccessRules[i])
+		if err := tx.Create(&host.AccessRules[i]).Error; err != nil {
+			return err
+		}
 	}
 	for i := range host.BasicAuths {
-		s.db.Create(&host.BasicAuths[i])
+		if err := tx.Create(&host.BasicAuths[i]).Error; err != nil {
+			return err
+		}
 	}
 
 	// Sync tag associations: replace all
-	s.db.Where("host_id = ?", id).Delete(&model.HostTag{})
 	for _, tagID := range req.TagIDs {
-		s.db.Create(&model.HostTag{HostID: id, TagID: tagID})
-	}
-
-	if err := s.ApplyConfig(); err != nil {
-		return nil, fmt.Errorf("host updated but Caddy config failed: %w", err)
+		if err := tx.Create(&model.HostTag{HostID: id, TagID: tagID}).Error; err != nil {
+			return err
+		}
 	}
-
-	return s.Get(id)
+	return nil
 }
 
-// Delete removes a host
+// Delete moves a host to the trash. Its sub-tables are kept so it can be
+// brought back with Restore or removed for good with Purge.
 func (s *HostService) Delete(id uint) error {
 	result := s.db.Delete(&model.Host{}, id)
 	if result.Error != nil {
@@ -508,12 +878,149 @@ func (s *HostService) Delete(id uint) error {
 		return fmt.Errorf("host not found")
 	}
 
-	if err := s.ApplyConfig(); err != nil {
+	if _, err := s.ApplyConfig(); err != nil {
 		return fmt.Errorf("host deleted but Caddy config failed: %w", err)
 	}
 	return nil
 }
 
+// Trash returns the soft-deleted hosts, most recently deleted first.
+func (s *HostService) Trash() ([]model.Host, error) {
+	var hosts []model.Host
+	err := s.db.Unscoped().Preload("Upstreams").Preload("CustomHeaders").Preload("AccessRules").Preload("Routes").Preload("BasicAuths").
+		Preload("Group").Preload("Tags").
+		Where("deleted_at IS NOT NULL").Order("deleted_at DESC").Find(&hosts).Error
+	return hosts, err
+}
+
+// getTrashed loads a host that is in the trash.
+func (s *HostService) getTrashed(id uint) (*model.Host, error) {
+	var host model.Host
+	err := s.db.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", id).First(&host).Error
+	if err != nil {
+		return nil, ErrNotInTrash
+	}
+	return &host, nil
+}
+
+// Restore brings a host back from the trash. It fails if a live host has
+// taken the domain in the meantime.
+func (s *HostService) Restore(id uint) (*model.Host, error) {
+	host, err := s.getTrashed(id)
+	if err != nil {
+		return nil, err
+	}
+
+	taken, err := s.findNameConflict(caddy.HostDomains(*host), id)
+	if err != nil {
+		return nil, err
+	}
+	if taken != "" {
+		return nil, fmt.Errorf("%w: %s", ErrDomainTaken, taken)
+	}
+
+	if err := s.db.Unscoped().Model(&model.Host{}).Where("id = ?", id).Update("deleted_at", nil).Error; err != nil {
+		return nil, fmt.Errorf("failed to restore host: %w", err)
+	}
+
+	if _, err := s.ApplyConfig(); err != nil {
+		return nil, fmt.Errorf("host restored but Caddy config failed: %w", err)
+	}
+	return s.Get(id)
+}
+
+// Purge permanently removes a host from the trash together with all of its
+// sub-table rows, tag associations and revisions.
+func (s *HostService) Purge(id uint) error {
+	if _, err := s.getTrashed(id); err != nil {
+		return err
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		for _, m := range []interface{}{
+			&model.HostTag{}, &model.Route{}, &model.Upstream{},
+			&model.CustomHeader{}, &model.AccessRule{}, &model.BasicAuth{},
+			&model.HostRevision{}, &model.HostSchedule{},
+		} {
+			if err := tx.Where("host_id = ?", id).Delete(m).Error; err != nil {
+				return fmt.Errorf("failed to purge host: %w", err)
+			}
+		}
+		if err := tx.Unscoped().Delete(&model.Host{}, id).Error; err != nil {
+			return fmt.Errorf("failed to purge host: %w", err)
+		}
+		return nil
+	})
+}
+
+// BatchResult is the outcome of a batch action for one host.
+type BatchResult struct {
+	ID    uint   `json:"id"`
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// maxBatchHosts caps the number of hosts a single batch request may touch.
+const maxBatchHosts = 500
+
+// Batch applies action ("enable", "disable" or "delete", which moves hosts
+// to the trash) to each host in ids within one transaction and regenerates
+// the Caddy config once. Hosts
+// that do not exist are reported in their result without failing the rest.
+func (s *HostService) Batch(ids []uint, action string) ([]BatchResult, error) {
+	switch action {
+	case "enable", "disable", "delete":
+	default:
+		return nil, fmt.Errorf("invalid batch action %q", action)
+	}
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("no hosts selected")
+	}
+	if len(ids) > maxBatchHosts {
+		return nil, fmt.Errorf("too many hosts (max %d)", maxBatchHosts)
+	}
+
+	results := make([]BatchResult, 0, len(ids))
+	changed := 0
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		seen := make(map[uint]bool, len(ids))
+		for _, id := range ids {
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+
+			var res *gorm.DB
+			switch action {
+			case "delete":
+				res = tx.Delete(&model.Host{}, id)
+			default:
+				res = tx.Model(&model.Host{}).Where("id = ?", id).Update("enabled", action == "enable")
+			}
+			if res.Error != nil {
+				return res.Error
+			}
+			if res.RowsAffected == 0 {
+				results = append(results, BatchResult{ID: id, Error: "host not found"})
+				continue
+			}
+			results = append(results, BatchResult{ID: id, OK: true})
+			changed++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("batch %s failed: %w", action, err)
+	}
+
+	if changed > 0 {
+		if _, err := s.ApplyConfig(); err != nil {
+			return results, fmt.Errorf("hosts updated but Caddy config failed: %w", err)
+		}
+	}
+	return results, nil
+}
+
 // Toggle enables/disables a host
 func (s *HostService) Toggle(id uint) (*model.Host, error) {
 	host, err := s.Get(id)
@@ -527,27 +1034,90 @@ func (s *HostService) Toggle(id uint) (*model.Host, error) {
 		return nil, err
 	}
 
-	if err := s.ApplyConfig(); err != nil {
+	result, err := s.ApplyConfig()
+	if err != nil {
 		return nil, fmt.Errorf("host toggled but Caddy config failed: %w", err)
 	}
 	// Return a fresh read so all associations and *bool fields are properly loaded.
+	return s.getApplied(id, result)
+}
+
+// SetMaintenance turns a host's maintenance mode on or off. A non-nil page
+// replaces the stored maintenance page.
+func (s *HostService) SetMaintenance(id uint, enabled bool, page *string) (*model.Host, error) {
+	host, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := map[string]interface{}{"maintenance_mode": enabled}
+	if page != nil {
+		if err := caddy.ValidateMaintenancePage(*page); err != nil {
+			return nil, err
+		}
+		updates["maintenance_page"] = *page
+	}
+	if err := s.db.Model(host).Updates(updates).Error; err != nil {
+		return nil, err
+	}
+
+	if _, err := s.ApplyConfig(); err != nil {
+		return nil, fmt.Errorf("maintenance mode updated but Caddy config failed: %w", err)
+	}
 	return s.Get(id)
 }
 
-// ApplyConfig regenerates the Caddyfile and reloads Caddy
-func (s *HostService) ApplyConfig() error {
-	hosts, err := s.List()
+// writeConfig regenerates the Caddyfile from the current DB state and
+// reloads Caddy. Hosts flagged with a config error are left out until they
+// are edited. Callers go through ApplyConfig or FlushConfig.
+func (s *HostService) writeConfig() (model.ApplyResult, error) {
+	hosts, dnsMap, err := s.renderInputs()
 	if err != nil {
-		return fmt.Errorf("failed to list hosts: %w", err)
+		return model.ApplyResult{}, err
 	}
+	return s.installConfig(caddy.RenderCaddyfile(unflaggedHosts(hosts), s.cfg, dnsMap))
+}
 
-	// Preload DNS providers for TLS rendering
+// unflaggedHosts returns the hosts without a config error, the ones a
+// Caddyfile is rendered from.
+func unflaggedHosts(hosts []model.Host) []model.Host {
+	var rendered []model.Host
+	for _, h := range hosts {
+		if h.ConfigError == "" {
+			rendered = append(rendered, h)
+		}
+	}
+	return rendered
+}
+
+// dnsProviders loads the DNS providers by ID with their configs decrypted
+// for rendering. A config that fails to decrypt is left empty, so its hosts
+// render without a DNS challenge.
+func (s *HostService) dnsProviders() map[uint]model.DnsProvider {
 	var providers []model.DnsProvider
 	s.db.Find(&providers)
 	dnsMap := make(map[uint]model.DnsProvider, len(providers))
 	for _, p := range providers {
+		plain, err := decryptDnsConfig(p.Config, s.cfg.JWTSecret)
+		if err != nil {
+			log.Printf("Warning: failed to decrypt DNS provider %s: %v", p.Name, err)
+		}
+		p.Config = plain
 		dnsMap[p.ID] = p
 	}
+	return dnsMap
+}
+
+// renderInputs loads the live hosts, with certificate paths resolved, and
+// the DNS providers they may reference.
+func (s *HostService) renderInputs() ([]model.Host, map[uint]model.DnsProvider, error) {
+	hosts, err := s.List()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list hosts: %w", err)
+	}
+
+	// Preload DNS providers for TLS rendering
+	dnsMap := s.dnsProviders()
 
 	// Resolve CertificateID → CustomCertPath/CustomKeyPath
 	var certs []model.Certificate
@@ -564,15 +1134,27 @@ func (s *HostService) ApplyConfig() error {
 			}
 		}
 	}
+	return hosts, dnsMap, nil
+}
 
-	content := caddy.RenderCaddyfile(hosts, s.cfg, dnsMap)
+// installConfig writes content as the live Caddyfile and reloads (or starts)
+// Caddy, rolling the file back if the reload fails.
+func (s *HostService) installConfig(content string) (model.ApplyResult, error) {
+	var result model.ApplyResult
 
 	// Read old Caddyfile for rollback if reload fails.
 	oldContent, _ := s.caddyMgr.GetCaddyfileContent()
 
+	// WriteCaddyfile validates before replacing the live file, so a bad host
+	// cannot take the other sites down with it.
 	if err := s.caddyMgr.WriteCaddyfile(content); err != nil {
-		return fmt.Errorf("failed to write Caddyfile: %w", err)
+		if errors.Is(err, caddy.ErrInvalidCaddyfile) {
+			result.ValidationError = err.Error()
+			return result, fmt.Errorf("%w: %v", ErrInvalidGeneratedConfig, err)
+		}
+		return result, fmt.Errorf("failed to write Caddyfile: %w", err)
 	}
+	result.Written = true
 
 	// Check auto_reload setting
 	var setting model.Setting
@@ -584,26 +1166,33 @@ func (s *HostService) ApplyConfig() error {
 	if autoReload {
 		if s.caddyMgr.IsRunning() {
 			if err := s.caddyMgr.RequestReload(); err != nil {
+				result.ReloadError = err.Error()
 				// Rollback: restore old Caddyfile so Caddy stays on the last known-good config.
 				if oldContent != "" {
 					if wErr := s.caddyMgr.WriteCaddyfile(oldContent); wErr != nil {
 						log.Printf("CRITICAL: failed to rollback Caddyfile: %v", wErr)
+					} else {
+						result.Written = false
 					}
 				}
-				return fmt.Errorf("failed to reload Caddy (config rolled back): %w", err)
+				return result, fmt.Errorf("failed to reload Caddy (config rolled back): %w", err)
 			}
+			result.Reloaded = true
 			log.Println("Caddy reloaded after config change")
 		} else {
 			if err := s.caddyMgr.Start(); err != nil {
 				log.Printf("⚠️  Failed to auto-start Caddy: %v", err)
-				// Don't return error — config was written successfully
+				// Don't return error — config was written successfully,
+				// the result tells the caller Caddy is not serving it.
+				result.ReloadError = fmt.Sprintf("failed to start Caddy: %v", err)
 			} else {
+				result.Reloaded = true
 				log.Println("Caddy auto-started after config change")
 			}
 		}
 	}
 
-	return nil
+	return result, nil
 }
 
 // UpdateCertPaths updates the custom certificate paths for a host
@@ -617,7 +1206,8 @@ func (s *HostService) UpdateCertPaths(id uint, certPath, keyPath string) error {
 	if err := s.db.Save(host).Error; err != nil {
 		return err
 	}
-	return s.ApplyConfig()
+	_, err = s.ApplyConfig()
+	return err
 }
 
 // ExportAll returns all hosts for export
@@ -636,10 +1226,40 @@ func (s *HostService) ExportAll() (*model.ExportData, error) {
 // ImportAll replaces all hosts with imported data
 func (s *HostService) ImportAll(data *model.ExportData) error {
 	// Validate ALL imported hosts before deleting anything.
-	for _, host := range data.Hosts {
-		if err := caddy.ValidateDomain(host.Domain); err != nil {
+	if err := validateImportHosts(data.Hosts); err != nil {
+		return err
+	}
+
+	// Wrap the entire delete + insert in a transaction so a mid-import
+	// failure doesn't leave the system with no hosts at all.
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		return replaceHosts(tx, data.Hosts, nil)
+	}); err != nil {
+		return err
+	}
+
+	_, err := s.ApplyConfig()
+	return err
+}
+
+// validateImportHosts checks every imported host the way create and update
+// would, normalizing each host's domain and aliases in place.
+func validateImportHosts(hosts []model.Host) error {
+	for i := range hosts {
+		host := &hosts[i]
+		domain, err := caddy.NormalizeDomain(host.Domain)
+		if err != nil {
+			return fmt.Errorf("import validation failed for '%s': %w", host.Domain, err)
+		}
+		if err := caddy.ValidateDomain(domain); err != nil {
+			return fmt.Errorf("import validation failed for '%s': %w", host.Domain, err)
+		}
+		aliases, err := normalizeAliases(domain, host.Aliases)
+		if err != nil {
 			return fmt.Errorf("import validation failed for '%s': %w", host.Domain, err)
 		}
+		host.Domain, host.Aliases = domain, aliases
+		host.DisplayDomain = caddy.DisplayDomain(domain)
 		for _, u := range host.Upstreams {
 			if err := caddy.ValidateUpstream(u.Address); err != nil {
 				return fmt.Errorf("import validation failed for upstream '%s' on '%s': %w", u.Address, host.Domain, err)
@@ -653,12 +1273,40 @@ func (s *HostService) ImportAll(data *model.ExportData) error {
 		if err := caddy.SanitizeCustomDirectives(host.CustomDirectives); err != nil {
 			return fmt.Errorf("import validation failed for custom directives on '%s': %w", host.Domain, err)
 		}
+		if err := caddy.ValidateForwardAuthURL(host.ForwardAuthURL); err != nil {
+			return fmt.Errorf("import validation failed for '%s': %w", host.Domain, err)
+		}
+		if err := caddy.ValidateHeaderList("forward_auth_copy_headers", host.ForwardAuthCopyHeaders); err != nil {
+			return fmt.Errorf("import validation failed for '%s': %w", host.Domain, err)
+		}
+		if err := caddy.ValidateSecurityHeadersConfig(host.SecurityHeadersConfig); err != nil {
+			return fmt.Errorf("import validation failed for '%s': %w", host.Domain, err)
+		}
+		if err := caddy.ValidateCors(host.CorsOrigins, boolVal(host.CorsAllowCredentials), host.CorsMaxAge); err != nil {
+			return fmt.Errorf("import validation failed for '%s': %w", host.Domain, err)
+		}
+		if _, err := caddy.NormalizeCompressionAlgorithms(host.CompressionAlgorithms); err != nil {
+			return fmt.Errorf("import validation failed for '%s': %w", host.Domain, err)
+		}
+		if _, err := caddy.NormalizeACMECA(host.ACMECA); err != nil {
+			return fmt.Errorf("import validation failed for '%s': %w", host.Domain, err)
+		}
+		if err := caddy.ValidatePHPFastCGI(host.PHPFastCGI); err != nil {
+			return fmt.Errorf("import validation failed for '%s': %w", host.Domain, err)
+		}
+		if err := caddy.ValidatePHPVersion(host.PHPVersion); err != nil {
+			return fmt.Errorf("import validation failed for '%s': %w", host.Domain, err)
+		}
+		if boolVal(host.SPAFallback) && host.HostType != "static" {
+			return fmt.Errorf("import validation failed for '%s': spa_fallback is only supported for static hosts", host.Domain)
+		}
 		// Validate all Caddyfile-embedded string fields.
 		for label, val := range map[string]string{
 			"redirect_url": host.RedirectURL, "root_path": host.RootPath,
 			"error_page_path": host.ErrorPagePath, "php_fastcgi": host.PHPFastCGI,
 			"index_files": host.IndexFiles, "cors_origins": host.CorsOrigins,
 			"cors_methods": host.CorsMethods, "cors_headers": host.CorsHeaders,
+			"basic_auth_realm": host.BasicAuthRealm,
 		} {
 			if err := caddy.ValidateCaddyValue(label, val); err != nil {
 				return fmt.Errorf("import validation failed for %s on '%s': %w", label, host.Domain, err)
@@ -677,113 +1325,160 @@ func (s *HostService) ImportAll(data *model.ExportData) error {
 				return fmt.Errorf("import validation failed for route on '%s': %w", host.Domain, err)
 			}
 		}
+		for _, ba := range host.BasicAuths {
+			if err := caddy.ValidateBasicAuthPath(ba.Path); err != nil {
+				return fmt.Errorf("import validation failed for basic auth on '%s': %w", host.Domain, err)
+			}
+		}
 	}
+	return nil
+}
 
-	// Wrap the entire delete + insert in a transaction so a mid-import
-	// failure doesn't leave the system with no hosts at all.
-	if err := s.db.Transaction(func(tx *gorm.DB) error {
-		tx.Exec("DELETE FROM host_tags")
-		tx.Exec("DELETE FROM basic_auths")
-		tx.Exec("DELETE FROM access_rules")
-		tx.Exec("DELETE FROM custom_headers")
-		tx.Exec("DELETE FROM routes")
-		tx.Exec("DELETE FROM upstreams")
-		tx.Exec("DELETE FROM hosts")
-
-		for _, host := range data.Hosts {
-			// Save original upstream IDs for route remapping.
-			origUpstreams := make([]model.Upstream, len(host.Upstreams))
-			copy(origUpstreams, host.Upstreams)
-
-			// Detach routes and tags — we'll insert them separately.
-			routes := host.Routes
-			host.Routes = nil
-			tags := host.Tags
-			host.Tags = nil
-
-			host.ID = 0
-			for i := range host.Upstreams {
-				host.Upstreams[i].ID = 0
-				host.Upstreams[i].HostID = 0
-			}
-			for i := range host.CustomHeaders {
-				host.CustomHeaders[i].ID = 0
-				host.CustomHeaders[i].HostID = 0
-			}
-			for i := range host.AccessRules {
-				host.AccessRules[i].ID = 0
-				host.AccessRules[i].HostID = 0
-			}
-			for i := range host.BasicAuths {
-				host.BasicAuths[i].ID = 0
-				host.BasicAuths[i].HostID = 0
-			}
+// importRefs maps the IDs a host export refers to onto the rows created for
+// them by a full configuration import. A nil map keeps the IDs as they are.
+type importRefs struct {
+	groups       map[uint]uint
+	dnsProviders map[uint]uint
+	certificates map[uint]uint
+}
 
-			if err := tx.Create(&host).Error; err != nil {
-				return fmt.Errorf("failed to import host %s: %w", host.Domain, err)
-			}
+// remap returns the new ID for old in ids, nil when it was not imported.
+func remap(ids map[uint]uint, old *uint) *uint {
+	if ids == nil || old == nil {
+		return old
+	}
+	if id, ok := ids[*old]; ok {
+		return &id
+	}
+	return nil
+}
 
-			// Rebuild tag associations: look up each tag by name, create if missing.
-			for _, tag := range tags {
-				var existing model.Tag
-				if err := tx.Where("name = ?", tag.Name).First(&existing).Error; err != nil {
-					// Tag doesn't exist — create it.
-					existing = model.Tag{Name: tag.Name, Color: tag.Color}
-					if err := tx.Create(&existing).Error; err != nil {
-						return fmt.Errorf("failed to create tag %s: %w", tag.Name, err)
-					}
-				}
-				if err := tx.Exec("INSERT INTO host_tags (host_id, tag_id) VALUES (?, ?)", host.ID, existing.ID).Error; err != nil {
-					return fmt.Errorf("failed to associate tag %s with host %s: %w", tag.Name, host.Domain, err)
+// replaceHosts deletes every host in tx and inserts hosts, rebuilding tag
+// associations by name and route upstream references.
+func replaceHosts(tx *gorm.DB, hosts []model.Host, refs *importRefs) error {
+	tx.Exec("DELETE FROM host_tags")
+	tx.Exec("DELETE FROM host_revisions")
+	tx.Exec("DELETE FROM basic_auths")
+	tx.Exec("DELETE FROM access_rules")
+	tx.Exec("DELETE FROM custom_headers")
+	tx.Exec("DELETE FROM routes")
+	tx.Exec("DELETE FROM upstreams")
+	tx.Exec("DELETE FROM hosts")
+
+	for _, host := range hosts {
+		// Save original upstream IDs for route remapping.
+		origUpstreams := make([]model.Upstream, len(host.Upstreams))
+		copy(origUpstreams, host.Upstreams)
+
+		// Detach routes and tags — we'll insert them separately.
+		routes := host.Routes
+		host.Routes = nil
+		tags := host.Tags
+		host.Tags = nil
+
+		host.ID = 0
+		if refs != nil {
+			host.Group = nil
+			host.GroupID = remap(refs.groups, host.GroupID)
+			host.DnsProviderID = remap(refs.dnsProviders, host.DnsProviderID)
+			host.CertificateID = remap(refs.certificates, host.CertificateID)
+		}
+		for i := range host.Upstreams {
+			host.Upstreams[i].ID = 0
+			host.Upstreams[i].HostID = 0
+		}
+		for i := range host.CustomHeaders {
+			host.CustomHeaders[i].ID = 0
+			host.CustomHeaders[i].HostID = 0
+		}
+		for i := range host.AccessRules {
+			host.AccessRules[i].ID = 0
+			host.AccessRules[i].HostID = 0
+		}
+		for i := range host.BasicAuths {
+			host.BasicAuths[i].ID = 0
+			host.BasicAuths[i].HostID = 0
+		}
+
+		if err := tx.Create(&host).Error; err != nil {
+			return fmt.Errorf("failed to import host %s: %w", host.Domain, err)
+		}
+
+		// Rebuild tag associations: look up each tag by name, create if missing.
+		for _, tag := range tags {
+			var existing model.Tag
+			if err := tx.Where("name = ?", tag.Name).First(&existing).Error; err != nil {
+				// Tag doesn't exist — create it.
+				existing = model.Tag{Name: tag.Name, Color: tag.Color}
+				if err := tx.Create(&existing).Error; err != nil {
+					return fmt.Errorf("failed to create tag %s: %w", tag.Name, err)
 				}
 			}
+			if err := tx.Exec("INSERT INTO host_tags (host_id, tag_id) VALUES (?, ?)", host.ID, existing.ID).Error; err != nil {
+				return fmt.Errorf("failed to associate tag %s with host %s: %w", tag.Name, host.Domain, err)
+			}
+		}
 
-			// Build old→new upstream ID mapping.
-			upstreamIDMap := make(map[uint]uint)
-			for i, orig := range origUpstreams {
-				if i < len(host.Upstreams) {
-					upstreamIDMap[orig.ID] = host.Upstreams[i].ID
-				}
+		// Build old→new upstream ID mapping.
+		upstreamIDMap := make(map[uint]uint)
+		for i, orig := range origUpstreams {
+			if i < len(host.Upstreams) {
+				upstreamIDMap[orig.ID] = host.Upstreams[i].ID
 			}
+		}
 
-			// Insert routes with remapped UpstreamIDs.
-			for _, r := range routes {
-				r.ID = 0
-				r.HostID = host.ID
-				if r.UpstreamID != nil {
-					if newID, ok := upstreamIDMap[*r.UpstreamID]; ok {
-						r.UpstreamID = &newID
-					} else {
-						r.UpstreamID = nil // orphan reference — clear it
-					}
-				}
-				if err := tx.Create(&r).Error; err != nil {
-					return fmt.Errorf("failed to import route for %s: %w", host.Domain, err)
+		// Insert routes with remapped UpstreamIDs.
+		for _, r := range routes {
+			r.ID = 0
+			r.HostID = host.ID
+			if r.UpstreamID != nil {
+				if newID, ok := upstreamIDMap[*r.UpstreamID]; ok {
+					r.UpstreamID = &newID
+				} else {
+					r.UpstreamID = nil // orphan reference — clear it
 				}
 			}
+			if err := tx.Create(&r).Error; err != nil {
+				return fmt.Errorf("failed to import route for %s: %w", host.Domain, err)
+			}
 		}
-		return nil
-	}); err != nil {
-		return err
 	}
+	return nil
+}
 
-	return s.ApplyConfig()
+// CloneOptions files a clone on creation. A nil GroupID or TagIDs copies the
+// source's group or tags; a GroupID of 0 leaves the clone ungrouped and an
+// empty (non-nil) TagIDs leaves it untagged.
+type CloneOptions struct {
+	GroupID *uint
+	TagIDs  []uint
 }
+
 // CloneHost creates a deep copy of an existing host with a new domain.
-// It copies all main table fields (except ID, Domain, CreatedAt, UpdatedAt)
+// It copies all main table fields (except ID, Domain, Aliases, CreatedAt, UpdatedAt)
 // and all sub-table records (upstreams, custom_headers, access_rules, basic_auths, routes).
-func (s *HostService) CloneHost(sourceID uint, newDomain string) (*model.Host, error) {
+// Group and tags follow the source unless opts overrides them.
+func (s *HostService) CloneHost(sourceID uint, newDomain string, opts CloneOptions) (*model.Host, error) {
+	newDomain, err := caddy.NormalizeDomain(newDomain)
+	if err != nil {
+		return nil, fmt.Errorf("invalid domain: %w", err)
+	}
 	// Validate domain for Caddyfile safety.
 	if err := caddy.ValidateDomain(newDomain); err != nil {
 		return nil, fmt.Errorf("invalid domain: %w", err)
 	}
 
-	// Domain uniqueness check
-	var count int64
-	s.db.Model(&model.Host{}).Where("domain = ?", newDomain).Count(&count)
-	if count > 0 {
+	// Domain uniqueness check (against primary domains and aliases)
+	taken, err := s.findNameConflict([]string{newDomain}, 0)
+	if err != nil {
+		return nil, err
+	}
+	if taken != "" {
 		return nil, fmt.Errorf("error.domain_exists")
 	}
+	if _, err := s.checkDomainOverlap([]string{newDomain}, 0); err != nil {
+		return nil, err
+	}
 
 	// Fetch source host with all associations
 	source, err := s.Get(sourceID)
@@ -796,34 +1491,53 @@ func (s *HostService) CloneHost(sourceID uint, newDomain string) (*model.Host, e
 	txErr := s.db.Transaction(func(tx *gorm.DB) error {
 		// Deep copy main table fields
 		newHost = &model.Host{
-			Domain:           newDomain,
-			HostType:         source.HostType,
-			Enabled:          copyBoolPtr(source.Enabled),
-			TLSEnabled:       copyBoolPtr(source.TLSEnabled),
-			HTTPRedirect:     copyBoolPtr(source.HTTPRedirect),
-			WebSocket:        copyBoolPtr(source.WebSocket),
-			RedirectURL:      source.RedirectURL,
-			RedirectCode:     source.RedirectCode,
-			CustomCertPath:   source.CustomCertPath,
-			CustomKeyPath:    source.CustomKeyPath,
-			TLSMode:          source.TLSMode,
-			DnsProviderID:    source.DnsProviderID,
-			CertificateID:    source.CertificateID,
-			Compression:      copyBoolPtr(source.Compression),
-			CacheEnabled:     copyBoolPtr(source.CacheEnabled),
-			CacheTTL:         source.CacheTTL,
-			CorsEnabled:      copyBoolPtr(source.CorsEnabled),
-			CorsOrigins:      source.CorsOrigins,
-			CorsMethods:      source.CorsMethods,
-			CorsHeaders:      source.CorsHeaders,
-			SecurityHeaders:  copyBoolPtr(source.SecurityHeaders),
-			ErrorPagePath:    source.ErrorPagePath,
-			CustomDirectives: source.CustomDirectives,
-			RootPath:         source.RootPath,
-			DirectoryBrowse:  copyBoolPtr(source.DirectoryBrowse),
-			PHPFastCGI:       source.PHPFastCGI,
-			IndexFiles:       source.IndexFiles,
-			GroupID:          source.GroupID,
+			Domain:                 newDomain,
+			DisplayDomain:          caddy.DisplayDomain(newDomain),
+			HostType:               source.HostType,
+			BasicAuthRealm:         source.BasicAuthRealm,
+			ForwardAuthURL:         source.ForwardAuthURL,
+			ForwardAuthCopyHeaders: source.ForwardAuthCopyHeaders,
+			SecurityHeadersConfig:  copySecurityHeadersConfig(source.SecurityHeadersConfig),
+			CorsAllowCredentials:   copyBoolPtr(source.CorsAllowCredentials),
+			CorsMaxAge:             source.CorsMaxAge,
+			CompressionAlgorithms:  source.CompressionAlgorithms,
+			ACMECA:                 source.ACMECA,
+			Enabled:                copyBoolPtr(source.Enabled),
+			TLSEnabled:             copyBoolPtr(source.TLSEnabled),
+			HTTPRedirect:           copyBoolPtr(source.HTTPRedirect),
+			WebSocket:              copyBoolPtr(source.WebSocket),
+			RedirectURL:            source.RedirectURL,
+			RedirectCode:           source.RedirectCode,
+			CustomCertPath:         source.CustomCertPath,
+			CustomKeyPath:          source.CustomKeyPath,
+			TLSMode:                source.TLSMode,
+			DnsProviderID:          source.DnsProviderID,
+			CertificateID:          source.CertificateID,
+			Compression:            copyBoolPtr(source.Compression),
+			CacheEnabled:           copyBoolPtr(source.CacheEnabled),
+			CacheTTL:               source.CacheTTL,
+			CorsEnabled:            copyBoolPtr(source.CorsEnabled),
+			CorsOrigins:            source.CorsOrigins,
+			CorsMethods:            source.CorsMethods,
+			CorsHeaders:            source.CorsHeaders,
+			SecurityHeaders:        copyBoolPtr(source.SecurityHeaders),
+			ErrorPagePath:          source.ErrorPagePath,
+			CustomDirectives:       source.CustomDirectives,
+			RootPath:               source.RootPath,
+			DirectoryBrowse:        copyBoolPtr(source.DirectoryBrowse),
+			SPAFallback:            copyBoolPtr(source.SPAFallback),
+			PHPFastCGI:             source.PHPFastCGI,
+			PHPVersion:             source.PHPVersion,
+			IndexFiles:             source.IndexFiles,
+			SeparateAccessLog:      copyBoolPtr(source.SeparateAccessLog),
+			MaxRequestBodySize:     source.MaxRequestBodySize,
+			ProxyReadTimeout:       source.ProxyReadTimeout,
+			ProxyWriteTimeout:      source.ProxyWriteTimeout,
+			ProxyDialTimeout:       source.ProxyDialTimeout,
+			GroupID:                source.GroupID,
+		}
+		if opts.GroupID != nil {
+			newHost.GroupID = uintPtrOrNil(opts.GroupID)
 		}
 
 		// Deep copy upstreams first (routes reference them by ID).
@@ -857,6 +1571,7 @@ func (s *HostService) CloneHost(sourceID uint, newDomain string) (*model.Host, e
 			newHost.BasicAuths = append(newHost.BasicAuths, model.BasicAuth{
 				Username:     ba.Username,
 				PasswordHash: ba.PasswordHash,
+				Path:         ba.Path,
 			})
 		}
 
@@ -891,9 +1606,15 @@ func (s *HostService) CloneHost(sourceID uint, newDomain string) (*model.Host, e
 			}
 		}
 
-		// Copy tag associations.
-		for _, tag := range source.Tags {
-			if err := tx.Create(&model.HostTag{HostID: newHost.ID, TagID: tag.ID}).Error; err != nil {
+		// Copy tag associations unless the caller chose the tags.
+		tagIDs := opts.TagIDs
+		if tagIDs == nil {
+			for _, tag := range source.Tags {
+				tagIDs = append(tagIDs, tag.ID)
+			}
+		}
+		for _, tagID := range tagIDs {
+			if err := tx.Create(&model.HostTag{HostID: newHost.ID, TagID: tagID}).Error; err != nil {
 				return fmt.Errorf("failed to clone tag: %w", err)
 			}
 		}
@@ -904,9 +1625,10 @@ func (s *HostService) CloneHost(sourceID uint, newDomain string) (*model.Host, e
 	if txErr != nil {
 		return nil, txErr
 	}
+	s.recordRevision(newHost.ID, 0, "")
 
 	// Apply config after successful clone
-	if err := s.ApplyConfig(); err != nil {
+	if _, err := s.ApplyConfig(); err != nil {
 		log.Printf("Warning: failed to apply config after clone: %v", err)
 	}
 
@@ -914,6 +1636,25 @@ func (s *HostService) CloneHost(sourceID uint, newDomain string) (*model.Host, e
 	return s.Get(newHost.ID)
 }
 
+// basicAuthHash returns the bcrypt hash for a credential, reusing
+// PasswordHash when the input already carries one.
+func basicAuthHash(ba model.BasicAuthInput) (string, error) {
+	if ba.PasswordHash != "" {
+		return ba.PasswordHash, nil
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(ba.Password), bcrypt.DefaultCost)
+	return string(hash), err
+}
+
+// copySecurityHeadersConfig returns a copy of cfg so hosts never share one.
+func copySecurityHeadersConfig(cfg *model.SecurityHeadersConfig) *model.SecurityHeadersConfig {
+	if cfg == nil {
+		return nil
+	}
+	c := *cfg
+	return &c
+}
+
 func boolOrDefault(ptr *bool, defaultVal bool) bool {
 	if ptr != nil {
 		return *ptr
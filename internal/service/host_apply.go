@@ -0,0 +1,100 @@
+package service
+
+import (
+	"sync"
+	"time"
+
+	"github.com/web-casa/webcasa/internal/model"
+)
+
+// configApplier coalesces config applies. Requests arriving within delay of
+// the first pending one share a single run, and every run starts after the
+// requests it answers were made, so it renders the latest DB state. The
+// run's error belongs to the whole batch: it does not say which request's
+// change caused it.
+type configApplier struct {
+	delay time.Duration
+	apply func() (model.ApplyResult, error)
+
+	runMu sync.Mutex // serialises runs so two never write the Caddyfile at once
+
+	mu      sync.Mutex
+	timer   *time.Timer
+	waiters []chan applyOutcome
+}
+
+// applyOutcome is the result of a run handed to each request it answers.
+type applyOutcome struct {
+	result model.ApplyResult
+	err    error
+}
+
+// request schedules a run and waits for its result, which is shared by
+// every request the run answers. With no delay it runs immediately.
+func (a *configApplier) request() (model.ApplyResult, error) {
+	if a.delay <= 0 {
+		return a.run(nil)
+	}
+
+	waiter := make(chan applyOutcome, 1)
+	a.mu.Lock()
+	a.waiters = append(a.waiters, waiter)
+	if a.timer == nil {
+		a.timer = time.AfterFunc(a.delay, func() {
+			if waiters := a.takeWaiters(); len(waiters) > 0 {
+				a.run(waiters)
+			}
+		})
+	}
+	a.mu.Unlock()
+	out := <-waiter
+	return out.result, out.err
+}
+
+// flush runs now, answering any pending requests with its result.
+func (a *configApplier) flush() (model.ApplyResult, error) {
+	return a.run(a.takeWaiters())
+}
+
+// takeWaiters detaches the pending requests and cancels their timer. A timer
+// that fired after a flush took its waiters finds none and does not run.
+func (a *configApplier) takeWaiters() []chan applyOutcome {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.timer != nil {
+		a.timer.Stop()
+		a.timer = nil
+	}
+	waiters := a.waiters
+	a.waiters = nil
+	return waiters
+}
+
+func (a *configApplier) run(waiters []chan applyOutcome) (model.ApplyResult, error) {
+	a.runMu.Lock()
+	result, err := a.apply()
+	a.runMu.Unlock()
+	for _, w := range waiters {
+		w <- applyOutcome{result, err}
+	}
+	return result, err
+}
+
+// ApplyConfig regenerates the Caddyfile and reloads Caddy. When an apply
+// debounce is configured, calls within the window are coalesced into one
+// reload; each call still returns that reload's result. The ApplyResult
+// says whether the Caddyfile was written and Caddy picked it up, which is
+// also reported when the error is nil (auto-reload off, a failed auto-start).
+//
+// The error may come from another change merged into the same reload, so
+// callers acting on it for one host must check that host themselves, as
+// Create and Update do through flagRejectedHosts.
+func (s *HostService) ApplyConfig() (model.ApplyResult, error) {
+	return s.applier.request()
+}
+
+// FlushConfig applies the config immediately, including any debounced
+// requests still waiting.
+func (s *HostService) FlushConfig() (model.ApplyResult, error) {
+	return s.applier.flush()
+}
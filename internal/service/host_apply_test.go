@@ -0,0 +1,235 @@
+package service
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/web-casa/webcasa/internal/model"
+	"gorm.io/gorm"
+)
+
+// countApplies wraps the service's config writer with a counter.
+func countApplies(svc *HostService, delay time.Duration) *int32 {
+	var n int32
+	write := svc.writeConfig
+	svc.applier.delay = delay
+	svc.applier.apply = func() (model.ApplyResult, error) {
+		atomic.AddInt32(&n, 1)
+		return write()
+	}
+	return &n
+}
+
+func TestApplyConfig_DebouncesRapidChanges(t *testing.T) {
+	db := setupTestDB(t)
+	svc := setupTestHostService(t, db)
+	var ids []uint
+	for _, d := range []string{"a.example.com", "b.example.com", "c.example.com"} {
+		ids = append(ids, createTestHost(t, svc, d, 1, 0, 0, 0, 0).ID)
+	}
+	applies := countApplies(svc, 500*time.Millisecond)
+
+	errs := make(chan error, len(ids))
+	for _, id := range ids {
+		db.Model(&model.Host{}).Where("id = ?", id).Update("enabled", false)
+		go func() {
+			_, err := svc.ApplyConfig()
+			errs <- err
+		}()
+	}
+	for range ids {
+		if err := <-errs; err != nil {
+			t.Fatalf("ApplyConfig: %v", err)
+		}
+	}
+
+	if n := atomic.LoadInt32(applies); n != 1 {
+		t.Errorf("%d config applies for %d rapid changes, want 1", n, len(ids))
+	}
+	content, _ := os.ReadFile(svc.cfg.CaddyfilePath)
+	if strings.Contains(string(content), ".example.com {") {
+		t.Errorf("Caddyfile does not reflect the last change (all hosts disabled):\n%s", content)
+	}
+}
+
+func TestApplyConfig_MergedRequestsShareError(t *testing.T) {
+	db := setupTestDB(t)
+	svc := setupTestHostService(t, db)
+	var runs int32
+	svc.applier.delay = 200 * time.Millisecond
+	svc.applier.apply = func() (model.ApplyResult, error) {
+		atomic.AddInt32(&runs, 1)
+		return model.ApplyResult{}, ErrInvalidGeneratedConfig
+	}
+
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			_, err := svc.ApplyConfig()
+			errs <- err
+		}()
+	}
+	for i := 0; i < 2; i++ {
+		if err := <-errs; !errors.Is(err, ErrInvalidGeneratedConfig) {
+			t.Errorf("merged request %d: err = %v, want the batch error", i, err)
+		}
+	}
+	if n := atomic.LoadInt32(&runs); n != 1 {
+		t.Errorf("%d runs, want 1", n)
+	}
+}
+
+func TestFlushConfig_AnswersPendingRequests(t *testing.T) {
+	db := setupTestDB(t)
+	svc := setupTestHostService(t, db)
+	host := createTestHost(t, svc, "flush.example.com", 1, 0, 0, 0, 0)
+	applies := countApplies(svc, time.Hour)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := svc.ApplyConfig()
+		done <- err
+	}()
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(5 * time.Millisecond) {
+		svc.applier.mu.Lock()
+		pending := len(svc.applier.waiters)
+		svc.applier.mu.Unlock()
+		if pending == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("ApplyConfig never queued")
+		}
+	}
+
+	db.Model(&model.Host{}).Where("id = ?", host.ID).Update("domain", "flushed.example.com")
+	if _, err := svc.FlushConfig(); err != nil {
+		t.Fatalf("FlushConfig: %v", err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("pending ApplyConfig: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("pending ApplyConfig not answered by FlushConfig")
+	}
+
+	if n := atomic.LoadInt32(applies); n != 1 {
+		t.Errorf("%d config applies, want 1", n)
+	}
+	content, _ := os.ReadFile(svc.cfg.CaddyfilePath)
+	if !strings.Contains(string(content), "flushed.example.com {") {
+		t.Errorf("flushed Caddyfile missing latest domain:\n%s", content)
+	}
+}
+
+func TestApplyConfig_NoDebounceAppliesEachCall(t *testing.T) {
+	db := setupTestDB(t)
+	svc := setupTestHostService(t, db)
+	applies := countApplies(svc, 0)
+
+	for i := 0; i < 3; i++ {
+		if _, err := svc.ApplyConfig(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := atomic.LoadInt32(applies); n != 3 {
+		t.Errorf("%d applies without debounce, want 3", n)
+	}
+}
+
+// useFailingCaddy simulates a Caddy whose reload and start commands fail
+// and turns auto_reload on. When running, its admin API answers 200.
+func useFailingCaddy(t *testing.T, db *gorm.DB, svc *HostService, running bool) {
+	t.Helper()
+	script := filepath.Join(t.TempDir(), "caddy")
+	body := "#!/bin/sh\n" +
+		"if [ \"$1\" = reload ] || [ \"$1\" = start ]; then\n" +
+		"  echo 'Error: loading new config: listen tcp :443: bind: address already in use'\n" +
+		"  exit 1\n" +
+		"fi\n" +
+		"exit 0\n"
+	if err := os.WriteFile(script, []byte(body), 0755); err != nil {
+		t.Fatal(err)
+	}
+	svc.cfg.CaddyBin = script
+
+	admin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	t.Cleanup(admin.Close)
+	if !running {
+		admin.Close()
+	}
+	svc.cfg.AdminAPI = admin.URL
+	db.Model(&model.Setting{}).Where("key = ?", "auto_reload").Update("value", "true")
+}
+
+func TestApplyConfig_ReportsReloadFailure(t *testing.T) {
+	db := setupTestDB(t)
+	svc := setupTestHostService(t, db)
+	host := createTestHost(t, svc, "reload.example.com", 1, 0, 0, 0, 0)
+	before, _ := os.ReadFile(svc.cfg.CaddyfilePath)
+	useFailingCaddy(t, db, svc, true)
+
+	db.Model(&model.Host{}).Where("id = ?", host.ID).Update("domain", "changed.example.com")
+	result, err := svc.ApplyConfig()
+	if err == nil {
+		t.Fatal("ApplyConfig succeeded although the reload failed")
+	}
+	if result.Written || result.Reloaded {
+		t.Errorf("result = %+v, want neither written nor reloaded after a rollback", result)
+	}
+	if !strings.Contains(result.ReloadError, "address already in use") {
+		t.Errorf("ReloadError = %q, want the reload output", result.ReloadError)
+	}
+	if after, _ := os.ReadFile(svc.cfg.CaddyfilePath); string(after) != string(before) {
+		t.Errorf("Caddyfile not rolled back after the failed reload:\n%s", after)
+	}
+
+	// Toggle still surfaces the failure as an error
+	if _, err := svc.Toggle(host.ID); err == nil {
+		t.Error("Toggle succeeded although the reload failed")
+	}
+}
+
+func TestApplyConfig_ReportsFailedStart(t *testing.T) {
+	db := setupTestDB(t)
+	svc := setupTestHostService(t, db)
+	host := createTestHost(t, svc, "start.example.com", 1, 0, 0, 0, 0)
+	useFailingCaddy(t, db, svc, false)
+
+	toggled, err := svc.Toggle(host.ID)
+	if err != nil {
+		t.Fatalf("Toggle: %v", err)
+	}
+	if toggled.Apply == nil {
+		t.Fatal("toggled host carries no apply result")
+	}
+	if !toggled.Apply.Written || toggled.Apply.Reloaded || toggled.Apply.ReloadError == "" {
+		t.Errorf("apply = %+v, want written but not reloaded, with the start error", *toggled.Apply)
+	}
+}
+
+func TestApplyConfig_ResultWithoutAutoReload(t *testing.T) {
+	db := setupTestDB(t)
+	svc := setupTestHostService(t, db)
+
+	host, err := svc.Create(&model.HostCreateRequest{
+		Domain:    "manual.example.com",
+		Upstreams: []model.UpstreamInput{{Address: "localhost:8080"}},
+	})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	want := model.ApplyResult{Written: true}
+	if host.Apply == nil || *host.Apply != want {
+		t.Errorf("apply = %+v, want %+v", host.Apply, want)
+	}
+}
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/web-casa/webcasa/internal/caddy"
+)
+
+// CaddyfileImportEntry is the outcome of importing one top-level block of a
+// Caddyfile.
+type CaddyfileImportEntry struct {
+	Address  string `json:"address"`
+	Line     int    `json:"line"`
+	Status   string `json:"status"` // "imported" or "skipped"
+	HostID   uint   `json:"host_id,omitempty"`
+	HostType string `json:"host_type,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// ImportCaddyfile creates a host for each site of a hand-written Caddyfile
+// that maps onto one (see caddy.HostRequestFromSite) and regenerates the
+// Caddy config once. Global options, snippets and sites that cannot be
+// mapped or conflict with existing hosts are skipped with a reason.
+func (s *HostService) ImportCaddyfile(content string) ([]CaddyfileImportEntry, error) {
+	blocks, err := caddy.ParseCaddyfile(content)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Caddyfile: %w", err)
+	}
+
+	entries := make([]CaddyfileImportEntry, 0, len(blocks))
+	imported := 0
+	for _, block := range blocks {
+		entry := CaddyfileImportEntry{Address: strings.Join(block.Addresses, ", "), Line: block.Line, Status: "skipped"}
+		switch {
+		case block.Snippet != "":
+			entry.Address = "(" + block.Snippet + ")"
+			entry.Reason = "snippets are not imported"
+		case len(block.Addresses) == 0:
+			entry.Reason = "global options are not imported"
+		default:
+			req, err := caddy.HostRequestFromSite(block)
+			if err != nil {
+				entry.Reason = err.Error()
+				break
+			}
+			host, err := s.create(req)
+			if err != nil {
+				entry.Reason = err.Error()
+				break
+			}
+			entry.Status, entry.HostID, entry.HostType = "imported", host.ID, host.HostType
+			imported++
+		}
+		entries = append(entries, entry)
+	}
+
+	if imported > 0 {
+		if _, err := s.ApplyConfig(); err != nil {
+			return entries, fmt.Errorf("hosts imported but Caddy config failed: %w", err)
+		}
+	}
+	return entries, nil
+}
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestImportCaddyfile(t *testing.T) {
+	db := setupTestDB(t)
+	svc := setupTestHostService(t, db)
+	createTestHost(t, svc, "taken.example.com", 1, 0, 0, 0, 0)
+
+	content := `{
+	email admin@example.com
+}
+
+api.example.com {
+	reverse_proxy localhost:8080 localhost:8081
+	header X-Served-By webcasa
+}
+
+static.example.com {
+	root * /srv/static
+	file_server
+}
+
+taken.example.com {
+	reverse_proxy localhost:9000
+}
+
+echo.example.com {
+	respond "hello"
+}
+`
+	results, err := svc.ImportCaddyfile(content)
+	if err != nil {
+		t.Fatalf("ImportCaddyfile: %v", err)
+	}
+	want := []struct{ address, status string }{
+		{"", "skipped"},
+		{"api.example.com", "imported"},
+		{"static.example.com", "imported"},
+		{"taken.example.com", "skipped"},
+		{"echo.example.com", "skipped"},
+	}
+	if len(results) != len(want) {
+		t.Fatalf("results = %+v, want %d entries", results, len(want))
+	}
+	for i, w := range want {
+		if results[i].Address != w.address || results[i].Status != w.status {
+			t.Errorf("result %d = %+v, want %q %s", i, results[i], w.address, w.status)
+		}
+		if w.status == "skipped" && results[i].Reason == "" {
+			t.Errorf("result %d skipped without a reason", i)
+		}
+	}
+
+	api, err := svc.Get(results[1].HostID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if api.HostType != "proxy" || len(api.Upstreams) != 2 || api.Upstreams[1].Address != "localhost:8081" {
+		t.Errorf("api host = %s with upstreams %+v", api.HostType, api.Upstreams)
+	}
+	if api.CustomDirectives != "header X-Served-By webcasa" {
+		t.Errorf("api custom directives = %q", api.CustomDirectives)
+	}
+
+	static, err := svc.Get(results[2].HostID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if static.HostType != "static" || static.RootPath != "/srv/static" || boolVal(static.DirectoryBrowse) {
+		t.Errorf("static host = %s root %q browse %v", static.HostType, static.RootPath, boolVal(static.DirectoryBrowse))
+	}
+
+	caddyfile, _ := os.ReadFile(svc.cfg.CaddyfilePath)
+	for _, site := range []string{"api.example.com {", "static.example.com {"} {
+		if !strings.Contains(string(caddyfile), site) {
+			t.Errorf("generated Caddyfile missing %q", site)
+		}
+	}
+}
+
+func TestImportCaddyfile_InvalidSyntax(t *testing.T) {
+	db := setupTestDB(t)
+	svc := setupTestHostService(t, db)
+	if _, err := svc.ImportCaddyfile("example.com {\n\treverse_proxy localhost:8080\n"); err == nil {
+		t.Error("unbalanced Caddyfile accepted")
+	}
+}
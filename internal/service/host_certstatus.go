@@ -0,0 +1,35 @@
+package service
+
+import (
+	"errors"
+
+	"github.com/web-casa/webcasa/internal/caddy"
+	"github.com/web-casa/webcasa/internal/model"
+)
+
+// ErrCertNotManaged is returned by CertStatus for hosts whose certificate
+// Caddy does not obtain itself (custom certificates, TLS off).
+var ErrCertNotManaged = errors.New("host certificate is not managed by Caddy")
+
+// CertStatus reports the ACME certificate Caddy manages for host id's
+// primary domain.
+func (s *HostService) CertStatus(id uint) (*caddy.ManagedCertStatus, error) {
+	host, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if !certManaged(host) {
+		return nil, ErrCertNotManaged
+	}
+	return s.caddyMgr.ManagedCertificate(host.Domain)
+}
+
+// certManaged reports whether Caddy obtains host's certificate itself.
+func certManaged(host *model.Host) bool {
+	switch host.TLSMode {
+	case "", "auto", "dns", "wildcard":
+	default:
+		return false
+	}
+	return host.TLSEnabled == nil || *host.TLSEnabled
+}
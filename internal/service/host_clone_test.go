@@ -43,6 +43,12 @@ func setupTestDB(t *testing.T) *gorm.DB {
 		&model.Group{},
 		&model.Tag{},
 		&model.HostTag{},
+		&model.TagRule{},
+		&model.HostRevision{},
+		&model.HostSchedule{},
+		&model.Certificate{},
+		&model.DnsProvider{},
+		&model.Template{},
 	)
 	if err != nil {
 		t.Fatalf("failed to migrate test db: %v", err)
@@ -179,7 +185,7 @@ func TestProperty1_CloneProducesEquivalentHost(t *testing.T) {
 
 			source := createTestHost(t, svc, sourceDomain, numUpstreams, numHeaders, numAccessRules, numBasicAuths, numRoutes)
 
-			cloned, err := svc.CloneHost(source.ID, cloneDomain)
+			cloned, err := svc.CloneHost(source.ID, cloneDomain, CloneOptions{})
 			if err != nil {
 				t.Logf("CloneHost failed: %v", err)
 				return false
@@ -314,7 +320,7 @@ func TestProperty2_CloneProducesIndependentRecords(t *testing.T) {
 
 			source := createTestHost(t, svc, sourceDomain, numUpstreams, numHeaders, numAccessRules, numBasicAuths, numRoutes)
 
-			cloned, err := svc.CloneHost(source.ID, cloneDomain)
+			cloned, err := svc.CloneHost(source.ID, cloneDomain, CloneOptions{})
 			if err != nil {
 				t.Logf("CloneHost failed: %v", err)
 				return false
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/web-casa/webcasa/internal/model"
+)
+
+// passwordPlaceholder stands in for a basic auth password, which is only
+// stored hashed.
+const passwordPlaceholder = "PASSWORD"
+
+// TestCommands are ready-made requests for trying a host from a shell.
+type TestCommands struct {
+	URL    string `json:"url"`
+	Curl   string `json:"curl"`
+	HTTPie string `json:"httpie"`
+}
+
+// TestCommands builds curl and HTTPie commands requesting host id's primary
+// domain, with a basic auth user (password left as a placeholder) and the
+// host's custom request headers prefilled.
+func (s *HostService) TestCommands(id uint) (*TestCommands, error) {
+	host, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	return buildTestCommands(host), nil
+}
+
+func buildTestCommands(host *model.Host) *TestCommands {
+	// Prefer a credential covering the whole host; a scoped one is tried
+	// on its own path.
+	var user, path string
+	for i, ba := range host.BasicAuths {
+		if i == 0 || ba.Path == "" {
+			user, path = ba.Username, ba.Path
+		}
+		if ba.Path == "" {
+			break
+		}
+	}
+	if path == "" {
+		path = "/"
+	}
+	url := siteURL(host) + path
+
+	curl := []string{"curl", "-i"}
+	httpie := []string{"http"}
+	if user != "" {
+		curl = append(curl, "-u", shellQuote(user+":"+passwordPlaceholder))
+		httpie = append(httpie, "-a", shellQuote(user+":"+passwordPlaceholder))
+	}
+	httpie = append(httpie, "GET", shellQuote(url))
+	curl = append(curl, shellQuote(url))
+	for _, h := range host.CustomHeaders {
+		if (h.Direction != "" && h.Direction != "request") || h.Operation == "delete" {
+			continue
+		}
+		curl = append(curl, "-H", shellQuote(h.Name+": "+h.Value))
+		httpie = append(httpie, shellQuote(h.Name+":"+h.Value))
+	}
+
+	return &TestCommands{
+		URL:    url,
+		Curl:   strings.Join(curl, " "),
+		HTTPie: strings.Join(httpie, " "),
+	}
+}
+
+// siteURL returns the scheme and name a host's site is reached at, without
+// a trailing slash.
+func siteURL(host *model.Host) string {
+	scheme := "https"
+	if host.TLSMode == "off" || (host.TLSEnabled != nil && !*host.TLSEnabled) {
+		scheme = "http"
+	}
+	// A wildcard site needs a concrete name to be requested.
+	domain := host.Domain
+	if strings.HasPrefix(domain, "*.") {
+		domain = "www." + domain[2:]
+	}
+	return scheme + "://" + domain
+}
+
+// shellSafe matches words a POSIX shell passes through unchanged.
+var shellSafe = regexp.MustCompile(`^[A-Za-z0-9@%+=:,./_-]+$`)
+
+// shellQuote quotes s for a POSIX shell when it needs quoting.
+func shellQuote(s string) string {
+	if shellSafe.MatchString(s) {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/web-casa/webcasa/internal/model"
+)
+
+func TestTestCommands_PlainHost(t *testing.T) {
+	db := setupTestDB(t)
+	svc := setupTestHostService(t, db)
+	host := createTestHost(t, svc, "shop.example.com", 1, 0, 0, 0, 0)
+
+	cmds, err := svc.TestCommands(host.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cmds.Curl != "curl -i https://shop.example.com/" {
+		t.Errorf("curl = %q", cmds.Curl)
+	}
+	if cmds.HTTPie != "http GET https://shop.example.com/" {
+		t.Errorf("httpie = %q", cmds.HTTPie)
+	}
+}
+
+func TestTestCommands_AuthAndHeaders(t *testing.T) {
+	db := setupTestDB(t)
+	svc := setupTestHostService(t, db)
+	host, err := svc.Create(&model.HostCreateRequest{
+		Domain:    "api.example.com",
+		Upstreams: []model.UpstreamInput{{Address: "localhost:8080"}},
+		BasicAuths: []model.BasicAuthInput{
+			{Username: "admin", Password: "secret", Path: "/admin"},
+			{Username: "alice", Password: "secret"},
+		},
+		CustomHeaders: []model.HeaderInput{
+			{Direction: "request", Operation: "set", Name: "X-Api-Key", Value: "it's-a-key"},
+			{Direction: "request", Operation: "delete", Name: "X-Debug"},
+			{Direction: "response", Operation: "set", Name: "X-Frame-Options", Value: "DENY"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmds, err := svc.TestCommands(host.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"https://api.example.com/",
+		"-u alice:PASSWORD",
+		`-H 'X-Api-Key: it'\''s-a-key'`,
+	} {
+		if !strings.Contains(cmds.Curl, want) {
+			t.Errorf("curl missing %q: %s", want, cmds.Curl)
+		}
+	}
+	for _, unwanted := range []string{"X-Debug", "X-Frame-Options", "secret"} {
+		if strings.Contains(cmds.Curl, unwanted) || strings.Contains(cmds.HTTPie, unwanted) {
+			t.Errorf("commands contain %q:\n%s\n%s", unwanted, cmds.Curl, cmds.HTTPie)
+		}
+	}
+	if !strings.Contains(cmds.HTTPie, "-a alice:PASSWORD") {
+		t.Errorf("httpie = %q", cmds.HTTPie)
+	}
+}
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/web-casa/webcasa/internal/caddy"
+)
+
+// upstreamDialTimeout bounds each upstream reachability probe.
+const upstreamDialTimeout = 3 * time.Second
+
+// HostDiagnostics bundles what is needed to troubleshoot a single host.
+type HostDiagnostics struct {
+	Domain    string           `json:"domain"`
+	HostBlock string           `json:"host_block"`
+	AccessLog []string         `json:"access_log"`
+	ErrorLog  []string         `json:"error_log"`
+	Upstreams []UpstreamHealth `json:"upstreams"`
+}
+
+// UpstreamHealth is the result of a TCP reachability probe.
+type UpstreamHealth struct {
+	Address   string `json:"address"`
+	Reachable bool   `json:"reachable"`
+	LatencyMs int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Diagnostics collects the rendered site block, the last lines of the host's
+// access log, recent Caddy errors mentioning the domain, and upstream
+// reachability for host id.
+func (s *HostService) Diagnostics(id uint, lines int) (*HostDiagnostics, error) {
+	host, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if lines <= 0 || lines > 1000 {
+		lines = 100
+	}
+
+	// The block is shown to the operator and may be sent to the AI
+	// assistant, so DNS credentials are masked.
+	providers := s.dnsProviders()
+	for id, p := range providers {
+		p.Config = maskDnsConfig(p.Config)
+		providers[id] = p
+	}
+
+	diag := &HostDiagnostics{
+		Domain:    host.Domain,
+		HostBlock: caddy.RenderHostBlock(*host, s.cfg, providers),
+	}
+
+	// Missing log files just mean no traffic or errors yet.
+	if name := caddy.AccessLogFileName(host.Domain); name != "" && (host.SeparateAccessLog == nil || *host.SeparateAccessLog) {
+		diag.AccessLog, _ = tailLogLines(filepath.Join(s.cfg.LogDir, name), lines, nil)
+	} else {
+		diag.AccessLog, _ = tailLogLines(filepath.Join(s.cfg.LogDir, caddy.SharedAccessLog), lines, func(line string) bool {
+			return strings.Contains(line, `"host":"`+host.Domain+`"`)
+		})
+	}
+	diag.ErrorLog, _ = tailLogLines(filepath.Join(s.cfg.LogDir, "caddy.log"), lines, func(line string) bool {
+		if !strings.Contains(line, host.Domain) {
+			return false
+		}
+		return strings.Contains(line, `"level":"error"`) || strings.Contains(line, `"level":"warn"`)
+	})
+
+	for _, u := range host.Upstreams {
+		diag.Upstreams = append(diag.Upstreams, probeUpstream(u.Address))
+	}
+	return diag, nil
+}
+
+// tailLogLines returns the last n lines of path for which keep returns true
+// (all lines when keep is nil).
+func tailLogLines(path string, n int, keep func(string) bool) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var out []string
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if keep != nil && !keep(line) {
+			continue
+		}
+		out = append(out, line)
+		if len(out) > n {
+			out = out[1:]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return out, fmt.Errorf("read %s: %w", path, err)
+	}
+	return out, nil
+}
+
+// probeUpstream checks that an upstream address accepts TCP connections.
+func probeUpstream(addr string) UpstreamHealth {
+	res := UpstreamHealth{Address: addr}
+
+	network, target := "tcp", addr
+	switch {
+	case strings.HasPrefix(addr, "unix/"):
+		network, target = "unix", strings.TrimPrefix(addr, "unix/")
+	case strings.HasPrefix(addr, "https://"):
+		target = withDefaultPort(strings.TrimPrefix(addr, "https://"), "443")
+	default:
+		target = withDefaultPort(strings.TrimPrefix(addr, "http://"), "80")
+	}
+
+	start := time.Now()
+	conn, err := net.DialTimeout(network, target, upstreamDialTimeout)
+	res.LatencyMs = time.Since(start).Milliseconds()
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	conn.Close()
+	res.Reachable = true
+	return res
+}
+
+// withDefaultPort appends port to hostport when it has none. Any path
+// suffix is dropped.
+func withDefaultPort(hostport, port string) string {
+	if i := strings.IndexByte(hostport, '/'); i >= 0 {
+		hostport = hostport[:i]
+	}
+	if _, _, err := net.SplitHostPort(hostport); err == nil {
+		return hostport
+	}
+	return net.JoinHostPort(hostport, port)
+}
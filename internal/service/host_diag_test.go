@@ -0,0 +1,93 @@
+package service
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/web-casa/webcasa/internal/model"
+)
+
+func TestHostDiagnostics(t *testing.T) {
+	db := setupTestDB(t)
+	svc := setupTestHostService(t, db)
+	host := createTestHost(t, svc, "diag.example.com", 1, 0, 0, 0, 0)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	db.Where("host_id = ?", host.ID).Delete(&model.Upstream{})
+	db.Create(&model.Upstream{HostID: host.ID, Address: ln.Addr().String(), Weight: 1})
+	db.Create(&model.Upstream{HostID: host.ID, Address: "127.0.0.1:1", Weight: 1, SortOrder: 1})
+
+	access := "GET /a 200\nGET /b 502\nGET /c 502\n"
+	os.WriteFile(filepath.Join(svc.cfg.LogDir, "access-diag.example.com.log"), []byte(access), 0644)
+	caddyLog := `{"level":"error","msg":"dial tcp: connection refused","host":"diag.example.com"}
+{"level":"info","msg":"served","host":"diag.example.com"}
+{"level":"error","msg":"unrelated","host":"other.example.com"}
+`
+	os.WriteFile(filepath.Join(svc.cfg.LogDir, "caddy.log"), []byte(caddyLog), 0644)
+
+	diag, err := svc.Diagnostics(host.ID, 2)
+	if err != nil {
+		t.Fatalf("Diagnostics: %v", err)
+	}
+	if !strings.HasPrefix(diag.HostBlock, "diag.example.com {") {
+		t.Errorf("host block should start with the site address, got:\n%s", diag.HostBlock)
+	}
+	if len(diag.AccessLog) != 2 || diag.AccessLog[0] != "GET /b 502" {
+		t.Errorf("access log tail = %q, want the last 2 lines", diag.AccessLog)
+	}
+	if len(diag.ErrorLog) != 1 || !strings.Contains(diag.ErrorLog[0], "connection refused") {
+		t.Errorf("error log should hold only this host's errors, got %q", diag.ErrorLog)
+	}
+	if len(diag.Upstreams) != 2 {
+		t.Fatalf("expected 2 upstream probes, got %+v", diag.Upstreams)
+	}
+	for _, u := range diag.Upstreams {
+		want := u.Address == ln.Addr().String()
+		if u.Reachable != want {
+			t.Errorf("upstream %s reachable = %v, want %v (%s)", u.Address, u.Reachable, want, u.Error)
+		}
+	}
+}
+
+func TestHostDiagnostics_MasksDnsCredentials(t *testing.T) {
+	db := setupTestDB(t)
+	svc := setupTestHostService(t, db)
+	provider := &model.DnsProvider{Name: "cf", Provider: "cloudflare"}
+	if err := NewDnsProviderService(db, svc.cfg.JWTSecret).Save(provider, `{"api_token":"cf-secret-token-1234"}`); err != nil {
+		t.Fatal(err)
+	}
+	host := createTestHost(t, svc, "dns.example.com", 1, 0, 0, 0, 0)
+	db.Model(host).Updates(map[string]interface{}{"tls_mode": "dns", "dns_provider_id": provider.ID})
+
+	diag, err := svc.Diagnostics(host.ID, 10)
+	if err != nil {
+		t.Fatalf("Diagnostics: %v", err)
+	}
+	if strings.Contains(diag.HostBlock, "cf-secret-token-1234") {
+		t.Fatalf("host block leaks the DNS token:\n%s", diag.HostBlock)
+	}
+	if !strings.Contains(diag.HostBlock, "dns cloudflare cf-s****1234") {
+		t.Errorf("host block should show the masked DNS challenge, got:\n%s", diag.HostBlock)
+	}
+}
+
+func TestWithDefaultPort(t *testing.T) {
+	tests := map[string]string{
+		"localhost:3000":  "localhost:3000",
+		"backend":         "backend:80",
+		"backend/api":     "backend:80",
+		"10.0.0.5:8443/x": "10.0.0.5:8443",
+	}
+	for in, want := range tests {
+		if got := withDefaultPort(in, "80"); got != want {
+			t.Errorf("withDefaultPort(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
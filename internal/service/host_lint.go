@@ -0,0 +1,124 @@
+package service
+
+import (
+	"net"
+	"strings"
+
+	"github.com/web-casa/webcasa/internal/model"
+)
+
+// Lint warning codes.
+const (
+	LintWebSocketOff          = "websocket_off"
+	LintCompressPrecompressed = "compression_precompressed"
+	LintSecurityHeadersOff    = "security_headers_off"
+)
+
+// LintWarning is an advisory finding about a host's configuration. It never
+// blocks saving the host.
+type LintWarning struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// wsHints are substrings of paths and directives that suggest a WebSocket
+// or other upgraded connection.
+var wsHints = []string{"upgrade", "websocket", "socket.io", "/ws", "ws://", "wss://", "sockjs"}
+
+// precompressedLabels are leading domain labels of sites that mostly serve
+// media or archives, which are already compressed.
+var precompressedLabels = map[string]bool{
+	"cdn": true, "media": true, "img": true, "images": true, "video": true,
+	"videos": true, "download": true, "downloads": true, "files": true,
+}
+
+// Lint inspects a host for settings that are valid but likely wrong, such
+// as a WebSocket app proxied without WebSocket support.
+func (s *HostService) Lint(host *model.Host) []LintWarning {
+	warnings := []LintWarning{}
+
+	if host.HostType == "proxy" && !boolVal(host.WebSocket) && looksLikeWebSocket(host) {
+		warnings = append(warnings, LintWarning{
+			Code:    LintWebSocketOff,
+			Message: "the upstream looks like a WebSocket app but WebSocket support is off; connection upgrades may be dropped",
+		})
+	}
+
+	if boolVal(host.Compression) && servesPrecompressed(host) {
+		warnings = append(warnings, LintWarning{
+			Code:    LintCompressPrecompressed,
+			Message: "compression is on for a site that seems to serve already-compressed data; it costs CPU for little gain",
+		})
+	}
+
+	if !boolVal(host.SecurityHeaders) && host.SecurityHeadersConfig == nil && isPublicDomain(host.Domain) {
+		warnings = append(warnings, LintWarning{
+			Code:    LintSecurityHeadersOff,
+			Message: "security headers are off on a public site",
+		})
+	}
+	return warnings
+}
+
+// LintHost loads host id and lints it.
+func (s *HostService) LintHost(id uint) ([]LintWarning, error) {
+	host, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	return s.Lint(host), nil
+}
+
+// looksLikeWebSocket reports whether a host's upstreams, routes, headers or
+// custom directives mention WebSocket-style traffic.
+func looksLikeWebSocket(host *model.Host) bool {
+	texts := []string{host.CustomDirectives}
+	for _, u := range host.Upstreams {
+		texts = append(texts, u.Address)
+	}
+	for _, r := range host.Routes {
+		texts = append(texts, r.Path)
+	}
+	for _, h := range host.CustomHeaders {
+		texts = append(texts, h.Name, h.Value)
+	}
+	for _, text := range texts {
+		text = strings.ToLower(text)
+		for _, hint := range wsHints {
+			if strings.Contains(text, hint) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// servesPrecompressed reports whether a host looks like it serves media or
+// archives, or already sets its own Content-Encoding.
+func servesPrecompressed(host *model.Host) bool {
+	for _, h := range host.CustomHeaders {
+		if h.Operation != "delete" && strings.EqualFold(h.Name, "Content-Encoding") {
+			return true
+		}
+	}
+	label, _, _ := strings.Cut(strings.ToLower(host.Domain), ".")
+	return precompressedLabels[label]
+}
+
+// isPublicDomain reports whether domain is reachable from the internet:
+// not an IP address, localhost or a private-use suffix.
+func isPublicDomain(domain string) bool {
+	name := strings.ToLower(domain)
+	if h, _, err := net.SplitHostPort(name); err == nil {
+		name = h
+	}
+	if name == "localhost" || net.ParseIP(name) != nil || !strings.Contains(name, ".") {
+		return false
+	}
+	for _, suffix := range []string{".localhost", ".local", ".lan", ".internal", ".home.arpa", ".test"} {
+		if strings.HasSuffix(name, suffix) {
+			return false
+		}
+	}
+	return true
+}
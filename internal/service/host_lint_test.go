@@ -0,0 +1,59 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/web-casa/webcasa/internal/model"
+)
+
+func lintCodes(warnings []LintWarning) map[string]bool {
+	codes := make(map[string]bool, len(warnings))
+	for _, w := range warnings {
+		codes[w.Code] = true
+	}
+	return codes
+}
+
+func TestLint_WebSocketOff(t *testing.T) {
+	db := setupTestDB(t)
+	svc := setupTestHostService(t, db)
+	off := false
+	host, err := svc.Create(&model.HostCreateRequest{
+		Domain:           "chat.example.com",
+		Upstreams:        []model.UpstreamInput{{Address: "localhost:3000"}},
+		WebSocket:        &off,
+		CustomDirectives: "@ws header Connection *Upgrade*",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	warnings, err := svc.LintHost(host.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !lintCodes(warnings)[LintWebSocketOff] {
+		t.Errorf("no websocket warning: %+v", warnings)
+	}
+
+	on := true
+	host.WebSocket = &on
+	if lintCodes(svc.Lint(host))[LintWebSocketOff] {
+		t.Error("websocket warning with WebSocket on")
+	}
+}
+
+func TestLint_CompressionAndSecurityHeaders(t *testing.T) {
+	svc := &HostService{}
+	on, off := true, false
+	host := &model.Host{Domain: "cdn.example.com", HostType: "proxy", Compression: &on, SecurityHeaders: &off}
+	codes := lintCodes(svc.Lint(host))
+	if !codes[LintCompressPrecompressed] || !codes[LintSecurityHeadersOff] {
+		t.Errorf("codes = %v, want compression and security header warnings", codes)
+	}
+
+	host = &model.Host{Domain: "nas.lan", HostType: "proxy", Compression: &on}
+	if codes := lintCodes(svc.Lint(host)); len(codes) != 0 {
+		t.Errorf("private host linted: %v", codes)
+	}
+}
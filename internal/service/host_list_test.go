@@ -0,0 +1,123 @@
+package service
+
+import (
+	"testing"
+)
+
+func hostDomains(t *testing.T, svc *HostService, filter HostListFilter) ([]string, int64) {
+	t.Helper()
+	hosts, total, err := svc.ListPage(filter)
+	if err != nil {
+		t.Fatalf("ListPage(%+v): %v", filter, err)
+	}
+	domains := make([]string, len(hosts))
+	for i, h := range hosts {
+		domains[i] = h.Domain
+	}
+	return domains, total
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestListPage_Boundaries(t *testing.T) {
+	db := setupTestDB(t)
+	svc := setupTestHostService(t, db)
+	for _, d := range []string{"a.example.com", "b.example.com", "c.example.com", "d.example.com", "e.example.com"} {
+		createTestHost(t, svc, d, 1, 0, 0, 0, 0)
+	}
+
+	cases := []struct {
+		limit, offset int
+		want          []string
+	}{
+		{0, 0, []string{"a.example.com", "b.example.com", "c.example.com", "d.example.com", "e.example.com"}},
+		{2, 0, []string{"a.example.com", "b.example.com"}},
+		{2, 4, []string{"e.example.com"}},
+		{2, 5, []string{}},
+		{10, 0, []string{"a.example.com", "b.example.com", "c.example.com", "d.example.com", "e.example.com"}},
+		{0, 3, []string{"d.example.com", "e.example.com"}},
+	}
+	for _, tc := range cases {
+		got, total := hostDomains(t, svc, HostListFilter{Limit: tc.limit, Offset: tc.offset})
+		if total != 5 {
+			t.Errorf("limit=%d offset=%d: total = %d, want 5", tc.limit, tc.offset, total)
+		}
+		if !equalStrings(got, tc.want) {
+			t.Errorf("limit=%d offset=%d: got %v, want %v", tc.limit, tc.offset, got, tc.want)
+		}
+	}
+
+	if _, _, err := svc.ListPage(HostListFilter{Limit: -1}); err == nil {
+		t.Error("negative limit accepted")
+	}
+}
+
+func TestListPage_SortByDomain(t *testing.T) {
+	db := setupTestDB(t)
+	svc := setupTestHostService(t, db)
+	for _, d := range []string{"mid.example.com", "zed.example.com", "alpha.example.com"} {
+		createTestHost(t, svc, d, 1, 0, 0, 0, 0)
+	}
+
+	asc, _ := hostDomains(t, svc, HostListFilter{Sort: "domain"})
+	if want := []string{"alpha.example.com", "mid.example.com", "zed.example.com"}; !equalStrings(asc, want) {
+		t.Errorf("domain: got %v, want %v", asc, want)
+	}
+	desc, _ := hostDomains(t, svc, HostListFilter{Sort: "-domain", Limit: 2})
+	if want := []string{"zed.example.com", "mid.example.com"}; !equalStrings(desc, want) {
+		t.Errorf("-domain: got %v, want %v", desc, want)
+	}
+	byID, _ := hostDomains(t, svc, HostListFilter{})
+	if want := []string{"mid.example.com", "zed.example.com", "alpha.example.com"}; !equalStrings(byID, want) {
+		t.Errorf("default: got %v, want %v", byID, want)
+	}
+
+	if _, _, err := svc.ListPage(HostListFilter{Sort: "upstream"}); err == nil || err.Error() != "error.invalid_sort" {
+		t.Errorf("unknown sort: err = %v, want error.invalid_sort", err)
+	}
+}
+
+func TestListPage_DomainFilter(t *testing.T) {
+	db := setupTestDB(t)
+	svc := setupTestHostService(t, db)
+	for _, d := range []string{"api.example.com", "app.example.com", "api.other.org", "myapi.test.com"} {
+		createTestHost(t, svc, d, 1, 0, 0, 0, 0)
+	}
+
+	got, total := hostDomains(t, svc, HostListFilter{Domain: "API", Sort: "domain"})
+	if want := []string{"api.example.com", "api.other.org", "myapi.test.com"}; !equalStrings(got, want) || total != 3 {
+		t.Errorf("api: got %v (total %d), want %v", got, total, want)
+	}
+
+	// The total counts every match, not just the page.
+	got, total = hostDomains(t, svc, HostListFilter{Domain: "example.com", Limit: 1})
+	if len(got) != 1 || total != 2 {
+		t.Errorf("example.com page: got %v (total %d), want 1 host of 2", got, total)
+	}
+
+	// LIKE wildcards in the filter match literally.
+	for _, pattern := range []string{"a_i", "a%i"} {
+		if got, total := hostDomains(t, svc, HostListFilter{Domain: pattern}); total != 0 {
+			t.Errorf("%s: got %v, want no match", pattern, got)
+		}
+	}
+
+	// List stays unpaginated.
+	all, err := svc.List(HostListFilter{Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 4 {
+		t.Errorf("List returned %d hosts, want 4", len(all))
+	}
+}
@@ -0,0 +1,216 @@
+package service
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+const (
+	// metadataTimeout bounds fetching an upstream's home page.
+	metadataTimeout = 3 * time.Second
+	// metadataMaxBytes caps how much of the page is read; the head comes first.
+	metadataMaxBytes = 256 * 1024
+	// metadataCacheTTL is how long a fetched result, empty or not, is reused.
+	metadataCacheTTL = 5 * time.Minute
+	// maxMetadataTitle caps the length of a returned title.
+	maxMetadataTitle = 200
+)
+
+// HostMetadata is what an upstream app's home page says about itself. All
+// fields are empty when the upstream could not be fetched or is not HTTP.
+type HostMetadata struct {
+	Title string `json:"title"`
+	// Favicon is absolute; relative links are resolved against the host's
+	// public site so a browser can load them.
+	Favicon string `json:"favicon"`
+}
+
+// metadataCache holds recently fetched metadata by upstream and site.
+type metadataCache struct {
+	mu      sync.Mutex
+	entries map[string]metadataEntry
+}
+
+type metadataEntry struct {
+	meta      HostMetadata
+	fetchedAt time.Time
+}
+
+func (c *metadataCache) get(key string, now time.Time) (HostMetadata, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok || now.Sub(e.fetchedAt) >= metadataCacheTTL {
+		return HostMetadata{}, false
+	}
+	return e.meta, true
+}
+
+func (c *metadataCache) put(key string, meta HostMetadata, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[string]metadataEntry)
+	}
+	for k, e := range c.entries {
+		if now.Sub(e.fetchedAt) >= metadataCacheTTL {
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = metadataEntry{meta: meta, fetchedAt: now}
+}
+
+// Metadata fetches the home page of host id's first upstream and returns its
+// title and favicon. Fetch failures give an empty result, not an error.
+func (s *HostService) Metadata(id uint) (*HostMetadata, error) {
+	host, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if host.HostType != "proxy" || len(host.Upstreams) == 0 {
+		return &HostMetadata{}, nil
+	}
+	target := upstreamPageURL(host.Upstreams[0].Address)
+	if target == "" {
+		return &HostMetadata{}, nil
+	}
+	site := siteURL(host)
+
+	key := target + " " + site
+	if meta, ok := s.metadata.get(key, time.Now()); ok {
+		return &meta, nil
+	}
+	meta := fetchMetadata(target, site)
+	s.metadata.put(key, meta, time.Now())
+	return &meta, nil
+}
+
+// upstreamPageURL returns the root URL of an HTTP upstream, or "" for one
+// that cannot be fetched directly (a unix socket or a placeholder).
+func upstreamPageURL(addr string) string {
+	if strings.HasPrefix(addr, "unix/") || strings.Contains(addr, "{") {
+		return ""
+	}
+	scheme := "http"
+	if strings.HasPrefix(addr, "https://") {
+		scheme = "https"
+	}
+	hostport := strings.TrimPrefix(strings.TrimPrefix(addr, "https://"), "http://")
+	if i := strings.IndexByte(hostport, '/'); i >= 0 {
+		hostport = hostport[:i]
+	}
+	if hostport == "" {
+		return ""
+	}
+	return scheme + "://" + hostport + "/"
+}
+
+// fetchMetadata reads target's title and favicon link. Redirects are
+// followed only within the upstream.
+func fetchMetadata(target, site string) HostMetadata {
+	client := &http.Client{
+		Timeout: metadataTimeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 3 || req.URL.Host != via[0].URL.Host {
+				return http.ErrUseLastResponse
+			}
+			return nil
+		},
+	}
+	req, err := http.NewRequest(http.MethodGet, target, nil)
+	if err != nil {
+		return HostMetadata{}
+	}
+	req.Header.Set("Accept", "text/html")
+	resp, err := client.Do(req)
+	if err != nil {
+		return HostMetadata{}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.Contains(resp.Header.Get("Content-Type"), "html") {
+		return HostMetadata{}
+	}
+
+	title, icon := parseHead(io.LimitReader(resp.Body, metadataMaxBytes))
+	meta := HostMetadata{Title: title}
+	if icon != "" {
+		base, err := url.Parse(site + resp.Request.URL.Path)
+		ref, refErr := url.Parse(icon)
+		if err == nil && refErr == nil {
+			if abs := base.ResolveReference(ref); abs.Scheme == "http" || abs.Scheme == "https" {
+				meta.Favicon = abs.String()
+			}
+		}
+	}
+	return meta
+}
+
+// parseHead returns the title and first icon link of an HTML document,
+// reading no further than its head.
+func parseHead(r io.Reader) (title, icon string) {
+	z := html.NewTokenizer(r)
+	inTitle := false
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return cleanTitle(title), icon
+		case html.StartTagToken, html.SelfClosingTagToken:
+			tok := z.Token()
+			switch tok.Data {
+			case "title":
+				inTitle = title == ""
+			case "link":
+				if icon == "" && isIconLink(tok) {
+					icon = htmlAttr(tok, "href")
+				}
+			case "body":
+				return cleanTitle(title), icon
+			}
+		case html.EndTagToken:
+			switch z.Token().Data {
+			case "title":
+				inTitle = false
+			case "head":
+				return cleanTitle(title), icon
+			}
+		case html.TextToken:
+			if inTitle {
+				title += string(z.Text())
+			}
+		}
+	}
+}
+
+// isIconLink reports whether a <link> names the page's icon.
+func isIconLink(tok html.Token) bool {
+	for _, rel := range strings.Fields(strings.ToLower(htmlAttr(tok, "rel"))) {
+		if rel == "icon" || rel == "apple-touch-icon" {
+			return htmlAttr(tok, "href") != ""
+		}
+	}
+	return false
+}
+
+func htmlAttr(tok html.Token, name string) string {
+	for _, a := range tok.Attr {
+		if a.Key == name {
+			return strings.TrimSpace(a.Val)
+		}
+	}
+	return ""
+}
+
+// cleanTitle collapses whitespace and caps the length of a page title.
+func cleanTitle(title string) string {
+	title = strings.Join(strings.Fields(title), " ")
+	if r := []rune(title); len(r) > maxMetadataTitle {
+		title = string(r[:maxMetadataTitle])
+	}
+	return title
+}
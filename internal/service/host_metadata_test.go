@@ -0,0 +1,102 @@
+package service
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/web-casa/webcasa/internal/model"
+)
+
+func TestHostMetadata(t *testing.T) {
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, `<!doctype html><html><head>
+<meta charset="utf-8">
+<title>
+  Grafana   Dashboard
+</title>
+<link rel="stylesheet" href="/app.css">
+<link rel="shortcut icon" href="/static/favicon.png">
+</head><body><title>not this</title></body></html>`)
+	}))
+	defer srv.Close()
+
+	db := setupTestDB(t)
+	svc := setupTestHostService(t, db)
+	host, err := svc.Create(&model.HostCreateRequest{
+		Domain:    "grafana.example.com",
+		Upstreams: []model.UpstreamInput{{Address: strings.TrimPrefix(srv.URL, "http://")}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	meta, err := svc.Metadata(host.ID)
+	if err != nil {
+		t.Fatalf("Metadata: %v", err)
+	}
+	if meta.Title != "Grafana Dashboard" {
+		t.Errorf("Title = %q, want Grafana Dashboard", meta.Title)
+	}
+	if meta.Favicon != "https://grafana.example.com/static/favicon.png" {
+		t.Errorf("Favicon = %q, want it resolved against the public site", meta.Favicon)
+	}
+
+	// A second request within the TTL is served from the cache.
+	if _, err := svc.Metadata(host.ID); err != nil {
+		t.Fatal(err)
+	}
+	if n := hits.Load(); n != 1 {
+		t.Errorf("upstream fetched %d times, want 1", n)
+	}
+
+	if _, err := svc.Metadata(9999); err == nil {
+		t.Error("unknown host should fail")
+	}
+}
+
+func TestHostMetadata_Unreachable(t *testing.T) {
+	db := setupTestDB(t)
+	svc := setupTestHostService(t, db)
+	host, err := svc.Create(&model.HostCreateRequest{
+		Domain:    "down.example.com",
+		Upstreams: []model.UpstreamInput{{Address: "127.0.0.1:1"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	meta, err := svc.Metadata(host.ID)
+	if err != nil {
+		t.Fatalf("Metadata: %v", err)
+	}
+	if *meta != (HostMetadata{}) {
+		t.Errorf("unreachable upstream gave %+v, want an empty result", meta)
+	}
+}
+
+func TestParseHead(t *testing.T) {
+	title, icon := parseHead(strings.NewReader(`<html><head><link rel="apple-touch-icon" href="touch.png"><link rel="icon" href="x.ico"></head><body><link rel="icon" href="late.ico"></body>`))
+	if title != "" || icon != "touch.png" {
+		t.Errorf("parseHead = %q, %q; want no title and the first icon link", title, icon)
+	}
+}
+
+func TestUpstreamPageURL(t *testing.T) {
+	for in, want := range map[string]string{
+		"localhost:3000":        "http://localhost:3000/",
+		"https://eol.wiki":      "https://eol.wiki/",
+		"http://10.0.0.5:80/ui": "http://10.0.0.5:80/",
+		"unix//run/app.sock":    "",
+	} {
+		if got := upstreamPageURL(in); got != want {
+			t.Errorf("upstreamPageURL(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
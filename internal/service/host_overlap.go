@@ -0,0 +1,126 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/web-casa/webcasa/internal/caddy"
+	"github.com/web-casa/webcasa/internal/model"
+)
+
+// DomainOverlapError is returned when a domain is covered by another host's
+// wildcard (or covers another host's domain) and reject_domain_overlap is on.
+type DomainOverlapError struct {
+	Domain     string
+	HostID     uint
+	HostDomain string
+}
+
+func (e *DomainOverlapError) Error() string {
+	return fmt.Sprintf("domain '%s' overlaps host '%s' (id %d)", e.Domain, e.HostDomain, e.HostID)
+}
+
+// normalizeAliases validates a comma-separated alias list and returns it
+// normalized like primary domains and de-duplicated. Aliases may not repeat the primary domain.
+func normalizeAliases(primary, raw string) (string, error) {
+	var aliases []string
+	seen := map[string]bool{strings.ToLower(primary): true}
+	for _, alias := range strings.Split(raw, ",") {
+		alias = strings.TrimSpace(alias)
+		if alias == "" {
+			continue
+		}
+		alias, err := caddy.NormalizeDomain(alias)
+		if err != nil {
+			return "", fmt.Errorf("invalid alias: %w", err)
+		}
+		if err := caddy.ValidateDomain(alias); err != nil {
+			return "", fmt.Errorf("invalid alias: %w", err)
+		}
+		if seen[strings.ToLower(alias)] {
+			continue
+		}
+		seen[strings.ToLower(alias)] = true
+		aliases = append(aliases, alias)
+	}
+	return strings.Join(aliases, ","), nil
+}
+
+// otherHostNames loads the id, domain and aliases of every live host except
+// excludeID.
+func (s *HostService) otherHostNames(excludeID uint) ([]model.Host, error) {
+	var hosts []model.Host
+	err := s.db.Select("id", "domain", "aliases").Where("id != ?", excludeID).Find(&hosts).Error
+	return hosts, err
+}
+
+// findNameConflict returns the first of names already served, as a primary
+// domain or an alias, by a live host other than excludeID.
+func (s *HostService) findNameConflict(names []string, excludeID uint) (string, error) {
+	hosts, err := s.otherHostNames(excludeID)
+	if err != nil {
+		return "", fmt.Errorf("failed to check domain uniqueness: %w", err)
+	}
+	taken := make(map[string]bool)
+	for _, h := range hosts {
+		for _, name := range caddy.HostDomains(h) {
+			taken[strings.ToLower(name)] = true
+		}
+	}
+	for _, name := range names {
+		if taken[strings.ToLower(name)] {
+			return name, nil
+		}
+	}
+	return "", nil
+}
+
+// CheckDomainAvailable returns an error when domain is already served, as a
+// primary domain or an alias, by a live host other than excludeID, or when
+// it overlaps one and reject_domain_overlap is on. It applies to callers
+// outside the service, such as plugins, the checks Create and Update make.
+func (s *HostService) CheckDomainAvailable(domain string, excludeID uint) error {
+	taken, err := s.findNameConflict([]string{domain}, excludeID)
+	if err != nil {
+		return err
+	}
+	if taken != "" {
+		return fmt.Errorf("domain '%s' already exists", taken)
+	}
+	_, err = s.checkDomainOverlap([]string{domain}, excludeID)
+	return err
+}
+
+// checkDomainOverlap looks for a live host other than excludeID with a name
+// overlapping any of names. An overlap is rejected with a
+// *DomainOverlapError when the reject_domain_overlap setting is "true", and
+// otherwise logged and returned as a warning for the caller to pass on.
+func (s *HostService) checkDomainOverlap(names []string, excludeID uint) (*model.HostWarning, error) {
+	hosts, err := s.otherHostNames(excludeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check domain overlap: %w", err)
+	}
+	for _, h := range hosts {
+		for _, other := range caddy.HostDomains(h) {
+			for _, name := range names {
+				if !caddy.DomainsOverlap(name, other) {
+					continue
+				}
+				overlap := &DomainOverlapError{Domain: name, HostID: h.ID, HostDomain: other}
+				var reject model.Setting
+				if s.db.Where("key = ?", "reject_domain_overlap").First(&reject).Error == nil && reject.Value == "true" {
+					return nil, overlap
+				}
+				log.Printf("Warning: %v; both hosts will compete for the same TLS names", overlap)
+				return &model.HostWarning{
+					Key:     "warning.domain_overlap",
+					Message: overlap.Error(),
+					HostID:  h.ID,
+					Domain:  other,
+				}, nil
+			}
+		}
+	}
+	return nil, nil
+}
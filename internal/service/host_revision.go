@@ -0,0 +1,195 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/web-casa/webcasa/internal/model"
+)
+
+// maxHostRevisions is how many revisions are kept per host; older ones are
+// pruned as new ones are recorded.
+const maxHostRevisions = 50
+
+// ErrRevisionNotFound is returned by RestoreRevision for an unknown revision.
+var ErrRevisionNotFound = errors.New("revision not found")
+
+// revisionCredential is a basic auth credential as stored in a revision.
+type revisionCredential struct {
+	Username     string `json:"username"`
+	PasswordHash string `json:"password_hash"`
+	Path         string `json:"path,omitempty"`
+}
+
+// recordRevision snapshots the host's current state as a new revision.
+// Failures are only logged: the change itself is already saved and history
+// must not make it look failed.
+func (s *HostService) recordRevision(id, userID uint, username string) {
+	host, err := s.Get(id)
+	if err != nil {
+		log.Printf("Warning: failed to load host %d for revision: %v", id, err)
+		return
+	}
+	snapshot, err := json.Marshal(host)
+	if err != nil {
+		log.Printf("Warning: failed to snapshot host %d: %v", id, err)
+		return
+	}
+	creds := make([]revisionCredential, 0, len(host.BasicAuths))
+	for _, ba := range host.BasicAuths {
+		creds = append(creds, revisionCredential{Username: ba.Username, PasswordHash: ba.PasswordHash, Path: ba.Path})
+	}
+	credJSON, _ := json.Marshal(creds)
+
+	rev := model.HostRevision{
+		HostID:      id,
+		Revision:    host.Version,
+		Snapshot:    string(snapshot),
+		Credentials: string(credJSON),
+		UserID:      userID,
+		Username:    username,
+	}
+	if err := s.db.Create(&rev).Error; err != nil {
+		log.Printf("Warning: failed to record revision %d of host %d: %v", host.Version, id, err)
+		return
+	}
+	if host.Version > maxHostRevisions {
+		s.db.Where("host_id = ? AND revision <= ?", id, host.Version-maxHostRevisions).Delete(&model.HostRevision{})
+	}
+}
+
+// Revisions returns a host's recorded revisions, newest first.
+func (s *HostService) Revisions(id uint) ([]model.HostRevision, error) {
+	if _, err := s.Get(id); err != nil {
+		return nil, err
+	}
+	var revs []model.HostRevision
+	err := s.db.Where("host_id = ?", id).Order("revision DESC").Find(&revs).Error
+	return revs, err
+}
+
+// RestoreRevision applies the configuration captured in revision rev as a
+// normal update, which records a new revision on top.
+func (s *HostService) RestoreRevision(id, rev, userID uint, username string) (*model.Host, error) {
+	current, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var revision model.HostRevision
+	if err := s.db.Where("host_id = ? AND revision = ?", id, rev).First(&revision).Error; err != nil {
+		return nil, ErrRevisionNotFound
+	}
+	var snap model.Host
+	if err := json.Unmarshal([]byte(revision.Snapshot), &snap); err != nil {
+		return nil, fmt.Errorf("revision %d is corrupt: %w", rev, err)
+	}
+	var creds []revisionCredential
+	if revision.Credentials != "" {
+		if err := json.Unmarshal([]byte(revision.Credentials), &creds); err != nil {
+			return nil, fmt.Errorf("revision %d is corrupt: %w", rev, err)
+		}
+	}
+
+	req := s.requestFromSnapshot(&snap, creds)
+	req.Version = current.Version
+	req.AuthorID = userID
+	req.Author = username
+	return s.Update(id, req)
+}
+
+// CurrentRequest returns the update request that reproduces host id as it
+// is now, at its current version, for callers that change only a few fields
+// and still go through Update.
+func (s *HostService) CurrentRequest(id uint) (*model.HostCreateRequest, error) {
+	host, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	creds := make([]revisionCredential, 0, len(host.BasicAuths))
+	for _, ba := range host.BasicAuths {
+		creds = append(creds, revisionCredential{Username: ba.Username, PasswordHash: ba.PasswordHash, Path: ba.Path})
+	}
+	req := s.requestFromSnapshot(host, creds)
+	req.Version = host.Version
+	return req, nil
+}
+
+// requestFromSnapshot rebuilds the update request that reproduces snap.
+// Groups and tags deleted since the snapshot was taken are dropped.
+func (s *HostService) requestFromSnapshot(snap *model.Host, creds []revisionCredential) *model.HostCreateRequest {
+	req := &model.HostCreateRequest{
+		Domain:                 snap.Domain,
+		Aliases:                snap.Aliases,
+		BasicAuthRealm:         snap.BasicAuthRealm,
+		ForwardAuthURL:         snap.ForwardAuthURL,
+		ForwardAuthCopyHeaders: snap.ForwardAuthCopyHeaders,
+		SecurityHeadersConfig:  snap.SecurityHeadersConfig,
+		CorsAllowCredentials:   copyBoolPtr(snap.CorsAllowCredentials),
+		CorsMaxAge:             snap.CorsMaxAge,
+		CompressionAlgorithms:  snap.CompressionAlgorithms,
+		ACMECA:                 snap.ACMECA,
+		HostType:               snap.HostType,
+		Enabled:                copyBoolPtr(snap.Enabled),
+		TLSEnabled:             copyBoolPtr(snap.TLSEnabled),
+		HTTPRedirect:           copyBoolPtr(snap.HTTPRedirect),
+		WebSocket:              copyBoolPtr(snap.WebSocket),
+		RedirectURL:            snap.RedirectURL,
+		RedirectCode:           snap.RedirectCode,
+		Compression:            copyBoolPtr(snap.Compression),
+		CacheEnabled:           copyBoolPtr(snap.CacheEnabled),
+		CacheTTL:               snap.CacheTTL,
+		CorsEnabled:            copyBoolPtr(snap.CorsEnabled),
+		CorsOrigins:            snap.CorsOrigins,
+		CorsMethods:            snap.CorsMethods,
+		CorsHeaders:            snap.CorsHeaders,
+		SecurityHeaders:        copyBoolPtr(snap.SecurityHeaders),
+		ErrorPagePath:          snap.ErrorPagePath,
+		RootPath:               snap.RootPath,
+		DirectoryBrowse:        copyBoolPtr(snap.DirectoryBrowse),
+		SPAFallback:            copyBoolPtr(snap.SPAFallback),
+		PHPFastCGI:             snap.PHPFastCGI,
+		PHPVersion:             snap.PHPVersion,
+		IndexFiles:             snap.IndexFiles,
+		SeparateAccessLog:      copyBoolPtr(snap.SeparateAccessLog),
+		MaxRequestBodySize:     snap.MaxRequestBodySize,
+		ProxyReadTimeout:       snap.ProxyReadTimeout,
+		ProxyWriteTimeout:      snap.ProxyWriteTimeout,
+		ProxyDialTimeout:       snap.ProxyDialTimeout,
+		TLSMode:                snap.TLSMode,
+		DnsProviderID:          snap.DnsProviderID,
+		CustomDirectives:       snap.CustomDirectives,
+	}
+	for _, u := range snap.Upstreams {
+		req.Upstreams = append(req.Upstreams, model.UpstreamInput{Address: u.Address, Weight: u.Weight})
+	}
+	for _, h := range snap.CustomHeaders {
+		req.CustomHeaders = append(req.CustomHeaders, model.HeaderInput{
+			Direction: h.Direction, Operation: h.Operation, Name: h.Name, Value: h.Value,
+		})
+	}
+	for _, a := range snap.AccessRules {
+		req.AccessRules = append(req.AccessRules, model.AccessInput{RuleType: a.RuleType, IPRange: a.IPRange})
+	}
+	for _, c := range creds {
+		req.BasicAuths = append(req.BasicAuths, model.BasicAuthInput{Username: c.Username, PasswordHash: c.PasswordHash, Path: c.Path})
+	}
+
+	if snap.GroupID != nil {
+		var count int64
+		s.db.Model(&model.Group{}).Where("id = ?", *snap.GroupID).Count(&count)
+		if count > 0 {
+			req.GroupID = snap.GroupID
+		}
+	}
+	if len(snap.Tags) > 0 {
+		tagIDs := make([]uint, 0, len(snap.Tags))
+		for _, tag := range snap.Tags {
+			tagIDs = append(tagIDs, tag.ID)
+		}
+		s.db.Model(&model.Tag{}).Where("id IN ?", tagIDs).Pluck("id", &req.TagIDs)
+	}
+	return req
+}
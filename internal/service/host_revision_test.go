@@ -0,0 +1,71 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/web-casa/webcasa/internal/model"
+)
+
+func TestHostRevisions_RecordAndRestore(t *testing.T) {
+	db := setupTestDB(t)
+	svc := setupTestHostService(t, db)
+	host := createTestHost(t, svc, "rev.example.com", 1, 0, 0, 1, 0)
+	var origHash string
+	db.Model(&model.BasicAuth{}).Where("host_id = ?", host.ID).Pluck("password_hash", &origHash)
+
+	version := host.Version
+	for _, upstream := range []string{"localhost:9001", "localhost:9002", "localhost:9003"} {
+		// The updates carry no basic auth, so they drop the credential.
+		updated, err := svc.Update(host.ID, &model.HostCreateRequest{
+			Domain:    "rev.example.com",
+			Upstreams: []model.UpstreamInput{{Address: upstream}},
+			Version:   version,
+			AuthorID:  7,
+			Author:    "alice",
+		})
+		if err != nil {
+			t.Fatalf("Update to %s: %v", upstream, err)
+		}
+		version = updated.Version
+	}
+
+	revs, err := svc.Revisions(host.ID)
+	if err != nil {
+		t.Fatalf("Revisions: %v", err)
+	}
+	// One revision for the create plus one per update, newest first.
+	if len(revs) != 4 {
+		t.Fatalf("%d revisions, want 4", len(revs))
+	}
+	if revs[0].Revision != 4 || revs[3].Revision != 1 {
+		t.Errorf("revision order = %d..%d, want 4..1", revs[0].Revision, revs[3].Revision)
+	}
+	if revs[0].Username != "alice" || revs[0].UserID != 7 {
+		t.Errorf("revision author = %q (%d), want alice (7)", revs[0].Username, revs[0].UserID)
+	}
+
+	restored, err := svc.RestoreRevision(host.ID, 1, 1, "bob")
+	if err != nil {
+		t.Fatalf("RestoreRevision: %v", err)
+	}
+	if len(restored.Upstreams) != 1 || restored.Upstreams[0].Address != "localhost:8080" {
+		t.Errorf("restored upstreams = %+v, want localhost:8080", restored.Upstreams)
+	}
+	if len(restored.BasicAuths) != 1 || restored.BasicAuths[0].PasswordHash != origHash {
+		t.Errorf("restore did not bring back the original basic auth credential")
+	}
+	if !boolVal(restored.Compression) || restored.CorsOrigins != host.CorsOrigins {
+		t.Errorf("restored options differ from revision 1: compression=%v cors_origins=%q",
+			boolVal(restored.Compression), restored.CorsOrigins)
+	}
+
+	revs, _ = svc.Revisions(host.ID)
+	if len(revs) != 5 || revs[0].Revision != restored.Version || revs[0].Username != "bob" {
+		t.Errorf("restore did not record a new revision: %d revisions, newest %+v", len(revs), revs[0])
+	}
+
+	if _, err := svc.RestoreRevision(host.ID, 99, 1, "bob"); !errors.Is(err, ErrRevisionNotFound) {
+		t.Errorf("unknown revision: err = %v, want ErrRevisionNotFound", err)
+	}
+}
@@ -0,0 +1,176 @@
+package service
+
+import (
+	"errors"
+	"log"
+
+	"github.com/web-casa/webcasa/internal/caddy"
+	"github.com/web-casa/webcasa/internal/model"
+)
+
+// maxConfigErrorLen bounds the validation output stored on a host.
+const maxConfigErrorLen = 2000
+
+// FlushConfigSafe applies the config like FlushConfig, for use at startup.
+// Hosts flagged earlier are retried. If Caddy rejects the full Caddyfile,
+// the hosts responsible are isolated, flagged with their config_error and
+// left out, so the remaining hosts are still served. It returns the hosts
+// left out.
+func (s *HostService) FlushConfigSafe() ([]model.Host, error) {
+	s.applier.runMu.Lock()
+	defer s.applier.runMu.Unlock()
+
+	hosts, dnsMap, err := s.renderInputs()
+	if err != nil {
+		return nil, err
+	}
+	_, err = s.installConfig(caddy.RenderCaddyfile(hosts, s.cfg, dnsMap))
+	if err == nil {
+		s.recordConfigErrors(nil)
+		return nil, nil
+	}
+	if !errors.Is(err, ErrInvalidGeneratedConfig) {
+		return nil, err
+	}
+
+	validate := func(subset []model.Host) error {
+		return s.caddyMgr.Validate(caddy.RenderCaddyfile(subset, s.cfg, dnsMap))
+	}
+	if vErr := validate(nil); vErr != nil {
+		// Nothing to isolate: the global options alone are rejected.
+		return nil, err
+	}
+	broken := isolateBrokenHosts(hosts, validate)
+	if len(broken) == 0 {
+		return nil, err
+	}
+
+	var good, bad []model.Host
+	for _, h := range hosts {
+		if msg, ok := broken[h.ID]; ok {
+			h.ConfigError = msg
+			bad = append(bad, h)
+		} else {
+			good = append(good, h)
+		}
+	}
+	if _, err := s.installConfig(caddy.RenderCaddyfile(good, s.cfg, dnsMap)); err != nil {
+		return nil, err
+	}
+	s.recordConfigErrors(bad)
+	for _, h := range bad {
+		log.Printf("⚠️  Safe mode: host %d (%s) left out of the Caddyfile: %s", h.ID, h.Domain, h.ConfigError)
+	}
+	return bad, nil
+}
+
+// isolateBrokenHosts returns, by host ID, the validation error of each host
+// whose block makes validate fail. Halves that validate on their own are
+// cleared with one call, so a single broken host
+// among n costs about 2·log2(n) validations. Failures that only a
+// combination of hosts causes are caught by a final pass adding the
+// remaining hosts one at a time.
+func isolateBrokenHosts(hosts []model.Host, validate func([]model.Host) error) map[uint]string {
+	broken := make(map[uint]string)
+	var bisect func(set []model.Host, err error)
+	bisect = func(set []model.Host, err error) {
+		if len(set) == 1 {
+			broken[set[0].ID] = err.Error()
+			return
+		}
+		mid := len(set) / 2
+		for _, half := range [][]model.Host{set[:mid], set[mid:]} {
+			if hErr := validate(half); hErr != nil {
+				bisect(half, hErr)
+			}
+		}
+	}
+	if err := validate(hosts); err != nil {
+		bisect(hosts, err)
+	}
+
+	var rest []model.Host
+	for _, h := range hosts {
+		if _, ok := broken[h.ID]; !ok {
+			rest = append(rest, h)
+		}
+	}
+	if validate(rest) == nil {
+		return broken
+	}
+	var accepted []model.Host
+	for _, h := range rest {
+		if err := validate(append(accepted[:len(accepted):len(accepted)], h)); err != nil {
+			broken[h.ID] = err.Error()
+			continue
+		}
+		accepted = append(accepted, h)
+	}
+	return broken
+}
+
+// recordConfigErrors stores the config errors of bad and clears them on
+// every other host.
+func (s *HostService) recordConfigErrors(bad []model.Host) {
+	ids := make([]uint, 0, len(bad))
+	for _, h := range bad {
+		s.flagConfigError(h.ID, h.ConfigError)
+		ids = append(ids, h.ID)
+	}
+	query := s.db.Model(&model.Host{}).Where("config_error <> ''")
+	if len(ids) > 0 {
+		query = query.Where("id NOT IN ?", ids)
+	}
+	if err := query.UpdateColumn("config_error", "").Error; err != nil {
+		log.Printf("Warning: failed to clear host config errors: %v", err)
+	}
+}
+
+// flagConfigError marks a host as breaking the Caddyfile so later applies
+// leave it out.
+func (s *HostService) flagConfigError(id uint, msg string) {
+	if len(msg) > maxConfigErrorLen {
+		msg = msg[:maxConfigErrorLen]
+	}
+	if err := s.db.Model(&model.Host{}).Where("id = ?", id).UpdateColumn("config_error", msg).Error; err != nil {
+		log.Printf("Warning: failed to flag config error on host %d: %v", id, err)
+	}
+}
+
+// flagRejectedHosts is called when applying a change to host id failed
+// with ErrInvalidGeneratedConfig. A debounced apply answers every change it
+// merged with the same error, so rather than blaming id, the hosts whose
+// blocks Caddy rejects are isolated and flagged, so they stop blocking other
+// edits. It reports whether id was left unflagged, in which case its change
+// can be applied again without the broken hosts.
+func (s *HostService) flagRejectedHosts(id uint) bool {
+	s.applier.runMu.Lock()
+	defer s.applier.runMu.Unlock()
+
+	hosts, dnsMap, err := s.renderInputs()
+	if err != nil {
+		log.Printf("Warning: failed to re-check rejected config: %v", err)
+		return false
+	}
+	validate := func(subset []model.Host) error {
+		return s.caddyMgr.Validate(caddy.RenderCaddyfile(subset, s.cfg, dnsMap))
+	}
+	if validate(nil) != nil {
+		// The global options alone are rejected: no host is to blame.
+		return false
+	}
+	broken := isolateBrokenHosts(unflaggedHosts(hosts), validate)
+	for brokenID, msg := range broken {
+		s.flagConfigError(brokenID, msg)
+	}
+	if _, ok := broken[id]; ok {
+		return false
+	}
+	// A concurrent caller sharing the failed apply may have flagged id first.
+	for _, h := range hosts {
+		if h.ID == id && h.ConfigError != "" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,190 @@
+package service
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/web-casa/webcasa/internal/model"
+)
+
+func TestFlushConfigSafe_IsolatesBrokenHost(t *testing.T) {
+	db := setupTestDB(t)
+	svc := setupTestHostService(t, db)
+
+	good := []string{"a.example.com", "b.example.com", "c.example.com", "d.example.com"}
+	for _, d := range good {
+		createTestHost(t, svc, d, 1, 0, 0, 0, 0)
+	}
+	broken := createTestHost(t, svc, "broken.example.com", 1, 0, 0, 0, 0)
+	// Break the host behind the service's back, as an older release or a
+	// Caddy upgrade could.
+	db.Model(&model.Host{}).Where("id = ?", broken.ID).Update("custom_directives", "bogus_directive on")
+	useValidatingCaddy(t, svc)
+
+	isolated, err := svc.FlushConfigSafe()
+	if err != nil {
+		t.Fatalf("FlushConfigSafe: %v", err)
+	}
+	if len(isolated) != 1 || isolated[0].ID != broken.ID {
+		t.Fatalf("isolated = %v, want only host %d", isolated, broken.ID)
+	}
+
+	content, _ := os.ReadFile(svc.cfg.CaddyfilePath)
+	for _, d := range good {
+		if !strings.Contains(string(content), d+" {") {
+			t.Errorf("good host %s missing from the Caddyfile", d)
+		}
+	}
+	if strings.Contains(string(content), "broken.example.com") {
+		t.Errorf("broken host rendered:\n%s", content)
+	}
+
+	var flagged []model.Host
+	db.Where("config_error <> ''").Find(&flagged)
+	if len(flagged) != 1 || flagged[0].ID != broken.ID || !strings.Contains(flagged[0].ConfigError, "bogus_directive") {
+		t.Errorf("flagged hosts = %+v, want only the broken one with Caddy's error", flagged)
+	}
+
+	// Normal applies keep leaving the flagged host out
+	if _, err := svc.FlushConfig(); err != nil {
+		t.Fatalf("FlushConfig with a flagged host: %v", err)
+	}
+
+	// Fixing the host clears the flag and serves it again
+	fixed, _ := svc.Get(broken.ID)
+	req := &model.HostCreateRequest{
+		Domain:    fixed.Domain,
+		Version:   fixed.Version,
+		Upstreams: []model.UpstreamInput{{Address: "localhost:8080"}},
+	}
+	if _, err := svc.Update(broken.ID, req); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	fixed, _ = svc.Get(broken.ID)
+	if fixed.ConfigError != "" {
+		t.Errorf("config_error not cleared by the fix: %q", fixed.ConfigError)
+	}
+	content, _ = os.ReadFile(svc.cfg.CaddyfilePath)
+	if !strings.Contains(string(content), "broken.example.com {") {
+		t.Errorf("fixed host not rendered:\n%s", content)
+	}
+}
+
+func TestFlushConfigSafe_ClearsStaleFlags(t *testing.T) {
+	db := setupTestDB(t)
+	svc := setupTestHostService(t, db)
+	host := createTestHost(t, svc, "a.example.com", 1, 0, 0, 0, 0)
+	db.Model(&model.Host{}).Where("id = ?", host.ID).Update("config_error", "old failure")
+
+	isolated, err := svc.FlushConfigSafe()
+	if err != nil || len(isolated) != 0 {
+		t.Fatalf("FlushConfigSafe = %v, %v; want nothing isolated", isolated, err)
+	}
+	if h, _ := svc.Get(host.ID); h.ConfigError != "" {
+		t.Errorf("stale config_error kept: %q", h.ConfigError)
+	}
+	content, _ := os.ReadFile(svc.cfg.CaddyfilePath)
+	if !strings.Contains(string(content), "a.example.com {") {
+		t.Errorf("retried host not rendered:\n%s", content)
+	}
+}
+
+// TestUpdate_MergedApplyFlagsOnlyBrokenHost saves a valid and a broken host
+// within one debounce window. The merged apply fails for both, but only the
+// broken host may be flagged; the valid one is applied again and served.
+func TestUpdate_MergedApplyFlagsOnlyBrokenHost(t *testing.T) {
+	db := setupTestDB(t)
+	svc := setupTestHostService(t, db)
+	good := createTestHost(t, svc, "good.example.com", 1, 0, 0, 0, 0)
+	bad := createTestHost(t, svc, "bad.example.com", 1, 0, 0, 0, 0)
+	useValidatingCaddy(t, svc)
+	svc.applier.delay = 200 * time.Millisecond
+
+	var wg sync.WaitGroup
+	var goodErr, badErr error
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		_, goodErr = svc.Update(good.ID, &model.HostCreateRequest{
+			Domain:    "good.example.com",
+			Version:   good.Version,
+			Upstreams: []model.UpstreamInput{{Address: "localhost:9090"}},
+		})
+	}()
+	go func() {
+		defer wg.Done()
+		_, badErr = svc.Update(bad.ID, &model.HostCreateRequest{
+			Domain:           "bad.example.com",
+			Version:          bad.Version,
+			Upstreams:        []model.UpstreamInput{{Address: "localhost:8080"}},
+			CustomDirectives: "bogus_directive on",
+		})
+	}()
+	wg.Wait()
+
+	if goodErr != nil {
+		t.Errorf("valid Update: %v", goodErr)
+	}
+	if !errors.Is(badErr, ErrInvalidGeneratedConfig) {
+		t.Errorf("broken Update: err = %v, want ErrInvalidGeneratedConfig", badErr)
+	}
+	if h, _ := svc.Get(good.ID); h.ConfigError != "" {
+		t.Errorf("valid host flagged: %q", h.ConfigError)
+	}
+	if h, _ := svc.Get(bad.ID); !strings.Contains(h.ConfigError, "bogus_directive") {
+		t.Errorf("broken host config_error = %q, want Caddy's error", h.ConfigError)
+	}
+	content, _ := os.ReadFile(svc.cfg.CaddyfilePath)
+	if !strings.Contains(string(content), "localhost:9090") {
+		t.Errorf("valid change not applied:\n%s", content)
+	}
+}
+
+func TestIsolateBrokenHosts(t *testing.T) {
+	var hosts []model.Host
+	for i := uint(1); i <= 16; i++ {
+		hosts = append(hosts, model.Host{ID: i})
+	}
+	has := func(set []model.Host, id uint) bool {
+		for _, h := range set {
+			if h.ID == id {
+				return true
+			}
+		}
+		return false
+	}
+
+	t.Run("single broken host", func(t *testing.T) {
+		calls := 0
+		broken := isolateBrokenHosts(hosts, func(set []model.Host) error {
+			calls++
+			if has(set, 11) {
+				return os.ErrInvalid
+			}
+			return nil
+		})
+		if len(broken) != 1 || broken[11] == "" {
+			t.Errorf("broken = %v, want host 11", broken)
+		}
+		if calls > 12 {
+			t.Errorf("%d validations for 16 hosts, want bisection", calls)
+		}
+	})
+
+	t.Run("conflicting pair", func(t *testing.T) {
+		// Hosts 3 and 12 only fail together, e.g. by claiming the same site.
+		broken := isolateBrokenHosts(hosts, func(set []model.Host) error {
+			if has(set, 3) && has(set, 12) {
+				return os.ErrInvalid
+			}
+			return nil
+		})
+		if len(broken) != 1 || broken[12] == "" {
+			t.Errorf("broken = %v, want the later host of the pair (12)", broken)
+		}
+	})
+}
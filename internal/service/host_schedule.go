@@ -0,0 +1,194 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"github.com/web-casa/webcasa/internal/model"
+)
+
+// Schedule actions passed to a HostScheduler's listener.
+const (
+	ScheduleEnable  = "enable"
+	ScheduleDisable = "disable"
+)
+
+// hostScheduleInterval is how often the scheduler looks for due actions;
+// cron's resolution is one minute.
+const hostScheduleInterval = time.Minute
+
+// ErrInvalidCron is returned by SetSchedule for an expression that does not
+// parse.
+var ErrInvalidCron = errors.New("invalid cron expression")
+
+// scheduleParser accepts standard 5-field expressions and descriptors such
+// as @daily. A CRON_TZ= prefix picks the time zone.
+var scheduleParser = cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// GetSchedule returns host id's schedule, empty when it has none.
+func (s *HostService) GetSchedule(id uint) (*model.HostSchedule, error) {
+	if _, err := s.Get(id); err != nil {
+		return nil, err
+	}
+	sched := model.HostSchedule{HostID: id}
+	if err := s.db.Where("host_id = ?", id).Limit(1).Find(&sched).Error; err != nil {
+		return nil, err
+	}
+	return &sched, nil
+}
+
+// SetSchedule sets the cron expressions that enable and disable host id.
+// Clearing both removes the schedule.
+func (s *HostService) SetSchedule(id uint, enableCron, disableCron string) (*model.HostSchedule, error) {
+	if _, err := s.Get(id); err != nil {
+		return nil, err
+	}
+	enableCron, disableCron = strings.TrimSpace(enableCron), strings.TrimSpace(disableCron)
+	for _, expr := range []string{enableCron, disableCron} {
+		if expr == "" {
+			continue
+		}
+		if _, err := scheduleParser.Parse(expr); err != nil {
+			return nil, fmt.Errorf("%w %q: %v", ErrInvalidCron, expr, err)
+		}
+	}
+
+	if enableCron == "" && disableCron == "" {
+		if err := s.db.Where("host_id = ?", id).Delete(&model.HostSchedule{}).Error; err != nil {
+			return nil, err
+		}
+		return &model.HostSchedule{HostID: id}, nil
+	}
+
+	sched := model.HostSchedule{HostID: id}
+	if err := s.db.Where("host_id = ?", id).Limit(1).Find(&sched).Error; err != nil {
+		return nil, err
+	}
+	sched.EnableCron, sched.DisableCron = enableCron, disableCron
+	if err := s.db.Save(&sched).Error; err != nil {
+		return nil, fmt.Errorf("failed to save schedule: %w", err)
+	}
+	return &sched, nil
+}
+
+// HostScheduler runs host schedules: each minute it enables or disables,
+// through Toggle, the hosts whose schedule fired since the previous check.
+type HostScheduler struct {
+	svc      *HostService
+	onToggle func(host *model.Host, action string)
+	now      func() time.Time
+
+	last     time.Time // when the previous check ran
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewHostScheduler creates a scheduler for svc's hosts. onToggle, if
+// non-nil, is called for every host the scheduler switches.
+func NewHostScheduler(svc *HostService, onToggle func(host *model.Host, action string)) *HostScheduler {
+	return &HostScheduler{
+		svc:      svc,
+		onToggle: onToggle,
+		now:      time.Now,
+		stop:     make(chan struct{}),
+	}
+}
+
+// Start runs the checks in the background.
+func (hs *HostScheduler) Start() {
+	hs.last = hs.now()
+	go func() {
+		ticker := time.NewTicker(hostScheduleInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-hs.stop:
+				return
+			case <-ticker.C:
+				hs.check()
+			}
+		}
+	}()
+}
+
+// Stop ends the background checks.
+func (hs *HostScheduler) Stop() {
+	hs.stopOnce.Do(func() { close(hs.stop) })
+}
+
+// check applies the actions due since the previous check. The first check
+// only records the time.
+func (hs *HostScheduler) check() {
+	now := hs.now()
+	since := hs.last
+	hs.last = now
+	if since.IsZero() || !now.After(since) {
+		return
+	}
+
+	var schedules []model.HostSchedule
+	if err := hs.svc.db.Find(&schedules).Error; err != nil {
+		log.Printf("Warning: failed to load host schedules: %v", err)
+		return
+	}
+	for _, sched := range schedules {
+		action := dueAction(sched, since, now)
+		if action == "" {
+			continue
+		}
+		// Hosts in the trash are skipped until restored.
+		host, err := hs.svc.Get(sched.HostID)
+		if err != nil {
+			continue
+		}
+		if boolVal(host.Enabled) == (action == ScheduleEnable) {
+			continue
+		}
+		toggled, err := hs.svc.Toggle(host.ID)
+		if err != nil {
+			log.Printf("Warning: scheduled %s of host %s failed: %v", action, host.Domain, err)
+			continue
+		}
+		log.Printf("Schedule: host %s %sd", host.Domain, action)
+		if hs.onToggle != nil {
+			hs.onToggle(toggled, action)
+		}
+	}
+}
+
+// dueAction returns the action of sched that fired last in (since, now],
+// or "" when neither fired. Disable wins a tie.
+func dueAction(sched model.HostSchedule, since, now time.Time) string {
+	enableAt := lastFire(sched.EnableCron, since, now)
+	disableAt := lastFire(sched.DisableCron, since, now)
+	switch {
+	case enableAt.IsZero() && disableAt.IsZero():
+		return ""
+	case enableAt.After(disableAt):
+		return ScheduleEnable
+	default:
+		return ScheduleDisable
+	}
+}
+
+// lastFire returns the latest time expr fires in (since, now], or the zero
+// time when it does not fire then or does not parse.
+func lastFire(expr string, since, now time.Time) time.Time {
+	if expr == "" {
+		return time.Time{}
+	}
+	sched, err := scheduleParser.Parse(expr)
+	if err != nil {
+		return time.Time{}
+	}
+	var fired time.Time
+	for t := sched.Next(since); !t.IsZero() && !t.After(now); t = sched.Next(t) {
+		fired = t
+	}
+	return fired
+}
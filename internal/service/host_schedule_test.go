@@ -0,0 +1,150 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/web-casa/webcasa/internal/model"
+)
+
+// fakeClock is a settable clock for driving a HostScheduler.
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) Now() time.Time { return c.t }
+
+func newTestScheduler(svc *HostService, clock *fakeClock) (*HostScheduler, *[]string) {
+	var actions []string
+	hs := NewHostScheduler(svc, func(host *model.Host, action string) {
+		actions = append(actions, host.Domain+" "+action)
+	})
+	hs.now = clock.Now
+	hs.check() // records the start time
+	return hs, &actions
+}
+
+func TestHostScheduler_Enable(t *testing.T) {
+	db := setupTestDB(t)
+	svc := setupTestHostService(t, db)
+	host := createTestHost(t, svc, "office.example.com", 1, 0, 0, 0, 0)
+	if _, err := svc.Toggle(host.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.SetSchedule(host.ID, "0 9 * * 1-5", "0 18 * * 1-5"); err != nil {
+		t.Fatalf("SetSchedule: %v", err)
+	}
+
+	// Monday 08:59, then past 09:00.
+	clock := &fakeClock{t: time.Date(2026, 10, 12, 8, 59, 0, 0, time.Local)}
+	hs, actions := newTestScheduler(svc, clock)
+	clock.t = clock.t.Add(90 * time.Second)
+	hs.check()
+
+	got, _ := svc.Get(host.ID)
+	if !boolVal(got.Enabled) {
+		t.Error("host not enabled at 09:00")
+	}
+	if len(*actions) != 1 || (*actions)[0] != "office.example.com enable" {
+		t.Errorf("listener got %v", *actions)
+	}
+
+	// Nothing fires in the next minute.
+	clock.t = clock.t.Add(time.Minute)
+	hs.check()
+	if len(*actions) != 1 {
+		t.Errorf("unexpected actions %v", *actions)
+	}
+}
+
+func TestHostScheduler_Disable(t *testing.T) {
+	db := setupTestDB(t)
+	svc := setupTestHostService(t, db)
+	host := createTestHost(t, svc, "office.example.com", 1, 0, 0, 0, 0)
+	if _, err := svc.SetSchedule(host.ID, "0 9 * * 1-5", "0 18 * * 1-5"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Monday 17:59 to 18:00.
+	clock := &fakeClock{t: time.Date(2026, 10, 12, 17, 59, 30, 0, time.Local)}
+	hs, actions := newTestScheduler(svc, clock)
+	clock.t = clock.t.Add(time.Minute)
+	hs.check()
+
+	got, _ := svc.Get(host.ID)
+	if boolVal(got.Enabled) {
+		t.Error("host still enabled after 18:00")
+	}
+	if len(*actions) != 1 || (*actions)[0] != "office.example.com disable" {
+		t.Errorf("listener got %v", *actions)
+	}
+
+	// A disable firing for an already disabled host changes nothing.
+	if _, err := svc.SetSchedule(host.ID, "", "0 18 * * 1-5"); err != nil {
+		t.Fatal(err)
+	}
+	clock.t = time.Date(2026, 10, 13, 17, 59, 30, 0, time.Local)
+	hs.check()
+	clock.t = clock.t.Add(time.Minute)
+	hs.check()
+	if len(*actions) != 1 {
+		t.Errorf("already disabled host toggled again: %v", *actions)
+	}
+}
+
+func TestSetSchedule_Validates(t *testing.T) {
+	db := setupTestDB(t)
+	svc := setupTestHostService(t, db)
+	host := createTestHost(t, svc, "office.example.com", 1, 0, 0, 0, 0)
+
+	for _, expr := range []string{"every morning", "61 * * * *", "* * * *"} {
+		if _, err := svc.SetSchedule(host.ID, expr, ""); !errors.Is(err, ErrInvalidCron) {
+			t.Errorf("SetSchedule(%q): err = %v, want ErrInvalidCron", expr, err)
+		}
+	}
+
+	if _, err := svc.SetSchedule(host.ID, "@daily", " 30 22 * * * "); err != nil {
+		t.Fatalf("SetSchedule: %v", err)
+	}
+	sched, err := svc.GetSchedule(host.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sched.EnableCron != "@daily" || sched.DisableCron != "30 22 * * *" {
+		t.Errorf("schedule = %+v", sched)
+	}
+
+	// Clearing both expressions removes the schedule.
+	if _, err := svc.SetSchedule(host.ID, "", ""); err != nil {
+		t.Fatal(err)
+	}
+	var count int64
+	db.Model(&model.HostSchedule{}).Where("host_id = ?", host.ID).Count(&count)
+	if count != 0 {
+		t.Errorf("%d schedules left after clearing", count)
+	}
+
+	if _, err := svc.SetSchedule(9999, "@daily", ""); err == nil {
+		t.Error("schedule for an unknown host accepted")
+	}
+}
+
+func TestDueAction(t *testing.T) {
+	sched := model.HostSchedule{EnableCron: "0 9 * * *", DisableCron: "0 18 * * *"}
+	day := func(h, m int) time.Time { return time.Date(2026, 10, 12, h, m, 0, 0, time.Local) }
+
+	cases := []struct {
+		since, now time.Time
+		want       string
+	}{
+		{day(8, 0), day(8, 59), ""},
+		{day(8, 59), day(9, 0), ScheduleEnable},
+		{day(17, 59), day(18, 1), ScheduleDisable},
+		// A long gap covering both runs the later action.
+		{day(8, 0), day(19, 0), ScheduleDisable},
+	}
+	for _, tc := range cases {
+		if got := dueAction(sched, tc.since, tc.now); got != tc.want {
+			t.Errorf("dueAction(%s..%s) = %q, want %q", tc.since.Format("15:04"), tc.now.Format("15:04"), got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"github.com/web-casa/webcasa/internal/model"
+)
+
+// HostSummary is the slice of a host a table view needs. Listing summaries
+// skips loading the host's upstreams, headers, access rules, routes and
+// basic auth rows.
+type HostSummary struct {
+	ID            uint     `json:"id"`
+	Domain        string   `json:"domain"`
+	HostType      string   `json:"host_type"`
+	Enabled       bool     `json:"enabled"`
+	TLSMode       string   `json:"tls_mode"`
+	GroupID       *uint    `json:"group_id"`
+	GroupName     string   `json:"group_name,omitempty"`
+	Tags          []string `json:"tags"`
+	UpstreamCount int      `json:"upstream_count"`
+}
+
+// ListSummary returns one page of host summaries matching filter along with
+// the number of hosts matching it across all pages. Upstreams are counted
+// in a single grouped query rather than loaded.
+func (s *HostService) ListSummary(filter HostListFilter) ([]HostSummary, int64, error) {
+	query, total, err := s.pageQuery(filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var hosts []model.Host
+	err = query.Select("hosts.id", "hosts.domain", "hosts.host_type", "hosts.enabled", "hosts.tls_mode", "hosts.group_id").
+		Preload("Group").Preload("Tags").Find(&hosts).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	counts := make(map[uint]int, len(hosts))
+	if len(hosts) > 0 {
+		ids := make([]uint, len(hosts))
+		for i, h := range hosts {
+			ids[i] = h.ID
+		}
+		var rows []struct {
+			HostID uint
+			Count  int
+		}
+		if err := s.db.Model(&model.Upstream{}).Select("host_id, COUNT(*) AS count").
+			Where("host_id IN ?", ids).Group("host_id").Scan(&rows).Error; err != nil {
+			return nil, 0, err
+		}
+		for _, r := range rows {
+			counts[r.HostID] = r.Count
+		}
+	}
+
+	summaries := make([]HostSummary, 0, len(hosts))
+	for _, h := range hosts {
+		sum := HostSummary{
+			ID:            h.ID,
+			Domain:        h.Domain,
+			HostType:      h.HostType,
+			Enabled:       boolVal(h.Enabled),
+			TLSMode:       h.TLSMode,
+			GroupID:       h.GroupID,
+			Tags:          make([]string, 0, len(h.Tags)),
+			UpstreamCount: counts[h.ID],
+		}
+		if h.Group != nil {
+			sum.GroupName = h.Group.Name
+		}
+		for _, tag := range h.Tags {
+			sum.Tags = append(sum.Tags, tag.Name)
+		}
+		summaries = append(summaries, sum)
+	}
+	return summaries, total, nil
+}
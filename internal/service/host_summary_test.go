@@ -0,0 +1,109 @@
+package service
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/web-casa/webcasa/internal/model"
+	"gorm.io/gorm"
+)
+
+func TestListSummary_MatchesHosts(t *testing.T) {
+	db := setupTestDB(t)
+	svc := setupTestHostService(t, db)
+	group := model.Group{Name: "prod"}
+	tag := model.Tag{Name: "api"}
+	if err := db.Create(&group).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&tag).Error; err != nil {
+		t.Fatal(err)
+	}
+	tagged, err := svc.Create(&model.HostCreateRequest{
+		Domain:    "api.example.com",
+		Upstreams: []model.UpstreamInput{{Address: "localhost:8080"}, {Address: "localhost:8081"}, {Address: "localhost:8082"}},
+		GroupID:   &group.ID,
+		TagIDs:    []uint{tag.ID},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	createTestHost(t, svc, "web.example.com", 1, 2, 2, 1, 1)
+
+	summaries, total, err := svc.ListSummary(HostListFilter{Sort: "domain"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hosts, _, err := svc.ListPage(HostListFilter{Sort: "domain"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 2 || len(summaries) != len(hosts) {
+		t.Fatalf("got %d summaries (total %d), want %d", len(summaries), total, len(hosts))
+	}
+	for i, sum := range summaries {
+		h := hosts[i]
+		if sum.ID != h.ID || sum.Domain != h.Domain || sum.HostType != h.HostType || sum.TLSMode != h.TLSMode || sum.Enabled != boolVal(h.Enabled) {
+			t.Errorf("summary %+v does not match host %s", sum, h.Domain)
+		}
+		if sum.UpstreamCount != len(h.Upstreams) {
+			t.Errorf("%s: upstream count = %d, want %d", h.Domain, sum.UpstreamCount, len(h.Upstreams))
+		}
+		if len(sum.Tags) != len(h.Tags) {
+			t.Errorf("%s: tags = %v, want %d", h.Domain, sum.Tags, len(h.Tags))
+		}
+	}
+
+	first := summaries[0]
+	if first.ID != tagged.ID || first.GroupName != "prod" || len(first.Tags) != 1 || first.Tags[0] != "api" || first.UpstreamCount != 3 {
+		t.Errorf("tagged host summary = %+v", first)
+	}
+}
+
+func TestListSummary_SkipsSubTables(t *testing.T) {
+	db := setupTestDB(t)
+	svc := setupTestHostService(t, db)
+	for _, d := range []string{"a.example.com", "b.example.com", "c.example.com"} {
+		createTestHost(t, svc, d, 2, 2, 2, 2, 2)
+	}
+
+	var mu sync.Mutex
+	var queries []string
+	record := func(tx *gorm.DB) {
+		mu.Lock()
+		queries = append(queries, tx.Statement.SQL.String())
+		mu.Unlock()
+	}
+	if err := db.Callback().Query().After("gorm:query").Register("test:record_sql", record); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Callback().Row().After("gorm:row").Register("test:record_sql", record); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		db.Callback().Query().Remove("test:record_sql")
+		db.Callback().Row().Remove("test:record_sql")
+	})
+
+	if _, _, err := svc.ListSummary(HostListFilter{}); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	for _, q := range queries {
+		for _, table := range []string{"custom_headers", "access_rules", "routes", "basic_auths"} {
+			if strings.Contains(q, table) {
+				t.Errorf("summary queried %s: %s", table, q)
+			}
+		}
+		if strings.Contains(q, "upstreams") && !strings.Contains(q, "COUNT(") {
+			t.Errorf("summary loaded upstream rows: %s", q)
+		}
+	}
+	// The host count, hosts, host_tags and upstream counts; the group and
+	// tag preloads are skipped when no host has any.
+	if len(queries) > 6 {
+		t.Errorf("summary ran %d queries, want a fixed handful: %v", len(queries), queries)
+	}
+}
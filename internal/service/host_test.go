@@ -0,0 +1,622 @@
+package service
+
+import (
+	"errors"
+	"maps"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/web-casa/webcasa/internal/model"
+	"gorm.io/gorm"
+)
+
+func TestHostCreate_MaxRequestBodySize(t *testing.T) {
+	db := setupTestDB(t)
+	svc := setupTestHostService(t, db)
+
+	upstreams := []model.UpstreamInput{{Address: "localhost:3000"}}
+	_, err := svc.Create(&model.HostCreateRequest{Domain: "bad.example.com", Upstreams: upstreams, MaxRequestBodySize: "ten megs"})
+	if err == nil || !strings.Contains(err.Error(), "max_request_body_size") {
+		t.Fatalf("unparseable size: err = %v, want max_request_body_size error", err)
+	}
+
+	host, err := svc.Create(&model.HostCreateRequest{Domain: "ok.example.com", Upstreams: upstreams, MaxRequestBodySize: " 10MB "})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if host.MaxRequestBodySize != "10MB" {
+		t.Errorf("MaxRequestBodySize = %q, want 10MB", host.MaxRequestBodySize)
+	}
+
+	_, err = svc.Update(host.ID, &model.HostCreateRequest{Domain: "ok.example.com", Upstreams: upstreams, MaxRequestBodySize: "5XB", Version: host.Version})
+	if err == nil {
+		t.Error("Update accepted an unparseable size")
+	}
+}
+
+func TestHostUpdate_Version(t *testing.T) {
+	db := setupTestDB(t)
+	svc := setupTestHostService(t, db)
+	host := createTestHost(t, svc, "v.example.com", 1, 0, 0, 0, 0)
+	if host.Version != 1 {
+		t.Fatalf("new host Version = %d, want 1", host.Version)
+	}
+
+	upstreams := []model.UpstreamInput{{Address: "localhost:3000"}}
+	updated, err := svc.Update(host.ID, &model.HostCreateRequest{Domain: "v2.example.com", Upstreams: upstreams, Version: 1})
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if updated.Version != 2 || updated.Domain != "v2.example.com" {
+		t.Errorf("after update: Version=%d Domain=%q, want 2 v2.example.com", updated.Version, updated.Domain)
+	}
+
+	// A second editor still holding version 1 must not overwrite the change.
+	_, err = svc.Update(host.ID, &model.HostCreateRequest{Domain: "stale.example.com", Upstreams: upstreams, Version: 1})
+	if !errors.Is(err, ErrStaleHost) {
+		t.Fatalf("stale update: err = %v, want ErrStaleHost", err)
+	}
+	current, err := svc.Get(host.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current.Version != 2 || current.Domain != "v2.example.com" {
+		t.Errorf("stale update changed host: Version=%d Domain=%q", current.Version, current.Domain)
+	}
+}
+
+func TestHostUpdate_FailedWriteKeepsVersionAndRows(t *testing.T) {
+	db := setupTestDB(t)
+	svc := setupTestHostService(t, db)
+	host := createTestHost(t, svc, "tx.example.com", 2, 1, 0, 0, 0)
+
+	// Fail the update partway through, after the upstreams were replaced.
+	db.Callback().Create().Before("gorm:create").Register("test:fail_headers", func(tx *gorm.DB) {
+		if tx.Statement.Table == "custom_headers" {
+			tx.AddError(errors.New("disk full"))
+		}
+	})
+	req := &model.HostCreateRequest{
+		Domain:        "tx2.example.com",
+		Upstreams:     []model.UpstreamInput{{Address: "localhost:9999"}},
+		CustomHeaders: []model.HeaderInput{{Name: "X-New", Value: "1"}},
+		Version:       host.Version,
+	}
+	if _, err := svc.Update(host.ID, req); err == nil || !strings.Contains(err.Error(), "disk full") {
+		t.Fatalf("Update: err = %v, want the header write failure", err)
+	}
+	db.Callback().Create().Remove("test:fail_headers")
+
+	current, err := svc.Get(host.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current.Version != host.Version || current.Domain != "tx.example.com" {
+		t.Errorf("failed update committed: Version=%d Domain=%q", current.Version, current.Domain)
+	}
+	if len(current.Upstreams) != 2 || current.Upstreams[0].Address != "localhost:8080" || len(current.CustomHeaders) != 1 {
+		t.Errorf("failed update replaced sub-tables: upstreams=%+v headers=%+v", current.Upstreams, current.CustomHeaders)
+	}
+
+	// The same editor can retry with the version they hold.
+	if _, err := svc.Update(host.ID, req); err != nil {
+		t.Fatalf("retry: %v", err)
+	}
+}
+
+func TestHostBatch(t *testing.T) {
+	db := setupTestDB(t)
+	svc := setupTestHostService(t, db)
+	a := createTestHost(t, svc, "a.example.com", 1, 0, 0, 0, 0)
+	b := createTestHost(t, svc, "b.example.com", 1, 0, 0, 0, 0)
+	c := createTestHost(t, svc, "c.example.com", 1, 0, 0, 0, 0)
+
+	enabled := func(id uint) bool {
+		var h model.Host
+		db.First(&h, id)
+		return boolVal(h.Enabled)
+	}
+
+	results, err := svc.Batch([]uint{a.ID, 999, b.ID, a.ID}, "disable")
+	if err != nil {
+		t.Fatalf("Batch disable: %v", err)
+	}
+	want := []BatchResult{{ID: a.ID, OK: true}, {ID: 999, Error: "host not found"}, {ID: b.ID, OK: true}}
+	if len(results) != len(want) {
+		t.Fatalf("results = %+v, want %+v", results, want)
+	}
+	for i := range want {
+		if results[i] != want[i] {
+			t.Errorf("results[%d] = %+v, want %+v", i, results[i], want[i])
+		}
+	}
+	if enabled(a.ID) || enabled(b.ID) || !enabled(c.ID) {
+		t.Errorf("after disable: a=%v b=%v c=%v", enabled(a.ID), enabled(b.ID), enabled(c.ID))
+	}
+
+	if _, err := svc.Batch([]uint{a.ID}, "enable"); err != nil || !enabled(a.ID) {
+		t.Errorf("Batch enable: err=%v enabled=%v", err, enabled(a.ID))
+	}
+
+	results, err = svc.Batch([]uint{b.ID, c.ID, 1000}, "delete")
+	if err != nil {
+		t.Fatalf("Batch delete: %v", err)
+	}
+	if !results[0].OK || !results[1].OK || results[2].OK {
+		t.Errorf("delete results = %+v", results)
+	}
+	var count int64
+	db.Model(&model.Host{}).Count(&count)
+	if count != 1 {
+		t.Errorf("%d hosts left after delete, want 1", count)
+	}
+
+	if _, err := svc.Batch([]uint{a.ID}, "restart"); err == nil {
+		t.Error("unknown action accepted")
+	}
+	if _, err := svc.Batch(nil, "enable"); err == nil {
+		t.Error("empty selection accepted")
+	}
+}
+
+// countHostRows returns how many sub-table rows reference hostID.
+func countHostRows(t *testing.T, db *gorm.DB, hostID uint) map[string]int64 {
+	t.Helper()
+	counts := make(map[string]int64)
+	for name, m := range map[string]interface{}{
+		"upstreams": &model.Upstream{}, "custom_headers": &model.CustomHeader{},
+		"access_rules": &model.AccessRule{}, "basic_auths": &model.BasicAuth{},
+		"routes": &model.Route{}, "host_tags": &model.HostTag{},
+	} {
+		var n int64
+		db.Model(m).Where("host_id = ?", hostID).Count(&n)
+		counts[name] = n
+	}
+	return counts
+}
+
+func TestHostTrash_DeleteRestore(t *testing.T) {
+	db := setupTestDB(t)
+	svc := setupTestHostService(t, db)
+	host := createTestHost(t, svc, "trash.example.com", 2, 1, 1, 1, 1)
+	tag := model.Tag{Name: "web"}
+	db.Create(&tag)
+	db.Create(&model.HostTag{HostID: host.ID, TagID: tag.ID})
+	before := countHostRows(t, db, host.ID)
+
+	if err := svc.Delete(host.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if hosts, _ := svc.List(); len(hosts) != 0 {
+		t.Errorf("List returned %d hosts after delete, want 0", len(hosts))
+	}
+	content, _ := os.ReadFile(svc.cfg.CaddyfilePath)
+	if strings.Contains(string(content), "trash.example.com") {
+		t.Errorf("trashed host still rendered:\n%s", content)
+	}
+	trash, err := svc.Trash()
+	if err != nil || len(trash) != 1 || trash[0].ID != host.ID || len(trash[0].Upstreams) != 2 {
+		t.Fatalf("Trash = %+v, %v; want the deleted host with its upstreams", trash, err)
+	}
+
+	restored, err := svc.Restore(host.ID)
+	if err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	if restored.Domain != "trash.example.com" || len(restored.Tags) != 1 {
+		t.Errorf("restored host = %q with %d tags", restored.Domain, len(restored.Tags))
+	}
+	if after := countHostRows(t, db, host.ID); !maps.Equal(before, after) {
+		t.Errorf("sub-table rows after restore = %v, want %v", after, before)
+	}
+	if _, err := svc.Restore(host.ID); !errors.Is(err, ErrNotInTrash) {
+		t.Errorf("restoring a live host: err = %v, want ErrNotInTrash", err)
+	}
+}
+
+func TestHostTrash_RestoreDomainTaken(t *testing.T) {
+	db := setupTestDB(t)
+	svc := setupTestHostService(t, db)
+	old := createTestHost(t, svc, "reuse.example.com", 1, 0, 0, 0, 0)
+	if err := svc.Delete(old.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	// The trashed host must not block re-creating its domain...
+	createTestHost(t, svc, "reuse.example.com", 1, 0, 0, 0, 0)
+
+	// ...but it then cannot be restored alongside the new one.
+	if _, err := svc.Restore(old.ID); !errors.Is(err, ErrDomainTaken) {
+		t.Fatalf("Restore: err = %v, want ErrDomainTaken", err)
+	}
+	if trash, _ := svc.Trash(); len(trash) != 1 {
+		t.Errorf("%d hosts in trash after failed restore, want 1", len(trash))
+	}
+}
+
+func TestHostTrash_Purge(t *testing.T) {
+	db := setupTestDB(t)
+	svc := setupTestHostService(t, db)
+	host := createTestHost(t, svc, "purge.example.com", 2, 1, 1, 1, 1)
+	tag := model.Tag{Name: "old"}
+	db.Create(&tag)
+	db.Create(&model.HostTag{HostID: host.ID, TagID: tag.ID})
+
+	if err := svc.Purge(host.ID); !errors.Is(err, ErrNotInTrash) {
+		t.Fatalf("purging a live host: err = %v, want ErrNotInTrash", err)
+	}
+	if err := svc.Delete(host.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.Purge(host.ID); err != nil {
+		t.Fatalf("Purge: %v", err)
+	}
+
+	var n int64
+	db.Unscoped().Model(&model.Host{}).Where("id = ?", host.ID).Count(&n)
+	if n != 0 {
+		t.Error("host row still present after purge")
+	}
+	for table, count := range countHostRows(t, db, host.ID) {
+		if count != 0 {
+			t.Errorf("%d %s rows left after purge", count, table)
+		}
+	}
+	if trash, _ := svc.Trash(); len(trash) != 0 {
+		t.Errorf("%d hosts in trash after purge, want 0", len(trash))
+	}
+}
+
+func TestHostCreate_DomainOverlap(t *testing.T) {
+	db := setupTestDB(t)
+	svc := setupTestHostService(t, db)
+	upstreams := []model.UpstreamInput{{Address: "localhost:3000"}}
+	create := func(domain string) (*model.Host, error) {
+		return svc.Create(&model.HostCreateRequest{Domain: domain, Upstreams: upstreams})
+	}
+
+	// Default policy only warns.
+	wild, err := create("*.example.com")
+	if err != nil {
+		t.Fatalf("Create wildcard: %v", err)
+	}
+	if wild.Warning != nil {
+		t.Errorf("Create wildcard: unexpected warning %+v", wild.Warning)
+	}
+	warned, err := create("warn.example.com")
+	if err != nil {
+		t.Fatalf("overlap rejected while reject_domain_overlap is off: %v", err)
+	}
+	if w := warned.Warning; w == nil || w.Key != "warning.domain_overlap" || w.HostID != wild.ID || w.Domain != "*.example.com" {
+		t.Errorf("Create overlapping: warning = %+v, want overlap with host %d", w, wild.ID)
+	}
+	other, err := create("plain.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	updated, err := svc.Update(other.ID, &model.HostCreateRequest{Domain: "moved.example.com", Upstreams: upstreams, Version: other.Version})
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if w := updated.Warning; w == nil || w.HostID != wild.ID {
+		t.Errorf("Update overlapping: warning = %+v, want overlap with host %d", w, wild.ID)
+	}
+
+	db.Create(&model.Setting{Key: "reject_domain_overlap", Value: "true"})
+
+	t.Run("exact under wildcard", func(t *testing.T) {
+		_, err := create("app.example.com")
+		var overlap *DomainOverlapError
+		if !errors.As(err, &overlap) || overlap.HostID != wild.ID {
+			t.Fatalf("err = %v, want overlap with host %d", err, wild.ID)
+		}
+	})
+
+	t.Run("wildcard covers exact", func(t *testing.T) {
+		exact, err := create("api.other.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = create("*.other.com")
+		var overlap *DomainOverlapError
+		if !errors.As(err, &overlap) || overlap.HostDomain != exact.Domain {
+			t.Fatalf("err = %v, want overlap with %s", err, exact.Domain)
+		}
+	})
+
+	t.Run("no overlap", func(t *testing.T) {
+		for _, domain := range []string{"example.com", "a.b.example.com", "*.sub.example.com"} {
+			if _, err := create(domain); err != nil {
+				t.Errorf("Create(%s): %v", domain, err)
+			}
+		}
+	})
+}
+
+func TestHostCreate_Aliases(t *testing.T) {
+	db := setupTestDB(t)
+	svc := setupTestHostService(t, db)
+	upstreams := []model.UpstreamInput{{Address: "localhost:3000"}}
+
+	host, err := svc.Create(&model.HostCreateRequest{
+		Domain: "example.com", Aliases: " www.example.com, example.com,WWW.example.com ,example.org", Upstreams: upstreams,
+	})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if host.Aliases != "www.example.com,example.org" {
+		t.Errorf("Aliases = %q, want www.example.com,example.org", host.Aliases)
+	}
+
+	// A new host may not claim another host's alias, as primary or alias.
+	if _, err := svc.Create(&model.HostCreateRequest{Domain: "example.org", Upstreams: upstreams}); err == nil {
+		t.Error("primary domain duplicating an alias accepted")
+	}
+	other, err := svc.Create(&model.HostCreateRequest{Domain: "other.com", Upstreams: upstreams})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = svc.Update(other.ID, &model.HostCreateRequest{
+		Domain: "other.com", Aliases: "www.example.com", Upstreams: upstreams, Version: other.Version,
+	})
+	if err == nil || !strings.Contains(err.Error(), "www.example.com") {
+		t.Errorf("alias duplicating another host's name: err = %v", err)
+	}
+	if _, err := svc.Create(&model.HostCreateRequest{Domain: "bad.com", Aliases: "bad host{", Upstreams: upstreams}); err == nil {
+		t.Error("invalid alias accepted")
+	}
+
+	// Keeping a host's own aliases on update is not a conflict.
+	if _, err := svc.Update(host.ID, &model.HostCreateRequest{
+		Domain: "example.com", Aliases: host.Aliases, Upstreams: upstreams, Version: host.Version,
+	}); err != nil {
+		t.Errorf("Update keeping own aliases: %v", err)
+	}
+}
+
+func TestHostCreate_NormalizesDomain(t *testing.T) {
+	db := setupTestDB(t)
+	svc := setupTestHostService(t, db)
+	upstreams := []model.UpstreamInput{{Address: "localhost:3000"}}
+
+	for in, want := range map[string]string{
+		"Upper.Example.COM": "upper.example.com",
+		"dot.example.com.":  "dot.example.com",
+		"münchen.de":        "xn--mnchen-3ya.de",
+	} {
+		host, err := svc.Create(&model.HostCreateRequest{Domain: in, Upstreams: upstreams})
+		if err != nil {
+			t.Fatalf("Create(%q): %v", in, err)
+		}
+		if host.Domain != want {
+			t.Errorf("Create(%q): Domain = %q, want %q", in, host.Domain, want)
+		}
+	}
+
+	var idn model.Host
+	if err := db.Where("domain = ?", "xn--mnchen-3ya.de").First(&idn).Error; err != nil {
+		t.Fatal(err)
+	}
+	if idn.DisplayDomain != "münchen.de" {
+		t.Errorf("DisplayDomain = %q, want münchen.de", idn.DisplayDomain)
+	}
+
+	// Spellings of a stored domain are the same domain.
+	for _, dup := range []string{"UPPER.example.com", "upper.example.com.", "MÜNCHEN.de", "xn--mnchen-3ya.de"} {
+		if _, err := svc.Create(&model.HostCreateRequest{Domain: dup, Upstreams: upstreams}); err == nil {
+			t.Errorf("Create(%q) duplicating a stored domain accepted", dup)
+		}
+	}
+
+	updated, err := svc.Update(idn.ID, &model.HostCreateRequest{
+		Domain: "Bücher.example.com.", Aliases: "München.de", Upstreams: upstreams, Version: idn.Version,
+	})
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if updated.Domain != "xn--bcher-kva.example.com" || updated.DisplayDomain != "bücher.example.com" {
+		t.Errorf("Update: Domain = %q, DisplayDomain = %q", updated.Domain, updated.DisplayDomain)
+	}
+	if updated.Aliases != "xn--mnchen-3ya.de" {
+		t.Errorf("Update: Aliases = %q, want xn--mnchen-3ya.de", updated.Aliases)
+	}
+}
+
+func TestImportAll_NormalizesDomains(t *testing.T) {
+	db := setupTestDB(t)
+	svc := setupTestHostService(t, db)
+
+	err := svc.ImportAll(&model.ExportData{Hosts: []model.Host{
+		{Domain: "Shop.Example.COM.", Aliases: "WWW.Shop.example.com", HostType: "proxy", Upstreams: []model.Upstream{{Address: "localhost:3000"}}},
+		{Domain: "münchen.de", HostType: "proxy", Upstreams: []model.Upstream{{Address: "localhost:3001"}}},
+	}})
+	if err != nil {
+		t.Fatalf("ImportAll: %v", err)
+	}
+
+	var hosts []model.Host
+	db.Order("id ASC").Find(&hosts)
+	if len(hosts) != 2 {
+		t.Fatalf("imported %d hosts, want 2", len(hosts))
+	}
+	if hosts[0].Domain != "shop.example.com" || hosts[0].Aliases != "www.shop.example.com" {
+		t.Errorf("host 1: Domain = %q, Aliases = %q", hosts[0].Domain, hosts[0].Aliases)
+	}
+	if hosts[1].Domain != "xn--mnchen-3ya.de" || hosts[1].DisplayDomain != "münchen.de" {
+		t.Errorf("host 2: Domain = %q, DisplayDomain = %q", hosts[1].Domain, hosts[1].DisplayDomain)
+	}
+}
+
+func TestHostCreate_SecurityHeadersConfig(t *testing.T) {
+	db := setupTestDB(t)
+	svc := setupTestHostService(t, db)
+	upstreams := []model.UpstreamInput{{Address: "localhost:3000"}}
+
+	cfg := &model.SecurityHeadersConfig{HSTSMaxAge: 600, FrameOptions: "SAMEORIGIN", ContentSecurityPolicy: "default-src 'self'"}
+	host, err := svc.Create(&model.HostCreateRequest{Domain: "sec.example.com", Upstreams: upstreams, SecurityHeadersConfig: cfg})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	got, err := svc.Get(host.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.SecurityHeadersConfig == nil || *got.SecurityHeadersConfig != *cfg {
+		t.Errorf("SecurityHeadersConfig = %+v, want %+v", got.SecurityHeadersConfig, cfg)
+	}
+	content, _ := os.ReadFile(svc.cfg.CaddyfilePath)
+	if !strings.Contains(string(content), "X-Frame-Options \"SAMEORIGIN\"") {
+		t.Errorf("Caddyfile missing configured header:\n%s", content)
+	}
+
+	// Clearing the configuration falls back to the preset toggle.
+	updated, err := svc.Update(host.ID, &model.HostCreateRequest{Domain: "sec.example.com", Upstreams: upstreams, Version: got.Version})
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if updated.SecurityHeadersConfig != nil {
+		t.Errorf("SecurityHeadersConfig = %+v after clearing, want nil", updated.SecurityHeadersConfig)
+	}
+
+	bad := &model.SecurityHeadersConfig{FrameOptions: "ALLOWALL"}
+	if _, err := svc.Create(&model.HostCreateRequest{Domain: "bad.example.com", Upstreams: upstreams, SecurityHeadersConfig: bad}); err == nil {
+		t.Error("invalid X-Frame-Options accepted")
+	}
+}
+
+func TestHostCreate_CorsCredentialsWildcard(t *testing.T) {
+	db := setupTestDB(t)
+	svc := setupTestHostService(t, db)
+	upstreams := []model.UpstreamInput{{Address: "localhost:3000"}}
+	on := true
+
+	_, err := svc.Create(&model.HostCreateRequest{
+		Domain: "api.example.com", Upstreams: upstreams,
+		CorsEnabled: &on, CorsOrigins: "*", CorsAllowCredentials: &on,
+	})
+	if err == nil || !strings.Contains(err.Error(), "wildcard") {
+		t.Fatalf("credentials with wildcard origin: err = %v", err)
+	}
+
+	host, err := svc.Create(&model.HostCreateRequest{
+		Domain: "api.example.com", Upstreams: upstreams,
+		CorsEnabled: &on, CorsOrigins: "https://app.example.com", CorsAllowCredentials: &on, CorsMaxAge: 600,
+	})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if !boolVal(host.CorsAllowCredentials) || host.CorsMaxAge != 600 {
+		t.Errorf("credentials = %v, max-age = %d", boolVal(host.CorsAllowCredentials), host.CorsMaxAge)
+	}
+
+	// An update that only widens the origins keeps credentials on, so it is
+	// rejected too.
+	_, err = svc.Update(host.ID, &model.HostCreateRequest{
+		Domain: "api.example.com", Upstreams: upstreams, CorsEnabled: &on, CorsOrigins: "*", Version: host.Version,
+	})
+	if err == nil {
+		t.Error("update to wildcard origin with stored credentials accepted")
+	}
+}
+
+func TestHostCreate_SPAFallbackStaticOnly(t *testing.T) {
+	db := setupTestDB(t)
+	svc := setupTestHostService(t, db)
+	on := true
+
+	_, err := svc.Create(&model.HostCreateRequest{
+		Domain: "api.example.com", Upstreams: []model.UpstreamInput{{Address: "localhost:3000"}}, SPAFallback: &on,
+	})
+	if err == nil || !strings.Contains(err.Error(), "spa_fallback") {
+		t.Errorf("SPA fallback on a proxy host: err = %v", err)
+	}
+
+	host, err := svc.Create(&model.HostCreateRequest{
+		Domain: "app.example.com", HostType: "static", RootPath: "/var/www/app", SPAFallback: &on,
+	})
+	if err != nil {
+		t.Fatalf("Create static SPA host: %v", err)
+	}
+	if !boolVal(host.SPAFallback) {
+		t.Error("SPAFallback not stored")
+	}
+
+	// Switching the host to another type drops the fallback.
+	updated, err := svc.Update(host.ID, &model.HostCreateRequest{
+		Domain: "app.example.com", HostType: "php", RootPath: "/var/www/app", Version: host.Version,
+	})
+	if err != nil {
+		t.Fatalf("Update to php: %v", err)
+	}
+	if boolVal(updated.SPAFallback) {
+		t.Error("SPAFallback kept after leaving the static type")
+	}
+}
+
+func TestCloneHost_GroupAndTags(t *testing.T) {
+	db := setupTestDB(t)
+	svc := setupTestHostService(t, db)
+	groupSvc := NewGroupService(db, nil, nil, svc)
+	tagSvc := NewTagService(db)
+
+	prod, err := groupSvc.Create("production", "#10b981")
+	if err != nil {
+		t.Fatal(err)
+	}
+	staging, err := groupSvc.Create("staging", "#f59e0b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	web, _ := tagSvc.Create("web", "#3b82f6")
+	api, _ := tagSvc.Create("api", "#ef4444")
+
+	source, err := svc.Create(&model.HostCreateRequest{
+		Domain: "app.example.com", Upstreams: []model.UpstreamInput{{Address: "localhost:3000"}},
+		GroupID: &prod.ID, TagIDs: []uint{web.ID, api.ID},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tagIDs := func(h *model.Host) []uint {
+		var ids []uint
+		for _, tag := range h.Tags {
+			ids = append(ids, tag.ID)
+		}
+		return ids
+	}
+
+	// By default the clone is filed like its source.
+	clone, err := svc.CloneHost(source.ID, "copy.example.com", CloneOptions{})
+	if err != nil {
+		t.Fatalf("CloneHost: %v", err)
+	}
+	if clone.GroupID == nil || *clone.GroupID != prod.ID {
+		t.Errorf("default clone group = %v, want %d", clone.GroupID, prod.ID)
+	}
+	if got := tagIDs(clone); len(got) != 2 {
+		t.Errorf("default clone tags = %v, want both source tags", got)
+	}
+
+	// Explicit options file it elsewhere.
+	clone, err = svc.CloneHost(source.ID, "staging.example.com", CloneOptions{GroupID: &staging.ID, TagIDs: []uint{api.ID}})
+	if err != nil {
+		t.Fatalf("CloneHost with options: %v", err)
+	}
+	if clone.GroupID == nil || *clone.GroupID != staging.ID {
+		t.Errorf("clone group = %v, want %d", clone.GroupID, staging.ID)
+	}
+	if got := tagIDs(clone); len(got) != 1 || got[0] != api.ID {
+		t.Errorf("clone tags = %v, want [%d]", got, api.ID)
+	}
+
+	// Group 0 and an empty tag list leave the clone unfiled.
+	none := uint(0)
+	clone, err = svc.CloneHost(source.ID, "bare.example.com", CloneOptions{GroupID: &none, TagIDs: []uint{}})
+	if err != nil {
+		t.Fatalf("CloneHost unfiled: %v", err)
+	}
+	if clone.GroupID != nil || len(clone.Tags) != 0 {
+		t.Errorf("unfiled clone has group %v, tags %v", clone.GroupID, tagIDs(clone))
+	}
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/web-casa/webcasa/internal/model"
+)
+
+// useValidatingCaddy points the service at a fake caddy binary whose
+// `validate` rejects any config mentioning bogus_directive.
+func useValidatingCaddy(t *testing.T, svc *HostService) {
+	t.Helper()
+	script := filepath.Join(t.TempDir(), "caddy")
+	body := "#!/bin/sh\n" +
+		"if [ \"$1\" = validate ] && grep -q bogus_directive \"$3\"; then\n" +
+		"  echo 'Error: unrecognized directive: bogus_directive'\n" +
+		"  exit 1\n" +
+		"fi\n" +
+		"exit 0\n"
+	if err := os.WriteFile(script, []byte(body), 0755); err != nil {
+		t.Fatal(err)
+	}
+	svc.cfg.CaddyBin = script
+}
+
+func TestApplyConfig_InvalidConfigKeepsLiveFile(t *testing.T) {
+	db := setupTestDB(t)
+	svc := setupTestHostService(t, db)
+	useValidatingCaddy(t, svc)
+
+	host := createTestHost(t, svc, "good.example.com", 1, 0, 0, 0, 0)
+	before, err := os.ReadFile(svc.cfg.CaddyfilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(before), "good.example.com {") {
+		t.Fatalf("initial Caddyfile missing the host:\n%s", before)
+	}
+
+	req := &model.HostCreateRequest{
+		Domain:           "good.example.com",
+		Version:          host.Version,
+		Upstreams:        []model.UpstreamInput{{Address: "localhost:8080"}},
+		CustomDirectives: "bogus_directive on",
+	}
+	_, err = svc.Update(host.ID, req)
+	if !errors.Is(err, ErrInvalidGeneratedConfig) {
+		t.Fatalf("Update with broken directives: err = %v, want ErrInvalidGeneratedConfig", err)
+	}
+
+	after, _ := os.ReadFile(svc.cfg.CaddyfilePath)
+	if string(after) != string(before) {
+		t.Errorf("live Caddyfile changed after a rejected config:\n%s", after)
+	}
+	if _, err := os.Stat(svc.cfg.CaddyfilePath + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("rejected temp Caddyfile left behind: %v", err)
+	}
+
+	// A create whose config is rejected is reported the same way
+	_, err = svc.Create(&model.HostCreateRequest{
+		Domain:           "bad.example.com",
+		Upstreams:        []model.UpstreamInput{{Address: "localhost:8081"}},
+		CustomDirectives: "bogus_directive off",
+	})
+	if !errors.Is(err, ErrInvalidGeneratedConfig) {
+		t.Errorf("Create with broken directives: err = %v, want ErrInvalidGeneratedConfig", err)
+	}
+	if after, _ := os.ReadFile(svc.cfg.CaddyfilePath); string(after) != string(before) {
+		t.Errorf("live Caddyfile changed after a rejected create:\n%s", after)
+	}
+}
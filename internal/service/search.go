@@ -0,0 +1,119 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/web-casa/webcasa/internal/model"
+	"gorm.io/gorm"
+)
+
+// searchLimit caps the results returned per category.
+const searchLimit = 10
+
+// SearchResult is one entity matching a search.
+type SearchResult struct {
+	Type   string `json:"type"` // host, template, group, tag or certificate
+	ID     uint   `json:"id"`
+	Name   string `json:"name"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// SearchResults groups matches by category. Every category is present,
+// empty when nothing matched.
+type SearchResults struct {
+	Hosts        []SearchResult `json:"hosts"`
+	Templates    []SearchResult `json:"templates"`
+	Groups       []SearchResult `json:"groups"`
+	Tags         []SearchResult `json:"tags"`
+	Certificates []SearchResult `json:"certificates"`
+}
+
+// Total returns the number of matches across all categories.
+func (r *SearchResults) Total() int {
+	return len(r.Hosts) + len(r.Templates) + len(r.Groups) + len(r.Tags) + len(r.Certificates)
+}
+
+// SearchService finds entities across the panel by partial name.
+type SearchService struct {
+	db *gorm.DB
+}
+
+// NewSearchService creates a new SearchService
+func NewSearchService(db *gorm.DB) *SearchService {
+	return &SearchService{db: db}
+}
+
+// Search returns, per category, up to searchLimit entities whose name
+// contains query, ignoring case: hosts by domain or alias, certificates by
+// name or domain, and templates, groups and tags by name.
+func (s *SearchService) Search(query string) (*SearchResults, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, fmt.Errorf("error.search_query_required")
+	}
+	pattern := "%" + escapeLike(strings.ToLower(query)) + "%"
+	results := &SearchResults{}
+
+	var hosts []model.Host
+	if err := s.db.Select("id", "domain", "host_type").
+		Where(`LOWER(domain) LIKE ? ESCAPE '\' OR LOWER(aliases) LIKE ? ESCAPE '\'`, pattern, pattern).
+		Order("domain ASC").Limit(searchLimit).Find(&hosts).Error; err != nil {
+		return nil, fmt.Errorf("failed to search hosts: %w", err)
+	}
+	results.Hosts = make([]SearchResult, 0, len(hosts))
+	for _, h := range hosts {
+		results.Hosts = append(results.Hosts, SearchResult{Type: "host", ID: h.ID, Name: h.Domain, Detail: h.HostType})
+	}
+
+	var templates []model.Template
+	if err := s.db.Select("id", "name", "category").
+		Where(`LOWER(name) LIKE ? ESCAPE '\'`, pattern).
+		Order("name ASC").Limit(searchLimit).Find(&templates).Error; err != nil {
+		return nil, fmt.Errorf("failed to search templates: %w", err)
+	}
+	results.Templates = make([]SearchResult, 0, len(templates))
+	for _, tpl := range templates {
+		results.Templates = append(results.Templates, SearchResult{Type: "template", ID: tpl.ID, Name: tpl.Name, Detail: tpl.Category})
+	}
+
+	var groups []model.Group
+	if err := s.db.Select("id", "name").
+		Where(`LOWER(name) LIKE ? ESCAPE '\'`, pattern).
+		Order("name ASC").Limit(searchLimit).Find(&groups).Error; err != nil {
+		return nil, fmt.Errorf("failed to search groups: %w", err)
+	}
+	results.Groups = make([]SearchResult, 0, len(groups))
+	for _, g := range groups {
+		results.Groups = append(results.Groups, SearchResult{Type: "group", ID: g.ID, Name: g.Name})
+	}
+
+	var tags []model.Tag
+	if err := s.db.Select("id", "name").
+		Where(`LOWER(name) LIKE ? ESCAPE '\'`, pattern).
+		Order("name ASC").Limit(searchLimit).Find(&tags).Error; err != nil {
+		return nil, fmt.Errorf("failed to search tags: %w", err)
+	}
+	results.Tags = make([]SearchResult, 0, len(tags))
+	for _, tag := range tags {
+		results.Tags = append(results.Tags, SearchResult{Type: "tag", ID: tag.ID, Name: tag.Name})
+	}
+
+	var certs []model.Certificate
+	if err := s.db.Select("id", "name", "domains").
+		Where(`LOWER(name) LIKE ? ESCAPE '\' OR LOWER(domains) LIKE ? ESCAPE '\'`, pattern, pattern).
+		Order("name ASC").Limit(searchLimit).Find(&certs).Error; err != nil {
+		return nil, fmt.Errorf("failed to search certificates: %w", err)
+	}
+	results.Certificates = make([]SearchResult, 0, len(certs))
+	for _, c := range certs {
+		results.Certificates = append(results.Certificates, SearchResult{Type: "certificate", ID: c.ID, Name: c.Name, Detail: c.Domains})
+	}
+
+	return results, nil
+}
+
+// escapeLike escapes the LIKE wildcards in s so they match literally.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
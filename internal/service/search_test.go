@@ -0,0 +1,66 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/web-casa/webcasa/internal/model"
+)
+
+func TestSearch_MatchesAcrossCategories(t *testing.T) {
+	db := setupTestDB(t)
+	if err := db.AutoMigrate(&model.Template{}, &model.Certificate{}); err != nil {
+		t.Fatal(err)
+	}
+	svc := setupTestHostService(t, db)
+	createTestHost(t, svc, "shop.example.com", 1, 0, 0, 0, 0)
+	createTestHost(t, svc, "blog.example.com", 1, 0, 0, 0, 0)
+	db.Create(&model.Template{Name: "Shop Backend", Type: "custom", Config: "{}"})
+	db.Create(&model.Certificate{Name: "wildcard", Domains: "*.example.com"})
+	db.Create(&model.Group{Name: "Production"})
+
+	results, err := NewSearchService(db).Search("SHOP")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results.Hosts) != 1 || results.Hosts[0].Name != "shop.example.com" || results.Hosts[0].Type != "host" {
+		t.Errorf("hosts = %+v, want shop.example.com", results.Hosts)
+	}
+	if len(results.Templates) != 1 || results.Templates[0].Name != "Shop Backend" {
+		t.Errorf("templates = %+v, want Shop Backend", results.Templates)
+	}
+	if results.Total() != 2 {
+		t.Errorf("total = %d, want 2 (groups %v, certificates %v)", results.Total(), results.Groups, results.Certificates)
+	}
+
+	// Certificates match by domain too
+	results, _ = NewSearchService(db).Search("*.example")
+	if len(results.Certificates) != 1 {
+		t.Errorf("certificates = %+v, want the wildcard", results.Certificates)
+	}
+}
+
+func TestSearch_LimitsAndEscapes(t *testing.T) {
+	db := setupTestDB(t)
+	if err := db.AutoMigrate(&model.Template{}, &model.Certificate{}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < searchLimit+5; i++ {
+		db.Create(&model.Group{Name: "team-" + string(rune('a'+i))})
+	}
+	s := NewSearchService(db)
+
+	results, err := s.Search("team")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results.Groups) != searchLimit {
+		t.Errorf("%d groups, want the cap of %d", len(results.Groups), searchLimit)
+	}
+	// "_" is matched literally, not as a LIKE wildcard
+	if results, _ := s.Search("team_"); results.Total() != 0 {
+		t.Errorf("%q matched %+v", "team_", results.Groups)
+	}
+	if _, err := s.Search("  "); err == nil {
+		t.Error("empty query accepted")
+	}
+}
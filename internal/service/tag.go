@@ -35,6 +35,11 @@ func (s *TagService) Get(id uint) (*model.Tag, error) {
 
 // Create creates a new tag
 func (s *TagService) Create(name, color string) (*model.Tag, error) {
+	color, err := normalizeColor(color)
+	if err != nil {
+		return nil, err
+	}
+
 	var count int64
 	s.db.Model(&model.Tag{}).Where("name = ?", name).Count(&count)
 	if count > 0 {
@@ -57,6 +62,9 @@ func (s *TagService) Update(id uint, name, color string) (*model.Tag, error) {
 	if err != nil {
 		return nil, err
 	}
+	if color, err = normalizeColor(color); err != nil {
+		return nil, err
+	}
 
 	var count int64
 	s.db.Model(&model.Tag{}).Where("name = ? AND id != ?", name, id).Count(&count)
@@ -79,8 +87,9 @@ func (s *TagService) Delete(id uint) error {
 		return fmt.Errorf("error.tag_not_found")
 	}
 
-	// Remove host_tags associations
+	// Remove host_tags associations and the rules applying the tag
 	s.db.Where("tag_id = ?", id).Delete(&model.HostTag{})
+	s.db.Where("tag_id = ?", id).Delete(&model.TagRule{})
 
 	if err := s.db.Delete(&model.Tag{}, id).Error; err != nil {
 		return fmt.Errorf("failed to delete tag: %w", err)
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"path"
+	"strings"
+
+	"github.com/web-casa/webcasa/internal/model"
+	"gorm.io/gorm"
+)
+
+// Host fields a tag rule can match.
+const (
+	TagRuleMatchDomain   = "domain"
+	TagRuleMatchHostType = "host_type"
+)
+
+// normalizeTagRule validates a rule's match field and pattern and returns the
+// pattern trimmed and lowercased.
+func normalizeTagRule(field, pattern string) (string, error) {
+	if field != TagRuleMatchDomain && field != TagRuleMatchHostType {
+		return "", fmt.Errorf("error.invalid_tag_rule")
+	}
+	pattern = strings.ToLower(strings.TrimSpace(pattern))
+	if pattern == "" {
+		return "", fmt.Errorf("error.invalid_tag_rule")
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return "", fmt.Errorf("error.invalid_tag_rule")
+	}
+	return pattern, nil
+}
+
+// tagRuleMatches reports whether rule applies to a host with the given
+// domain and type.
+func tagRuleMatches(rule model.TagRule, domain, hostType string) bool {
+	value := domain
+	if rule.MatchField == TagRuleMatchHostType {
+		value = hostType
+	}
+	ok, _ := path.Match(rule.Pattern, strings.ToLower(value))
+	return ok
+}
+
+// addRuleTags attaches the tags of every matching rule the host does not
+// carry yet and returns how many were added.
+func addRuleTags(db *gorm.DB, rules []model.TagRule, host model.Host) (int, error) {
+	var existing []uint
+	if err := db.Model(&model.HostTag{}).Where("host_id = ?", host.ID).Pluck("tag_id", &existing).Error; err != nil {
+		return 0, err
+	}
+	has := make(map[uint]bool, len(existing))
+	for _, id := range existing {
+		has[id] = true
+	}
+
+	added := 0
+	for _, rule := range rules {
+		if has[rule.TagID] || !tagRuleMatches(rule, host.Domain, host.HostType) {
+			continue
+		}
+		if err := db.Create(&model.HostTag{HostID: host.ID, TagID: rule.TagID}).Error; err != nil {
+			return added, err
+		}
+		has[rule.TagID] = true
+		added++
+	}
+	return added, nil
+}
+
+// applyTagRules tags a just-saved host according to the tag rules. Failures
+// are only logged: the host itself is already saved.
+func (s *HostService) applyTagRules(host *model.Host) {
+	var rules []model.TagRule
+	if err := s.db.Find(&rules).Error; err != nil {
+		log.Printf("Warning: failed to load tag rules for host %d: %v", host.ID, err)
+		return
+	}
+	if len(rules) == 0 {
+		return
+	}
+	if _, err := addRuleTags(s.db, rules, *host); err != nil {
+		log.Printf("Warning: failed to apply tag rules to host %d: %v", host.ID, err)
+	}
+}
+
+// ListRules returns all tag rules
+func (s *TagService) ListRules() ([]model.TagRule, error) {
+	var rules []model.TagRule
+	err := s.db.Order("id ASC").Find(&rules).Error
+	return rules, err
+}
+
+// CreateRule adds a rule applying tagID to hosts whose field matches pattern.
+// Existing hosts are only tagged by ApplyRules.
+func (s *TagService) CreateRule(tagID uint, field, pattern string) (*model.TagRule, error) {
+	if _, err := s.Get(tagID); err != nil {
+		return nil, fmt.Errorf("error.tag_not_found")
+	}
+	pattern, err := normalizeTagRule(field, pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	rule := &model.TagRule{TagID: tagID, MatchField: field, Pattern: pattern}
+	if err := s.db.Create(rule).Error; err != nil {
+		return nil, fmt.Errorf("failed to create tag rule: %w", err)
+	}
+	return rule, nil
+}
+
+// DeleteRule removes a tag rule. Tags it already applied stay on their hosts.
+func (s *TagService) DeleteRule(id uint) error {
+	res := s.db.Delete(&model.TagRule{}, id)
+	if res.Error != nil {
+		return fmt.Errorf("failed to delete tag rule: %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return fmt.Errorf("error.tag_rule_not_found")
+	}
+	return nil
+}
+
+// ApplyRules evaluates every tag rule against all live hosts and returns the
+// number of tags added.
+func (s *TagService) ApplyRules() (int, error) {
+	rules, err := s.ListRules()
+	if err != nil {
+		return 0, err
+	}
+	if len(rules) == 0 {
+		return 0, nil
+	}
+
+	var hosts []model.Host
+	if err := s.db.Select("id", "domain", "host_type").Find(&hosts).Error; err != nil {
+		return 0, fmt.Errorf("failed to load hosts: %w", err)
+	}
+	added := 0
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		for _, host := range hosts {
+			n, err := addRuleTags(tx, rules, host)
+			if err != nil {
+				return err
+			}
+			added += n
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to apply tag rules: %w", err)
+	}
+	return added, nil
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/web-casa/webcasa/internal/model"
+)
+
+// hostTagIDs returns the IDs of the tags attached to a host.
+func hostTagIDs(t *testing.T, svc *HostService, id uint) map[uint]bool {
+	t.Helper()
+	host, err := svc.Get(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := make(map[uint]bool)
+	for _, tag := range host.Tags {
+		ids[tag.ID] = true
+	}
+	return ids
+}
+
+func TestTagRules_AppliedOnCreateAndUpdate(t *testing.T) {
+	db := setupTestDB(t)
+	hostSvc := setupTestHostService(t, db)
+	tagSvc := NewTagService(db)
+
+	prod, _ := tagSvc.Create("prod", "")
+	proxy, _ := tagSvc.Create("proxy", "")
+	if _, err := tagSvc.CreateRule(prod.ID, TagRuleMatchDomain, "*.Prod.example.com"); err != nil {
+		t.Fatalf("CreateRule: %v", err)
+	}
+	if _, err := tagSvc.CreateRule(proxy.ID, TagRuleMatchHostType, "proxy"); err != nil {
+		t.Fatalf("CreateRule: %v", err)
+	}
+
+	matched := createTestHost(t, hostSvc, "api.prod.example.com", 1, 0, 0, 0, 0)
+	if tags := hostTagIDs(t, hostSvc, matched.ID); !tags[prod.ID] || !tags[proxy.ID] {
+		t.Errorf("matching host tags = %v, want prod and proxy", tags)
+	}
+
+	other := createTestHost(t, hostSvc, "api.dev.example.com", 1, 0, 0, 0, 0)
+	if tags := hostTagIDs(t, hostSvc, other.ID); tags[prod.ID] || !tags[proxy.ID] {
+		t.Errorf("non-matching host tags = %v, want only proxy", tags)
+	}
+
+	// Renaming into the pattern picks the tag up on update
+	req := &model.HostCreateRequest{
+		Domain:    "web.prod.example.com",
+		Version:   other.Version,
+		Upstreams: []model.UpstreamInput{{Address: "localhost:8080"}},
+	}
+	if _, err := hostSvc.Update(other.ID, req); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if tags := hostTagIDs(t, hostSvc, other.ID); !tags[prod.ID] {
+		t.Errorf("renamed host tags = %v, want prod", tags)
+	}
+}
+
+func TestTagRules_BackfillTagsExistingHosts(t *testing.T) {
+	db := setupTestDB(t)
+	hostSvc := setupTestHostService(t, db)
+	tagSvc := NewTagService(db)
+
+	a := createTestHost(t, hostSvc, "a.shop.example.com", 1, 0, 0, 0, 0)
+	b := createTestHost(t, hostSvc, "b.shop.example.com", 1, 0, 0, 0, 0)
+	c := createTestHost(t, hostSvc, "blog.example.com", 1, 0, 0, 0, 0)
+
+	shop, _ := tagSvc.Create("shop", "")
+	if _, err := tagSvc.CreateRule(shop.ID, TagRuleMatchDomain, "*.shop.example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if tags := hostTagIDs(t, hostSvc, a.ID); tags[shop.ID] {
+		t.Fatal("creating a rule tagged an existing host before the backfill")
+	}
+
+	added, err := tagSvc.ApplyRules()
+	if err != nil {
+		t.Fatalf("ApplyRules: %v", err)
+	}
+	if added != 2 {
+		t.Errorf("added = %d, want 2", added)
+	}
+	for _, id := range []uint{a.ID, b.ID} {
+		if !hostTagIDs(t, hostSvc, id)[shop.ID] {
+			t.Errorf("host %d not tagged by backfill", id)
+		}
+	}
+	if hostTagIDs(t, hostSvc, c.ID)[shop.ID] {
+		t.Error("non-matching host tagged by backfill")
+	}
+
+	// Running the backfill again adds nothing
+	if added, err := tagSvc.ApplyRules(); err != nil || added != 0 {
+		t.Errorf("second ApplyRules = %d, %v; want 0, nil", added, err)
+	}
+}
+
+func TestTagRules_Validation(t *testing.T) {
+	db := setupTestDB(t)
+	tagSvc := NewTagService(db)
+	tag, _ := tagSvc.Create("t", "")
+
+	for _, tc := range []struct{ field, pattern, want string }{
+		{"aliases", "*.example.com", "error.invalid_tag_rule"},
+		{TagRuleMatchDomain, "  ", "error.invalid_tag_rule"},
+		{TagRuleMatchDomain, "[a-", "error.invalid_tag_rule"},
+	} {
+		if _, err := tagSvc.CreateRule(tag.ID, tc.field, tc.pattern); err == nil || err.Error() != tc.want {
+			t.Errorf("CreateRule(%q, %q): err = %v, want %s", tc.field, tc.pattern, err, tc.want)
+		}
+	}
+	if _, err := tagSvc.CreateRule(9999, TagRuleMatchDomain, "*"); err == nil || err.Error() != "error.tag_not_found" {
+		t.Errorf("CreateRule with unknown tag: err = %v, want error.tag_not_found", err)
+	}
+
+	// Deleting the tag removes its rules
+	if _, err := tagSvc.CreateRule(tag.ID, TagRuleMatchDomain, "*"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tagSvc.Delete(tag.ID); err != nil {
+		t.Fatal(err)
+	}
+	if rules, _ := tagSvc.ListRules(); len(rules) != 0 {
+		t.Errorf("%d rules left after deleting their tag", len(rules))
+	}
+}
@@ -2,8 +2,11 @@ package service
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"regexp"
+	"strings"
 	"time"
 
 	"github.com/web-casa/webcasa/internal/caddy"
@@ -13,44 +16,61 @@ import (
 
 // TemplateConfig represents the JSON snapshot of a host configuration stored in a template.
 type TemplateConfig struct {
-	HostType         string              `json:"host_type"`
-	TLSMode          string              `json:"tls_mode"`
-	TLSEnabled       *bool               `json:"tls_enabled"`
-	HTTPRedirect     *bool               `json:"http_redirect"`
-	WebSocket        *bool               `json:"websocket"`
-	Compression      *bool               `json:"compression"`
-	CorsEnabled      *bool               `json:"cors_enabled"`
-	CorsOrigins      string              `json:"cors_origins"`
-	CorsMethods      string              `json:"cors_methods"`
-	CorsHeaders      string              `json:"cors_headers"`
-	SecurityHeaders  *bool               `json:"security_headers"`
-	ErrorPagePath    string              `json:"error_page_path"`
-	CacheEnabled     *bool               `json:"cache_enabled"`
-	CacheTTL         int                 `json:"cache_ttl"`
-	RootPath         string              `json:"root_path"`
-	DirectoryBrowse  *bool               `json:"directory_browse"`
-	PHPFastCGI       string              `json:"php_fastcgi"`
-	IndexFiles       string              `json:"index_files"`
-	CustomDirectives string              `json:"custom_directives"`
-	RedirectURL      string              `json:"redirect_url"`
-	RedirectCode     int                 `json:"redirect_code"`
-	Upstreams        []model.UpstreamInput  `json:"upstreams"`
-	CustomHeaders    []model.HeaderInput    `json:"custom_headers"`
-	AccessRules      []model.AccessInput    `json:"access_rules"`
-	BasicAuths       []TemplateBasicAuth    `json:"basic_auths"`
+	HostType               string                `json:"host_type"`
+	TLSMode                string                `json:"tls_mode"`
+	TLSEnabled             *bool                 `json:"tls_enabled"`
+	HTTPRedirect           *bool                 `json:"http_redirect"`
+	WebSocket              *bool                 `json:"websocket"`
+	Compression            *bool                 `json:"compression"`
+	CorsEnabled            *bool                 `json:"cors_enabled"`
+	CorsOrigins            string                `json:"cors_origins"`
+	CorsMethods            string                `json:"cors_methods"`
+	CorsHeaders            string                `json:"cors_headers"`
+	SecurityHeaders        *bool                 `json:"security_headers"`
+	ErrorPagePath          string                `json:"error_page_path"`
+	CacheEnabled           *bool                 `json:"cache_enabled"`
+	CacheTTL               int                   `json:"cache_ttl"`
+	RootPath               string                `json:"root_path"`
+	DirectoryBrowse        *bool                 `json:"directory_browse"`
+	SPAFallback            *bool                 `json:"spa_fallback,omitempty"`
+	PHPFastCGI             string                `json:"php_fastcgi"`
+	PHPVersion             string                `json:"php_version,omitempty"`
+	IndexFiles             string                `json:"index_files"`
+	SeparateAccessLog      *bool                 `json:"separate_access_log,omitempty"`
+	MaxRequestBodySize     string                `json:"max_request_body_size,omitempty"`
+	ProxyReadTimeout       string                `json:"proxy_read_timeout,omitempty"`
+	ProxyWriteTimeout      string                `json:"proxy_write_timeout,omitempty"`
+	ProxyDialTimeout       string                `json:"proxy_dial_timeout,omitempty"`
+	BasicAuthRealm         string                `json:"basic_auth_realm,omitempty"`
+	ForwardAuthURL         string                `json:"forward_auth_url,omitempty"`
+	ForwardAuthCopyHeaders string                `json:"forward_auth_copy_headers,omitempty"`
+	CustomDirectives       string                `json:"custom_directives"`
+	RedirectURL            string                `json:"redirect_url"`
+	RedirectCode           int                   `json:"redirect_code"`
+	Upstreams              []model.UpstreamInput `json:"upstreams"`
+	CustomHeaders          []model.HeaderInput   `json:"custom_headers"`
+	AccessRules            []model.AccessInput   `json:"access_rules"`
+	BasicAuths             []TemplateBasicAuth   `json:"basic_auths"`
+	// Per-header security settings (nil keeps the SecurityHeaders preset)
+	SecurityHeadersConfig *model.SecurityHeadersConfig `json:"security_headers_config,omitempty"`
+	CorsAllowCredentials  *bool                        `json:"cors_allow_credentials,omitempty"`
+	CorsMaxAge            int                          `json:"cors_max_age,omitempty"`
+	CompressionAlgorithms string                       `json:"compression_algorithms,omitempty"`
+	ACMECA                string                       `json:"acme_ca,omitempty"`
 }
 
 // TemplateBasicAuth stores basic auth with the password hash directly (snapshot).
 type TemplateBasicAuth struct {
 	Username     string `json:"username"`
 	PasswordHash string `json:"password_hash"`
+	Path         string `json:"path,omitempty"`
 }
 
 // TemplateExport is the JSON format for exporting a template.
 type TemplateExport struct {
-	Version    string               `json:"version"`
-	ExportedAt string               `json:"exported_at"`
-	Template   TemplateExportData   `json:"template"`
+	Version    string             `json:"version"`
+	ExportedAt string             `json:"exported_at"`
+	Template   TemplateExportData `json:"template"`
 }
 
 // TemplateExportData is the template portion of the export JSON.
@@ -58,6 +78,7 @@ type TemplateExportData struct {
 	Name        string          `json:"name"`
 	Description string          `json:"description"`
 	Type        string          `json:"type"`
+	Category    string          `json:"category,omitempty"`
 	Config      json.RawMessage `json:"config"`
 }
 
@@ -72,10 +93,29 @@ func NewTemplateService(db *gorm.DB, hostSvc *HostService) *TemplateService {
 	return &TemplateService{db: db, hostSvc: hostSvc}
 }
 
-// List returns all templates.
-func (s *TemplateService) List() ([]model.Template, error) {
+// TemplateListFilter holds optional filter parameters for listing templates
+type TemplateListFilter struct {
+	Category string
+	Type     string // "preset" or "custom"
+}
+
+// List returns all templates, optionally filtered by category and/or type.
+func (s *TemplateService) List(filters ...TemplateListFilter) ([]model.Template, error) {
+	var filter TemplateListFilter
+	if len(filters) > 0 {
+		filter = filters[0]
+	}
+
+	query := s.db.Order("id ASC")
+	if filter.Category != "" {
+		query = query.Where("category = ?", strings.ToLower(filter.Category))
+	}
+	if filter.Type != "" {
+		query = query.Where("type = ?", filter.Type)
+	}
+
 	var templates []model.Template
-	err := s.db.Order("id ASC").Find(&templates).Error
+	err := query.Find(&templates).Error
 	return templates, err
 }
 
@@ -88,8 +128,9 @@ func (s *TemplateService) Get(id uint) (*model.Template, error) {
 	return &tpl, nil
 }
 
-// Create creates a new custom template.
-func (s *TemplateService) Create(name, description, configJSON string) (*model.Template, error) {
+// Create creates a new custom template. An empty category files it under its
+// host type.
+func (s *TemplateService) Create(name, description, category, configJSON string) (*model.Template, error) {
 	// Validate config JSON
 	var cfg TemplateConfig
 	if err := json.Unmarshal([]byte(configJSON), &cfg); err != nil {
@@ -98,11 +139,16 @@ func (s *TemplateService) Create(name, description, configJSON string) (*model.T
 	if cfg.HostType == "" {
 		return nil, fmt.Errorf("error.template_missing_fields")
 	}
+	category, err := normalizeTemplateCategory(category, cfg.HostType)
+	if err != nil {
+		return nil, err
+	}
 
 	tpl := &model.Template{
 		Name:        name,
 		Description: description,
 		Type:        "custom",
+		Category:    category,
 		Config:      configJSON,
 	}
 	if err := s.db.Create(tpl).Error; err != nil {
@@ -111,8 +157,9 @@ func (s *TemplateService) Create(name, description, configJSON string) (*model.T
 	return tpl, nil
 }
 
-// Update modifies an existing custom template. Preset templates cannot be modified.
-func (s *TemplateService) Update(id uint, name, description, configJSON string) (*model.Template, error) {
+// Update modifies an existing custom template. Preset templates cannot be
+// modified. An empty category keeps the current one.
+func (s *TemplateService) Update(id uint, name, description, category, configJSON string) (*model.Template, error) {
 	tpl, err := s.Get(id)
 	if err != nil {
 		return nil, fmt.Errorf("error.template_not_found")
@@ -133,6 +180,12 @@ func (s *TemplateService) Update(id uint, name, description, configJSON string)
 		tpl.Config = configJSON
 	}
 
+	if category != "" {
+		if tpl.Category, err = normalizeTemplateCategory(category, ""); err != nil {
+			return nil, err
+		}
+	}
+
 	tpl.Name = name
 	tpl.Description = description
 	if err := s.db.Save(tpl).Error; err != nil {
@@ -156,8 +209,9 @@ func (s *TemplateService) Delete(id uint) error {
 	return nil
 }
 
-// SaveAsTemplate creates a template from an existing host's configuration snapshot.
-func (s *TemplateService) SaveAsTemplate(hostID uint, name, description string) (*model.Template, error) {
+// SaveAsTemplate creates a template from an existing host's configuration
+// snapshot. An empty category files it under the host's type.
+func (s *TemplateService) SaveAsTemplate(hostID uint, name, description, category string) (*model.Template, error) {
 	host, err := s.hostSvc.Get(hostID)
 	if err != nil {
 		return nil, fmt.Errorf("error.host_not_found")
@@ -168,11 +222,16 @@ func (s *TemplateService) SaveAsTemplate(hostID uint, name, description string)
 	if err != nil {
 		return nil, fmt.Errorf("failed to serialize host config: %w", err)
 	}
+	category, err = normalizeTemplateCategory(category, cfg.HostType)
+	if err != nil {
+		return nil, err
+	}
 
 	tpl := &model.Template{
 		Name:        name,
 		Description: description,
 		Type:        "custom",
+		Category:    category,
 		Config:      string(configJSON),
 	}
 	if err := s.db.Create(tpl).Error; err != nil {
@@ -188,42 +247,196 @@ func (s *TemplateService) CreateFromTemplate(templateID uint, domain string) (*m
 		return nil, fmt.Errorf("error.template_not_found")
 	}
 
+	host, err := hostFromTemplate(tpl, domain)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkDomainAvailable(host.Domain); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Create(host).Error; err != nil {
+		return nil, fmt.Errorf("failed to create host from template: %w", err)
+	}
+	s.hostSvc.recordRevision(host.ID, 0, "")
+
+	if _, err := s.hostSvc.ApplyConfig(); err != nil {
+		log.Printf("Warning: failed to apply config after creating host from template: %v", err)
+	}
+
+	return s.hostSvc.Get(host.ID)
+}
+
+// TemplateHostResult is the outcome of CreateHostsFromTemplate for one domain.
+type TemplateHostResult struct {
+	Domain string `json:"domain"`
+	Status string `json:"status"` // "created", "skipped" (domain conflict) or "failed"
+	HostID uint   `json:"host_id,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// CreateHostsFromTemplate creates a host from the template for each domain
+// within one transaction and regenerates the Caddy config once. Domains that
+// are already served (or overlap a served one when overlaps are rejected) are
+// skipped and domains the template produces an invalid host for fail, both
+// without affecting the rest.
+func (s *TemplateService) CreateHostsFromTemplate(templateID uint, domains []string) ([]TemplateHostResult, error) {
+	if len(domains) == 0 {
+		return nil, fmt.Errorf("no domains given")
+	}
+	if len(domains) > maxBatchHosts {
+		return nil, fmt.Errorf("too many domains (max %d)", maxBatchHosts)
+	}
+	tpl, err := s.Get(templateID)
+	if err != nil {
+		return nil, fmt.Errorf("error.template_not_found")
+	}
 	var cfg TemplateConfig
 	if err := json.Unmarshal([]byte(tpl.Config), &cfg); err != nil {
 		return nil, fmt.Errorf("error.invalid_template_json")
 	}
 
-	// Check domain uniqueness
-	var count int64
-	s.db.Model(&model.Host{}).Where("domain = ?", domain).Count(&count)
-	if count > 0 {
-		return nil, fmt.Errorf("error.domain_exists")
+	results := make([]TemplateHostResult, 0, len(domains))
+	var hosts []*model.Host
+	var created []int // index in results of each host to create
+	seen := make(map[string]bool, len(domains))
+	for _, domain := range domains {
+		domain = strings.TrimSpace(domain)
+		if domain == "" {
+			continue
+		}
+		res := TemplateHostResult{Domain: domain}
+		// An invalid domain is reported by hostFromTemplate below.
+		name := strings.ToLower(domain)
+		if normalized, err := caddy.NormalizeDomain(domain); err == nil {
+			name = normalized
+		}
+		if seen[name] {
+			res.Status, res.Error = "skipped", "domain listed more than once"
+			results = append(results, res)
+			continue
+		}
+		seen[name] = true
+
+		var overlap *DomainOverlapError
+		err := s.checkDomainAvailable(name)
+		switch {
+		case err != nil && err.Error() == "error.domain_exists":
+			res.Status, res.Error = "skipped", "domain already exists"
+			results = append(results, res)
+			continue
+		case errors.As(err, &overlap):
+			res.Status, res.Error = "skipped", err.Error()
+			results = append(results, res)
+			continue
+		case err != nil:
+			return nil, err
+		}
+
+		host, err := hostFromTemplate(tpl, domain)
+		if err != nil {
+			res.Status, res.Error = "failed", err.Error()
+			results = append(results, res)
+			continue
+		}
+		res.Status = "created"
+		created = append(created, len(results))
+		results = append(results, res)
+		hosts = append(hosts, host)
+	}
+	if len(hosts) == 0 {
+		return results, nil
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		for i, host := range hosts {
+			if err := tx.Create(host).Error; err != nil {
+				return fmt.Errorf("%s: %w", host.Domain, err)
+			}
+			results[created[i]].HostID = host.ID
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create hosts from template: %w", err)
+	}
+	for _, host := range hosts {
+		s.hostSvc.recordRevision(host.ID, 0, "")
+	}
+
+	if _, err := s.hostSvc.ApplyConfig(); err != nil {
+		log.Printf("Warning: failed to apply config after creating hosts from template: %v", err)
+	}
+	return results, nil
+}
+
+// checkDomainAvailable rejects a domain already served by a host, and one
+// overlapping a served domain when reject_domain_overlap is on.
+func (s *TemplateService) checkDomainAvailable(domain string) error {
+	taken, err := s.hostSvc.findNameConflict([]string{domain}, 0)
+	if err != nil {
+		return err
+	}
+	if taken != "" {
+		return fmt.Errorf("error.domain_exists")
+	}
+	_, err = s.hostSvc.checkDomainOverlap([]string{domain}, 0)
+	return err
+}
+
+// hostFromTemplate builds the host tpl describes for domain, validated the
+// same way HostService.Create validates a request. It is not saved.
+func hostFromTemplate(tpl *model.Template, domain string) (*model.Host, error) {
+	domain, err := caddy.NormalizeDomain(domain)
+	if err != nil {
+		return nil, fmt.Errorf("invalid domain: %w", err)
 	}
+	var cfg TemplateConfig
+	if err := json.Unmarshal([]byte(tpl.Config), &cfg); err != nil {
+		return nil, fmt.Errorf("error.invalid_template_json")
+	}
+	cfg.expandPlaceholders(domain)
 
 	host := &model.Host{
-		Domain:           domain,
-		HostType:         stringOrDefault(cfg.HostType, "proxy"),
-		Enabled:          boolPtr(true),
-		TLSEnabled:       copyBoolPtrOrDefault(cfg.TLSEnabled, true),
-		HTTPRedirect:     copyBoolPtrOrDefault(cfg.HTTPRedirect, true),
-		WebSocket:        copyBoolPtrOrDefault(cfg.WebSocket, false),
-		Compression:      copyBoolPtrOrDefault(cfg.Compression, false),
-		CorsEnabled:      copyBoolPtrOrDefault(cfg.CorsEnabled, false),
-		CorsOrigins:      cfg.CorsOrigins,
-		CorsMethods:      cfg.CorsMethods,
-		CorsHeaders:      cfg.CorsHeaders,
-		SecurityHeaders:  copyBoolPtrOrDefault(cfg.SecurityHeaders, false),
-		ErrorPagePath:    cfg.ErrorPagePath,
-		CacheEnabled:     copyBoolPtrOrDefault(cfg.CacheEnabled, false),
-		CacheTTL:         intOrDefault(cfg.CacheTTL, 300),
-		RootPath:         cfg.RootPath,
-		DirectoryBrowse:  copyBoolPtrOrDefault(cfg.DirectoryBrowse, false),
-		PHPFastCGI:       cfg.PHPFastCGI,
-		IndexFiles:       cfg.IndexFiles,
-		CustomDirectives: cfg.CustomDirectives,
-		RedirectURL:      cfg.RedirectURL,
-		RedirectCode:     intOrDefault(cfg.RedirectCode, 301),
-		TLSMode:          stringOrDefault(cfg.TLSMode, "auto"),
+		Domain:                 domain,
+		DisplayDomain:          caddy.DisplayDomain(domain),
+		HostType:               stringOrDefault(cfg.HostType, "proxy"),
+		Enabled:                boolPtr(true),
+		TLSEnabled:             copyBoolPtrOrDefault(cfg.TLSEnabled, true),
+		HTTPRedirect:           copyBoolPtrOrDefault(cfg.HTTPRedirect, true),
+		WebSocket:              copyBoolPtrOrDefault(cfg.WebSocket, false),
+		Compression:            copyBoolPtrOrDefault(cfg.Compression, false),
+		CorsEnabled:            copyBoolPtrOrDefault(cfg.CorsEnabled, false),
+		CorsOrigins:            cfg.CorsOrigins,
+		CorsMethods:            cfg.CorsMethods,
+		CorsHeaders:            cfg.CorsHeaders,
+		BasicAuthRealm:         cfg.BasicAuthRealm,
+		ForwardAuthURL:         cfg.ForwardAuthURL,
+		ForwardAuthCopyHeaders: cfg.ForwardAuthCopyHeaders,
+		SecurityHeadersConfig:  copySecurityHeadersConfig(cfg.SecurityHeadersConfig),
+		CorsAllowCredentials:   copyBoolPtrOrDefault(cfg.CorsAllowCredentials, false),
+		CorsMaxAge:             cfg.CorsMaxAge,
+		CompressionAlgorithms:  cfg.CompressionAlgorithms,
+		ACMECA:                 cfg.ACMECA,
+		SecurityHeaders:        copyBoolPtrOrDefault(cfg.SecurityHeaders, false),
+		ErrorPagePath:          cfg.ErrorPagePath,
+		CacheEnabled:           copyBoolPtrOrDefault(cfg.CacheEnabled, false),
+		CacheTTL:               intOrDefault(cfg.CacheTTL, 300),
+		RootPath:               cfg.RootPath,
+		DirectoryBrowse:        copyBoolPtrOrDefault(cfg.DirectoryBrowse, false),
+		SPAFallback:            copyBoolPtrOrDefault(cfg.SPAFallback, false),
+		PHPFastCGI:             cfg.PHPFastCGI,
+		PHPVersion:             cfg.PHPVersion,
+		IndexFiles:             cfg.IndexFiles,
+		SeparateAccessLog:      copyBoolPtrOrDefault(cfg.SeparateAccessLog, true),
+		MaxRequestBodySize:     cfg.MaxRequestBodySize,
+		ProxyReadTimeout:       cfg.ProxyReadTimeout,
+		ProxyWriteTimeout:      cfg.ProxyWriteTimeout,
+		ProxyDialTimeout:       cfg.ProxyDialTimeout,
+		CustomDirectives:       cfg.CustomDirectives,
+		RedirectURL:            cfg.RedirectURL,
+		RedirectCode:           intOrDefault(cfg.RedirectCode, 301),
+		TLSMode:                stringOrDefault(cfg.TLSMode, "auto"),
 	}
 
 	// Add upstreams
@@ -264,6 +477,7 @@ func (s *TemplateService) CreateFromTemplate(templateID uint, domain string) (*m
 		host.BasicAuths = append(host.BasicAuths, model.BasicAuth{
 			Username:     ba.Username,
 			PasswordHash: ba.PasswordHash,
+			Path:         ba.Path,
 		})
 	}
 
@@ -293,6 +507,15 @@ func (s *TemplateService) CreateFromTemplate(templateID uint, domain string) (*m
 			return nil, fmt.Errorf("root_path is required for %s hosts", hostType)
 		}
 	}
+	if err := caddy.ValidatePHPFastCGI(host.PHPFastCGI); err != nil {
+		return nil, fmt.Errorf("template validation: %w", err)
+	}
+	if err := caddy.ValidatePHPVersion(host.PHPVersion); err != nil {
+		return nil, fmt.Errorf("template validation: %w", err)
+	}
+	if boolVal(host.SPAFallback) && hostType != "static" {
+		return nil, fmt.Errorf("spa_fallback is only supported for static hosts")
+	}
 
 	// Validate upstreams.
 	for _, u := range host.Upstreams {
@@ -313,16 +536,51 @@ func (s *TemplateService) CreateFromTemplate(templateID uint, domain string) (*m
 		return nil, fmt.Errorf("invalid custom directives in template: %w", err)
 	}
 
+	if err := caddy.ValidateForwardAuthURL(host.ForwardAuthURL); err != nil {
+		return nil, fmt.Errorf("template validation: %w", err)
+	}
+	if err := caddy.ValidateHeaderList("forward_auth_copy_headers", host.ForwardAuthCopyHeaders); err != nil {
+		return nil, fmt.Errorf("template validation: %w", err)
+	}
+	if err := caddy.ValidateSecurityHeadersConfig(host.SecurityHeadersConfig); err != nil {
+		return nil, fmt.Errorf("template validation: %w", err)
+	}
+	if err := caddy.ValidateCors(host.CorsOrigins, boolVal(host.CorsAllowCredentials), host.CorsMaxAge); err != nil {
+		return nil, fmt.Errorf("template validation: %w", err)
+	}
+	if host.CompressionAlgorithms, err = caddy.NormalizeCompressionAlgorithms(host.CompressionAlgorithms); err != nil {
+		return nil, fmt.Errorf("template validation: %w", err)
+	}
+	if host.ACMECA, err = caddy.NormalizeACMECA(host.ACMECA); err != nil {
+		return nil, fmt.Errorf("template validation: %w", err)
+	}
+
+	if host.MaxRequestBodySize != "" {
+		if _, err := caddy.ParseByteSize(host.MaxRequestBodySize); err != nil {
+			return nil, fmt.Errorf("template validation: invalid max_request_body_size: %w", err)
+		}
+	}
+	for label, val := range map[string]string{
+		"proxy_read_timeout":  host.ProxyReadTimeout,
+		"proxy_write_timeout": host.ProxyWriteTimeout,
+		"proxy_dial_timeout":  host.ProxyDialTimeout,
+	} {
+		if err := caddy.ValidateDuration(label, val); err != nil {
+			return nil, fmt.Errorf("template validation: %w", err)
+		}
+	}
+
 	// Validate all string fields that get embedded in Caddyfile.
 	for label, val := range map[string]string{
-		"redirect_url":    host.RedirectURL,
-		"root_path":       host.RootPath,
-		"error_page_path": host.ErrorPagePath,
-		"php_fastcgi":     host.PHPFastCGI,
-		"index_files":     host.IndexFiles,
-		"cors_origins":    host.CorsOrigins,
-		"cors_methods":    host.CorsMethods,
-		"cors_headers":    host.CorsHeaders,
+		"redirect_url":     host.RedirectURL,
+		"root_path":        host.RootPath,
+		"error_page_path":  host.ErrorPagePath,
+		"php_fastcgi":      host.PHPFastCGI,
+		"index_files":      host.IndexFiles,
+		"cors_origins":     host.CorsOrigins,
+		"cors_methods":     host.CorsMethods,
+		"cors_headers":     host.CorsHeaders,
+		"basic_auth_realm": host.BasicAuthRealm,
 	} {
 		if err := caddy.ValidateCaddyValue(label, val); err != nil {
 			return nil, fmt.Errorf("template validation: %w", err)
@@ -336,16 +594,13 @@ func (s *TemplateService) CreateFromTemplate(templateID uint, domain string) (*m
 			return nil, fmt.Errorf("template validation: %w", err)
 		}
 	}
-
-	if err := s.db.Create(host).Error; err != nil {
-		return nil, fmt.Errorf("failed to create host from template: %w", err)
-	}
-
-	if err := s.hostSvc.ApplyConfig(); err != nil {
-		log.Printf("Warning: failed to apply config after creating host from template: %v", err)
+	for _, ba := range host.BasicAuths {
+		if err := caddy.ValidateBasicAuthPath(ba.Path); err != nil {
+			return nil, fmt.Errorf("template validation: %w", err)
+		}
 	}
 
-	return s.hostSvc.Get(host.ID)
+	return host, nil
 }
 
 // Export serializes a template to the export JSON format.
@@ -362,6 +617,7 @@ func (s *TemplateService) Export(templateID uint) ([]byte, error) {
 			Name:        tpl.Name,
 			Description: tpl.Description,
 			Type:        tpl.Type,
+			Category:    tpl.Category,
 			Config:      json.RawMessage(tpl.Config),
 		},
 	}
@@ -396,12 +652,17 @@ func (s *TemplateService) Import(jsonData []byte) (*model.Template, error) {
 	if cfg.HostType == "" {
 		return nil, fmt.Errorf("error.template_missing_fields")
 	}
+	category, err := normalizeTemplateCategory(export.Template.Category, cfg.HostType)
+	if err != nil {
+		return nil, err
+	}
 
 	// Always import as custom type
 	tpl := &model.Template{
 		Name:        export.Template.Name,
 		Description: export.Template.Description,
 		Type:        "custom",
+		Category:    category,
 		Config:      string(export.Template.Config),
 	}
 	if err := s.db.Create(tpl).Error; err != nil {
@@ -410,138 +671,148 @@ func (s *TemplateService) Import(jsonData []byte) (*model.Template, error) {
 	return tpl, nil
 }
 
-// SeedPresets creates the 6 built-in preset templates if the templates table is empty.
+// SeedPresets reconciles the built-in preset templates with their current
+// definitions: missing presets are created and rows whose PresetVersion is
+// behind are upgraded in place. A preset is identified by its name, so bump
+// PresetVersion, not the name, when changing one. Custom templates are never
+// touched.
 func (s *TemplateService) SeedPresets() {
-	var count int64
-	s.db.Model(&model.Template{}).Count(&count)
-	if count > 0 {
-		return
-	}
-
 	presets := []model.Template{
 		{
-			Name:        "WordPress Reverse Proxy",
-			Description: "Reverse proxy for WordPress with compression enabled",
-			Type:        "preset",
-			Config:      mustJSON(TemplateConfig{
-				HostType:    "proxy",
-				TLSMode:     "auto",
-				TLSEnabled:  boolPtr(true),
-				HTTPRedirect: boolPtr(true),
-				Compression: boolPtr(true),
-				WebSocket:   boolPtr(false),
-				CorsEnabled: boolPtr(false),
+			Name:          "WordPress Reverse Proxy",
+			Description:   "Reverse proxy for WordPress with compression enabled",
+			Type:          "preset",
+			Category:      "cms",
+			PresetVersion: 1,
+			Config: mustJSON(TemplateConfig{
+				HostType:        "proxy",
+				TLSMode:         "auto",
+				TLSEnabled:      boolPtr(true),
+				HTTPRedirect:    boolPtr(true),
+				Compression:     boolPtr(true),
+				WebSocket:       boolPtr(false),
+				CorsEnabled:     boolPtr(false),
 				SecurityHeaders: boolPtr(false),
-				CacheEnabled: boolPtr(false),
-				CacheTTL:    300,
+				CacheEnabled:    boolPtr(false),
+				CacheTTL:        300,
 				DirectoryBrowse: boolPtr(false),
-				RedirectCode: 301,
+				RedirectCode:    301,
 				Upstreams: []model.UpstreamInput{
 					{Address: "localhost:8080", Weight: 1},
 				},
 			}),
 		},
 		{
-			Name:        "SPA Static Site",
-			Description: "Static site for Single Page Applications with index.html fallback",
-			Type:        "preset",
-			Config:      mustJSON(TemplateConfig{
-				HostType:    "static",
-				TLSMode:     "auto",
-				TLSEnabled:  boolPtr(true),
-				HTTPRedirect: boolPtr(true),
-				Compression: boolPtr(true),
-				WebSocket:   boolPtr(false),
-				CorsEnabled: boolPtr(false),
+			Name:          "SPA Static Site",
+			Description:   "Static site for Single Page Applications with index.html fallback",
+			Type:          "preset",
+			Category:      "static",
+			PresetVersion: 1,
+			Config: mustJSON(TemplateConfig{
+				HostType:        "static",
+				TLSMode:         "auto",
+				TLSEnabled:      boolPtr(true),
+				HTTPRedirect:    boolPtr(true),
+				Compression:     boolPtr(true),
+				WebSocket:       boolPtr(false),
+				CorsEnabled:     boolPtr(false),
 				SecurityHeaders: boolPtr(false),
-				CacheEnabled: boolPtr(false),
-				CacheTTL:    300,
-				RootPath:    "/var/www/spa",
-				IndexFiles:  "index.html",
+				CacheEnabled:    boolPtr(false),
+				CacheTTL:        300,
+				RootPath:        "/var/www/spa",
+				IndexFiles:      "index.html",
 				DirectoryBrowse: boolPtr(false),
-				RedirectCode: 301,
+				RedirectCode:    301,
 			}),
 		},
 		{
-			Name:        "API Reverse Proxy",
-			Description: "Reverse proxy for API services with CORS and security headers",
-			Type:        "preset",
-			Config:      mustJSON(TemplateConfig{
-				HostType:    "proxy",
-				TLSMode:     "auto",
-				TLSEnabled:  boolPtr(true),
-				HTTPRedirect: boolPtr(true),
-				Compression: boolPtr(false),
-				WebSocket:   boolPtr(false),
-				CorsEnabled: boolPtr(true),
+			Name:          "API Reverse Proxy",
+			Description:   "Reverse proxy for API services with CORS and security headers",
+			Type:          "preset",
+			Category:      "proxy",
+			PresetVersion: 1,
+			Config: mustJSON(TemplateConfig{
+				HostType:        "proxy",
+				TLSMode:         "auto",
+				TLSEnabled:      boolPtr(true),
+				HTTPRedirect:    boolPtr(true),
+				Compression:     boolPtr(false),
+				WebSocket:       boolPtr(false),
+				CorsEnabled:     boolPtr(true),
 				SecurityHeaders: boolPtr(true),
-				CacheEnabled: boolPtr(false),
-				CacheTTL:    300,
+				CacheEnabled:    boolPtr(false),
+				CacheTTL:        300,
 				DirectoryBrowse: boolPtr(false),
-				RedirectCode: 301,
+				RedirectCode:    301,
 				Upstreams: []model.UpstreamInput{
 					{Address: "localhost:3000", Weight: 1},
 				},
 			}),
 		},
 		{
-			Name:        "PHP-FPM Site",
-			Description: "PHP site with FastCGI process manager",
-			Type:        "preset",
-			Config:      mustJSON(TemplateConfig{
-				HostType:    "php",
-				TLSMode:     "auto",
-				TLSEnabled:  boolPtr(true),
-				HTTPRedirect: boolPtr(true),
-				Compression: boolPtr(true),
-				WebSocket:   boolPtr(false),
-				CorsEnabled: boolPtr(false),
+			Name:          "PHP-FPM Site",
+			Description:   "PHP site with FastCGI process manager",
+			Type:          "preset",
+			Category:      "php",
+			PresetVersion: 1,
+			Config: mustJSON(TemplateConfig{
+				HostType:        "php",
+				TLSMode:         "auto",
+				TLSEnabled:      boolPtr(true),
+				HTTPRedirect:    boolPtr(true),
+				Compression:     boolPtr(true),
+				WebSocket:       boolPtr(false),
+				CorsEnabled:     boolPtr(false),
 				SecurityHeaders: boolPtr(false),
-				CacheEnabled: boolPtr(false),
-				CacheTTL:    300,
-				RootPath:    "/var/www/php",
-				PHPFastCGI:  "localhost:9000",
+				CacheEnabled:    boolPtr(false),
+				CacheTTL:        300,
+				RootPath:        "/var/www/php",
+				PHPFastCGI:      "localhost:9000",
 				DirectoryBrowse: boolPtr(false),
-				RedirectCode: 301,
+				RedirectCode:    301,
 			}),
 		},
 		{
-			Name:        "Static File Download Site",
-			Description: "Static file server with directory browsing enabled",
-			Type:        "preset",
-			Config:      mustJSON(TemplateConfig{
-				HostType:    "static",
-				TLSMode:     "auto",
-				TLSEnabled:  boolPtr(true),
-				HTTPRedirect: boolPtr(true),
-				Compression: boolPtr(false),
-				WebSocket:   boolPtr(false),
-				CorsEnabled: boolPtr(false),
+			Name:          "Static File Download Site",
+			Description:   "Static file server with directory browsing enabled",
+			Type:          "preset",
+			Category:      "static",
+			PresetVersion: 1,
+			Config: mustJSON(TemplateConfig{
+				HostType:        "static",
+				TLSMode:         "auto",
+				TLSEnabled:      boolPtr(true),
+				HTTPRedirect:    boolPtr(true),
+				Compression:     boolPtr(false),
+				WebSocket:       boolPtr(false),
+				CorsEnabled:     boolPtr(false),
 				SecurityHeaders: boolPtr(false),
-				CacheEnabled: boolPtr(false),
-				CacheTTL:    300,
-				RootPath:    "/var/www/files",
+				CacheEnabled:    boolPtr(false),
+				CacheTTL:        300,
+				RootPath:        "/var/www/files",
 				DirectoryBrowse: boolPtr(true),
-				RedirectCode: 301,
+				RedirectCode:    301,
 			}),
 		},
 		{
-			Name:        "WebSocket Application",
-			Description: "Reverse proxy with WebSocket support enabled",
-			Type:        "preset",
-			Config:      mustJSON(TemplateConfig{
-				HostType:    "proxy",
-				TLSMode:     "auto",
-				TLSEnabled:  boolPtr(true),
-				HTTPRedirect: boolPtr(true),
-				Compression: boolPtr(false),
-				WebSocket:   boolPtr(true),
-				CorsEnabled: boolPtr(false),
+			Name:          "WebSocket Application",
+			Description:   "Reverse proxy with WebSocket support enabled",
+			Type:          "preset",
+			Category:      "proxy",
+			PresetVersion: 1,
+			Config: mustJSON(TemplateConfig{
+				HostType:        "proxy",
+				TLSMode:         "auto",
+				TLSEnabled:      boolPtr(true),
+				HTTPRedirect:    boolPtr(true),
+				Compression:     boolPtr(false),
+				WebSocket:       boolPtr(true),
+				CorsEnabled:     boolPtr(false),
 				SecurityHeaders: boolPtr(false),
-				CacheEnabled: boolPtr(false),
-				CacheTTL:    300,
+				CacheEnabled:    boolPtr(false),
+				CacheTTL:        300,
 				DirectoryBrowse: boolPtr(false),
-				RedirectCode: 301,
+				RedirectCode:    301,
 				Upstreams: []model.UpstreamInput{
 					{Address: "localhost:3000", Weight: 1},
 				},
@@ -549,38 +820,92 @@ func (s *TemplateService) SeedPresets() {
 		},
 	}
 
+	var seeded, upgraded int
 	for _, p := range presets {
-		if err := s.db.Create(&p).Error; err != nil {
-			log.Printf("Warning: failed to seed preset template '%s': %v", p.Name, err)
+		var existing model.Template
+		err := s.db.Where("type = ? AND name = ?", "preset", p.Name).First(&existing).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			if err := s.db.Create(&p).Error; err != nil {
+				log.Printf("Warning: failed to seed preset template '%s': %v", p.Name, err)
+				continue
+			}
+			seeded++
+		case err != nil:
+			log.Printf("Warning: failed to load preset template '%s': %v", p.Name, err)
+		case existing.PresetVersion < p.PresetVersion:
+			err := s.db.Model(&existing).Select("Description", "Category", "Config", "PresetVersion").Updates(&model.Template{
+				Description:   p.Description,
+				Category:      p.Category,
+				Config:        p.Config,
+				PresetVersion: p.PresetVersion,
+			}).Error
+			if err != nil {
+				log.Printf("Warning: failed to upgrade preset template '%s': %v", p.Name, err)
+				continue
+			}
+			upgraded++
 		}
 	}
-	log.Println("Seeded 6 preset templates")
+	if seeded > 0 || upgraded > 0 {
+		log.Printf("Preset templates: %d seeded, %d upgraded", seeded, upgraded)
+	}
+}
+
+var templateCategoryRegex = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,31}$`)
+
+// normalizeTemplateCategory lowercases a template category, falling back to
+// hostType when it is empty.
+func normalizeTemplateCategory(category, hostType string) (string, error) {
+	category = strings.ToLower(strings.TrimSpace(category))
+	if category == "" {
+		category = strings.ToLower(hostType)
+	}
+	if !templateCategoryRegex.MatchString(category) {
+		return "", fmt.Errorf("error.invalid_template_category")
+	}
+	return category, nil
 }
 
 // hostToTemplateConfig converts a Host (with loaded associations) to a TemplateConfig.
 func (s *TemplateService) hostToTemplateConfig(host *model.Host) TemplateConfig {
 	cfg := TemplateConfig{
-		HostType:         host.HostType,
-		TLSMode:          host.TLSMode,
-		TLSEnabled:       copyBoolPtr(host.TLSEnabled),
-		HTTPRedirect:     copyBoolPtr(host.HTTPRedirect),
-		WebSocket:        copyBoolPtr(host.WebSocket),
-		Compression:      copyBoolPtr(host.Compression),
-		CorsEnabled:      copyBoolPtr(host.CorsEnabled),
-		CorsOrigins:      host.CorsOrigins,
-		CorsMethods:      host.CorsMethods,
-		CorsHeaders:      host.CorsHeaders,
-		SecurityHeaders:  copyBoolPtr(host.SecurityHeaders),
-		ErrorPagePath:    host.ErrorPagePath,
-		CacheEnabled:     copyBoolPtr(host.CacheEnabled),
-		CacheTTL:         host.CacheTTL,
-		RootPath:         host.RootPath,
-		DirectoryBrowse:  copyBoolPtr(host.DirectoryBrowse),
-		PHPFastCGI:       host.PHPFastCGI,
-		IndexFiles:       host.IndexFiles,
-		CustomDirectives: host.CustomDirectives,
-		RedirectURL:      host.RedirectURL,
-		RedirectCode:     host.RedirectCode,
+		HostType:               host.HostType,
+		TLSMode:                host.TLSMode,
+		TLSEnabled:             copyBoolPtr(host.TLSEnabled),
+		HTTPRedirect:           copyBoolPtr(host.HTTPRedirect),
+		WebSocket:              copyBoolPtr(host.WebSocket),
+		Compression:            copyBoolPtr(host.Compression),
+		CorsEnabled:            copyBoolPtr(host.CorsEnabled),
+		CorsOrigins:            host.CorsOrigins,
+		CorsMethods:            host.CorsMethods,
+		CorsHeaders:            host.CorsHeaders,
+		BasicAuthRealm:         host.BasicAuthRealm,
+		ForwardAuthURL:         host.ForwardAuthURL,
+		ForwardAuthCopyHeaders: host.ForwardAuthCopyHeaders,
+		SecurityHeadersConfig:  copySecurityHeadersConfig(host.SecurityHeadersConfig),
+		CorsAllowCredentials:   copyBoolPtr(host.CorsAllowCredentials),
+		CorsMaxAge:             host.CorsMaxAge,
+		CompressionAlgorithms:  host.CompressionAlgorithms,
+		ACMECA:                 host.ACMECA,
+		SecurityHeaders:        copyBoolPtr(host.SecurityHeaders),
+		ErrorPagePath:          host.ErrorPagePath,
+		CacheEnabled:           copyBoolPtr(host.CacheEnabled),
+		CacheTTL:               host.CacheTTL,
+		RootPath:               host.RootPath,
+		DirectoryBrowse:        copyBoolPtr(host.DirectoryBrowse),
+		SPAFallback:            copyBoolPtr(host.SPAFallback),
+		PHPFastCGI:             host.PHPFastCGI,
+		PHPVersion:             host.PHPVersion,
+		IndexFiles:             host.IndexFiles,
+		SeparateAccessLog:      copyBoolPtr(host.SeparateAccessLog),
+		MaxRequestBodySize:     host.MaxRequestBodySize,
+		ProxyReadTimeout:       host.ProxyReadTimeout,
+		ProxyWriteTimeout:      host.ProxyWriteTimeout,
+		ProxyDialTimeout:       host.ProxyDialTimeout,
+		CustomDirectives:       host.CustomDirectives,
+		RedirectURL:            host.RedirectURL,
+		RedirectCode:           host.RedirectCode,
 	}
 
 	for _, u := range host.Upstreams {
@@ -611,12 +936,67 @@ func (s *TemplateService) hostToTemplateConfig(host *model.Host) TemplateConfig
 		cfg.BasicAuths = append(cfg.BasicAuths, TemplateBasicAuth{
 			Username:     ba.Username,
 			PasswordHash: ba.PasswordHash,
+			Path:         ba.Path,
 		})
 	}
 
 	return cfg
 }
 
+// expandPlaceholders substitutes domain-derived values into the fields of a
+// template that commonly embed the domain:
+//
+//	{{domain}}         the new host's domain, e.g. app.example.com
+//	{{domain_slug}}    the domain as a path/name-safe slug, e.g. app-example-com
+//	{{upstream_host}}  the domain's first label, e.g. app (a typical container name)
+//
+// Substituted fields are root_path, error_page_path, php_fastcgi,
+// redirect_url, cors_origins, basic_auth_realm, forward_auth_url,
+// custom_directives, upstream addresses, custom header values and basic auth
+// paths. Type, mode and size fields are left as-is. Unknown placeholders are
+// not touched and fail validation like any other stray brace.
+func (c *TemplateConfig) expandPlaceholders(domain string) {
+	name := strings.TrimPrefix(strings.ToLower(domain), "*.")
+	label, _, _ := strings.Cut(name, ".")
+	r := strings.NewReplacer(
+		"{{domain}}", domain,
+		"{{domain_slug}}", domainSlug(name),
+		"{{upstream_host}}", label,
+	)
+
+	for _, field := range []*string{
+		&c.RootPath, &c.ErrorPagePath, &c.PHPFastCGI, &c.RedirectURL,
+		&c.CorsOrigins, &c.BasicAuthRealm, &c.ForwardAuthURL, &c.CustomDirectives,
+	} {
+		*field = r.Replace(*field)
+	}
+	for i := range c.Upstreams {
+		c.Upstreams[i].Address = r.Replace(c.Upstreams[i].Address)
+	}
+	for i := range c.CustomHeaders {
+		c.CustomHeaders[i].Value = r.Replace(c.CustomHeaders[i].Value)
+	}
+	for i := range c.BasicAuths {
+		c.BasicAuths[i].Path = r.Replace(c.BasicAuths[i].Path)
+	}
+}
+
+// domainSlug turns a domain into lowercase letters, digits and single dashes.
+func domainSlug(domain string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(domain) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-")
+}
+
 // copyBoolPtrOrDefault copies a *bool pointer, returning a pointer to defaultVal if nil.
 func copyBoolPtrOrDefault(ptr *bool, defaultVal bool) *bool {
 	if ptr == nil {
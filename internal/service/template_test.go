@@ -3,6 +3,7 @@ package service
 import (
 	"encoding/json"
 	"fmt"
+	"sync/atomic"
 	"testing"
 
 	"github.com/web-casa/webcasa/internal/model"
@@ -41,7 +42,7 @@ func TestProperty16_HostTemplateHostRoundTrip(t *testing.T) {
 			source := createTestHost(t, hostSvc, sourceDomain, numUpstreams, numHeaders, numAccessRules, numBasicAuths, 0)
 
 			// Save as template
-			tpl, err := tplSvc.SaveAsTemplate(source.ID, "Test Template", "test desc")
+			tpl, err := tplSvc.SaveAsTemplate(source.ID, "Test Template", "test desc", "")
 			if err != nil {
 				t.Logf("SaveAsTemplate failed: %v", err)
 				return false
@@ -159,7 +160,7 @@ func TestProperty17_TemplateExportImportRoundTrip(t *testing.T) {
 			domain := fmt.Sprintf("export-src-%d.example.com", suffix)
 			source := createTestHost(t, hostSvc, domain, numUpstreams, numHeaders, 0, 0, 0)
 
-			tpl, err := tplSvc.SaveAsTemplate(source.ID, fmt.Sprintf("Export Test %d", suffix), "export test desc")
+			tpl, err := tplSvc.SaveAsTemplate(source.ID, fmt.Sprintf("Export Test %d", suffix), "export test desc", "")
 			if err != nil {
 				t.Logf("SaveAsTemplate failed: %v", err)
 				return false
@@ -269,7 +270,7 @@ func TestProperty18_PresetTemplatesImmutable(t *testing.T) {
 			}
 
 			// Attempt to update — should fail
-			_, err = tplSvc.Update(preset.ID, "New Name", "New Desc", "")
+			_, err = tplSvc.Update(preset.ID, "New Name", "New Desc", "", "")
 			if err == nil || err.Error() != "error.preset_immutable" {
 				t.Logf("Update should have returned error.preset_immutable, got: %v", err)
 				return false
@@ -349,3 +350,228 @@ func TestProperty19_InvalidTemplateJSONRejected(t *testing.T) {
 
 	properties.TestingRun(t)
 }
+
+func TestCreateFromTemplate_Placeholders(t *testing.T) {
+	tplSvc, _ := setupTestTemplateService(t)
+
+	static, err := tplSvc.Create("site", "", "", `{"host_type":"static","root_path":"/var/www/{{domain}}","error_page_path":"/srv/errors/{{domain_slug}}"}`)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	host, err := tplSvc.CreateFromTemplate(static.ID, "Blog.Example.com")
+	if err != nil {
+		t.Fatalf("CreateFromTemplate: %v", err)
+	}
+	// Placeholders expand to the domain as stored, normalized to lower case.
+	if host.RootPath != "/var/www/blog.example.com" {
+		t.Errorf("root_path = %q, want /var/www/blog.example.com", host.RootPath)
+	}
+	if host.ErrorPagePath != "/srv/errors/blog-example-com" {
+		t.Errorf("error_page_path = %q, want /srv/errors/blog-example-com", host.ErrorPagePath)
+	}
+
+	proxy, err := tplSvc.Create("container", "", "", `{"host_type":"proxy","upstreams":[{"address":"{{upstream_host}}:8080"}]}`)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	host, err = tplSvc.CreateFromTemplate(proxy.ID, "grafana.example.com")
+	if err != nil {
+		t.Fatalf("CreateFromTemplate: %v", err)
+	}
+	if len(host.Upstreams) != 1 || host.Upstreams[0].Address != "grafana:8080" {
+		t.Errorf("upstreams = %+v, want grafana:8080", host.Upstreams)
+	}
+
+	unknown, err := tplSvc.Create("unknown", "", "", `{"host_type":"static","root_path":"/var/www/{{site}}"}`)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if _, err := tplSvc.CreateFromTemplate(unknown.ID, "other.example.com"); err == nil {
+		t.Error("unknown placeholder accepted")
+	}
+}
+
+func TestTemplateList_FilterByCategoryAndType(t *testing.T) {
+	tplSvc, _ := setupTestTemplateService(t)
+	tplSvc.SeedPresets()
+
+	if _, err := tplSvc.Create("Ghost", "", "CMS", `{"host_type":"proxy","upstreams":[{"address":"localhost:2368"}]}`); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	docs, err := tplSvc.Create("Docs", "", "", `{"host_type":"static","root_path":"/srv/docs"}`)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if docs.Category != "static" {
+		t.Errorf("default category = %q, want the host type static", docs.Category)
+	}
+	if _, err := tplSvc.Create("Bad", "", "no spaces", `{"host_type":"proxy"}`); err == nil || err.Error() != "error.invalid_template_category" {
+		t.Errorf("invalid category: err = %v", err)
+	}
+
+	names := func(filter TemplateListFilter) []string {
+		t.Helper()
+		templates, err := tplSvc.List(filter)
+		if err != nil {
+			t.Fatalf("List(%+v): %v", filter, err)
+		}
+		var out []string
+		for _, tpl := range templates {
+			out = append(out, tpl.Name)
+		}
+		return out
+	}
+
+	if got := names(TemplateListFilter{Category: "cms"}); fmt.Sprint(got) != "[WordPress Reverse Proxy Ghost]" {
+		t.Errorf("category cms = %v", got)
+	}
+	if got := names(TemplateListFilter{Category: "cms", Type: "custom"}); fmt.Sprint(got) != "[Ghost]" {
+		t.Errorf("category cms, type custom = %v", got)
+	}
+	if got := names(TemplateListFilter{Category: "static", Type: "preset"}); fmt.Sprint(got) != "[SPA Static Site Static File Download Site]" {
+		t.Errorf("category static, type preset = %v", got)
+	}
+	if got := names(TemplateListFilter{Type: "custom"}); len(got) != 2 {
+		t.Errorf("type custom = %v, want 2 templates", got)
+	}
+}
+
+func TestSeedPresets_Categories(t *testing.T) {
+	tplSvc, _ := setupTestTemplateService(t)
+	tplSvc.SeedPresets()
+
+	want := map[string]string{
+		"WordPress Reverse Proxy":   "cms",
+		"SPA Static Site":           "static",
+		"API Reverse Proxy":         "proxy",
+		"PHP-FPM Site":              "php",
+		"Static File Download Site": "static",
+		"WebSocket Application":     "proxy",
+	}
+	check := func() {
+		t.Helper()
+		presets, err := tplSvc.List(TemplateListFilter{Type: "preset"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(presets) != len(want) {
+			t.Fatalf("%d presets, want %d", len(presets), len(want))
+		}
+		for _, p := range presets {
+			if p.Category != want[p.Name] {
+				t.Errorf("preset %q category = %q, want %q", p.Name, p.Category, want[p.Name])
+			}
+		}
+	}
+	check()
+
+	// Presets seeded before categories existed are upgraded on the next start.
+	tplSvc.db.Model(&model.Template{}).Where("type = ?", "preset").
+		Updates(map[string]interface{}{"category": "", "preset_version": 0})
+	tplSvc.SeedPresets()
+	check()
+}
+
+func TestSeedPresets_UpgradesOutdatedPresets(t *testing.T) {
+	tplSvc, _ := setupTestTemplateService(t)
+	tplSvc.SeedPresets()
+
+	var preset model.Template
+	if err := tplSvc.db.Where("type = ? AND name = ?", "preset", "WebSocket Application").First(&preset).Error; err != nil {
+		t.Fatal(err)
+	}
+	current := preset
+	tplSvc.db.Model(&preset).Updates(map[string]interface{}{
+		"description":    "old description",
+		"config":         `{"host_type":"proxy"}`,
+		"preset_version": 0,
+	})
+	custom, err := tplSvc.Create("WebSocket Application", "my own", "", `{"host_type":"proxy","websocket":false}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A lost preset row is recreated.
+	tplSvc.db.Where("type = ? AND name = ?", "preset", "API Reverse Proxy").Delete(&model.Template{})
+
+	tplSvc.SeedPresets()
+
+	var upgraded model.Template
+	tplSvc.db.First(&upgraded, preset.ID)
+	if upgraded.Config != current.Config || upgraded.Description != current.Description || upgraded.PresetVersion != current.PresetVersion {
+		t.Errorf("outdated preset not upgraded in place: %+v", upgraded)
+	}
+	var after model.Template
+	tplSvc.db.First(&after, custom.ID)
+	if after.Type != "custom" || after.Description != "my own" || after.Config != custom.Config || after.PresetVersion != 0 {
+		t.Errorf("custom template was modified: %+v", after)
+	}
+	presets, _ := tplSvc.List(TemplateListFilter{Type: "preset"})
+	if len(presets) != 6 {
+		t.Errorf("%d presets after reconcile, want 6", len(presets))
+	}
+
+	// Reconciling again is a no-op.
+	tplSvc.SeedPresets()
+	var count int64
+	tplSvc.db.Model(&model.Template{}).Count(&count)
+	if count != 7 {
+		t.Errorf("%d templates after a second reconcile, want 7", count)
+	}
+}
+
+func TestCreateHostsFromTemplate_MixedDomains(t *testing.T) {
+	tplSvc, hostSvc := setupTestTemplateService(t)
+	createTestHost(t, hostSvc, "taken.example.com", 1, 0, 0, 0, 0)
+	applies := countApplies(hostSvc, 0)
+
+	tpl, err := tplSvc.Create("site", "", "", `{"host_type":"static","root_path":"/var/www/{{domain}}"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := tplSvc.CreateHostsFromTemplate(tpl.ID, []string{
+		"a.example.com", "taken.example.com", "bad domain", "A.example.com", " b.example.com ",
+	})
+	if err != nil {
+		t.Fatalf("CreateHostsFromTemplate: %v", err)
+	}
+
+	want := []struct{ domain, status string }{
+		{"a.example.com", "created"},
+		{"taken.example.com", "skipped"},
+		{"bad domain", "failed"},
+		{"A.example.com", "skipped"},
+		{"b.example.com", "created"},
+	}
+	if len(results) != len(want) {
+		t.Fatalf("results = %+v, want %d entries", results, len(want))
+	}
+	for i, w := range want {
+		r := results[i]
+		if r.Domain != w.domain || r.Status != w.status {
+			t.Errorf("result %d = %+v, want %s %s", i, r, w.domain, w.status)
+		}
+		if (r.Status == "created") != (r.HostID != 0) {
+			t.Errorf("result %d host id = %d", i, r.HostID)
+		}
+		if r.Status != "created" && r.Error == "" {
+			t.Errorf("result %d has no error", i)
+		}
+	}
+
+	for _, r := range []TemplateHostResult{results[0], results[4]} {
+		host, err := hostSvc.Get(r.HostID)
+		if err != nil {
+			t.Fatalf("created host %s: %v", r.Domain, err)
+		}
+		if host.RootPath != "/var/www/"+r.Domain {
+			t.Errorf("%s root_path = %q", r.Domain, host.RootPath)
+		}
+	}
+	hosts, _ := hostSvc.List()
+	if len(hosts) != 3 {
+		t.Errorf("%d hosts, want 3", len(hosts))
+	}
+	if n := atomic.LoadInt32(applies); n != 1 {
+		t.Errorf("%d config applies, want 1", n)
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"math/big"
 	"time"
 
+	"github.com/pquerna/otp"
 	"github.com/pquerna/otp/totp"
 	"github.com/web-casa/webcasa/internal/config"
 	"github.com/web-casa/webcasa/internal/model"
@@ -31,15 +32,22 @@ type RecoveryCodeEntry struct {
 	Used bool   `json:"used"`
 }
 
-// TOTPService handles TOTP 2FA operations
+// TOTPService handles 2FA operations: TOTP, email codes and recovery codes
 type TOTPService struct {
-	db  *gorm.DB
-	cfg *config.Config
+	db     *gorm.DB
+	cfg    *config.Config
+	mailer Mailer
 }
 
-// NewTOTPService creates a new TOTPService
+// NewTOTPService creates a new TOTPService. Email codes are sent through the
+// SMTP server configured in panel settings.
 func NewTOTPService(db *gorm.DB, cfg *config.Config) *TOTPService {
-	return &TOTPService{db: db, cfg: cfg}
+	return &TOTPService{db: db, cfg: cfg, mailer: NewSMTPMailer(db, cfg.JWTSecret)}
+}
+
+// SetMailer replaces the mailer used for email 2FA codes.
+func (s *TOTPService) SetMailer(m Mailer) {
+	s.mailer = m
 }
 
 // deriveAESKey derives a 32-byte AES key from the JWT secret using HKDF-SHA256
@@ -115,24 +123,50 @@ func decryptAESGCM(encoded string, key []byte) ([]byte, error) {
 // totpPeriod is the TOTP step length in seconds, matching totp.Validate defaults.
 const totpPeriod = 30
 
-// matchedTimestep validates code against secret over the same ±1 window that
-// totp.Validate uses, and returns the timestep (Unix time / period) the code
-// matched. ok is false if the code is not valid for any step in the window.
-func matchedTimestep(code, secret string, now time.Time) (step int64, ok bool) {
+// defaultTOTPSkew is the ±1 step window totp.Validate uses. maxTOTPSkew bounds
+// the configurable login window so a misconfiguration cannot accept codes
+// from minutes away.
+const (
+	defaultTOTPSkew = 1
+	maxTOTPSkew     = 10
+)
+
+// matchedTimestep validates code against secret within ±skew steps of now and
+// returns the timestep (Unix time / period) the code matched. ok is false if
+// the code is not valid for any step in the window.
+func matchedTimestep(code, secret string, now time.Time, skew uint) (step int64, ok bool) {
+	opts := totp.ValidateOpts{
+		Period:    totpPeriod,
+		Digits:    otp.DigitsSix,
+		Algorithm: otp.AlgorithmSHA1,
+	}
 	current := now.Unix() / totpPeriod
-	// Check current step first, then ±1, so the latest matching step wins.
-	for _, s := range []int64{current, current + 1, current - 1} {
-		expected, err := totp.GenerateCode(secret, time.Unix(s*totpPeriod, 0))
-		if err != nil {
-			continue
-		}
-		if expected == code {
+	// Check current step first, then outwards (+d before -d), so the latest
+	// matching step wins.
+	steps := []int64{current}
+	for d := int64(1); d <= int64(skew); d++ {
+		steps = append(steps, current+d, current-d)
+	}
+	for _, s := range steps {
+		if valid, err := totp.ValidateCustom(code, secret, time.Unix(s*totpPeriod, 0).UTC(), opts); err == nil && valid {
 			return s, true
 		}
 	}
 	return 0, false
 }
 
+// loginSkew returns the configured login window in steps, capped at maxTOTPSkew.
+func (s *TOTPService) loginSkew() uint {
+	skew := s.cfg.TOTPSkewPeriods
+	if skew < 0 {
+		return 0
+	}
+	if skew > maxTOTPSkew {
+		return maxTOTPSkew
+	}
+	return uint(skew)
+}
+
 // GenerateSecret generates a TOTP secret for a user, encrypts and stores it.
 // Returns the otpauth URI for QR code generation.
 func (s *TOTPService) GenerateSecret(userID uint) (string, error) {
@@ -186,6 +220,26 @@ func generateRecoveryCodes() []string {
 	return codes
 }
 
+// newRecoveryCodeSet generates a fresh set of recovery codes and returns the
+// plaintext codes alongside their bcrypt-hashed JSON form for storage.
+func newRecoveryCodeSet() ([]string, string, error) {
+	plainCodes := generateRecoveryCodes()
+	entries := make([]RecoveryCodeEntry, 0, len(plainCodes))
+	for _, pc := range plainCodes {
+		hash, err := bcrypt.GenerateFromPassword([]byte(pc), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to hash recovery code: %w", err)
+		}
+		entries = append(entries, RecoveryCodeEntry{Hash: string(hash), Used: false})
+	}
+
+	codesJSON, err := json.Marshal(entries)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to marshal recovery codes: %w", err)
+	}
+	return plainCodes, string(codesJSON), nil
+}
+
 // VerifyAndEnable verifies a TOTP code and enables 2FA for the user.
 // Returns the 8 plaintext recovery codes on success.
 func (s *TOTPService) VerifyAndEnable(userID uint, code string) ([]string, error) {
@@ -211,27 +265,18 @@ func (s *TOTPService) VerifyAndEnable(userID uint, code string) ([]string, error
 	}
 
 	// Generate 8 recovery codes
-	plainCodes := generateRecoveryCodes()
-	var entries []RecoveryCodeEntry
-	for _, pc := range plainCodes {
-		hash, err := bcrypt.GenerateFromPassword([]byte(pc), bcrypt.DefaultCost)
-		if err != nil {
-			return nil, fmt.Errorf("failed to hash recovery code: %w", err)
-		}
-		entries = append(entries, RecoveryCodeEntry{Hash: string(hash), Used: false})
-	}
-
-	codesJSON, err := json.Marshal(entries)
+	plainCodes, codesJSON, err := newRecoveryCodeSet()
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal recovery codes: %w", err)
+		return nil, err
 	}
 
 	// Enable 2FA. Record the timestep used to enable so the same code cannot be
 	// replayed at login (TOTP replay protection).
 	enabled := true
 	user.TOTPEnabled = &enabled
-	user.RecoveryCodes = string(codesJSON)
-	if step, ok := matchedTimestep(code, string(secretBytes), time.Now()); ok {
+	user.TwoFactorMethod = TwoFactorTOTP
+	user.RecoveryCodes = codesJSON
+	if step, ok := matchedTimestep(code, string(secretBytes), time.Now(), defaultTOTPSkew); ok {
 		user.LastTOTPTimestep = step
 	}
 	if err := s.db.Save(&user).Error; err != nil {
@@ -241,7 +286,75 @@ func (s *TOTPService) VerifyAndEnable(userID uint, code string) ([]string, error
 	return plainCodes, nil
 }
 
-// Disable verifies a TOTP code and disables 2FA for the user.
+// RegenerateRecoveryCodes verifies a current 2FA code and replaces the user's
+// recovery codes with a fresh set, invalidating every old code. Returns the
+// new plaintext codes; only their hashes are stored.
+func (s *TOTPService) RegenerateRecoveryCodes(userID uint, code string) ([]string, error) {
+	var user model.User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		return nil, fmt.Errorf("error.user_not_found")
+	}
+
+	if user.TOTPEnabled == nil || !*user.TOTPEnabled {
+		return nil, fmt.Errorf("error.2fa_not_enabled")
+	}
+
+	// Only a second-factor code is accepted here — a recovery code must not be
+	// able to mint more recovery codes. Replayed codes are rejected as at login.
+	updates := map[string]interface{}{}
+	if user.TwoFactorMethod == TwoFactorEmail {
+		ok, err := s.consumeEmailCode(&user, code)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, fmt.Errorf("error.invalid_totp")
+		}
+	} else {
+		secretBytes, err := s.decryptTOTPSecret(user.TOTPSecret)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt TOTP secret: %w", err)
+		}
+		step, ok := matchedTimestep(code, string(secretBytes), time.Now(), defaultTOTPSkew)
+		if !ok || step <= user.LastTOTPTimestep {
+			return nil, fmt.Errorf("error.invalid_totp")
+		}
+		updates["last_totp_timestep"] = step
+	}
+
+	plainCodes, codesJSON, err := newRecoveryCodeSet()
+	if err != nil {
+		return nil, err
+	}
+	updates["recovery_codes"] = codesJSON
+	if err := s.db.Model(&model.User{}).Where("id = ?", user.ID).Updates(updates).Error; err != nil {
+		return nil, fmt.Errorf("failed to save recovery codes: %w", err)
+	}
+
+	return plainCodes, nil
+}
+
+// RemainingRecoveryCodes counts the unused entries in a user's stored
+// recovery codes. Malformed or empty data counts as none left.
+func RemainingRecoveryCodes(recoveryCodes string) int {
+	if recoveryCodes == "" {
+		return 0
+	}
+	var entries []RecoveryCodeEntry
+	if err := json.Unmarshal([]byte(recoveryCodes), &entries); err != nil {
+		return 0
+	}
+	remaining := 0
+	for _, e := range entries {
+		if !e.Used {
+			remaining++
+		}
+	}
+	return remaining
+}
+
+// Disable verifies a 2FA code (TOTP or email, per the user's method) and
+// disables 2FA for the user.
 func (s *TOTPService) Disable(userID uint, code string) error {
 	var user model.User
 	if err := s.db.First(&user, userID).Error; err != nil {
@@ -252,15 +365,25 @@ func (s *TOTPService) Disable(userID uint, code string) error {
 		return fmt.Errorf("error.2fa_not_enabled")
 	}
 
-	// Decrypt and validate TOTP code
-	secretBytes, err := s.decryptTOTPSecret(user.TOTPSecret)
-	if err != nil {
-		return fmt.Errorf("failed to decrypt TOTP secret: %w", err)
-	}
+	if user.TwoFactorMethod == TwoFactorEmail {
+		ok, err := s.consumeEmailCode(&user, code)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("error.invalid_totp")
+		}
+	} else {
+		// Decrypt and validate TOTP code
+		secretBytes, err := s.decryptTOTPSecret(user.TOTPSecret)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt TOTP secret: %w", err)
+		}
 
-	valid := totp.Validate(code, string(secretBytes))
-	if !valid {
-		return fmt.Errorf("error.invalid_totp")
+		valid := totp.Validate(code, string(secretBytes))
+		if !valid {
+			return fmt.Errorf("error.invalid_totp")
+		}
 	}
 
 	// Disable 2FA
@@ -269,9 +392,11 @@ func (s *TOTPService) Disable(userID uint, code string) error {
 	user.TOTPSecret = ""
 	user.RecoveryCodes = ""
 	if err := s.db.Model(&user).Updates(map[string]interface{}{
-		"totp_enabled":   false,
-		"totp_secret":    "",
-		"recovery_codes": "",
+		"totp_enabled":      false,
+		"totp_secret":       "",
+		"recovery_codes":    "",
+		"two_factor_method": TwoFactorTOTP,
+		"email_otp_hash":    "",
 	}).Error; err != nil {
 		return fmt.Errorf("failed to disable 2FA: %w", err)
 	}
@@ -279,7 +404,7 @@ func (s *TOTPService) Disable(userID uint, code string) error {
 	return nil
 }
 
-// ValidateLogin validates a TOTP code or recovery code for login.
+// ValidateLogin validates a TOTP code, email code or recovery code for login.
 // Returns true if the code is valid.
 func (s *TOTPService) ValidateLogin(userID uint, code string) (bool, error) {
 	var user model.User
@@ -291,24 +416,35 @@ func (s *TOTPService) ValidateLogin(userID uint, code string) (bool, error) {
 		return false, fmt.Errorf("error.2fa_not_enabled")
 	}
 
-	// Try TOTP code first
-	secretBytes, err := s.decryptTOTPSecret(user.TOTPSecret)
-	if err != nil {
-		return false, fmt.Errorf("failed to decrypt TOTP secret: %w", err)
-	}
-
-	// Replay protection: find the timestep the code matches and reject any code
-	// from a timestep already consumed (<= the last accepted one). On success we
-	// persist the new timestep so the same code cannot be reused within its window.
-	if step, ok := matchedTimestep(code, string(secretBytes), time.Now()); ok {
-		if step <= user.LastTOTPTimestep {
-			return false, nil
+	// Try the user's second factor first: an emailed code or a TOTP code.
+	if user.TwoFactorMethod == TwoFactorEmail {
+		ok, err := s.consumeEmailCode(&user, code)
+		if err != nil {
+			return false, err
 		}
-		if err := s.db.Model(&model.User{}).Where("id = ?", user.ID).
-			Update("last_totp_timestep", step).Error; err != nil {
-			return false, fmt.Errorf("failed to record TOTP timestep: %w", err)
+		if ok {
+			return true, nil
+		}
+	} else {
+		secretBytes, err := s.decryptTOTPSecret(user.TOTPSecret)
+		if err != nil {
+			return false, fmt.Errorf("failed to decrypt TOTP secret: %w", err)
+		}
+
+		// Replay protection: find the timestep the code matches and reject any code
+		// from a timestep already consumed (<= the last accepted one). On success we
+		// persist the new timestep so the same code cannot be reused within its window.
+		// The window is cfg.TOTPSkewPeriods steps either side, for drifting clocks.
+		if step, ok := matchedTimestep(code, string(secretBytes), time.Now(), s.loginSkew()); ok {
+			if step <= user.LastTOTPTimestep {
+				return false, nil
+			}
+			if err := s.db.Model(&model.User{}).Where("id = ?", user.ID).
+				Update("last_totp_timestep", step).Error; err != nil {
+				return false, fmt.Errorf("failed to record TOTP timestep: %w", err)
+			}
+			return true, nil
 		}
-		return true, nil
 	}
 
 	// Try recovery codes
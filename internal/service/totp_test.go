@@ -54,7 +54,8 @@ func setupTOTPTestDB(t *testing.T) (*TOTPService, *config.Config) {
 	)
 
 	cfg := &config.Config{
-		JWTSecret: testJWTSecret,
+		JWTSecret:       testJWTSecret,
+		TOTPSkewPeriods: 1,
 	}
 	svc := NewTOTPService(db, cfg)
 	return svc, cfg
@@ -246,7 +247,7 @@ func setupTestRouter(t *testing.T, svc *TOTPService, cfg *config.Config) *gin.En
 				c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid TOTP code", "error_key": "error.invalid_totp"})
 				return
 			}
-			token, _ := auth.GenerateToken(claims.UserID, claims.Username, cfg.JWTSecret)
+			token, _ := auth.GenerateToken(claims.UserID, claims.Username, "", cfg.JWTSecret)
 			c.JSON(http.StatusOK, gin.H{"token": token})
 			return
 		}
@@ -275,7 +276,7 @@ func setupTestRouter(t *testing.T, svc *TOTPService, cfg *config.Config) *gin.En
 			}
 		}
 
-		token, _ := auth.GenerateToken(user.ID, user.Username, cfg.JWTSecret)
+		token, _ := auth.GenerateToken(user.ID, user.Username, "", cfg.JWTSecret)
 		c.JSON(http.StatusOK, gin.H{"token": token})
 	})
 
@@ -375,6 +376,57 @@ func enableTestUser2FA(t *testing.T, svc *TOTPService, userID uint) ([]string, s
 	return codes, secret
 }
 
+// previousPeriodCode enables 2FA for a new user and returns the code for the
+// period before the current one. The replay marker is reset so the earlier
+// step is not rejected as already used.
+func previousPeriodCode(t *testing.T, svc *TOTPService, username string) (uint, string) {
+	t.Helper()
+	user := createTestUser(t, svc, username, "password123")
+	_, secret := enableTestUser2FA(t, svc, user.ID)
+	svc.db.Model(&model.User{}).Where("id = ?", user.ID).Update("last_totp_timestep", 0)
+	code, err := totp.GenerateCode(secret, time.Now().Add(-totpPeriod*time.Second))
+	if err != nil {
+		t.Fatalf("GenerateCode failed: %v", err)
+	}
+	return user.ID, code
+}
+
+func TestValidateLogin_ClockSkew(t *testing.T) {
+	svc, cfg := setupTOTPTestDB(t)
+
+	cfg.TOTPSkewPeriods = 1
+	userID, code := previousPeriodCode(t, svc, "drifting")
+	if valid, err := svc.ValidateLogin(userID, code); err != nil || !valid {
+		t.Errorf("skew=1: previous-period code rejected: valid=%v err=%v", valid, err)
+	}
+
+	cfg.TOTPSkewPeriods = 0
+	userID, code = previousPeriodCode(t, svc, "strict")
+	if valid, err := svc.ValidateLogin(userID, code); err != nil || valid {
+		t.Errorf("skew=0: previous-period code accepted: valid=%v err=%v", valid, err)
+	}
+}
+
+func TestMatchedTimestep_SkewWindow(t *testing.T) {
+	secret := "JBSWY3DPEHPK3PXP"
+	now := time.Unix(1700000000, 0)
+	current := now.Unix() / totpPeriod
+	code, _ := totp.GenerateCode(secret, now.Add(-2*totpPeriod*time.Second))
+
+	if _, ok := matchedTimestep(code, secret, now, 1); ok {
+		t.Error("code two periods old should be outside a ±1 window")
+	}
+	if step, ok := matchedTimestep(code, secret, now, 2); !ok || step != current-2 {
+		t.Errorf("skew=2: step=%d ok=%v, want %d", step, ok, current-2)
+	}
+
+	// The configured skew is capped.
+	svc := &TOTPService{cfg: &config.Config{TOTPSkewPeriods: 1000}}
+	if got := svc.loginSkew(); got != maxTOTPSkew {
+		t.Errorf("loginSkew = %d, want cap %d", got, maxTOTPSkew)
+	}
+}
+
 // Feature: phase6-enhancements, Property 7: 恢复码一次性使用 — first use succeeds, second use
 // of same code fails.
 // **Validates: Requirements 4.6**
@@ -693,3 +745,40 @@ func TestDeriveAESKey_Deterministic(t *testing.T) {
 		})
 	}
 }
+
+func TestRegenerateRecoveryCodes(t *testing.T) {
+	svc, _ := setupTOTPTestDB(t)
+	user := createTestUser(t, svc, "regen", "password123")
+	oldCodes, secret := enableTestUser2FA(t, svc, user.ID)
+
+	// A recovery code cannot stand in for the TOTP code.
+	if _, err := svc.RegenerateRecoveryCodes(user.ID, oldCodes[0]); err == nil || err.Error() != "error.invalid_totp" {
+		t.Fatalf("regenerate with a recovery code: err = %v, want error.invalid_totp", err)
+	}
+
+	// The enabling code's step is already consumed; clear it so the current
+	// code counts as fresh.
+	svc.db.Model(&model.User{}).Where("id = ?", user.ID).Update("last_totp_timestep", 0)
+	code, _ := totp.GenerateCode(secret, time.Now())
+	newCodes, err := svc.RegenerateRecoveryCodes(user.ID, code)
+	if err != nil {
+		t.Fatalf("RegenerateRecoveryCodes: %v", err)
+	}
+	if len(newCodes) != 8 {
+		t.Fatalf("got %d new codes, want 8", len(newCodes))
+	}
+
+	// Replaying the same TOTP code is rejected.
+	if _, err := svc.RegenerateRecoveryCodes(user.ID, code); err == nil {
+		t.Error("replayed TOTP code should not regenerate codes again")
+	}
+
+	for _, old := range oldCodes[:2] {
+		if valid, _ := svc.ValidateLogin(user.ID, old); valid {
+			t.Errorf("old recovery code %q still works after regeneration", old)
+		}
+	}
+	if valid, err := svc.ValidateLogin(user.ID, newCodes[0]); err != nil || !valid {
+		t.Errorf("new recovery code rejected: valid=%v err=%v", valid, err)
+	}
+}
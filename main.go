@@ -10,6 +10,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -18,6 +19,7 @@ import (
 	"github.com/web-casa/webcasa/internal/config"
 	"github.com/web-casa/webcasa/internal/database"
 	"github.com/web-casa/webcasa/internal/handler"
+	"github.com/web-casa/webcasa/internal/metrics"
 	"github.com/web-casa/webcasa/internal/model"
 	"github.com/web-casa/webcasa/internal/notify"
 	"github.com/web-casa/webcasa/internal/plugin"
@@ -66,14 +68,27 @@ func main() {
 
 	// Initialize services
 	hostSvc := service.NewHostService(db, caddyMgr, cfg)
+	dnsSvc := service.NewDnsProviderService(db, cfg.JWTSecret)
+
+	// DNS provider credentials are encrypted at rest; encrypt rows saved
+	// in plain text by earlier versions.
+	if n, err := dnsSvc.EncryptPlaintextConfigs(); err != nil {
+		log.Printf("⚠️  Failed to encrypt DNS provider configs: %v", err)
+	} else if n > 0 {
+		log.Printf("🔒 Encrypted %d plain-text DNS provider config(s)", n)
+	}
 
 	// Ensure a valid Caddyfile exists on startup
 	// This generates it from the database (even if empty → minimal global options)
 	if err := caddyMgr.EnsureCaddyfile(); err != nil {
 		log.Printf("⚠️  Failed to ensure Caddyfile: %v", err)
 	}
-	if err := hostSvc.ApplyConfig(); err != nil {
+	// Safe mode: hosts whose blocks break the Caddyfile are left out and
+	// flagged so the rest are still served.
+	if isolated, err := hostSvc.FlushConfigSafe(); err != nil {
 		log.Printf("⚠️  Failed to apply initial config: %v", err)
+	} else if len(isolated) > 0 {
+		log.Printf("⚠️  %d host(s) left out of the Caddyfile for config errors, see the dashboard", len(isolated))
 	}
 
 	// Auto-start Caddy if not already running
@@ -82,6 +97,8 @@ func main() {
 		if err := caddyMgr.Start(); err != nil {
 			log.Printf("⚠️  Failed to auto-start Caddy: %v", err)
 		}
+	} else {
+		caddyMgr.ExpectRunning()
 	}
 
 	// Setup Gin
@@ -135,8 +152,16 @@ func main() {
 	// ============ API Routes ============
 	api := r.Group("/api")
 
+	auth.SetTokenLifetimes(
+		time.Duration(cfg.JWTTTLMinutes)*time.Minute,
+		time.Duration(cfg.TempTokenTTLSeconds)*time.Second,
+	)
+
 	// Public routes (no auth required)
 	limiters := auth.NewLimiters()
+	if err := limiters.Persist(db); err != nil {
+		log.Printf("⚠️  Failed to load persisted login attempts: %v", err)
+	}
 	totpSvc := service.NewTOTPService(db, cfg)
 	authH := handler.NewAuthHandler(db, cfg, limiters, totpSvc)
 	api.POST("/auth/login", authH.Login)
@@ -144,6 +169,18 @@ func main() {
 	api.GET("/auth/need-setup", authH.NeedSetup)
 	api.GET("/auth/altcha-challenge", authH.AltchaChallenge)
 
+	// Health probe for load balancers and monitoring (no auth)
+	healthH := handler.NewHealthHandler(db, caddyMgr, Version)
+	api.GET("/health", healthH.Check)
+
+	// Prometheus metrics: gated by WEBCASA_METRICS_TOKEN, or localhost-only
+	// without one unless WEBCASA_METRICS_PUBLIC=true
+	if err := metrics.Register(metrics.NewPanelCollector(db, caddyMgr)); err != nil {
+		log.Printf("⚠️  Failed to register panel metrics: %v", err)
+	}
+	metricsH := handler.NewMetricsHandler(cfg.MetricsToken, cfg.MetricsPublic)
+	api.GET("/metrics", metricsH.Serve)
+
 	// API-token scope gate: fail-closed for scoped "wc_" tokens on mutating
 	// REST routes (only ["*"] tokens may mutate). No-op for JWT/session auth.
 	// See auth.RequireFullScopeForMutations for rationale.
@@ -153,44 +190,67 @@ func main() {
 	protected := api.Group("")
 	protected.Use(auth.Middleware(cfg.JWTSecret, auth.WithDB(db)))
 	protected.Use(tokenScopeGate)
+	protected.Use(handler.AuditMutations(db))
 
 	// Operator routes (JWT + operator/admin/owner role required)
 	operatorOnly := api.Group("")
 	operatorOnly.Use(auth.Middleware(cfg.JWTSecret, auth.WithDB(db)))
 	operatorOnly.Use(auth.RequireOperator(db))
 	operatorOnly.Use(tokenScopeGate)
+	operatorOnly.Use(handler.AuditMutations(db))
 
 	// Admin-only routes (JWT + admin/owner role required)
 	adminOnly := api.Group("")
 	adminOnly.Use(auth.Middleware(cfg.JWTSecret, auth.WithDB(db)))
 	adminOnly.Use(auth.RequireAdmin(db))
 	adminOnly.Use(tokenScopeGate)
+	adminOnly.Use(handler.AuditMutations(db))
 
 	// User info
 	protected.GET("/auth/me", authH.Me)
 	// Invalidate all of the caller's outstanding JWTs by bumping their token
 	// version (see auth.Middleware token-version check).
-	protected.POST("/auth/logout-all", authH.LogoutAll)
+	protected.POST("/auth/revoke-sessions", authH.LogoutAll)
+	protected.POST("/auth/logout-all", authH.LogoutAll) // legacy alias
+	protected.POST("/auth/change-password", authH.ChangePassword)
 
 	// 2FA TOTP endpoints
 	protected.POST("/auth/2fa/setup", authH.Setup2FA)
 	protected.POST("/auth/2fa/verify", authH.Verify2FA)
 	protected.POST("/auth/2fa/disable", authH.Disable2FA)
+	protected.POST("/auth/2fa/recovery/regenerate", authH.RegenerateRecoveryCodes)
+	protected.POST("/auth/2fa/email/setup", authH.SetupEmail2FA)
+	protected.POST("/auth/2fa/email/verify", authH.VerifyEmail2FA)
+	protected.POST("/auth/2fa/email/send-code", authH.SendEmail2FACode)
 
 	// Dashboard stats
-	dashH := handler.NewDashboardHandler(hostSvc, caddyMgr, Version)
+	dashH := handler.NewDashboardHandler(hostSvc, caddyMgr, cfg, Version)
 	protected.GET("/dashboard/stats", dashH.Stats)
+	protected.GET("/dashboard/system", dashH.System)
 	protected.GET("/news", dashH.News)
 
 	// Host CRUD
 	hostH := handler.NewHostHandler(hostSvc, db)
 	protected.GET("/hosts", hostH.List)
 	adminOnly.POST("/hosts", hostH.Create)
+	adminOnly.POST("/hosts/batch", hostH.Batch)
+	protected.GET("/hosts/trash", hostH.Trash)
 	protected.GET("/hosts/:id", hostH.Get)
 	adminOnly.PUT("/hosts/:id", hostH.Update)
 	adminOnly.DELETE("/hosts/:id", hostH.Delete)
+	adminOnly.POST("/hosts/:id/restore", hostH.Restore)
+	adminOnly.DELETE("/hosts/:id/purge", hostH.Purge)
 	operatorOnly.PATCH("/hosts/:id/toggle", hostH.Toggle)
+	operatorOnly.PATCH("/hosts/:id/maintenance", hostH.SetMaintenance)
+	protected.GET("/hosts/:id/schedule", hostH.GetSchedule)
+	operatorOnly.PUT("/hosts/:id/schedule", hostH.SetSchedule)
 	adminOnly.POST("/hosts/:id/clone", hostH.Clone)
+	protected.GET("/hosts/:id/revisions", hostH.Revisions)
+	protected.GET("/hosts/:id/cert-status", hostH.CertStatus)
+	protected.GET("/hosts/:id/curl", hostH.Curl)
+	protected.GET("/hosts/:id/lint", hostH.Lint)
+	protected.GET("/hosts/:id/metadata", hostH.Metadata)
+	adminOnly.POST("/hosts/:id/revisions/:rev/restore", hostH.RestoreRevision)
 
 	// SSL Certificate management (admin only — modifies TLS config)
 	certH := handler.NewCertHandler(hostSvc, cfg)
@@ -200,6 +260,7 @@ func main() {
 	// Caddy process control (operator for start/stop/reload, admin for config)
 	caddyH := handler.NewCaddyHandler(caddyMgr, db)
 	protected.GET("/caddy/status", caddyH.Status)
+	protected.GET("/caddy/metrics/summary", caddyH.MetricsSummary)
 	operatorOnly.POST("/caddy/start", caddyH.Start)
 	operatorOnly.POST("/caddy/stop", caddyH.Stop)
 	operatorOnly.POST("/caddy/reload", caddyH.Reload)
@@ -216,11 +277,16 @@ func main() {
 	protected.GET("/logs/files", logH.ListLogFiles)
 	protected.GET("/logs/download", logH.Download)
 	protected.GET("/logs/system", logH.GetSystemLog)
+	protected.GET("/logs/stream", logH.Stream)
+	protected.GET("/logs/access", logH.AccessLog)
 
 	// Config import/export (admin only)
-	exportH := handler.NewExportHandler(hostSvc)
+	exportH := handler.NewExportHandler(hostSvc, service.NewConfigService(db, hostSvc, cfg.JWTSecret))
 	adminOnly.GET("/config/export", exportH.Export)
 	adminOnly.POST("/config/import", exportH.Import)
+	adminOnly.POST("/config/export/full", exportH.ExportFull)
+	adminOnly.POST("/config/import/full", exportH.ImportFull)
+	adminOnly.POST("/caddy/caddyfile/import", exportH.ImportCaddyfile)
 
 	// User management (admin only)
 	userH := handler.NewUserHandler(db)
@@ -229,14 +295,32 @@ func main() {
 	adminOnly.PUT("/users/:id", userH.Update)
 	adminOnly.DELETE("/users/:id", userH.Delete)
 
+	// Brute-force lockouts (admin only)
+	adminOnly.GET("/auth/lockouts", authH.ListLockouts)
+	adminOnly.DELETE("/auth/lockouts", authH.ClearLockout)
+
+	// Full backups (admin only — archives hold the database and TLS keys)
+	backupSvc := service.NewBackupService(db, cfg)
+	backupSvc.StartSchedule()
+	backupH := handler.NewBackupHandler(backupSvc, db, hostSvc)
+	adminOnly.POST("/backup/now", backupH.Now)
+	adminOnly.POST("/backup/restore", backupH.Restore)
+	adminOnly.GET("/backup/list", backupH.List)
+	adminOnly.GET("/backup/download/:id", backupH.Download)
+
 	// Audit logs (admin only — contains user actions, IPs, sensitive context)
-	auditH := handler.NewAuditHandler(db)
+	auditH := handler.NewAuditHandler(db, cfg.AuditRetentionDays)
+	auditH.StartRetention()
 	adminOnly.GET("/audit/logs", auditH.List)
+	adminOnly.GET("/audit/logs/export", auditH.Export)
+	adminOnly.POST("/audit/logs/prune", auditH.Prune)
 
 	// DNS providers (admin only for mutations)
-	dnsH := handler.NewDnsProviderHandler(db)
+	dnsH := handler.NewDnsProviderHandler(dnsSvc, db)
 	protected.GET("/dns-providers", dnsH.List)
+	protected.GET("/dns-providers/default", dnsH.Default)
 	protected.GET("/dns-providers/:id", dnsH.Get)
+	protected.GET("/dns-providers/:id/render-preview", dnsH.RenderPreview)
 	adminOnly.POST("/dns-providers", dnsH.Create)
 	adminOnly.PUT("/dns-providers/:id", dnsH.Update)
 	adminOnly.DELETE("/dns-providers/:id", dnsH.Delete)
@@ -250,7 +334,9 @@ func main() {
 	groupSvc := service.NewGroupService(db, caddyMgr, cfg, hostSvc)
 	groupH := handler.NewGroupHandler(groupSvc, db)
 	protected.GET("/groups", groupH.List)
+	protected.GET("/groups/palette", groupH.Palette)
 	adminOnly.POST("/groups", groupH.Create)
+	adminOnly.PATCH("/groups/reorder", groupH.Reorder)
 	adminOnly.PUT("/groups/:id", groupH.Update)
 	adminOnly.DELETE("/groups/:id", groupH.Delete)
 	adminOnly.POST("/groups/:id/batch-enable", groupH.BatchEnable)
@@ -263,10 +349,14 @@ func main() {
 	adminOnly.POST("/tags", tagH.Create)
 	adminOnly.PUT("/tags/:id", tagH.Update)
 	adminOnly.DELETE("/tags/:id", tagH.Delete)
+	protected.GET("/tags/rules", tagH.ListRules)
+	adminOnly.POST("/tags/rules", tagH.CreateRule)
+	adminOnly.POST("/tags/rules/apply", tagH.ApplyRules)
+	adminOnly.DELETE("/tags/rules/:id", tagH.DeleteRule)
 
 	// Templates
 	tplSvc := service.NewTemplateService(db, hostSvc)
-	tplSvc.SeedPresets() // Seed preset templates if table is empty
+	tplSvc.SeedPresets() // Seed missing preset templates and upgrade outdated ones
 	tplH := handler.NewTemplateHandler(tplSvc, db)
 	protected.GET("/templates", tplH.List)
 	adminOnly.POST("/templates", tplH.Create)
@@ -275,15 +365,23 @@ func main() {
 	adminOnly.POST("/templates/import", tplH.Import)
 	protected.GET("/templates/:id/export", tplH.Export)
 	adminOnly.POST("/templates/:id/create-host", tplH.CreateHost)
+	adminOnly.POST("/templates/:id/create-hosts", tplH.CreateHosts)
 	adminOnly.POST("/hosts/:id/save-as-template", tplH.SaveAsTemplate)
 
+	// Global search
+	searchH := handler.NewSearchHandler(service.NewSearchService(db))
+	protected.GET("/search", searchH.Search)
+
 	// Settings (admin only — may contain sensitive values)
-	settingH := handler.NewSettingHandler(db)
+	settingH := handler.NewSettingHandler(db, cfg.JWTSecret)
 	adminOnly.GET("/settings/all", settingH.GetAll)
 	adminOnly.PUT("/settings", settingH.Update)
+	adminOnly.POST("/settings/smtp/test", settingH.TestSMTP)
 
 	// Notifications
 	notifier := notify.NewNotifier(db, slog.Default())
+	// Email channels without their own SMTP server use the panel's.
+	notifier.SetMailer(service.NewSMTPMailer(db, cfg.JWTSecret))
 	notifyH := handler.NewNotifyHandler(notifier)
 	adminOnly.GET("/notify/channels", notifyH.ListChannels)
 	adminOnly.POST("/notify/channels", notifyH.CreateChannel)
@@ -304,6 +402,10 @@ func main() {
 	publicPluginRouter := api.Group("/plugins") // public routes (no JWT) for webhooks etc.
 	pluginMgr := initPlugins(db, pluginRouter, operatorPluginRouter, adminPluginRouter, publicPluginRouter, hostSvc, caddyMgr, cfg)
 
+	// Live event-bus stream for the frontend (WebSocket)
+	eventsH := handler.NewEventsHandler(pluginMgr.EventBus())
+	protected.GET("/events/stream", eventsH.Stream)
+
 	// ============ Notification Integration ============
 	// Subscribe notifier to EventBus for deploy/backup/monitoring events
 	eventBus := pluginMgr.EventBus()
@@ -337,12 +439,84 @@ func main() {
 			Type: e.Type, Title: title, Message: formatEventMessage(e), Data: e.Payload, Time: e.Time,
 		})
 	})
+	eventBus.Subscribe("system.caddy.*", func(e plugin.Event) {
+		title := formatEventTitle(e)
+		notifier.Send(notify.NotifyEvent{
+			Type: e.Type, Title: title, Message: formatEventMessage(e), Data: e.Payload, Time: e.Time,
+		})
+	})
 	eventBus.Subscribe("cronjob.task.failed", func(e plugin.Event) {
 		title := formatEventTitle(e)
 		notifier.Send(notify.NotifyEvent{
 			Type: e.Type, Title: title, Message: formatEventMessage(e), Data: e.Payload, Time: e.Time,
 		})
 	})
+	eventBus.Subscribe("host.*", func(e plugin.Event) {
+		title := formatEventTitle(e)
+		notifier.Send(notify.NotifyEvent{
+			Type: e.Type, Title: title, Message: formatEventMessage(e), Data: e.Payload, Time: e.Time,
+		})
+	})
+	eventBus.Subscribe("cert.*", func(e plugin.Event) {
+		title := formatEventTitle(e)
+		notifier.Send(notify.NotifyEvent{
+			Type: e.Type, Title: title, Message: formatEventMessage(e), Data: e.Payload, Time: e.Time,
+		})
+	})
+
+	// Host changes made through the API are published as host.* events and
+	// Caddy starts, stops and failed reloads as system.caddy.* events.
+	hostH.SetEventBus(eventBus)
+	caddyMgr.OnLifecycle(func(e caddy.LifecycleEvent) {
+		payload := map[string]interface{}{}
+		if e.Err != nil {
+			payload["error"] = e.Err.Error()
+		}
+		eventBus.Publish(plugin.Event{Type: "system.caddy." + e.Kind, Source: "core", Payload: payload})
+	})
+
+	// ============ Caddy Watchdog ============
+	// Restarts Caddy if it dies while it should be running; every outage step
+	// is audited and published as a system.caddy.* event.
+	watchdog := caddy.NewWatchdog(caddyMgr, time.Duration(cfg.WatchdogIntervalSeconds)*time.Second, cfg.WatchdogMaxRetries,
+		func(e caddy.WatchdogEvent) {
+			action, detail := "DOWN", "Caddy found not running, restarting"
+			switch e.Kind {
+			case caddy.WatchdogRecovered:
+				action, detail = "RESTART", fmt.Sprintf("Watchdog restarted Caddy after %d attempt(s)", e.Attempts)
+			case caddy.WatchdogGaveUp:
+				action, detail = "RESTART_FAILED", fmt.Sprintf("Watchdog gave up restarting Caddy after %d attempts: %v", e.Attempts, e.Err)
+			}
+			handler.WriteAuditLog(db, nil, 0, "system", action, "caddy", "", detail)
+			eventBus.Publish(plugin.Event{
+				Type:    "system.caddy." + e.Kind,
+				Source:  "core",
+				Payload: map[string]interface{}{"attempts": e.Attempts, "detail": detail},
+			})
+		})
+	watchdog.Start()
+
+	// ============ Host Schedules ============
+	// Enables and disables hosts on their cron schedules; each switch is
+	// audited as a system action and published as host.toggled.
+	hostScheduler := service.NewHostScheduler(hostSvc, hostH.ScheduledToggle)
+	hostScheduler.Start()
+
+	// ============ Certificate Expiry ============
+	// Publishes a cert.expiring event daily for each certificate close to
+	// expiry.
+	certChecker := service.NewCertExpiryChecker(hostSvc, func(cert service.ExpiringCert) {
+		eventBus.Publish(plugin.Event{
+			Type:   "cert.expiring",
+			Source: "core",
+			Payload: map[string]interface{}{
+				"name": cert.Name, "domains": cert.Domains, "source": cert.Source,
+				"expires_at": cert.ExpiresAt.Format(time.RFC3339),
+				"days_left":  int(time.Until(cert.ExpiresAt).Hours() / 24),
+			},
+		})
+	})
+	certChecker.Start()
 
 	// ============ Version Checker ============
 	versionChecker := versioncheck.NewChecker(
@@ -485,6 +659,7 @@ func setupFrontend(r *gin.Engine) {
 // formatEventTitle generates a human-readable title for a notification event.
 func formatEventTitle(e plugin.Event) string {
 	projectName, _ := e.Payload["project_name"].(string)
+	domain, _ := e.Payload["domain"].(string)
 	switch e.Type {
 	case "deploy.build.failed":
 		return fmt.Sprintf("Build Failed: %s", projectName)
@@ -492,6 +667,32 @@ func formatEventTitle(e plugin.Event) string {
 		return fmt.Sprintf("Build Success: %s", projectName)
 	case "deploy.trigger_build":
 		return fmt.Sprintf("Build Triggered: %s", projectName)
+	case "system.caddy.down":
+		return "Caddy Down"
+	case "system.caddy.recovered":
+		return "Caddy Restarted"
+	case "system.caddy.gave_up":
+		return "Caddy Restart Failed"
+	case "system.caddy.started":
+		return "Caddy Started"
+	case "system.caddy.start_failed":
+		return "Caddy Start Failed"
+	case "system.caddy.stopped":
+		return "Caddy Stopped"
+	case "system.caddy.reload_failed":
+		return "Caddy Reload Failed"
+	case "host.created":
+		return fmt.Sprintf("Host Created: %s", domain)
+	case "host.deleted":
+		return fmt.Sprintf("Host Deleted: %s", domain)
+	case "host.toggled":
+		if e.Payload["enabled"] == true {
+			return fmt.Sprintf("Host Enabled: %s", domain)
+		}
+		return fmt.Sprintf("Host Disabled: %s", domain)
+	case "cert.expiring":
+		name, _ := e.Payload["name"].(string)
+		return fmt.Sprintf("Certificate Expiring: %s", name)
 	case "cronjob.task.failed":
 		taskName, _ := e.Payload["task_name"].(string)
 		return fmt.Sprintf("Cron Job Failed: %s", taskName)
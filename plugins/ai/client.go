@@ -34,6 +34,17 @@ type LLMClient struct {
 	model      string
 	apiFormat  string // openai-chat | anthropic-messages | google-generativeai
 	httpClient *http.Client
+
+	// onUsage, when set, receives the token usage ChatStream's provider
+	// reports for each request.
+	onUsage func(TokenUsage)
+}
+
+// reportUsage passes u to onUsage, if set.
+func (c *LLMClient) reportUsage(u TokenUsage) {
+	if c.onUsage != nil {
+		c.onUsage(u)
+	}
 }
 
 // openAIChatURL returns the OpenAI-compatible chat completions endpoint,
@@ -152,11 +163,12 @@ func (c *LLMClient) buildRequest(ctx context.Context, messages []chatMessage, st
 // ── OpenAI Chat Completions ──
 
 type openAIChatRequest struct {
-	Model       string        `json:"model"`
-	Messages    []chatMessage `json:"messages"`
-	Stream      bool          `json:"stream"`
-	Temperature float64       `json:"temperature,omitempty"`
-	MaxTokens   int           `json:"max_tokens,omitempty"`
+	Model         string               `json:"model"`
+	Messages      []chatMessage        `json:"messages"`
+	Stream        bool                 `json:"stream"`
+	Temperature   float64              `json:"temperature,omitempty"`
+	MaxTokens     int                  `json:"max_tokens,omitempty"`
+	StreamOptions *openAIStreamOptions `json:"stream_options,omitempty"`
 }
 
 type openAIStreamChunk struct {
@@ -166,6 +178,7 @@ type openAIStreamChunk struct {
 		} `json:"delta"`
 		FinishReason *string `json:"finish_reason"`
 	} `json:"choices"`
+	Usage *openAIUsage `json:"usage"`
 }
 
 func (c *LLMClient) buildOpenAIRequest(ctx context.Context, messages []chatMessage, stream bool) (*http.Request, error) {
@@ -175,7 +188,9 @@ func (c *LLMClient) buildOpenAIRequest(ctx context.Context, messages []chatMessa
 		Stream:      stream,
 		Temperature: 0.7,
 	}
-	if !stream {
+	if stream {
+		body.StreamOptions = &openAIStreamOptions{IncludeUsage: true}
+	} else {
 		body.MaxTokens = 5
 	}
 
@@ -216,6 +231,11 @@ type anthropicStreamEvent struct {
 		Type string `json:"type"`
 		Text string `json:"text"`
 	} `json:"delta,omitempty"`
+	// Usage arrives on message_start (input) and message_delta (output).
+	Message struct {
+		Usage anthropicUsage `json:"usage"`
+	} `json:"message,omitempty"`
+	Usage *anthropicUsage `json:"usage,omitempty"`
 }
 
 func (c *LLMClient) buildAnthropicRequest(ctx context.Context, messages []chatMessage, stream bool) (*http.Request, error) {
@@ -293,6 +313,11 @@ type googleStreamChunk struct {
 			} `json:"parts"`
 		} `json:"content"`
 	} `json:"candidates"`
+	// UsageMetadata holds the running totals; the last chunk's are final.
+	UsageMetadata *struct {
+		PromptTokenCount     int `json:"promptTokenCount"`
+		CandidatesTokenCount int `json:"candidatesTokenCount"`
+	} `json:"usageMetadata"`
 }
 
 func (c *LLMClient) buildGoogleRequest(ctx context.Context, messages []chatMessage, stream bool) (*http.Request, error) {
@@ -351,6 +376,14 @@ func (c *LLMClient) parseSSEStream(r io.Reader, cb StreamCallback) error {
 }
 
 func (c *LLMClient) parseOpenAIStream(r io.Reader, cb StreamCallback) error {
+	// With include_usage the usage arrives in a chunk of its own after the
+	// finish_reason chunk, so read up to [DONE].
+	var usage *openAIUsage
+	defer func() {
+		if usage != nil {
+			c.reportUsage(TokenUsage{PromptTokens: usage.PromptTokens, CompletionTokens: usage.CompletionTokens})
+		}
+	}()
 	scanner := bufio.NewScanner(r)
 	for scanner.Scan() {
 		line := scanner.Text()
@@ -366,6 +399,9 @@ func (c *LLMClient) parseOpenAIStream(r io.Reader, cb StreamCallback) error {
 		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
 			continue
 		}
+		if chunk.Usage != nil {
+			usage = chunk.Usage
+		}
 
 		for _, choice := range chunk.Choices {
 			if choice.Delta.Content != "" {
@@ -379,6 +415,13 @@ func (c *LLMClient) parseOpenAIStream(r io.Reader, cb StreamCallback) error {
 }
 
 func (c *LLMClient) parseAnthropicStream(r io.Reader, cb StreamCallback) error {
+	var usage TokenUsage
+	haveUsage := false
+	defer func() {
+		if haveUsage {
+			c.reportUsage(usage)
+		}
+	}()
 	scanner := bufio.NewScanner(r)
 	for scanner.Scan() {
 		line := scanner.Text()
@@ -393,6 +436,15 @@ func (c *LLMClient) parseAnthropicStream(r io.Reader, cb StreamCallback) error {
 		}
 
 		switch event.Type {
+		case "message_start":
+			usage.PromptTokens = event.Message.Usage.InputTokens
+			usage.CompletionTokens = event.Message.Usage.OutputTokens
+			haveUsage = true
+		case "message_delta":
+			if event.Usage != nil {
+				usage.CompletionTokens = event.Usage.OutputTokens
+				haveUsage = true
+			}
 		case "content_block_delta":
 			if event.Delta.Text != "" {
 				if err := cb(event.Delta.Text); err != nil {
@@ -409,6 +461,12 @@ func (c *LLMClient) parseAnthropicStream(r io.Reader, cb StreamCallback) error {
 }
 
 func (c *LLMClient) parseGoogleStream(r io.Reader, cb StreamCallback) error {
+	var usage *TokenUsage
+	defer func() {
+		if usage != nil {
+			c.reportUsage(*usage)
+		}
+	}()
 	scanner := bufio.NewScanner(r)
 	for scanner.Scan() {
 		line := scanner.Text()
@@ -421,6 +479,9 @@ func (c *LLMClient) parseGoogleStream(r io.Reader, cb StreamCallback) error {
 		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
 			continue
 		}
+		if m := chunk.UsageMetadata; m != nil {
+			usage = &TokenUsage{PromptTokens: m.PromptTokenCount, CompletionTokens: m.CandidatesTokenCount}
+		}
 
 		for _, candidate := range chunk.Candidates {
 			for _, part := range candidate.Content.Parts {
@@ -13,9 +13,10 @@ import (
 
 // StreamEvent represents an event from the LLM stream during tool use.
 type StreamEvent struct {
-	Type     string    // "delta", "tool_call", "tool_result", "confirm_required", "done"
-	Content  string    // text content for delta events
-	ToolCall *ToolCall // for tool_call events (complete, after accumulation)
+	Type     string      // "delta", "tool_call", "tool_result", "confirm_required", "usage", "done"
+	Content  string      // text content for delta events
+	ToolCall *ToolCall   // for tool_call events (complete, after accumulation)
+	Usage    *TokenUsage // for usage events (only when the provider reports it)
 }
 
 // PendingConfirmation holds info about a tool call awaiting user approval.
@@ -63,6 +64,19 @@ type openAIToolRequest struct {
 	Tools       []map[string]interface{} `json:"tools,omitempty"`
 	Stream      bool                     `json:"stream"`
 	Temperature float64                  `json:"temperature,omitempty"`
+	// StreamOptions asks for a final usage chunk; providers that don't
+	// support it ignore the field.
+	StreamOptions *openAIStreamOptions `json:"stream_options,omitempty"`
+}
+
+type openAIStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+// openAIUsage is the token usage block of a chat completion.
+type openAIUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
 }
 
 // openAIToolChunk extends the basic chunk to include tool_calls in delta.
@@ -74,6 +88,7 @@ type openAIToolChunk struct {
 		} `json:"delta"`
 		FinishReason *string `json:"finish_reason"`
 	} `json:"choices"`
+	Usage *openAIUsage `json:"usage"`
 }
 
 type openAIToolCallDelta struct {
@@ -98,11 +113,12 @@ func (c *LLMClient) chatToolsOpenAI(ctx context.Context, messages []ToolUseMessa
 	}
 
 	body := openAIToolRequest{
-		Model:       c.model,
-		Messages:    apiMsgs,
-		Tools:       tools,
-		Stream:      true,
-		Temperature: 0.7,
+		Model:         c.model,
+		Messages:      apiMsgs,
+		Tools:         tools,
+		Stream:        true,
+		Temperature:   0.7,
+		StreamOptions: &openAIStreamOptions{IncludeUsage: true},
 	}
 
 	payload, err := json.Marshal(body)
@@ -183,6 +199,11 @@ func (c *LLMClient) parseOpenAIToolStream(r io.Reader, cb StreamEventCallback) e
 
 	// Accumulate tool calls (index → ToolCall)
 	toolCalls := make(map[int]*ToolCall)
+	var usage *openAIUsage
+
+	// Keep reading after finish_reason: with include_usage the usage chunk
+	// (empty choices) arrives after it, just before [DONE].
+	finishReason := ""
 
 	for scanner.Scan() {
 		line := scanner.Text()
@@ -198,6 +219,12 @@ func (c *LLMClient) parseOpenAIToolStream(r io.Reader, cb StreamEventCallback) e
 		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
 			continue
 		}
+		if chunk.Usage != nil {
+			usage = chunk.Usage
+		}
+		if finishReason != "" {
+			continue
+		}
 
 		for _, choice := range chunk.Choices {
 			// Text content delta
@@ -223,27 +250,20 @@ func (c *LLMClient) parseOpenAIToolStream(r io.Reader, cb StreamEventCallback) e
 				tc.Arguments += tcd.Function.Arguments
 			}
 
-			// When finish_reason is "tool_calls", emit accumulated tool calls
-			if choice.FinishReason != nil && *choice.FinishReason == "tool_calls" {
-				for i := 0; i < len(toolCalls); i++ {
-					if tc, ok := toolCalls[i]; ok {
-						if err := cb(StreamEvent{Type: "tool_call", ToolCall: tc}); err != nil {
-							return err
-						}
-					}
-				}
-				return cb(StreamEvent{Type: "done"})
-			}
-
-			// Normal stop
-			if choice.FinishReason != nil && *choice.FinishReason == "stop" {
-				return cb(StreamEvent{Type: "done"})
+			// "tool_calls" or "stop" ends the turn.
+			if choice.FinishReason != nil && (*choice.FinishReason == "tool_calls" || *choice.FinishReason == "stop") {
+				finishReason = *choice.FinishReason
 			}
 		}
 	}
 
-	// If we get here without a finish event, emit done anyway
-	if len(toolCalls) > 0 {
+	if err := scanner.Err(); err != nil && finishReason == "" {
+		return err
+	}
+
+	// Emit accumulated tool calls in index order, also when the stream ended
+	// without a finish event.
+	if finishReason != "stop" {
 		for i := 0; i < len(toolCalls); i++ {
 			if tc, ok := toolCalls[i]; ok {
 				if err := cb(StreamEvent{Type: "tool_call", ToolCall: tc}); err != nil {
@@ -252,9 +272,13 @@ func (c *LLMClient) parseOpenAIToolStream(r io.Reader, cb StreamEventCallback) e
 			}
 		}
 	}
-
-	if err := scanner.Err(); err != nil {
-		return err
+	if usage != nil {
+		if err := cb(StreamEvent{Type: "usage", Usage: &TokenUsage{
+			PromptTokens:     usage.PromptTokens,
+			CompletionTokens: usage.CompletionTokens,
+		}}); err != nil {
+			return err
+		}
 	}
 	return cb(StreamEvent{Type: "done"})
 }
@@ -287,6 +311,16 @@ type anthropicToolStreamEvent struct {
 		Text        string `json:"text,omitempty"`
 		PartialJSON string `json:"partial_json,omitempty"`
 	} `json:"delta,omitempty"`
+	// Usage arrives on message_start (input) and message_delta (output).
+	Message *struct {
+		Usage anthropicUsage `json:"usage"`
+	} `json:"message,omitempty"`
+	Usage *anthropicUsage `json:"usage,omitempty"`
+}
+
+type anthropicUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
 }
 
 func (c *LLMClient) chatToolsAnthropic(ctx context.Context, messages []ToolUseMessage, tools []map[string]interface{}, cb StreamEventCallback) error {
@@ -415,6 +449,8 @@ func (c *LLMClient) parseAnthropicToolStream(r io.Reader, cb StreamEventCallback
 
 	blocks := make(map[int]*blockState)
 	eventsReceived := 0
+	var usage TokenUsage
+	haveUsage := false
 	var rawLines strings.Builder
 
 	for scanner.Scan() {
@@ -437,6 +473,20 @@ func (c *LLMClient) parseAnthropicToolStream(r io.Reader, cb StreamEventCallback
 		eventsReceived++
 
 		switch event.Type {
+		case "message_start":
+			if event.Message != nil {
+				usage.PromptTokens = event.Message.Usage.InputTokens
+				usage.CompletionTokens = event.Message.Usage.OutputTokens
+				haveUsage = true
+			}
+
+		case "message_delta":
+			if event.Usage != nil {
+				// output_tokens here is cumulative for the message.
+				usage.CompletionTokens = event.Usage.OutputTokens
+				haveUsage = true
+			}
+
 		case "content_block_start":
 			if event.ContentBlock != nil {
 				blocks[event.Index] = &blockState{
@@ -481,6 +531,11 @@ func (c *LLMClient) parseAnthropicToolStream(r io.Reader, cb StreamEventCallback
 			delete(blocks, event.Index)
 
 		case "message_stop":
+			if haveUsage {
+				if err := cb(StreamEvent{Type: "usage", Usage: &usage}); err != nil {
+					return err
+				}
+			}
 			return cb(StreamEvent{Type: "done"})
 
 		case "error":
@@ -0,0 +1,118 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Import limits keep a single upload from flooding the messages table.
+const (
+	maxImportMessages     = 1000
+	maxImportTitleRunes   = 200
+	maxImportMessageBytes = 256 * 1024
+)
+
+// ConversationExport is the portable JSON format for a conversation. It holds
+// only the title and the ordered role/content pairs — no IDs, owners,
+// timestamps or provider details.
+type ConversationExport struct {
+	Version    string            `json:"version"`
+	ExportedAt string            `json:"exported_at"`
+	Title      string            `json:"title"`
+	Messages   []ExportedMessage `json:"messages"`
+}
+
+// ExportedMessage is one message in a ConversationExport.
+type ExportedMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// validImportRoles are the message roles a conversation can hold.
+var validImportRoles = map[string]bool{"user": true, "assistant": true, "system": true}
+
+// ExportConversation serializes a conversation owned by userID.
+func (s *Service) ExportConversation(id, userID uint) ([]byte, error) {
+	conv, err := s.GetConversation(id, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error.ai_conversation_not_found")
+	}
+
+	export := ConversationExport{
+		Version:    "1.0",
+		ExportedAt: time.Now().Format(time.RFC3339),
+		Title:      conv.Title,
+		Messages:   make([]ExportedMessage, 0, len(conv.Messages)),
+	}
+	for _, m := range conv.Messages {
+		export.Messages = append(export.Messages, ExportedMessage{Role: m.Role, Content: m.Content})
+	}
+
+	data, err := json.MarshalIndent(export, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize conversation: %w", err)
+	}
+	return data, nil
+}
+
+// ImportConversation validates an exported conversation and recreates it for
+// userID, preserving message order.
+func (s *Service) ImportConversation(jsonData []byte, userID uint) (*Conversation, error) {
+	var export ConversationExport
+	if err := json.Unmarshal(jsonData, &export); err != nil {
+		return nil, fmt.Errorf("error.ai_invalid_conversation_json")
+	}
+	if err := validateConversationExport(&export); err != nil {
+		return nil, err
+	}
+
+	conv := Conversation{UserID: userID, Title: export.Title}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&conv).Error; err != nil {
+			return err
+		}
+		// Messages are read back ordered by created_at; space them out so
+		// the original order survives.
+		base := time.Now()
+		msgs := make([]Message, len(export.Messages))
+		for i, m := range export.Messages {
+			msgs[i] = Message{
+				ConversationID: conv.ID,
+				Role:           m.Role,
+				Content:        m.Content,
+				CreatedAt:      base.Add(time.Duration(i) * time.Millisecond),
+			}
+		}
+		return tx.CreateInBatches(msgs, 100).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to import conversation: %w", err)
+	}
+	return s.GetConversation(conv.ID, userID)
+}
+
+// validateConversationExport checks the structure of an import document and
+// normalizes its title.
+func validateConversationExport(export *ConversationExport) error {
+	if len(export.Messages) == 0 || len(export.Messages) > maxImportMessages {
+		return fmt.Errorf("error.ai_invalid_conversation_json")
+	}
+	for _, m := range export.Messages {
+		if !validImportRoles[m.Role] || strings.TrimSpace(m.Content) == "" || len(m.Content) > maxImportMessageBytes {
+			return fmt.Errorf("error.ai_invalid_conversation_json")
+		}
+	}
+
+	export.Title = strings.TrimSpace(export.Title)
+	if export.Title == "" {
+		export.Title = "Imported conversation"
+	}
+	if runes := []rune(export.Title); len(runes) > maxImportTitleRunes {
+		export.Title = string(runes[:maxImportTitleRunes])
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package ai
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestConversationExportImport_RoundTrip(t *testing.T) {
+	src := newProfileTestService(t)
+	conv := Conversation{UserID: 1, Title: "Fix 502 on shop"}
+	src.db.Create(&conv)
+	now := time.Now()
+	src.db.Create(&Message{ConversationID: conv.ID, Role: "user", Content: "Why does shop.example.com return 502?", CreatedAt: now})
+	src.db.Create(&Message{ConversationID: conv.ID, Role: "assistant", Content: "The upstream on port 3000 is down.", CreatedAt: now.Add(time.Second)})
+
+	data, err := src.ExportConversation(conv.ID, 1)
+	if err != nil {
+		t.Fatalf("export: %v", err)
+	}
+	for _, leaked := range []string{`"id"`, `"conversation_id"`, `"user_id"`, `"created_at"`} {
+		if strings.Contains(string(data), leaked) {
+			t.Errorf("export should not contain %s:\n%s", leaked, data)
+		}
+	}
+	if _, err := src.ExportConversation(conv.ID, 2); err == nil {
+		t.Error("other users must not export the conversation")
+	}
+
+	dst := newProfileTestService(t)
+	imported, err := dst.ImportConversation(data, 5)
+	if err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	if imported.UserID != 5 || imported.Title != "Fix 502 on shop" {
+		t.Errorf("imported conversation = %+v", imported)
+	}
+	if len(imported.Messages) != 2 {
+		t.Fatalf("expected 2 messages, got %d", len(imported.Messages))
+	}
+	if m := imported.Messages[0]; m.Role != "user" || m.Content != "Why does shop.example.com return 502?" {
+		t.Errorf("first message = %+v", m)
+	}
+	if m := imported.Messages[1]; m.Role != "assistant" || m.Content != "The upstream on port 3000 is down." {
+		t.Errorf("second message = %+v", m)
+	}
+}
+
+func TestConversationImport_RejectsMalformed(t *testing.T) {
+	svc := newProfileTestService(t)
+	tests := map[string]string{
+		"not json":      `{"title":`,
+		"no messages":   `{"title":"x","messages":[]}`,
+		"bad role":      `{"title":"x","messages":[{"role":"tool","content":"hi"}]}`,
+		"empty content": `{"title":"x","messages":[{"role":"user","content":"  "}]}`,
+	}
+	for name, body := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := svc.ImportConversation([]byte(body), 1); err == nil || err.Error() != "error.ai_invalid_conversation_json" {
+				t.Errorf("err = %v, want error.ai_invalid_conversation_json", err)
+			}
+		})
+	}
+
+	var count int64
+	svc.db.Model(&Conversation{}).Count(&count)
+	if count != 0 {
+		t.Errorf("rejected imports should not create conversations, got %d", count)
+	}
+
+	// The handler surfaces the error key.
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/conversations/import", strings.NewReader(tests["bad role"]))
+	c.Set("user_id", uint(1))
+	NewHandler(svc).ImportConversation(c)
+	var resp map[string]string
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if w.Code != http.StatusBadRequest || resp["error_key"] != "error.ai_invalid_conversation_json" {
+		t.Errorf("handler = %d %v", w.Code, resp)
+	}
+}
@@ -2,7 +2,9 @@ package ai
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"strconv"
 	"strings"
@@ -78,9 +80,20 @@ func writeSSEEvent(w gin.ResponseWriter, event, payload string) {
 	fmt.Fprint(w, "\n")
 }
 
+// profileIDQuery reads the optional ?profile_id= query parameter (0 = global config).
+func profileIDQuery(c *gin.Context) uint {
+	id, _ := strconv.ParseUint(c.Query("profile_id"), 10, 64)
+	return uint(id)
+}
+
 // GetConfig returns the AI configuration (API key masked).
 func (h *Handler) GetConfig(c *gin.Context) {
-	c.JSON(http.StatusOK, h.svc.GetConfig())
+	cfg, err := h.svc.GetConfig(profileIDQuery(c))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, cfg)
 }
 
 // GetPresets returns available AI provider presets.
@@ -95,7 +108,7 @@ func (h *Handler) UpdateConfig(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	if err := h.svc.UpdateConfig(cfg); err != nil {
+	if err := h.svc.UpdateConfig(profileIDQuery(c), cfg); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -104,10 +117,77 @@ func (h *Handler) UpdateConfig(c *gin.Context) {
 
 // TestConnection tests the AI API connectivity.
 func (h *Handler) TestConnection(c *gin.Context) {
-	if err := h.svc.TestConnection(c.Request.Context()); err != nil {
+	if err := h.svc.TestConnection(c.Request.Context(), profileIDQuery(c)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// ListProfiles returns the provider profiles (API keys masked).
+func (h *Handler) ListProfiles(c *gin.Context) {
+	profiles, err := h.svc.ListProfiles()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"profiles": profiles})
+}
+
+// CreateProfile adds a provider profile.
+func (h *Handler) CreateProfile(c *gin.Context) {
+	var p AIProfile
+	if err := c.ShouldBindJSON(&p); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	created, err := h.svc.CreateProfile(p)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, created)
+}
+
+// UpdateProfile edits a provider profile. A masked api_key is ignored;
+// is_default=true makes it the default.
+func (h *Handler) UpdateProfile(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var p AIProfile
+	if err := c.ShouldBindJSON(&p); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	updated, err := h.svc.UpdateProfile(uint(id), p)
+	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if p.IsDefault {
+		if err := h.svc.SetDefaultProfile(uint(id)); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		updated.IsDefault = true
+	}
+	c.JSON(http.StatusOK, updated)
+}
+
+// DeleteProfile removes a provider profile.
+func (h *Handler) DeleteProfile(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	if err := h.svc.DeleteProfile(uint(id)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{"status": "ok"})
 }
 
@@ -138,6 +218,10 @@ func (h *Handler) Chat(c *gin.Context) {
 		return
 	}
 
+	if h.rejectOverQuota(c) {
+		return
+	}
+
 	// Set SSE headers.
 	c.Header("Content-Type", "text/event-stream")
 	c.Header("Cache-Control", "no-cache")
@@ -183,6 +267,26 @@ func (h *Handler) Chat(c *gin.Context) {
 	c.Writer.Flush()
 }
 
+// GetUsage returns token usage totals and the daily cap.
+func (h *Handler) GetUsage(c *gin.Context) {
+	summary, err := h.svc.GetUsage(getUserID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, summary)
+}
+
+// rejectOverQuota writes a 429 and returns true once the daily token cap is
+// used up. Call it before switching a response to SSE.
+func (h *Handler) rejectOverQuota(c *gin.Context) bool {
+	if err := h.svc.checkQuota(); errors.Is(err, ErrQuotaExceeded) {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error(), "error_key": "error.ai_quota_exceeded"})
+		return true
+	}
+	return false
+}
+
 // ListConversations returns conversations for the current user.
 func (h *Handler) ListConversations(c *gin.Context) {
 	convs, err := h.svc.ListConversations(getUserID(c))
@@ -222,6 +326,52 @@ func (h *Handler) DeleteConversation(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"status": "ok"})
 }
 
+// ExportConversation returns a conversation as a JSON file download.
+func (h *Handler) ExportConversation(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id", "error_key": "error.invalid_id"})
+		return
+	}
+	data, err := h.svc.ExportConversation(uint(id), getUserID(c))
+	if err != nil {
+		if err.Error() == "error.ai_conversation_not_found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Conversation not found", "error_key": "error.ai_conversation_not_found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=conversation_%d.json", id))
+	c.Data(http.StatusOK, "application/json", data)
+}
+
+// ImportConversation recreates a conversation from an exported JSON document,
+// sent either as a "file" upload or as the raw request body.
+func (h *Handler) ImportConversation(c *gin.Context) {
+	var reader io.Reader = c.Request.Body
+	if file, _, err := c.Request.FormFile("file"); err == nil {
+		defer file.Close()
+		reader = file
+	}
+	data, err := io.ReadAll(io.LimitReader(reader, 4*1024*1024)) // 4MB cap
+	if err != nil || len(data) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No file uploaded", "error_key": "error.invalid_request"})
+		return
+	}
+
+	conv, err := h.svc.ImportConversation(data, getUserID(c))
+	if err != nil {
+		if err.Error() == "error.ai_invalid_conversation_json" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid conversation file", "error_key": "error.ai_invalid_conversation_json"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "error_key": "error.ai_conversation_import_failed"})
+		return
+	}
+	c.JSON(http.StatusCreated, conv)
+}
+
 // GenerateCompose converts natural language to Docker Compose YAML (SSE).
 func (h *Handler) GenerateCompose(c *gin.Context) {
 	var req GenerateComposeRequest
@@ -234,6 +384,10 @@ func (h *Handler) GenerateCompose(c *gin.Context) {
 		return
 	}
 
+	if h.rejectOverQuota(c) {
+		return
+	}
+
 	c.Header("Content-Type", "text/event-stream")
 	c.Header("Cache-Control", "no-cache")
 	c.Header("Connection", "keep-alive")
@@ -253,6 +407,49 @@ func (h *Handler) GenerateCompose(c *gin.Context) {
 	c.Writer.Flush()
 }
 
+// GenerateHost converts natural language to a host config (SSE). Model
+// output streams as data events; the validated config is sent as a final
+// "host" event for the user to review before creating it.
+func (h *Handler) GenerateHost(c *gin.Context) {
+	var req struct {
+		Description string `json:"description"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Description == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "description is required"})
+		return
+	}
+
+	if h.rejectOverQuota(c) {
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Writer.Flush()
+
+	host, err := h.svc.GenerateCaddyfile(c.Request.Context(), req.Description, func(delta string) error {
+		if err := writeSSEData(c.Writer, delta); err != nil {
+			return err
+		}
+		c.Writer.Flush()
+		return nil
+	})
+	if err != nil {
+		writeSSEEvent(c.Writer, "error", err.Error())
+	} else {
+		data, _ := json.Marshal(host)
+		writeSSEEvent(c.Writer, "host", string(data))
+	}
+	writeSSEEvent(c.Writer, "done", "")
+	c.Writer.Flush()
+}
+
 // GenerateDockerfile converts natural language to an optimized Dockerfile (SSE).
 func (h *Handler) GenerateDockerfile(c *gin.Context) {
 	var req struct {
@@ -267,6 +464,10 @@ func (h *Handler) GenerateDockerfile(c *gin.Context) {
 		return
 	}
 
+	if h.rejectOverQuota(c) {
+		return
+	}
+
 	c.Header("Content-Type", "text/event-stream")
 	c.Header("Cache-Control", "no-cache")
 	c.Header("Connection", "keep-alive")
@@ -296,6 +497,10 @@ func (h *Handler) ReviewCode(c *gin.Context) {
 		return
 	}
 
+	if h.rejectOverQuota(c) {
+		return
+	}
+
 	c.Header("Content-Type", "text/event-stream")
 	c.Header("Cache-Control", "no-cache")
 	c.Header("Connection", "keep-alive")
@@ -394,6 +599,10 @@ func (h *Handler) Diagnose(c *gin.Context) {
 		return
 	}
 
+	if h.rejectOverQuota(c) {
+		return
+	}
+
 	c.Header("Content-Type", "text/event-stream")
 	c.Header("Cache-Control", "no-cache")
 	c.Header("Connection", "keep-alive")
@@ -413,6 +622,42 @@ func (h *Handler) Diagnose(c *gin.Context) {
 	c.Writer.Flush()
 }
 
+// DiagnoseHost streams an AI diagnosis for a host built from its Caddy
+// config, logs and upstream health (SSE).
+func (h *Handler) DiagnoseHost(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	if _, err := h.svc.coreAPI.GetHost(uint(id)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "host not found"})
+		return
+	}
+
+	if h.rejectOverQuota(c) {
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Writer.Flush()
+
+	if err := h.svc.DiagnoseHost(c.Request.Context(), uint(id), func(delta string) error {
+		if err := writeSSEData(c.Writer, delta); err != nil {
+			return err
+		}
+		c.Writer.Flush()
+		return nil
+	}); err != nil {
+		writeSSEEvent(c.Writer, "error", err.Error())
+	}
+	writeSSEEvent(c.Writer, "done", "")
+	c.Writer.Flush()
+}
+
 // RunInspection triggers a manual system inspection.
 func (h *Handler) RunInspection(c *gin.Context) {
 	inspection := h.svc.tools.inspection
@@ -0,0 +1,186 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/web-casa/webcasa/internal/caddy"
+	"github.com/web-casa/webcasa/internal/model"
+)
+
+// systemPromptHostGen asks the LLM for a single host config matching
+// model.HostCreateRequest. Keep the field list in sync with that struct.
+const systemPromptHostGen = `You are a Caddy reverse proxy expert for the Web.Casa panel. Convert the user's description into ONE host configuration as a JSON object.
+Rules:
+- Output ONLY the JSON object, no explanations or markdown fences
+- Use only the fields listed below; omit fields you do not need
+
+Host types (host_type):
+- "proxy": reverse proxy to one or more backends. Requires "upstreams".
+- "redirect": redirect every request to another URL. Requires "redirect_url".
+- "static": serve files from a directory. Requires "root_path".
+- "php": serve a PHP site via PHP-FPM. Requires "root_path"; set "php_fastcgi" (e.g. "localhost:9000" or "unix//run/php-fpm/www.sock").
+
+Fields:
+- domain (string, required): e.g. "app.example.com" or "*.example.com"
+- host_type (string): one of proxy, redirect, static, php (default proxy)
+- upstreams (array of {"address": "host:port", "weight": 1}): proxy backends
+- redirect_url (string), redirect_code (301, 302, 307 or 308)
+- root_path (string), index_files (space-separated, e.g. "index.html index.php"), directory_browse (bool)
+- tls_enabled (bool), http_redirect (bool), tls_mode ("auto" or "off")
+- websocket (bool), compression (bool)
+- cache_enabled (bool), cache_ttl (seconds)
+- cors_enabled (bool), cors_origins, cors_methods, cors_headers (comma-separated strings)
+- security_headers (bool), error_page_path (string)
+- custom_headers (array of {"direction": "request"|"response", "operation": "set"|"add"|"delete", "name": "...", "value": "..."})
+- access_rules (array of {"rule_type": "allow"|"deny", "ip_range": "CIDR or IP"})`
+
+// validRedirectCodes are the redirect status codes Caddy's redir accepts here.
+var validRedirectCodes = map[int]bool{301: true, 302: true, 307: true, 308: true}
+
+// GenerateCaddyfile converts a natural language description into a host
+// config. The raw model output is streamed through cb while it arrives; the
+// parsed and validated request is returned for the user to review — nothing
+// is created.
+func (s *Service) GenerateCaddyfile(ctx context.Context, description string, cb StreamCallback) (*model.HostCreateRequest, error) {
+	client, err := s.getClient()
+	if err != nil {
+		return nil, err
+	}
+	return generateHostConfig(ctx, client, description, cb)
+}
+
+// generateHostConfig runs the host-generation prompt against client.
+func generateHostConfig(ctx context.Context, client *LLMClient, description string, cb StreamCallback) (*model.HostCreateRequest, error) {
+	messages := []chatMessage{
+		{Role: "system", Content: systemPromptHostGen},
+		{Role: "user", Content: description},
+	}
+
+	var raw strings.Builder
+	if err := client.ChatStream(ctx, messages, func(delta string) error {
+		raw.WriteString(delta)
+		if cb != nil {
+			return cb(delta)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return parseHostConfig(raw.String())
+}
+
+// parseHostConfig decodes LLM output into a HostCreateRequest and validates
+// it. Markdown fences are tolerated; unknown fields are not.
+func parseHostConfig(raw string) (*model.HostCreateRequest, error) {
+	text := strings.TrimSpace(raw)
+	text = strings.TrimPrefix(text, "```json")
+	text = strings.TrimPrefix(text, "```")
+	text = strings.TrimSuffix(text, "```")
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, fmt.Errorf("AI returned an empty host config")
+	}
+
+	dec := json.NewDecoder(bytes.NewReader([]byte(text)))
+	dec.DisallowUnknownFields()
+	var req model.HostCreateRequest
+	if err := dec.Decode(&req); err != nil {
+		return nil, fmt.Errorf("AI returned invalid host config JSON: %w", err)
+	}
+	if dec.More() {
+		return nil, fmt.Errorf("AI returned invalid host config JSON: trailing data after object")
+	}
+
+	if err := validateHostConfig(&req); err != nil {
+		return nil, fmt.Errorf("AI returned an invalid host config: %w", err)
+	}
+	return &req, nil
+}
+
+// validateHostConfig applies the same per-type requirements as host
+// creation, plus restrictions on fields an LLM should never fill in.
+func validateHostConfig(req *model.HostCreateRequest) error {
+	if err := caddy.ValidateDomain(req.Domain); err != nil {
+		return err
+	}
+
+	if req.HostType == "" {
+		req.HostType = "proxy"
+	}
+	switch req.HostType {
+	case "proxy":
+		if len(req.Upstreams) == 0 {
+			return fmt.Errorf("at least one upstream is required for proxy hosts")
+		}
+	case "redirect":
+		if req.RedirectURL == "" {
+			return fmt.Errorf("redirect_url is required for redirect hosts")
+		}
+	case "static", "php":
+		if req.RootPath == "" {
+			return fmt.Errorf("root_path is required for %s hosts", req.HostType)
+		}
+	default:
+		return fmt.Errorf("invalid host_type: %s (must be 'proxy', 'redirect', 'static', or 'php')", req.HostType)
+	}
+
+	for _, u := range req.Upstreams {
+		if err := caddy.ValidateUpstream(u.Address); err != nil {
+			return err
+		}
+	}
+	if req.RedirectCode != 0 && !validRedirectCodes[req.RedirectCode] {
+		return fmt.Errorf("invalid redirect_code: %d", req.RedirectCode)
+	}
+	if req.TLSMode != "" && req.TLSMode != "auto" && req.TLSMode != "off" {
+		return fmt.Errorf("tls_mode must be 'auto' or 'off'")
+	}
+	for _, r := range req.AccessRules {
+		if r.RuleType != "allow" && r.RuleType != "deny" {
+			return fmt.Errorf("invalid access rule type: %s", r.RuleType)
+		}
+		if err := caddy.ValidateIPRange(r.IPRange); err != nil {
+			return err
+		}
+	}
+	for label, val := range map[string]string{
+		"redirect_url":    req.RedirectURL,
+		"root_path":       req.RootPath,
+		"error_page_path": req.ErrorPagePath,
+		"php_fastcgi":     req.PHPFastCGI,
+		"index_files":     req.IndexFiles,
+		"cors_origins":    req.CorsOrigins,
+		"cors_methods":    req.CorsMethods,
+		"cors_headers":    req.CorsHeaders,
+	} {
+		if err := caddy.ValidateCaddyValue(label, val); err != nil {
+			return err
+		}
+	}
+	for _, h := range req.CustomHeaders {
+		if err := caddy.ValidateCaddyValue("header name", h.Name); err != nil {
+			return err
+		}
+		if err := caddy.ValidateCaddyValue("header value", h.Value); err != nil {
+			return err
+		}
+	}
+
+	// Raw directives, credentials and panel-specific IDs must come from the
+	// user, not the model.
+	if req.CustomDirectives != "" {
+		return fmt.Errorf("custom_directives are not allowed in generated configs")
+	}
+	if len(req.BasicAuths) > 0 {
+		return fmt.Errorf("basic_auths are not allowed in generated configs")
+	}
+	if req.DnsProviderID != nil || req.GroupID != nil || len(req.TagIDs) > 0 {
+		return fmt.Errorf("dns_provider_id, group_id and tag_ids are not allowed in generated configs")
+	}
+	return nil
+}
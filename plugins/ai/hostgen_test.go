@@ -0,0 +1,197 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// newMockLLMClient returns an LLMClient whose endpoint streams reply as an
+// OpenAI chat completion, and a func returning the last request body. The
+// SSRF-safe transport is swapped for the test server's, since it refuses
+// loopback dials.
+func newMockLLMClient(t *testing.T, reply string) (*LLMClient, func() string) {
+	t.Helper()
+	var mu sync.Mutex
+	var lastBody string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		lastBody = string(body)
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		// Split the reply to exercise delta accumulation.
+		mid := len(reply) / 2
+		for _, part := range []string{reply[:mid], reply[mid:]} {
+			chunk, _ := json.Marshal(map[string]any{
+				"choices": []map[string]any{{"delta": map[string]string{"content": part}}},
+			})
+			fmt.Fprintf(w, "data: %s\n\n", chunk)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	t.Cleanup(srv.Close)
+
+	client := NewLLMClient(srv.URL, "sk-test", "test-model", "")
+	client.httpClient = srv.Client()
+	return client, func() string {
+		mu.Lock()
+		defer mu.Unlock()
+		return lastBody
+	}
+}
+
+func TestGenerateCaddyfile_Valid(t *testing.T) {
+	reply := "```json\n" + `{"domain":"app.example.com","host_type":"proxy","upstreams":[{"address":"localhost:3000"}],"websocket":true}` + "\n```"
+	client, _ := newMockLLMClient(t, reply)
+
+	var streamed strings.Builder
+	host, err := generateHostConfig(context.Background(), client, "proxy app.example.com to port 3000 with websockets", func(d string) error {
+		streamed.WriteString(d)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("generateHostConfig: %v", err)
+	}
+	if streamed.String() != reply {
+		t.Errorf("streamed output = %q, want raw reply", streamed.String())
+	}
+	if host.Domain != "app.example.com" || host.HostType != "proxy" {
+		t.Errorf("unexpected host: %+v", host)
+	}
+	if len(host.Upstreams) != 1 || host.Upstreams[0].Address != "localhost:3000" {
+		t.Errorf("unexpected upstreams: %+v", host.Upstreams)
+	}
+	if host.WebSocket == nil || !*host.WebSocket {
+		t.Error("websocket should be enabled")
+	}
+}
+
+func TestGenerateCaddyfile_InvalidJSON(t *testing.T) {
+	client, _ := newMockLLMClient(t, `Sure! Here is your config: {"domain": "app.example.com",`)
+
+	_, err := generateHostConfig(context.Background(), client, "anything", nil)
+	if err == nil {
+		t.Fatal("expected malformed JSON to be rejected")
+	}
+	if !strings.Contains(err.Error(), "invalid host config JSON") {
+		t.Errorf("error should explain the JSON problem, got: %v", err)
+	}
+}
+
+func TestParseHostConfig_SchemaViolations(t *testing.T) {
+	tests := []struct {
+		name, raw, want string
+	}{
+		{"unknown field", `{"domain":"a.example.com","upstreams":[{"address":"localhost:80"}],"listen":":80"}`, "unknown field"},
+		{"bad domain", `{"domain":"a.example.com {","upstreams":[{"address":"localhost:80"}]}`, "domain"},
+		{"proxy without upstream", `{"domain":"a.example.com","host_type":"proxy"}`, "upstream is required"},
+		{"redirect without url", `{"domain":"a.example.com","host_type":"redirect"}`, "redirect_url is required"},
+		{"static without root", `{"domain":"a.example.com","host_type":"static"}`, "root_path is required"},
+		{"unknown type", `{"domain":"a.example.com","host_type":"tcp"}`, "invalid host_type"},
+		{"bad redirect code", `{"domain":"a.example.com","host_type":"redirect","redirect_url":"https://b.example.com","redirect_code":200}`, "redirect_code"},
+		{"custom directives", `{"domain":"a.example.com","upstreams":[{"address":"localhost:80"}],"custom_directives":"respond 200"}`, "custom_directives"},
+		{"basic auth", `{"domain":"a.example.com","upstreams":[{"address":"localhost:80"}],"basic_auths":[{"username":"u","password":"p"}]}`, "basic_auths"},
+		{"empty", "  ", "empty"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := parseHostConfig(tc.raw)
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("error %q should mention %q", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseHostConfig_DefaultsToProxy(t *testing.T) {
+	host, err := parseHostConfig(`{"domain":"a.example.com","upstreams":[{"address":"localhost:8080"}]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if host.HostType != "proxy" {
+		t.Errorf("host_type = %q, want proxy", host.HostType)
+	}
+}
+
+// hostDiagStubCoreAPI serves canned host diagnostics.
+type hostDiagStubCoreAPI struct {
+	stubCoreAPI
+	diag map[string]interface{}
+}
+
+func (s *hostDiagStubCoreAPI) GetHostDiagnostics(id uint, lines int) (map[string]interface{}, error) {
+	if id != 7 {
+		return nil, fmt.Errorf("host not found")
+	}
+	return s.diag, nil
+}
+
+func TestDiagnoseHost_PromptIncludesBlockAndLogs(t *testing.T) {
+	api := &hostDiagStubCoreAPI{diag: map[string]interface{}{
+		"domain":     "shop.example.com",
+		"host_block": "shop.example.com {\n\treverse_proxy localhost:3000\n}",
+		"access_log": []string{`{"status":502,"uri":"/checkout"}`},
+		"error_log":  []string{`{"level":"error","msg":"dial tcp 127.0.0.1:3000: connect: connection refused"}`},
+		"upstreams": []map[string]interface{}{
+			{"address": "localhost:3000", "reachable": false, "latency_ms": int64(0), "error": "connection refused"},
+		},
+	}}
+	client, lastBody := newMockLLMClient(t, "The upstream on port 3000 is down.")
+
+	var out strings.Builder
+	err := diagnoseHost(context.Background(), client, api, 7, func(d string) error {
+		out.WriteString(d)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("diagnoseHost: %v", err)
+	}
+	if out.String() != "The upstream on port 3000 is down." {
+		t.Errorf("streamed diagnosis = %q", out.String())
+	}
+
+	var req struct {
+		Messages []chatMessage `json:"messages"`
+	}
+	if err := json.Unmarshal([]byte(lastBody()), &req); err != nil {
+		t.Fatalf("decode LLM request: %v", err)
+	}
+	if len(req.Messages) != 2 {
+		t.Fatalf("expected system + user messages, got %d", len(req.Messages))
+	}
+	prompt := req.Messages[1].Content
+	for _, want := range []string{
+		"shop.example.com",
+		"reverse_proxy localhost:3000",
+		`"uri":"/checkout"`,
+		"connect: connection refused",
+		"localhost:3000: UNREACHABLE",
+	} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt missing %q:\n%s", want, prompt)
+		}
+	}
+}
+
+func TestDiagnoseHost_UnknownHost(t *testing.T) {
+	api := &hostDiagStubCoreAPI{}
+	client, lastBody := newMockLLMClient(t, "unused")
+
+	if err := diagnoseHost(context.Background(), client, api, 99, func(string) error { return nil }); err == nil {
+		t.Fatal("expected error for unknown host")
+	}
+	if lastBody() != "" {
+		t.Error("LLM should not be called when diagnostics are unavailable")
+	}
+}
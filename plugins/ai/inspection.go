@@ -66,8 +66,7 @@ func (is *InspectionService) Start() {
 	is.mu.Lock()
 	defer is.mu.Unlock()
 
-	enabled := is.configStore.Get("inspection_enabled")
-	if enabled != "true" {
+	if !is.configStore.GetBool("inspection_enabled", false) {
 		is.logger.Info("inspection scheduler disabled")
 		return
 	}
@@ -129,12 +128,8 @@ func (is *InspectionService) scheduleLoop() {
 
 // getHour returns the configured inspection hour (0-23), default 8.
 func (is *InspectionService) getHour() int {
-	h := is.configStore.Get("inspection_hour")
-	if h == "" {
-		return 8
-	}
-	hour, err := strconv.Atoi(h)
-	if err != nil || hour < 0 || hour > 23 {
+	hour := is.configStore.GetInt("inspection_hour", 8)
+	if hour < 0 || hour > 23 {
 		return 8
 	}
 	return hour
@@ -179,8 +174,7 @@ func (is *InspectionService) RunInspection() (*InspectionReport, error) {
 	report.OverallScore = is.scoreFindings(report.Findings)
 
 	// 6. Generate AI summary (optional)
-	aiEnabled := is.configStore.Get("inspection_ai_summary")
-	if aiEnabled != "false" {
+	if is.configStore.GetBool("inspection_ai_summary", true) {
 		summary, err := is.generateAISummary(report)
 		if err != nil {
 			is.logger.Warn("inspection: AI summary failed", "err", err)
@@ -30,11 +30,42 @@ type Message struct {
 
 func (Message) TableName() string { return "plugin_ai_messages" }
 
+// AIProfile is a named LLM provider configuration. APIKey is stored
+// encrypted and only ever returned masked.
+type AIProfile struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Name      string    `json:"name" gorm:"size:64;uniqueIndex;not null"`
+	BaseURL   string    `json:"base_url"`
+	APIKey    string    `json:"api_key"`
+	Model     string    `json:"model"`
+	APIFormat string    `json:"api_format"` // openai-chat | anthropic-messages | google-generativeai
+	IsDefault bool      `json:"is_default" gorm:"default:false"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (AIProfile) TableName() string { return "plugin_ai_profiles" }
+
+// AIUsage aggregates provider-reported token usage per conversation per day.
+type AIUsage struct {
+	ID               uint      `json:"id" gorm:"primaryKey"`
+	Day              string    `json:"day" gorm:"size:10;not null;uniqueIndex:idx_ai_usage_day_conv"` // YYYY-MM-DD, server local time
+	ConversationID   uint      `json:"conversation_id" gorm:"not null;default:0;uniqueIndex:idx_ai_usage_day_conv"`
+	UserID           uint      `json:"user_id" gorm:"index;not null;default:0"`
+	PromptTokens     int64     `json:"prompt_tokens"`
+	CompletionTokens int64     `json:"completion_tokens"`
+	Requests         int64     `json:"requests"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+func (AIUsage) TableName() string { return "plugin_ai_usage" }
+
 // ChatRequest is the request body for the chat endpoint.
 type ChatRequest struct {
 	ConversationID uint   `json:"conversation_id"` // 0 = new conversation
 	Message        string `json:"message"`
-	Context        string `json:"context"` // optional page context
+	Context        string `json:"context"`    // optional page context
+	ProfileID      uint   `json:"profile_id"` // 0 = default profile
 }
 
 // GenerateComposeRequest for text-to-template.
@@ -62,6 +93,9 @@ func (InspectionRecord) TableName() string { return "plugin_ai_inspections" }
 
 // AIConfig holds the AI provider configuration.
 type AIConfig struct {
+	// ProfileID is set when the provider fields come from an AIProfile.
+	ProfileID      uint   `json:"profile_id,omitempty"`
+	ProfileName    string `json:"profile_name,omitempty"`
 	BaseURL        string `json:"base_url"`
 	APIKey         string `json:"api_key"` // masked in response
 	Model          string `json:"model"`
@@ -70,4 +104,7 @@ type AIConfig struct {
 	// Separate embedding API credentials (optional — falls back to main base_url/api_key if empty).
 	EmbeddingBaseURL string `json:"embedding_base_url,omitempty"`
 	EmbeddingAPIKey  string `json:"embedding_api_key,omitempty"` // masked in response
+	// DailyTokenLimit caps prompt+completion tokens per day (0 = unlimited).
+	// Nil on update leaves the saved value unchanged.
+	DailyTokenLimit *int64 `json:"daily_token_limit,omitempty"`
 }
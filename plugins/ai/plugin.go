@@ -42,7 +42,7 @@ func (p *Plugin) Metadata() pluginpkg.Metadata {
 // Init initialises the AI plugin: migrates DB, registers routes.
 func (p *Plugin) Init(ctx *pluginpkg.Context) error {
 	// Migrate models.
-	if err := ctx.DB.AutoMigrate(&Conversation{}, &Message{}, &Memory{}, &InspectionRecord{}); err != nil {
+	if err := ctx.DB.AutoMigrate(&Conversation{}, &Message{}, &Memory{}, &InspectionRecord{}, &AIUsage{}, &AIProfile{}); err != nil {
 		return fmt.Errorf("migrate: %w", err)
 	}
 
@@ -89,6 +89,13 @@ func (p *Plugin) Init(ctx *pluginpkg.Context) error {
 	a.POST("/config/test", p.handler.TestConnection)
 	a.POST("/config/test-embedding", p.handler.TestEmbeddingConnection)
 
+	// Provider profiles — operators can list them (keys masked) to pick one
+	// per chat; managing them is admin-only.
+	o.GET("/profiles", p.handler.ListProfiles)
+	a.POST("/profiles", p.handler.CreateProfile)
+	a.PUT("/profiles/:id", p.handler.UpdateProfile)
+	a.DELETE("/profiles/:id", p.handler.DeleteProfile)
+
 	// Chat (SSE) — operator+ only: drives the LLM (spends credits) and can reach
 	// mutating tools via the confirm flow, so it must not be available to viewers.
 	o.POST("/chat", p.handler.Chat)
@@ -97,6 +104,12 @@ func (p *Plugin) Init(ctx *pluginpkg.Context) error {
 	r.GET("/conversations", p.handler.ListConversations)
 	r.GET("/conversations/:id", p.handler.GetConversation)
 	r.DELETE("/conversations/:id", p.handler.DeleteConversation)
+	r.GET("/conversations/:id/export", p.handler.ExportConversation)
+	r.POST("/conversations/import", p.handler.ImportConversation)
+
+	// Token usage — totals are visible at viewer level; per-conversation
+	// breakdown is scoped to the caller.
+	r.GET("/usage", p.handler.GetUsage)
 
 	// Tool confirmations — operator+ only: confirming executes pending (possibly
 	// mutating) tool calls.
@@ -105,7 +118,9 @@ func (p *Plugin) Init(ctx *pluginpkg.Context) error {
 	// Tools (SSE) — operator+ only: each call invokes the LLM (cost-bearing).
 	o.POST("/generate-compose", p.handler.GenerateCompose)
 	o.POST("/generate-dockerfile", p.handler.GenerateDockerfile)
+	o.POST("/generate-host", p.handler.GenerateHost)
 	o.POST("/diagnose", p.handler.Diagnose)
+	o.POST("/diagnose-host/:id", p.handler.DiagnoseHost)
 
 	// Review-code reads project source files — admin only.
 	a.POST("/review-code", p.handler.ReviewCode)
@@ -163,6 +178,16 @@ func (p *Plugin) handleBuildFailureDiagnosis(db *gorm.DB, logger *slog.Logger, e
 	logger.Info("auto AI diagnosis completed", "deployment_id", uint(deploymentID), "project", projectName)
 }
 
+// Health reports whether an LLM provider is configured, either as the
+// default profile or in the global settings. It does not contact the provider.
+func (p *Plugin) Health() error {
+	if p.svc == nil {
+		return fmt.Errorf("not initialised")
+	}
+	_, err := p.svc.newClient(0)
+	return err
+}
+
 // Start is called after Init. Starts the inspection scheduler.
 func (p *Plugin) Start() error {
 	if p.inspection != nil {
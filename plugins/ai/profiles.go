@@ -0,0 +1,138 @@
+package ai
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// ListProfiles returns all provider profiles with their API keys masked.
+func (s *Service) ListProfiles() ([]AIProfile, error) {
+	var profiles []AIProfile
+	if err := s.db.Order("name ASC").Find(&profiles).Error; err != nil {
+		return nil, err
+	}
+	for i := range profiles {
+		key, _ := Decrypt(profiles[i].APIKey, s.jwtSecret)
+		profiles[i].APIKey = MaskAPIKey(key)
+	}
+	return profiles, nil
+}
+
+// CreateProfile stores a new provider profile. The API key is encrypted
+// before saving; the returned profile carries the masked key.
+func (s *Service) CreateProfile(p AIProfile) (*AIProfile, error) {
+	p.Name = strings.TrimSpace(p.Name)
+	p.BaseURL = strings.TrimRight(p.BaseURL, "/")
+	if p.Name == "" || p.BaseURL == "" || p.APIKey == "" || p.Model == "" {
+		return nil, fmt.Errorf("name, base URL, API key and model are required")
+	}
+	if p.APIFormat == "" {
+		p.APIFormat = "openai-chat"
+	}
+	plain := p.APIKey
+	enc, err := Encrypt(plain, s.jwtSecret)
+	if err != nil {
+		return nil, fmt.Errorf("encrypt api key: %w", err)
+	}
+	p.ID = 0
+	p.APIKey = enc
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if p.IsDefault {
+			if err := tx.Model(&AIProfile{}).Where("is_default = ?", true).Update("is_default", false).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Create(&p).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	p.APIKey = MaskAPIKey(plain)
+	return &p, nil
+}
+
+// UpdateProfile applies the non-empty fields of in to profile id. An API key
+// containing "****" is the masked placeholder and leaves the key unchanged.
+func (s *Service) UpdateProfile(id uint, in AIProfile) (*AIProfile, error) {
+	p, err := s.getProfile(id)
+	if err != nil {
+		return nil, err
+	}
+	if name := strings.TrimSpace(in.Name); name != "" {
+		p.Name = name
+	}
+	if in.BaseURL != "" {
+		p.BaseURL = strings.TrimRight(in.BaseURL, "/")
+	}
+	if in.Model != "" {
+		p.Model = in.Model
+	}
+	if in.APIFormat != "" {
+		p.APIFormat = in.APIFormat
+	}
+	if in.APIKey != "" && !strings.Contains(in.APIKey, "****") {
+		enc, err := Encrypt(in.APIKey, s.jwtSecret)
+		if err != nil {
+			return nil, fmt.Errorf("encrypt api key: %w", err)
+		}
+		p.APIKey = enc
+	}
+	if err := s.db.Save(p).Error; err != nil {
+		return nil, err
+	}
+	key, _ := Decrypt(p.APIKey, s.jwtSecret)
+	p.APIKey = MaskAPIKey(key)
+	return p, nil
+}
+
+// DeleteProfile removes profile id. Chats fall back to the global config
+// when the default profile is deleted.
+func (s *Service) DeleteProfile(id uint) error {
+	res := s.db.Delete(&AIProfile{}, id)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return fmt.Errorf("AI profile not found")
+	}
+	return nil
+}
+
+// SetDefaultProfile marks profile id as the one used when a request names
+// no profile.
+func (s *Service) SetDefaultProfile(id uint) error {
+	if _, err := s.getProfile(id); err != nil {
+		return err
+	}
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&AIProfile{}).Where("is_default = ?", true).Update("is_default", false).Error; err != nil {
+			return err
+		}
+		return tx.Model(&AIProfile{}).Where("id = ?", id).Update("is_default", true).Error
+	})
+}
+
+// getProfile loads profile id with its key still encrypted.
+func (s *Service) getProfile(id uint) (*AIProfile, error) {
+	var p AIProfile
+	if err := s.db.First(&p, id).Error; err != nil {
+		return nil, fmt.Errorf("AI profile not found")
+	}
+	return &p, nil
+}
+
+// profileClient builds an LLM client from profile id.
+func (s *Service) profileClient(id uint) (*LLMClient, error) {
+	p, err := s.getProfile(id)
+	if err != nil {
+		return nil, err
+	}
+	apiKey, err := Decrypt(p.APIKey, s.jwtSecret)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt api key: %w", err)
+	}
+	return NewLLMClient(p.BaseURL, apiKey, p.Model, p.APIFormat), nil
+}
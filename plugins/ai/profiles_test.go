@@ -0,0 +1,207 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	pluginpkg "github.com/web-casa/webcasa/internal/plugin"
+)
+
+// profileTestServer is a mock OpenAI endpoint that records the model named in
+// each request it receives.
+type profileTestServer struct {
+	*httptest.Server
+	mu     sync.Mutex
+	models []string
+}
+
+func newProfileTestServer(t *testing.T) *profileTestServer {
+	t.Helper()
+	ps := &profileTestServer{}
+	ps.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var req struct {
+			Model string `json:"model"`
+		}
+		json.Unmarshal(body, &req)
+		ps.mu.Lock()
+		ps.models = append(ps.models, req.Model)
+		ps.mu.Unlock()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, `data: {"choices":[{"delta":{"content":"ok"},"finish_reason":"stop"}]}`+"\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	t.Cleanup(ps.Close)
+	return ps
+}
+
+func (ps *profileTestServer) received() []string {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	return append([]string(nil), ps.models...)
+}
+
+func newProfileTestService(t *testing.T) *Service {
+	t.Helper()
+	db := setupFeatureTestDB(t)
+	if err := db.AutoMigrate(&Conversation{}, &Message{}, &Memory{}, &AIUsage{}, &AIProfile{}); err != nil {
+		t.Fatal(err)
+	}
+	cs := pluginpkg.NewConfigStore(db, "ai")
+	cs.Set("memory_enabled", "false")
+	return NewService(db, cs, &stubCoreAPI{}, featureTestLogger(), "secret")
+}
+
+func TestProfiles_SwitchPerChat(t *testing.T) {
+	srvA := newProfileTestServer(t)
+	srvB := newProfileTestServer(t)
+	svc := newProfileTestService(t)
+	// Both test servers share the same loopback transport.
+	svc.httpClient = srvA.Client()
+
+	a, err := svc.CreateProfile(AIProfile{Name: "fast", BaseURL: srvA.URL, APIKey: "sk-aaaa-1111", Model: "model-a", IsDefault: true})
+	if err != nil {
+		t.Fatalf("create profile A: %v", err)
+	}
+	b, err := svc.CreateProfile(AIProfile{Name: "smart", BaseURL: srvB.URL + "/", APIKey: "sk-bbbb-2222", Model: "model-b"})
+	if err != nil {
+		t.Fatalf("create profile B: %v", err)
+	}
+
+	chat := func(profileID uint) {
+		t.Helper()
+		_, err := svc.ChatWithTools(context.Background(), ChatRequest{Message: "hi", ProfileID: profileID}, 1, "admin", func(StreamEvent) error { return nil })
+		if err != nil {
+			t.Fatalf("chat with profile %d: %v", profileID, err)
+		}
+	}
+	chat(a.ID)
+	chat(b.ID)
+	chat(0) // default profile
+
+	if got := srvA.received(); len(got) != 2 || got[0] != "model-a" || got[1] != "model-a" {
+		t.Errorf("server A received %v, want two model-a requests", got)
+	}
+	if got := srvB.received(); len(got) != 1 || got[0] != "model-b" {
+		t.Errorf("server B received %v, want one model-b request", got)
+	}
+
+	// Switching the default reroutes requests that name no profile.
+	if err := svc.SetDefaultProfile(b.ID); err != nil {
+		t.Fatal(err)
+	}
+	chat(0)
+	if got := srvB.received(); len(got) != 2 {
+		t.Errorf("server B should receive the default-profile chat, got %v", got)
+	}
+
+	var defaults int64
+	svc.db.Model(&AIProfile{}).Where("is_default = ?", true).Count(&defaults)
+	if defaults != 1 {
+		t.Errorf("expected exactly one default profile, got %d", defaults)
+	}
+
+	if _, err := svc.ChatWithTools(context.Background(), ChatRequest{Message: "hi", ProfileID: 999}, 1, "admin", func(StreamEvent) error { return nil }); err == nil {
+		t.Error("expected error for unknown profile")
+	}
+}
+
+func TestProfiles_KeysEncryptedAndMasked(t *testing.T) {
+	svc := newProfileTestService(t)
+	const key = "sk-live-abcdef123456"
+
+	created, err := svc.CreateProfile(AIProfile{Name: "main", BaseURL: "https://api.example.com/v1", APIKey: key, Model: "m"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.APIKey == key {
+		t.Error("create response should not echo the plaintext key")
+	}
+
+	var stored AIProfile
+	svc.db.First(&stored, created.ID)
+	if stored.APIKey == key || strings.Contains(stored.APIKey, "abcdef") {
+		t.Errorf("key stored unencrypted: %q", stored.APIKey)
+	}
+	if plain, err := Decrypt(stored.APIKey, "secret"); err != nil || plain != key {
+		t.Errorf("decrypt stored key = %q, %v", plain, err)
+	}
+
+	list, err := svc.ListProfiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].APIKey != MaskAPIKey(key) {
+		t.Fatalf("listing = %+v, want masked key", list)
+	}
+
+	cfg, err := svc.GetConfig(created.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.ProfileName != "main" || cfg.APIKey != MaskAPIKey(key) || cfg.Model != "m" {
+		t.Errorf("profile config = %+v", cfg)
+	}
+
+	// Saving the masked key back must not overwrite the real one.
+	if err := svc.UpdateConfig(created.ID, AIConfig{APIKey: cfg.APIKey, Model: "m2"}); err != nil {
+		t.Fatal(err)
+	}
+	svc.db.First(&stored, created.ID)
+	if plain, _ := Decrypt(stored.APIKey, "secret"); plain != key {
+		t.Errorf("masked update replaced the key with %q", plain)
+	}
+	if stored.Model != "m2" {
+		t.Errorf("model = %q, want m2", stored.Model)
+	}
+	// Profile updates leave the global config alone.
+	if svc.configStore.Get("model") != "" {
+		t.Error("profile-scoped update should not touch the global config")
+	}
+}
+
+func TestProfiles_ConfigZeroFollowsDefaultProfile(t *testing.T) {
+	svc := newProfileTestService(t)
+	if err := svc.UpdateConfig(0, AIConfig{BaseURL: "https://global.example.com", APIKey: "sk-global-0000", Model: "global"}); err != nil {
+		t.Fatal(err)
+	}
+	if cfg, _ := svc.GetConfig(0); cfg.ProfileID != 0 || cfg.Model != "global" {
+		t.Fatalf("without a default profile, config = %+v, want the global config", cfg)
+	}
+
+	def, err := svc.CreateProfile(AIProfile{Name: "main", BaseURL: "https://api.example.com", APIKey: "sk-main-1111", Model: "main", IsDefault: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := svc.GetConfig(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.ProfileID != def.ID || cfg.Model != "main" {
+		t.Errorf("config = %+v, want the default profile", cfg)
+	}
+
+	if err := svc.UpdateConfig(0, AIConfig{APIKey: cfg.APIKey, Model: "main-2"}); err != nil {
+		t.Fatal(err)
+	}
+	var stored AIProfile
+	svc.db.First(&stored, def.ID)
+	if stored.Model != "main-2" {
+		t.Errorf("default profile model = %q, want main-2", stored.Model)
+	}
+	if svc.configStore.Get("model") != "global" {
+		t.Error("update with no profile should edit the default profile, not the global config")
+	}
+	client, err := svc.newClient(0)
+	if err != nil || client.model != "main-2" {
+		t.Errorf("newClient(0) = %+v, %v, want the edited default profile", client, err)
+	}
+}
@@ -6,7 +6,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"net/http"
 	"os"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -35,6 +37,7 @@ type Service struct {
 	jwtSecret   string // for API key encryption
 	tools       *ToolRegistry
 	memory      *MemoryService
+	httpClient  *http.Client // overrides the LLM client transport (tests)
 
 	// pendingConfirms tracks tool calls that require user confirmation.
 	// Key: pending_id, Value: pendingEntry with user ownership and approval channel.
@@ -74,45 +77,85 @@ func NewService(db *gorm.DB, configStore *pluginpkg.ConfigStore, coreAPI pluginp
 
 // ── Config ──
 
-// GetConfig returns the AI config (API key is masked).
-func (s *Service) GetConfig() AIConfig {
-	encKey := s.configStore.Get("api_key")
-	apiKey, _ := Decrypt(encKey, s.jwtSecret)
-	apiFormat := s.configStore.Get("api_format")
-	if apiFormat == "" {
-		apiFormat = "openai-chat"
-	}
+// GetConfig returns the AI config (API keys are masked). The provider
+// fields come from profileID, resolved like newClient does, so zero shows
+// the default profile when one is set; embedding settings and the token cap
+// are global.
+func (s *Service) GetConfig(profileID uint) (AIConfig, error) {
+	profileID = s.resolveProfile(profileID)
 	encEmbKey := s.configStore.Get("embedding_api_key")
 	embAPIKey, _ := Decrypt(encEmbKey, s.jwtSecret)
-	return AIConfig{
-		BaseURL:          s.configStore.Get("base_url"),
-		APIKey:           MaskAPIKey(apiKey),
-		Model:            s.configStore.Get("model"),
-		APIFormat:        apiFormat,
+	dailyLimit := s.dailyTokenLimit()
+	cfg := AIConfig{
 		EmbeddingModel:   s.configStore.Get("embedding_model"),
 		EmbeddingBaseURL: s.configStore.Get("embedding_base_url"),
 		EmbeddingAPIKey:  MaskAPIKey(embAPIKey),
+		DailyTokenLimit:  &dailyLimit,
 	}
-}
 
-// UpdateConfig saves the AI configuration. If api_key is "****" it is left unchanged.
-func (s *Service) UpdateConfig(cfg AIConfig) error {
-	if cfg.BaseURL != "" {
-		s.configStore.Set("base_url", strings.TrimRight(cfg.BaseURL, "/"))
+	if profileID > 0 {
+		p, err := s.getProfile(profileID)
+		if err != nil {
+			return AIConfig{}, err
+		}
+		apiKey, _ := Decrypt(p.APIKey, s.jwtSecret)
+		cfg.ProfileID = p.ID
+		cfg.ProfileName = p.Name
+		cfg.BaseURL = p.BaseURL
+		cfg.APIKey = MaskAPIKey(apiKey)
+		cfg.Model = p.Model
+		cfg.APIFormat = p.APIFormat
+	} else {
+		apiKey, _ := Decrypt(s.configStore.Get("api_key"), s.jwtSecret)
+		cfg.BaseURL = s.configStore.Get("base_url")
+		cfg.APIKey = MaskAPIKey(apiKey)
+		cfg.Model = s.configStore.Get("model")
+		cfg.APIFormat = s.configStore.Get("api_format")
 	}
-	if cfg.Model != "" {
-		s.configStore.Set("model", cfg.Model)
+	if cfg.APIFormat == "" {
+		cfg.APIFormat = "openai-chat"
 	}
-	// Only update the key if it's not the masked placeholder.
-	if cfg.APIKey != "" && !strings.Contains(cfg.APIKey, "****") {
-		enc, err := Encrypt(cfg.APIKey, s.jwtSecret)
-		if err != nil {
-			return fmt.Errorf("encrypt api key: %w", err)
+	return cfg, nil
+}
+
+// UpdateConfig saves the AI configuration. If api_key is "****" it is left
+// unchanged. The provider fields are saved to profileID, resolved like
+// newClient does, and to the global config only when that resolves to zero.
+func (s *Service) UpdateConfig(profileID uint, cfg AIConfig) error {
+	profileID = s.resolveProfile(profileID)
+	if profileID > 0 {
+		if _, err := s.UpdateProfile(profileID, AIProfile{
+			BaseURL:   cfg.BaseURL,
+			APIKey:    cfg.APIKey,
+			Model:     cfg.Model,
+			APIFormat: cfg.APIFormat,
+		}); err != nil {
+			return err
+		}
+	} else {
+		if cfg.BaseURL != "" {
+			s.configStore.Set("base_url", strings.TrimRight(cfg.BaseURL, "/"))
+		}
+		if cfg.Model != "" {
+			s.configStore.Set("model", cfg.Model)
+		}
+		// Only update the key if it's not the masked placeholder.
+		if cfg.APIKey != "" && !strings.Contains(cfg.APIKey, "****") {
+			enc, err := Encrypt(cfg.APIKey, s.jwtSecret)
+			if err != nil {
+				return fmt.Errorf("encrypt api key: %w", err)
+			}
+			s.configStore.Set("api_key", enc)
+		}
+		if cfg.APIFormat != "" {
+			s.configStore.Set("api_format", cfg.APIFormat)
 		}
-		s.configStore.Set("api_key", enc)
 	}
-	if cfg.APIFormat != "" {
-		s.configStore.Set("api_format", cfg.APIFormat)
+	if cfg.DailyTokenLimit != nil {
+		if *cfg.DailyTokenLimit < 0 {
+			return fmt.Errorf("daily_token_limit must not be negative")
+		}
+		s.configStore.Set("daily_token_limit", strconv.FormatInt(*cfg.DailyTokenLimit, 10))
 	}
 	// Save embedding model (empty string disables embedding).
 	s.configStore.Set("embedding_model", cfg.EmbeddingModel)
@@ -134,9 +177,10 @@ func (s *Service) UpdateConfig(cfg AIConfig) error {
 	return nil
 }
 
-// TestConnection tests the LLM connectivity.
-func (s *Service) TestConnection(ctx context.Context) error {
-	client, err := s.getClient()
+// TestConnection tests the LLM connectivity of a profile (0 = default).
+func (s *Service) TestConnection(ctx context.Context, profileID uint) error {
+	// Bypasses the daily quota: checking credentials shouldn't be blocked.
+	client, err := s.newClient(profileID)
 	if err != nil {
 		return err
 	}
@@ -237,6 +281,7 @@ func (s *Service) Chat(ctx context.Context, req ChatRequest, userID uint, cb Str
 	s.db.Where("conversation_id = ?", conv.ID).Order("created_at ASC").Find(&history)
 
 	apiMessages := s.buildMessages(userID, history, req.Context)
+	client.onUsage = s.usageRecorder(userID, conv.ID)
 
 	// Stream the response, collecting full content.
 	var fullContent strings.Builder
@@ -281,7 +326,7 @@ func (s *Service) ResolveConfirmation(pendingID string, approved bool, userID ui
 // ChatWithTools handles a user message with tool use support.
 // The callback receives StreamEvents: text deltas, tool calls, tool results, and done.
 func (s *Service) ChatWithTools(ctx context.Context, req ChatRequest, userID uint, userRole string, cb StreamEventCallback) (uint, error) {
-	client, err := s.getClient()
+	client, err := s.getClientFor(req.ProfileID)
 	if err != nil {
 		return 0, err
 	}
@@ -324,6 +369,10 @@ func (s *Service) ChatWithTools(ctx context.Context, req ChatRequest, userID uin
 	const maxRounds = 10
 	var fullContent strings.Builder
 
+	// Record provider-reported usage per round, including rounds of a turn
+	// that later fails.
+	recordRoundUsage := s.usageRecorder(userID, conv.ID)
+
 	for round := 0; round < maxRounds; round++ {
 		var pendingToolCalls []ToolCall
 		var roundText strings.Builder
@@ -336,6 +385,8 @@ func (s *Service) ChatWithTools(ctx context.Context, req ChatRequest, userID uin
 			case "tool_call":
 				pendingToolCalls = append(pendingToolCalls, *event.ToolCall)
 				return cb(event) // Forward tool_call to frontend
+			case "usage":
+				recordRoundUsage(*event.Usage)
 			case "done":
 				// Don't forward done yet — check if we need another round
 			}
@@ -487,11 +538,10 @@ func (s *Service) ChatWithTools(ctx context.Context, req ChatRequest, userID uin
 	s.db.Model(&conv).UpdateColumn("updated_at", gorm.Expr("CURRENT_TIMESTAMP"))
 
 	// Async memory extraction after conversation turn.
-	if s.configStore.Get("memory_enabled") != "false" && s.configStore.Get("auto_extract") != "false" {
-		convID := conv.ID
+	if s.configStore.GetBool("memory_enabled", true) && s.configStore.GetBool("auto_extract", true) {
 		userMessage := req.Message
 		assistantResponse := fullContent.String()
-		go s.extractMemories(userID, convID, userMessage, assistantResponse)
+		go s.extractMemories(userID, conv.ID, userMessage, assistantResponse)
 	}
 
 	return conv.ID, nil
@@ -502,7 +552,7 @@ func (s *Service) buildToolMessages(userID uint, history []Message, pageContext
 	systemPrompt := systemPromptToolUse
 
 	// Inject relevant memories from previous interactions.
-	if s.configStore.Get("memory_enabled") != "false" {
+	if s.configStore.GetBool("memory_enabled", true) {
 		var query string
 		for i := len(history) - 1; i >= 0; i-- {
 			if history[i].Role == "user" {
@@ -607,6 +657,9 @@ func (s *Service) GenerateDockerfileSync(ctx context.Context, description string
 
 // ── Diagnose ──
 
+// systemPromptDiagnose is shared by log and host diagnosis.
+const systemPromptDiagnose = "You are a senior DevOps engineer and system administrator. Diagnose errors concisely and provide actionable fixes. Use markdown formatting."
+
 // Diagnose analyzes error logs and returns diagnosis + fix suggestions.
 func (s *Service) Diagnose(ctx context.Context, req DiagnoseRequest, cb StreamCallback) error {
 	client, err := s.getClient()
@@ -621,10 +674,7 @@ func (s *Service) Diagnose(ctx context.Context, req DiagnoseRequest, cb StreamCa
 	prompt += "Logs:\n```\n" + req.Logs + "\n```"
 
 	messages := []chatMessage{
-		{
-			Role:    "system",
-			Content: "You are a senior DevOps engineer and system administrator. Diagnose errors concisely and provide actionable fixes. Use markdown formatting.",
-		},
+		{Role: "system", Content: systemPromptDiagnose},
 		{Role: "user", Content: prompt},
 	}
 
@@ -645,6 +695,67 @@ func (s *Service) DiagnoseSync(ctx context.Context, req DiagnoseRequest) (string
 	return result.String(), nil
 }
 
+// hostDiagnosisLogLines is how many access/error log lines DiagnoseHost sends.
+const hostDiagnosisLogLines = 100
+
+// DiagnoseHost pulls a host's rendered Caddy block, recent access/error logs
+// and upstream health from the core and streams a targeted diagnosis.
+func (s *Service) DiagnoseHost(ctx context.Context, hostID uint, cb StreamCallback) error {
+	client, err := s.getClient()
+	if err != nil {
+		return err
+	}
+	return diagnoseHost(ctx, client, s.coreAPI, hostID, cb)
+}
+
+func diagnoseHost(ctx context.Context, client *LLMClient, api pluginpkg.CoreAPI, hostID uint, cb StreamCallback) error {
+	diag, err := api.GetHostDiagnostics(hostID, hostDiagnosisLogLines)
+	if err != nil {
+		return err
+	}
+	messages := []chatMessage{
+		{Role: "system", Content: systemPromptDiagnose},
+		{Role: "user", Content: buildHostDiagnosisPrompt(diag)},
+	}
+	return client.ChatStream(ctx, messages, cb)
+}
+
+// buildHostDiagnosisPrompt formats GetHostDiagnostics output for the LLM.
+func buildHostDiagnosisPrompt(diag map[string]interface{}) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "The site %v served by Caddy is misbehaving. Using the config, logs and upstream checks below, provide:\n", diag["domain"])
+	b.WriteString("1. Root cause analysis\n2. Step-by-step fix instructions\n3. Prevention suggestions\n\n")
+
+	fmt.Fprintf(&b, "Caddyfile site block:\n```\n%v\n```\n\n", diag["host_block"])
+
+	b.WriteString("Upstream health:\n")
+	upstreams, _ := diag["upstreams"].([]map[string]interface{})
+	if len(upstreams) == 0 {
+		b.WriteString("- (no upstreams)\n")
+	}
+	for _, u := range upstreams {
+		if reachable, _ := u["reachable"].(bool); reachable {
+			fmt.Fprintf(&b, "- %v: reachable (%vms)\n", u["address"], u["latency_ms"])
+		} else {
+			fmt.Fprintf(&b, "- %v: UNREACHABLE: %v\n", u["address"], u["error"])
+		}
+	}
+
+	for _, section := range []struct{ title, key string }{
+		{"Recent Caddy errors for this site", "error_log"},
+		{"Recent access log", "access_log"},
+	} {
+		lines, _ := diag[section.key].([]string)
+		fmt.Fprintf(&b, "\n%s:\n", section.title)
+		if len(lines) == 0 {
+			b.WriteString("(empty)\n")
+			continue
+		}
+		b.WriteString("```\n" + strings.Join(lines, "\n") + "\n```\n")
+	}
+	return b.String()
+}
+
 // ── Code Review ──
 
 // ReviewCode streams a code review for a project.
@@ -862,7 +973,58 @@ Alerts (most recent first):
 
 // ── Internal helpers ──
 
+// getClient returns a client for the default provider, or
+// ErrQuotaExceeded once today's daily_token_limit has been used up.
 func (s *Service) getClient() (*LLMClient, error) {
+	return s.getClientFor(0)
+}
+
+// getClientFor is getClient for a specific profile (0 = default).
+func (s *Service) getClientFor(profileID uint) (*LLMClient, error) {
+	if err := s.checkQuota(); err != nil {
+		return nil, err
+	}
+	return s.newClient(profileID)
+}
+
+// newClient builds an LLM client for profileID. Zero selects the default
+// profile, falling back to the global config when none is marked default.
+func (s *Service) newClient(profileID uint) (*LLMClient, error) {
+	var (
+		client *LLMClient
+		err    error
+	)
+	if profileID = s.resolveProfile(profileID); profileID > 0 {
+		client, err = s.profileClient(profileID)
+	} else {
+		client, err = s.configClient()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if s.httpClient != nil {
+		client.httpClient = s.httpClient
+	}
+	// Streamed features count towards the daily cap like chats do.
+	client.onUsage = s.usageRecorder(0, 0)
+	return client, nil
+}
+
+// resolveProfile returns the profile a request for profileID uses: zero
+// selects the default profile, and stays zero (the global config) when none
+// is marked default.
+func (s *Service) resolveProfile(profileID uint) uint {
+	if profileID == 0 {
+		var def AIProfile
+		if s.db.Where("is_default = ?", true).First(&def).Error == nil {
+			return def.ID
+		}
+	}
+	return profileID
+}
+
+// configClient builds an LLM client from the global provider config.
+func (s *Service) configClient() (*LLMClient, error) {
 	baseURL := s.configStore.Get("base_url")
 	encKey := s.configStore.Get("api_key")
 	model := s.configStore.Get("model")
@@ -884,7 +1046,7 @@ func (s *Service) buildMessages(userID uint, history []Message, pageContext stri
 	systemPrompt := systemPromptBasic
 
 	// Inject relevant memories from previous interactions.
-	if s.configStore.Get("memory_enabled") != "false" {
+	if s.configStore.GetBool("memory_enabled", true) {
 		var query string
 		for i := len(history) - 1; i >= 0; i-- {
 			if history[i].Role == "user" {
@@ -259,7 +259,7 @@ func RegisterBuiltinTools(r *ToolRegistry) {
 				},
 				"framework": map[string]interface{}{
 					"type":        "string",
-					"description": "Framework preset: nextjs, nuxt, vite, remix, express, go, laravel, flask, django, dockerfile, custom. Leave empty for auto-detection.",
+					"description": "Framework preset: nextjs, nuxt, vite, remix, express, go, laravel, flask, django, fastapi, rust, deno, bun, dockerfile, custom. Leave empty for auto-detection.",
 				},
 				"deploy_mode": map[string]interface{}{
 					"type":        "string",
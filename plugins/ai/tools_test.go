@@ -7,6 +7,7 @@ import (
 	"os"
 	"testing"
 
+	"github.com/gin-gonic/gin"
 	pluginpkg "github.com/web-casa/webcasa/internal/plugin"
 	"gorm.io/gorm"
 )
@@ -24,6 +25,9 @@ func (s *stubCoreAPI) ListHosts() ([]map[string]interface{}, error) {
 func (s *stubCoreAPI) GetHost(id uint) (map[string]interface{}, error) {
 	return map[string]interface{}{"id": id, "domain": "example.com"}, nil
 }
+func (s *stubCoreAPI) GetHostByDomain(domain string) (*pluginpkg.HostInfo, error) {
+	return nil, nil
+}
 func (s *stubCoreAPI) UpdateHostUpstream(hostID uint, newUpstream string) error { return nil }
 func (s *stubCoreAPI) ReloadCaddy() error                                       { return nil }
 func (s *stubCoreAPI) GetSetting(key string) (string, error)                    { return "", nil }
@@ -106,6 +110,10 @@ func (s *stubCoreAPI) PHPListRuntimes() ([]map[string]interface{}, error)
 func (s *stubCoreAPI) PHPListSites() ([]map[string]interface{}, error)               { return nil, nil }
 
 // NLOps stubs
+func (s *stubCoreAPI) GetHostDiagnostics(id uint, lines int) (map[string]interface{}, error) {
+	return map[string]interface{}{}, nil
+}
+
 func (s *stubCoreAPI) ToggleHost(id uint) error                                   { return nil }
 func (s *stubCoreAPI) CloneHost(id uint, newDomain string) (uint, error)          { return 1, nil }
 func (s *stubCoreAPI) GetCaddyStatus() (map[string]interface{}, error)            { return nil, nil }
@@ -135,6 +143,7 @@ func (s *stubCoreAPI) CronJobLogs(taskID uint, limit int) ([]map[string]interfac
 func (s *stubCoreAPI) CronJobTrigger(id uint) error                    { return nil }
 func (s *stubCoreAPI) EncryptSecret(plaintext string) (string, error)  { return plaintext, nil }
 func (s *stubCoreAPI) DecryptSecret(ciphertext string) (string, error) { return ciphertext, nil }
+func (s *stubCoreAPI) WriteAuditLog(c *gin.Context, action, target, targetID, detail string) {}
 
 func newTestRegistry() *ToolRegistry {
 	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
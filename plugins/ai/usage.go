@@ -0,0 +1,144 @@
+package ai
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrQuotaExceeded is returned once today's token total reaches the
+// configured daily_token_limit.
+var ErrQuotaExceeded = errors.New("daily AI token limit reached")
+
+// TokenUsage is the token count a provider reported for one request.
+type TokenUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
+// UsageTotals sums prompt and completion tokens.
+type UsageTotals struct {
+	PromptTokens     int64 `json:"prompt_tokens"`
+	CompletionTokens int64 `json:"completion_tokens"`
+	TotalTokens      int64 `json:"total_tokens"`
+	Requests         int64 `json:"requests"`
+}
+
+// DailyUsage is the usage total for one day.
+type DailyUsage struct {
+	Day string `json:"day"`
+	UsageTotals
+}
+
+// ConversationUsage is the all-time usage total for one conversation.
+type ConversationUsage struct {
+	ConversationID uint   `json:"conversation_id"`
+	Title          string `json:"title"`
+	UsageTotals
+}
+
+// UsageSummary is returned by GET /usage.
+type UsageSummary struct {
+	Today         UsageTotals         `json:"today"`
+	DailyLimit    int64               `json:"daily_limit"` // 0 = unlimited
+	Days          []DailyUsage        `json:"days"`
+	Conversations []ConversationUsage `json:"conversations"`
+}
+
+// usageDay is the key days are bucketed by.
+func usageDay(t time.Time) string { return t.Format("2006-01-02") }
+
+// recordUsage adds one request's usage to today's row for the conversation.
+func (s *Service) recordUsage(userID, convID uint, u TokenUsage) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		row := AIUsage{Day: usageDay(time.Now()), ConversationID: convID}
+		if err := tx.Where(&row).Attrs(AIUsage{UserID: userID}).FirstOrCreate(&row).Error; err != nil {
+			return err
+		}
+		return tx.Model(&row).UpdateColumns(map[string]interface{}{
+			"prompt_tokens":     gorm.Expr("prompt_tokens + ?", u.PromptTokens),
+			"completion_tokens": gorm.Expr("completion_tokens + ?", u.CompletionTokens),
+			"requests":          gorm.Expr("requests + 1"),
+			"updated_at":        time.Now(),
+		}).Error
+	})
+}
+
+// usageRecorder returns a hook recording each request's usage against
+// userID and convID (0 for features outside a conversation).
+func (s *Service) usageRecorder(userID, convID uint) func(TokenUsage) {
+	return func(u TokenUsage) {
+		if err := s.recordUsage(userID, convID, u); err != nil {
+			s.logger.Warn("failed to record AI usage", "conversation_id", convID, "err", err)
+		}
+	}
+}
+
+// dailyTokenLimit returns the configured cap on tokens per day (0 = none).
+func (s *Service) dailyTokenLimit() int64 {
+	n := s.configStore.GetInt("daily_token_limit", 0)
+	if n < 0 {
+		return 0
+	}
+	return int64(n)
+}
+
+// checkQuota returns ErrQuotaExceeded when today's usage has reached the cap.
+func (s *Service) checkQuota() error {
+	limit := s.dailyTokenLimit()
+	if limit == 0 {
+		return nil
+	}
+	if s.usageTotals(usageDay(time.Now())).TotalTokens >= limit {
+		return ErrQuotaExceeded
+	}
+	return nil
+}
+
+// usageTotals sums all usage recorded for day.
+func (s *Service) usageTotals(day string) UsageTotals {
+	var t UsageTotals
+	s.db.Model(&AIUsage{}).Where("day = ?", day).
+		Select("COALESCE(SUM(prompt_tokens),0) AS prompt_tokens, COALESCE(SUM(completion_tokens),0) AS completion_tokens, COALESCE(SUM(requests),0) AS requests").
+		Scan(&t)
+	t.TotalTokens = t.PromptTokens + t.CompletionTokens
+	return t
+}
+
+// GetUsage returns today's total, the daily cap, the last 30 days, and the
+// user's conversations by token use.
+func (s *Service) GetUsage(userID uint) (*UsageSummary, error) {
+	summary := &UsageSummary{
+		Today:      s.usageTotals(usageDay(time.Now())),
+		DailyLimit: s.dailyTokenLimit(),
+	}
+
+	since := usageDay(time.Now().AddDate(0, 0, -29))
+	if err := s.db.Model(&AIUsage{}).Where("day >= ?", since).
+		Select("day, SUM(prompt_tokens) AS prompt_tokens, SUM(completion_tokens) AS completion_tokens, SUM(requests) AS requests").
+		Group("day").Order("day DESC").
+		Scan(&summary.Days).Error; err != nil {
+		return nil, err
+	}
+	for i := range summary.Days {
+		d := &summary.Days[i]
+		d.TotalTokens = d.PromptTokens + d.CompletionTokens
+	}
+
+	if err := s.db.Table("plugin_ai_usage AS u").
+		Joins("LEFT JOIN plugin_ai_conversations AS c ON c.id = u.conversation_id").
+		Where("u.user_id = ? AND u.conversation_id <> 0", userID).
+		Select("u.conversation_id, COALESCE(c.title, '') AS title, SUM(u.prompt_tokens) AS prompt_tokens, SUM(u.completion_tokens) AS completion_tokens, SUM(u.requests) AS requests").
+		Group("u.conversation_id, c.title").
+		Order("SUM(u.prompt_tokens + u.completion_tokens) DESC").
+		Limit(50).
+		Scan(&summary.Conversations).Error; err != nil {
+		return nil, err
+	}
+	for i := range summary.Conversations {
+		c := &summary.Conversations[i]
+		c.TotalTokens = c.PromptTokens + c.CompletionTokens
+	}
+	return summary, nil
+}
@@ -0,0 +1,202 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	pluginpkg "github.com/web-casa/webcasa/internal/plugin"
+)
+
+// newUsageTestService returns a Service whose LLM answers every chat with
+// "ok" and reports the given usage, OpenAI-style (usage chunk after the
+// finish_reason chunk).
+func newUsageTestService(t *testing.T, prompt, completion int) *Service {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, `data: {"choices":[{"delta":{"content":"ok"}}]}`+"\n\n")
+		fmt.Fprint(w, `data: {"choices":[{"delta":{},"finish_reason":"stop"}]}`+"\n\n")
+		fmt.Fprintf(w, `data: {"choices":[],"usage":{"prompt_tokens":%d,"completion_tokens":%d}}`+"\n\n", prompt, completion)
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	t.Cleanup(srv.Close)
+
+	db := setupFeatureTestDB(t)
+	if err := db.AutoMigrate(&Conversation{}, &Message{}, &Memory{}, &AIUsage{}, &AIProfile{}); err != nil {
+		t.Fatal(err)
+	}
+	cs := pluginpkg.NewConfigStore(db, "ai")
+	cs.Set("memory_enabled", "false")
+
+	enc, err := Encrypt("sk-test", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cs.Set("base_url", srv.URL)
+	cs.Set("api_key", enc)
+	cs.Set("model", "test-model")
+
+	svc := NewService(db, cs, &stubCoreAPI{}, featureTestLogger(), "secret")
+	svc.httpClient = srv.Client()
+	return svc
+}
+
+func chatOnce(t *testing.T, svc *Service, userID uint, msg string) (uint, error) {
+	t.Helper()
+	return svc.ChatWithTools(context.Background(), ChatRequest{Message: msg}, userID, "admin", func(StreamEvent) error { return nil })
+}
+
+func TestUsage_AccumulatesAcrossChats(t *testing.T) {
+	svc := newUsageTestService(t, 100, 20)
+
+	conv1, err := chatOnce(t, svc, 1, "first")
+	if err != nil {
+		t.Fatalf("chat 1: %v", err)
+	}
+	conv2, err := chatOnce(t, svc, 1, "second")
+	if err != nil {
+		t.Fatalf("chat 2: %v", err)
+	}
+	// A follow-up in the first conversation lands on the same row.
+	if _, err := svc.ChatWithTools(context.Background(), ChatRequest{ConversationID: conv1, Message: "again"}, 1, "admin", func(StreamEvent) error { return nil }); err != nil {
+		t.Fatalf("chat 3: %v", err)
+	}
+
+	summary, err := svc.GetUsage(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := UsageTotals{PromptTokens: 300, CompletionTokens: 60, TotalTokens: 360, Requests: 3}
+	if summary.Today != want {
+		t.Errorf("today = %+v, want %+v", summary.Today, want)
+	}
+	if len(summary.Days) != 1 || summary.Days[0].UsageTotals != want {
+		t.Errorf("days = %+v", summary.Days)
+	}
+
+	byConv := map[uint]UsageTotals{}
+	for _, c := range summary.Conversations {
+		byConv[c.ConversationID] = c.UsageTotals
+	}
+	if got := byConv[conv1]; got.TotalTokens != 240 || got.Requests != 2 {
+		t.Errorf("conversation %d usage = %+v, want 240 tokens over 2 requests", conv1, got)
+	}
+	if got := byConv[conv2]; got.TotalTokens != 120 || got.Requests != 1 {
+		t.Errorf("conversation %d usage = %+v, want 120 tokens over 1 request", conv2, got)
+	}
+
+	// Other users don't see these conversations.
+	other, _ := svc.GetUsage(2)
+	if len(other.Conversations) != 0 {
+		t.Errorf("user 2 should see no conversations, got %+v", other.Conversations)
+	}
+}
+
+func TestUsage_DailyCapRejects(t *testing.T) {
+	svc := newUsageTestService(t, 100, 50)
+	svc.configStore.Set("daily_token_limit", "200")
+
+	// 150 tokens: still under the cap.
+	if _, err := chatOnce(t, svc, 1, "first"); err != nil {
+		t.Fatalf("chat under cap: %v", err)
+	}
+	// Starts under the cap (150 < 200) and ends over it (300).
+	if _, err := chatOnce(t, svc, 1, "second"); err != nil {
+		t.Fatalf("chat starting under cap: %v", err)
+	}
+
+	_, err := chatOnce(t, svc, 1, "third")
+	if !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("expected ErrQuotaExceeded, got %v", err)
+	}
+	if _, err := svc.GenerateCaddyfile(context.Background(), "proxy a.example.com", nil); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("other AI features should also be blocked, got %v", err)
+	}
+	if got := svc.usageTotals(usageDay(time.Now())).Requests; got != 2 {
+		t.Errorf("rejected request should not be counted, requests = %d", got)
+	}
+
+	// Raising the cap unblocks.
+	svc.configStore.Set("daily_token_limit", "0")
+	if _, err := chatOnce(t, svc, 1, "fourth"); err != nil {
+		t.Fatalf("chat with cap removed: %v", err)
+	}
+}
+
+func TestUsage_StreamedFeaturesCountTowardsCap(t *testing.T) {
+	svc := newUsageTestService(t, 100, 50)
+	svc.configStore.Set("daily_token_limit", "100")
+	noop := func(string) error { return nil }
+
+	if err := svc.GenerateCompose(context.Background(), "a redis cache", noop); err != nil {
+		t.Fatalf("compose under cap: %v", err)
+	}
+	if got := svc.usageTotals(usageDay(time.Now())); got.TotalTokens != 150 || got.Requests != 1 {
+		t.Fatalf("streamed usage not recorded: %+v", got)
+	}
+	if err := svc.GenerateDockerfile(context.Background(), "a go service", noop); !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("expected ErrQuotaExceeded for a streamed feature, got %v", err)
+	}
+}
+
+func TestUsage_HandlerReturnsQuotaErrorKey(t *testing.T) {
+	svc := newUsageTestService(t, 10, 10)
+	svc.configStore.Set("daily_token_limit", "1")
+	if _, err := chatOnce(t, svc, 1, "use it up"); err != nil {
+		t.Fatal(err)
+	}
+
+	h := NewHandler(svc)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/chat", strings.NewReader(`{"message":"hi"}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Set("user_id", uint(1))
+	h.Chat(c)
+
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want 429", w.Code)
+	}
+	var body map[string]string
+	json.Unmarshal(w.Body.Bytes(), &body)
+	if body["error_key"] != "error.ai_quota_exceeded" {
+		t.Errorf("error_key = %q", body["error_key"])
+	}
+	if strings.Contains(w.Header().Get("Content-Type"), "event-stream") {
+		t.Error("quota rejection should be plain JSON, not SSE")
+	}
+}
+
+func TestParseAnthropicToolStream_ReportsUsage(t *testing.T) {
+	stream := strings.Join([]string{
+		`data: {"type":"message_start","message":{"usage":{"input_tokens":42,"output_tokens":1}}}`,
+		`data: {"type":"content_block_start","index":0,"content_block":{"type":"text"}}`,
+		`data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"hi"}}`,
+		`data: {"type":"content_block_stop","index":0}`,
+		`data: {"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":7}}`,
+		`data: {"type":"message_stop"}`,
+	}, "\n\n")
+
+	var got *TokenUsage
+	c := NewLLMClient("http://unused", "k", "m", "anthropic-messages")
+	err := c.parseAnthropicToolStream(strings.NewReader(stream), func(e StreamEvent) error {
+		if e.Type == "usage" {
+			got = e.Usage
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got == nil || got.PromptTokens != 42 || got.CompletionTokens != 7 {
+		t.Fatalf("usage = %+v, want 42 prompt / 7 completion", got)
+	}
+}
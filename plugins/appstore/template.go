@@ -112,7 +112,7 @@ func (ts *TemplateService) DeployFromTemplate(req *CreateFromTemplateRequest) (u
 		// Check domain uniqueness against existing hosts so we don't create a
 		// project that will fail silently when setting up the reverse proxy.
 		var domainCount int64
-		ts.db.Table("hosts").Where("domain = ?", req.Domain).Count(&domainCount)
+		ts.db.Table("hosts").Where("domain = ? AND deleted_at IS NULL", req.Domain).Count(&domainCount)
 		if domainCount > 0 {
 			return 0, fmt.Errorf("domain %q is already in use by an existing host", req.Domain)
 		}
@@ -2,6 +2,7 @@ package deploy
 
 import (
 	"errors"
+	"fmt"
 	"io"
 	"log/slog"
 	"sync"
@@ -118,6 +119,76 @@ func TestBuild_PendingTriggersRerun(t *testing.T) {
 	}
 }
 
+// TestBuild_ThreeRapidTriggers_CoalesceInOrder fires three Build() calls
+// back-to-back and verifies exactly two builds run (the in-flight one plus
+// a single coalesced follow-up), strictly one after the other, with the
+// queued state visible only while the follow-up is waiting.
+func TestBuild_ThreeRapidTriggers_CoalesceInOrder(t *testing.T) {
+	s := newTestService()
+
+	var (
+		mu     sync.Mutex
+		events []string
+		runs   atomic.Int32
+	)
+	starts := make(chan struct{}, 10)
+	release := make(chan struct{})
+	s.buildRunner = func(projectID uint) error {
+		n := runs.Add(1)
+		mu.Lock()
+		events = append(events, fmt.Sprintf("start-%d", n))
+		mu.Unlock()
+		starts <- struct{}{}
+		<-release
+		mu.Lock()
+		events = append(events, fmt.Sprintf("end-%d", n))
+		mu.Unlock()
+		return nil
+	}
+
+	if err := s.Build(1); err != nil {
+		t.Fatalf("first Build: %v", err)
+	}
+	if err := s.Build(1); !errors.Is(err, ErrBuildCoalesced) {
+		t.Fatalf("second Build: want ErrBuildCoalesced, got %v", err)
+	}
+	if err := s.Build(1); !errors.Is(err, ErrBuildCoalesced) {
+		t.Fatalf("third Build: want ErrBuildCoalesced, got %v", err)
+	}
+
+	select {
+	case <-starts:
+	case <-time.After(1 * time.Second):
+		t.Fatal("first build never started")
+	}
+	if !s.IsBuildQueued(1) {
+		t.Fatal("IsBuildQueued = false while a coalesced rebuild is waiting")
+	}
+
+	release <- struct{}{}
+	select {
+	case <-starts:
+	case <-time.After(1 * time.Second):
+		t.Fatal("coalesced rebuild never started")
+	}
+	if s.IsBuildQueued(1) {
+		t.Fatal("IsBuildQueued = true after the coalesced rebuild was picked up")
+	}
+	release <- struct{}{}
+
+	waitInflightClear(t, s, 1, 2*time.Second)
+
+	if got := runs.Load(); got != 2 {
+		t.Fatalf("expected 2 runs (current + 1 coalesced), got %d", got)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	want := []string{"start-1", "end-1", "start-2", "end-2"}
+	if fmt.Sprint(events) != fmt.Sprint(want) {
+		t.Fatalf("build order = %v, want %v", events, want)
+	}
+}
+
 // TestBuild_ParallelProjectsIndependent verifies dedup is per-project:
 // concurrent Build() for different project IDs all proceed.
 func TestBuild_ParallelProjectsIndependent(t *testing.T) {
@@ -0,0 +1,122 @@
+package deploy
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// initTestGitRepo creates a throwaway git repo with a single commit on
+// `main` and returns a file:// URL the GitClient can clone from.
+func initTestGitRepo(t *testing.T, files map[string]string) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+	for _, args := range [][]string{
+		{"init", "-q", "-b", "main"},
+		{"add", "-A"},
+		{"-c", "user.email=test@example.com", "-c", "user.name=test", "commit", "-q", "-m", "init"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	return "file://" + dir
+}
+
+// newBuildTestService returns a Service wired with a real GitClient and
+// Builder rooted in a temp dir, suitable for driving runBuildOnce
+// end-to-end against a local repo.
+func newBuildTestService(t *testing.T) *Service {
+	t.Helper()
+	db := openPollerTestDB(t)
+	dataDir := t.TempDir()
+	git := NewGitClient(filepath.Join(dataDir, "sources"))
+	return &Service{
+		db:            db,
+		git:           git,
+		builder:       NewBuilder(git, dataDir),
+		logger:        slog.New(slog.NewTextHandler(io.Discard, nil)),
+		dataDir:       dataDir,
+		activeLogs:    make(map[uint]*LogWriter),
+		buildInflight: make(map[uint]bool),
+		buildPending:  make(map[uint]bool),
+		buildSem:      make(chan struct{}, 64),
+	}
+}
+
+func TestBuildTimeout_Default(t *testing.T) {
+	s := &Service{}
+	if got := s.buildTimeout(&Project{}); got != 30*time.Minute {
+		t.Fatalf("unset BuildTimeout = %v, want 30m", got)
+	}
+	if got := s.buildTimeout(&Project{BuildTimeout: 5}); got != 5*time.Minute {
+		t.Fatalf("BuildTimeout=5 = %v, want 5m", got)
+	}
+}
+
+// TestRunBuild_TimeoutMarksFailed drives a build whose install step
+// sleeps far beyond the deadline and verifies the deployment fails with
+// the timeout message and the sleep is killed promptly.
+func TestRunBuild_TimeoutMarksFailed(t *testing.T) {
+	s := newBuildTestService(t)
+	s.buildTimeoutUnit = time.Second
+
+	project := &Project{
+		Name:         "slow",
+		GitURL:       initTestGitRepo(t, map[string]string{"README": "hi"}),
+		GitBranch:    "main",
+		InstallCmd:   "sleep 30",
+		BuildTimeout: 1,
+		WebhookToken: "tok-timeout",
+	}
+	if err := s.db.Create(project).Error; err != nil {
+		t.Fatalf("create project: %v", err)
+	}
+
+	start := time.Now()
+	if err := s.runBuildOnce(project.ID); err != nil {
+		t.Fatalf("runBuildOnce: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 15*time.Second {
+		t.Fatalf("build took %v; process group was not killed on timeout", elapsed)
+	}
+
+	var dep Deployment
+	if err := s.db.Where("project_id = ?", project.ID).First(&dep).Error; err != nil {
+		t.Fatalf("load deployment: %v", err)
+	}
+	if dep.Status != "failed" {
+		t.Fatalf("deployment status = %q, want failed", dep.Status)
+	}
+
+	var got Project
+	s.db.First(&got, project.ID)
+	if got.Status != "error" {
+		t.Fatalf("project status = %q, want error", got.Status)
+	}
+	if got.ErrorMsg != "build timed out after 1s" {
+		t.Fatalf("error_msg = %q, want %q", got.ErrorMsg, "build timed out after 1s")
+	}
+
+	logContent, _ := s.builder.ReadLog(project.ID, dep.BuildNum)
+	if !strings.Contains(logContent, "build timed out after 1s") {
+		t.Fatalf("build log missing timeout line:\n%s", logContent)
+	}
+}
@@ -3,12 +3,15 @@ package deploy
 import (
 	"context"
 	"fmt"
+	"net"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/web-casa/webcasa/internal/execx"
+	"gorm.io/gorm"
 )
 
 // Builder orchestrates the build pipeline for a project.
@@ -78,13 +81,21 @@ func (b *Builder) setupBuildCache(project *Project, logWriter *LogWriter) []stri
 		extraEnv = append(extraEnv, fmt.Sprintf("npm_config_cache=%s", npmCache))
 		logWriter.Write([]byte(fmt.Sprintf("==> Build cache: npm_config_cache=%s\n", npmCache)))
 
-	case project.Framework == "flask" || project.Framework == "django":
+	case project.Framework == "flask" || project.Framework == "django" ||
+		project.Framework == "fastapi" || project.Framework == "python":
 		// pip cache for Python projects
 		pipCache := filepath.Join(cacheDir, "pip")
 		os.MkdirAll(pipCache, 0755)
 		extraEnv = append(extraEnv, fmt.Sprintf("PIP_CACHE_DIR=%s", pipCache))
 		logWriter.Write([]byte(fmt.Sprintf("==> Build cache: PIP_CACHE_DIR=%s\n", pipCache)))
 
+	case project.Framework == "bun":
+		// bun's global install cache
+		bunCache := filepath.Join(cacheDir, "bun")
+		os.MkdirAll(bunCache, 0755)
+		extraEnv = append(extraEnv, fmt.Sprintf("BUN_INSTALL_CACHE_DIR=%s", bunCache))
+		logWriter.Write([]byte(fmt.Sprintf("==> Build cache: BUN_INSTALL_CACHE_DIR=%s\n", bunCache)))
+
 	case project.Framework == "laravel":
 		// composer cache for PHP projects
 		composerCache := filepath.Join(cacheDir, "composer")
@@ -134,6 +145,40 @@ func (b *Builder) Build(ctx context.Context, project *Project, httpsToken string
 	commit, _ := b.git.GetCommitHash(project.ID)
 	logWriter.Write([]byte(fmt.Sprintf("Commit: %s\n\n", commit)))
 
+	return b.installAndBuild(ctx, project, projectDir, commit, cacheEnv, start, logWriter)
+}
+
+// BuildInDir is Build for a checkout outside the project's main source
+// dir (per-environment builds). The branch is always cloned fresh into
+// dir, so no pull/fast-forward state carries over between builds.
+func (b *Builder) BuildInDir(ctx context.Context, project *Project, dir, httpsToken string, logWriter *LogWriter) BuildResult {
+	start := time.Now()
+	cacheEnv := b.setupBuildCache(project, logWriter)
+
+	branch := project.GitBranch
+	if branch == "" {
+		branch = "main"
+	}
+	logWriter.Write([]byte("=== Step 1/3: Fetching source code ===\n"))
+	if err := b.git.CloneToDir(ctx, project.GitURL, branch, project.DeployKey, httpsToken, dir, logWriter); err != nil {
+		return BuildResult{ErrorMsg: fmt.Sprintf("git clone failed: %v", err), Duration: time.Since(start)}
+	}
+
+	commit, _ := b.git.CommitHashIn(dir)
+	logWriter.Write([]byte(fmt.Sprintf("Commit: %s\n\n", commit)))
+
+	return b.installAndBuild(ctx, project, dir, commit, cacheEnv, start, logWriter)
+}
+
+// installAndBuild runs steps 2 and 3 of the pipeline in projectDir.
+func (b *Builder) installAndBuild(ctx context.Context, project *Project, projectDir, commit string, cacheEnv []string, start time.Time, logWriter *LogWriter) BuildResult {
+	// Opt-in dependency cache, keyed by the lockfile as checked out
+	// (before install can rewrite it).
+	var lockHash string
+	if project.CacheEnabled {
+		lockHash = b.restoreDepCache(project, projectDir, logWriter)
+	}
+
 	// Step 2: Install dependencies
 	if project.InstallCmd != "" {
 		logWriter.Write([]byte("=== Step 2/3: Installing dependencies ===\n"))
@@ -156,6 +201,10 @@ func (b *Builder) Build(ctx context.Context, project *Project, httpsToken string
 		logWriter.Write([]byte("=== Step 3/3: No build command, skipping ===\n\n"))
 	}
 
+	if project.CacheEnabled {
+		b.saveDepCache(project, projectDir, lockHash, logWriter)
+	}
+
 	logWriter.Write([]byte(fmt.Sprintf("=== Build completed in %s ===\n", time.Since(start).Round(time.Millisecond))))
 	return BuildResult{
 		Success:  true,
@@ -208,26 +257,178 @@ func (b *Builder) ReadLog(projectID uint, buildNum int) (string, error) {
 	return string(data), nil
 }
 
+// portRangeSize is the width of the primary project port range; the
+// zero-downtime alternate of a primary port sits portRangeSize above it.
+const portRangeSize = 5000
+
 // PortAllocator finds a free port for a project.
 type PortAllocator struct {
 	basePort int
+	db       *gorm.DB // optional; nil skips the uniqueness check against stored projects
+
+	// probe reports whether a port can be bound right now. Replaced in
+	// tests to simulate ports held by non-deploy services.
+	probe func(port int) bool
+
+	mu       sync.Mutex
+	reserved map[int]uint // port → project, for allocations not yet persisted
 }
 
 // NewPortAllocator creates a port allocator starting from the given base port.
-func NewPortAllocator(basePort int) *PortAllocator {
-	return &PortAllocator{basePort: basePort}
+func NewPortAllocator(basePort int, db *gorm.DB) *PortAllocator {
+	return &PortAllocator{basePort: basePort, db: db, probe: portFree, reserved: make(map[int]uint)}
 }
 
-// AllocatePort assigns a port based on the project ID to avoid conflicts.
+// portFree reports whether nothing is currently listening on port.
+func portFree(port int) bool {
+	l, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return false
+	}
+	l.Close()
+	return true
+}
+
+// AllocatePort assigns a port for a project. It prefers basePort+ID and
+// walks forward through the primary range past ports that are bound by
+// another process or already assigned to another project (including the
+// zero-downtime alternate). Falls back to basePort+ID if the whole range
+// is exhausted.
 func (pa *PortAllocator) AllocatePort(projectID uint) int {
-	return pa.basePort + int(projectID)
+	pa.mu.Lock()
+	defer pa.mu.Unlock()
+
+	for port, owner := range pa.reserved {
+		if owner == projectID {
+			return port
+		}
+	}
+
+	preferred := pa.basePort + int(projectID)
+	start := int(projectID) % portRangeSize
+	for i := 0; i < portRangeSize; i++ {
+		port := pa.basePort + (start+i)%portRangeSize
+		if port == pa.basePort {
+			continue
+		}
+		if pa.takenLocked(port, projectID) {
+			continue
+		}
+		pa.reserved[port] = projectID
+		return port
+	}
+	return preferred
+}
+
+// takenLocked reports whether port (or its zero-downtime alternate) is
+// unavailable to projectID. Caller holds pa.mu.
+func (pa *PortAllocator) takenLocked(port int, projectID uint) bool {
+	alternate := port + portRangeSize
+	for _, p := range []int{port, alternate} {
+		if owner, ok := pa.reserved[p]; ok && owner != projectID {
+			return true
+		}
+	}
+	if pa.db != nil {
+		var count int64
+		pa.db.Model(&Project{}).Where("id <> ? AND port IN ?", projectID, []int{port, alternate}).Count(&count)
+		if count > 0 {
+			return true
+		}
+	}
+	if pa.probe != nil && (!pa.probe(port) || !pa.probe(alternate)) {
+		return true
+	}
+	return false
+}
+
+// ReleasePort drops any in-memory reservation held by a project so its
+// port can be handed out again once the project row is gone.
+func (pa *PortAllocator) ReleasePort(projectID uint) {
+	pa.mu.Lock()
+	defer pa.mu.Unlock()
+	for port, owner := range pa.reserved {
+		if owner == projectID {
+			delete(pa.reserved, port)
+		}
+	}
+}
+
+// environmentPortBase is where per-environment ports start. Main project
+// ports live in [10000, 20000) (primary + zero-downtime alternate) and
+// previews in [20000, 30000), so environments take [30000, 40000).
+const (
+	environmentPortBase  = 30000
+	environmentPortRange = 10000
+)
+
+// AllocateEnvironmentPort assigns a port for a project environment. It
+// prefers environmentPortBase+ID and walks forward through the environment
+// range past ports that are bound or already assigned, like AllocatePort.
+// Falls back to environmentPortBase+ID if the whole range is exhausted.
+func (pa *PortAllocator) AllocateEnvironmentPort(envID uint) int {
+	pa.mu.Lock()
+	defer pa.mu.Unlock()
+
+	start := int(envID) % environmentPortRange
+	for i := 0; i < environmentPortRange; i++ {
+		port := environmentPortBase + (start+i)%environmentPortRange
+		if port == environmentPortBase {
+			continue
+		}
+		if !pa.environmentPortTakenLocked(port, envID) {
+			return port
+		}
+	}
+	return environmentPortBase + int(envID)
+}
+
+// EnvironmentPortAvailable reports whether environment envID can be given
+// port.
+func (pa *PortAllocator) EnvironmentPortAvailable(port int, envID uint) bool {
+	pa.mu.Lock()
+	defer pa.mu.Unlock()
+	return !pa.environmentPortTakenLocked(port, envID)
+}
+
+// environmentPortTakenLocked reports whether port is reserved for a
+// project, stored on a project (as its primary or zero-downtime port), a
+// preview or another environment, or bound right now. Caller holds pa.mu.
+func (pa *PortAllocator) environmentPortTakenLocked(port int, envID uint) bool {
+	if _, ok := pa.reserved[port]; ok {
+		return true
+	}
+	if pa.db != nil {
+		var count int64
+		pa.db.Model(&Project{}).Where("port IN ?", []int{port, port - portRangeSize}).Count(&count)
+		if count > 0 {
+			return true
+		}
+		pa.db.Model(&PreviewDeployment{}).Where("port = ? OR base_port IN ?", port, []int{port, port - portRangeSize}).Count(&count)
+		if count > 0 {
+			return true
+		}
+		pa.db.Model(&ProjectEnvironment{}).Where("id <> ? AND port = ?", envID, port).Count(&count)
+		if count > 0 {
+			return true
+		}
+	}
+	return pa.probe != nil && !pa.probe(port)
 }
 
 // AlternatePort returns a different port for zero-downtime deployment.
-// It alternates between the primary range (basePort+ID) and secondary range (basePort+5000+ID).
+// A port in the primary range [basePort, basePort+5000) maps to the
+// secondary range 5000 above it and back again. Ports outside both ranges
+// (set by hand) alternate via basePort+ID as before.
 func (pa *PortAllocator) AlternatePort(currentPort int, projectID uint) int {
+	switch {
+	case currentPort > pa.basePort && currentPort < pa.basePort+portRangeSize:
+		return currentPort + portRangeSize
+	case currentPort > pa.basePort+portRangeSize && currentPort < pa.basePort+2*portRangeSize:
+		return currentPort - portRangeSize
+	}
 	primary := pa.basePort + int(projectID)
-	alternate := primary + 5000
+	alternate := primary + portRangeSize
 	if currentPort == alternate {
 		return primary
 	}
@@ -0,0 +1,155 @@
+package deploy
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// depCacheSpec names the dependency directories worth preserving for a
+// framework and the lockfiles whose contents key the cache.
+type depCacheSpec struct {
+	dirs      []string
+	lockfiles []string
+}
+
+var nodeDepCache = depCacheSpec{
+	dirs:      []string{"node_modules"},
+	lockfiles: []string{"package-lock.json", "yarn.lock", "pnpm-lock.yaml", "bun.lockb", "bun.lock"},
+}
+
+var pythonDepCache = depCacheSpec{
+	dirs:      []string{".venv"},
+	lockfiles: []string{"requirements.txt", "poetry.lock", "uv.lock"},
+}
+
+var phpDepCache = depCacheSpec{
+	dirs:      []string{"vendor"},
+	lockfiles: []string{"composer.lock"},
+}
+
+// depCacheSpecs maps a project framework to its dependency cache layout.
+// Go is absent: modules already live in the shared GOMODCACHE.
+var depCacheSpecs = map[string]depCacheSpec{
+	"nextjs": nodeDepCache, "nuxt": nodeDepCache, "vite": nodeDepCache,
+	"remix": nodeDepCache, "express": nodeDepCache, "nodejs": nodeDepCache, "bun": nodeDepCache,
+	"flask": pythonDepCache, "django": pythonDepCache, "fastapi": pythonDepCache, "python": pythonDepCache,
+	"laravel": phpDepCache, "php": phpDepCache,
+	// HOME is the checkout during builds, so cargo's registry lands in .cargo.
+	"rust": {dirs: []string{".cargo"}, lockfiles: []string{"Cargo.lock"}},
+}
+
+// depCacheDir holds the saved dependency directories plus the lockfile
+// hash they were installed from.
+func (b *Builder) depCacheDir(projectID uint) string {
+	return filepath.Join(b.CacheDir(projectID), "deps")
+}
+
+// lockfileHash hashes every lockfile of spec present in dir. Returns ""
+// when there is none, since without a lockfile there is nothing to tell
+// a stale cache from a valid one.
+func lockfileHash(dir string, spec depCacheSpec) string {
+	h := sha256.New()
+	found := false
+	for _, name := range spec.lockfiles {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			continue
+		}
+		found = true
+		fmt.Fprintf(h, "%s\x00%d\x00", name, len(data))
+		h.Write(data)
+	}
+	if !found {
+		return ""
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// restoreDepCache copies cached dependency directories into dir before
+// install. On a lockfile change the cache and any dependency directories
+// already in dir are dropped so install starts from scratch. Returns the
+// lockfile hash to pass to saveDepCache ("" disables saving).
+func (b *Builder) restoreDepCache(project *Project, dir string, logWriter *LogWriter) string {
+	spec, ok := depCacheSpecs[project.Framework]
+	if !ok {
+		logWriter.Write([]byte(fmt.Sprintf("==> Dependency cache: not supported for framework %q\n", project.Framework)))
+		return ""
+	}
+	hash := lockfileHash(dir, spec)
+	if hash == "" {
+		logWriter.Write([]byte("==> Dependency cache: no lockfile found, skipping\n"))
+		return ""
+	}
+
+	cacheDir := b.depCacheDir(project.ID)
+	stored, _ := os.ReadFile(filepath.Join(cacheDir, "lockhash"))
+	if strings.TrimSpace(string(stored)) != hash {
+		if len(stored) > 0 {
+			logWriter.Write([]byte("==> Dependency cache: lockfile changed, running a fresh install\n"))
+		} else {
+			logWriter.Write([]byte("==> Dependency cache: empty, running a fresh install\n"))
+		}
+		os.RemoveAll(cacheDir)
+		for _, d := range spec.dirs {
+			os.RemoveAll(filepath.Join(dir, d))
+		}
+		return hash
+	}
+
+	for _, d := range spec.dirs {
+		src := filepath.Join(cacheDir, d)
+		if _, err := os.Stat(src); err != nil {
+			continue
+		}
+		dst := filepath.Join(dir, d)
+		os.RemoveAll(dst)
+		if err := copyTree(src, dst, nil); err != nil {
+			// A partial restore is worse than none; let install rebuild it.
+			os.RemoveAll(dst)
+			logWriter.Write([]byte(fmt.Sprintf("==> Dependency cache: restore of %s failed: %v\n", d, err)))
+			continue
+		}
+		logWriter.Write([]byte(fmt.Sprintf("==> Dependency cache: restored %s\n", d)))
+	}
+	return hash
+}
+
+// saveDepCache stores the dependency directories of a successful build
+// under the given lockfile hash, replacing any previous cache.
+func (b *Builder) saveDepCache(project *Project, dir, hash string, logWriter *LogWriter) {
+	spec, ok := depCacheSpecs[project.Framework]
+	if !ok || hash == "" {
+		return
+	}
+	cacheDir := b.depCacheDir(project.ID)
+	tmp := cacheDir + ".tmp"
+	os.RemoveAll(tmp)
+	if err := os.MkdirAll(tmp, 0755); err != nil {
+		return
+	}
+	for _, d := range spec.dirs {
+		src := filepath.Join(dir, d)
+		if _, err := os.Stat(src); err != nil {
+			continue
+		}
+		if err := copyTree(src, filepath.Join(tmp, d), nil); err != nil {
+			os.RemoveAll(tmp)
+			logWriter.Write([]byte(fmt.Sprintf("==> Dependency cache: save failed: %v\n", err)))
+			return
+		}
+	}
+	if err := os.WriteFile(filepath.Join(tmp, "lockhash"), []byte(hash+"\n"), 0644); err != nil {
+		os.RemoveAll(tmp)
+		return
+	}
+	os.RemoveAll(cacheDir)
+	if err := os.Rename(tmp, cacheDir); err != nil {
+		os.RemoveAll(tmp)
+		return
+	}
+	logWriter.Write([]byte("==> Dependency cache: saved\n"))
+}
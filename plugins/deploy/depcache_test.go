@@ -0,0 +1,75 @@
+package deploy
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// depCacheInstall reports whether node_modules was already present (a
+// cache hit) and otherwise "installs" it with a marker file.
+const depCacheInstall = `if [ -f node_modules/marker ]; then echo RESTORED; else mkdir -p node_modules && echo installed > node_modules/marker && echo FRESH; fi`
+
+func runCachedInstall(t *testing.T, b *Builder, project *Project, lockfile string) string {
+	t.Helper()
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "package-lock.json"), []byte(lockfile), 0644)
+	logPath := filepath.Join(t.TempDir(), "build.log")
+	lw, err := NewLogWriter(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := b.installAndBuild(context.Background(), project, dir, "abc", nil, time.Now(), lw)
+	lw.Close()
+	if !res.Success {
+		t.Fatalf("build failed: %s", res.ErrorMsg)
+	}
+	out, _ := os.ReadFile(logPath)
+	return string(out)
+}
+
+func TestDepCache_RestoresOnUnchangedLockfile(t *testing.T) {
+	b := NewBuilder(nil, t.TempDir())
+	project := &Project{ID: 1, Framework: "express", CacheEnabled: true, InstallCmd: depCacheInstall}
+
+	if log := runCachedInstall(t, b, project, `{"lockfileVersion":3}`); !strings.Contains(log, "FRESH") {
+		t.Fatalf("first build should install from scratch:\n%s", log)
+	}
+	// Each build gets a brand-new checkout; only the cache can carry
+	// node_modules across.
+	log := runCachedInstall(t, b, project, `{"lockfileVersion":3}`)
+	if !strings.Contains(log, "restored node_modules") || !strings.Contains(log, "RESTORED") {
+		t.Fatalf("second build should restore node_modules from cache:\n%s", log)
+	}
+}
+
+func TestDepCache_LockfileChangeForcesFreshInstall(t *testing.T) {
+	b := NewBuilder(nil, t.TempDir())
+	project := &Project{ID: 2, Framework: "express", CacheEnabled: true, InstallCmd: depCacheInstall}
+
+	runCachedInstall(t, b, project, `{"v":1}`)
+	log := runCachedInstall(t, b, project, `{"v":2}`)
+	if !strings.Contains(log, "lockfile changed") || !strings.Contains(log, "FRESH") {
+		t.Fatalf("changed lockfile should trigger a fresh install:\n%s", log)
+	}
+	// The new lockfile's install is what's cached now.
+	if log := runCachedInstall(t, b, project, `{"v":2}`); !strings.Contains(log, "RESTORED") {
+		t.Fatalf("cache should be re-keyed to the new lockfile:\n%s", log)
+	}
+}
+
+func TestDepCache_DisabledByDefault(t *testing.T) {
+	b := NewBuilder(nil, t.TempDir())
+	project := &Project{ID: 3, Framework: "express", InstallCmd: depCacheInstall}
+
+	runCachedInstall(t, b, project, `{}`)
+	if log := runCachedInstall(t, b, project, `{}`); !strings.Contains(log, "FRESH") {
+		t.Fatalf("cache must be opt-in:\n%s", log)
+	}
+	if _, err := os.Stat(b.depCacheDir(3)); !os.IsNotExist(err) {
+		t.Fatalf("no dependency cache should be written when disabled (err=%v)", err)
+	}
+}
@@ -1,6 +1,7 @@
 package deploy
 
 import (
+	"net"
 	"os"
 	"path/filepath"
 	"testing"
@@ -120,6 +121,84 @@ func TestDetectFramework_DockerfilePriority(t *testing.T) {
 	}
 }
 
+func TestDetectFramework_FastAPIPyproject(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "pyproject.toml"), []byte("[project]\nname = \"svc\"\ndependencies = [\"FastAPI>=0.110\", \"uvicorn\"]\n"), 0644)
+
+	preset := DetectFramework(dir)
+	if preset.Framework != "fastapi" {
+		t.Fatalf("expected fastapi, got %s", preset.Framework)
+	}
+	if preset.InstallCmd != "pip install ." {
+		t.Fatalf("pyproject install = %q, want pip install .", preset.InstallCmd)
+	}
+	if !contains(preset.StartCmd, "uvicorn") || preset.Port != 8000 {
+		t.Fatalf("unexpected fastapi preset: %+v", preset)
+	}
+}
+
+func TestDetectFramework_PythonGeneric(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "requirements.txt"), []byte("requests==2.31.0\n"), 0644)
+
+	preset := DetectFramework(dir)
+	if preset.Framework != "python" || preset.InstallCmd != "pip install -r requirements.txt" {
+		t.Fatalf("unexpected generic python preset: %+v", preset)
+	}
+}
+
+func TestDetectFramework_Rust(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "Cargo.toml"), []byte("[package]\nname = \"my-api\"\nversion = \"0.1.0\"\n\n[dependencies]\nname = \"ignored\"\n"), 0644)
+
+	preset := DetectFramework(dir)
+	if preset.Framework != "rust" {
+		t.Fatalf("expected rust, got %s", preset.Framework)
+	}
+	if preset.BuildCmd != "cargo build --release" || preset.StartCmd != "./target/release/my-api" {
+		t.Fatalf("unexpected rust preset: %+v", preset)
+	}
+}
+
+func TestDetectFramework_RustUnsafeName(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "Cargo.toml"), []byte("[package]\nname = \"x; rm -rf /\"\n"), 0644)
+
+	preset := DetectFramework(dir)
+	if preset.StartCmd != frameworkPresets["rust"].StartCmd {
+		t.Fatalf("unsafe crate name leaked into start command: %q", preset.StartCmd)
+	}
+}
+
+func TestDetectFramework_Deno(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "deno.json"), []byte(`{"tasks":{"start":"deno run -A server.ts"}}`), 0644)
+	// A package.json alongside deno.json must not turn it into Node.js.
+	os.WriteFile(filepath.Join(dir, "package.json"), []byte(`{"dependencies":{"express":"4.18.0"}}`), 0644)
+
+	preset := DetectFramework(dir)
+	if preset.Framework != "deno" {
+		t.Fatalf("expected deno, got %s", preset.Framework)
+	}
+	if preset.StartCmd != "deno task start" || preset.Port != 8000 {
+		t.Fatalf("unexpected deno preset: %+v", preset)
+	}
+}
+
+func TestDetectFramework_Bun(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "bun.lockb"), []byte{0}, 0644)
+	os.WriteFile(filepath.Join(dir, "package.json"), []byte(`{"scripts":{"build":"bun build ./src","start":"bun src/index.ts"},"dependencies":{"next":"14.0.0"}}`), 0644)
+
+	preset := DetectFramework(dir)
+	if preset.Framework != "bun" {
+		t.Fatalf("expected bun, got %s", preset.Framework)
+	}
+	if preset.InstallCmd != "bun install" || preset.BuildCmd != "bun run build" || preset.StartCmd != "bun run start" {
+		t.Fatalf("unexpected bun preset: %+v", preset)
+	}
+}
+
 // ── Model tests ──
 
 func TestGetEnvSuggestions(t *testing.T) {
@@ -160,7 +239,7 @@ func TestGetEnvSuggestions_RequiredFields(t *testing.T) {
 // ── Builder helper tests ──
 
 func TestPortAllocator(t *testing.T) {
-	pa := NewPortAllocator(10000)
+	pa := NewPortAllocator(10000, nil)
 
 	port := pa.AllocatePort(5)
 	if port != 10005 {
@@ -169,7 +248,7 @@ func TestPortAllocator(t *testing.T) {
 }
 
 func TestPortAllocator_AlternatePort(t *testing.T) {
-	pa := NewPortAllocator(10000)
+	pa := NewPortAllocator(10000, nil)
 
 	primary := pa.AllocatePort(5) // 10005
 	alt := pa.AlternatePort(primary, 5)
@@ -184,6 +263,79 @@ func TestPortAllocator_AlternatePort(t *testing.T) {
 	}
 }
 
+func TestPortAllocator_SkipsOccupiedPort(t *testing.T) {
+	pa := NewPortAllocator(10000, nil)
+	// 10005 is held by some non-deploy service.
+	pa.probe = func(port int) bool { return port != 10005 }
+
+	if port := pa.AllocatePort(5); port != 10006 {
+		t.Fatalf("expected occupied 10005 to be skipped (got %d)", port)
+	}
+	// Allocation is stable for the same project.
+	if port := pa.AllocatePort(5); port != 10006 {
+		t.Fatalf("second allocation for project 5 = %d, want 10006", port)
+	}
+	// Project 6's preferred port is now reserved by project 5.
+	if port := pa.AllocatePort(6); port != 10007 {
+		t.Fatalf("expected project 6 to skip reserved 10006 (got %d)", port)
+	}
+}
+
+func TestPortAllocator_ProbeDetectsListener(t *testing.T) {
+	l, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Skipf("cannot listen: %v", err)
+	}
+	defer l.Close()
+	if portFree(l.Addr().(*net.TCPAddr).Port) {
+		t.Fatal("portFree reported a bound port as free")
+	}
+}
+
+func TestPortAllocator_DeleteFreesPort(t *testing.T) {
+	db := openPollerTestDB(t)
+	pa := NewPortAllocator(10000, db)
+	pa.probe = func(port int) bool { return port != 10001 }
+
+	// Project 1 can't have 10001, so it takes 10002 — project 2's
+	// preferred port, which is then unavailable to project 2.
+	p1 := &Project{ID: 1, Name: "one", WebhookToken: "tok-one"}
+	db.Create(p1)
+	p1.Port = pa.AllocatePort(1)
+	db.Model(p1).Update("port", p1.Port)
+	if p1.Port != 10002 {
+		t.Fatalf("project 1 port = %d, want 10002", p1.Port)
+	}
+
+	// Another allocator instance (e.g. after restart, no in-memory
+	// reservations) must still see the DB assignment.
+	fresh := NewPortAllocator(10000, db)
+	fresh.probe = pa.probe
+	if port := fresh.AllocatePort(2); port != 10003 {
+		t.Fatalf("project 2 port = %d, want 10003 (10002 belongs to project 1)", port)
+	}
+	fresh.ReleasePort(2)
+
+	// Deleting project 1 frees 10002.
+	db.Delete(&Project{}, 1)
+	fresh.ReleasePort(1)
+	if port := fresh.AllocatePort(2); port != 10002 {
+		t.Fatalf("after deleting project 1, project 2 port = %d, want 10002", port)
+	}
+}
+
+func TestPortAllocator_AlternateOfShiftedPort(t *testing.T) {
+	pa := NewPortAllocator(10000, nil)
+	// A project bumped off its preferred port alternates around the
+	// port it actually got.
+	if alt := pa.AlternatePort(10007, 5); alt != 15007 {
+		t.Fatalf("alternate of 10007 = %d, want 15007", alt)
+	}
+	if back := pa.AlternatePort(15007, 5); back != 10007 {
+		t.Fatalf("alternate of 15007 = %d, want 10007", back)
+	}
+}
+
 func TestCacheDir(t *testing.T) {
 	dataDir := t.TempDir()
 	git := NewGitClient(filepath.Join(dataDir, "sources"))
@@ -7,6 +7,8 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
+	"strings"
 	"time"
 )
 
@@ -17,6 +19,15 @@ func DetectFramework(dir string) FrameworkPreset {
 		return frameworkPresets["dockerfile"]
 	}
 
+	// Bun and Deno projects often carry a package.json too, so check
+	// their runtime-specific markers before falling through to Node.js.
+	if preset := detectBunFramework(dir); preset != nil {
+		return *preset
+	}
+	if preset := detectDenoFramework(dir); preset != nil {
+		return *preset
+	}
+
 	// Check package.json for Node.js frameworks
 	if preset := detectNodeFramework(dir); preset != nil {
 		return *preset
@@ -27,12 +38,17 @@ func DetectFramework(dir string) FrameworkPreset {
 		return frameworkPresets["go"]
 	}
 
+	// Check Cargo.toml for Rust projects
+	if preset := detectRustFramework(dir); preset != nil {
+		return *preset
+	}
+
 	// Check composer.json for PHP/Laravel
 	if preset := detectPHPFramework(dir); preset != nil {
 		return *preset
 	}
 
-	// Check requirements.txt / manage.py for Python
+	// Check requirements.txt / pyproject.toml / manage.py for Python
 	if preset := detectPythonFramework(dir); preset != nil {
 		return *preset
 	}
@@ -166,27 +182,132 @@ func detectPythonFramework(dir string) *FrameworkPreset {
 		return &p
 	}
 
-	// Check requirements.txt for flask
-	reqPath := filepath.Join(dir, "requirements.txt")
-	data, err := os.ReadFile(reqPath)
+	// Dependencies come from requirements.txt or, for PEP 621 projects,
+	// pyproject.toml. Both are scanned as plain text for package names.
+	installCmd := "pip install -r requirements.txt"
+	data, err := os.ReadFile(filepath.Join(dir, "requirements.txt"))
+	if err != nil {
+		data, err = os.ReadFile(filepath.Join(dir, "pyproject.toml"))
+		if err != nil {
+			return nil
+		}
+		installCmd = "pip install ."
+	}
+
+	content := strings.ToLower(string(data))
+	var p FrameworkPreset
+	switch {
+	case strings.Contains(content, "fastapi"):
+		p = frameworkPresets["fastapi"]
+	case strings.Contains(content, "flask"):
+		p = frameworkPresets["flask"]
+	case strings.Contains(content, "django"):
+		p = frameworkPresets["django"]
+	default:
+		// Generic Python
+		p = FrameworkPreset{
+			Name: "Python", Framework: "python",
+			StartCmd: "python app.py", Port: 8000,
+		}
+	}
+	p.InstallCmd = installCmd
+	return &p
+}
+
+func detectRustFramework(dir string) *FrameworkPreset {
+	data, err := os.ReadFile(filepath.Join(dir, "Cargo.toml"))
 	if err != nil {
 		return nil
 	}
+	p := frameworkPresets["rust"]
+	if name := cargoPackageName(string(data)); name != "" {
+		p.StartCmd = "./target/release/" + name
+	}
+	return &p
+}
 
-	content := string(data)
-	if contains(content, "flask") || contains(content, "Flask") {
-		p := frameworkPresets["flask"]
-		return &p
+// cargoPackageName extracts `name` from the [package] table of a
+// Cargo.toml. Only the simple `name = "..."` form is recognised; anything
+// else leaves the preset's default binary path in place.
+func cargoPackageName(content string) string {
+	inPackage := false
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "[") {
+			inPackage = line == "[package]"
+			continue
+		}
+		if !inPackage {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok || strings.TrimSpace(key) != "name" {
+			continue
+		}
+		name := strings.Trim(strings.TrimSpace(value), `"'`)
+		// The binary name lands in a shell command; only accept names
+		// cargo itself would accept.
+		if cargoNameRe.MatchString(name) {
+			return name
+		}
+		return ""
 	}
-	if contains(content, "django") || contains(content, "Django") {
-		p := frameworkPresets["django"]
-		return &p
+	return ""
+}
+
+var cargoNameRe = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+func detectDenoFramework(dir string) *FrameworkPreset {
+	var data []byte
+	var err error
+	for _, name := range []string{"deno.json", "deno.jsonc"} {
+		if data, err = os.ReadFile(filepath.Join(dir, name)); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return nil
 	}
 
-	// Generic Python
-	p := FrameworkPreset{
-		Name: "Python", Framework: "python",
-		InstallCmd: "pip install -r requirements.txt", StartCmd: "python app.py", Port: 8000,
+	p := frameworkPresets["deno"]
+	var cfg struct {
+		Tasks map[string]string `json:"tasks"`
+	}
+	// deno.jsonc may contain comments; a parse failure just means we
+	// can't see the tasks and keep the default entrypoint.
+	if json.Unmarshal(data, &cfg) == nil {
+		if _, ok := cfg.Tasks["build"]; ok {
+			p.BuildCmd = "deno task build"
+		}
+		if _, ok := cfg.Tasks["start"]; ok {
+			p.StartCmd = "deno task start"
+		}
+	}
+	return &p
+}
+
+func detectBunFramework(dir string) *FrameworkPreset {
+	if _, err := os.Stat(filepath.Join(dir, "bun.lockb")); err != nil {
+		if _, err := os.Stat(filepath.Join(dir, "bun.lock")); err != nil {
+			return nil
+		}
+	}
+
+	p := frameworkPresets["bun"]
+	data, err := os.ReadFile(filepath.Join(dir, "package.json"))
+	if err != nil {
+		return &p
+	}
+	var pkg struct {
+		Scripts map[string]string `json:"scripts"`
+	}
+	if json.Unmarshal(data, &pkg) == nil {
+		if _, ok := pkg.Scripts["build"]; ok {
+			p.BuildCmd = "bun run build"
+		}
+		if _, ok := pkg.Scripts["start"]; ok {
+			p.StartCmd = "bun run start"
+		}
 	}
 	return &p
 }
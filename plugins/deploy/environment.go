@@ -0,0 +1,436 @@
+package deploy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/web-casa/webcasa/internal/caddy"
+	"gorm.io/gorm"
+)
+
+// validEnvironmentName restricts environment names to a charset that is
+// safe inside a systemd unit name and a filesystem path.
+var validEnvironmentName = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,31}$`)
+
+// ErrEnvironmentNotFound is returned when an environment does not exist in
+// the given project. Handlers map it to 404.
+var ErrEnvironmentNotFound = fmt.Errorf("environment not found")
+
+// ErrInvalidEnvironment wraps a rejected environment domain or port.
+// Handlers map it to 400.
+var ErrInvalidEnvironment = fmt.Errorf("invalid environment")
+
+// ListEnvironments returns a project's environments with live status.
+func (s *Service) ListEnvironments(projectID uint) ([]ProjectEnvironment, error) {
+	var envs []ProjectEnvironment
+	if err := s.db.Where("project_id = ?", projectID).Order("id asc").Find(&envs).Error; err != nil {
+		return nil, err
+	}
+	for i := range envs {
+		s.populateEnvironment(&envs[i])
+	}
+	return envs, nil
+}
+
+// GetEnvironment returns one environment, scoped to its project.
+func (s *Service) GetEnvironment(projectID, envID uint) (*ProjectEnvironment, error) {
+	var env ProjectEnvironment
+	if err := s.db.Where("id = ? AND project_id = ?", envID, projectID).First(&env).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrEnvironmentNotFound
+		}
+		return nil, err
+	}
+	s.populateEnvironment(&env)
+	return &env, nil
+}
+
+// populateEnvironment decodes env vars and resolves live status.
+func (s *Service) populateEnvironment(env *ProjectEnvironment) {
+	if env.EnvVars != "" {
+		json.Unmarshal([]byte(env.EnvVars), &env.EnvVarList)
+	}
+	if env.Status == "running" && s.proc != nil && !s.proc.IsEnvironmentRunning(env.ProjectID, env.Name) {
+		env.Status = "stopped"
+	}
+}
+
+// CreateEnvironment adds an environment to a bare-mode project and
+// assigns it a port.
+func (s *Service) CreateEnvironment(env *ProjectEnvironment) error {
+	if !validEnvironmentName.MatchString(env.Name) {
+		return fmt.Errorf("invalid environment name %q: use lowercase letters, digits and dashes (max 32)", env.Name)
+	}
+	var project Project
+	if err := s.db.First(&project, env.ProjectID).Error; err != nil {
+		return fmt.Errorf("project not found: %w", err)
+	}
+	if project.DeployMode == "docker" {
+		return fmt.Errorf("environments are only supported for bare deploy mode")
+	}
+	domain, err := s.environmentDomain(&project, 0, env.Domain)
+	if err != nil {
+		return err
+	}
+	env.Domain = domain
+	if env.Port != 0 {
+		if err := s.checkEnvironmentPort(0, env.Port); err != nil {
+			return err
+		}
+	}
+
+	if len(env.EnvVarList) > 0 {
+		data, _ := json.Marshal(env.EnvVarList)
+		env.EnvVars = string(data)
+	}
+	env.Status = "pending"
+	if err := s.db.Create(env).Error; err != nil {
+		return err
+	}
+	if env.Port == 0 {
+		env.Port = s.ports.AllocateEnvironmentPort(env.ID)
+		s.db.Model(env).Update("port", env.Port)
+	}
+	return nil
+}
+
+// UpdateEnvironment updates an environment's domain, branch, port or env
+// vars. A domain change is applied to the environment's host right away;
+// everything else takes effect on the next build of that environment.
+// Port 0 reassigns a free environment port.
+func (s *Service) UpdateEnvironment(projectID, envID uint, updates map[string]interface{}) error {
+	env, err := s.GetEnvironment(projectID, envID)
+	if err != nil {
+		return err
+	}
+	if raw, ok := updates["domain"]; ok {
+		d, isStr := raw.(string)
+		if !isStr {
+			return fmt.Errorf("%w: domain must be a string", ErrInvalidEnvironment)
+		}
+		project, err := s.GetProject(projectID)
+		if err != nil {
+			return err
+		}
+		if updates["domain"], err = s.environmentDomain(project, envID, d); err != nil {
+			return err
+		}
+	}
+	if raw, ok := updates["port"]; ok {
+		var port int
+		switch v := raw.(type) {
+		case float64:
+			port = int(v)
+			if float64(port) != v {
+				return fmt.Errorf("%w: port must be a whole number", ErrInvalidEnvironment)
+			}
+		case int:
+			port = v
+		default:
+			return fmt.Errorf("%w: port must be a number", ErrInvalidEnvironment)
+		}
+		if port == 0 {
+			port = s.ports.AllocateEnvironmentPort(envID)
+		} else if port != env.Port {
+			if err := s.checkEnvironmentPort(envID, port); err != nil {
+				return err
+			}
+		}
+		updates["port"] = port
+	}
+	if list, ok := updates["env_vars"].([]EnvVar); ok {
+		data, _ := json.Marshal(list)
+		updates["env_vars"] = string(data)
+	}
+	// A domain change renames the existing host; clearing the domain
+	// removes it. A port change reaches the host on the next build.
+	deleted, err := s.renameHost(env.HostID, env.Domain, updates)
+	if err != nil {
+		return err
+	}
+	if deleted {
+		updates["host_id"] = 0
+	}
+	return s.db.Model(&ProjectEnvironment{}).Where("id = ?", envID).Updates(updates).Error
+}
+
+// environmentDomain normalizes domain for environment envID of project.
+// It must be a valid host name, differ from the project's domain and not be
+// used by another environment. An empty domain means no host.
+func (s *Service) environmentDomain(project *Project, envID uint, domain string) (string, error) {
+	if strings.TrimSpace(domain) == "" {
+		return "", nil
+	}
+	d, err := caddy.NormalizeDomain(domain)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidEnvironment, err)
+	}
+	if err := caddy.ValidateDomain(d); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidEnvironment, err)
+	}
+	if strings.EqualFold(d, project.Domain) {
+		return "", fmt.Errorf("%w: environment domain must differ from the project domain", ErrInvalidEnvironment)
+	}
+	var count int64
+	s.db.Model(&ProjectEnvironment{}).Where("id <> ? AND LOWER(domain) = LOWER(?)", envID, d).Count(&count)
+	if count > 0 {
+		return "", fmt.Errorf("%w: domain %q is used by another environment", ErrInvalidEnvironment, d)
+	}
+	return d, nil
+}
+
+// checkEnvironmentPort rejects a port environment envID cannot listen on:
+// out of range, assigned to another project, preview or environment, or
+// bound by another process.
+func (s *Service) checkEnvironmentPort(envID uint, port int) error {
+	if port < 1 || port > 65535 {
+		return fmt.Errorf("%w: port must be between 1 and 65535", ErrInvalidEnvironment)
+	}
+	if !s.ports.EnvironmentPortAvailable(port, envID) {
+		return fmt.Errorf("%w: port %d is already in use", ErrInvalidEnvironment, port)
+	}
+	return nil
+}
+
+// DeleteEnvironment tears down an environment's service, host, checkout
+// and deployment history.
+func (s *Service) DeleteEnvironment(projectID, envID uint) error {
+	env, err := s.GetEnvironment(projectID, envID)
+	if err != nil {
+		return err
+	}
+	if s.isEnvironmentBuildInflight(envID) {
+		return fmt.Errorf("environment %q is building; retry after the build finishes", env.Name)
+	}
+	s.teardownEnvironment(env)
+	s.db.Where("project_id = ? AND environment_id = ?", projectID, envID).Delete(&Deployment{})
+	return s.db.Delete(&ProjectEnvironment{}, envID).Error
+}
+
+// teardownEnvironment removes the runtime artefacts of an environment.
+func (s *Service) teardownEnvironment(env *ProjectEnvironment) {
+	s.proc.UninstallEnvironment(env.ProjectID, env.Name)
+	if env.HostID > 0 {
+		s.coreAPI.DeleteHost(env.HostID)
+		s.coreAPI.ReloadCaddy()
+	}
+	os.RemoveAll(s.git.EnvironmentDir(env.ProjectID, env.ID))
+	os.RemoveAll(s.environmentLogDir(env.ProjectID, env.ID))
+}
+
+// environmentLogDir holds the build logs for one environment.
+func (s *Service) environmentLogDir(projectID, envID uint) string {
+	return fmt.Sprintf("%s/env_%d", s.builder.LogDir(projectID), envID)
+}
+
+// EnvironmentBuildLogPath returns the log path for one environment build.
+func (s *Service) EnvironmentBuildLogPath(projectID, envID uint, buildNum int) string {
+	return fmt.Sprintf("%s/build_%d.log", s.environmentLogDir(projectID, envID), buildNum)
+}
+
+func (s *Service) isEnvironmentBuildInflight(envID uint) bool {
+	s.buildMu.Lock()
+	defer s.buildMu.Unlock()
+	return s.envBuildInflight[envID]
+}
+
+// BuildEnvironment triggers a build of one project environment. Same
+// contract as Build: non-blocking, coalesces triggers during an in-flight
+// build, shares the panel-wide build semaphore.
+func (s *Service) BuildEnvironment(projectID, envID uint) error {
+	if _, err := s.GetEnvironment(projectID, envID); err != nil {
+		return err
+	}
+
+	s.buildMu.Lock()
+	if s.envBuildInflight == nil {
+		s.envBuildInflight = make(map[uint]bool)
+		s.envBuildPending = make(map[uint]bool)
+	}
+	if s.envBuildInflight[envID] {
+		s.envBuildPending[envID] = true
+		s.buildMu.Unlock()
+		return ErrBuildCoalesced
+	}
+	select {
+	case s.buildSem <- struct{}{}:
+	default:
+		s.buildMu.Unlock()
+		return ErrBuildQueueFull
+	}
+	s.envBuildInflight[envID] = true
+	s.buildMu.Unlock()
+
+	go s.environmentBuildLoop(projectID, envID)
+	return nil
+}
+
+// environmentBuildLoop mirrors buildLoop for environment builds.
+func (s *Service) environmentBuildLoop(projectID, envID uint) {
+	defer func() { <-s.buildSem }()
+	for {
+		if err := s.runEnvironmentBuildOnce(projectID, envID); err != nil {
+			s.logger.Error("environment build failed", "project_id", projectID, "env_id", envID, "err", err)
+		}
+		s.buildMu.Lock()
+		if s.envBuildPending[envID] {
+			delete(s.envBuildPending, envID)
+			s.buildMu.Unlock()
+			continue
+		}
+		delete(s.envBuildInflight, envID)
+		s.buildMu.Unlock()
+		return
+	}
+}
+
+// runEnvironmentBuildOnce clones the environment's branch, builds it,
+// and (re)starts its service, creating the reverse-proxy host on first
+// success.
+func (s *Service) runEnvironmentBuildOnce(projectID, envID uint) error {
+	project, err := s.GetProject(projectID)
+	if err != nil {
+		return err
+	}
+	env, err := s.GetEnvironment(projectID, envID)
+	if err != nil {
+		return err
+	}
+
+	buildNum := env.CurrentBuild + 1
+	deployment := &Deployment{
+		ProjectID:     projectID,
+		EnvironmentID: envID,
+		BuildNum:      buildNum,
+		Status:        "building",
+	}
+	if err := s.db.Create(deployment).Error; err != nil {
+		return err
+	}
+	s.db.Model(&ProjectEnvironment{}).Where("id = ?", envID).Updates(map[string]interface{}{
+		"status":        "building",
+		"current_build": buildNum,
+		"error_msg":     "",
+	})
+
+	logPath := s.EnvironmentBuildLogPath(projectID, envID, buildNum)
+	os.MkdirAll(s.environmentLogDir(projectID, envID), 0755)
+	logWriter, err := NewLogWriter(logPath)
+	if err != nil {
+		return fmt.Errorf("create log writer: %w", err)
+	}
+	defer logWriter.Close()
+	deployment.LogFile = logPath
+
+	fail := func(msg string) error {
+		logWriter.Write([]byte("ERROR: " + msg + "\n"))
+		deployment.Status = "failed"
+		s.db.Save(deployment)
+		s.db.Model(&ProjectEnvironment{}).Where("id = ?", envID).Updates(map[string]interface{}{
+			"status":    "error",
+			"error_msg": msg,
+		})
+		return nil
+	}
+
+	authMethod, deployKey, httpsToken, err := s.GetGitCredentials(project)
+	if err != nil {
+		return fail(fmt.Sprintf("git credentials failed: %v", err))
+	}
+	buildProject := *project
+	buildProject.DeployKey = deployKey
+	if (authMethod == "github_app" || authMethod == "github_oauth") && httpsToken != "" {
+		converted, cerr := ConvertSSHToCleanHTTPS(project.GitURL)
+		if cerr != nil {
+			return fail(fmt.Sprintf("convert git URL: %v", cerr))
+		}
+		if extractHost(converted) != "github.com" {
+			return fail(fmt.Sprintf("auth_method %q requires a github.com URL; got %q", authMethod, project.GitURL))
+		}
+		buildProject.GitURL = converted
+		buildProject.DeployKey = ""
+	}
+	if env.Branch != "" {
+		buildProject.GitBranch = env.Branch
+	}
+	env.EnvVarList = mergeEnvVars(project.EnvVarList, env.EnvVarList)
+	buildProject.EnvVarList = env.EnvVarList
+
+	logWriter.Write([]byte(fmt.Sprintf("=== Environment %s: branch %s, port %d ===\n", env.Name, buildProject.GitBranch, env.Port)))
+
+	buildTimeout := s.buildTimeout(project)
+	ctx, cancel := context.WithTimeout(context.Background(), buildTimeout)
+	defer cancel()
+
+	dir := s.git.EnvironmentDir(projectID, envID)
+	result := s.builder.BuildInDir(ctx, &buildProject, dir, httpsToken, logWriter)
+	deployment.GitCommit = result.Commit
+	deployment.Duration = int(result.Duration.Seconds())
+	if !result.Success {
+		if ctx.Err() == context.DeadlineExceeded {
+			result.ErrorMsg = fmt.Sprintf("build timed out after %ds", int(buildTimeout/time.Second))
+		}
+		return fail(result.ErrorMsg)
+	}
+	GenerateEnvFile(dir, env.EnvVarList)
+
+	if project.StartCommand != "" {
+		logWriter.Write([]byte("\n=== Starting process ===\n"))
+		if err := s.proc.InstallEnvironment(&buildProject, env, dir); err != nil {
+			return fail(fmt.Sprintf("service install failed: %v", err))
+		}
+		if err := s.proc.RestartEnvironment(projectID, env.Name); err != nil {
+			return fail(fmt.Sprintf("service start failed: %v", err))
+		}
+		s.runHealthCheck(project, env.Port, logWriter)
+	}
+
+	deployment.Status = "success"
+	s.db.Save(deployment)
+	s.db.Model(&ProjectEnvironment{}).Where("id = ?", envID).Updates(map[string]interface{}{
+		"status":               "running",
+		"last_deployed_commit": result.Commit,
+	})
+
+	if env.Domain != "" && env.HostID == 0 && env.Port > 0 {
+		hostID, err := s.ensureHost(env.Domain, env.Port)
+		if err != nil {
+			s.logger.Error("create environment host failed", "project", project.Name, "env", env.Name, "error", err)
+		} else {
+			s.db.Model(&ProjectEnvironment{}).Where("id = ?", envID).Update("host_id", hostID)
+			s.coreAPI.ReloadCaddy()
+		}
+	} else {
+		s.syncHostUpstream(env.HostID, env.Port)
+	}
+
+	logWriter.Write([]byte(fmt.Sprintf("=== Environment %s deployed ===\n", env.Name)))
+	s.logger.Info("environment build completed", "project", project.Name, "env", env.Name, "build", buildNum)
+	return nil
+}
+
+// mergeEnvVars layers overrides on top of base; override keys win and
+// keep base ordering where they replace an existing key.
+func mergeEnvVars(base, overrides []EnvVar) []EnvVar {
+	merged := make([]EnvVar, 0, len(base)+len(overrides))
+	idx := make(map[string]int, len(base))
+	for _, ev := range base {
+		idx[ev.Key] = len(merged)
+		merged = append(merged, ev)
+	}
+	for _, ev := range overrides {
+		if i, ok := idx[ev.Key]; ok {
+			merged[i] = ev
+			continue
+		}
+		idx[ev.Key] = len(merged)
+		merged = append(merged, ev)
+	}
+	return merged
+}
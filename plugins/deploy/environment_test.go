@@ -0,0 +1,428 @@
+package deploy
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	pluginpkg "github.com/web-casa/webcasa/internal/plugin"
+)
+
+// recordingCoreAPI records host creation. Embedding the interface keeps
+// the stub small; any method the code under test doesn't expect panics.
+type recordingCoreAPI struct {
+	pluginpkg.CoreAPI
+
+	mu      sync.Mutex
+	hosts   []pluginpkg.CreateHostRequest
+	deleted []uint
+	updated []uint
+}
+
+func (r *recordingCoreAPI) CreateHost(req pluginpkg.CreateHostRequest) (uint, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.hosts = append(r.hosts, req)
+	return uint(len(r.hosts)), nil
+}
+
+func (r *recordingCoreAPI) DeleteHost(id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.deleted = append(r.deleted, id)
+	return nil
+}
+
+// UpdateHost edits the recorded host in place.
+func (r *recordingCoreAPI) UpdateHost(id uint, req pluginpkg.UpdateHostRequest) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	h := &r.hosts[id-1]
+	if req.Domain != "" {
+		h.Domain = req.Domain
+	}
+	if req.Upstream != "" {
+		h.UpstreamAddr = req.Upstream
+	}
+	r.updated = append(r.updated, id)
+	return nil
+}
+
+func (r *recordingCoreAPI) GetHost(id uint) (map[string]interface{}, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	h := r.hosts[id-1]
+	return map[string]interface{}{"id": id, "domain": h.Domain, "upstreams": []string{h.UpstreamAddr}}, nil
+}
+
+func (r *recordingCoreAPI) GetHostByDomain(domain string) (*pluginpkg.HostInfo, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, h := range r.hosts {
+		id := uint(i + 1)
+		if strings.EqualFold(h.Domain, domain) && !slices.Contains(r.deleted, id) {
+			return &pluginpkg.HostInfo{ID: id, Domain: h.Domain, HostType: "proxy", Upstreams: []string{h.UpstreamAddr}}, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *recordingCoreAPI) ReloadCaddy() error { return nil }
+
+// stubProcessManager returns a ProcessManager that writes units to a temp
+// dir and records systemctl invocations instead of running them.
+func stubProcessManager(t *testing.T) (*ProcessManager, *[]string) {
+	t.Helper()
+	var (
+		mu    sync.Mutex
+		calls []string
+	)
+	pm := NewProcessManager(t.TempDir())
+	pm.unitDir = t.TempDir()
+	pm.run = func(args ...string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		calls = append(calls, strings.Join(args, " "))
+		if args[0] == "is-active" {
+			return fmt.Errorf("inactive")
+		}
+		return nil
+	}
+	return pm, &calls
+}
+
+func waitEnvStatus(t *testing.T, s *Service, envID uint, want string) ProjectEnvironment {
+	t.Helper()
+	deadline := time.Now().Add(30 * time.Second)
+	var env ProjectEnvironment
+	for time.Now().Before(deadline) {
+		s.db.First(&env, envID)
+		if env.Status == want && !s.isEnvironmentBuildInflight(envID) {
+			return env
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("environment %d status = %q (err %q), want %q", envID, env.Status, env.ErrorMsg, want)
+	return env
+}
+
+func TestEnvironments_BuildTwoGetDistinctPortsAndHosts(t *testing.T) {
+	s := newBuildTestService(t)
+	s.db.AutoMigrate(&ProjectEnvironment{})
+	api := &recordingCoreAPI{}
+	s.coreAPI = api
+	s.ports = NewPortAllocator(10000, nil)
+	s.health = NewHealthChecker()
+	pm, _ := stubProcessManager(t)
+	s.proc = pm
+
+	project := &Project{
+		Name:               "app",
+		Domain:             "app.example.com",
+		GitURL:             initTestGitRepo(t, map[string]string{"server.sh": "echo hi"}),
+		GitBranch:          "main",
+		StartCommand:       "./server.sh",
+		WebhookToken:       "tok-envs",
+		DeployMode:         "bare",
+		HealthCheckTimeout: 1,
+		HealthCheckRetries: 1,
+		EnvVars:            `[{"key":"NODE_ENV","value":"production"},{"key":"SHARED","value":"1"}]`,
+	}
+	if err := s.db.Create(project).Error; err != nil {
+		t.Fatalf("create project: %v", err)
+	}
+
+	staging := &ProjectEnvironment{ProjectID: project.ID, Name: "staging", Domain: "staging.example.com",
+		EnvVarList: []EnvVar{{Key: "NODE_ENV", Value: "staging"}}}
+	production := &ProjectEnvironment{ProjectID: project.ID, Name: "production", Domain: "www.example.com"}
+	for _, env := range []*ProjectEnvironment{staging, production} {
+		if err := s.CreateEnvironment(env); err != nil {
+			t.Fatalf("create %s: %v", env.Name, err)
+		}
+	}
+	if staging.Port == 0 || production.Port == 0 || staging.Port == production.Port {
+		t.Fatalf("ports not distinct: staging=%d production=%d", staging.Port, production.Port)
+	}
+
+	for _, env := range []*ProjectEnvironment{staging, production} {
+		if err := s.BuildEnvironment(project.ID, env.ID); err != nil {
+			t.Fatalf("build %s: %v", env.Name, err)
+		}
+	}
+	gotStaging := waitEnvStatus(t, s, staging.ID, "running")
+	gotProd := waitEnvStatus(t, s, production.ID, "running")
+
+	api.mu.Lock()
+	hosts := append([]pluginpkg.CreateHostRequest(nil), api.hosts...)
+	api.mu.Unlock()
+	if len(hosts) != 2 {
+		t.Fatalf("created %d hosts, want 2: %+v", len(hosts), hosts)
+	}
+	upstreams := map[string]string{}
+	for _, h := range hosts {
+		upstreams[h.Domain] = h.UpstreamAddr
+	}
+	if upstreams["staging.example.com"] != fmt.Sprintf("localhost:%d", staging.Port) ||
+		upstreams["www.example.com"] != fmt.Sprintf("localhost:%d", production.Port) {
+		t.Fatalf("unexpected host upstreams: %v", upstreams)
+	}
+	if gotStaging.HostID == 0 || gotProd.HostID == 0 || gotStaging.HostID == gotProd.HostID {
+		t.Fatalf("host ids not recorded distinctly: staging=%d production=%d", gotStaging.HostID, gotProd.HostID)
+	}
+
+	// Each environment gets its own unit with its own PORT and merged env.
+	unit, err := os.ReadFile(filepath.Join(pm.unitDir, EnvironmentServiceName(project.ID, "staging")+".service"))
+	if err != nil {
+		t.Fatalf("read staging unit: %v", err)
+	}
+	for _, want := range []string{
+		fmt.Sprintf("Environment=PORT=%d", staging.Port),
+		"Environment=NODE_ENV=staging",
+		"Environment=SHARED=1",
+	} {
+		if !strings.Contains(string(unit), want) {
+			t.Errorf("staging unit missing %q:\n%s", want, unit)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(pm.unitDir, EnvironmentServiceName(project.ID, "production")+".service")); err != nil {
+		t.Fatalf("production unit missing: %v", err)
+	}
+
+	// Environment deployments stay out of the main project's history.
+	deps, _ := s.GetDeployments(project.ID)
+	if len(deps) != 0 {
+		t.Fatalf("main deployments = %d, want 0", len(deps))
+	}
+}
+
+func TestEnvironments_DomainAndPortChangesKeepHost(t *testing.T) {
+	s := newBuildTestService(t)
+	s.db.AutoMigrate(&ProjectEnvironment{})
+	api := &recordingCoreAPI{}
+	s.coreAPI = api
+	s.ports = NewPortAllocator(10000, nil)
+	s.health = NewHealthChecker()
+	s.proc, _ = stubProcessManager(t)
+
+	project := &Project{
+		Name:               "app",
+		GitURL:             initTestGitRepo(t, map[string]string{"server.sh": "echo hi"}),
+		GitBranch:          "main",
+		StartCommand:       "./server.sh",
+		WebhookToken:       "tok-rename",
+		DeployMode:         "bare",
+		HealthCheckTimeout: 1,
+		HealthCheckRetries: 1,
+	}
+	if err := s.db.Create(project).Error; err != nil {
+		t.Fatalf("create project: %v", err)
+	}
+	env := &ProjectEnvironment{ProjectID: project.ID, Name: "staging", Domain: "staging.example.com"}
+	if err := s.CreateEnvironment(env); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.BuildEnvironment(project.ID, env.ID); err != nil {
+		t.Fatal(err)
+	}
+	hostID := waitEnvStatus(t, s, env.ID, "running").HostID
+
+	newPort := env.Port + 100
+	if err := s.UpdateEnvironment(project.ID, env.ID, map[string]interface{}{
+		"domain": "qa.example.com",
+		"port":   newPort,
+	}); err != nil {
+		t.Fatalf("UpdateEnvironment: %v", err)
+	}
+	if err := s.BuildEnvironment(project.ID, env.ID); err != nil {
+		t.Fatal(err)
+	}
+	got := waitEnvStatus(t, s, env.ID, "running")
+
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	if got.HostID != hostID || len(api.hosts) != 1 || len(api.deleted) != 0 {
+		t.Fatalf("host recreated: id %d -> %d, created %d, deleted %v", hostID, got.HostID, len(api.hosts), api.deleted)
+	}
+	if h := api.hosts[0]; h.Domain != "qa.example.com" || h.UpstreamAddr != fmt.Sprintf("localhost:%d", newPort) {
+		t.Errorf("host = %s -> %s, want qa.example.com -> localhost:%d", h.Domain, h.UpstreamAddr, newPort)
+	}
+}
+
+func TestEnsureHost_LinksExistingHost(t *testing.T) {
+	s := newBuildTestService(t)
+	s.db.AutoMigrate(&ProjectEnvironment{})
+	api := &recordingCoreAPI{hosts: []pluginpkg.CreateHostRequest{
+		{Domain: "app.example.com", UpstreamAddr: "localhost:3000"},
+	}}
+	s.coreAPI = api
+
+	hostID, err := s.ensureHost("App.Example.com", 10001)
+	if err != nil {
+		t.Fatalf("ensureHost: %v", err)
+	}
+	if hostID != 1 || len(api.hosts) != 1 {
+		t.Fatalf("host %d, %d hosts; want existing host 1 linked", hostID, len(api.hosts))
+	}
+	if got := api.hosts[0].UpstreamAddr; got != "localhost:10001" {
+		t.Errorf("upstream = %q, want localhost:10001", got)
+	}
+
+	// A host another project already owns is a collision, not a link.
+	if err := s.db.Create(&Project{Name: "other", WebhookToken: "tok-other", HostID: hostID}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.ensureHost("app.example.com", 10002); err == nil {
+		t.Error("ensureHost linked a host owned by another project")
+	}
+
+	if hostID, err = s.ensureHost("new.example.com", 10003); err != nil || hostID != 2 {
+		t.Errorf("ensureHost(new) = %d, %v; want new host 2", hostID, err)
+	}
+}
+
+func TestCreateEnvironment_Validation(t *testing.T) {
+	s := newBuildTestService(t)
+	s.db.AutoMigrate(&ProjectEnvironment{})
+	s.ports = NewPortAllocator(10000, nil)
+
+	project := &Project{Name: "p", Domain: "p.example.com", WebhookToken: "tok-val"}
+	s.db.Create(project)
+	docker := &Project{Name: "d", DeployMode: "docker", WebhookToken: "tok-val-d"}
+	s.db.Create(docker)
+
+	cases := []struct {
+		name string
+		env  ProjectEnvironment
+	}{
+		{"bad name", ProjectEnvironment{ProjectID: project.ID, Name: "Staging!"}},
+		{"same domain as project", ProjectEnvironment{ProjectID: project.ID, Name: "staging", Domain: "p.example.com"}},
+		{"docker project", ProjectEnvironment{ProjectID: docker.ID, Name: "staging"}},
+		{"missing project", ProjectEnvironment{ProjectID: 999, Name: "staging"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			env := tc.env
+			if err := s.CreateEnvironment(&env); err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
+
+func TestUpdateEnvironment_Validation(t *testing.T) {
+	s := newBuildTestService(t)
+	s.db.AutoMigrate(&ProjectEnvironment{}, &PreviewDeployment{})
+	s.ports = NewPortAllocator(10000, s.db)
+	busy := 0
+	s.ports.probe = func(port int) bool { return port != busy }
+
+	project := &Project{Name: "p", Domain: "p.example.com", Port: 10001, WebhookToken: "tok-upd"}
+	s.db.Create(project)
+	staging := &ProjectEnvironment{ProjectID: project.ID, Name: "staging", Domain: "staging.example.com"}
+	qa := &ProjectEnvironment{ProjectID: project.ID, Name: "qa"}
+	for _, env := range []*ProjectEnvironment{staging, qa} {
+		if err := s.CreateEnvironment(env); err != nil {
+			t.Fatal(err)
+		}
+	}
+	busy = environmentPortBase + 500
+
+	if err := s.UpdateEnvironment(project.ID, 999, map[string]interface{}{"branch": "main"}); !errors.Is(err, ErrEnvironmentNotFound) {
+		t.Errorf("missing environment: err = %v, want ErrEnvironmentNotFound", err)
+	}
+	for name, updates := range map[string]map[string]interface{}{
+		"invalid domain":          {"domain": "bad domain"},
+		"project domain":          {"domain": "P.example.com"},
+		"other environment":       {"domain": "staging.example.com"},
+		"non-string domain":       {"domain": 5.0},
+		"out of range port":       {"port": 70000.0},
+		"fractional port":         {"port": 30001.5},
+		"other environment port":  {"port": float64(staging.Port)},
+		"project port":            {"port": float64(project.Port)},
+		"project alternate port":  {"port": float64(project.Port + portRangeSize)},
+		"port bound by a process": {"port": float64(busy)},
+	} {
+		if err := s.UpdateEnvironment(project.ID, qa.ID, updates); !errors.Is(err, ErrInvalidEnvironment) {
+			t.Errorf("%s: err = %v, want ErrInvalidEnvironment", name, err)
+		}
+	}
+
+	if err := s.UpdateEnvironment(project.ID, qa.ID, map[string]interface{}{"domain": "QA.Example.com", "port": 0.0}); err != nil {
+		t.Fatalf("valid update: %v", err)
+	}
+	got, _ := s.GetEnvironment(project.ID, qa.ID)
+	if got.Domain != "qa.example.com" || got.Port == 0 || got.Port == staging.Port || got.Port == busy {
+		t.Errorf("after update: domain %q port %d", got.Domain, got.Port)
+	}
+	// Keeping its own port is not a conflict with itself.
+	if err := s.UpdateEnvironment(project.ID, qa.ID, map[string]interface{}{"port": float64(got.Port)}); err != nil {
+		t.Errorf("unchanged port: %v", err)
+	}
+}
+
+func TestUpdateEnvironmentHandler_StatusCodes(t *testing.T) {
+	s := newBuildTestService(t)
+	s.db.AutoMigrate(&ProjectEnvironment{})
+	s.ports = NewPortAllocator(10000, nil)
+	project := &Project{Name: "p", Domain: "p.example.com", WebhookToken: "tok-upd-h"}
+	s.db.Create(project)
+	env := &ProjectEnvironment{ProjectID: project.ID, Name: "staging"}
+	if err := s.CreateEnvironment(env); err != nil {
+		t.Fatal(err)
+	}
+
+	r := gin.New()
+	r.PUT("/projects/:id/environments/:envId", NewHandler(s).UpdateEnvironment)
+	put := func(envID uint, body string) int {
+		req := httptest.NewRequest(http.MethodPut, fmt.Sprintf("/projects/%d/environments/%d", project.ID, envID), strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := put(999, `{"branch":"main"}`); code != http.StatusNotFound {
+		t.Errorf("missing environment: status %d, want 404", code)
+	}
+	if code := put(env.ID, `{"domain":"p.example.com"}`); code != http.StatusBadRequest {
+		t.Errorf("project domain: status %d, want 400", code)
+	}
+	if code := put(env.ID, `{"port":0}`); code != http.StatusOK {
+		t.Errorf("reassign port: status %d, want 200", code)
+	}
+}
+
+func TestAllocateEnvironmentPort_SkipsTakenPorts(t *testing.T) {
+	s := newBuildTestService(t)
+	s.db.AutoMigrate(&ProjectEnvironment{}, &PreviewDeployment{})
+	pa := NewPortAllocator(10000, s.db)
+	pa.probe = func(port int) bool { return port != environmentPortBase+1 }
+
+	if got := pa.AllocateEnvironmentPort(1); got != environmentPortBase+2 {
+		t.Fatalf("bound preferred port: got %d, want %d", got, environmentPortBase+2)
+	}
+	s.db.Create(&ProjectEnvironment{ProjectID: 1, Name: "other", Port: environmentPortBase + 3})
+	if got := pa.AllocateEnvironmentPort(3); got != environmentPortBase+4 {
+		t.Fatalf("port stored on another environment: got %d, want %d", got, environmentPortBase+4)
+	}
+}
+
+func TestMergeEnvVars(t *testing.T) {
+	got := mergeEnvVars(
+		[]EnvVar{{Key: "A", Value: "1"}, {Key: "B", Value: "2"}},
+		[]EnvVar{{Key: "B", Value: "override"}, {Key: "C", Value: "3"}},
+	)
+	want := []EnvVar{{Key: "A", Value: "1"}, {Key: "B", Value: "override"}, {Key: "C", Value: "3"}}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("mergeEnvVars = %v, want %v", got, want)
+	}
+}
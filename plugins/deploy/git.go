@@ -351,7 +351,17 @@ func sanitizeRemoteURL(u string) string {
 
 // GetCommitHash returns the current commit hash of the project directory.
 func (g *GitClient) GetCommitHash(projectID uint) (string, error) {
-	dir := g.ProjectDir(projectID)
+	return g.CommitHashIn(g.ProjectDir(projectID))
+}
+
+// EnvironmentDir returns the checkout directory for a project environment.
+// Environments keep their own checkout so each can track its own branch.
+func (g *GitClient) EnvironmentDir(projectID, envID uint) string {
+	return filepath.Join(g.workDir, fmt.Sprintf("project_%d_env_%d", projectID, envID))
+}
+
+// CommitHashIn returns the short HEAD commit hash of the checkout at dir.
+func (g *GitClient) CommitHashIn(dir string) (string, error) {
 	cmd := exec.Command("git", "rev-parse", "--short", "HEAD")
 	cmd.Dir = dir
 	out, err := cmd.Output()
@@ -9,7 +9,6 @@ import (
 	"log/slog"
 	"net/http"
 	"net/url"
-	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -269,8 +268,7 @@ func (s *GitHubOAuthService) exchangeCodeForToken(code string) (string, error) {
 
 // fetchInstallationInfo calls the GitHub API to get installation account details.
 func (s *GitHubOAuthService) fetchInstallationInfo(installationID int64) (*GitHubInstallation, error) {
-	appIDStr := s.configStore.Get("github_app_id")
-	appID, _ := strconv.ParseInt(appIDStr, 10, 64)
+	appID := int64(s.configStore.GetInt("github_app_id", 0))
 	if appID == 0 {
 		return nil, fmt.Errorf("github_app_id not configured")
 	}
@@ -406,8 +404,7 @@ func (s *GitHubOAuthService) ListRepos(installationID int64) ([]GitHubRepo, erro
 // GetInstallationToken obtains a GitHub App installation access token
 // using the globally configured App credentials.
 func (s *GitHubOAuthService) GetInstallationToken(installationID int64) (string, error) {
-	appIDStr := s.configStore.Get("github_app_id")
-	appID, _ := strconv.ParseInt(appIDStr, 10, 64)
+	appID := int64(s.configStore.GetInt("github_app_id", 0))
 	if appID == 0 {
 		return "", fmt.Errorf("github_app_id not configured")
 	}
@@ -2,6 +2,7 @@ package deploy
 
 import (
 	"bytes"
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"crypto/subtle"
@@ -11,11 +12,14 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"regexp"
 	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/web-casa/webcasa/internal/auth"
 	"github.com/web-casa/webcasa/plugins/deploy/builders"
 )
 
@@ -52,6 +56,43 @@ func validateForkRefSHA(ref, sha string) error {
 	return nil
 }
 
+// pushEvent is the subset of a GitHub / GitLab / Gitea push payload the
+// webhook needs. All three use `ref` + `after`; GitLab also sends
+// `checkout_sha`, which is empty when the push deleted the branch.
+type pushEvent struct {
+	Ref     string
+	Commit  string // validated hex SHA, or "" if absent/malformed
+	Deleted bool
+}
+
+// parsePushPayload extracts the pushed ref and head commit. Unparseable
+// bodies yield a zero pushEvent so the caller falls back to building.
+func parsePushPayload(body []byte) pushEvent {
+	var payload struct {
+		Ref         string `json:"ref"`
+		After       string `json:"after"`
+		CheckoutSHA string `json:"checkout_sha"`
+		Deleted     bool   `json:"deleted"`
+	}
+	if len(body) == 0 || json.Unmarshal(body, &payload) != nil {
+		return pushEvent{}
+	}
+	ev := pushEvent{Ref: payload.Ref, Deleted: payload.Deleted}
+	sha := payload.After
+	if sha == "" {
+		sha = payload.CheckoutSHA
+	}
+	if strings.Trim(sha, "0") == "" && sha != "" {
+		// All-zero `after` marks a branch deletion on every forge.
+		ev.Deleted = true
+		sha = ""
+	}
+	if validHeadSHA.MatchString(sha) {
+		ev.Commit = strings.ToLower(sha)
+	}
+	return ev
+}
+
 // Handler provides HTTP handlers for the deploy plugin API.
 type Handler struct {
 	svc *Service
@@ -115,6 +156,7 @@ func (h *Handler) CreateProject(c *gin.Context) {
 		MemoryLimit        int      `json:"memory_limit"`
 		CPULimit           int      `json:"cpu_limit"`
 		BuildTimeout       int      `json:"build_timeout"`
+		CacheEnabled       bool     `json:"cache_enabled"`
 		// GitHub App auth fields
 		AuthMethod           string `json:"auth_method"`
 		GitHubAppID          int64  `json:"github_app_id"`
@@ -123,6 +165,8 @@ func (h *Handler) CreateProject(c *gin.Context) {
 		// GitHub OAuth fields
 		GitHubOAuthInstallID uint   `json:"github_oauth_install_id"`
 		GitHubRepoFullName   string `json:"github_repo_full_name"`
+		// Optional HMAC secret for GitHub signatures / GitLab token
+		WebhookSecret string `json:"webhook_secret"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -133,6 +177,13 @@ func (h *Handler) CreateProject(c *gin.Context) {
 	if branch == "" {
 		branch = "main"
 	}
+	if req.BuildTimeout < 0 || req.BuildTimeout > maxBuildTimeoutMinutes {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("build_timeout must be between 0 and %d minutes", maxBuildTimeoutMinutes)})
+		return
+	}
+	if req.BuildTimeout == 0 {
+		req.BuildTimeout = defaultBuildTimeoutMinutes
+	}
 
 	project := &Project{
 		Name:                 req.Name,
@@ -154,12 +205,14 @@ func (h *Handler) CreateProject(c *gin.Context) {
 		MemoryLimit:          req.MemoryLimit,
 		CPULimit:             req.CPULimit,
 		BuildTimeout:         req.BuildTimeout,
+		CacheEnabled:         req.CacheEnabled,
 		AuthMethod:           req.AuthMethod,
 		GitHubAppID:          req.GitHubAppID,
 		GitHubPrivateKey:     req.GitHubPrivateKey,
 		GitHubInstallationID: req.GitHubInstallationID,
 		GitHubOAuthInstallID: req.GitHubOAuthInstallID,
 		GitHubRepoFullName:   req.GitHubRepoFullName,
+		WebhookSecret:        req.WebhookSecret,
 	}
 
 	if err := h.svc.CreateProject(project); err != nil {
@@ -193,7 +246,7 @@ func (h *Handler) UpdateProject(c *gin.Context) {
 		"health_check_method": true, "health_check_expect_code": true,
 		"health_check_expect_body": true, "health_check_start_period": true,
 		"memory_limit": true, "cpu_limit": true, "build_timeout": true, "build_type": true,
-		"auth_method": true, "github_app_id": true, "webhook_secret": true,
+		"auth_method": true, "github_app_id": true, "webhook_secret": true, "keep_releases": true, "cache_enabled": true,
 		"github_private_key": true, "github_installation_id": true,
 		"github_oauth_install_id": true, "github_repo_full_name": true,
 		"preview_enabled": true, "preview_expiry": true, "github_token": true,
@@ -236,6 +289,28 @@ func (h *Handler) UpdateProject(c *gin.Context) {
 		filtered["git_poll_interval_sec"] = n
 	}
 
+	// build_timeout is minutes; 0 resets to the default.
+	if bt, ok := filtered["build_timeout"]; ok {
+		n, isNum := bt.(float64)
+		if !isNum || n < 0 || n > maxBuildTimeoutMinutes {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("build_timeout must be between 0 and %d minutes", maxBuildTimeoutMinutes)})
+			return
+		}
+		if n == 0 {
+			n = defaultBuildTimeoutMinutes
+		}
+		filtered["build_timeout"] = int(n)
+	}
+
+	if kr, ok := filtered["keep_releases"]; ok {
+		n, isNum := kr.(float64)
+		if !isNum || n < 1 || n > 50 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "keep_releases must be between 1 and 50"})
+			return
+		}
+		filtered["keep_releases"] = int(n)
+	}
+
 	// Handle env_vars specially: convert from JSON array to []EnvVar then to JSON string
 	if raw, ok := filtered["env_vars"]; ok {
 		data, _ := json.Marshal(raw)
@@ -389,6 +464,103 @@ func (h *Handler) GetBuildLog(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"log": log, "type": "build", "build_num": buildNum})
 }
 
+var wsUpgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			return true
+		}
+		u, err := url.Parse(origin)
+		if err != nil {
+			return false
+		}
+		return u.Host == r.Host
+	},
+}
+
+// BuildLogWS GET /api/plugins/deploy/projects/:id/logs/ws
+// Streams the in-progress build log line by line (backlog first, then
+// live writes) and closes once the build finishes. With no active build
+// the most recent build log is sent and the socket closed.
+func (h *Handler) BuildLogWS(c *gin.Context) {
+	conn, err := wsUpgrader.Upgrade(c.Writer, c.Request, auth.WSUpgradeResponseHeader(c))
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		conn.WriteMessage(websocket.TextMessage, []byte("Error: invalid id"))
+		return
+	}
+
+	backlog, live, cancelStream, err := h.svc.OpenBuildLogStream(uint(id))
+	if err != nil {
+		conn.WriteMessage(websocket.TextMessage, []byte("Error: "+err.Error()))
+		return
+	}
+	defer cancelStream()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Detect client disconnect.
+	go func() {
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				cancel()
+				return
+			}
+		}
+	}()
+
+	// Writes arrive in arbitrary chunks; buffer partial lines so each
+	// message is exactly one log line.
+	var pending []byte
+	emit := func(chunk []byte) bool {
+		pending = append(pending, chunk...)
+		for {
+			i := bytes.IndexByte(pending, '\n')
+			if i < 0 {
+				return true
+			}
+			if err := conn.WriteMessage(websocket.TextMessage, pending[:i]); err != nil {
+				return false
+			}
+			pending = pending[i+1:]
+		}
+	}
+	flush := func() {
+		if len(pending) > 0 {
+			conn.WriteMessage(websocket.TextMessage, pending)
+		}
+		conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, "build finished"))
+	}
+
+	if !emit(backlog) {
+		return
+	}
+	if live == nil {
+		flush()
+		return
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case chunk, ok := <-live:
+			if !ok {
+				flush()
+				return
+			}
+			if !emit(chunk) {
+				return
+			}
+		}
+	}
+}
+
 // Webhook POST /api/plugins/deploy/webhook/:token
 func (h *Handler) Webhook(c *gin.Context) {
 	token := c.Param("token")
@@ -400,6 +572,10 @@ func (h *Handler) Webhook(c *gin.Context) {
 		return
 	}
 
+	body, _ := io.ReadAll(io.LimitReader(c.Request.Body, 1024*1024)) // 1MB cap
+	// Restore body so downstream code can re-read it.
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
 	// Verify webhook signature if the project has a webhook secret configured.
 	if project.WebhookSecret != "" {
 		// Decrypt the stored secret (it's AES-GCM encrypted).
@@ -409,10 +585,6 @@ func (h *Handler) Webhook(c *gin.Context) {
 			return
 		}
 
-		body, _ := io.ReadAll(io.LimitReader(c.Request.Body, 1024*1024)) // 1MB cap
-		// Restore body so downstream code can re-read it.
-		c.Request.Body = io.NopCloser(bytes.NewReader(body))
-
 		verified := false
 
 		// GitHub: HMAC-SHA256 signature in X-Hub-Signature-256 header.
@@ -448,12 +620,38 @@ func (h *Handler) Webhook(c *gin.Context) {
 		return
 	}
 
+	// GitHub sends a ping when the hook is first saved; acknowledge it
+	// without building so the delivery shows green in the repo settings.
+	if c.GetHeader("X-GitHub-Event") == "ping" {
+		c.JSON(http.StatusOK, gin.H{"ok": true, "message": "pong"})
+		return
+	}
+
 	if !project.AutoDeploy {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "auto-deploy is disabled"})
 		return
 	}
 
-	if err := h.svc.Build(project.ID); err != nil {
+	// Only build pushes to the watched branch. Bodies without a ref
+	// (manual curl triggers, CI "deploy now" calls) keep the historical
+	// build-on-any-POST behaviour.
+	push := parsePushPayload(body)
+	if push.Ref != "" {
+		watched := project.GitBranch
+		if watched == "" {
+			watched = "main"
+		}
+		if push.Ref != "refs/heads/"+watched {
+			c.JSON(http.StatusOK, gin.H{"ok": true, "message": "push to " + push.Ref + " ignored; watching " + watched})
+			return
+		}
+		if push.Deleted {
+			c.JSON(http.StatusOK, gin.H{"ok": true, "message": "branch deletion ignored"})
+			return
+		}
+	}
+
+	if err := h.svc.BuildForCommit(project.ID, push.Commit); err != nil {
 		if errors.Is(err, ErrBuildCoalesced) {
 			c.JSON(http.StatusOK, gin.H{"ok": true, "message": "build coalesced into queued request"})
 			return
@@ -1018,6 +1216,151 @@ func (h *Handler) DeleteCronJob(c *gin.Context) {
 
 // ---- ExtraProcess Handlers ----
 
+// ListEnvironments GET /api/plugins/deploy/projects/:id/environments
+func (h *Handler) ListEnvironments(c *gin.Context) {
+	id, err := parseUintParam(c, "id")
+	if err != nil {
+		return
+	}
+	envs, err := h.svc.ListEnvironments(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, envs)
+}
+
+// CreateEnvironment POST /api/plugins/deploy/projects/:id/environments
+func (h *Handler) CreateEnvironment(c *gin.Context) {
+	id, err := parseUintParam(c, "id")
+	if err != nil {
+		return
+	}
+	var req struct {
+		Name    string   `json:"name" binding:"required"`
+		Domain  string   `json:"domain"`
+		Branch  string   `json:"branch"`
+		Port    int      `json:"port"`
+		EnvVars []EnvVar `json:"env_vars"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Branch != "" && (!validHeadRef.MatchString(req.Branch) || strings.HasPrefix(req.Branch, "-")) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid branch name"})
+		return
+	}
+	env := &ProjectEnvironment{
+		ProjectID:  id,
+		Name:       req.Name,
+		Domain:     req.Domain,
+		Branch:     req.Branch,
+		Port:       req.Port,
+		EnvVarList: req.EnvVars,
+	}
+	if err := h.svc.CreateEnvironment(env); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, env)
+}
+
+// UpdateEnvironment PUT /api/plugins/deploy/projects/:id/environments/:envId
+func (h *Handler) UpdateEnvironment(c *gin.Context) {
+	id, err := parseUintParam(c, "id")
+	if err != nil {
+		return
+	}
+	envID, err := parseUintParam(c, "envId")
+	if err != nil {
+		return
+	}
+	var req map[string]interface{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	allowed := map[string]bool{"domain": true, "branch": true, "port": true, "env_vars": true}
+	filtered := make(map[string]interface{})
+	for k, v := range req {
+		if allowed[k] {
+			filtered[k] = v
+		}
+	}
+	if b, ok := filtered["branch"]; ok {
+		bs, isStr := b.(string)
+		if !isStr || (bs != "" && (!validHeadRef.MatchString(bs) || strings.HasPrefix(bs, "-"))) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid branch name"})
+			return
+		}
+	}
+	if raw, ok := filtered["env_vars"]; ok {
+		data, _ := json.Marshal(raw)
+		var envVars []EnvVar
+		if err := json.Unmarshal(data, &envVars); err == nil {
+			filtered["env_vars"] = envVars
+		}
+	}
+	if err := h.svc.UpdateEnvironment(id, envID, filtered); err != nil {
+		switch {
+		case errors.Is(err, ErrEnvironmentNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, ErrInvalidEnvironment):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// DeleteEnvironment DELETE /api/plugins/deploy/projects/:id/environments/:envId
+func (h *Handler) DeleteEnvironment(c *gin.Context) {
+	id, err := parseUintParam(c, "id")
+	if err != nil {
+		return
+	}
+	envID, err := parseUintParam(c, "envId")
+	if err != nil {
+		return
+	}
+	if err := h.svc.DeleteEnvironment(id, envID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// BuildEnvironment POST /api/plugins/deploy/projects/:id/environments/:envId/build
+func (h *Handler) BuildEnvironment(c *gin.Context) {
+	id, err := parseUintParam(c, "id")
+	if err != nil {
+		return
+	}
+	envID, err := parseUintParam(c, "envId")
+	if err != nil {
+		return
+	}
+	if err := h.svc.BuildEnvironment(id, envID); err != nil {
+		if errors.Is(err, ErrBuildCoalesced) {
+			c.JSON(http.StatusOK, gin.H{"ok": true, "message": "build coalesced into queued request"})
+			return
+		}
+		if errors.Is(err, ErrBuildQueueFull) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"error":   err.Error(),
+				"message": "panel is at concurrent-build capacity; retry shortly",
+			})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true, "message": "build started"})
+}
+
 // ListExtraProcesses GET /api/plugins/deploy/projects/:id/processes
 func (h *Handler) ListExtraProcesses(c *gin.Context) {
 	id, err := parseUintParam(c, "id")
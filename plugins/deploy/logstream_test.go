@@ -0,0 +1,116 @@
+package deploy
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+func dialBuildLogWS(t *testing.T, s *Service, projectID uint) *websocket.Conn {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/projects/:id/logs/ws", NewHandler(s).BuildLogWS)
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + fmt.Sprintf("/projects/%d/logs/ws", projectID)
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// readAllLines reads text messages until the server closes the socket.
+func readAllLines(t *testing.T, conn *websocket.Conn) []string {
+	t.Helper()
+	var lines []string
+	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	for {
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			if !websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+				t.Fatalf("read: %v (got %q so far)", err, lines)
+			}
+			return lines
+		}
+		lines = append(lines, string(msg))
+	}
+}
+
+// TestBuildLogWS_StreamsActiveBuildInOrder simulates a build writing
+// lines (some before the client connects, some split across writes) and
+// asserts every line arrives in order and the socket closes once the
+// writer is torn down.
+func TestBuildLogWS_StreamsActiveBuildInOrder(t *testing.T) {
+	s := newBuildTestService(t)
+	logPath := t.TempDir() + "/build_1.log"
+	lw, err := NewLogWriter(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.activeLogs[7] = lw
+	lw.Write([]byte("==> step 0\n"))
+
+	conn := dialBuildLogWS(t, s, 7)
+
+	// Wait for the handler to subscribe before writing live lines so the
+	// non-blocking broadcast can't race the subscription.
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		lw.mu.Lock()
+		n := len(lw.subscribers)
+		lw.mu.Unlock()
+		if n > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("handler never subscribed")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	go func() {
+		for i := 1; i <= 20; i++ {
+			lw.Write([]byte(fmt.Sprintf("==> step %d\n", i)))
+		}
+		lw.Write([]byte("partial "))
+		lw.Write([]byte("line\n"))
+		lw.Close()
+		s.mu.Lock()
+		delete(s.activeLogs, 7)
+		s.mu.Unlock()
+	}()
+
+	lines := readAllLines(t, conn)
+	var want []string
+	for i := 0; i <= 20; i++ {
+		want = append(want, fmt.Sprintf("==> step %d", i))
+	}
+	want = append(want, "partial line")
+	if strings.Join(lines, "|") != strings.Join(want, "|") {
+		t.Fatalf("lines = %q\nwant    %q", lines, want)
+	}
+}
+
+func TestBuildLogWS_NoActiveBuildSendsLastLog(t *testing.T) {
+	s := newBuildTestService(t)
+	project := &Project{Name: "ws", CurrentBuild: 3, WebhookToken: "tok-ws"}
+	s.db.Create(project)
+	os.MkdirAll(s.builder.LogDir(project.ID), 0755)
+	os.WriteFile(s.builder.LogPath(project.ID, 3), []byte("a\nb\nc"), 0644)
+
+	conn := dialBuildLogWS(t, s, project.ID)
+	lines := readAllLines(t, conn)
+	if strings.Join(lines, "|") != "a|b|c" {
+		t.Fatalf("lines = %q, want [a b c]", lines)
+	}
+}
@@ -9,6 +9,8 @@ import (
 type LogWriter struct {
 	mu          sync.Mutex
 	file        *os.File
+	path        string
+	closed      bool
 	subscribers []chan []byte
 }
 
@@ -21,7 +23,7 @@ func NewLogWriter(path string) (*LogWriter, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &LogWriter{file: f}, nil
+	return &LogWriter{file: f, path: path}, nil
 }
 
 // Write implements io.Writer.
@@ -58,6 +60,25 @@ func (lw *LogWriter) Subscribe() chan []byte {
 	return ch
 }
 
+// SubscribeWithBacklog atomically returns everything written so far and a
+// channel for subsequent writes, so a late viewer sees neither gaps nor
+// duplicates. If the writer is already closed the channel is closed too.
+func (lw *LogWriter) SubscribeWithBacklog() ([]byte, chan []byte) {
+	lw.mu.Lock()
+	defer lw.mu.Unlock()
+	var backlog []byte
+	if lw.path != "" {
+		backlog, _ = os.ReadFile(lw.path)
+	}
+	ch := make(chan []byte, 64)
+	if lw.closed {
+		close(ch)
+		return backlog, ch
+	}
+	lw.subscribers = append(lw.subscribers, ch)
+	return backlog, ch
+}
+
 // Unsubscribe removes a subscriber channel.
 func (lw *LogWriter) Unsubscribe(ch chan []byte) {
 	lw.mu.Lock()
@@ -80,6 +101,7 @@ func (lw *LogWriter) Close() error {
 		close(ch)
 	}
 	lw.subscribers = nil
+	lw.closed = true
 	if lw.file != nil {
 		return lw.file.Close()
 	}
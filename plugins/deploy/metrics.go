@@ -0,0 +1,38 @@
+package deploy
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"gorm.io/gorm"
+)
+
+var buildsDesc = prometheus.NewDesc(
+	"webcasa_deploy_builds",
+	"Recorded deployments by build status.",
+	[]string{"status"}, nil,
+)
+
+// buildCollector reports deployment counts per status for /api/metrics.
+type buildCollector struct {
+	db *gorm.DB
+}
+
+func (bc *buildCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- buildsDesc
+}
+
+func (bc *buildCollector) Collect(ch chan<- prometheus.Metric) {
+	var rows []struct {
+		Status string
+		Count  int64
+	}
+	if err := bc.db.Model(&Deployment{}).
+		Select("status, COUNT(*) AS count").
+		Group("status").
+		Scan(&rows).Error; err != nil {
+		ch <- prometheus.NewInvalidMetric(buildsDesc, err)
+		return
+	}
+	for _, r := range rows {
+		ch <- prometheus.MustNewConstMetric(buildsDesc, prometheus.GaugeValue, float64(r.Count), r.Status)
+	}
+}
@@ -0,0 +1,33 @@
+package deploy
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestBuildCollector_CountsByStatus(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	if err := db.AutoMigrate(&Deployment{}); err != nil {
+		t.Fatal(err)
+	}
+	for i, status := range []string{"success", "success", "failed"} {
+		db.Create(&Deployment{ProjectID: 1, BuildNum: i + 1, Status: status})
+	}
+
+	want := `
+# HELP webcasa_deploy_builds Recorded deployments by build status.
+# TYPE webcasa_deploy_builds gauge
+webcasa_deploy_builds{status="failed"} 1
+webcasa_deploy_builds{status="success"} 2
+`
+	if err := testutil.CollectAndCompare(&buildCollector{db: db}, strings.NewReader(want)); err != nil {
+		t.Error(err)
+	}
+}
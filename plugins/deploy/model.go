@@ -48,6 +48,13 @@ type Project struct {
 	CPULimit     int `gorm:"default:0" json:"cpu_limit"`      // percentage (100 = 1 core), 0 = unlimited
 	BuildTimeout int `gorm:"default:30" json:"build_timeout"` // minutes
 
+	// Bare-mode release snapshots kept for rollback (see ReleaseManager)
+	KeepReleases int `gorm:"default:5" json:"keep_releases"`
+
+	// Preserve dependency dirs (node_modules, vendor, ...) between builds,
+	// invalidated when the lockfile changes
+	CacheEnabled bool `gorm:"default:false" json:"cache_enabled"`
+
 	// GitHub App authentication fields
 	AuthMethod           string `gorm:"size:32;default:ssh_key" json:"auth_method"` // ssh_key | github_app | github_oauth
 	GitHubAppID          int64  `gorm:"default:0" json:"github_app_id"`
@@ -95,6 +102,9 @@ type Project struct {
 	HasGitHubKey   bool     `gorm:"-" json:"has_github_private_key"` // indicates if GitHub App key is set
 	HasGitHubToken bool     `gorm:"-" json:"has_github_token"`       // PB-R1-L1: indicates github_token is set (for PR-comment UI placeholder)
 	WebhookURL     string   `gorm:"-" json:"webhook_url,omitempty"`  // populated only for admin detail view
+	Queued         bool     `gorm:"-" json:"queued"`                 // a coalesced rebuild is waiting behind the in-flight build
+
+	Environments []ProjectEnvironment `gorm:"-" json:"environments,omitempty"` // populated by ListProjects
 }
 
 func (Project) TableName() string {
@@ -115,6 +125,36 @@ type EnvVar struct {
 	Secret bool   `json:"secret,omitempty"`
 }
 
+// ProjectEnvironment is an additional named deploy target for a project
+// (e.g. staging next to production). Each environment checks out its own
+// branch into its own source dir, runs as its own systemd service on its
+// own port, and gets its own reverse-proxy host. Bare mode only.
+//
+// EnvVars holds overrides layered on top of the project's env vars; keys
+// set here win.
+type ProjectEnvironment struct {
+	ID                 uint      `gorm:"primaryKey" json:"id"`
+	ProjectID          uint      `gorm:"not null;uniqueIndex:ux_project_env_name" json:"project_id"`
+	Name               string    `gorm:"size:32;not null;uniqueIndex:ux_project_env_name" json:"name"` // staging, production, ...
+	Domain             string    `gorm:"size:255" json:"domain"`
+	Branch             string    `gorm:"size:128" json:"branch"` // empty = project's GitBranch
+	EnvVars            string    `gorm:"type:text" json:"-"`     // JSON-encoded []EnvVar overrides
+	Port               int       `gorm:"default:0" json:"port"`  // auto-assigned if 0
+	HostID             uint      `gorm:"default:0" json:"host_id"`
+	Status             string    `gorm:"size:32;default:pending" json:"status"` // pending, building, running, stopped, error
+	CurrentBuild       int       `gorm:"default:0" json:"current_build"`
+	LastDeployedCommit string    `gorm:"size:64" json:"last_deployed_commit"`
+	ErrorMsg           string    `gorm:"type:text" json:"error_msg"`
+	CreatedAt          time.Time `json:"created_at"`
+	UpdatedAt          time.Time `json:"updated_at"`
+
+	EnvVarList []EnvVar `gorm:"-" json:"env_vars,omitempty"`
+}
+
+func (ProjectEnvironment) TableName() string {
+	return "plugin_deploy_project_environments"
+}
+
 // Deployment records one build/deploy attempt.
 type Deployment struct {
 	ID              uint      `gorm:"primaryKey" json:"id"`
@@ -123,9 +163,10 @@ type Deployment struct {
 	GitCommit       string    `gorm:"size:64" json:"git_commit"`
 	Status          string    `gorm:"size:32;default:building" json:"status"` // building, success, failed, rolled_back
 	LogFile         string    `gorm:"size:512" json:"log_file"`
-	Duration        int       `json:"duration"`                                    // seconds
-	DiagnosisResult string    `gorm:"type:text" json:"diagnosis_result,omitempty"` // AI diagnosis of build failure
-	ImageTag        string    `gorm:"size:128" json:"image_tag,omitempty"`         // Docker image tag for rollback
+	Duration        int       `json:"duration"`                                        // seconds
+	DiagnosisResult string    `gorm:"type:text" json:"diagnosis_result,omitempty"`     // AI diagnosis of build failure
+	ImageTag        string    `gorm:"size:128" json:"image_tag,omitempty"`             // Docker image tag for rollback
+	EnvironmentID   uint      `gorm:"index;default:0" json:"environment_id,omitempty"` // 0 = the project's main deployment
 	CreatedAt       time.Time `json:"created_at"`
 }
 
@@ -266,6 +307,22 @@ var frameworkPresets = map[string]FrameworkPreset{
 		Name: "Django", Framework: "django",
 		InstallCmd: "pip install -r requirements.txt", BuildCmd: "python manage.py collectstatic --noinput", StartCmd: "gunicorn config.wsgi:application", Port: 8000,
 	},
+	"fastapi": {
+		Name: "FastAPI", Framework: "fastapi",
+		InstallCmd: "pip install -r requirements.txt", BuildCmd: "", StartCmd: "uvicorn main:app --host 127.0.0.1 --port 8000", Port: 8000,
+	},
+	"rust": {
+		Name: "Rust", Framework: "rust",
+		InstallCmd: "", BuildCmd: "cargo build --release", StartCmd: "./target/release/app", Port: 8080,
+	},
+	"deno": {
+		Name: "Deno", Framework: "deno",
+		InstallCmd: "deno install", BuildCmd: "", StartCmd: "deno run -A main.ts", Port: 8000,
+	},
+	"bun": {
+		Name: "Bun", Framework: "bun",
+		InstallCmd: "bun install", BuildCmd: "", StartCmd: "bun run index.ts", Port: 3000,
+	},
 	"dockerfile": {
 		Name: "Dockerfile", Framework: "dockerfile",
 		InstallCmd: "", BuildCmd: "", StartCmd: "", Port: 0, // handled by Docker
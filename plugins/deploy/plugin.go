@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/web-casa/webcasa/internal/metrics"
 	pluginpkg "github.com/web-casa/webcasa/internal/plugin"
 )
 
@@ -52,7 +53,7 @@ func (p *Plugin) Init(ctx *pluginpkg.Context) error {
 	}
 
 	// Migrate models
-	if err := ctx.DB.AutoMigrate(&Project{}, &Deployment{}, &PreviewDeployment{}, &CronJob{}, &ExtraProcess{}, &GitHubInstallation{}); err != nil {
+	if err := ctx.DB.AutoMigrate(&Project{}, &Deployment{}, &PreviewDeployment{}, &CronJob{}, &ExtraProcess{}, &GitHubInstallation{}, &ProjectEnvironment{}); err != nil {
 		return fmt.Errorf("migrate: %w", err)
 	}
 
@@ -77,6 +78,10 @@ func (p *Plugin) Init(ctx *pluginpkg.Context) error {
 	p.svc = NewService(ctx.DB, ctx.CoreAPI, ctx.EventBus, ctx.Logger, ctx.DataDir, jwtSecret, ctx.ConfigStore)
 	p.handler = NewHandler(p.svc)
 
+	if err := metrics.Register(&buildCollector{db: ctx.DB}); err != nil {
+		ctx.Logger.Warn("failed to register deploy metrics", "err", err)
+	}
+
 	// Register API routes under /api/plugins/deploy/
 	r := ctx.Router         // read-only (any authenticated user)
 	o := ctx.OperatorRouter // operator+ (operations: build/start/stop)
@@ -128,9 +133,17 @@ func (p *Plugin) Init(ctx *pluginpkg.Context) error {
 	a.DELETE("/projects/:id/processes/:procId", p.handler.DeleteExtraProcess)
 	a.POST("/projects/:id/processes/:procId/restart", p.handler.RestartExtraProcess)
 
+	// Environments (staging/production/...): admin config, operator builds
+	r.GET("/projects/:id/environments", p.handler.ListEnvironments)
+	a.POST("/projects/:id/environments", p.handler.CreateEnvironment)
+	a.PUT("/projects/:id/environments/:envId", p.handler.UpdateEnvironment)
+	a.DELETE("/projects/:id/environments/:envId", p.handler.DeleteEnvironment)
+	o.POST("/projects/:id/environments/:envId/build", p.handler.BuildEnvironment)
+
 	// Deployments & logs (read)
 	r.GET("/projects/:id/deployments", p.handler.GetDeployments)
 	r.GET("/projects/:id/logs", p.handler.GetBuildLog)
+	r.GET("/projects/:id/logs/ws", p.handler.BuildLogWS)
 
 	// Preview deployments (v0.14+). Webhook is unauthenticated (signed);
 	// list + log are read-only; delete is admin because it tears down
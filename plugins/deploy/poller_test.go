@@ -21,6 +21,10 @@ func openPollerTestDB(t *testing.T) *gorm.DB {
 	if err != nil {
 		t.Fatalf("open sqlite: %v", err)
 	}
+	// Each connection to ":memory:" is a separate database; keep one so
+	// background builds see the tables migrated here.
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
 	if err := db.AutoMigrate(&Project{}, &Deployment{}); err != nil {
 		t.Fatalf("migrate: %v", err)
 	}
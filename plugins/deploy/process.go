@@ -36,11 +36,22 @@ var svcTmpl = template.Must(template.New("service").Parse(serviceTemplate))
 // ProcessManager manages project processes via systemd.
 type ProcessManager struct {
 	logDir string
+
+	// unitDir is where unit files are written; run invokes systemctl.
+	// Both are swappable so tests can exercise unit rendering and
+	// lifecycle ordering without a live systemd.
+	unitDir string
+	run     func(args ...string) error
 }
 
 // NewProcessManager creates a new process manager.
 func NewProcessManager(logDir string) *ProcessManager {
-	return &ProcessManager{logDir: logDir}
+	return &ProcessManager{logDir: logDir, unitDir: "/etc/systemd/system", run: systemctl}
+}
+
+// isActive reports whether a unit is active.
+func (pm *ProcessManager) isActive(serviceName string) bool {
+	return pm.run("is-active", "--quiet", serviceName) == nil
 }
 
 // ServiceName returns the systemd service name for a project.
@@ -50,12 +61,12 @@ func ServiceName(projectID uint) string {
 
 // Install creates and enables a systemd service for the project.
 func (pm *ProcessManager) Install(project *Project, workDir string) error {
-	return pm.installService(ServiceName(project.ID), project, workDir, project.Port)
+	return pm.installService(ServiceName(project.ID), project, workDir, project.Port, pm.RuntimeLogPath(project.ID))
 }
 
 // InstallStaging creates a staging systemd service with a custom port for zero-downtime deployment.
 func (pm *ProcessManager) InstallStaging(project *Project, workDir string, port int) error {
-	return pm.installService(ServiceName(project.ID)+"-staging", project, workDir, port)
+	return pm.installService(ServiceName(project.ID)+"-staging", project, workDir, port, pm.RuntimeLogPath(project.ID))
 }
 
 // PromoteStaging stops the main service, removes it, renames the staging service, and reloads systemd.
@@ -64,48 +75,47 @@ func (pm *ProcessManager) PromoteStaging(projectID uint) error {
 	stagingName := mainName + "-staging"
 
 	// Stop and remove old main service
-	systemctl("stop", mainName)
-	systemctl("disable", mainName)
-	mainUnitPath := filepath.Join("/etc/systemd/system", mainName+".service")
+	pm.run("stop", mainName)
+	pm.run("disable", mainName)
+	mainUnitPath := filepath.Join(pm.unitDir, mainName+".service")
 	os.Remove(mainUnitPath)
 
 	// Rename staging service file to main
-	stagingUnitPath := filepath.Join("/etc/systemd/system", stagingName+".service")
+	stagingUnitPath := filepath.Join(pm.unitDir, stagingName+".service")
 	os.Rename(stagingUnitPath, mainUnitPath)
 
 	// Reload systemd to recognize the renamed file
-	if err := systemctl("daemon-reload"); err != nil {
+	if err := pm.run("daemon-reload"); err != nil {
 		return err
 	}
 
 	// Enable the service under its canonical name
-	return systemctl("enable", mainName)
+	return pm.run("enable", mainName)
 }
 
 // CleanupStaging removes a staging service if it exists (on failure).
 func (pm *ProcessManager) CleanupStaging(projectID uint) {
 	stagingName := ServiceName(projectID) + "-staging"
-	systemctl("stop", stagingName)
-	systemctl("disable", stagingName)
-	unitPath := filepath.Join("/etc/systemd/system", stagingName+".service")
+	pm.run("stop", stagingName)
+	pm.run("disable", stagingName)
+	unitPath := filepath.Join(pm.unitDir, stagingName+".service")
 	os.Remove(unitPath)
-	systemctl("daemon-reload")
+	pm.run("daemon-reload")
 }
 
 // StartStaging starts the staging service.
 func (pm *ProcessManager) StartStaging(projectID uint) error {
-	return systemctl("start", ServiceName(projectID)+"-staging")
+	return pm.run("start", ServiceName(projectID)+"-staging")
 }
 
 // IsStagingRunning checks if the staging service is active.
 func (pm *ProcessManager) IsStagingRunning(projectID uint) bool {
-	cmd := exec.Command("systemctl", "is-active", "--quiet", ServiceName(projectID)+"-staging")
-	return cmd.Run() == nil
+	return pm.isActive(ServiceName(projectID) + "-staging")
 }
 
 // installService is the internal implementation for creating a systemd service unit.
-func (pm *ProcessManager) installService(serviceName string, project *Project, workDir string, port int) error {
-	unitPath := filepath.Join("/etc/systemd/system", serviceName+".service")
+func (pm *ProcessManager) installService(serviceName string, project *Project, workDir string, port int, runtimeLog string) error {
+	unitPath := filepath.Join(pm.unitDir, serviceName+".service")
 
 	// Prepare env lines
 	var envLines []string
@@ -116,8 +126,6 @@ func (pm *ProcessManager) installService(serviceName string, project *Project, w
 		envLines = append(envLines, fmt.Sprintf("PORT=%d", port))
 	}
 
-	// Runtime log file
-	runtimeLog := filepath.Join(pm.logDir, fmt.Sprintf("project_%d", project.ID), "runtime.log")
 	os.MkdirAll(filepath.Dir(runtimeLog), 0755)
 
 	// Resource limits
@@ -158,41 +166,79 @@ func (pm *ProcessManager) installService(serviceName string, project *Project, w
 	}
 
 	// Reload systemd and enable service
-	if err := systemctl("daemon-reload"); err != nil {
+	if err := pm.run("daemon-reload"); err != nil {
 		return err
 	}
-	return systemctl("enable", serviceName)
+	return pm.run("enable", serviceName)
 }
 
 // Start starts the project's systemd service.
 func (pm *ProcessManager) Start(projectID uint) error {
-	return systemctl("start", ServiceName(projectID))
+	return pm.run("start", ServiceName(projectID))
 }
 
 // Stop stops the project's systemd service.
 func (pm *ProcessManager) Stop(projectID uint) error {
-	return systemctl("stop", ServiceName(projectID))
+	return pm.run("stop", ServiceName(projectID))
 }
 
 // Restart restarts the project's systemd service.
 func (pm *ProcessManager) Restart(projectID uint) error {
-	return systemctl("restart", ServiceName(projectID))
+	return pm.run("restart", ServiceName(projectID))
 }
 
 // Uninstall stops, disables, and removes the systemd service.
 func (pm *ProcessManager) Uninstall(projectID uint) error {
 	name := ServiceName(projectID)
-	systemctl("stop", name)
-	systemctl("disable", name)
-	unitPath := filepath.Join("/etc/systemd/system", name+".service")
+	pm.run("stop", name)
+	pm.run("disable", name)
+	unitPath := filepath.Join(pm.unitDir, name+".service")
 	os.Remove(unitPath)
-	return systemctl("daemon-reload")
+	return pm.run("daemon-reload")
 }
 
 // IsRunning checks if the project's systemd service is active.
 func (pm *ProcessManager) IsRunning(projectID uint) bool {
-	cmd := exec.Command("systemctl", "is-active", "--quiet", ServiceName(projectID))
-	return cmd.Run() == nil
+	return pm.isActive(ServiceName(projectID))
+}
+
+// EnvironmentServiceName returns the systemd service name for a project environment.
+func EnvironmentServiceName(projectID uint, envName string) string {
+	return fmt.Sprintf("webcasa-project-%d-env-%s", projectID, envName)
+}
+
+// InstallEnvironment creates and enables the systemd service for one
+// project environment. env carries the merged env vars and the
+// environment's port; project supplies the start command and limits.
+func (pm *ProcessManager) InstallEnvironment(project *Project, env *ProjectEnvironment, workDir string) error {
+	p := *project
+	p.Name = fmt.Sprintf("%s (%s)", project.Name, env.Name)
+	p.EnvVarList = env.EnvVarList
+	return pm.installService(EnvironmentServiceName(project.ID, env.Name), &p, workDir, env.Port, pm.EnvironmentLogPath(project.ID, env.Name))
+}
+
+// RestartEnvironment restarts a project environment's service.
+func (pm *ProcessManager) RestartEnvironment(projectID uint, envName string) error {
+	return pm.run("restart", EnvironmentServiceName(projectID, envName))
+}
+
+// UninstallEnvironment stops, disables, and removes a project environment's service.
+func (pm *ProcessManager) UninstallEnvironment(projectID uint, envName string) error {
+	name := EnvironmentServiceName(projectID, envName)
+	pm.run("stop", name)
+	pm.run("disable", name)
+	os.Remove(filepath.Join(pm.unitDir, name+".service"))
+	return pm.run("daemon-reload")
+}
+
+// IsEnvironmentRunning checks if a project environment's service is active.
+func (pm *ProcessManager) IsEnvironmentRunning(projectID uint, envName string) bool {
+	return pm.isActive(EnvironmentServiceName(projectID, envName))
+}
+
+// EnvironmentLogPath returns the runtime log path for a project environment.
+func (pm *ProcessManager) EnvironmentLogPath(projectID uint, envName string) string {
+	return filepath.Join(pm.logDir, fmt.Sprintf("project_%d", projectID), fmt.Sprintf("runtime_%s.log", envName))
 }
 
 // RuntimeLogPath returns the path to the runtime log file.
@@ -234,7 +280,7 @@ func ExtraProcessServiceName(projectID uint, procName string, instance int) stri
 func (pm *ProcessManager) InstallExtraProcess(project *Project, proc *ExtraProcess, workDir string) error {
 	for i := 1; i <= proc.Instances; i++ {
 		svcName := ExtraProcessServiceName(project.ID, proc.Name, i)
-		unitPath := filepath.Join("/etc/systemd/system", svcName+".service")
+		unitPath := filepath.Join(pm.unitDir, svcName+".service")
 
 		var envLines []string
 		for _, ev := range project.EnvVarList {
@@ -271,12 +317,12 @@ func (pm *ProcessManager) InstallExtraProcess(project *Project, proc *ExtraProce
 		f.Close()
 	}
 
-	if err := systemctl("daemon-reload"); err != nil {
+	if err := pm.run("daemon-reload"); err != nil {
 		return err
 	}
 	for i := 1; i <= proc.Instances; i++ {
 		svcName := ExtraProcessServiceName(project.ID, proc.Name, i)
-		if err := systemctl("enable", svcName); err != nil {
+		if err := pm.run("enable", svcName); err != nil {
 			return err
 		}
 	}
@@ -287,7 +333,7 @@ func (pm *ProcessManager) InstallExtraProcess(project *Project, proc *ExtraProce
 func (pm *ProcessManager) StartExtraProcess(projectID uint, proc *ExtraProcess) error {
 	for i := 1; i <= proc.Instances; i++ {
 		svcName := ExtraProcessServiceName(projectID, proc.Name, i)
-		if err := systemctl("start", svcName); err != nil {
+		if err := pm.run("start", svcName); err != nil {
 			return err
 		}
 	}
@@ -298,7 +344,7 @@ func (pm *ProcessManager) StartExtraProcess(projectID uint, proc *ExtraProcess)
 func (pm *ProcessManager) StopExtraProcess(projectID uint, proc *ExtraProcess) error {
 	for i := 1; i <= proc.Instances; i++ {
 		svcName := ExtraProcessServiceName(projectID, proc.Name, i)
-		if err := systemctl("stop", svcName); err != nil {
+		if err := pm.run("stop", svcName); err != nil {
 			return err
 		}
 	}
@@ -309,7 +355,7 @@ func (pm *ProcessManager) StopExtraProcess(projectID uint, proc *ExtraProcess) e
 func (pm *ProcessManager) RestartExtraProcess(projectID uint, proc *ExtraProcess) error {
 	for i := 1; i <= proc.Instances; i++ {
 		svcName := ExtraProcessServiceName(projectID, proc.Name, i)
-		if err := systemctl("restart", svcName); err != nil {
+		if err := pm.run("restart", svcName); err != nil {
 			return err
 		}
 	}
@@ -320,19 +366,18 @@ func (pm *ProcessManager) RestartExtraProcess(projectID uint, proc *ExtraProcess
 func (pm *ProcessManager) UninstallExtraProcess(projectID uint, proc *ExtraProcess) {
 	for i := 1; i <= proc.Instances; i++ {
 		svcName := ExtraProcessServiceName(projectID, proc.Name, i)
-		systemctl("stop", svcName)
-		systemctl("disable", svcName)
-		unitPath := filepath.Join("/etc/systemd/system", svcName+".service")
+		pm.run("stop", svcName)
+		pm.run("disable", svcName)
+		unitPath := filepath.Join(pm.unitDir, svcName+".service")
 		os.Remove(unitPath)
 	}
-	systemctl("daemon-reload")
+	pm.run("daemon-reload")
 }
 
 // IsExtraProcessRunning checks if the first instance of an extra process is active.
 func (pm *ProcessManager) IsExtraProcessRunning(projectID uint, proc *ExtraProcess) bool {
 	svcName := ExtraProcessServiceName(projectID, proc.Name, 1)
-	cmd := exec.Command("systemctl", "is-active", "--quiet", svcName)
-	return cmd.Run() == nil
+	return pm.isActive(svcName)
 }
 
 // resolveStartCommand makes relative paths absolute.
@@ -0,0 +1,198 @@
+package deploy
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+)
+
+// defaultKeepReleases is how many bare-mode release snapshots are kept
+// per project when Project.KeepReleases is unset.
+const defaultKeepReleases = 5
+
+// ReleaseManager keeps per-build snapshots of a bare-mode project's built
+// source tree under releases/project_<id>/<buildNum>, plus a `current`
+// symlink the systemd unit's WorkingDirectory points at. Rollback flips
+// the symlink to an older snapshot and restarts — the code that actually
+// ran for that build comes back, not just the build counter.
+type ReleaseManager struct {
+	baseDir string
+}
+
+// NewReleaseManager creates a release manager rooted at dataDir/releases.
+func NewReleaseManager(dataDir string) *ReleaseManager {
+	return &ReleaseManager{baseDir: filepath.Join(dataDir, "releases")}
+}
+
+// ProjectDir returns the directory holding all releases of a project.
+func (rm *ReleaseManager) ProjectDir(projectID uint) string {
+	return filepath.Join(rm.baseDir, fmt.Sprintf("project_%d", projectID))
+}
+
+// ReleaseDir returns the snapshot directory for one build.
+func (rm *ReleaseManager) ReleaseDir(projectID uint, buildNum int) string {
+	return filepath.Join(rm.ProjectDir(projectID), strconv.Itoa(buildNum))
+}
+
+// CurrentLink returns the path of the `current` symlink.
+func (rm *ReleaseManager) CurrentLink(projectID uint) string {
+	return filepath.Join(rm.ProjectDir(projectID), "current")
+}
+
+// Exists reports whether a snapshot for buildNum is on disk.
+func (rm *ReleaseManager) Exists(projectID uint, buildNum int) bool {
+	info, err := os.Stat(rm.ReleaseDir(projectID, buildNum))
+	return err == nil && info.IsDir()
+}
+
+// Create snapshots srcDir (minus .git) as the release for buildNum,
+// replacing any partial snapshot left by an earlier crashed attempt.
+func (rm *ReleaseManager) Create(projectID uint, buildNum int, srcDir string) (string, error) {
+	dst := rm.ReleaseDir(projectID, buildNum)
+	tmp := dst + ".tmp"
+	os.RemoveAll(tmp)
+	if err := copyTree(srcDir, tmp, map[string]bool{".git": true}); err != nil {
+		os.RemoveAll(tmp)
+		return "", fmt.Errorf("snapshot release %d: %w", buildNum, err)
+	}
+	os.RemoveAll(dst)
+	if err := os.Rename(tmp, dst); err != nil {
+		os.RemoveAll(tmp)
+		return "", fmt.Errorf("finalize release %d: %w", buildNum, err)
+	}
+	return dst, nil
+}
+
+// Activate atomically points `current` at the release for buildNum.
+// The link target is relative so the data dir can be moved.
+func (rm *ReleaseManager) Activate(projectID uint, buildNum int) error {
+	if !rm.Exists(projectID, buildNum) {
+		return fmt.Errorf("release %d not found", buildNum)
+	}
+	link := rm.CurrentLink(projectID)
+	tmp := link + ".tmp"
+	os.Remove(tmp)
+	if err := os.Symlink(strconv.Itoa(buildNum), tmp); err != nil {
+		return fmt.Errorf("create current link: %w", err)
+	}
+	if err := os.Rename(tmp, link); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("swap current link: %w", err)
+	}
+	return nil
+}
+
+// Current returns the build number `current` points at, or 0 if unset.
+func (rm *ReleaseManager) Current(projectID uint) int {
+	target, err := os.Readlink(rm.CurrentLink(projectID))
+	if err != nil {
+		return 0
+	}
+	n, _ := strconv.Atoi(filepath.Base(target))
+	return n
+}
+
+// List returns the build numbers with a snapshot on disk, ascending.
+func (rm *ReleaseManager) List(projectID uint) []int {
+	entries, err := os.ReadDir(rm.ProjectDir(projectID))
+	if err != nil {
+		return nil
+	}
+	var builds []int
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		if n, err := strconv.Atoi(e.Name()); err == nil {
+			builds = append(builds, n)
+		}
+	}
+	sort.Ints(builds)
+	return builds
+}
+
+// Prune removes all but the newest keep snapshots. The active release is
+// never removed, even when it's older than the retention window (i.e.
+// right after a rollback).
+func (rm *ReleaseManager) Prune(projectID uint, keep int) []int {
+	if keep <= 0 {
+		keep = defaultKeepReleases
+	}
+	builds := rm.List(projectID)
+	if len(builds) <= keep {
+		return nil
+	}
+	current := rm.Current(projectID)
+	var removed []int
+	for _, n := range builds[:len(builds)-keep] {
+		if n == current {
+			continue
+		}
+		if err := os.RemoveAll(rm.ReleaseDir(projectID, n)); err == nil {
+			removed = append(removed, n)
+		}
+	}
+	return removed
+}
+
+// Remove deletes every release of a project.
+func (rm *ReleaseManager) Remove(projectID uint) error {
+	return os.RemoveAll(rm.ProjectDir(projectID))
+}
+
+// copyTree copies src into dst preserving file modes and symlinks.
+// Top-level entries named in skip are not copied.
+func copyTree(src, dst string, skip map[string]bool) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		if rel != "." && skip[rel] {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		target := filepath.Join(dst, rel)
+
+		switch {
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case info.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm())
+		case info.Mode().IsRegular():
+			return copyFile(path, target, info.Mode().Perm())
+		default:
+			// Sockets, devices, fifos: not meaningful in a release.
+			return nil
+		}
+	})
+}
+
+func copyFile(src, dst string, perm os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
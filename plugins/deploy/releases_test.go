@@ -0,0 +1,144 @@
+package deploy
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeRelease(t *testing.T, rm *ReleaseManager, projectID uint, buildNum int, content string) {
+	t.Helper()
+	src := t.TempDir()
+	os.WriteFile(filepath.Join(src, "app.txt"), []byte(content), 0644)
+	os.MkdirAll(filepath.Join(src, ".git"), 0755)
+	if _, err := rm.Create(projectID, buildNum, src); err != nil {
+		t.Fatalf("create release %d: %v", buildNum, err)
+	}
+}
+
+func readCurrent(t *testing.T, rm *ReleaseManager, projectID uint) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(rm.CurrentLink(projectID), "app.txt"))
+	if err != nil {
+		t.Fatalf("read through current link: %v", err)
+	}
+	return string(data)
+}
+
+func TestReleaseManager_CreateActivateFlip(t *testing.T) {
+	rm := NewReleaseManager(t.TempDir())
+	writeRelease(t, rm, 1, 1, "v1")
+	writeRelease(t, rm, 1, 2, "v2")
+
+	if _, err := os.Stat(filepath.Join(rm.ReleaseDir(1, 1), ".git")); !os.IsNotExist(err) {
+		t.Fatalf(".git should not be copied into a release (stat err=%v)", err)
+	}
+
+	if err := rm.Activate(1, 2); err != nil {
+		t.Fatalf("activate 2: %v", err)
+	}
+	if got := readCurrent(t, rm, 1); got != "v2" {
+		t.Fatalf("current content = %q, want v2", got)
+	}
+	if err := rm.Activate(1, 1); err != nil {
+		t.Fatalf("activate 1: %v", err)
+	}
+	if rm.Current(1) != 1 || readCurrent(t, rm, 1) != "v1" {
+		t.Fatalf("current = %d (%q), want build 1", rm.Current(1), readCurrent(t, rm, 1))
+	}
+	if err := rm.Activate(1, 9); err == nil {
+		t.Fatal("activating a missing release should fail")
+	}
+}
+
+func TestReleaseManager_PruneKeepsCurrent(t *testing.T) {
+	rm := NewReleaseManager(t.TempDir())
+	for n := 1; n <= 5; n++ {
+		writeRelease(t, rm, 1, n, "v")
+	}
+	// Simulate a rollback to an old build before pruning.
+	rm.Activate(1, 1)
+
+	removed := rm.Prune(1, 2)
+	if !reflect.DeepEqual(removed, []int{2, 3}) {
+		t.Fatalf("pruned %v, want [2 3]", removed)
+	}
+	for _, n := range []int{1, 4, 5} {
+		if !rm.Exists(1, n) {
+			t.Errorf("release %d should survive prune", n)
+		}
+	}
+}
+
+// TestRollback_FlipsReleaseAndRestarts verifies bare-mode rollback
+// re-points `current` at the target build and restarts the service, and
+// that a failed build can never be a rollback target.
+func TestRollback_FlipsReleaseAndRestarts(t *testing.T) {
+	s := newBuildTestService(t)
+	s.releases = NewReleaseManager(s.dataDir)
+	pm, calls := stubProcessManager(t)
+	s.proc = pm
+	s.db.AutoMigrate(&ExtraProcess{})
+
+	project := &Project{Name: "rb", StartCommand: "./app", DeployMode: "bare", Status: "running", CurrentBuild: 3, WebhookToken: "tok-rb"}
+	s.db.Create(project)
+	s.db.Create(&Deployment{ProjectID: project.ID, BuildNum: 1, Status: "success"})
+	s.db.Create(&Deployment{ProjectID: project.ID, BuildNum: 2, Status: "success"})
+	s.db.Create(&Deployment{ProjectID: project.ID, BuildNum: 3, Status: "failed"})
+	writeRelease(t, s.releases, project.ID, 1, "v1")
+	writeRelease(t, s.releases, project.ID, 2, "v2")
+	s.releases.Activate(project.ID, 2)
+
+	if err := s.Rollback(project.ID, 3); err == nil {
+		t.Fatal("rollback to a failed build should be rejected")
+	}
+	if s.releases.Current(project.ID) != 2 {
+		t.Fatalf("failed rollback moved current to %d", s.releases.Current(project.ID))
+	}
+
+	if err := s.Rollback(project.ID, 1); err != nil {
+		t.Fatalf("rollback to 1: %v", err)
+	}
+	if got := readCurrent(t, s.releases, project.ID); got != "v1" {
+		t.Fatalf("current content after rollback = %q, want v1", got)
+	}
+
+	unit, err := os.ReadFile(filepath.Join(pm.unitDir, ServiceName(project.ID)+".service"))
+	if err != nil {
+		t.Fatalf("unit not rewritten: %v", err)
+	}
+	if !strings.Contains(string(unit), "WorkingDirectory="+s.releases.CurrentLink(project.ID)) {
+		t.Fatalf("unit does not run from current release:\n%s", unit)
+	}
+	if !strings.Contains(strings.Join(*calls, "\n"), "restart "+ServiceName(project.ID)) {
+		t.Fatalf("service not restarted; systemctl calls: %v", *calls)
+	}
+
+	var dep Deployment
+	s.db.Where("project_id = ? AND build_num = 2", project.ID).First(&dep)
+	if dep.Status != "rolled_back" {
+		t.Fatalf("build 2 status = %q, want rolled_back", dep.Status)
+	}
+}
+
+func TestRollback_MissingReleaseRejected(t *testing.T) {
+	s := newBuildTestService(t)
+	s.releases = NewReleaseManager(s.dataDir)
+	pm, _ := stubProcessManager(t)
+	s.proc = pm
+
+	project := &Project{Name: "rb2", StartCommand: "./app", DeployMode: "bare", WebhookToken: "tok-rb2"}
+	s.db.Create(project)
+	s.db.Create(&Deployment{ProjectID: project.ID, BuildNum: 1, Status: "success"})
+
+	if err := s.Rollback(project.ID, 1); err == nil || !strings.Contains(err.Error(), "no longer on disk") {
+		t.Fatalf("rollback without snapshot: err = %v", err)
+	}
+	var dep Deployment
+	s.db.Where("project_id = ? AND build_num = 1", project.ID).First(&dep)
+	if dep.Status != "success" {
+		t.Fatalf("rejected rollback must not touch deployment state; got %q", dep.Status)
+	}
+}
@@ -36,6 +36,14 @@ var ErrBuildQueueFull = fmt.Errorf("build queue at capacity")
 // concurrent webhook load.
 const defaultMaxConcurrentBuilds = 3
 
+// defaultBuildTimeoutMinutes caps a single build when the project leaves
+// BuildTimeout unset. Matches the column's gorm default.
+const defaultBuildTimeoutMinutes = 30
+
+// maxBuildTimeoutMinutes bounds user-supplied BuildTimeout so a typo
+// can't pin a build slot for days.
+const maxBuildTimeoutMinutes = 24 * 60
+
 // Service is the main deploy service that coordinates Git, Builder, and ProcessManager.
 type Service struct {
 	db        *gorm.DB
@@ -45,6 +53,7 @@ type Service struct {
 	docker    *DockerRunner
 	health    *HealthChecker
 	ports     *PortAllocator
+	releases  *ReleaseManager
 	coreAPI   pluginpkg.CoreAPI
 	eventBus  *pluginpkg.EventBus
 	logger    *slog.Logger
@@ -68,6 +77,17 @@ type Service struct {
 	buildPending  map[uint]bool
 	buildGroup    singleflight.Group
 
+	// buildCommits holds the commit SHA reported by the push webhook that
+	// triggered (or was coalesced into) the next build, keyed by project.
+	// runBuildOnce consumes it so the Deployment row shows the pushed SHA
+	// while the build is still running. Guarded by buildMu.
+	buildCommits map[uint]string
+
+	// Environment builds use the same inflight/pending scheme as project
+	// builds, keyed by environment ID. Lazily allocated; guarded by buildMu.
+	envBuildInflight map[uint]bool
+	envBuildPending  map[uint]bool
+
 	// buildSem caps the number of CONCURRENT runBuildOnce executions
 	// across ALL projects. Per-project dedup (buildInflight + pending
 	// flag) prevents the same project from running twice; this
@@ -82,6 +102,10 @@ type Service struct {
 	// buildRunner lets tests replace runBuildOnce; nil in production.
 	buildRunner func(projectID uint) error
 
+	// buildTimeoutUnit scales Project.BuildTimeout; zero means minutes.
+	// Tests shrink it so a timeout fires in milliseconds.
+	buildTimeoutUnit time.Duration
+
 	// GitHub App auth helper
 	ghApp *GitHubAppAuth
 
@@ -122,7 +146,8 @@ func NewService(db *gorm.DB, coreAPI pluginpkg.CoreAPI, eventBus *pluginpkg.Even
 		proc:          NewProcessManager(logDir),
 		docker:        NewDockerRunner(),
 		health:        NewHealthChecker(),
-		ports:         NewPortAllocator(10000),
+		ports:         NewPortAllocator(10000, db),
+		releases:      NewReleaseManager(dataDir),
 		coreAPI:       coreAPI,
 		eventBus:      eventBus,
 		logger:        logger,
@@ -184,6 +209,10 @@ func (s *Service) ListProjects() ([]Project, error) {
 		projects[i].HasDeployKey = projects[i].DeployKey != ""
 		projects[i].HasGitHubKey = projects[i].GitHubPrivateKey != ""
 		projects[i].HasGitHubToken = projects[i].GitHubToken != ""
+		projects[i].Queued = s.IsBuildQueued(projects[i].ID)
+		if envs, err := s.ListEnvironments(projects[i].ID); err == nil && len(envs) > 0 {
+			projects[i].Environments = envs
+		}
 	}
 	return projects, nil
 }
@@ -214,6 +243,7 @@ func (s *Service) GetProject(id uint) (*Project, error) {
 	project.HasDeployKey = project.DeployKey != ""
 	project.HasGitHubKey = project.GitHubPrivateKey != ""
 	project.HasGitHubToken = project.GitHubToken != ""
+	project.Queued = s.IsBuildQueued(project.ID)
 	return &project, nil
 }
 
@@ -250,6 +280,15 @@ func (s *Service) CreateProject(project *Project) error {
 		project.GitHubPrivateKey = enc
 	}
 
+	// Encrypt webhook HMAC secret before saving
+	if project.WebhookSecret != "" {
+		enc, err := crypto.Encrypt(project.WebhookSecret, s.jwtSecret)
+		if err != nil {
+			return fmt.Errorf("encrypt webhook secret: %w", err)
+		}
+		project.WebhookSecret = enc
+	}
+
 	// Default auth method
 	if project.AuthMethod == "" {
 		project.AuthMethod = "ssh_key"
@@ -335,6 +374,20 @@ func (s *Service) UpdateProject(id uint, updates map[string]interface{}) error {
 		}
 	}
 
+	if _, ok := updates["domain"]; ok {
+		project, err := s.GetProject(id)
+		if err != nil {
+			return err
+		}
+		deleted, err := s.renameHost(project.HostID, project.Domain, updates)
+		if err != nil {
+			return err
+		}
+		if deleted {
+			updates["host_id"] = 0
+		}
+	}
+
 	return s.db.Model(&Project{}).Where("id = ?", id).Updates(updates).Error
 }
 
@@ -387,9 +440,18 @@ func (s *Service) DeleteProject(id uint) error {
 	// 4. Remove source code
 	os.RemoveAll(s.git.ProjectDir(id))
 
-	// 5. Remove logs and build cache
+	// 4b. Tear down per-environment services, hosts and checkouts
+	var envs []ProjectEnvironment
+	s.db.Where("project_id = ?", id).Find(&envs)
+	for i := range envs {
+		s.teardownEnvironment(&envs[i])
+	}
+
+	// 5. Remove logs, build cache and release snapshots
 	os.RemoveAll(s.builder.LogDir(id))
 	s.builder.ClearCache(id)
+	s.releases.Remove(id)
+	s.ports.ReleasePort(id)
 
 	// 6. Stop and remove extra processes
 	var extraProcs []ExtraProcess
@@ -413,6 +475,7 @@ func (s *Service) DeleteProject(id uint) error {
 	s.db.Where("project_id = ?", id).Delete(&CronJob{})
 	s.db.Where("project_id = ?", id).Delete(&ExtraProcess{})
 	s.db.Where("project_id = ?", id).Delete(&Deployment{})
+	s.db.Where("project_id = ?", id).Delete(&ProjectEnvironment{})
 	return s.db.Delete(&Project{}, id).Error
 }
 
@@ -447,6 +510,40 @@ func (s *Service) IsBuildInflight(projectID uint) bool {
 	return s.buildInflight[projectID]
 }
 
+// IsBuildQueued reports whether a coalesced rebuild is waiting for the
+// in-flight build to finish. Surfaced as Project.Queued so the UI can
+// show "queued" next to "building" after rapid webhook pushes.
+func (s *Service) IsBuildQueued(projectID uint) bool {
+	s.buildMu.Lock()
+	defer s.buildMu.Unlock()
+	return s.buildPending[projectID]
+}
+
+// BuildForCommit is Build for push webhooks: it records the pushed commit
+// SHA so the resulting Deployment carries it from the start. When the
+// trigger is coalesced, the latest SHA wins — it's the one the queued
+// rebuild will check out.
+func (s *Service) BuildForCommit(projectID uint, commit string) error {
+	if commit != "" {
+		s.buildMu.Lock()
+		if s.buildCommits == nil {
+			s.buildCommits = make(map[uint]string)
+		}
+		s.buildCommits[projectID] = commit
+		s.buildMu.Unlock()
+	}
+	return s.Build(projectID)
+}
+
+// takeBuildCommit returns and clears the webhook-reported commit for a project.
+func (s *Service) takeBuildCommit(projectID uint) string {
+	s.buildMu.Lock()
+	defer s.buildMu.Unlock()
+	commit := s.buildCommits[projectID]
+	delete(s.buildCommits, projectID)
+	return commit
+}
+
 // Build triggers a build for a project. Always non-blocking.
 //
 // Semantics:
@@ -591,6 +688,7 @@ func (s *Service) runBuildOnce(projectID uint) error {
 	deployment := &Deployment{
 		ProjectID: projectID,
 		BuildNum:  buildNum,
+		GitCommit: s.takeBuildCommit(projectID),
 		Status:    "building",
 	}
 	if err := s.db.Create(deployment).Error; err != nil {
@@ -620,6 +718,21 @@ func (s *Service) runBuildOnce(projectID uint) error {
 	return nil
 }
 
+// buildTimeout resolves the per-project build deadline. BuildTimeout is
+// stored in minutes; zero or negative falls back to the default so rows
+// created before the column existed keep the historical 30-minute cap.
+func (s *Service) buildTimeout(project *Project) time.Duration {
+	unit := s.buildTimeoutUnit
+	if unit == 0 {
+		unit = time.Minute
+	}
+	n := project.BuildTimeout
+	if n <= 0 {
+		n = defaultBuildTimeoutMinutes
+	}
+	return time.Duration(n) * unit
+}
+
 // runBuild executes the full build pipeline synchronously.
 // Build lifecycle (inflight/pending flags) is owned by buildLoop, not this func.
 func (s *Service) runBuild(project *Project, deployment *Deployment, logWriter *LogWriter) {
@@ -630,10 +743,7 @@ func (s *Service) runBuild(project *Project, deployment *Deployment, logWriter *
 		s.mu.Unlock()
 	}()
 
-	buildTimeout := 30 * time.Minute
-	if project.BuildTimeout > 0 {
-		buildTimeout = time.Duration(project.BuildTimeout) * time.Minute
-	}
+	buildTimeout := s.buildTimeout(project)
 	ctx, cancel := context.WithTimeout(context.Background(), buildTimeout)
 	defer cancel()
 
@@ -700,11 +810,22 @@ func (s *Service) runBuild(project *Project, deployment *Deployment, logWriter *
 
 	result := s.builder.Build(ctx, &buildProject, buildToken, logWriter)
 
-	deployment.GitCommit = result.Commit
+	// The checked-out commit is authoritative; the webhook SHA recorded
+	// at creation only survives when the builder couldn't read HEAD.
+	if result.Commit != "" {
+		deployment.GitCommit = result.Commit
+	}
 	deployment.Duration = int(result.Duration.Seconds())
 	deployment.LogFile = s.builder.LogPath(project.ID, deployment.BuildNum)
 
 	if !result.Success {
+		// The builder only sees a killed process group; translate a
+		// deadline hit into a message the UI can show without the
+		// operator having to dig through the log tail.
+		if ctx.Err() == context.DeadlineExceeded {
+			result.ErrorMsg = fmt.Sprintf("build timed out after %ds", int(buildTimeout/time.Second))
+			logWriter.Write([]byte("ERROR: " + result.ErrorMsg + "\n"))
+		}
 		deployment.Status = "failed"
 		s.db.Save(deployment)
 		s.db.Model(&Project{}).Where("id = ?", project.ID).Updates(map[string]interface{}{
@@ -766,7 +887,16 @@ func (s *Service) runBuild(project *Project, deployment *Deployment, logWriter *
 	if project.DeployMode == "docker" {
 		s.runDockerDeploy(project, deployment, logWriter)
 	} else {
-		s.runBareDeploy(project, projectDir, logWriter)
+		workDir, err := s.activateRelease(project, deployment.BuildNum, projectDir, logWriter)
+		if err != nil {
+			logWriter.Write([]byte(fmt.Sprintf("ERROR: %v\n", err)))
+			s.db.Model(&Project{}).Where("id = ?", project.ID).Updates(map[string]interface{}{
+				"status":    "error",
+				"error_msg": err.Error(),
+			})
+			return
+		}
+		s.runBareDeploy(project, workDir, logWriter)
 	}
 
 	// Start extra processes after successful deploy
@@ -775,15 +905,36 @@ func (s *Service) runBuild(project *Project, deployment *Deployment, logWriter *
 	s.logger.Info("build completed", "project", project.Name, "build", deployment.BuildNum, "duration", result.Duration)
 }
 
+// activateRelease snapshots the freshly built source tree as release
+// buildNum, points the `current` symlink at it, and prunes old releases.
+// Returns the path the process manager should use as WorkingDirectory.
+func (s *Service) activateRelease(project *Project, buildNum int, projectDir string, logWriter *LogWriter) (string, error) {
+	if _, err := s.releases.Create(project.ID, buildNum, projectDir); err != nil {
+		return "", err
+	}
+	if err := s.releases.Activate(project.ID, buildNum); err != nil {
+		return "", err
+	}
+	logWriter.Write([]byte(fmt.Sprintf("==> Release #%d activated\n", buildNum)))
+	if pruned := s.releases.Prune(project.ID, project.KeepReleases); len(pruned) > 0 {
+		logWriter.Write([]byte(fmt.Sprintf("==> Pruned old releases: %v\n", pruned)))
+	}
+	return s.releases.CurrentLink(project.ID), nil
+}
+
+// releaseWorkDir returns the directory bare-mode processes run from: the
+// `current` release when one exists, else the raw checkout (projects
+// last built before release snapshots were introduced).
+func (s *Service) releaseWorkDir(projectID uint) string {
+	if s.releases != nil && s.releases.Current(projectID) > 0 {
+		return s.releases.CurrentLink(projectID)
+	}
+	return s.git.ProjectDir(projectID)
+}
+
 // setupReverseProxy creates a Caddy reverse proxy entry for the project.
 func (s *Service) setupReverseProxy(project *Project) {
-	hostID, err := s.coreAPI.CreateHost(pluginpkg.CreateHostRequest{
-		Domain:       project.Domain,
-		UpstreamAddr: fmt.Sprintf("localhost:%d", project.Port),
-		TLSEnabled:   true,
-		HTTPRedirect: true,
-		WebSocket:    true,
-	})
+	hostID, err := s.ensureHost(project.Domain, project.Port)
 	if err != nil {
 		s.logger.Error("create host failed", "project", project.Name, "error", err)
 		return
@@ -808,6 +959,78 @@ func (s *Service) updateUpstream(project *Project, newPort int) {
 	}
 }
 
+// ensureHost returns a proxy host for domain pointing at port. A host that
+// already has the domain is linked and re-pointed rather than duplicated,
+// unless it is not a reverse proxy or another project or environment
+// already owns it.
+func (s *Service) ensureHost(domain string, port int) (uint, error) {
+	existing, err := s.coreAPI.GetHostByDomain(domain)
+	if err != nil {
+		return 0, fmt.Errorf("look up host %s: %w", domain, err)
+	}
+	if existing == nil {
+		return s.coreAPI.CreateHost(pluginpkg.CreateHostRequest{
+			Domain:       domain,
+			UpstreamAddr: fmt.Sprintf("localhost:%d", port),
+			TLSEnabled:   true,
+			HTTPRedirect: true,
+			WebSocket:    true,
+		})
+	}
+
+	if existing.HostType != "proxy" {
+		return 0, fmt.Errorf("domain %s is already used by a %s host", domain, existing.HostType)
+	}
+	var owners int64
+	s.db.Model(&Project{}).Where("host_id = ?", existing.ID).Count(&owners)
+	if owners == 0 {
+		s.db.Model(&ProjectEnvironment{}).Where("host_id = ?", existing.ID).Count(&owners)
+	}
+	if owners > 0 {
+		return 0, fmt.Errorf("domain %s is already linked to another deployment", domain)
+	}
+	s.syncHostUpstream(existing.ID, port)
+	s.logger.Info("linked existing host", "domain", domain, "host", existing.ID)
+	return existing.ID, nil
+}
+
+// syncHostUpstream points an existing host at port once the service runs
+// there, e.g. after the project's port was changed. The host is updated in
+// place, keeping its ID and any settings edited in the panel.
+func (s *Service) syncHostUpstream(hostID uint, port int) {
+	if hostID == 0 || port == 0 {
+		return
+	}
+	want := fmt.Sprintf("localhost:%d", port)
+	if h, err := s.coreAPI.GetHost(hostID); err == nil {
+		if ups, _ := h["upstreams"].([]string); len(ups) > 0 && ups[0] == want {
+			return
+		}
+	}
+	if err := s.coreAPI.UpdateHost(hostID, pluginpkg.UpdateHostRequest{Upstream: want}); err != nil {
+		s.logger.Error("update host upstream failed", "host", hostID, "error", err)
+	}
+}
+
+// renameHost applies a domain change to an existing host in place. It
+// reports whether the host was deleted instead because the domain was
+// cleared.
+func (s *Service) renameHost(hostID uint, oldDomain string, updates map[string]interface{}) (deleted bool, err error) {
+	d, ok := updates["domain"].(string)
+	if !ok || d == oldDomain || hostID == 0 {
+		return false, nil
+	}
+	if d == "" {
+		s.coreAPI.DeleteHost(hostID)
+		s.coreAPI.ReloadCaddy()
+		return true, nil
+	}
+	if err := s.coreAPI.UpdateHost(hostID, pluginpkg.UpdateHostRequest{Domain: d}); err != nil {
+		return false, fmt.Errorf("update host domain: %w", err)
+	}
+	return false, nil
+}
+
 // runBareDeploy handles post-build deploy for bare (systemd) mode with zero-downtime support.
 func (s *Service) runBareDeploy(project *Project, projectDir string, logWriter *LogWriter) {
 	if project.StartCommand == "" {
@@ -856,6 +1079,8 @@ func (s *Service) runBareDeploy(project *Project, projectDir string, logWriter *
 
 		if project.Domain != "" && project.HostID == 0 {
 			s.setupReverseProxy(project)
+		} else {
+			s.syncHostUpstream(project.HostID, project.Port)
 		}
 		return
 	}
@@ -1049,6 +1274,8 @@ func (s *Service) runDockerDeploy(project *Project, deployment *Deployment, logW
 
 		if project.Domain != "" && project.HostID == 0 {
 			s.setupReverseProxy(project)
+		} else {
+			s.syncHostUpstream(project.HostID, project.Port)
 		}
 		return
 	}
@@ -1163,7 +1390,7 @@ func (s *Service) StopProject(id uint) error {
 // Rollback rolls back to a previous build version.
 func (s *Service) Rollback(projectID uint, buildNum int) error {
 	var deployment Deployment
-	if err := s.db.Where("project_id = ? AND build_num = ? AND status = ?", projectID, buildNum, "success").First(&deployment).Error; err != nil {
+	if err := s.db.Where("project_id = ? AND environment_id = 0 AND build_num = ? AND status = ?", projectID, buildNum, "success").First(&deployment).Error; err != nil {
 		return fmt.Errorf("deployment not found or was not successful")
 	}
 
@@ -1172,8 +1399,14 @@ func (s *Service) Rollback(projectID uint, buildNum int) error {
 		return err
 	}
 
+	// Bare mode restores the release snapshot; refuse before touching
+	// any state if it has been pruned or predates release snapshots.
+	if project.DeployMode != "docker" && !s.releases.Exists(projectID, buildNum) {
+		return fmt.Errorf("release for build #%d is no longer on disk (pruned, or built before release snapshots were kept)", buildNum)
+	}
+
 	// Mark newer deployments as rolled back
-	s.db.Model(&Deployment{}).Where("project_id = ? AND build_num > ?", projectID, buildNum).Update("status", "rolled_back")
+	s.db.Model(&Deployment{}).Where("project_id = ? AND environment_id = 0 AND build_num > ?", projectID, buildNum).Update("status", "rolled_back")
 	s.db.Model(&Project{}).Where("id = ?", projectID).Update("current_build", buildNum)
 
 	if project.DeployMode == "docker" {
@@ -1219,14 +1452,37 @@ func (s *Service) Rollback(projectID uint, buildNum int) error {
 		return nil
 	}
 
-	// Bare mode: restart the process
-	return s.proc.Restart(projectID)
+	// Bare mode: re-point `current` at the target release, rewrite the
+	// unit (older units may still point at the raw checkout) and restart.
+	if err := s.releases.Activate(projectID, buildNum); err != nil {
+		return fmt.Errorf("activate release %d: %w", buildNum, err)
+	}
+	if project.StartCommand == "" {
+		return nil
+	}
+	workDir := s.releases.CurrentLink(projectID)
+	if err := s.proc.Install(project, workDir); err != nil {
+		return fmt.Errorf("reinstall service: %w", err)
+	}
+	if err := s.proc.Restart(projectID); err != nil {
+		return err
+	}
+	var procs []ExtraProcess
+	s.db.Where("project_id = ? AND enabled = ?", projectID, true).Find(&procs)
+	for i := range procs {
+		if err := s.proc.InstallExtraProcess(project, &procs[i], workDir); err != nil {
+			s.logger.Error("reinstall extra process on rollback failed", "project", project.Name, "process", procs[i].Name, "err", err)
+			continue
+		}
+		s.proc.RestartExtraProcess(projectID, &procs[i])
+	}
+	return s.db.Model(&Project{}).Where("id = ?", projectID).Update("status", "running").Error
 }
 
 // GetDeployments returns all deployments for a project.
 func (s *Service) GetDeployments(projectID uint) ([]Deployment, error) {
 	var deployments []Deployment
-	err := s.db.Where("project_id = ?", projectID).Order("build_num desc").Find(&deployments).Error
+	err := s.db.Where("project_id = ? AND environment_id = 0", projectID).Order("build_num desc").Find(&deployments).Error
 	return deployments, err
 }
 
@@ -1255,6 +1511,27 @@ func (s *Service) GetActiveLogWriter(projectID uint) *LogWriter {
 	return s.activeLogs[projectID]
 }
 
+// OpenBuildLogStream returns the build log written so far and, when a
+// build is in progress, a channel of subsequent writes that is closed
+// when the build finishes. With no active build the channel is nil and
+// the backlog is the most recent build's log. Callers must call the
+// returned cancel func once they stop reading.
+func (s *Service) OpenBuildLogStream(projectID uint) ([]byte, chan []byte, func(), error) {
+	if lw := s.GetActiveLogWriter(projectID); lw != nil {
+		backlog, ch := lw.SubscribeWithBacklog()
+		return backlog, ch, func() { lw.Unsubscribe(ch) }, nil
+	}
+	var project Project
+	if err := s.db.Select("current_build").First(&project, projectID).Error; err != nil {
+		return nil, nil, nil, err
+	}
+	log, err := s.builder.ReadLog(projectID, project.CurrentBuild)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("no build log available")
+	}
+	return []byte(log), nil, func() {}, nil
+}
+
 // HandleWebhook processes a Git webhook trigger.
 func (s *Service) HandleWebhook(token string) error {
 	var project Project
@@ -1478,7 +1755,7 @@ func (s *Service) StartExtraProcesses(project *Project) {
 		return
 	}
 
-	projectDir := s.git.ProjectDir(project.ID)
+	projectDir := s.releaseWorkDir(project.ID)
 
 	for _, proc := range procs {
 		if project.DeployMode == "docker" {
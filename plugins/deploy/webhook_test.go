@@ -0,0 +1,165 @@
+package deploy
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/web-casa/webcasa/internal/crypto"
+)
+
+const testWebhookJWT = "test-jwt-secret-for-webhook-tests"
+
+// newWebhookTestRouter wires the push webhook to a Service whose builds
+// are counted instead of executed.
+func newWebhookTestRouter(t *testing.T, secret string) (*gin.Engine, *Service, *Project, *atomic.Int32) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	s := newPollerTestService(t, openPollerTestDB(t))
+	s.jwtSecret = testWebhookJWT
+
+	var builds atomic.Int32
+	s.buildRunner = func(projectID uint) error {
+		builds.Add(1)
+		return nil
+	}
+
+	project := &Project{
+		Name:         "hooked",
+		GitBranch:    "main",
+		AutoDeploy:   true,
+		WebhookToken: "hooktoken",
+	}
+	if secret != "" {
+		enc, err := crypto.Encrypt(secret, testWebhookJWT)
+		if err != nil {
+			t.Fatalf("encrypt secret: %v", err)
+		}
+		project.WebhookSecret = enc
+	}
+	if err := s.db.Create(project).Error; err != nil {
+		t.Fatalf("create project: %v", err)
+	}
+
+	r := gin.New()
+	r.POST("/webhook/:token", NewHandler(s).Webhook)
+	return r, s, project, &builds
+}
+
+func githubSignature(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func postWebhook(r *gin.Engine, body []byte, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/webhook/hooktoken", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestWebhook_ValidGitHubSignature_BuildsWithCommit(t *testing.T) {
+	r, s, project, builds := newWebhookTestRouter(t, "s3cret")
+	body := []byte(`{"ref":"refs/heads/main","after":"0123456789abcdef0123456789abcdef01234567"}`)
+
+	w := postWebhook(r, body, map[string]string{
+		"X-GitHub-Event":      "push",
+		"X-Hub-Signature-256": githubSignature("s3cret", body),
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body=%s", w.Code, w.Body.String())
+	}
+	waitInflightClear(t, s, project.ID, 2*time.Second)
+	if got := builds.Load(); got != 1 {
+		t.Fatalf("builds = %d, want 1", got)
+	}
+	// buildRunner bypasses runBuildOnce, so the SHA must still be parked
+	// for the Deployment row the real runner would create.
+	if got := s.takeBuildCommit(project.ID); got != "0123456789abcdef0123456789abcdef01234567" {
+		t.Fatalf("recorded commit = %q", got)
+	}
+}
+
+func TestWebhook_InvalidSignature_Rejected(t *testing.T) {
+	r, _, _, builds := newWebhookTestRouter(t, "s3cret")
+	body := []byte(`{"ref":"refs/heads/main","after":"0123456789abcdef0123456789abcdef01234567"}`)
+
+	w := postWebhook(r, body, map[string]string{
+		"X-GitHub-Event":      "push",
+		"X-Hub-Signature-256": githubSignature("wrong", body),
+	})
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want 401", w.Code)
+	}
+	if got := builds.Load(); got != 0 {
+		t.Fatalf("builds = %d, want 0", got)
+	}
+}
+
+func TestWebhook_GitLabToken(t *testing.T) {
+	r, s, project, builds := newWebhookTestRouter(t, "s3cret")
+	body := []byte(`{"ref":"refs/heads/main","checkout_sha":"abcdef1234567"}`)
+
+	if w := postWebhook(r, body, map[string]string{"X-Gitlab-Token": "nope"}); w.Code != http.StatusUnauthorized {
+		t.Fatalf("bad token status = %d, want 401", w.Code)
+	}
+	if w := postWebhook(r, body, map[string]string{"X-Gitlab-Token": "s3cret"}); w.Code != http.StatusOK {
+		t.Fatalf("good token status = %d, want 200", w.Code)
+	}
+	waitInflightClear(t, s, project.ID, 2*time.Second)
+	if got := builds.Load(); got != 1 {
+		t.Fatalf("builds = %d, want 1", got)
+	}
+}
+
+func TestWebhook_NonWatchedBranch_Ignored(t *testing.T) {
+	r, _, _, builds := newWebhookTestRouter(t, "s3cret")
+	body := []byte(`{"ref":"refs/heads/feature-x","after":"0123456789abcdef0123456789abcdef01234567"}`)
+
+	w := postWebhook(r, body, map[string]string{
+		"X-GitHub-Event":      "push",
+		"X-Hub-Signature-256": githubSignature("s3cret", body),
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", w.Code)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if got := builds.Load(); got != 0 {
+		t.Fatalf("builds = %d, want 0 for non-watched branch", got)
+	}
+}
+
+func TestParsePushPayload(t *testing.T) {
+	cases := []struct {
+		name string
+		body string
+		want pushEvent
+	}{
+		{"empty body", ``, pushEvent{}},
+		{"not json", `hello`, pushEvent{}},
+		{"github push", `{"ref":"refs/heads/main","after":"ABCDEF1234567"}`, pushEvent{Ref: "refs/heads/main", Commit: "abcdef1234567"}},
+		{"gitlab checkout_sha", `{"ref":"refs/heads/dev","checkout_sha":"abcdef1234567"}`, pushEvent{Ref: "refs/heads/dev", Commit: "abcdef1234567"}},
+		{"deletion", `{"ref":"refs/heads/main","after":"0000000000000000000000000000000000000000"}`, pushEvent{Ref: "refs/heads/main", Deleted: true}},
+		{"malformed sha dropped", `{"ref":"refs/heads/main","after":"--upload-pack=x"}`, pushEvent{Ref: "refs/heads/main"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := parsePushPayload([]byte(tc.body)); got != tc.want {
+				t.Fatalf("parsePushPayload(%s) = %+v, want %+v", tc.body, got, tc.want)
+			}
+		})
+	}
+}
@@ -213,6 +213,25 @@ func (p *Plugin) tryReconnect() bool {
 	return true
 }
 
+// Health reports whether the Docker daemon answers a ping.
+func (p *Plugin) Health() error {
+	p.stateMu.RLock()
+	client, detail := p.client, p.dockerError
+	p.stateMu.RUnlock()
+	if client == nil {
+		if detail == "" {
+			detail = "not connected"
+		}
+		return fmt.Errorf("docker daemon unavailable: %s", detail)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx); err != nil {
+		return fmt.Errorf("docker daemon unreachable: %w", err)
+	}
+	return nil
+}
+
 // dockerStatus returns the current Docker availability status.
 // It performs a live check: if the cached state says Docker is unavailable
 // but the binary is installed, it tries to reconnect.
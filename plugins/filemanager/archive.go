@@ -3,15 +3,20 @@ package filemanager
 import (
 	"archive/tar"
 	"archive/zip"
+	"compress/bzip2"
 	"compress/gzip"
 	"fmt"
 	"io"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
 )
 
-const maxExtractSize = 1 << 30 // 1 GB total extraction limit
+// maxExtractSize is the total extraction limit (1 GB). A var so tests
+// can exercise the guard without writing a gigabyte.
+var maxExtractSize int64 = 1 << 30
+
 func (f *FileOps) Compress(paths []string, destPath, format string) error {
 	absDest, err := f.safePath(destPath)
 	if err != nil {
@@ -30,10 +35,14 @@ func (f *FileOps) Compress(paths []string, destPath, format string) error {
 	switch format {
 	case "tar.gz", "tgz":
 		return f.compressTarGz(absPaths, absDest)
+	case "tar.bz2", "tbz2":
+		return f.compressTarWith(absPaths, absDest, "bzip2")
+	case "tar.xz", "txz":
+		return f.compressTarWith(absPaths, absDest, "xz")
 	case "zip":
 		return f.compressZip(absPaths, absDest)
 	default:
-		return fmt.Errorf("unsupported format: %s (use tar.gz or zip)", format)
+		return fmt.Errorf("unsupported format: %s (use tar.gz, tar.bz2, tar.xz or zip)", format)
 	}
 }
 
@@ -52,6 +61,10 @@ func (f *FileOps) Extract(archivePath, destDir string) error {
 	switch {
 	case strings.HasSuffix(lower, ".tar.gz") || strings.HasSuffix(lower, ".tgz"):
 		return f.extractTarGz(absSrc, absDest)
+	case strings.HasSuffix(lower, ".tar.bz2") || strings.HasSuffix(lower, ".tbz2"):
+		return f.extractTarBz2(absSrc, absDest)
+	case strings.HasSuffix(lower, ".tar.xz") || strings.HasSuffix(lower, ".txz"):
+		return f.extractTarXz(absSrc, absDest)
 	case strings.HasSuffix(lower, ".zip"):
 		return f.extractZip(absSrc, absDest)
 	default:
@@ -71,6 +84,53 @@ func (f *FileOps) compressTarGz(sources []string, dest string) error {
 	tw := tar.NewWriter(gw)
 	defer tw.Close()
 
+	return writeTarEntries(tw, sources)
+}
+
+// compressTarWith writes a tar stream through an external compressor
+// (bzip2 or xz). The standard library has no writer for either format,
+// and both tools ship with every mainstream distribution.
+func (f *FileOps) compressTarWith(sources []string, dest, compressor string) error {
+	if _, err := exec.LookPath(compressor); err != nil {
+		return fmt.Errorf("%s is not installed on the server", compressor)
+	}
+	out, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	cmd := exec.Command(compressor, "-c")
+	cmd.Stdout = out
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	tw := tar.NewWriter(stdin)
+	writeErr := writeTarEntries(tw, sources)
+	if writeErr == nil {
+		writeErr = tw.Close()
+	}
+	stdin.Close()
+	waitErr := cmd.Wait()
+	if writeErr != nil {
+		os.Remove(dest)
+		return writeErr
+	}
+	if waitErr != nil {
+		os.Remove(dest)
+		return fmt.Errorf("%s failed: %w", compressor, waitErr)
+	}
+	return nil
+}
+
+// writeTarEntries adds each source tree to tw, skipping symlinks and
+// special files.
+func writeTarEntries(tw *tar.Writer, sources []string) error {
 	for _, src := range sources {
 		if err := filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
 			if err != nil {
@@ -161,13 +221,69 @@ func (f *FileOps) extractTarGz(src, dest string) error {
 	}
 	defer gr.Close()
 
+	return extractTarStream(gr, dest)
+}
+
+func (f *FileOps) extractTarBz2(src, dest string) error {
+	file, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return extractTarStream(bzip2.NewReader(file), dest)
+}
+
+// extractTarXz decompresses via the xz tool (no xz reader in the
+// standard library). The tool is killed if extraction stops early.
+func (f *FileOps) extractTarXz(src, dest string) error {
+	if _, err := exec.LookPath("xz"); err != nil {
+		return fmt.Errorf("xz is not installed on the server")
+	}
+	file, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	cmd := exec.Command("xz", "-dc")
+	cmd.Stdin = file
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	extractErr := extractTarStream(stdout, dest)
+	if extractErr != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return extractErr
+	}
+	// The tar reader stops at the end-of-archive marker; drain what follows
+	// (record padding) so xz can finish writing it and exit.
+	if _, err := io.Copy(io.Discard, stdout); err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return fmt.Errorf("xz failed: %w", err)
+	}
+	if err := cmd.Wait(); err != nil {
+		return fmt.Errorf("xz failed: %w", err)
+	}
+	return nil
+}
+
+// extractTarStream extracts a tar stream into dest with zip-slip,
+// symlink-traversal and total-size protections.
+func extractTarStream(r io.Reader, dest string) error {
 	var totalWritten int64
 	destResolved, _ := filepath.EvalSymlinks(dest)
 	if destResolved == "" {
 		destResolved = filepath.Clean(dest)
 	}
 
-	tr := tar.NewReader(gr)
+	tr := tar.NewReader(r)
 	for {
 		header, err := tr.Next()
 		if err == io.EOF {
@@ -1,6 +1,7 @@
 package filemanager
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"io/fs"
@@ -11,10 +12,23 @@ import (
 	"strings"
 	"syscall"
 	"time"
+
+	"github.com/web-casa/webcasa/internal/auth"
 )
 
 const maxReadSize = 10 << 20 // 10 MB
 
+// maxPreviewLength bounds a single ReadRange/ReadTail request.
+const maxPreviewLength = 1 << 20 // 1 MB
+
+// Search bounds: a walk stops after visiting this many entries or running
+// this long, whichever comes first, so a search from "/" can't pin the disk.
+const (
+	maxSearchVisited  = 200000
+	maxSearchDuration = 10 * time.Second
+	maxSearchResults  = 1000
+)
+
 // FileOps provides safe file system operations within a root path.
 type FileOps struct {
 	rootPath string
@@ -25,6 +39,11 @@ func NewFileOps(rootPath string) *FileOps {
 	return &FileOps{rootPath: filepath.Clean(rootPath)}
 }
 
+// hasFullAccess reports whether role may use the unrestricted admin root.
+func hasFullAccess(role string) bool {
+	return role == auth.RoleOwner || role == auth.RoleAdmin
+}
+
 // FileInfo describes a file or directory.
 type FileInfo struct {
 	Name      string    `json:"name"`
@@ -133,6 +152,63 @@ func (f *FileOps) Read(reqPath string) (string, error) {
 	return string(data), nil
 }
 
+// ReadRange returns up to length bytes starting at offset, plus the total
+// file size, so large files can be previewed without loading them whole.
+// length is capped at maxPreviewLength; reading past EOF returns a
+// short slice.
+func (f *FileOps) ReadRange(reqPath string, offset, length int64) ([]byte, int64, error) {
+	abs, err := f.safePath(reqPath)
+	if err != nil {
+		return nil, 0, err
+	}
+	file, err := os.Open(abs)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, 0, err
+	}
+	if info.IsDir() {
+		return nil, 0, fmt.Errorf("cannot read directory")
+	}
+	size := info.Size()
+	if offset < 0 || offset > size {
+		return nil, size, fmt.Errorf("offset %d out of range (file size %d)", offset, size)
+	}
+	if length <= 0 || length > maxPreviewLength {
+		length = maxPreviewLength
+	}
+	if remaining := size - offset; length > remaining {
+		length = remaining
+	}
+	buf := make([]byte, length)
+	n, err := file.ReadAt(buf, offset)
+	if err != nil && err != io.EOF {
+		return nil, size, err
+	}
+	return buf[:n], size, nil
+}
+
+// ReadTail returns the last n bytes of a file (capped at
+// maxPreviewLength), its offset and the total size.
+func (f *FileOps) ReadTail(reqPath string, n int64) ([]byte, int64, int64, error) {
+	fi, err := f.Stat(reqPath)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	if n <= 0 || n > maxPreviewLength {
+		n = maxPreviewLength
+	}
+	offset := fi.Size - n
+	if offset < 0 {
+		offset = 0
+	}
+	data, size, err := f.ReadRange(reqPath, offset, n)
+	return data, offset, size, err
+}
+
 // Write creates or overwrites a file.
 func (f *FileOps) Write(reqPath, content string) error {
 	abs, err := f.safePath(reqPath)
@@ -228,6 +304,277 @@ func (f *FileOps) Rename(oldPath, newPath string) error {
 	return os.Rename(absOld, absNew)
 }
 
+// MoveResult reports the outcome of moving one path in a bulk move.
+type MoveResult struct {
+	Path  string `json:"path"`
+	Dest  string `json:"dest,omitempty"`
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// Move moves each path into destDir (created if missing), keeping its
+// base name. An existing target is only replaced when overwrite is set.
+// Failures are reported per path; one failure doesn't stop the rest.
+func (f *FileOps) Move(paths []string, destDir string, overwrite bool) ([]MoveResult, error) {
+	absDest, err := f.safePath(destDir)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(absDest, 0755); err != nil {
+		return nil, err
+	}
+
+	results := make([]MoveResult, 0, len(paths))
+	for _, p := range paths {
+		res := MoveResult{Path: p}
+		if target, err := f.moveOne(p, absDest, overwrite); err != nil {
+			res.Error = err.Error()
+		} else {
+			res.OK = true
+			res.Dest = target
+		}
+		results = append(results, res)
+	}
+	return results, nil
+}
+
+// moveOne moves a single path into absDest and returns the new path
+// relative to root.
+func (f *FileOps) moveOne(reqPath, absDest string, overwrite bool) (string, error) {
+	absSrc, err := f.safePath(reqPath)
+	if err != nil {
+		return "", err
+	}
+	if absSrc == f.rootPath {
+		return "", fmt.Errorf("cannot move root directory")
+	}
+	info, err := os.Lstat(absSrc)
+	if err != nil {
+		return "", err
+	}
+	absTarget := filepath.Join(absDest, filepath.Base(absSrc))
+	rel := f.buildRelPath(absTarget)
+
+	// Already in the destination: nothing to do.
+	if absTarget == absSrc {
+		return rel, nil
+	}
+	if info.IsDir() && (absDest == absSrc || strings.HasPrefix(absDest, absSrc+"/")) {
+		return "", fmt.Errorf("cannot move a directory into itself")
+	}
+	// Replacing the target would delete the source along with it when the
+	// target is the source or one of its ancestors. Compare resolved paths
+	// so a symlinked parent can't disguise that.
+	srcResolved, err := filepath.EvalSymlinks(absSrc)
+	if err != nil {
+		srcResolved = absSrc
+	}
+	targetResolved, err := filepath.EvalSymlinks(absTarget)
+	if err != nil {
+		targetResolved = absTarget
+	}
+	if targetResolved == srcResolved || strings.HasPrefix(srcResolved, targetResolved+"/") {
+		return "", fmt.Errorf("cannot replace a directory containing the source")
+	}
+	if _, err := os.Lstat(absTarget); err == nil {
+		if !overwrite {
+			return "", fmt.Errorf("destination already exists")
+		}
+		if err := os.RemoveAll(absTarget); err != nil {
+			return "", err
+		}
+	}
+	if err := os.Rename(absSrc, absTarget); err != nil {
+		return "", err
+	}
+	return rel, nil
+}
+
+// Copy copies a file, or a directory recursively, preserving mode bits.
+// Symlinks inside a copied tree are recreated as links, not followed.
+// The destination must not already exist.
+func (f *FileOps) Copy(srcPath, dstPath string) error {
+	absSrc, err := f.safePath(srcPath)
+	if err != nil {
+		return err
+	}
+	absDst, err := f.safePath(dstPath)
+	if err != nil {
+		return err
+	}
+	info, err := os.Lstat(absSrc)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Lstat(absDst); err == nil {
+		return fmt.Errorf("destination already exists")
+	}
+
+	if info.IsDir() {
+		// Compare resolved paths so a symlinked parent can't disguise a
+		// copy into the source's own subtree (which would never finish).
+		srcResolved, err := filepath.EvalSymlinks(absSrc)
+		if err != nil {
+			srcResolved = absSrc
+		}
+		dstParent, err := filepath.EvalSymlinks(filepath.Dir(absDst))
+		if err != nil {
+			dstParent = filepath.Dir(absDst)
+		}
+		dstResolved := filepath.Join(dstParent, filepath.Base(absDst))
+		if dstResolved == srcResolved || strings.HasPrefix(dstResolved, srcResolved+"/") {
+			return fmt.Errorf("cannot copy a directory into itself")
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(absDst), 0755); err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return copyEntry(absSrc, absDst, info)
+	}
+	// Directory modes are applied after the walk (deepest first) so a
+	// read-only source directory doesn't block copying its children.
+	type dirMode struct {
+		path string
+		mode fs.FileMode
+	}
+	var dirs []dirMode
+	err = filepath.Walk(absSrc, func(path string, fi fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(absSrc, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(absDst, rel)
+		if fi.IsDir() {
+			dirs = append(dirs, dirMode{target, fi.Mode().Perm()})
+		}
+		return copyEntry(path, target, fi)
+	})
+	if err != nil {
+		return err
+	}
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if err := os.Chmod(dirs[i].path, dirs[i].mode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyEntry copies a single file or symlink, or creates a directory
+// (whose final mode the caller applies).
+func copyEntry(src, dst string, info fs.FileInfo) error {
+	switch {
+	case info.Mode()&fs.ModeSymlink != 0:
+		target, err := os.Readlink(src)
+		if err != nil {
+			return err
+		}
+		return os.Symlink(target, dst)
+	case info.IsDir():
+		return os.MkdirAll(dst, 0700)
+	case info.Mode().IsRegular():
+		in, err := os.Open(src)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_EXCL, info.Mode().Perm())
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(out, in); err != nil {
+			out.Close()
+			return err
+		}
+		if err := out.Close(); err != nil {
+			return err
+		}
+		return os.Chmod(dst, info.Mode().Perm())
+	default:
+		return fmt.Errorf("cannot copy special file %s", filepath.Base(src))
+	}
+}
+
+// Search walks the tree under root and returns entries whose name
+// matches pattern: a glob when it contains any of *?[, else a
+// case-insensitive substring. Symlinks are never followed or returned.
+// At most maxResults entries are returned and the walk is bounded by
+// maxSearchVisited entries and maxSearchDuration.
+func (f *FileOps) Search(root, pattern string, maxResults int) ([]FileInfo, error) {
+	absRoot, err := f.safePath(root)
+	if err != nil {
+		return nil, err
+	}
+	pattern = strings.TrimSpace(pattern)
+	if pattern == "" {
+		return nil, fmt.Errorf("search pattern required")
+	}
+	if maxResults <= 0 || maxResults > maxSearchResults {
+		maxResults = maxSearchResults
+	}
+
+	lower := strings.ToLower(pattern)
+	isGlob := strings.ContainsAny(pattern, "*?[")
+	if isGlob {
+		if _, err := filepath.Match(lower, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern: %w", err)
+		}
+	}
+	match := func(name string) bool {
+		name = strings.ToLower(name)
+		if isGlob {
+			ok, _ := filepath.Match(lower, name)
+			return ok
+		}
+		return strings.Contains(name, lower)
+	}
+
+	deadline := time.Now().Add(maxSearchDuration)
+	visited := 0
+	results := make([]FileInfo, 0)
+	errStop := errors.New("search limit reached")
+
+	err = filepath.WalkDir(absRoot, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Unreadable subtree: skip it rather than failing the search.
+			if d != nil && d.IsDir() && path != absRoot {
+				return fs.SkipDir
+			}
+			if path == absRoot {
+				return err
+			}
+			return nil
+		}
+		visited++
+		if visited > maxSearchVisited || time.Now().After(deadline) {
+			return errStop
+		}
+		if path == absRoot || d.Type()&fs.ModeSymlink != 0 {
+			return nil
+		}
+		if match(d.Name()) {
+			info, err := d.Info()
+			if err != nil {
+				return nil
+			}
+			results = append(results, f.buildFileInfo(path, info))
+			if len(results) >= maxResults {
+				return errStop
+			}
+		}
+		return nil
+	})
+	if err != nil && err != errStop {
+		return nil, err
+	}
+	return results, nil
+}
+
 // Chmod changes file permissions.
 func (f *FileOps) Chmod(reqPath string, mode os.FileMode) error {
 	abs, err := f.safePath(reqPath)
@@ -257,16 +604,20 @@ func (f *FileOps) Download(reqPath string) (io.ReadCloser, string, int64, error)
 	return file, filepath.Base(abs), info.Size(), nil
 }
 
-func (f *FileOps) buildFileInfo(absPath string, info fs.FileInfo) FileInfo {
-	// Relative path from root for API response.
+// buildRelPath converts an absolute path under root to the "/"-rooted
+// path used in API responses.
+func (f *FileOps) buildRelPath(absPath string) string {
 	relPath, _ := filepath.Rel(f.rootPath, absPath)
 	if !strings.HasPrefix(relPath, "/") {
 		relPath = "/" + relPath
 	}
+	return relPath
+}
 
+func (f *FileOps) buildFileInfo(absPath string, info fs.FileInfo) FileInfo {
 	fi := FileInfo{
 		Name:      info.Name(),
-		Path:      relPath,
+		Path:      f.buildRelPath(absPath),
 		Size:      info.Size(),
 		Mode:      info.Mode().String(),
 		ModeOctal: fmt.Sprintf("%04o", info.Mode().Perm()),
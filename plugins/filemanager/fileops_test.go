@@ -1,8 +1,12 @@
 package filemanager
 
 import (
+	"archive/tar"
+	"bytes"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -77,6 +81,59 @@ func TestReadWrite(t *testing.T) {
 	}
 }
 
+func TestReadRangeHead(t *testing.T) {
+	root := t.TempDir()
+	ops := NewFileOps(root)
+	ops.Write("/big.log", "0123456789abcdef")
+
+	data, size, err := ops.ReadRange("/big.log", 0, 4)
+	if err != nil || string(data) != "0123" || size != 16 {
+		t.Fatalf("head = %q size=%d err=%v", data, size, err)
+	}
+	data, _, _ = ops.ReadRange("/big.log", 10, 100)
+	if string(data) != "abcdef" {
+		t.Fatalf("range past EOF should be short, got %q", data)
+	}
+}
+
+func TestReadTail(t *testing.T) {
+	root := t.TempDir()
+	ops := NewFileOps(root)
+	ops.Write("/big.log", "line1\nline2\nline3\n")
+
+	data, offset, size, err := ops.ReadTail("/big.log", 6)
+	if err != nil || string(data) != "line3\n" || offset != 12 || size != 18 {
+		t.Fatalf("tail = %q offset=%d size=%d err=%v", data, offset, size, err)
+	}
+	data, offset, _, _ = ops.ReadTail("/big.log", 1000)
+	if offset != 0 || len(data) != 18 {
+		t.Fatalf("tail larger than file should return whole file, got offset=%d len=%d", offset, len(data))
+	}
+}
+
+func TestReadRangeOutOfBounds(t *testing.T) {
+	root := t.TempDir()
+	ops := NewFileOps(root)
+	ops.Write("/small.txt", "abc")
+
+	if _, _, err := ops.ReadRange("/small.txt", 10, 1); err == nil {
+		t.Fatal("expected error for offset beyond EOF")
+	}
+	if _, _, err := ops.ReadRange("/small.txt", -1, 1); err == nil {
+		t.Fatal("expected error for negative offset")
+	}
+}
+
+func TestReadRangeDirectory(t *testing.T) {
+	root := t.TempDir()
+	ops := NewFileOps(root)
+	ops.Mkdir("/dir")
+
+	if _, _, err := ops.ReadRange("/dir", 0, 10); err == nil {
+		t.Fatal("expected error reading a directory")
+	}
+}
+
 func TestMkdirAndDelete(t *testing.T) {
 	root := t.TempDir()
 	ops := NewFileOps(root)
@@ -113,6 +170,252 @@ func TestRename(t *testing.T) {
 	}
 }
 
+func TestCopyFile(t *testing.T) {
+	root := t.TempDir()
+	ops := NewFileOps(root)
+
+	ops.Write("/src.sh", "#!/bin/sh")
+	ops.Chmod("/src.sh", 0750)
+	if err := ops.Copy("/src.sh", "/sub/dst.sh"); err != nil {
+		t.Fatalf("copy error: %v", err)
+	}
+	got, err := ops.Read("/sub/dst.sh")
+	if err != nil || got != "#!/bin/sh" {
+		t.Fatalf("copied content mismatch: %v %q", err, got)
+	}
+	info, _ := os.Stat(filepath.Join(root, "sub/dst.sh"))
+	if info.Mode().Perm() != 0750 {
+		t.Errorf("got mode %v, want 0750", info.Mode().Perm())
+	}
+	if _, err := os.Stat(filepath.Join(root, "src.sh")); err != nil {
+		t.Fatal("source should still exist")
+	}
+	if err := ops.Copy("/src.sh", "/sub/dst.sh"); err == nil {
+		t.Fatal("expected error copying onto an existing file")
+	}
+}
+
+func TestCopyDirRecursive(t *testing.T) {
+	root := t.TempDir()
+	ops := NewFileOps(root)
+
+	ops.Write("/site/index.html", "<h1>hi</h1>")
+	ops.Write("/site/assets/app.js", "console.log(1)")
+	ops.Chmod("/site/assets", 0700)
+	os.Symlink("index.html", filepath.Join(root, "site/home.html"))
+
+	if err := ops.Copy("/site", "/backup/site"); err != nil {
+		t.Fatalf("copy error: %v", err)
+	}
+	if got, _ := ops.Read("/backup/site/assets/app.js"); got != "console.log(1)" {
+		t.Fatalf("nested file not copied: %q", got)
+	}
+	info, _ := os.Stat(filepath.Join(root, "backup/site/assets"))
+	if info.Mode().Perm() != 0700 {
+		t.Errorf("dir mode %v, want 0700", info.Mode().Perm())
+	}
+	if link, err := os.Readlink(filepath.Join(root, "backup/site/home.html")); err != nil || link != "index.html" {
+		t.Errorf("symlink not preserved: %q %v", link, err)
+	}
+}
+
+func TestCopyDirIntoItself(t *testing.T) {
+	root := t.TempDir()
+	ops := NewFileOps(root)
+
+	ops.Write("/dir/a.txt", "a")
+	if err := ops.Copy("/dir", "/dir/nested"); err == nil {
+		t.Fatal("expected error copying a directory into itself")
+	}
+	if _, err := os.Stat(filepath.Join(root, "dir/nested")); !os.IsNotExist(err) {
+		t.Fatal("nothing should be created on a rejected self-copy")
+	}
+}
+
+func TestCopyTraversal(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	ops := NewFileOps(root)
+
+	ops.Write("/secret.txt", "s")
+	os.Symlink(outside, filepath.Join(root, "escape"))
+
+	if err := ops.Copy("/secret.txt", "/escape/secret.txt"); err == nil {
+		t.Fatal("expected error copying out of root via symlink")
+	}
+	if err := ops.Copy("/escape", "/inside"); err == nil {
+		t.Fatal("expected error copying a source that resolves outside root")
+	}
+	if _, err := os.Stat(filepath.Join(outside, "secret.txt")); !os.IsNotExist(err) {
+		t.Fatal("file escaped root")
+	}
+}
+
+func TestMoveMultiple(t *testing.T) {
+	root := t.TempDir()
+	ops := NewFileOps(root)
+
+	ops.Write("/a.txt", "a")
+	ops.Write("/b.txt", "b")
+	ops.Write("/docs/c.txt", "c")
+
+	results, err := ops.Move([]string{"/a.txt", "/b.txt", "/docs", "/missing.txt"}, "/archive", false)
+	if err != nil {
+		t.Fatalf("move error: %v", err)
+	}
+	if len(results) != 4 {
+		t.Fatalf("got %d results, want 4", len(results))
+	}
+	for _, r := range results[:3] {
+		if !r.OK {
+			t.Errorf("%s: unexpected failure %q", r.Path, r.Error)
+		}
+	}
+	if results[3].OK || results[3].Error == "" {
+		t.Error("missing source should be reported as a failure")
+	}
+	if got, _ := ops.Read("/archive/docs/c.txt"); got != "c" {
+		t.Fatalf("moved directory content mismatch: %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(root, "a.txt")); !os.IsNotExist(err) {
+		t.Fatal("source should be gone after move")
+	}
+
+	// Moving into the directory it already lives in is a no-op.
+	results, _ = ops.Move([]string{"/archive/a.txt"}, "/archive", false)
+	if !results[0].OK {
+		t.Fatalf("self-move should succeed as a no-op: %q", results[0].Error)
+	}
+	if got, _ := ops.Read("/archive/a.txt"); got != "a" {
+		t.Fatal("self-move must not disturb the file")
+	}
+}
+
+func TestMoveOverwrite(t *testing.T) {
+	root := t.TempDir()
+	ops := NewFileOps(root)
+
+	ops.Write("/new/report.txt", "new")
+	ops.Write("/dest/report.txt", "old")
+
+	results, _ := ops.Move([]string{"/new/report.txt"}, "/dest", false)
+	if results[0].OK {
+		t.Fatal("expected conflict without overwrite")
+	}
+	if got, _ := ops.Read("/dest/report.txt"); got != "old" {
+		t.Fatal("existing file must be untouched on conflict")
+	}
+
+	results, _ = ops.Move([]string{"/new/report.txt"}, "/dest", true)
+	if !results[0].OK {
+		t.Fatalf("overwrite move failed: %q", results[0].Error)
+	}
+	if got, _ := ops.Read("/dest/report.txt"); got != "new" {
+		t.Fatalf("overwrite did not replace content: %q", got)
+	}
+}
+
+func TestMoveOverwriteAncestor(t *testing.T) {
+	root := t.TempDir()
+	ops := NewFileOps(root)
+
+	// Moving /a/x/x into /a targets /a/x, the source's own parent.
+	ops.Write("/a/x/x/data.txt", "keep")
+
+	results, _ := ops.Move([]string{"/a/x/x"}, "/a", true)
+	if results[0].OK {
+		t.Fatal("expected overwriting an ancestor of the source to be refused")
+	}
+	if got, _ := ops.Read("/a/x/x/data.txt"); got != "keep" {
+		t.Fatalf("source was lost: %q", got)
+	}
+}
+
+func TestMoveTraversal(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	ops := NewFileOps(root)
+
+	ops.Write("/a.txt", "a")
+	os.Symlink(outside, filepath.Join(root, "escape"))
+
+	if _, err := ops.Move([]string{"/a.txt"}, "/escape", false); err == nil {
+		t.Fatal("expected error for destination outside root")
+	}
+	os.WriteFile(filepath.Join(outside, "x.txt"), []byte("x"), 0644)
+	results, err := ops.Move([]string{"/escape/x.txt"}, "/", false)
+	if err != nil || results[0].OK {
+		t.Fatalf("source outside root should fail per-path: %v %+v", err, results)
+	}
+	if _, err := os.Stat(filepath.Join(outside, "x.txt")); err != nil {
+		t.Fatal("outside file must not be moved")
+	}
+}
+
+func TestSearch(t *testing.T) {
+	root := t.TempDir()
+	ops := NewFileOps(root)
+
+	ops.Write("/app/config.yaml", "")
+	ops.Write("/app/nested/Config.JSON", "")
+	ops.Write("/app/readme.md", "")
+
+	got, err := ops.Search("/app", "config", 0)
+	if err != nil {
+		t.Fatalf("search error: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("substring search found %d, want 2: %+v", len(got), got)
+	}
+
+	got, _ = ops.Search("/", "*.md", 0)
+	if len(got) != 1 || got[0].Path != "/app/readme.md" {
+		t.Fatalf("glob search = %+v, want /app/readme.md", got)
+	}
+
+	got, _ = ops.Search("/app", "nothing-here", 0)
+	if len(got) != 0 {
+		t.Fatalf("expected no matches, got %+v", got)
+	}
+}
+
+func TestSearchMaxResults(t *testing.T) {
+	root := t.TempDir()
+	ops := NewFileOps(root)
+
+	for i := 0; i < 10; i++ {
+		ops.Write(filepath.Join("/logs", string(rune('a'+i))+".log"), "")
+	}
+	got, err := ops.Search("/logs", "*.log", 3)
+	if err != nil {
+		t.Fatalf("search error: %v", err)
+	}
+	if len(got) != 3 {
+		t.Fatalf("got %d results, want cap of 3", len(got))
+	}
+}
+
+func TestSearchDoesNotFollowSymlinks(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	ops := NewFileOps(root)
+
+	os.WriteFile(filepath.Join(outside, "secret.key"), []byte("x"), 0600)
+	os.Symlink(outside, filepath.Join(root, "link"))
+	ops.Write("/local.key", "")
+
+	got, err := ops.Search("/", "*.key", 0)
+	if err != nil {
+		t.Fatalf("search error: %v", err)
+	}
+	if len(got) != 1 || got[0].Path != "/local.key" {
+		t.Fatalf("search escaped root via symlink: %+v", got)
+	}
+	if _, err := ops.Search("/link", "*", 0); err == nil {
+		t.Fatal("expected error searching from a symlink outside root")
+	}
+}
+
 func TestChmod(t *testing.T) {
 	root := t.TempDir()
 	ops := NewFileOps(root)
@@ -198,3 +501,99 @@ func TestCompressExtractZip(t *testing.T) {
 		t.Fatalf("extracted content mismatch: %v %q", err, got)
 	}
 }
+
+func TestCompressExtractTarBz2AndXz(t *testing.T) {
+	for _, format := range []string{"tar.bz2", "tar.xz"} {
+		t.Run(format, func(t *testing.T) {
+			if _, err := exec.LookPath(map[string]string{"tar.bz2": "bzip2", "tar.xz": "xz"}[format]); err != nil {
+				t.Skipf("compressor not installed: %v", err)
+			}
+			root := t.TempDir()
+			ops := NewFileOps(root)
+
+			ops.Write("/src/a.txt", "aaa")
+			ops.Write("/src/nested/b.txt", "bbb")
+
+			archive := "/archive." + format
+			if err := ops.Compress([]string{"/src"}, archive, format); err != nil {
+				t.Fatalf("compress %s error: %v", format, err)
+			}
+			ops.Mkdir("/out")
+			if err := ops.Extract(archive, "/out"); err != nil {
+				t.Fatalf("extract %s error: %v", format, err)
+			}
+			got, err := ops.Read("/out/src/nested/b.txt")
+			if err != nil || got != "bbb" {
+				t.Fatalf("extracted content mismatch: %v %q", err, got)
+			}
+		})
+	}
+}
+
+func TestExtractTarXzWithTrailingPadding(t *testing.T) {
+	if _, err := exec.LookPath("xz"); err != nil {
+		t.Skip("xz not installed")
+	}
+	root := t.TempDir()
+	ops := NewFileOps(root)
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	tw.WriteHeader(&tar.Header{Name: "a.txt", Mode: 0644, Size: 3})
+	tw.Write([]byte("aaa"))
+	tw.Close()
+	// Zero padding after the end-of-archive marker, more than a pipe holds.
+	buf.Write(make([]byte, 1<<20))
+
+	cmd := exec.Command("xz", "-c")
+	cmd.Stdin = &buf
+	compressed, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("xz: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "padded.tar.xz"), compressed, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ops.Mkdir("/out")
+	if err := ops.Extract("/padded.tar.xz", "/out"); err != nil {
+		t.Fatalf("extract padded archive: %v", err)
+	}
+	if got, err := ops.Read("/out/a.txt"); err != nil || got != "aaa" {
+		t.Fatalf("extracted content mismatch: %v %q", err, got)
+	}
+}
+
+func TestExtractSizeLimit(t *testing.T) {
+	if _, err := exec.LookPath("xz"); err != nil {
+		t.Skip("xz not installed")
+	}
+	root := t.TempDir()
+	ops := NewFileOps(root)
+	ops.Write("/src/big.txt", strings.Repeat("x", 4096))
+
+	for _, format := range []string{"tar.gz", "tar.bz2", "tar.xz"} {
+		archive := "/big." + format
+		if err := ops.Compress([]string{"/src"}, archive, format); err != nil {
+			t.Fatalf("compress %s: %v", format, err)
+		}
+		func() {
+			old := maxExtractSize
+			maxExtractSize = 1024
+			defer func() { maxExtractSize = old }()
+			ops.Mkdir("/out-" + format)
+			err := ops.Extract(archive, "/out-"+format)
+			if err == nil || !strings.Contains(err.Error(), "too large") {
+				t.Fatalf("%s: expected size limit error, got %v", format, err)
+			}
+		}()
+	}
+}
+
+func TestCompressUnknownFormat(t *testing.T) {
+	ops := NewFileOps(t.TempDir())
+	ops.Write("/a.txt", "a")
+	if err := ops.Compress([]string{"/a.txt"}, "/a.rar", "rar"); err == nil || !strings.Contains(err.Error(), "unsupported format") {
+		t.Fatalf("expected unsupported format error, got %v", err)
+	}
+}
@@ -0,0 +1,170 @@
+package filemanager
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Grep defaults and hard limits.
+const (
+	defaultGrepMatches  = 200
+	maxGrepMatches      = 2000
+	defaultGrepFileSize = 1 << 20   // 1 MB
+	maxGrepFileSize     = 10 << 20  // 10 MB, same as Read
+	maxGrepBytesScanned = 100 << 20 // 100 MB across all files
+	maxGrepLineLength   = 500       // longer lines are truncated in results
+	binarySniffSize     = 8000
+)
+
+// GrepOptions tunes a content search. Zero values take the defaults.
+type GrepOptions struct {
+	IgnoreCase  bool
+	MaxMatches  int
+	MaxFileSize int64
+}
+
+// GrepMatch is one matching line.
+type GrepMatch struct {
+	Path    string `json:"path"`
+	Line    int    `json:"line"`
+	Content string `json:"content"`
+}
+
+// GrepResult holds the matches and whether a limit cut the search short.
+type GrepResult struct {
+	Matches   []GrepMatch `json:"matches"`
+	Truncated bool        `json:"truncated"`
+}
+
+// Grep searches the contents of text files under root for query. Binary
+// files (a NUL byte in the first 8000 bytes), files above MaxFileSize and
+// symlinks are skipped. The search stops at MaxMatches, after
+// maxGrepBytesScanned bytes, or after maxSearchDuration.
+func (f *FileOps) Grep(root, query string, opts GrepOptions) (*GrepResult, error) {
+	absRoot, err := f.safePath(root)
+	if err != nil {
+		return nil, err
+	}
+	if query == "" {
+		return nil, fmt.Errorf("query required")
+	}
+	if opts.MaxMatches <= 0 {
+		opts.MaxMatches = defaultGrepMatches
+	}
+	if opts.MaxMatches > maxGrepMatches {
+		opts.MaxMatches = maxGrepMatches
+	}
+	if opts.MaxFileSize <= 0 {
+		opts.MaxFileSize = defaultGrepFileSize
+	}
+	if opts.MaxFileSize > maxGrepFileSize {
+		opts.MaxFileSize = maxGrepFileSize
+	}
+
+	needle := []byte(query)
+	if opts.IgnoreCase {
+		needle = bytes.ToLower(needle)
+	}
+
+	result := &GrepResult{Matches: make([]GrepMatch, 0)}
+	deadline := time.Now().Add(maxSearchDuration)
+	var scanned int64
+	errStop := errors.New("grep limit reached")
+
+	err = filepath.WalkDir(absRoot, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == absRoot {
+				return err
+			}
+			if d != nil && d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if time.Now().After(deadline) {
+			result.Truncated = true
+			return errStop
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil || info.Size() > opts.MaxFileSize {
+			return nil
+		}
+		if scanned+info.Size() > maxGrepBytesScanned {
+			result.Truncated = true
+			return errStop
+		}
+		scanned += info.Size()
+
+		matches, err := grepFile(path, needle, opts.IgnoreCase, opts.MaxMatches-len(result.Matches))
+		if err != nil {
+			return nil
+		}
+		rel := f.buildRelPath(path)
+		for _, m := range matches {
+			m.Path = rel
+			result.Matches = append(result.Matches, m)
+		}
+		if len(result.Matches) >= opts.MaxMatches {
+			result.Truncated = true
+			return errStop
+		}
+		return nil
+	})
+	if err != nil && err != errStop {
+		return nil, err
+	}
+	return result, nil
+}
+
+// grepFile returns up to limit matching lines of a text file, or nothing
+// if the file looks binary.
+func grepFile(path string, needle []byte, ignoreCase bool, limit int) ([]GrepMatch, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	head := make([]byte, binarySniffSize)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, err
+	}
+	if bytes.IndexByte(head[:n], 0) >= 0 {
+		return nil, nil
+	}
+
+	scanner := bufio.NewScanner(io.MultiReader(bytes.NewReader(head[:n]), file))
+	scanner.Buffer(make([]byte, 0, 64*1024), maxGrepFileSize)
+	var matches []GrepMatch
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := scanner.Bytes()
+		hay := line
+		if ignoreCase {
+			hay = bytes.ToLower(line)
+		}
+		if !bytes.Contains(hay, needle) {
+			continue
+		}
+		content := strings.TrimRight(string(line), "\r")
+		if len(content) > maxGrepLineLength {
+			content = content[:maxGrepLineLength]
+		}
+		matches = append(matches, GrepMatch{Line: lineNo, Content: content})
+		if len(matches) >= limit {
+			break
+		}
+	}
+	return matches, nil
+}
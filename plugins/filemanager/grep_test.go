@@ -0,0 +1,82 @@
+package filemanager
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGrepNested(t *testing.T) {
+	root := t.TempDir()
+	ops := NewFileOps(root)
+
+	ops.Write("/etc/app/nested/server.conf", "port = 80\nlisten 0.0.0.0\nserver_name example.com\n")
+	ops.Write("/etc/app/other.conf", "nothing here\n")
+
+	res, err := ops.Grep("/etc", "server_name", GrepOptions{})
+	if err != nil {
+		t.Fatalf("grep error: %v", err)
+	}
+	if len(res.Matches) != 1 {
+		t.Fatalf("got %d matches, want 1: %+v", len(res.Matches), res.Matches)
+	}
+	m := res.Matches[0]
+	if m.Path != "/etc/app/nested/server.conf" || m.Line != 3 || m.Content != "server_name example.com" {
+		t.Fatalf("unexpected match: %+v", m)
+	}
+}
+
+func TestGrepIgnoreCase(t *testing.T) {
+	root := t.TempDir()
+	ops := NewFileOps(root)
+
+	ops.Write("/log.txt", "ERROR: disk full\nerror: retry\ninfo: ok\n")
+
+	res, _ := ops.Grep("/", "error", GrepOptions{})
+	if len(res.Matches) != 1 {
+		t.Fatalf("case-sensitive: got %d matches, want 1", len(res.Matches))
+	}
+	res, _ = ops.Grep("/", "error", GrepOptions{IgnoreCase: true})
+	if len(res.Matches) != 2 {
+		t.Fatalf("ignore-case: got %d matches, want 2", len(res.Matches))
+	}
+}
+
+func TestGrepSkipsBinary(t *testing.T) {
+	root := t.TempDir()
+	ops := NewFileOps(root)
+
+	os.WriteFile(filepath.Join(root, "blob.bin"), []byte("needle\x00\x01\x02needle"), 0644)
+	ops.Write("/text.txt", "needle\n")
+
+	res, _ := ops.Grep("/", "needle", GrepOptions{})
+	if len(res.Matches) != 1 || res.Matches[0].Path != "/text.txt" {
+		t.Fatalf("binary file should be skipped: %+v", res.Matches)
+	}
+}
+
+func TestGrepSizeCap(t *testing.T) {
+	root := t.TempDir()
+	ops := NewFileOps(root)
+
+	ops.Write("/big.txt", strings.Repeat("x", 2048)+"\nneedle\n")
+	ops.Write("/small.txt", "needle\n")
+
+	res, _ := ops.Grep("/", "needle", GrepOptions{MaxFileSize: 1024})
+	if len(res.Matches) != 1 || res.Matches[0].Path != "/small.txt" {
+		t.Fatalf("file over size cap should be skipped: %+v", res.Matches)
+	}
+}
+
+func TestGrepMatchCap(t *testing.T) {
+	root := t.TempDir()
+	ops := NewFileOps(root)
+
+	ops.Write("/many.txt", strings.Repeat("hit\n", 50))
+
+	res, _ := ops.Grep("/", "hit", GrepOptions{MaxMatches: 5})
+	if len(res.Matches) != 5 || !res.Truncated {
+		t.Fatalf("got %d matches (truncated=%v), want 5 truncated", len(res.Matches), res.Truncated)
+	}
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
 	"github.com/web-casa/webcasa/internal/auth"
+	"gorm.io/gorm"
 )
 
 const maxUploadSize = 100 << 20 // 100 MB
@@ -34,19 +35,59 @@ var wsUpgrader = websocket.Upgrader{
 
 // Handler exposes file manager REST and WebSocket endpoints.
 type Handler struct {
-	fileOps *FileOps
-	termMgr *TerminalManager
+	fileOps    *FileOps // admin/owner root
+	sandboxOps *FileOps // root for lower roles; nil disables their access
+	db         *gorm.DB
+	termMgr    *TerminalManager
+	uploads    *ChunkedUploads
 }
 
-// NewHandler creates a new file manager handler.
-func NewHandler(fileOps *FileOps, termMgr *TerminalManager) *Handler {
-	return &Handler{fileOps: fileOps, termMgr: termMgr}
+// NewHandler creates a new file manager handler. sandboxOps may be nil, in
+// which case only admins and the owner can browse files.
+func NewHandler(fileOps, sandboxOps *FileOps, db *gorm.DB, termMgr *TerminalManager, uploads *ChunkedUploads) *Handler {
+	return &Handler{fileOps: fileOps, sandboxOps: sandboxOps, db: db, termMgr: termMgr, uploads: uploads}
+}
+
+// userRole returns the caller's panel role. The RBAC middleware stores it
+// on admin/operator routes; on plain JWT routes it is looked up by user_id.
+func (h *Handler) userRole(c *gin.Context) string {
+	if role, ok := c.Get("user_role"); ok {
+		if s, ok := role.(string); ok {
+			return s
+		}
+	}
+	userID, _ := c.Get("user_id")
+	if h.db == nil || userID == nil {
+		return auth.RoleViewer
+	}
+	var role string
+	if err := h.db.Table("users").Select("role").Where("id = ?", userID).Row().Scan(&role); err != nil {
+		return auth.RoleViewer
+	}
+	return role
+}
+
+// scopedOps returns the FileOps rooted for the caller's role. It writes a
+// 403 and returns nil when the role has no file access.
+func (h *Handler) scopedOps(c *gin.Context) *FileOps {
+	if hasFullAccess(h.userRole(c)) {
+		return h.fileOps
+	}
+	if h.sandboxOps == nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "File access is not enabled for your role"})
+		return nil
+	}
+	return h.sandboxOps
 }
 
 // List returns directory entries.
 func (h *Handler) List(c *gin.Context) {
+	ops := h.scopedOps(c)
+	if ops == nil {
+		return
+	}
 	path := c.DefaultQuery("path", "/")
-	entries, err := h.fileOps.List(path)
+	entries, err := ops.List(path)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -54,14 +95,61 @@ func (h *Handler) List(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"files": entries, "path": path})
 }
 
+// Search finds files by name under a directory.
+func (h *Handler) Search(c *gin.Context) {
+	ops := h.scopedOps(c)
+	if ops == nil {
+		return
+	}
+	path := c.DefaultQuery("path", "/")
+	q := c.Query("q")
+	if q == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "q required"})
+		return
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "200"))
+	files, err := ops.Search(path, q, limit)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"files": files, "path": path, "q": q})
+}
+
+// Grep searches file contents under a directory.
+func (h *Handler) Grep(c *gin.Context) {
+	ops := h.scopedOps(c)
+	if ops == nil {
+		return
+	}
+	path := c.DefaultQuery("path", "/")
+	q := c.Query("q")
+	if q == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "q required"})
+		return
+	}
+	ignoreCase, _ := strconv.ParseBool(c.DefaultQuery("ignore_case", "false"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "0"))
+	result, err := ops.Grep(path, q, GrepOptions{IgnoreCase: ignoreCase, MaxMatches: limit})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
 // Read returns file content as string.
 func (h *Handler) Read(c *gin.Context) {
+	ops := h.scopedOps(c)
+	if ops == nil {
+		return
+	}
 	path := c.Query("path")
 	if path == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "path required"})
 		return
 	}
-	content, err := h.fileOps.Read(path)
+	content, err := ops.Read(path)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -69,6 +157,48 @@ func (h *Handler) Read(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"content": content, "path": path})
 }
 
+// Preview returns a bounded byte range of a file. With tail=N it returns
+// the last N bytes instead. X-File-Size carries the total size and
+// X-Range-Offset where the returned bytes start.
+func (h *Handler) Preview(c *gin.Context) {
+	ops := h.scopedOps(c)
+	if ops == nil {
+		return
+	}
+	path := c.Query("path")
+	if path == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "path required"})
+		return
+	}
+
+	var (
+		data   []byte
+		offset int64
+		size   int64
+		err    error
+	)
+	if tail := c.Query("tail"); tail != "" {
+		n, perr := strconv.ParseInt(tail, 10, 64)
+		if perr != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "tail must be a positive byte count"})
+			return
+		}
+		data, offset, size, err = ops.ReadTail(path, n)
+	} else {
+		var length int64
+		offset, _ = strconv.ParseInt(c.DefaultQuery("offset", "0"), 10, 64)
+		length, _ = strconv.ParseInt(c.DefaultQuery("length", "0"), 10, 64)
+		data, size, err = ops.ReadRange(path, offset, length)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("X-File-Size", strconv.FormatInt(size, 10))
+	c.Header("X-Range-Offset", strconv.FormatInt(offset, 10))
+	c.Data(http.StatusOK, "text/plain; charset=utf-8", data)
+}
+
 // Write saves content to a file.
 func (h *Handler) Write(c *gin.Context) {
 	// Limit request body to maxReadSize (10 MB) to prevent OOM.
@@ -120,14 +250,65 @@ func (h *Handler) Upload(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"status": "ok", "path": dest})
 }
 
+// UploadChunk stores one chunk of a resumable upload. The raw request
+// body is the chunk; X-Upload-Id, X-Chunk-Index (0-based) and
+// X-Total-Chunks identify it.
+func (h *Handler) UploadChunk(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxUploadSize)
+
+	id := c.GetHeader("X-Upload-Id")
+	index, err1 := strconv.Atoi(c.GetHeader("X-Chunk-Index"))
+	total, err2 := strconv.Atoi(c.GetHeader("X-Total-Chunks"))
+	if id == "" || err1 != nil || err2 != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "X-Upload-Id, X-Chunk-Index and X-Total-Chunks headers required"})
+		return
+	}
+	if err := h.uploads.PutChunk(id, index, total, c.Request.Body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "index": index})
+}
+
+// UploadComplete assembles a chunked upload into its destination.
+func (h *Handler) UploadComplete(c *gin.Context) {
+	var req struct {
+		UploadID string `json:"upload_id" binding:"required"`
+		Path     string `json:"path" binding:"required"`
+		Filename string `json:"filename"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	dest := req.Path
+	if strings.HasSuffix(dest, "/") {
+		if req.Filename == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "filename required when path is a directory"})
+			return
+		}
+		// Same traversal guard as Upload.
+		dest += filepath.Base(req.Filename)
+	}
+	if err := h.uploads.Complete(h.fileOps, req.UploadID, dest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "path": dest})
+}
+
 // Download streams a file.
 func (h *Handler) Download(c *gin.Context) {
+	ops := h.scopedOps(c)
+	if ops == nil {
+		return
+	}
 	path := c.Query("path")
 	if path == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "path required"})
 		return
 	}
-	reader, name, size, err := h.fileOps.Download(path)
+	reader, name, size, err := ops.Download(path)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -199,6 +380,48 @@ func (h *Handler) Rename(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"status": "ok"})
 }
 
+// Move moves several files or directories into one destination directory.
+func (h *Handler) Move(c *gin.Context) {
+	var req struct {
+		Paths     []string `json:"paths" binding:"required"`
+		Dest      string   `json:"dest" binding:"required"`
+		Overwrite bool     `json:"overwrite"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	results, err := h.fileOps.Move(req.Paths, req.Dest, req.Overwrite)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	failed := 0
+	for _, r := range results {
+		if !r.OK {
+			failed++
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"results": results, "failed": failed})
+}
+
+// Copy copies a file or directory.
+func (h *Handler) Copy(c *gin.Context) {
+	var req struct {
+		Src string `json:"src" binding:"required"`
+		Dst string `json:"dst" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.fileOps.Copy(req.Src, req.Dst); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
 // Chmod changes file permissions.
 func (h *Handler) Chmod(c *gin.Context) {
 	var req struct {
@@ -223,12 +446,16 @@ func (h *Handler) Chmod(c *gin.Context) {
 
 // Info returns file metadata.
 func (h *Handler) Info(c *gin.Context) {
+	ops := h.scopedOps(c)
+	if ops == nil {
+		return
+	}
 	path := c.Query("path")
 	if path == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "path required"})
 		return
 	}
-	fi, err := h.fileOps.Stat(path)
+	fi, err := ops.Stat(path)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -271,6 +498,20 @@ func (h *Handler) Extract(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"status": "ok"})
 }
 
+// ListTerminals returns the live terminal sessions.
+func (h *Handler) ListTerminals(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"sessions": h.termMgr.List()})
+}
+
+// KillTerminal terminates a terminal session.
+func (h *Handler) KillTerminal(c *gin.Context) {
+	if err := h.termMgr.Kill(c.Param("id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
 // TerminalWS handles WebSocket terminal connections.
 func (h *Handler) TerminalWS(c *gin.Context) {
 	cols, _ := strconv.ParseUint(c.DefaultQuery("cols", "80"), 10, 16)
@@ -291,13 +532,16 @@ func (h *Handler) TerminalWS(c *gin.Context) {
 	}
 	defer h.termMgr.Close(session.ID)
 
-	// PTY → WebSocket (server output).
+	// PTY → WebSocket (server output). When the PTY goes away (shell
+	// exit, Kill, idle reaper) close the socket so the read loop below
+	// unblocks too.
 	done := make(chan struct{})
 	go func() {
 		defer close(done)
+		defer conn.Close()
 		buf := make([]byte, 4096)
 		for {
-			n, err := session.PTY.Read(buf)
+			n, err := session.Read(buf)
 			if err != nil {
 				return
 			}
@@ -321,13 +565,13 @@ func (h *Handler) TerminalWS(c *gin.Context) {
 			case "resize":
 				h.termMgr.Resize(session.ID, input.Cols, input.Rows)
 			case "data":
-				session.PTY.Write([]byte(input.Data))
+				session.Write([]byte(input.Data))
 			}
 			continue
 		}
 
 		// Raw keystrokes.
-		session.PTY.Write(msg)
+		session.Write(msg)
 	}
 
 	<-done
@@ -0,0 +1,143 @@
+package filemanager
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/web-casa/webcasa/internal/auth"
+	"github.com/web-casa/webcasa/internal/model"
+	pluginpkg "github.com/web-casa/webcasa/internal/plugin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const testJWTSecret = "filemanager-test-secret"
+
+// setupRoleRouter registers the plugin's routes behind the same JWT and
+// admin middleware the panel uses, with root_path at adminRoot and, when
+// set, viewer_root at sandbox. It returns the router and a token per role.
+func setupRoleRouter(t *testing.T, adminRoot, sandbox string) (*gin.Engine, map[string]string) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	t.Cleanup(func() { sqlDB.Close() })
+	if err := db.AutoMigrate(&model.User{}, &model.Setting{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	db.Create(&model.Setting{Key: "plugin.filemanager.root_path", Value: adminRoot})
+	if sandbox != "" {
+		db.Create(&model.Setting{Key: "plugin.filemanager.viewer_root", Value: sandbox})
+	}
+
+	tokens := map[string]string{}
+	for _, role := range []string{auth.RoleAdmin, auth.RoleViewer} {
+		u := model.User{Username: role, Role: role}
+		if err := db.Create(&u).Error; err != nil {
+			t.Fatalf("create %s: %v", role, err)
+		}
+		tok, err := auth.GenerateToken(u.ID, u.Username, role, testJWTSecret)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tokens[role] = tok
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	api := r.Group("/api/plugins/filemanager", auth.Middleware(testJWTSecret, auth.WithDB(db)))
+	admin := api.Group("", auth.RequireAdmin(db))
+	p := New()
+	if err := p.Init(&pluginpkg.Context{
+		DB:          db,
+		Router:      api,
+		AdminRouter: admin,
+		Logger:      slog.New(slog.NewTextHandler(io.Discard, nil)),
+		DataDir:     t.TempDir(),
+		ConfigStore: pluginpkg.NewConfigStore(db, "filemanager"),
+	}); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	return r, tokens
+}
+
+func doFileRequest(r *gin.Engine, method, path, token string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(method, "/api/plugins/filemanager"+path, strings.NewReader(`{"path":"/new.txt","content":"x"}`))
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestHandler_ViewerConfinedToSandbox(t *testing.T) {
+	adminRoot := t.TempDir()
+	sandbox := filepath.Join(adminRoot, "sandbox")
+	os.MkdirAll(sandbox, 0755)
+	os.WriteFile(filepath.Join(adminRoot, "secret.txt"), []byte("top"), 0644)
+	os.WriteFile(filepath.Join(sandbox, "public.txt"), []byte("ok"), 0644)
+	os.Symlink(adminRoot, filepath.Join(sandbox, "up"))
+	r, tokens := setupRoleRouter(t, adminRoot, sandbox)
+	viewer := tokens[auth.RoleViewer]
+
+	w := doFileRequest(r, http.MethodGet, "/list?path=/", viewer)
+	if w.Code != http.StatusOK {
+		t.Fatalf("viewer list: %d %s", w.Code, w.Body)
+	}
+	var listing struct {
+		Files []FileInfo `json:"files"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &listing)
+	for _, f := range listing.Files {
+		if f.Name == "secret.txt" || f.Name == "sandbox" {
+			t.Fatalf("viewer listing shows the admin root: %s", w.Body)
+		}
+	}
+
+	w = doFileRequest(r, http.MethodGet, "/read?path=/public.txt", viewer)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"content":"ok"`) {
+		t.Fatalf("viewer read inside sandbox: %d %s", w.Code, w.Body)
+	}
+	for _, path := range []string{"/../secret.txt", "/up/secret.txt", "/secret.txt"} {
+		w := doFileRequest(r, http.MethodGet, "/read?path="+path, viewer)
+		if w.Code == http.StatusOK || strings.Contains(w.Body.String(), "top") {
+			t.Errorf("viewer read %s outside the sandbox: %d %s", path, w.Code, w.Body)
+		}
+	}
+	if w := doFileRequest(r, http.MethodPost, "/write", viewer); w.Code != http.StatusForbidden {
+		t.Errorf("viewer write: %d, want 403", w.Code)
+	}
+
+	w = doFileRequest(r, http.MethodGet, "/read?path=/secret.txt", tokens[auth.RoleAdmin])
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"content":"top"`) {
+		t.Fatalf("admin read at admin root: %d %s", w.Code, w.Body)
+	}
+}
+
+func TestHandler_ViewerWithoutSandboxRefused(t *testing.T) {
+	adminRoot := t.TempDir()
+	os.WriteFile(filepath.Join(adminRoot, "secret.txt"), []byte("top"), 0644)
+	r, tokens := setupRoleRouter(t, adminRoot, "")
+
+	for _, path := range []string{"/list?path=/", "/read?path=/secret.txt"} {
+		if w := doFileRequest(r, http.MethodGet, path, tokens[auth.RoleViewer]); w.Code != http.StatusForbidden {
+			t.Errorf("viewer GET %s without viewer_root: %d %s, want 403", path, w.Code, w.Body)
+		}
+	}
+	if w := doFileRequest(r, http.MethodGet, "/list?path=/", tokens[auth.RoleAdmin]); w.Code != http.StatusOK {
+		t.Errorf("admin list: %d %s", w.Code, w.Body)
+	}
+}
@@ -1,6 +1,7 @@
 package filemanager
 
 import (
+	"path/filepath"
 	"time"
 
 	pluginpkg "github.com/web-casa/webcasa/internal/plugin"
@@ -10,10 +11,17 @@ import (
 type Plugin struct {
 	fileOps *FileOps
 	termMgr *TerminalManager
+	uploads *ChunkedUploads
 	handler *Handler
 	stopCh  chan struct{}
+
+	idleTimeout time.Duration // terminal sessions idle this long are reaped
 }
 
+// defaultTerminalIdleTimeout applies when terminal_idle_timeout (minutes)
+// is unset or invalid.
+const defaultTerminalIdleTimeout = 30 * time.Minute
+
 // New creates a new File Manager plugin instance.
 func New() *Plugin {
 	return &Plugin{}
@@ -47,25 +55,50 @@ func (p *Plugin) Init(ctx *pluginpkg.Context) error {
 		rootPath = "/"
 	}
 
+	p.idleTimeout = defaultTerminalIdleTimeout
+	if mins := ctx.ConfigStore.GetInt("terminal_idle_timeout", 0); mins > 0 {
+		p.idleTimeout = time.Duration(mins) * time.Minute
+	}
+
+	// viewer_root sandboxes non-admin roles. When unset they get no file
+	// access and the read routes stay admin-only.
+	var sandboxOps *FileOps
+	viewerRoot := ctx.ConfigStore.Get("viewer_root")
+	if viewerRoot != "" {
+		sandboxOps = NewFileOps(viewerRoot)
+	}
+
 	p.fileOps = NewFileOps(rootPath)
 	p.termMgr = NewTerminalManager(ctx.Logger)
-	p.handler = NewHandler(p.fileOps, p.termMgr)
+	p.uploads = NewChunkedUploads(filepath.Join(ctx.DataDir, "uploads"))
+	p.handler = NewHandler(p.fileOps, sandboxOps, ctx.DB, p.termMgr, p.uploads)
 
-	_ = ctx.Router       // unused — all file ops require admin
 	a := ctx.AdminRouter // admin-only
 
-	// File operations (read — admin only, viewers must not browse server files)
-	a.GET("/list", p.handler.List)
-	a.GET("/read", p.handler.Read)
-	a.GET("/download", p.handler.Download)
-	a.GET("/info", p.handler.Info)
+	// File operations (read). Handlers pick the root from the caller's role,
+	// so lower roles only ever see their sandbox.
+	r := ctx.AdminRouter
+	if sandboxOps != nil {
+		r = ctx.Router
+	}
+	r.GET("/list", p.handler.List)
+	r.GET("/read", p.handler.Read)
+	r.GET("/preview", p.handler.Preview)
+	r.GET("/download", p.handler.Download)
+	r.GET("/info", p.handler.Info)
+	r.GET("/search", p.handler.Search)
+	r.GET("/grep", p.handler.Grep)
 
 	// File operations (write/modify)
 	a.POST("/write", p.handler.Write)
 	a.POST("/upload", p.handler.Upload)
+	a.POST("/upload/chunk", p.handler.UploadChunk)
+	a.POST("/upload/complete", p.handler.UploadComplete)
 	a.POST("/mkdir", p.handler.Mkdir)
 	a.DELETE("/delete", p.handler.Delete)
 	a.POST("/rename", p.handler.Rename)
+	a.POST("/copy", p.handler.Copy)
+	a.POST("/move", p.handler.Move)
 	a.POST("/chmod", p.handler.Chmod)
 
 	// Archive (admin)
@@ -74,12 +107,14 @@ func (p *Plugin) Init(ctx *pluginpkg.Context) error {
 
 	// Terminal (admin - full shell access)
 	a.GET("/terminal/ws", p.handler.TerminalWS)
+	a.GET("/terminals", p.handler.ListTerminals)
+	a.DELETE("/terminals/:id", p.handler.KillTerminal)
 
 	ctx.Logger.Info("File Manager plugin routes registered")
 	return nil
 }
 
-// Start begins background session cleanup.
+// Start begins background cleanup of terminal sessions and abandoned uploads.
 func (p *Plugin) Start() error {
 	p.stopCh = make(chan struct{})
 	go func() {
@@ -89,11 +124,13 @@ func (p *Plugin) Start() error {
 			select {
 			case <-ticker.C:
 				p.termMgr.CleanupStale(2 * time.Hour)
+				p.uploads.CleanupStale()
 			case <-p.stopCh:
 				return
 			}
 		}
 	}()
+	go p.termMgr.RunIdleReaper(p.idleTimeout, time.Minute, p.stopCh)
 	return nil
 }
 
@@ -5,6 +5,7 @@ import (
 	"log/slog"
 	"os"
 	"os/exec"
+	"sort"
 	"sync"
 	"time"
 
@@ -27,6 +28,61 @@ type TerminalSession struct {
 	PTY     *os.File
 	Cmd     *exec.Cmd
 	Created time.Time
+
+	mu           sync.Mutex
+	cols, rows   uint16
+	lastActivity time.Time
+}
+
+// SessionInfo is the listing view of a terminal session.
+type SessionInfo struct {
+	ID           string    `json:"id"`
+	Created      time.Time `json:"created"`
+	Cols         uint16    `json:"cols"`
+	Rows         uint16    `json:"rows"`
+	LastActivity time.Time `json:"last_activity"`
+}
+
+func (s *TerminalSession) touch() {
+	s.mu.Lock()
+	s.lastActivity = time.Now()
+	s.mu.Unlock()
+}
+
+// Read reads PTY output and records activity.
+func (s *TerminalSession) Read(p []byte) (int, error) {
+	n, err := s.PTY.Read(p)
+	if n > 0 {
+		s.touch()
+	}
+	return n, err
+}
+
+// Write sends input to the PTY and records activity.
+func (s *TerminalSession) Write(p []byte) (int, error) {
+	s.touch()
+	return s.PTY.Write(p)
+}
+
+func (s *TerminalSession) info() SessionInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return SessionInfo{ID: s.ID, Created: s.Created, Cols: s.cols, Rows: s.rows, LastActivity: s.lastActivity}
+}
+
+func (s *TerminalSession) idleSince() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastActivity
+}
+
+// terminate closes the PTY and kills the shell.
+func (s *TerminalSession) terminate() {
+	s.PTY.Close()
+	if s.Cmd.Process != nil {
+		s.Cmd.Process.Kill()
+		s.Cmd.Wait()
+	}
 }
 
 // TerminalInput is the message format from client to server.
@@ -83,11 +139,15 @@ func (tm *TerminalManager) Create(cols, rows uint16) (*TerminalSession, error) {
 		return nil, fmt.Errorf("start pty: %w", err)
 	}
 
+	now := time.Now()
 	session := &TerminalSession{
-		ID:      slotID,
-		PTY:     ptmx,
-		Cmd:     cmd,
-		Created: time.Now(),
+		ID:           slotID,
+		PTY:          ptmx,
+		Cmd:          cmd,
+		Created:      now,
+		cols:         cols,
+		rows:         rows,
+		lastActivity: now,
 	}
 
 	tm.mu.Lock()
@@ -103,10 +163,82 @@ func (tm *TerminalManager) Resize(sessionID string, cols, rows uint16) error {
 	tm.mu.RLock()
 	session, ok := tm.sessions[sessionID]
 	tm.mu.RUnlock()
-	if !ok {
+	if !ok || session == nil {
+		return fmt.Errorf("session not found")
+	}
+	if err := pty.Setsize(session.PTY, &pty.Winsize{Cols: cols, Rows: rows}); err != nil {
+		return err
+	}
+	session.mu.Lock()
+	session.cols, session.rows = cols, rows
+	session.mu.Unlock()
+	session.touch()
+	return nil
+}
+
+// List returns all live sessions, oldest first.
+func (tm *TerminalManager) List() []SessionInfo {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+	list := make([]SessionInfo, 0, len(tm.sessions))
+	for _, s := range tm.sessions {
+		if s == nil {
+			continue // placeholder slot
+		}
+		list = append(list, s.info())
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Created.Before(list[j].Created) })
+	return list
+}
+
+// Kill terminates a session by ID. Its WebSocket, if still attached,
+// closes once the PTY read fails.
+func (tm *TerminalManager) Kill(sessionID string) error {
+	tm.mu.RLock()
+	session, ok := tm.sessions[sessionID]
+	tm.mu.RUnlock()
+	if !ok || session == nil {
 		return fmt.Errorf("session not found")
 	}
-	return pty.Setsize(session.PTY, &pty.Winsize{Cols: cols, Rows: rows})
+	tm.Close(sessionID)
+	return nil
+}
+
+// ReapIdle closes sessions with no PTY input or output for longer than
+// idle.
+func (tm *TerminalManager) ReapIdle(idle time.Duration) {
+	cutoff := time.Now().Add(-idle)
+	tm.mu.Lock()
+	var reaped []*TerminalSession
+	for id, s := range tm.sessions {
+		if s == nil {
+			continue
+		}
+		if s.idleSince().Before(cutoff) {
+			delete(tm.sessions, id)
+			reaped = append(reaped, s)
+		}
+	}
+	tm.mu.Unlock()
+
+	for _, s := range reaped {
+		s.terminate()
+		tm.logger.Info("idle terminal session reaped", "id", s.ID, "idle_timeout", idle)
+	}
+}
+
+// RunIdleReaper calls ReapIdle every interval until stop is closed.
+func (tm *TerminalManager) RunIdleReaper(idle, interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			tm.ReapIdle(idle)
+		case <-stop:
+			return
+		}
+	}
 }
 
 // Close terminates and cleans up a session.
@@ -118,15 +250,11 @@ func (tm *TerminalManager) Close(sessionID string) {
 	}
 	tm.mu.Unlock()
 
-	if !ok {
+	if !ok || session == nil {
 		return
 	}
 
-	session.PTY.Close()
-	if session.Cmd.Process != nil {
-		session.Cmd.Process.Kill()
-		session.Cmd.Wait()
-	}
+	session.terminate()
 	tm.logger.Info("terminal session closed", "id", sessionID)
 }
 
@@ -0,0 +1,89 @@
+package filemanager
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func newTestTerminalManager(t *testing.T) *TerminalManager {
+	t.Helper()
+	tm := NewTerminalManager(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	t.Cleanup(tm.CloseAll)
+	return tm
+}
+
+func TestTerminalListAndKill(t *testing.T) {
+	tm := newTestTerminalManager(t)
+	s, err := tm.Create(100, 30)
+	if err != nil {
+		t.Skipf("pty unavailable: %v", err)
+	}
+
+	list := tm.List()
+	if len(list) != 1 || list[0].ID != s.ID || list[0].Cols != 100 || list[0].Rows != 30 {
+		t.Fatalf("unexpected session list: %+v", list)
+	}
+	if list[0].LastActivity.IsZero() {
+		t.Fatal("last activity should be set at creation")
+	}
+
+	if err := tm.Kill(s.ID); err != nil {
+		t.Fatalf("kill: %v", err)
+	}
+	if len(tm.List()) != 0 {
+		t.Fatal("killed session should no longer be listed")
+	}
+	if err := tm.Kill(s.ID); err == nil {
+		t.Fatal("expected error killing an unknown session")
+	}
+}
+
+func TestTerminalActivityTracking(t *testing.T) {
+	tm := newTestTerminalManager(t)
+	s, err := tm.Create(80, 24)
+	if err != nil {
+		t.Skipf("pty unavailable: %v", err)
+	}
+	before := tm.List()[0].LastActivity
+	time.Sleep(10 * time.Millisecond)
+	s.Write([]byte("\n"))
+	if after := tm.List()[0].LastActivity; !after.After(before) {
+		t.Fatalf("write should bump last activity (%v → %v)", before, after)
+	}
+	tm.Resize(s.ID, 120, 40)
+	if info := tm.List()[0]; info.Cols != 120 || info.Rows != 40 {
+		t.Fatalf("resize not reflected in listing: %+v", info)
+	}
+}
+
+func TestTerminalIdleReaper(t *testing.T) {
+	tm := newTestTerminalManager(t)
+	idle, err := tm.Create(80, 24)
+	if err != nil {
+		t.Skipf("pty unavailable: %v", err)
+	}
+	active, _ := tm.Create(80, 24)
+
+	idle.mu.Lock()
+	idle.lastActivity = time.Now().Add(-time.Hour)
+	idle.mu.Unlock()
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go tm.RunIdleReaper(30*time.Minute, 10*time.Millisecond, stop)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		list := tm.List()
+		if len(list) == 1 {
+			if list[0].ID != active.ID {
+				t.Fatalf("reaper closed the wrong session: %+v", list)
+			}
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("idle session was not reaped: %+v", tm.List())
+}
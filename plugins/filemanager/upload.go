@@ -0,0 +1,218 @@
+package filemanager
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Chunked upload limits. The total across all chunks is capped at
+// maxUploadSize, the same as a single-request upload.
+const (
+	maxUploadChunks  = 10000
+	chunkedUploadTTL = 2 * time.Hour
+)
+
+var validUploadID = regexp.MustCompile(`^[A-Za-z0-9_-]{8,64}$`)
+
+// ChunkedUploads stages resumable uploads: each chunk is stored as its
+// own part file so chunks may arrive in any order or be retried, and the
+// parts are joined into the destination only once all have arrived.
+type ChunkedUploads struct {
+	dir      string
+	ttl      time.Duration
+	maxTotal int64
+
+	mu       sync.Mutex
+	sessions map[string]*chunkSession
+}
+
+type chunkSession struct {
+	total    int
+	parts    map[int]int64 // chunk index → size
+	size     int64
+	modified time.Time
+}
+
+// NewChunkedUploads stages uploads under dir.
+func NewChunkedUploads(dir string) *ChunkedUploads {
+	os.MkdirAll(dir, 0700)
+	return &ChunkedUploads{
+		dir:      dir,
+		ttl:      chunkedUploadTTL,
+		maxTotal: maxUploadSize,
+		sessions: make(map[string]*chunkSession),
+	}
+}
+
+func (u *ChunkedUploads) sessionDir(id string) string {
+	return filepath.Join(u.dir, id)
+}
+
+// PutChunk stores chunk index (0-based) of an upload with total chunks.
+// Re-sending a chunk replaces the earlier copy.
+func (u *ChunkedUploads) PutChunk(id string, index, total int, r io.Reader) error {
+	if !validUploadID.MatchString(id) {
+		return fmt.Errorf("invalid upload id")
+	}
+	if total < 1 || total > maxUploadChunks {
+		return fmt.Errorf("total chunks must be between 1 and %d", maxUploadChunks)
+	}
+	if index < 0 || index >= total {
+		return fmt.Errorf("chunk index out of range")
+	}
+
+	u.mu.Lock()
+	sess, ok := u.sessions[id]
+	if !ok {
+		sess = &chunkSession{total: total, parts: make(map[int]int64)}
+		u.sessions[id] = sess
+	} else if sess.total != total {
+		u.mu.Unlock()
+		return fmt.Errorf("total chunks changed mid-upload (was %d)", sess.total)
+	}
+	// Reserve the remaining budget so concurrent chunks can't jointly
+	// exceed the cap; the previous copy of a retried chunk is released.
+	budget := u.maxTotal - sess.size + sess.parts[index]
+	sess.modified = time.Now()
+	u.mu.Unlock()
+
+	if err := os.MkdirAll(u.sessionDir(id), 0700); err != nil {
+		return err
+	}
+	partPath := filepath.Join(u.sessionDir(id), strconv.Itoa(index)+".part")
+	tmp := partPath + ".tmp"
+	out, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	n, err := io.Copy(out, io.LimitReader(r, budget+1))
+	closeErr := out.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err == nil && n > budget {
+		err = fmt.Errorf("upload exceeds %d MB limit", u.maxTotal>>20)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.sessions[id] != sess {
+		os.Remove(tmp)
+		return fmt.Errorf("upload expired")
+	}
+	if sess.size-sess.parts[index]+n > u.maxTotal {
+		os.Remove(tmp)
+		return fmt.Errorf("upload exceeds %d MB limit", u.maxTotal>>20)
+	}
+	if err := os.Rename(tmp, partPath); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	sess.size += n - sess.parts[index]
+	sess.parts[index] = n
+	sess.modified = time.Now()
+	return nil
+}
+
+// Complete joins all chunks of an upload into reqPath via fileOps and
+// discards the staged parts. Fails, keeping the parts, if any chunk is
+// missing so the client can resend it.
+func (u *ChunkedUploads) Complete(fileOps *FileOps, id, reqPath string) error {
+	if !validUploadID.MatchString(id) {
+		return fmt.Errorf("invalid upload id")
+	}
+	abs, err := fileOps.safePath(reqPath)
+	if err != nil {
+		return err
+	}
+	u.mu.Lock()
+	sess, ok := u.sessions[id]
+	if !ok {
+		u.mu.Unlock()
+		return fmt.Errorf("unknown upload id")
+	}
+	var missing []int
+	for i := 0; i < sess.total; i++ {
+		if _, ok := sess.parts[i]; !ok {
+			missing = append(missing, i)
+		}
+	}
+	if len(missing) > 0 {
+		u.mu.Unlock()
+		return fmt.Errorf("missing chunks: %v", missing)
+	}
+	// Detach the session so no chunk can land while we assemble.
+	delete(u.sessions, id)
+	total := sess.total
+	u.mu.Unlock()
+	defer os.RemoveAll(u.sessionDir(id))
+
+	if err := os.MkdirAll(filepath.Dir(abs), 0755); err != nil {
+		return err
+	}
+	// Assemble next to the destination so the final rename is atomic.
+	tmp, err := os.CreateTemp(filepath.Dir(abs), ".upload-*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	for i := 0; i < total; i++ {
+		part, err := os.Open(filepath.Join(u.sessionDir(id), strconv.Itoa(i)+".part"))
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmpName)
+			return err
+		}
+		_, err = io.Copy(tmp, part)
+		part.Close()
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmpName)
+			return err
+		}
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	// CreateTemp uses 0600; match a normal upload.
+	os.Chmod(tmpName, 0644)
+	if err := os.Rename(tmpName, abs); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	return nil
+}
+
+// CleanupStale drops uploads untouched for longer than the TTL, including
+// part directories left behind by a restart.
+func (u *ChunkedUploads) CleanupStale() {
+	cutoff := time.Now().Add(-u.ttl)
+	u.mu.Lock()
+	for id, sess := range u.sessions {
+		if sess.modified.Before(cutoff) {
+			delete(u.sessions, id)
+			os.RemoveAll(u.sessionDir(id))
+		}
+	}
+	entries, _ := os.ReadDir(u.dir)
+	for _, e := range entries {
+		if _, live := u.sessions[e.Name()]; live {
+			continue
+		}
+		if info, err := e.Info(); err == nil && info.ModTime().Before(cutoff) {
+			os.RemoveAll(filepath.Join(u.dir, e.Name()))
+		}
+	}
+	u.mu.Unlock()
+}
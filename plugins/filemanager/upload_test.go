@@ -0,0 +1,111 @@
+package filemanager
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestChunkedUpload_InOrder(t *testing.T) {
+	root := t.TempDir()
+	ops := NewFileOps(root)
+	up := NewChunkedUploads(t.TempDir())
+
+	for i, chunk := range []string{"hello ", "chunked ", "world"} {
+		if err := up.PutChunk("upload-0001", i, 3, strings.NewReader(chunk)); err != nil {
+			t.Fatalf("chunk %d: %v", i, err)
+		}
+	}
+	if err := up.Complete(ops, "upload-0001", "/dir/out.txt"); err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+	if got, _ := ops.Read("/dir/out.txt"); got != "hello chunked world" {
+		t.Fatalf("assembled content = %q", got)
+	}
+	if _, err := os.Stat(up.sessionDir("upload-0001")); !os.IsNotExist(err) {
+		t.Fatal("staged parts should be removed after completion")
+	}
+}
+
+func TestChunkedUpload_OutOfOrderAndRetry(t *testing.T) {
+	root := t.TempDir()
+	ops := NewFileOps(root)
+	up := NewChunkedUploads(t.TempDir())
+
+	up.PutChunk("upload-0002", 2, 3, strings.NewReader("C"))
+	up.PutChunk("upload-0002", 0, 3, strings.NewReader("a-garbled"))
+	up.PutChunk("upload-0002", 1, 3, strings.NewReader("B"))
+	// Retried chunk replaces the earlier copy.
+	up.PutChunk("upload-0002", 0, 3, strings.NewReader("A"))
+
+	if err := up.Complete(ops, "upload-0002", "/out.bin"); err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+	if got, _ := ops.Read("/out.bin"); got != "ABC" {
+		t.Fatalf("assembled content = %q, want ABC", got)
+	}
+}
+
+func TestChunkedUpload_MissingChunk(t *testing.T) {
+	root := t.TempDir()
+	ops := NewFileOps(root)
+	up := NewChunkedUploads(t.TempDir())
+
+	up.PutChunk("upload-0003", 0, 3, strings.NewReader("A"))
+	up.PutChunk("upload-0003", 2, 3, strings.NewReader("C"))
+
+	err := up.Complete(ops, "upload-0003", "/out.txt")
+	if err == nil || !strings.Contains(err.Error(), "[1]") {
+		t.Fatalf("expected missing chunk 1 error, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "out.txt")); !os.IsNotExist(err) {
+		t.Fatal("nothing should be written on a failed completion")
+	}
+	// The client can still send the missing chunk and finish.
+	up.PutChunk("upload-0003", 1, 3, strings.NewReader("B"))
+	if err := up.Complete(ops, "upload-0003", "/out.txt"); err != nil {
+		t.Fatalf("complete after resend: %v", err)
+	}
+}
+
+func TestChunkedUpload_TotalLimit(t *testing.T) {
+	up := NewChunkedUploads(t.TempDir())
+	up.maxTotal = 10
+
+	if err := up.PutChunk("upload-0004", 0, 2, strings.NewReader("123456")); err != nil {
+		t.Fatalf("first chunk: %v", err)
+	}
+	if err := up.PutChunk("upload-0004", 1, 2, strings.NewReader("7890X")); err == nil {
+		t.Fatal("expected total size limit error")
+	}
+}
+
+func TestChunkedUpload_Validation(t *testing.T) {
+	up := NewChunkedUploads(t.TempDir())
+	if err := up.PutChunk("../../etc", 0, 1, strings.NewReader("x")); err == nil {
+		t.Fatal("expected invalid upload id error")
+	}
+	if err := up.PutChunk("upload-0005", 3, 3, strings.NewReader("x")); err == nil {
+		t.Fatal("expected index out of range error")
+	}
+	up.PutChunk("upload-0005", 0, 3, strings.NewReader("x"))
+	if err := up.PutChunk("upload-0005", 1, 4, strings.NewReader("x")); err == nil {
+		t.Fatal("expected error when total chunks changes")
+	}
+}
+
+func TestChunkedUpload_CleanupStale(t *testing.T) {
+	up := NewChunkedUploads(t.TempDir())
+	up.PutChunk("upload-0006", 0, 2, strings.NewReader("x"))
+	up.sessions["upload-0006"].modified = time.Now().Add(-3 * time.Hour)
+
+	up.CleanupStale()
+	if _, ok := up.sessions["upload-0006"]; ok {
+		t.Fatal("stale session should be dropped")
+	}
+	if _, err := os.Stat(up.sessionDir("upload-0006")); !os.IsNotExist(err) {
+		t.Fatal("stale parts should be removed")
+	}
+}
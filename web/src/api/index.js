@@ -108,6 +108,14 @@ export const authAPI = {
     setup2FA: () => api.post('/auth/2fa/setup'),
     verify2FA: (code) => api.post('/auth/2fa/verify', { code }),
     disable2FA: (code) => api.post('/auth/2fa/disable', { code }),
+    regenerateRecoveryCodes: (code) => api.post('/auth/2fa/recovery/regenerate', { code }),
+    setupEmail2FA: (email) => api.post('/auth/2fa/email/setup', { email }),
+    verifyEmail2FA: (code) => api.post('/auth/2fa/email/verify', { code }),
+    sendEmail2FACode: () => api.post('/auth/2fa/email/send-code'),
+    revokeSessions: () => api.post('/auth/revoke-sessions'),
+    changePassword: (oldPassword, newPassword) => api.post('/auth/change-password', { old_password: oldPassword, new_password: newPassword }),
+    listLockouts: () => api.get('/auth/lockouts'),
+    clearLockout: (ip, username) => api.delete('/auth/lockouts', { params: { ip, username } }),
 }
 
 // ============ Hosts ============
@@ -115,10 +123,23 @@ export const hostAPI = {
     list: (params) => api.get('/hosts', { params }),
     get: (id) => api.get(`/hosts/${id}`),
     create: (data) => api.post('/hosts', data),
+    batch: (ids, action) => api.post('/hosts/batch', { ids, action }),
     update: (id, data) => api.put(`/hosts/${id}`, data),
     delete: (id) => api.delete(`/hosts/${id}`),
+    trash: () => api.get('/hosts/trash'),
+    restore: (id) => api.post(`/hosts/${id}/restore`),
+    purge: (id) => api.delete(`/hosts/${id}/purge`),
     toggle: (id) => api.patch(`/hosts/${id}/toggle`),
+    setMaintenance: (id, data) => api.patch(`/hosts/${id}/maintenance`, data),
     clone: (id, data) => api.post(`/hosts/${id}/clone`, data),
+    revisions: (id) => api.get(`/hosts/${id}/revisions`),
+    certStatus: (id) => api.get(`/hosts/${id}/cert-status`),
+    curl: (id) => api.get(`/hosts/${id}/curl`),
+    lint: (id) => api.get(`/hosts/${id}/lint`),
+    metadata: (id) => api.get(`/hosts/${id}/metadata`),
+    getSchedule: (id) => api.get(`/hosts/${id}/schedule`),
+    setSchedule: (id, data) => api.put(`/hosts/${id}/schedule`, data),
+    restoreRevision: (id, rev) => api.post(`/hosts/${id}/revisions/${rev}/restore`),
     uploadCert: (id, formData) => api.post(`/hosts/${id}/cert`, formData, {
         headers: { 'Content-Type': 'multipart/form-data' },
     }),
@@ -130,9 +151,16 @@ export const dnsCheckAPI = {
     check: (domain) => api.get('/dns-check', { params: { domain } }),
 }
 
+// ============ Search ============
+export const searchAPI = {
+    search: (q) => api.get('/search', { params: { q } }),
+}
+
 // ============ Groups ============
 export const groupAPI = {
     list: () => api.get('/groups'),
+    palette: () => api.get('/groups/palette'),
+    reorder: (ids) => api.patch('/groups/reorder', { ids }),
     create: (data) => api.post('/groups', data),
     update: (id, data) => api.put(`/groups/${id}`, data),
     delete: (id) => api.delete(`/groups/${id}`),
@@ -146,16 +174,22 @@ export const tagAPI = {
     create: (data) => api.post('/tags', data),
     update: (id, data) => api.put(`/tags/${id}`, data),
     delete: (id) => api.delete(`/tags/${id}`),
+    listRules: () => api.get('/tags/rules'),
+    createRule: (data) => api.post('/tags/rules', data),
+    deleteRule: (id) => api.delete(`/tags/rules/${id}`),
+    applyRules: () => api.post('/tags/rules/apply'),
 }
 
 // ============ Caddy ============
 export const caddyAPI = {
     status: () => api.get('/caddy/status'),
+    metricsSummary: () => api.get('/caddy/metrics/summary'),
     start: () => api.post('/caddy/start'),
     stop: () => api.post('/caddy/stop'),
     reload: () => api.post('/caddy/reload'),
     caddyfile: () => api.get('/caddy/caddyfile'),
     saveCaddyfile: (content, reload = false) => api.post('/caddy/caddyfile', { content, reload }),
+    importCaddyfile: (content) => api.post('/caddy/caddyfile/import', { content }),
     format: (content) => api.post('/caddy/fmt', { content }),
     validate: (content) => api.post('/caddy/validate', { content }),
 }
@@ -166,17 +200,47 @@ export const logAPI = {
     files: () => api.get('/logs/files'),
     downloadUrl: (type) => `/api/logs/download?type=${type}`,
     system: (params) => api.get('/logs/system', { params }),
+    access: (params) => api.get('/logs/access', { params }),
+    streamWsUrl: (file) => {
+        const proto = window.location.protocol === 'https:' ? 'wss:' : 'ws:'
+        return `${proto}//${window.location.host}/api/logs/stream?file=${encodeURIComponent(file)}`
+    },
+}
+
+// ============ Events ============
+export const eventsAPI = {
+    // topics: array of event types or prefix patterns, e.g. ['cert.expiring', 'deploy.*']
+    streamWsUrl: (topics) => {
+        const proto = window.location.protocol === 'https:' ? 'wss:' : 'ws:'
+        return `${proto}//${window.location.host}/api/events/stream?topics=${encodeURIComponent(topics.join(','))}`
+    },
 }
 
 // ============ Config ============
 export const configAPI = {
     export: () => api.get('/config/export'),
     import: (data) => api.post('/config/import', data),
+    exportFull: (secrets, passphrase) => api.post('/config/export/full', { secrets, passphrase }),
+    importFull: (config, passphrase) => api.post('/config/import/full', { config, passphrase }),
+}
+
+// ============ Full Backups ============
+export const panelBackupAPI = {
+    now: () => api.post('/backup/now', {}, { timeout: 300000 }),
+    list: () => api.get('/backup/list'),
+    download: (id) => api.get(`/backup/download/${encodeURIComponent(id)}`, { responseType: 'blob' }),
+    restore: (file) => {
+        const form = new FormData()
+        form.append('file', file)
+        form.append('confirm', 'true')
+        return api.post('/backup/restore', form, { timeout: 600000 })
+    },
 }
 
 // ============ Dashboard ============
 export const dashboardAPI = {
     stats: () => api.get('/dashboard/stats'),
+    system: () => api.get('/dashboard/system'),
     news: () => api.get('/news'),
 }
 
@@ -191,11 +255,13 @@ export const userAPI = {
 // ============ Audit ============
 export const auditAPI = {
     list: (params) => api.get('/audit/logs', { params }),
+    export: (params) => api.get('/audit/logs/export', { params, responseType: 'blob' }),
+    prune: (days) => api.post('/audit/logs/prune', days ? { days } : {}),
 }
 
 // ============ Templates ============
 export const templateAPI = {
-    list: () => api.get('/templates'),
+    list: (params) => api.get('/templates', { params }),
     create: (data) => api.post('/templates', data),
     update: (id, data) => api.put(`/templates/${id}`, data),
     delete: (id) => api.delete(`/templates/${id}`),
@@ -204,6 +270,7 @@ export const templateAPI = {
     }),
     export: (id) => api.get(`/templates/${id}/export`, { responseType: 'blob' }),
     createHost: (id, data) => api.post(`/templates/${id}/create-host`, data),
+    createHosts: (id, domains) => api.post(`/templates/${id}/create-hosts`, { domains }),
     saveAsTemplate: (hostId, data) => api.post(`/hosts/${hostId}/save-as-template`, data),
 }
 
@@ -211,6 +278,8 @@ export const templateAPI = {
 export const settingAPI = {
     getAll: () => api.get('/settings/all'),
     update: (key, value) => api.put('/settings', { key, value }),
+    // to: recipient of the test email; empty sends it to the current user
+    testSMTP: (to) => api.post('/settings/smtp/test', to ? { to } : {}),
 }
 
 // ============ Certificates ============
@@ -506,6 +575,8 @@ export const pluginAPI = {
 export const dnsProviderAPI = {
     list: () => api.get('/dns-providers'),
     get: (id) => api.get(`/dns-providers/${id}`),
+    getDefault: () => api.get('/dns-providers/default'),
+    renderPreview: (id) => api.get(`/dns-providers/${id}/render-preview`),
     create: (data) => api.post('/dns-providers', data),
     update: (id, data) => api.put(`/dns-providers/${id}`, data),
     delete: (id) => api.delete(`/dns-providers/${id}`),
@@ -0,0 +1,86 @@
+import { useState, useEffect } from 'react'
+import { Box, Flex, Text, TextField, Badge } from '@radix-ui/themes'
+import { Search } from 'lucide-react'
+import { useNavigate } from 'react-router'
+import { useTranslation } from 'react-i18next'
+import { searchAPI } from '../api'
+
+// Where each search result category is managed.
+const CATEGORY_ROUTES = {
+    hosts: '/hosts',
+    templates: '/store/templates',
+    groups: '/settings',
+    tags: '/settings',
+    certificates: '/settings',
+}
+
+// GlobalSearch finds hosts, templates, groups, tags and certificates by
+// partial name and jumps to the page managing the picked result.
+export default function GlobalSearch({ onNavigate }) {
+    const { t } = useTranslation()
+    const navigate = useNavigate()
+    const [query, setQuery] = useState('')
+    const [results, setResults] = useState(null)
+
+    useEffect(() => {
+        const q = query.trim()
+        if (!q) {
+            setResults(null)
+            return
+        }
+        const timer = setTimeout(() => {
+            searchAPI.search(q).then(res => setResults(res.data)).catch(() => setResults(null))
+        }, 250)
+        return () => clearTimeout(timer)
+    }, [query])
+
+    const open = (category) => {
+        setQuery('')
+        setResults(null)
+        navigate(CATEGORY_ROUTES[category])
+        onNavigate?.()
+    }
+
+    return (
+        <Box style={{ position: 'relative' }}>
+            <TextField.Root
+                size="2"
+                placeholder={t('search.placeholder')}
+                value={query}
+                onChange={(e) => setQuery(e.target.value)}
+                onKeyDown={(e) => { if (e.key === 'Escape') setQuery('') }}
+            >
+                <TextField.Slot><Search size={14} /></TextField.Slot>
+            </TextField.Root>
+            {results && (
+                <Box
+                    style={{
+                        position: 'absolute', top: '100%', left: 0, right: 0, zIndex: 20, marginTop: 4,
+                        background: 'var(--cp-card)', border: '1px solid var(--cp-border)', borderRadius: 8,
+                        maxHeight: 360, overflowY: 'auto', padding: 8,
+                    }}
+                >
+                    {results.total === 0 && (
+                        <Text size="1" color="gray">{t('search.no_results')}</Text>
+                    )}
+                    {Object.keys(CATEGORY_ROUTES).map(category => (results.results[category] || []).length > 0 && (
+                        <Box key={category} mb="2">
+                            <Text size="1" weight="bold" color="gray">{t(`search.${category}`)}</Text>
+                            {results.results[category].map(item => (
+                                <Flex
+                                    key={`${item.type}-${item.id}`}
+                                    align="center" justify="between" gap="2" py="1"
+                                    style={{ cursor: 'pointer' }}
+                                    onClick={() => open(category)}
+                                >
+                                    <Text size="2" truncate>{item.name}</Text>
+                                    {item.detail && <Badge size="1" variant="soft" color="gray">{item.detail}</Badge>}
+                                </Flex>
+                            ))}
+                        </Box>
+                    ))}
+                </Box>
+            )}
+        </Box>
+    )
+}
@@ -1,6 +1,6 @@
 import { useState, useEffect, useRef, useCallback } from 'react'
 import { Box, Flex, Text, Button, Badge, Callout } from '@radix-ui/themes'
-import { Save, Check, X, FileCode, AlignLeft, RefreshCw } from 'lucide-react'
+import { Save, Check, X, FileCode, AlignLeft, RefreshCw, Download } from 'lucide-react'
 import { caddyAPI } from '../api/index.js'
 import { EditorView, basicSetup } from 'codemirror'
 import { EditorState } from '@codemirror/state'
@@ -16,6 +16,8 @@ export default function CaddyfileEditor() {
     const [validating, setValidating] = useState(false)
     const [validationResult, setValidationResult] = useState(null) // { valid, error }
     const [message, setMessage] = useState(null) // { type: 'success'|'error', text }
+    const [importing, setImporting] = useState(false)
+    const [importResults, setImportResults] = useState(null) // per-block import outcome
     const [hasChanges, setHasChanges] = useState(false)
     const editorRef = useRef(null)
     const viewRef = useRef(null)
@@ -128,6 +130,21 @@ export default function CaddyfileEditor() {
         setSaving(false)
     }
 
+    // Create hosts from the sites in the editor (e.g. a pasted, hand-written Caddyfile).
+    const handleImportHosts = async () => {
+        setImporting(true)
+        setImportResults(null)
+        try {
+            const res = await caddyAPI.importCaddyfile(content)
+            setImportResults(res.data.results || [])
+            setMessage({ type: 'success', text: t('editor.import_result', { imported: res.data.imported, skipped: res.data.skipped }) })
+        } catch (e) {
+            if (e.response?.data?.results) setImportResults(e.response.data.results)
+            setMessage({ type: 'error', text: e.response?.data?.error || t('editor.import_failed') })
+        }
+        setImporting(false)
+    }
+
     const handleReset = () => {
         if (viewRef.current) {
             viewRef.current.dispatch({
@@ -173,6 +190,10 @@ export default function CaddyfileEditor() {
                         {validationResult?.valid ? <Check size={14} /> : <X size={14} />}
                         {validating ? t('editor.validating') : t('editor.validate')}
                     </Button>
+                    <Button variant="soft" size="2" onClick={handleImportHosts} disabled={importing || !content.trim()}>
+                        <Download size={14} />
+                        {importing ? t('editor.importing') : t('editor.import_hosts')}
+                    </Button>
                     <Button variant="soft" size="2" onClick={handleReset} disabled={!hasChanges}>
                         <RefreshCw size={14} />
                         {t('editor.reset')}
@@ -211,6 +232,18 @@ export default function CaddyfileEditor() {
                 </Callout.Root>
             )}
 
+            {importResults?.some(r => r.status === 'skipped') && (
+                <Callout.Root color="yellow" size="1" mb="3">
+                    <Callout.Text>
+                        {importResults.filter(r => r.status === 'skipped').map(r => (
+                            <Text as="div" size="1" key={`${r.line}-${r.address}`}>
+                                {t('editor.import_skipped', { address: r.address || t('editor.global_options'), line: r.line, reason: r.reason })}
+                            </Text>
+                        ))}
+                    </Callout.Text>
+                </Callout.Root>
+            )}
+
             <Box
                 ref={editorRef}
                 style={{
@@ -1,9 +1,9 @@
 import { useState, useEffect } from 'react'
-import { Box, Card, Flex, Grid, Heading, Text, Badge, Spinner, Tooltip } from '@radix-ui/themes'
+import { Box, Card, Flex, Grid, Heading, Text, Badge, Spinner, Tooltip, Callout, Code } from '@radix-ui/themes'
 import {
     Globe, Container, Package, Cpu, Monitor, Clock,
     Server, ArrowUpRight, Plus, Terminal, FolderOpen,
-    ArrowUp, ArrowDown, ExternalLink,
+    ArrowUp, ArrowDown, ExternalLink, AlertTriangle,
 } from 'lucide-react'
 import {
     dashboardAPI, dockerAPI, pluginAPI, monitoringAPI,
@@ -209,6 +209,7 @@ export default function Dashboard() {
     const hosts = stats?.hosts || {}
     const system = stats?.system || {}
     const caddy = stats?.caddy || {}
+    const configErrors = stats?.config_errors || []
 
     // Compute stat card values
     const runningContainers = containers ? containers.filter(c => c.state === 'running').length : null
@@ -244,6 +245,24 @@ export default function Dashboard() {
                 {t('dashboard.subtitle')}
             </Text>
 
+            {configErrors.length > 0 && (
+                <Callout.Root color="red" mb="5">
+                    <Callout.Icon><AlertTriangle size={16} /></Callout.Icon>
+                    <Callout.Text>
+                        {t('dashboard.config_errors', { count: configErrors.length })}
+                    </Callout.Text>
+                    <Flex direction="column" gap="1">
+                        {configErrors.map(h => (
+                            <Tooltip key={h.id} content={h.error}>
+                                <Text size="2" style={{ cursor: 'pointer' }} onClick={() => navigate('/hosts')}>
+                                    <Code>{h.domain}</Code>
+                                </Text>
+                            </Tooltip>
+                        ))}
+                    </Flex>
+                </Callout.Root>
+            )}
+
             {/* ── Row 1: Core Stats ── */}
             <Grid columns={{ initial: '1', sm: '2', md: '4' }} gap="4" mb="5">
                 <StatCard
@@ -470,6 +470,8 @@ export default function FileManager() {
                             <Select.Trigger />
                             <Select.Content>
                                 <Select.Item value="tar.gz">tar.gz</Select.Item>
+                                <Select.Item value="tar.bz2">tar.bz2</Select.Item>
+                                <Select.Item value="tar.xz">tar.xz</Select.Item>
                                 <Select.Item value="zip">zip</Select.Item>
                             </Select.Content>
                         </Select.Root>
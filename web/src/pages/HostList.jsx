@@ -7,13 +7,34 @@ import {
 import {
     Plus, Pencil, Trash2, Globe, AlertCircle, X, ChevronRight,
     ArrowRightLeft, Shield, Lock, Copy, CheckCircle, AlertTriangle, Circle,
-    FolderOpen, Tags, Layers, ExternalLink,
+    FolderOpen, Tags, Layers, ExternalLink, Construction, ArchiveRestore, History, Terminal, Clock,
 } from 'lucide-react'
 import { hostAPI, dnsProviderAPI, settingAPI, certificateAPI, dnsCheckAPI, groupAPI, tagAPI, templateAPI } from '../api/index.js'
+import { badgeColor } from '../utils/colors.js'
+import { copyToClipboard } from '../utils/clipboard.js'
 import { useTranslation } from 'react-i18next'
 
+// Starting point when switching to per-header security settings; matches
+// the one-click preset.
+const DEFAULT_SECURITY_HEADERS_CONFIG = {
+    hsts_max_age: 31536000,
+    hsts_include_subdomains: true,
+    hsts_preload: true,
+    frame_options: 'DENY',
+    content_security_policy: '',
+    referrer_policy: 'strict-origin-when-cross-origin',
+}
+
+const REFERRER_POLICIES = [
+    'no-referrer', 'no-referrer-when-downgrade', 'origin', 'origin-when-cross-origin',
+    'same-origin', 'strict-origin', 'strict-origin-when-cross-origin', 'unsafe-url',
+]
+
+const COMPRESSION_ALGORITHMS = ['gzip', 'zstd', 'br']
+
 const DEFAULT_FORM = {
     domain: '',
+    aliases: '',
     host_type: 'proxy',
     tls_enabled: true,
     http_redirect: true,
@@ -25,18 +46,31 @@ const DEFAULT_FORM = {
     custom_headers: [],
     access_rules: [],
     basic_auths: [],
+    basic_auth_realm: '',
+    forward_auth_url: '',
+    forward_auth_copy_headers: '',
     custom_directives: '',
     compression: false,
+    compression_algorithms: '',
     cors_enabled: false,
     cors_origins: '*',
     cors_methods: 'GET, POST, PUT, DELETE, OPTIONS',
     cors_headers: 'Content-Type, Authorization',
+    cors_allow_credentials: false,
+    cors_max_age: 86400,
     security_headers: false,
+    security_headers_config: null,
+    separate_access_log: true,
     error_page_path: '',
+    max_request_body_size: '',
+    proxy_read_timeout: '',
+    proxy_write_timeout: '',
+    proxy_dial_timeout: '',
     cache_enabled: false,
     cache_ttl: 300,
     tls_mode: 'auto',
     dns_provider_id: null,
+    acme_ca: '',
     group_id: null,
     tag_ids: [],
 }
@@ -62,6 +96,9 @@ function HostFormDialog({ open, onClose, onSaved, host }) {
     const [groups, setGroups] = useState([])
     const [allTags, setAllTags] = useState([])
     const [templates, setTemplates] = useState([])
+    const [templateCategory, setTemplateCategory] = useState('')
+    const [certStatus, setCertStatus] = useState(null)
+    const [lintWarnings, setLintWarnings] = useState([])
 
     useEffect(() => {
         dnsProviderAPI.list().then(res => setDnsProviders(res.data.providers || [])).catch(() => { })
@@ -82,6 +119,7 @@ function HostFormDialog({ open, onClose, onSaved, host }) {
                 ...DEFAULT_FORM,
                 ...config,
                 domain: '', // always let user fill in domain
+                aliases: '',
                 upstreams: config.upstreams?.length ? config.upstreams : [{ address: '' }],
                 basic_auths: [],
                 tag_ids: config.tag_ids || [],
@@ -93,6 +131,7 @@ function HostFormDialog({ open, onClose, onSaved, host }) {
         if (host) {
             setForm({
                 domain: host.domain,
+                aliases: (host.aliases || '').split(',').filter(Boolean).join(', '),
                 host_type: host.host_type || 'proxy',
                 tls_enabled: host.tls_enabled,
                 http_redirect: host.http_redirect,
@@ -106,18 +145,34 @@ function HostFormDialog({ open, onClose, onSaved, host }) {
                 custom_headers: host.custom_headers || [],
                 access_rules: host.access_rules || [],
                 basic_auths: [], // never pre-fill passwords
+                basic_auth_realm: host.basic_auth_realm || '',
+                forward_auth_url: host.forward_auth_url || '',
+                forward_auth_copy_headers: host.forward_auth_copy_headers || '',
                 custom_directives: host.custom_directives || '',
                 compression: host.compression || false,
+                compression_algorithms: host.compression_algorithms || '',
                 cors_enabled: host.cors_enabled || false,
                 cors_origins: host.cors_origins || '*',
                 cors_methods: host.cors_methods || 'GET, POST, PUT, DELETE, OPTIONS',
                 cors_headers: host.cors_headers || 'Content-Type, Authorization',
+                cors_allow_credentials: host.cors_allow_credentials || false,
+                cors_max_age: host.cors_max_age || 86400,
                 security_headers: host.security_headers || false,
+                security_headers_config: host.security_headers_config || null,
+                separate_access_log: host.separate_access_log ?? true,
+                spa_fallback: host.spa_fallback || false,
+                php_version: host.php_version || '',
                 error_page_path: host.error_page_path || '',
+                max_request_body_size: host.max_request_body_size || '',
+                proxy_read_timeout: host.proxy_read_timeout || '',
+                proxy_write_timeout: host.proxy_write_timeout || '',
+                proxy_dial_timeout: host.proxy_dial_timeout || '',
+                version: host.version,
                 cache_enabled: host.cache_enabled || false,
                 cache_ttl: host.cache_ttl || 300,
                 tls_mode: host.tls_mode || 'auto',
                 dns_provider_id: host.dns_provider_id || null,
+                acme_ca: host.acme_ca || '',
                 group_id: host.group_id || null,
                 tag_ids: host.tags?.map(t => t.id) || [],
             })
@@ -127,6 +182,12 @@ function HostFormDialog({ open, onClose, onSaved, host }) {
         setError('')
         setDnsResult(null)
         setDnsChecking(false)
+        setCertStatus(null)
+        setLintWarnings([])
+        if (host && open) {
+            hostAPI.certStatus(host.id).then(res => setCertStatus(res.data)).catch(() => { })
+            hostAPI.lint(host.id).then(res => setLintWarnings(res.data.warnings || [])).catch(() => { })
+        }
     }, [host, open])
 
     // Debounced DNS check on domain change
@@ -156,6 +217,7 @@ function HostFormDialog({ open, onClose, onSaved, host }) {
             const res = await certificateAPI.upload(fd)
             const newCert = res.data.certificate
             setForm({ ...form, certificate_id: newCert.id })
+            if (res.data.cert_chain_incomplete) setError(t('cert.chain_incomplete'))
             setCertFile(null)
             setKeyFile(null)
             certificateAPI.list().then(r => setCertificates(r.data.certificates || []))
@@ -175,18 +237,32 @@ function HostFormDialog({ open, onClose, onSaved, host }) {
                     ? form.upstreams.filter((u) => u.address.trim())
                     : [],
                 basic_auths: form.basic_auths.filter((a) => a.username && a.password),
+                spa_fallback: form.host_type === 'static' && !!form.spa_fallback,
                 group_id: form.group_id || null,
                 tag_ids: form.tag_ids || [],
             }
-            if (isEdit) {
-                await hostAPI.update(host.id, payload)
-            } else {
-                await hostAPI.create(payload)
-            }
-            onSaved()
+            const res = isEdit
+                ? await hostAPI.update(host.id, payload)
+                : await hostAPI.create(payload)
+            onSaved(res.data?.apply, res.data?.warning)
             onClose()
         } catch (err) {
-            setError(err.response?.data?.error || t('host.save_failed'))
+            const data = err.response?.data
+            if (data?.error_key === 'error.stale_host') {
+                setError(t('error.stale_host'))
+                return
+            }
+            if (data?.error_key === 'error.domain_overlap') {
+                setError(t('error.domain_overlap', { domain: data.conflict?.domain }))
+                return
+            }
+            if (data?.error_key === 'error.invalid_generated_config') {
+                // The host was saved; refresh the list behind the dialog.
+                setError(t('error.invalid_generated_config'))
+                onSaved()
+                return
+            }
+            setError(data?.error || t('host.save_failed'))
         } finally {
             setSaving(false)
         }
@@ -208,7 +284,7 @@ function HostFormDialog({ open, onClose, onSaved, host }) {
     }
 
     const addBasicAuth = () => {
-        setForm({ ...form, basic_auths: [...form.basic_auths, { username: '', password: '' }] })
+        setForm({ ...form, basic_auths: [...form.basic_auths, { username: '', password: '', path: '' }] })
     }
 
     const removeBasicAuth = (idx) => {
@@ -239,12 +315,38 @@ function HostFormDialog({ open, onClose, onSaved, host }) {
                         </Callout.Root>
                     )}
 
+                    {lintWarnings.length > 0 && (
+                        <Callout.Root color="orange" size="1">
+                            <Callout.Icon><AlertTriangle size={14} /></Callout.Icon>
+                            <Callout.Text>
+                                {lintWarnings.map(w => (
+                                    <span key={w.code} style={{ display: 'block' }}>
+                                        {t(`host.lint.${w.code}`, { defaultValue: w.message })}
+                                    </span>
+                                ))}
+                            </Callout.Text>
+                        </Callout.Root>
+                    )}
+
                     {/* Template quick-apply (create mode only) */}
                     {!isEdit && templates.length > 0 && (
                         <Flex direction="column" gap="2">
-                            <Text size="1" weight="medium" color="gray">{t('template.from_template')}</Text>
+                            <Flex gap="1" align="center" wrap="wrap">
+                                <Text size="1" weight="medium" color="gray" mr="1">{t('template.from_template')}</Text>
+                                {[...new Set(templates.map(tpl => tpl.category).filter(Boolean))].map(cat => (
+                                    <Badge
+                                        key={cat}
+                                        size="1"
+                                        variant={templateCategory === cat ? 'solid' : 'outline'}
+                                        style={{ cursor: 'pointer', userSelect: 'none' }}
+                                        onClick={() => setTemplateCategory(templateCategory === cat ? '' : cat)}
+                                    >
+                                        {cat}
+                                    </Badge>
+                                ))}
+                            </Flex>
                             <Flex gap="2" wrap="wrap">
-                                {templates.map(tpl => (
+                                {templates.filter(tpl => !templateCategory || tpl.category === templateCategory).map(tpl => (
                                     <Tooltip key={tpl.id} content={tpl.description || tpl.name}>
                                         <Button
                                             variant="outline"
@@ -306,6 +408,17 @@ function HostFormDialog({ open, onClose, onSaved, host }) {
                         </Flex>
                     </Flex>
 
+                    <Flex direction="column" gap="1">
+                        <Text size="2" weight="medium">{t('host.aliases')}</Text>
+                        <TextField.Root
+                            placeholder="www.example.com, example.org"
+                            value={form.aliases}
+                            onChange={(e) => setForm({ ...form, aliases: e.target.value })}
+                            size="2"
+                        />
+                        <Text size="1" color="gray">{t('host.aliases_hint')}</Text>
+                    </Flex>
+
                     {/* Group & Tags */}
                     <Flex gap="3" align="end">
                         <Flex direction="column" gap="1" style={{ flex: 1 }}>
@@ -339,7 +452,7 @@ function HostFormDialog({ open, onClose, onSaved, host }) {
                                             key={tag.id}
                                             size="1"
                                             variant={selected ? 'solid' : 'outline'}
-                                            color={tag.color || 'gray'}
+                                            color={badgeColor(tag.color)}
                                             style={{ cursor: 'pointer', userSelect: 'none' }}
                                             onClick={() => {
                                                 const ids = selected
@@ -462,6 +575,17 @@ function HostFormDialog({ open, onClose, onSaved, host }) {
                                                 <Text size="1" color="gray">
                                                     {t('host.php_fastcgi_hint')}
                                                 </Text>
+                                                <Text size="2" weight="medium" mt="2">{t('host.php_version')}</Text>
+                                                <TextField.Root
+                                                    placeholder="8.3"
+                                                    value={form.php_version || ''}
+                                                    onChange={(e) => setForm({ ...form, php_version: e.target.value })}
+                                                    disabled={!!form.php_fastcgi}
+                                                    size="2"
+                                                />
+                                                <Text size="1" color="gray">
+                                                    {t('host.php_version_hint')}
+                                                </Text>
                                             </Flex>
                                         )}
                                         {isStatic && (
@@ -476,6 +600,18 @@ function HostFormDialog({ open, onClose, onSaved, host }) {
                                                 />
                                             </Flex>
                                         )}
+                                        {isStatic && (
+                                            <Flex justify="between" align="center">
+                                                <Flex direction="column">
+                                                    <Text size="2" weight="medium">{t('host.spa_fallback')}</Text>
+                                                    <Text size="1" color="gray">{t('host.spa_fallback_hint')}</Text>
+                                                </Flex>
+                                                <Switch
+                                                    checked={form.spa_fallback || false}
+                                                    onCheckedChange={(v) => setForm({ ...form, spa_fallback: v })}
+                                                />
+                                            </Flex>
+                                        )}
                                         <Flex direction="column" gap="1">
                                             <Text size="2" weight="medium">{t('host.index_files')}</Text>
                                             <TextField.Root
@@ -561,6 +697,53 @@ function HostFormDialog({ open, onClose, onSaved, host }) {
                                             {dnsProviders.length === 0 && (
                                                 <Text size="1" color="red">{t('dns.no_providers_hint')}</Text>
                                             )}
+                                            {!form.dns_provider_id && dnsProviders.some(p => p.is_default) && (
+                                                <Text size="1" color="gray">
+                                                    {t('host.dns_provider_default_hint', { name: dnsProviders.find(p => p.is_default).name })}
+                                                </Text>
+                                            )}
+                                        </Flex>
+                                    )}
+
+                                    {['auto', 'dns', 'wildcard'].includes(form.tls_mode || 'auto') && (
+                                        <Flex direction="column" gap="1" pl="4" style={{ borderLeft: '2px solid var(--cp-border-subtle)' }}>
+                                            <Text size="1" color="gray">{t('host.acme_ca')}</Text>
+                                            <Select.Root
+                                                value={!form.acme_ca ? 'default' : form.acme_ca === 'staging' ? 'staging' : 'custom'}
+                                                onValueChange={(v) => setForm({
+                                                    ...form,
+                                                    acme_ca: v === 'default' ? '' : v === 'staging' ? 'staging' : 'https://',
+                                                })}
+                                                size="2"
+                                            >
+                                                <Select.Trigger />
+                                                <Select.Content>
+                                                    <Select.Item value="default">{t('host.acme_ca_default')}</Select.Item>
+                                                    <Select.Item value="staging">{t('host.acme_ca_staging')}</Select.Item>
+                                                    <Select.Item value="custom">{t('host.acme_ca_custom')}</Select.Item>
+                                                </Select.Content>
+                                            </Select.Root>
+                                            {form.acme_ca && form.acme_ca !== 'staging' && (
+                                                <TextField.Root
+                                                    placeholder="https://acme.example.com/directory"
+                                                    value={form.acme_ca}
+                                                    onChange={(e) => setForm({ ...form, acme_ca: e.target.value })}
+                                                />
+                                            )}
+                                            {form.acme_ca === 'staging' && (
+                                                <Text size="1" color="orange">{t('host.acme_ca_staging_hint')}</Text>
+                                            )}
+                                            {certStatus && (
+                                                <Text size="1" color={certStatus.status === 'valid' ? 'gray' : 'orange'}>
+                                                    {certStatus.status === 'pending'
+                                                        ? t('host.cert_status_pending')
+                                                        : t(`host.cert_status_${certStatus.status}`, {
+                                                            issuer: certStatus.issuer,
+                                                            date: new Date(certStatus.not_after).toLocaleDateString(),
+                                                            count: certStatus.days_until_renewal,
+                                                        })}
+                                                </Text>
+                                            )}
                                         </Flex>
                                     )}
 
@@ -656,6 +839,49 @@ function HostFormDialog({ open, onClose, onSaved, host }) {
                                         />
                                     </Flex>
 
+                                    {form.compression && (() => {
+                                        // Empty means the server default (gzip, zstd).
+                                        const selected = form.compression_algorithms
+                                            ? form.compression_algorithms.split(',')
+                                            : ['gzip', 'zstd']
+                                        const toggle = (algo, on) => {
+                                            const next = COMPRESSION_ALGORITHMS.filter((a) => (a === algo ? on : selected.includes(a)))
+                                            setForm({ ...form, compression_algorithms: next.join(',') })
+                                        }
+                                        return (
+                                            <Flex direction="column" gap="1" pl="4" style={{ borderLeft: '2px solid var(--cp-border-subtle)' }}>
+                                                <Text size="1" color="gray">{t('host.compression_algorithms')}</Text>
+                                                <Flex gap="4">
+                                                    {COMPRESSION_ALGORITHMS.map((algo) => (
+                                                        <Flex key={algo} align="center" gap="2">
+                                                            <Switch
+                                                                size="1"
+                                                                checked={selected.includes(algo)}
+                                                                disabled={selected.length === 1 && selected.includes(algo)}
+                                                                onCheckedChange={(v) => toggle(algo, v)}
+                                                            />
+                                                            <Text size="1">{algo}</Text>
+                                                        </Flex>
+                                                    ))}
+                                                </Flex>
+                                                {selected.includes('br') && (
+                                                    <Text size="1" color="orange">{t('host.compression_br_hint')}</Text>
+                                                )}
+                                            </Flex>
+                                        )
+                                    })()}
+
+                                    <Flex justify="between" align="center">
+                                        <Flex direction="column">
+                                            <Text size="2" weight="medium">{t('host.separate_access_log')}</Text>
+                                            <Text size="1" color="gray">{t('host.separate_access_log_hint')}</Text>
+                                        </Flex>
+                                        <Switch
+                                            checked={form.separate_access_log}
+                                            onCheckedChange={(v) => setForm({ ...form, separate_access_log: v })}
+                                        />
+                                    </Flex>
+
                                     <Separator size="4" style={{ opacity: 0.15 }} />
                                     <Text size="2" weight="bold" style={{ color: 'var(--cp-text-secondary)' }}>{t('host.security')}</Text>
 
@@ -670,6 +896,101 @@ function HostFormDialog({ open, onClose, onSaved, host }) {
                                         />
                                     </Flex>
 
+                                    <Flex justify="between" align="center">
+                                        <Flex direction="column">
+                                            <Text size="2" weight="medium">{t('host.security_headers_custom')}</Text>
+                                            <Text size="1" color="gray">{t('host.security_headers_custom_hint')}</Text>
+                                        </Flex>
+                                        <Switch
+                                            checked={!!form.security_headers_config}
+                                            onCheckedChange={(v) => setForm({
+                                                ...form,
+                                                security_headers_config: v ? { ...DEFAULT_SECURITY_HEADERS_CONFIG } : null,
+                                            })}
+                                        />
+                                    </Flex>
+
+                                    {form.security_headers_config && (() => {
+                                        const sec = form.security_headers_config
+                                        const setSec = (patch) => setForm({ ...form, security_headers_config: { ...sec, ...patch } })
+                                        return (
+                                            <Flex direction="column" gap="2" pl="4" style={{ borderLeft: '2px solid var(--cp-border-subtle)' }}>
+                                                <Box>
+                                                    <Text size="1" color="gray" mb="1">{t('host.hsts_max_age')}</Text>
+                                                    <TextField.Root
+                                                        type="number"
+                                                        min={0}
+                                                        value={sec.hsts_max_age}
+                                                        onChange={(e) => setSec({ hsts_max_age: parseInt(e.target.value, 10) || 0 })}
+                                                        size="2"
+                                                    />
+                                                </Box>
+                                                <Flex gap="4">
+                                                    <Flex align="center" gap="2">
+                                                        <Switch
+                                                            size="1"
+                                                            checked={sec.hsts_include_subdomains}
+                                                            disabled={!sec.hsts_max_age}
+                                                            onCheckedChange={(v) => setSec({ hsts_include_subdomains: v })}
+                                                        />
+                                                        <Text size="1">{t('host.hsts_include_subdomains')}</Text>
+                                                    </Flex>
+                                                    <Flex align="center" gap="2">
+                                                        <Switch
+                                                            size="1"
+                                                            checked={sec.hsts_preload}
+                                                            disabled={!sec.hsts_max_age}
+                                                            onCheckedChange={(v) => setSec({ hsts_preload: v })}
+                                                        />
+                                                        <Text size="1">{t('host.hsts_preload')}</Text>
+                                                    </Flex>
+                                                </Flex>
+                                                <Flex gap="3">
+                                                    <Box style={{ flex: 1 }}>
+                                                        <Text size="1" color="gray" mb="1">X-Frame-Options</Text>
+                                                        <Select.Root
+                                                            value={sec.frame_options || 'none'}
+                                                            onValueChange={(v) => setSec({ frame_options: v === 'none' ? '' : v })}
+                                                            size="2"
+                                                        >
+                                                            <Select.Trigger style={{ width: '100%' }} />
+                                                            <Select.Content>
+                                                                <Select.Item value="none">{t('host.header_not_sent')}</Select.Item>
+                                                                <Select.Item value="DENY">DENY</Select.Item>
+                                                                <Select.Item value="SAMEORIGIN">SAMEORIGIN</Select.Item>
+                                                            </Select.Content>
+                                                        </Select.Root>
+                                                    </Box>
+                                                    <Box style={{ flex: 1 }}>
+                                                        <Text size="1" color="gray" mb="1">Referrer-Policy</Text>
+                                                        <Select.Root
+                                                            value={sec.referrer_policy || 'none'}
+                                                            onValueChange={(v) => setSec({ referrer_policy: v === 'none' ? '' : v })}
+                                                            size="2"
+                                                        >
+                                                            <Select.Trigger style={{ width: '100%' }} />
+                                                            <Select.Content>
+                                                                <Select.Item value="none">{t('host.header_not_sent')}</Select.Item>
+                                                                {REFERRER_POLICIES.map((p) => (
+                                                                    <Select.Item key={p} value={p}>{p}</Select.Item>
+                                                                ))}
+                                                            </Select.Content>
+                                                        </Select.Root>
+                                                    </Box>
+                                                </Flex>
+                                                <Box>
+                                                    <Text size="1" color="gray" mb="1">Content-Security-Policy</Text>
+                                                    <TextField.Root
+                                                        placeholder="default-src 'self'"
+                                                        value={sec.content_security_policy}
+                                                        onChange={(e) => setSec({ content_security_policy: e.target.value })}
+                                                        size="2"
+                                                    />
+                                                </Box>
+                                            </Flex>
+                                        )
+                                    })()}
+
                                     <Flex justify="between" align="center">
                                         <Flex direction="column">
                                             <Text size="2" weight="medium">{t('host.cors')}</Text>
@@ -707,6 +1028,26 @@ function HostFormDialog({ open, onClose, onSaved, host }) {
                                                     placeholder="Content-Type, Authorization"
                                                 />
                                             </Box>
+                                            <Box>
+                                                <Text size="1" color="gray" mb="1">{t('host.cors_max_age')}</Text>
+                                                <TextField.Root
+                                                    type="number"
+                                                    min={0}
+                                                    value={form.cors_max_age}
+                                                    onChange={(e) => setForm({ ...form, cors_max_age: parseInt(e.target.value, 10) || 0 })}
+                                                />
+                                            </Box>
+                                            <Flex justify="between" align="center">
+                                                <Flex direction="column">
+                                                    <Text size="1" weight="medium">{t('host.cors_allow_credentials')}</Text>
+                                                    <Text size="1" color="gray">{t('host.cors_allow_credentials_hint')}</Text>
+                                                </Flex>
+                                                <Switch
+                                                    size="1"
+                                                    checked={form.cors_allow_credentials}
+                                                    onCheckedChange={(v) => setForm({ ...form, cors_allow_credentials: v })}
+                                                />
+                                            </Flex>
                                         </Flex>
                                     )}
 
@@ -728,6 +1069,39 @@ function HostFormDialog({ open, onClose, onSaved, host }) {
                                     <Separator size="4" style={{ opacity: 0.15 }} />
                                     <Text size="2" weight="bold" style={{ color: 'var(--cp-text-secondary)' }}>{t('host.advanced')}</Text>
 
+                                    <Box>
+                                        <Text size="2" weight="medium" mb="1">{t('host.max_request_body_size')}</Text>
+                                        <Text size="1" color="gray" mb="2" as="p">
+                                            {t('host.max_request_body_size_hint')}
+                                        </Text>
+                                        <TextField.Root
+                                            value={form.max_request_body_size}
+                                            onChange={(e) => setForm({ ...form, max_request_body_size: e.target.value })}
+                                            placeholder="10MB"
+                                        />
+                                    </Box>
+
+                                    {isProxy && (
+                                        <Box>
+                                            <Text size="2" weight="medium" mb="1">{t('host.proxy_timeouts')}</Text>
+                                            <Text size="1" color="gray" mb="2" as="p">
+                                                {t('host.proxy_timeouts_hint')}
+                                            </Text>
+                                            <Flex gap="3">
+                                                {['dial', 'read', 'write'].map((kind) => (
+                                                    <Box key={kind} style={{ flex: 1 }}>
+                                                        <Text size="1" color="gray">{t(`host.proxy_${kind}_timeout`)}</Text>
+                                                        <TextField.Root
+                                                            value={form[`proxy_${kind}_timeout`]}
+                                                            onChange={(e) => setForm({ ...form, [`proxy_${kind}_timeout`]: e.target.value })}
+                                                            placeholder={kind === 'dial' ? '10s' : '5m'}
+                                                        />
+                                                    </Box>
+                                                ))}
+                                            </Flex>
+                                        </Box>
+                                    )}
+
                                     <Box>
                                         <Text size="2" weight="medium" mb="1">{t('host.custom_directives')}</Text>
                                         <Text size="1" color="gray" mb="2" as="p">
@@ -763,6 +1137,9 @@ function HostFormDialog({ open, onClose, onSaved, host }) {
                                             {t('host.no_auth_hint')}
                                         </Text>
                                     )}
+                                    {form.basic_auths.length > 0 && (
+                                        <Text size="1" color="gray">{t('host.auth_path_hint')}</Text>
+                                    )}
                                     {form.basic_auths.map((auth, i) => (
                                         <Flex key={i} gap="2" align="center">
                                             <TextField.Root
@@ -788,6 +1165,17 @@ function HostFormDialog({ open, onClose, onSaved, host }) {
                                                 }}
                                                 size="2"
                                             />
+                                            <TextField.Root
+                                                style={{ flex: 1 }}
+                                                placeholder={t('host.auth_path_placeholder')}
+                                                value={auth.path || ''}
+                                                onChange={(e) => {
+                                                    const auths = [...form.basic_auths]
+                                                    auths[i] = { ...auths[i], path: e.target.value }
+                                                    setForm({ ...form, basic_auths: auths })
+                                                }}
+                                                size="2"
+                                            />
                                             <IconButton
                                                 variant="ghost"
                                                 color="red"
@@ -806,6 +1194,33 @@ function HostFormDialog({ open, onClose, onSaved, host }) {
                                             </Callout.Text>
                                         </Callout.Root>
                                     )}
+                                    <Flex direction="column" gap="1" mt="2">
+                                        <Text size="2" weight="medium">{t('host.auth_realm')}</Text>
+                                        <TextField.Root
+                                            placeholder={t('host.auth_realm_placeholder')}
+                                            value={form.basic_auth_realm}
+                                            onChange={(e) => setForm({ ...form, basic_auth_realm: e.target.value })}
+                                            size="2"
+                                        />
+                                    </Flex>
+                                    <Separator size="4" my="2" />
+                                    <Flex direction="column" gap="1">
+                                        <Text size="2" weight="medium">{t('host.forward_auth')}</Text>
+                                        <Text size="1" color="gray">{t('host.forward_auth_hint')}</Text>
+                                        <TextField.Root
+                                            placeholder="http://authelia:9091/api/authz/forward-auth"
+                                            value={form.forward_auth_url}
+                                            onChange={(e) => setForm({ ...form, forward_auth_url: e.target.value })}
+                                            size="2"
+                                        />
+                                        <TextField.Root
+                                            placeholder={t('host.forward_auth_headers_placeholder')}
+                                            value={form.forward_auth_copy_headers}
+                                            onChange={(e) => setForm({ ...form, forward_auth_copy_headers: e.target.value })}
+                                            disabled={!form.forward_auth_url}
+                                            size="2"
+                                        />
+                                    </Flex>
                                 </Flex>
                             </Box>
                         </Tabs.Content>
@@ -841,6 +1256,7 @@ function SaveAsTemplateButton({ hostId, t }) {
     const [open, setOpen] = useState(false)
     const [name, setName] = useState('')
     const [description, setDescription] = useState('')
+    const [category, setCategory] = useState('')
     const [saving, setSaving] = useState(false)
     const [error, setError] = useState('')
     const [success, setSuccess] = useState(false)
@@ -848,6 +1264,7 @@ function SaveAsTemplateButton({ hostId, t }) {
     const handleOpen = () => {
         setName('')
         setDescription('')
+        setCategory('')
         setError('')
         setSuccess(false)
         setSaving(false)
@@ -859,7 +1276,7 @@ function SaveAsTemplateButton({ hostId, t }) {
         setError('')
         setSaving(true)
         try {
-            await templateAPI.saveAsTemplate(hostId, { name: name.trim(), description: description.trim() })
+            await templateAPI.saveAsTemplate(hostId, { name: name.trim(), description: description.trim(), category: category.trim() })
             setSuccess(true)
             setTimeout(() => setOpen(false), 1000)
         } catch (err) {
@@ -909,6 +1326,15 @@ function SaveAsTemplateButton({ hostId, t }) {
                                 size="2"
                             />
                         </Flex>
+                        <Flex direction="column" gap="1">
+                            <Text size="2" weight="medium">{t('template.category')}</Text>
+                            <TextField.Root
+                                placeholder={t('template.category_placeholder')}
+                                value={category}
+                                onChange={(e) => setCategory(e.target.value)}
+                                size="2"
+                            />
+                        </Flex>
                         <Flex gap="3" justify="end">
                             <Button variant="soft" color="gray" onClick={() => setOpen(false)}>
                                 {t('common.cancel')}
@@ -955,29 +1381,39 @@ function DnsStatusIcon({ status, dnsResult, t }) {
 }
 
 // ============ Clone Dialog ============
-function CloneDialog({ open, onClose, host, onCloned, t }) {
+function CloneDialog({ open, onClose, host, onCloned, groups, allTags, t }) {
     const [newDomain, setNewDomain] = useState('')
+    const [groupId, setGroupId] = useState(null)
+    const [tagIds, setTagIds] = useState([])
     const [cloning, setCloning] = useState(false)
     const [error, setError] = useState('')
 
     useEffect(() => {
         if (open) {
             setNewDomain('')
+            // File the clone like its source unless changed here.
+            setGroupId(host?.group_id || null)
+            setTagIds(host?.tags?.map((tag) => tag.id) || [])
             setError('')
             setCloning(false)
         }
-    }, [open])
+    }, [open, host])
 
     const handleClone = async () => {
         if (!newDomain.trim()) return
         setError('')
         setCloning(true)
         try {
-            await hostAPI.clone(host.id, { domain: newDomain.trim() })
+            await hostAPI.clone(host.id, { domain: newDomain.trim(), group_id: groupId || 0, tag_ids: tagIds })
             onCloned()
             onClose()
         } catch (err) {
-            setError(err.response?.data?.error || t('clone.failed'))
+            const data = err.response?.data
+            if (data?.error_key === 'error.domain_overlap') {
+                setError(t('error.domain_overlap', { domain: data.conflict?.domain }))
+            } else {
+                setError(data?.error || t('clone.failed'))
+            }
         } finally {
             setCloning(false)
         }
@@ -1008,6 +1444,48 @@ function CloneDialog({ open, onClose, host, onCloned, t }) {
                             onKeyDown={(e) => e.key === 'Enter' && handleClone()}
                         />
                     </Flex>
+                    {groups.length > 0 && (
+                        <Flex direction="column" gap="1">
+                            <Text size="2" weight="medium">{t('group.label')}</Text>
+                            <Select.Root
+                                value={groupId ? String(groupId) : '__none__'}
+                                onValueChange={(v) => setGroupId(v === '__none__' ? null : Number(v))}
+                                size="2"
+                            >
+                                <Select.Trigger placeholder={t('group.select_placeholder')} />
+                                <Select.Content>
+                                    <Select.Item value="__none__">{t('group.none')}</Select.Item>
+                                    {groups.map(g => (
+                                        <Select.Item key={g.id} value={String(g.id)}>{g.name}</Select.Item>
+                                    ))}
+                                </Select.Content>
+                            </Select.Root>
+                        </Flex>
+                    )}
+                    {allTags.length > 0 && (
+                        <Flex direction="column" gap="1">
+                            <Text size="2" weight="medium">{t('tag.label')}</Text>
+                            <Flex gap="1" wrap="wrap">
+                                {allTags.map(tag => {
+                                    const selected = tagIds.includes(tag.id)
+                                    return (
+                                        <Badge
+                                            key={tag.id}
+                                            size="1"
+                                            variant={selected ? 'solid' : 'outline'}
+                                            color={badgeColor(tag.color)}
+                                            style={{ cursor: 'pointer', userSelect: 'none' }}
+                                            onClick={() => setTagIds(selected
+                                                ? tagIds.filter(id => id !== tag.id)
+                                                : [...tagIds, tag.id])}
+                                        >
+                                            {tag.name}
+                                        </Badge>
+                                    )
+                                })}
+                            </Flex>
+                        </Flex>
+                    )}
                     <Flex gap="3" justify="end">
                         <Dialog.Close>
                             <Button variant="soft" color="gray">{t('common.cancel')}</Button>
@@ -1023,6 +1501,301 @@ function CloneDialog({ open, onClose, host, onCloned, t }) {
     )
 }
 
+// ============ Schedule Dialog ============
+function ScheduleDialog({ open, onClose, host, t }) {
+    const [enableCron, setEnableCron] = useState('')
+    const [disableCron, setDisableCron] = useState('')
+    const [loading, setLoading] = useState(false)
+    const [saving, setSaving] = useState(false)
+    const [error, setError] = useState('')
+
+    useEffect(() => {
+        if (!open || !host) return
+        setError('')
+        setSaving(false)
+        setLoading(true)
+        hostAPI.getSchedule(host.id).then((res) => {
+            setEnableCron(res.data.enable_cron || '')
+            setDisableCron(res.data.disable_cron || '')
+        }).catch(() => {
+            setEnableCron('')
+            setDisableCron('')
+        }).finally(() => setLoading(false))
+    }, [open, host])
+
+    const handleSave = async () => {
+        setError('')
+        setSaving(true)
+        try {
+            await hostAPI.setSchedule(host.id, { enable_cron: enableCron.trim(), disable_cron: disableCron.trim() })
+            onClose()
+        } catch (err) {
+            setError(err.response?.data?.error || t('schedule.failed'))
+        } finally {
+            setSaving(false)
+        }
+    }
+
+    return (
+        <Dialog.Root open={open} onOpenChange={(o) => !o && onClose()}>
+            <Dialog.Content maxWidth="420px" style={{ background: 'var(--cp-card)' }}>
+                <Dialog.Title>{t('schedule.title')}</Dialog.Title>
+                <Dialog.Description size="2" color="gray">{host?.domain}</Dialog.Description>
+                {loading ? (
+                    <Flex justify="center" p="5"><Spinner size="2" /></Flex>
+                ) : (
+                    <Flex direction="column" gap="4" mt="3">
+                        {error && (
+                            <Callout.Root color="red" size="1">
+                                <Callout.Icon><AlertCircle size={14} /></Callout.Icon>
+                                <Callout.Text>{error}</Callout.Text>
+                            </Callout.Root>
+                        )}
+                        <Flex direction="column" gap="1">
+                            <Text size="2" weight="medium">{t('schedule.enable_cron')}</Text>
+                            <TextField.Root placeholder="0 9 * * 1-5" value={enableCron} onChange={(e) => setEnableCron(e.target.value)} size="2" />
+                        </Flex>
+                        <Flex direction="column" gap="1">
+                            <Text size="2" weight="medium">{t('schedule.disable_cron')}</Text>
+                            <TextField.Root placeholder="0 18 * * 1-5" value={disableCron} onChange={(e) => setDisableCron(e.target.value)} size="2" />
+                        </Flex>
+                        <Text size="1" color="gray">{t('schedule.hint')}</Text>
+                        <Flex gap="3" justify="end">
+                            <Dialog.Close>
+                                <Button variant="soft" color="gray">{t('common.cancel')}</Button>
+                            </Dialog.Close>
+                            <Button onClick={handleSave} disabled={saving}>
+                                {saving ? <Spinner size="1" /> : <Clock size={14} />}
+                                {t('common.save')}
+                            </Button>
+                        </Flex>
+                    </Flex>
+                )}
+            </Dialog.Content>
+        </Dialog.Root>
+    )
+}
+
+// ============ Revision History Dialog ============
+function HistoryDialog({ open, onClose, host, onRestored, t }) {
+    const [revisions, setRevisions] = useState([])
+    const [loading, setLoading] = useState(true)
+    const [restoring, setRestoring] = useState(null)
+    const [error, setError] = useState('')
+
+    useEffect(() => {
+        if (!open || !host) return
+        setError('')
+        setLoading(true)
+        hostAPI.revisions(host.id)
+            .then((res) => setRevisions(res.data.revisions || []))
+            .catch((err) => setError(err.response?.data?.error || t('revision.load_failed')))
+            .finally(() => setLoading(false))
+    }, [open, host, t])
+
+    const handleRestore = async (rev) => {
+        setError('')
+        setRestoring(rev.revision)
+        try {
+            await hostAPI.restoreRevision(host.id, rev.revision)
+            onRestored()
+            onClose()
+        } catch (err) {
+            if (err.response?.status === 409) {
+                setError(t('error.stale_host'))
+            } else {
+                setError(err.response?.data?.error || t('revision.restore_failed'))
+            }
+        } finally {
+            setRestoring(null)
+        }
+    }
+
+    return (
+        <Dialog.Root open={open} onOpenChange={(o) => !o && onClose()}>
+            <Dialog.Content maxWidth="520px" style={{ background: 'var(--cp-card)' }}>
+                <Dialog.Title>{t('revision.title', { domain: host?.domain })}</Dialog.Title>
+                <Dialog.Description size="2" color="gray" mb="4">
+                    {t('revision.description')}
+                </Dialog.Description>
+                {error && (
+                    <Callout.Root color="red" size="1" mb="3">
+                        <Callout.Icon><AlertCircle size={14} /></Callout.Icon>
+                        <Callout.Text>{error}</Callout.Text>
+                    </Callout.Root>
+                )}
+                {loading ? (
+                    <Flex justify="center" p="5"><Spinner size="2" /></Flex>
+                ) : revisions.length === 0 ? (
+                    <Text size="2" color="gray">{t('revision.empty')}</Text>
+                ) : (
+                    <Flex direction="column" gap="2">
+                        {revisions.map((rev) => (
+                            <Flex key={rev.id} justify="between" align="center" gap="3">
+                                <Box>
+                                    <Flex align="center" gap="2">
+                                        <Text size="2" weight="medium">{t('revision.label', { revision: rev.revision })}</Text>
+                                        {rev.revision === host?.version && (
+                                            <Badge size="1" color="green" variant="soft">{t('revision.current')}</Badge>
+                                        )}
+                                    </Flex>
+                                    <Text as="div" size="1" color="gray">
+                                        {t('revision.changed_by', {
+                                            user: rev.username || t('revision.system'),
+                                            time: new Date(rev.created_at).toLocaleString(),
+                                        })}
+                                    </Text>
+                                </Box>
+                                {rev.revision !== host?.version && (
+                                    <Button size="1" variant="soft" onClick={() => handleRestore(rev)} disabled={restoring !== null}>
+                                        {restoring === rev.revision ? <Spinner size="1" /> : <ArchiveRestore size={12} />}
+                                        {t('revision.restore')}
+                                    </Button>
+                                )}
+                            </Flex>
+                        ))}
+                    </Flex>
+                )}
+                <Flex justify="end" mt="4">
+                    <Dialog.Close>
+                        <Button variant="soft" color="gray">{t('common.close')}</Button>
+                    </Dialog.Close>
+                </Flex>
+            </Dialog.Content>
+        </Dialog.Root>
+    )
+}
+
+// ============ Trash Dialog ============
+function TrashDialog({ open, onClose, onRestored, t }) {
+    const [hosts, setHosts] = useState([])
+    const [loading, setLoading] = useState(true)
+    const [busy, setBusy] = useState(null)
+    const [purgeHost, setPurgeHost] = useState(null)
+    const [error, setError] = useState('')
+
+    const fetchTrash = useCallback(async () => {
+        try {
+            const res = await hostAPI.trash()
+            setHosts(res.data.hosts || [])
+        } catch (err) {
+            setError(err.response?.data?.error || t('host.trash_load_failed'))
+        } finally {
+            setLoading(false)
+        }
+    }, [t])
+
+    useEffect(() => {
+        if (open) {
+            setError('')
+            setLoading(true)
+            fetchTrash()
+        }
+    }, [open, fetchTrash])
+
+    const handleRestore = async (host) => {
+        setError('')
+        setBusy(host.id)
+        try {
+            await hostAPI.restore(host.id)
+            onRestored()
+            fetchTrash()
+        } catch (err) {
+            if (err.response?.status === 409) {
+                setError(t('error.restore_domain_taken', { domain: host.domain }))
+            } else {
+                setError(err.response?.data?.error || t('host.restore_failed'))
+            }
+        } finally {
+            setBusy(null)
+        }
+    }
+
+    const handlePurge = async () => {
+        setError('')
+        setBusy(purgeHost.id)
+        try {
+            await hostAPI.purge(purgeHost.id)
+            fetchTrash()
+        } catch (err) {
+            setError(err.response?.data?.error || t('host.purge_failed'))
+        } finally {
+            setBusy(null)
+            setPurgeHost(null)
+        }
+    }
+
+    return (
+        <Dialog.Root open={open} onOpenChange={(o) => !o && onClose()}>
+            <Dialog.Content maxWidth="560px" style={{ background: 'var(--cp-card)' }}>
+                <Dialog.Title>{t('host.trash_title')}</Dialog.Title>
+                <Dialog.Description size="2" color="gray" mb="4">
+                    {t('host.trash_description')}
+                </Dialog.Description>
+                {error && (
+                    <Callout.Root color="red" size="1" mb="3">
+                        <Callout.Icon><AlertCircle size={14} /></Callout.Icon>
+                        <Callout.Text>{error}</Callout.Text>
+                    </Callout.Root>
+                )}
+                {loading ? (
+                    <Flex justify="center" p="5"><Spinner size="2" /></Flex>
+                ) : hosts.length === 0 ? (
+                    <Text size="2" color="gray">{t('host.trash_empty')}</Text>
+                ) : (
+                    <Flex direction="column" gap="2">
+                        {hosts.map((host) => (
+                            <Flex key={host.id} justify="between" align="center" gap="3">
+                                <Box>
+                                    <Text size="2" weight="medium" title={host.display_domain ? host.domain : undefined}>{host.display_domain || host.domain}</Text>
+                                    <Text as="div" size="1" color="gray">
+                                        {t('host.deleted_at', { time: new Date(host.deleted_at).toLocaleString() })}
+                                    </Text>
+                                </Box>
+                                <Flex gap="2">
+                                    <Button size="1" variant="soft" onClick={() => handleRestore(host)} disabled={busy === host.id}>
+                                        <ArchiveRestore size={12} />
+                                        {t('host.restore')}
+                                    </Button>
+                                    <Button size="1" variant="soft" color="red" onClick={() => setPurgeHost(host)} disabled={busy === host.id}>
+                                        <Trash2 size={12} />
+                                        {t('host.purge')}
+                                    </Button>
+                                </Flex>
+                            </Flex>
+                        ))}
+                    </Flex>
+                )}
+                <Flex justify="end" mt="4">
+                    <Dialog.Close>
+                        <Button variant="soft" color="gray">{t('common.close')}</Button>
+                    </Dialog.Close>
+                </Flex>
+
+                <AlertDialog.Root open={!!purgeHost} onOpenChange={(o) => !o && setPurgeHost(null)}>
+                    <AlertDialog.Content maxWidth="400px" style={{ background: 'var(--cp-card)' }}>
+                        <AlertDialog.Title>{t('host.purge_title')}</AlertDialog.Title>
+                        <AlertDialog.Description size="2">
+                            {t('host.confirm_purge', { domain: purgeHost?.domain })}
+                        </AlertDialog.Description>
+                        <Flex gap="3" mt="4" justify="end">
+                            <AlertDialog.Cancel>
+                                <Button variant="soft" color="gray">{t('common.cancel')}</Button>
+                            </AlertDialog.Cancel>
+                            <AlertDialog.Action>
+                                <Button color="red" onClick={handlePurge}>
+                                    <Trash2 size={14} />
+                                    {t('host.purge')}
+                                </Button>
+                            </AlertDialog.Action>
+                        </Flex>
+                    </AlertDialog.Content>
+                </AlertDialog.Root>
+            </Dialog.Content>
+        </Dialog.Root>
+    )
+}
+
 // ============ Delete Confirmation ============
 function DeleteDialog({ open, onClose, host, onConfirm }) {
     const { t } = useTranslation()
@@ -1056,7 +1829,14 @@ function DeleteDialog({ open, onClose, host, onConfirm }) {
 }
 
 // ============ Mobile Host Card ============
-function HostCard({ host, t, onEdit, onDelete, onToggle, onClone, toggling, dnsStatus }) {
+// ProxyIcon shows the upstream app's favicon, falling back to the globe.
+function ProxyIcon({ favicon }) {
+    const [failed, setFailed] = useState(false)
+    if (!favicon || failed) return <Globe size={14} color="#10b981" />
+    return <img src={favicon} alt="" width={14} height={14} onError={() => setFailed(true)} style={{ flexShrink: 0 }} />
+}
+
+function HostCard({ host, t, onEdit, onDelete, onToggle, onClone, onHistory, onSchedule, toggling, dnsStatus, metadata }) {
     return (
         <Box className="mobile-host-card" mb="3">
             <Flex justify="between" align="start" mb="2">
@@ -1068,11 +1848,16 @@ function HostCard({ host, t, onEdit, onDelete, onToggle, onClone, toggling, dnsS
                     ) : host.host_type === 'php' ? (
                         <Globe size={14} color="#8b5cf6" />
                     ) : (
-                        <Globe size={14} color="#10b981" />
+                        <ProxyIcon favicon={metadata?.favicon} />
                     )}
-                    <Text size="2" weight="bold" style={{ color: 'var(--cp-text)', wordBreak: 'break-all' }}>
-                        {host.domain}
+                    <Text size="2" weight="bold" style={{ color: 'var(--cp-text)', wordBreak: 'break-all' }} title={host.display_domain ? host.domain : undefined}>
+                        {host.display_domain || host.domain}
                     </Text>
+                    {host.aliases && (
+                        <Badge size="1" variant="soft" color="gray">
+                            {t('host.alias_count', { count: host.aliases.split(',').length })}
+                        </Badge>
+                    )}
                     {dnsStatus && <DnsStatusIcon status={dnsStatus.status} dnsResult={dnsStatus} t={t} />}
                 </Flex>
                 <Badge
@@ -1084,6 +1869,10 @@ function HostCard({ host, t, onEdit, onDelete, onToggle, onClone, toggling, dnsS
                 </Badge>
             </Flex>
 
+            {metadata?.title && (
+                <Text as="p" size="1" color="gray" mb="2">{metadata.title}</Text>
+            )}
+
             <Flex align="center" gap="2" mb="3">
                 <Badge color={host.tls_enabled ? 'green' : 'gray'} variant="soft" size="1">
                     {host.tls_enabled ? 'HTTPS' : 'HTTP'}
@@ -1091,13 +1880,25 @@ function HostCard({ host, t, onEdit, onDelete, onToggle, onClone, toggling, dnsS
                 <Badge color={host.enabled ? 'green' : 'gray'} variant="soft" size="1">
                     {host.enabled ? t('common.enabled') : t('common.disabled')}
                 </Badge>
+                {host.config_error && (
+                    <Tooltip content={host.config_error}>
+                        <Badge color="red" variant="soft" size="1">
+                            {t('host.config_error')}
+                        </Badge>
+                    </Tooltip>
+                )}
+                {host.maintenance_mode && (
+                    <Badge color="amber" variant="soft" size="1">
+                        {t('host.maintenance')}
+                    </Badge>
+                )}
                 {host.group && (
-                    <Badge color={host.group.color || 'gray'} variant="soft" size="1">
+                    <Badge color={badgeColor(host.group.color)} variant="soft" size="1">
                         <FolderOpen size={10} /> {host.group.name}
                     </Badge>
                 )}
                 {host.tags?.map(tag => (
-                    <Badge key={tag.id} color={tag.color || 'gray'} variant="outline" size="1">
+                    <Badge key={tag.id} color={badgeColor(tag.color)} variant="outline" size="1">
                         {tag.name}
                     </Badge>
                 ))}
@@ -1113,6 +1914,16 @@ function HostCard({ host, t, onEdit, onDelete, onToggle, onClone, toggling, dnsS
                     />
                 </Tooltip>
                 <Flex gap="2">
+                    <Tooltip content={t('revision.tooltip')}>
+                        <IconButton variant="soft" size="1" onClick={() => onHistory(host)}>
+                            <History size={14} />
+                        </IconButton>
+                    </Tooltip>
+                    <Tooltip content={t('schedule.tooltip')}>
+                        <IconButton variant="soft" size="1" onClick={() => onSchedule(host)}>
+                            <Clock size={14} />
+                        </IconButton>
+                    </Tooltip>
                     <Tooltip content={t('clone.tooltip')}>
                         <IconButton variant="soft" size="1" onClick={() => onClone(host)}>
                             <Copy size={14} />
@@ -1143,12 +1954,23 @@ export default function HostList() {
     const [showForm, setShowForm] = useState(false)
     const [deleteHost, setDeleteHost] = useState(null)
     const [cloneHost, setCloneHost] = useState(null)
+    const [historyHost, setHistoryHost] = useState(null)
+    const [scheduleHost, setScheduleHost] = useState(null)
+    const [showTrash, setShowTrash] = useState(false)
     const [toggling, setToggling] = useState(null)
     const [dnsStatuses, setDnsStatuses] = useState({})
+    const [metadata, setMetadata] = useState({})
     const [filterGroupId, setFilterGroupId] = useState('')
     const [filterTagId, setFilterTagId] = useState('')
+    const [domainQuery, setDomainQuery] = useState('')
+    const [filterDomain, setFilterDomain] = useState('')
+    const [page, setPage] = useState(1)
+    const [total, setTotal] = useState(0)
+    const perPage = 50
     const [groups, setGroups] = useState([])
     const [allTags, setAllTags] = useState([])
+    const [applyWarning, setApplyWarning] = useState('')
+    const [copiedCurl, setCopiedCurl] = useState(null)
     const [isMobile, setIsMobile] = useState(() =>
         typeof window !== 'undefined' && window.matchMedia('(max-width: 767px)').matches
     )
@@ -1165,14 +1987,52 @@ export default function HostList() {
             const params = {}
             if (filterGroupId) params.group_id = filterGroupId
             if (filterTagId) params.tag_id = filterTagId
+            if (filterDomain) params.domain = filterDomain
+            params.limit = perPage
+            params.offset = (page - 1) * perPage
             const res = await hostAPI.list(params)
-            setHosts(res.data.hosts || [])
+            const list = res.data.hosts || []
+            // Step back when the current page emptied, e.g. after a delete.
+            if (list.length === 0 && page > 1 && res.data.total > 0) {
+                setPage(Math.ceil(res.data.total / perPage))
+                return
+            }
+            setHosts(list)
+            setTotal(res.data.total || 0)
         } catch (err) {
             console.error('Failed to fetch hosts:', err)
         } finally {
             setLoading(false)
         }
-    }, [filterGroupId, filterTagId])
+    }, [filterGroupId, filterTagId, filterDomain, page])
+
+    // Apply the domain filter once typing pauses.
+    useEffect(() => {
+        const timer = setTimeout(() => {
+            setFilterDomain(domainQuery.trim())
+            setPage(1)
+        }, 300)
+        return () => clearTimeout(timer)
+    }, [domainQuery])
+
+    const totalPages = Math.ceil(total / perPage)
+
+    // Warn when a change was saved but Caddy is not serving it, or was
+    // saved despite a warning such as a domain overlap.
+    const noteApply = (apply, warning) => {
+        if (apply?.reload_error) {
+            setApplyWarning(t('host.apply_reload_failed', { error: apply.reload_error }))
+        } else if (warning?.key === 'warning.domain_overlap') {
+            setApplyWarning(t('host.domain_overlap_warning', { domain: warning.domain }))
+        } else {
+            setApplyWarning(warning?.message || '')
+        }
+    }
+
+    const handleSaved = (apply, warning) => {
+        noteApply(apply, warning)
+        fetchHosts()
+    }
 
     const fetchGroupsAndTags = useCallback(async () => {
         try {
@@ -1202,10 +2062,22 @@ export default function HostList() {
         })
     }, [hosts])
 
+    // Fetch the upstream app's title and favicon for proxy hosts
+    useEffect(() => {
+        hosts.forEach((host) => {
+            if (host.host_type === 'proxy' && !metadata[host.id]) {
+                hostAPI.metadata(host.id).then((res) => {
+                    setMetadata((prev) => ({ ...prev, [host.id]: res.data }))
+                }).catch(() => {})
+            }
+        })
+    }, [hosts])
+
     const handleToggle = async (host) => {
         setToggling(host.id)
         try {
-            await hostAPI.toggle(host.id)
+            const res = await hostAPI.toggle(host.id)
+            noteApply(res.data?.apply)
             fetchHosts()
         } catch (err) {
             console.error('Failed to toggle host:', err)
@@ -1214,6 +2086,30 @@ export default function HostList() {
         }
     }
 
+    const handleCopyCurl = async (host) => {
+        try {
+            const res = await hostAPI.curl(host.id)
+            copyToClipboard(res.data.curl, () => {
+                setCopiedCurl(host.id)
+                setTimeout(() => setCopiedCurl(null), 2000)
+            })
+        } catch (err) {
+            console.error('Failed to build curl command:', err)
+        }
+    }
+
+    const handleMaintenance = async (host) => {
+        setToggling(host.id)
+        try {
+            await hostAPI.setMaintenance(host.id, { enabled: !host.maintenance_mode })
+            fetchHosts()
+        } catch (err) {
+            console.error('Failed to set maintenance mode:', err)
+        } finally {
+            setToggling(null)
+        }
+    }
+
     const handleDelete = async () => {
         try {
             await hostAPI.delete(deleteHost.id)
@@ -1253,7 +2149,7 @@ export default function HostList() {
         if (host.host_type === 'php') {
             return (
                 <Flex align="center" gap="1">
-                    <Text size="1" color="gray">🐘 {host.root_path || '-'} → {host.php_fastcgi || 'localhost:9000'}</Text>
+                    <Text size="1" color="gray">🐘 {host.root_path || '-'} → {host.php_fastcgi || (host.php_version ? `PHP ${host.php_version} (unix socket)` : 'localhost:9000')}</Text>
                 </Flex>
             )
         }
@@ -1278,67 +2174,89 @@ export default function HostList() {
                         {t('host.subtitle')}
                     </Text>
                 </Box>
-                <Button size="2" onClick={openCreate}>
-                    <Plus size={16} />
-                    {t('host.add_host')}
-                </Button>
+                <Flex gap="2">
+                    <Button size="2" variant="soft" color="gray" onClick={() => setShowTrash(true)}>
+                        <Trash2 size={16} />
+                        {t('host.trash')}
+                    </Button>
+                    <Button size="2" onClick={openCreate}>
+                        <Plus size={16} />
+                        {t('host.add_host')}
+                    </Button>
+                </Flex>
             </Flex>
 
-            {/* Group & Tag Filters */}
-            {(groups.length > 0 || allTags.length > 0) && (
-                <Flex gap="3" mb="4" align="end" wrap="wrap" direction={isMobile ? 'column' : 'row'}>
-                    {groups.length > 0 && (
-                        <Flex direction="column" gap="1" style={isMobile ? { width: '100%' } : {}}>
-                            <Text size="1" color="gray">{t('group.filter')}</Text>
-                            <Select.Root
-                                value={filterGroupId}
-                                onValueChange={(v) => { setFilterGroupId(v); setLoading(true) }}
-                                size="2"
-                            >
-                                <Select.Trigger placeholder={t('group.all')} style={isMobile ? { width: '100%' } : { minWidth: 140 }} />
-                                <Select.Content>
-                                    <Select.Item value="">{t('group.all')}</Select.Item>
-                                    {groups.map(g => (
-                                        <Select.Item key={g.id} value={String(g.id)}>
-                                            <Flex align="center" gap="2">
-                                                <Box style={{ width: 8, height: 8, borderRadius: '50%', background: g.color || '#9ca3af' }} />
-                                                {g.name}
-                                            </Flex>
-                                        </Select.Item>
-                                    ))}
-                                </Select.Content>
-                            </Select.Root>
-                        </Flex>
-                    )}
-                    {allTags.length > 0 && (
-                        <Flex direction="column" gap="1" style={isMobile ? { width: '100%' } : {}}>
-                            <Text size="1" color="gray">{t('tag.filter')}</Text>
-                            <Flex gap="1" wrap="wrap">
-                                {allTags.map(tag => {
-                                    const active = filterTagId === String(tag.id)
-                                    return (
-                                        <Badge
-                                            key={tag.id}
-                                            size="1"
-                                            variant={active ? 'solid' : 'outline'}
-                                            color={tag.color || 'gray'}
-                                            style={{ cursor: 'pointer', userSelect: 'none' }}
-                                            onClick={() => {
-                                                const newVal = active ? '' : String(tag.id)
-                                                setFilterTagId(newVal)
-                                                setLoading(true)
-                                            }}
-                                        >
-                                            <Tags size={10} /> {tag.name}
-                                        </Badge>
-                                    )
-                                })}
-                            </Flex>
-                        </Flex>
-                    )}
-                </Flex>
+            {applyWarning && (
+                <Callout.Root color="orange" size="1" mb="4">
+                    <Callout.Icon><AlertCircle size={14} /></Callout.Icon>
+                    <Callout.Text>{applyWarning}</Callout.Text>
+                </Callout.Root>
             )}
 
+            {/* Domain, Group & Tag Filters */}
+            <Flex gap="3" mb="4" align="end" wrap="wrap" direction={isMobile ? 'column' : 'row'}>
+                <Flex direction="column" gap="1" style={isMobile ? { width: '100%' } : {}}>
+                    <Text size="1" color="gray">{t('host.domain_filter')}</Text>
+                    <TextField.Root
+                        size="2"
+                        value={domainQuery}
+                        onChange={(e) => setDomainQuery(e.target.value)}
+                        placeholder={t('host.domain_filter_placeholder')}
+                        style={isMobile ? { width: '100%' } : { minWidth: 200 }}
+                    />
+                </Flex>
+                {groups.length > 0 && (
+                    <Flex direction="column" gap="1" style={isMobile ? { width: '100%' } : {}}>
+                        <Text size="1" color="gray">{t('group.filter')}</Text>
+                        <Select.Root
+                            value={filterGroupId}
+                            onValueChange={(v) => { setFilterGroupId(v); setPage(1); setLoading(true) }}
+                            size="2"
+                        >
+                            <Select.Trigger placeholder={t('group.all')} style={isMobile ? { width: '100%' } : { minWidth: 140 }} />
+                            <Select.Content>
+                                <Select.Item value="">{t('group.all')}</Select.Item>
+                                {groups.map(g => (
+                                    <Select.Item key={g.id} value={String(g.id)}>
+                                        <Flex align="center" gap="2">
+                                            <Box style={{ width: 8, height: 8, borderRadius: '50%', background: g.color || '#9ca3af' }} />
+                                            {g.name}
+                                        </Flex>
+                                    </Select.Item>
+                                ))}
+                            </Select.Content>
+                        </Select.Root>
+                    </Flex>
+                )}
+                {allTags.length > 0 && (
+                    <Flex direction="column" gap="1" style={isMobile ? { width: '100%' } : {}}>
+                        <Text size="1" color="gray">{t('tag.filter')}</Text>
+                        <Flex gap="1" wrap="wrap">
+                            {allTags.map(tag => {
+                                const active = filterTagId === String(tag.id)
+                                return (
+                                    <Badge
+                                        key={tag.id}
+                                        size="1"
+                                        variant={active ? 'solid' : 'outline'}
+                                        color={badgeColor(tag.color)}
+                                        style={{ cursor: 'pointer', userSelect: 'none' }}
+                                        onClick={() => {
+                                            const newVal = active ? '' : String(tag.id)
+                                            setFilterTagId(newVal)
+                                            setPage(1)
+                                            setLoading(true)
+                                        }}
+                                    >
+                                        <Tags size={10} /> {tag.name}
+                                    </Badge>
+                                )
+                            })}
+                        </Flex>
+                    </Flex>
+                )}
+            </Flex>
+
             {loading ? (
                 <Flex justify="center" p="9">
                     <Spinner size="3" />
@@ -1365,8 +2283,11 @@ export default function HostList() {
                             onDelete={setDeleteHost}
                             onToggle={handleToggle}
                             onClone={setCloneHost}
+                            onHistory={setHistoryHost}
+                            onSchedule={setScheduleHost}
                             toggling={toggling}
                             dnsStatus={dnsStatuses[host.domain]}
+                            metadata={metadata[host.id]}
                         />
                     ))}
                 </Box>
@@ -1397,9 +2318,19 @@ export default function HostList() {
                                             ) : host.host_type === 'php' ? (
                                                 <Globe size={14} color="#8b5cf6" />
                                             ) : (
-                                                <Globe size={14} color="#10b981" />
+                                                <ProxyIcon favicon={metadata[host.id]?.favicon} />
+                                            )}
+                                            <Text weight="medium" title={host.display_domain ? host.domain : undefined}>{host.display_domain || host.domain}</Text>
+                                            {metadata[host.id]?.title && (
+                                                <Text size="1" color="gray">{metadata[host.id].title}</Text>
+                                            )}
+                                            {host.aliases && (
+                                                <Tooltip content={host.aliases.split(',').join(', ')}>
+                                                    <Badge size="1" variant="soft" color="gray">
+                                                        {t('host.alias_count', { count: host.aliases.split(',').length })}
+                                                    </Badge>
+                                                </Tooltip>
                                             )}
-                                            <Text weight="medium">{host.domain}</Text>
                                             <Tooltip content={t('host.visit_site')}>
                                                 <IconButton
                                                     size="1"
@@ -1426,13 +2357,25 @@ export default function HostList() {
                                                     <Lock size={12} color="#8b5cf6" />
                                                 </Tooltip>
                                             )}
+                                            {host.config_error && (
+                                                <Tooltip content={host.config_error}>
+                                                    <Badge color="red" variant="soft" size="1">
+                                                        {t('host.config_error')}
+                                                    </Badge>
+                                                </Tooltip>
+                                            )}
+                                            {host.maintenance_mode && (
+                                                <Badge color="amber" variant="soft" size="1">
+                                                    {t('host.maintenance')}
+                                                </Badge>
+                                            )}
                                             {host.group && (
-                                                <Badge color={host.group.color || 'gray'} variant="soft" size="1">
+                                                <Badge color={badgeColor(host.group.color)} variant="soft" size="1">
                                                     <FolderOpen size={10} /> {host.group.name}
                                                 </Badge>
                                             )}
                                             {host.tags?.map(tag => (
-                                                <Badge key={tag.id} color={tag.color || 'gray'} variant="outline" size="1">
+                                                <Badge key={tag.id} color={badgeColor(tag.color)} variant="outline" size="1">
                                                     {tag.name}
                                                 </Badge>
                                             ))}
@@ -1467,6 +2410,45 @@ export default function HostList() {
                                     </Table.Cell>
                                     <Table.Cell>
                                         <Flex gap="2">
+                                            <Tooltip content={host.maintenance_mode ? t('host.maintenance_off') : t('host.maintenance_on')}>
+                                                <IconButton
+                                                    variant="ghost"
+                                                    color={host.maintenance_mode ? 'amber' : 'gray'}
+                                                    size="1"
+                                                    onClick={() => handleMaintenance(host)}
+                                                    disabled={toggling === host.id}
+                                                >
+                                                    <Construction size={14} />
+                                                </IconButton>
+                                            </Tooltip>
+                                            <Tooltip content={copiedCurl === host.id ? t('host.curl_copied') : t('host.copy_curl')}>
+                                                <IconButton
+                                                    variant="ghost"
+                                                    size="1"
+                                                    color={copiedCurl === host.id ? 'green' : 'gray'}
+                                                    onClick={() => handleCopyCurl(host)}
+                                                >
+                                                    <Terminal size={14} />
+                                                </IconButton>
+                                            </Tooltip>
+                                            <Tooltip content={t('revision.tooltip')}>
+                                                <IconButton
+                                                    variant="ghost"
+                                                    size="1"
+                                                    onClick={() => setHistoryHost(host)}
+                                                >
+                                                    <History size={14} />
+                                                </IconButton>
+                                            </Tooltip>
+                                            <Tooltip content={t('schedule.tooltip')}>
+                                                <IconButton
+                                                    variant="ghost"
+                                                    size="1"
+                                                    onClick={() => setScheduleHost(host)}
+                                                >
+                                                    <Clock size={14} />
+                                                </IconButton>
+                                            </Tooltip>
                                             <Tooltip content={t('clone.tooltip')}>
                                                 <IconButton
                                                     variant="ghost"
@@ -1504,12 +2486,25 @@ export default function HostList() {
                 </Card>
             )}
 
+            {/* Pagination */}
+            {totalPages > 1 && (
+                <Flex justify="center" align="center" gap="2" mt="4">
+                    <Button size="1" variant="soft" disabled={page <= 1} onClick={() => { setPage(p => p - 1); setLoading(true) }}>
+                        {t('common.prev_page')}
+                    </Button>
+                    <Text size="2">{page} / {totalPages}</Text>
+                    <Button size="1" variant="soft" disabled={page >= totalPages} onClick={() => { setPage(p => p + 1); setLoading(true) }}>
+                        {t('common.next_page')}
+                    </Button>
+                </Flex>
+            )}
+
             {/* Form Dialog */}
             <HostFormDialog
                 open={showForm}
                 onClose={() => setShowForm(false)}
                 host={editHost}
-                onSaved={fetchHosts}
+                onSaved={handleSaved}
             />
 
             {/* Delete Confirmation */}
@@ -1526,6 +2521,33 @@ export default function HostList() {
                 onClose={() => setCloneHost(null)}
                 host={cloneHost}
                 onCloned={() => { setDnsStatuses({}); fetchHosts() }}
+                groups={groups}
+                allTags={allTags}
+                t={t}
+            />
+
+            {/* Revision History */}
+            <HistoryDialog
+                open={!!historyHost}
+                onClose={() => setHistoryHost(null)}
+                host={historyHost}
+                onRestored={fetchHosts}
+                t={t}
+            />
+
+            {/* Schedule Dialog */}
+            <ScheduleDialog
+                open={!!scheduleHost}
+                onClose={() => setScheduleHost(null)}
+                host={scheduleHost}
+                t={t}
+            />
+
+            {/* Trash */}
+            <TrashDialog
+                open={showTrash}
+                onClose={() => setShowTrash(false)}
+                onRestored={fetchHosts}
                 t={t}
             />
         </Box>
@@ -24,6 +24,7 @@ import { useAIChatStore } from '../stores/aiChat.js'
 import { dashboardAPI } from '../api/index.js'
 import { useTranslation } from 'react-i18next'
 import logoImg from '../assets/logo.png'
+import GlobalSearch from '../components/GlobalSearch.jsx'
 
 // Fixed navigation items (always shown)
 const fixedNavItems = [
@@ -147,6 +148,10 @@ export default function Layout() {
                 )}
             </Flex>
 
+            <Box px="3" pb="2">
+                <GlobalSearch onNavigate={() => { if (isMobile) setSidebarOpen(false) }} />
+            </Box>
+
             <Separator size="4" style={{ background: 'var(--cp-border)' }} />
 
             {/* Nav items */}
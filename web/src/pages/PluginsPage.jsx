@@ -118,7 +118,8 @@ export default function PluginsPage() {
             if (currentEnabled) {
                 await pluginAPI.disable(id)
             } else {
-                await pluginAPI.enable(id)
+                const res = await pluginAPI.enable(id)
+                if (res.data?.restart_required) alert(t('plugins.restart_required'))
             }
             await fetchPlugins()
             refreshNav()
@@ -196,6 +197,11 @@ export default function PluginsPage() {
                                                 {t('common.enabled')}
                                             </Badge>
                                         )}
+                                        {p.health?.status === 'unhealthy' && (
+                                            <Badge color="red" variant="soft" size="1" title={p.health.error}>
+                                                {t('plugins.unhealthy')}
+                                            </Badge>
+                                        )}
                                     </Flex>
                                     <Text size="2" color="gray">{t(`plugins.descriptions.${p.id}`, { defaultValue: p.description })}</Text>
                                     {p.dependencies?.length > 0 && (
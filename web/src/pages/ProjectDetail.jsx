@@ -1272,6 +1272,17 @@ export default function ProjectDetail() {
                                 }} />
                                 <Text size="2">{t('deploy.auto_deploy')}</Text>
                             </Flex>
+                            <Flex align="center" gap="2">
+                                <Switch checked={!!project.cache_enabled} onCheckedChange={async (v) => {
+                                    try {
+                                        await deployAPI.updateProject(id, { cache_enabled: v })
+                                        fetchProject()
+                                    } catch {
+                                        fetchProject()
+                                    }
+                                }} />
+                                <Text size="2">{t('deploy.dependency_cache')}</Text>
+                            </Flex>
 
                             <Separator size="4" my="2" />
 
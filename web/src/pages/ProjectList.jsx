@@ -129,9 +129,14 @@ export default function ProjectList() {
                                     </Flex>
                                 </Table.Cell>
                                 <Table.Cell>
-                                    <Badge color={statusColors[project.status] || 'gray'}>
-                                        {t(`deploy.status_${project.status}`)}
-                                    </Badge>
+                                    <Flex gap="1" align="center">
+                                        <Badge color={statusColors[project.status] || 'gray'}>
+                                            {t(`deploy.status_${project.status}`)}
+                                        </Badge>
+                                        {project.queued && (
+                                            <Badge variant="soft" color="orange">{t('deploy.status_queued')}</Badge>
+                                        )}
+                                    </Flex>
                                 </Table.Cell>
                                 <Table.Cell>
                                     <Text size="2" color="gray">#{project.current_build || '—'}</Text>
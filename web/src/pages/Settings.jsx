@@ -10,7 +10,7 @@ import {
     Plus, Pencil, Trash2, Power, PowerOff, X, Shield, Eye,
     ChevronLeft, ChevronRight, ClipboardList, FileText, Search,
     Bot, Save, TestTube, Check, Package, Star,
-    Activity, HardDrive,
+    Activity, HardDrive, ArrowUp, ArrowDown,
 } from 'lucide-react'
 import { QRCodeSVG } from 'qrcode.react'
 import {
@@ -18,6 +18,7 @@ import {
     userAPI, logAPI, auditAPI, aiAPI, dnsProviderAPI, certificateAPI,
     deployAPI, backupAPI, notifyAPI,
 } from '../api/index.js'
+import { DEFAULT_PALETTE, badgeColor, paletteHex } from '../utils/colors.js'
 import { useAuthStore } from '../stores/auth.js'
 import { useTranslation } from 'react-i18next'
 import { useSearchParams } from 'react-router'
@@ -119,7 +120,11 @@ function GeneralTab({ showMessage }) {
     const [caddyfile, setCaddyfile] = useState('')
     const [actionLoading, setActionLoading] = useState(null)
     const fileInputRef = useRef(null)
+    const fullFileInputRef = useRef(null)
+    const [exportSecrets, setExportSecrets] = useState('excluded')
+    const [passphrase, setPassphrase] = useState('')
     const [autoReload, setAutoReload] = useState(true)
+    const [rejectOverlap, setRejectOverlap] = useState(false)
     const [serverIpv4, setServerIpv4] = useState('')
     const [serverIpv6, setServerIpv6] = useState('')
     const [wildcardDomain, setWildcardDomain] = useState('')
@@ -139,13 +144,15 @@ function GeneralTab({ showMessage }) {
     // Groups & Tags state
     const [groups, setGroups] = useState([])
     const [allTags, setAllTags] = useState([])
-    const [groupForm, setGroupForm] = useState({ name: '', color: 'gray' })
-    const [tagForm, setTagForm] = useState({ name: '', color: 'gray' })
+    const [groupForm, setGroupForm] = useState({ name: '', color: '' })
+    const [tagForm, setTagForm] = useState({ name: '', color: '' })
     const [editingGroup, setEditingGroup] = useState(null)
     const [editingTag, setEditingTag] = useState(null)
     const [groupTagLoading, setGroupTagLoading] = useState(false)
+    const [tagRules, setTagRules] = useState([])
+    const [ruleForm, setRuleForm] = useState({ tag_id: '', match_field: 'domain', pattern: '' })
 
-    const PRESET_COLORS = ['red', 'orange', 'yellow', 'green', 'blue', 'purple', 'pink', 'gray']
+    const [palette, setPalette] = useState(DEFAULT_PALETTE)
 
     useEffect(() => {
         const mql = window.matchMedia('(max-width: 767px)')
@@ -177,6 +184,7 @@ function GeneralTab({ showMessage }) {
             const res = await settingAPI.getAll()
             const settings = res.data.settings || {}
             setAutoReload(settings.auto_reload !== 'false')
+            setRejectOverlap(settings.reject_domain_overlap === 'true')
             setServerIpv4(settings.server_ipv4 || '')
             setServerIpv6(settings.server_ipv6 || '')
             setWildcardDomain(settings.wildcard_domain || '')
@@ -261,6 +269,17 @@ function GeneralTab({ showMessage }) {
         }
     }
 
+    const handleToggleRejectOverlap = async (value) => {
+        setRejectOverlap(value)
+        try {
+            await settingAPI.update('reject_domain_overlap', value ? 'true' : 'false')
+            showMessage('success', t('common.save_success'))
+        } catch {
+            setRejectOverlap(!value)
+            showMessage('error', t('settings.save_failed'))
+        }
+    }
+
     const handleSaveIPs = async () => {
         try {
             await Promise.all([
@@ -322,13 +341,19 @@ function GeneralTab({ showMessage }) {
     // Groups & Tags handlers
     const fetchGroupsAndTags = async () => {
         try {
-            const [gRes, tRes] = await Promise.all([groupAPI.list(), tagAPI.list()])
+            const [gRes, tRes, rRes] = await Promise.all([groupAPI.list(), tagAPI.list(), tagAPI.listRules()])
             setGroups(gRes.data.groups || [])
             setAllTags(tRes.data.tags || [])
+            setTagRules(rRes.data.rules || [])
         } catch { /* ignore */ }
     }
 
-    useEffect(() => { fetchGroupsAndTags() }, [])
+    useEffect(() => {
+        fetchGroupsAndTags()
+        groupAPI.palette().then(res => {
+            if (res.data.palette?.length) setPalette(res.data.palette)
+        }).catch(() => { /* keep the built-in palette */ })
+    }, [])
 
     const handleSaveGroup = async () => {
         setGroupTagLoading(true)
@@ -338,7 +363,7 @@ function GeneralTab({ showMessage }) {
             } else {
                 await groupAPI.create(groupForm)
             }
-            setGroupForm({ name: '', color: 'gray' })
+            setGroupForm({ name: '', color: '' })
             setEditingGroup(null)
             showMessage('success', editingGroup ? t('common.update_success') : t('common.create_success'))
             await fetchGroupsAndTags()
@@ -358,6 +383,20 @@ function GeneralTab({ showMessage }) {
         } finally { setGroupTagLoading(false) }
     }
 
+    const handleMoveGroup = async (index, delta) => {
+        const ids = groups.map(g => g.id)
+        const target = index + delta
+        if (target < 0 || target >= ids.length) return
+        ;[ids[index], ids[target]] = [ids[target], ids[index]]
+        setGroupTagLoading(true)
+        try {
+            const res = await groupAPI.reorder(ids)
+            setGroups(res.data.groups || [])
+        } catch (err) {
+            showMessage('error', err.response?.data?.error || t('common.operation_failed'))
+        } finally { setGroupTagLoading(false) }
+    }
+
     const handleBatchEnable = async (group) => {
         try {
             await groupAPI.batchEnable(group.id)
@@ -384,7 +423,7 @@ function GeneralTab({ showMessage }) {
             } else {
                 await tagAPI.create(tagForm)
             }
-            setTagForm({ name: '', color: 'gray' })
+            setTagForm({ name: '', color: '' })
             setEditingTag(null)
             showMessage('success', editingTag ? t('common.update_success') : t('common.create_success'))
             await fetchGroupsAndTags()
@@ -404,6 +443,38 @@ function GeneralTab({ showMessage }) {
         } finally { setGroupTagLoading(false) }
     }
 
+    const handleCreateRule = async () => {
+        setGroupTagLoading(true)
+        try {
+            await tagAPI.createRule({ ...ruleForm, tag_id: Number(ruleForm.tag_id) })
+            setRuleForm({ tag_id: '', match_field: 'domain', pattern: '' })
+            showMessage('success', t('common.create_success'))
+            await fetchGroupsAndTags()
+        } catch (err) {
+            showMessage('error', err.response?.data?.error || t('common.operation_failed'))
+        } finally { setGroupTagLoading(false) }
+    }
+
+    const handleDeleteRule = async (rule) => {
+        setGroupTagLoading(true)
+        try {
+            await tagAPI.deleteRule(rule.id)
+            await fetchGroupsAndTags()
+        } catch (err) {
+            showMessage('error', err.response?.data?.error || t('common.delete_failed'))
+        } finally { setGroupTagLoading(false) }
+    }
+
+    const handleApplyRules = async () => {
+        setGroupTagLoading(true)
+        try {
+            const res = await tagAPI.applyRules()
+            showMessage('success', t('tag.rules_applied', { count: res.data.added || 0 }))
+        } catch (err) {
+            showMessage('error', err.response?.data?.error || t('common.operation_failed'))
+        } finally { setGroupTagLoading(false) }
+    }
+
     const handleExport = async () => {
         setActionLoading('export')
         try {
@@ -440,6 +511,43 @@ function GeneralTab({ showMessage }) {
         }
     }
 
+    const handleExportFull = async () => {
+        setActionLoading('export_full')
+        try {
+            const res = await configAPI.exportFull(exportSecrets, passphrase)
+            const blob = new Blob([JSON.stringify(res.data, null, 2)], { type: 'application/json' })
+            const url = URL.createObjectURL(blob)
+            const link = document.createElement('a')
+            link.href = url
+            link.download = `webcasa-config-${new Date().toISOString().slice(0, 10)}.json`
+            link.click()
+            URL.revokeObjectURL(url)
+            showMessage('success', t('settings.export_success'))
+        } catch (err) {
+            const key = err.response?.data?.error_key
+            showMessage('error', key ? t(key) : t('settings.export_failed'))
+        } finally { setActionLoading(null) }
+    }
+
+    const handleImportFull = async (e) => {
+        const file = e.target.files?.[0]
+        if (!file) return
+        setActionLoading('import_full')
+        try {
+            const config = JSON.parse(await file.text())
+            const res = await configAPI.importFull(config, passphrase)
+            showMessage('success', t('settings.full_import_success', { hosts: res.data.hosts }))
+            await fetchStatus()
+            await fetchCaddyfile()
+        } catch (err) {
+            const key = err.response?.data?.error_key
+            showMessage('error', key ? t(key) : t('settings.import_invalid'))
+        } finally {
+            setActionLoading(null)
+            e.target.value = ''
+        }
+    }
+
     const running = caddyStatus?.running
 
     return (
@@ -496,6 +604,13 @@ function GeneralTab({ showMessage }) {
                         <Callout.Icon><AlertCircle size={14} /></Callout.Icon>
                         <Callout.Text>{t('settings.auto_reload_callout')}</Callout.Text>
                     </Callout.Root>
+                    <Flex justify="between" align="center" mt="4">
+                        <Flex direction="column" style={{ flex: 1 }}>
+                            <Text size="2" weight="medium">{t('settings.reject_domain_overlap')}</Text>
+                            <Text size="1" color="gray">{t('settings.reject_domain_overlap_hint')}</Text>
+                        </Flex>
+                        <Switch checked={rejectOverlap} onCheckedChange={handleToggleRejectOverlap} />
+                    </Flex>
                 </Card>
 
                 <Card mt="4" style={{ background: 'var(--cp-card)', border: '1px solid var(--cp-border)' }}>
@@ -595,6 +710,47 @@ function GeneralTab({ showMessage }) {
                         <Callout.Text>{t('settings.import_warning')}</Callout.Text>
                     </Callout.Root>
                 </Card>
+
+                <Card mt="4" style={{ background: 'var(--cp-card)', border: '1px solid var(--cp-border)' }}>
+                    <Heading size="3" mb="4">{t('settings.full_config')}</Heading>
+                    <Text size="2" color="gray" mb="4" as="p">{t('settings.full_config_hint')}</Text>
+                    <Flex gap="3" mb="4" wrap="wrap" align="end" direction={isMobile ? 'column' : 'row'}>
+                        <Flex direction="column" gap="1">
+                            <Text size="1" color="gray">{t('settings.export_secrets')}</Text>
+                            <Select.Root value={exportSecrets} onValueChange={setExportSecrets}>
+                                <Select.Trigger />
+                                <Select.Content>
+                                    <Select.Item value="excluded">{t('settings.secrets_excluded')}</Select.Item>
+                                    <Select.Item value="encrypted">{t('settings.secrets_encrypted')}</Select.Item>
+                                    <Select.Item value="included">{t('settings.secrets_included')}</Select.Item>
+                                </Select.Content>
+                            </Select.Root>
+                        </Flex>
+                        <Flex direction="column" gap="1" style={isMobile ? { width: '100%' } : { minWidth: 240 }}>
+                            <Text size="1" color="gray">{t('settings.export_passphrase')}</Text>
+                            <TextField.Root type="password" value={passphrase} onChange={(e) => setPassphrase(e.target.value)} placeholder={t('settings.export_passphrase_hint')} />
+                        </Flex>
+                    </Flex>
+                    <Flex gap="3" wrap="wrap" direction={isMobile ? 'column' : 'row'}>
+                        <Button onClick={handleExportFull} disabled={actionLoading === 'export_full'} style={isMobile ? { width: '100%' } : {}}>
+                            <Download size={14} /> {actionLoading === 'export_full' ? t('settings.exporting') : t('settings.export_full')}
+                        </Button>
+                        <Button variant="soft" color="gray" onClick={() => fullFileInputRef.current?.click()} disabled={actionLoading === 'import_full'} style={isMobile ? { width: '100%' } : {}}>
+                            <Upload size={14} /> {actionLoading === 'import_full' ? t('settings.importing') : t('settings.import_full')}
+                        </Button>
+                        <input ref={fullFileInputRef} type="file" accept=".json" onChange={handleImportFull} style={{ display: 'none' }} />
+                    </Flex>
+                    {exportSecrets === 'included' && (
+                        <Callout.Root color="red" size="1" mt="4">
+                            <Callout.Icon><AlertCircle size={14} /></Callout.Icon>
+                            <Callout.Text>{t('settings.secrets_included_warning')}</Callout.Text>
+                        </Callout.Root>
+                    )}
+                    <Callout.Root color="orange" size="1" mt="4">
+                        <Callout.Icon><AlertCircle size={14} /></Callout.Icon>
+                        <Callout.Text>{t('settings.full_import_warning')}</Callout.Text>
+                    </Callout.Root>
+                </Card>
             </Tabs.Content>
 
             {/* Security (2FA) */}
@@ -690,9 +846,9 @@ function GeneralTab({ showMessage }) {
                         <Flex direction="column" gap="1">
                             <Text size="1" color="gray">{t('group.color')}</Text>
                             <Flex gap="1">
-                                {PRESET_COLORS.map(c => (
-                                    <Box key={c} onClick={() => setGroupForm({ ...groupForm, color: c })} style={{ width: 24, height: 24, borderRadius: '50%', cursor: 'pointer', outline: groupForm.color === c ? '2px solid var(--cp-text)' : 'none', outlineOffset: 2 }}>
-                                        <Badge color={c} variant="solid" size="1" style={{ width: 24, height: 24, borderRadius: '50%', display: 'block' }}>&nbsp;</Badge>
+                                {palette.map(p => (
+                                    <Box key={p.color} title={p.name} onClick={() => setGroupForm({ ...groupForm, color: p.color })} style={{ width: 24, height: 24, borderRadius: '50%', cursor: 'pointer', outline: groupForm.color === p.color ? '2px solid var(--cp-text)' : 'none', outlineOffset: 2 }}>
+                                        <Box style={{ width: 24, height: 24, borderRadius: '50%', background: p.color }} />
                                     </Box>
                                 ))}
                             </Flex>
@@ -702,7 +858,7 @@ function GeneralTab({ showMessage }) {
                                 {editingGroup ? t('common.save') : <><Plus size={14} /> {t('group.create')}</>}
                             </Button>
                             {editingGroup && (
-                                <Button size="2" variant="soft" color="gray" onClick={() => { setEditingGroup(null); setGroupForm({ name: '', color: 'gray' }) }}>
+                                <Button size="2" variant="soft" color="gray" onClick={() => { setEditingGroup(null); setGroupForm({ name: '', color: '' }) }}>
                                     {t('common.cancel')}
                                 </Button>
                             )}
@@ -713,20 +869,27 @@ function GeneralTab({ showMessage }) {
                         <Text size="2" color="gray">{t('group.no_groups')}</Text>
                     ) : (
                         <Flex direction="column" gap="2">
-                            {groups.map(group => (
+                            {groups.map((group, index) => (
                                 <Card key={group.id} style={{ background: 'var(--cp-input-bg)', border: '1px solid var(--cp-border-subtle)' }}>
                                     <Flex justify="between" align="center" wrap="wrap" gap="2">
-                                        <Badge color={group.color || 'gray'} variant="solid" size="2">
+                                        <Badge color={badgeColor(group.color)} variant="solid" size="2">
                                             <FolderOpen size={12} /> {group.name}
                                         </Badge>
-                                        <Flex gap="2" wrap="wrap">
+                                        <Text size="1" color="gray">{t('group.host_count', { count: group.host_count || 0 })}</Text>
+                                        <Flex gap="2" wrap="wrap" align="center">
+                                            <IconButton size="1" variant="ghost" color="gray" disabled={groupTagLoading || index === 0} onClick={() => handleMoveGroup(index, -1)} title={t('group.move_up')}>
+                                                <ArrowUp size={14} />
+                                            </IconButton>
+                                            <IconButton size="1" variant="ghost" color="gray" disabled={groupTagLoading || index === groups.length - 1} onClick={() => handleMoveGroup(index, 1)} title={t('group.move_down')}>
+                                                <ArrowDown size={14} />
+                                            </IconButton>
                                             <Button size="1" variant="soft" color="green" onClick={() => handleBatchEnable(group)}>
                                                 <Power size={12} /> {t('group.batch_enable')}
                                             </Button>
                                             <Button size="1" variant="soft" color="orange" onClick={() => handleBatchDisable(group)}>
                                                 <PowerOff size={12} /> {t('group.batch_disable')}
                                             </Button>
-                                            <IconButton size="1" variant="ghost" onClick={() => { setEditingGroup(group); setGroupForm({ name: group.name, color: group.color || 'gray' }) }}>
+                                            <IconButton size="1" variant="ghost" onClick={() => { setEditingGroup(group); setGroupForm({ name: group.name, color: paletteHex(group.color, palette) }) }}>
                                                 <Pencil size={14} />
                                             </IconButton>
                                             <IconButton size="1" variant="ghost" color="red" onClick={() => handleDeleteGroup(group)}>
@@ -752,9 +915,9 @@ function GeneralTab({ showMessage }) {
                         <Flex direction="column" gap="1">
                             <Text size="1" color="gray">{t('tag.color')}</Text>
                             <Flex gap="1">
-                                {PRESET_COLORS.map(c => (
-                                    <Box key={c} onClick={() => setTagForm({ ...tagForm, color: c })} style={{ width: 24, height: 24, borderRadius: '50%', cursor: 'pointer', outline: tagForm.color === c ? '2px solid var(--cp-text)' : 'none', outlineOffset: 2 }}>
-                                        <Badge color={c} variant="solid" size="1" style={{ width: 24, height: 24, borderRadius: '50%', display: 'block' }}>&nbsp;</Badge>
+                                {palette.map(p => (
+                                    <Box key={p.color} title={p.name} onClick={() => setTagForm({ ...tagForm, color: p.color })} style={{ width: 24, height: 24, borderRadius: '50%', cursor: 'pointer', outline: tagForm.color === p.color ? '2px solid var(--cp-text)' : 'none', outlineOffset: 2 }}>
+                                        <Box style={{ width: 24, height: 24, borderRadius: '50%', background: p.color }} />
                                     </Box>
                                 ))}
                             </Flex>
@@ -764,7 +927,7 @@ function GeneralTab({ showMessage }) {
                                 {editingTag ? t('common.save') : <><Plus size={14} /> {t('tag.create')}</>}
                             </Button>
                             {editingTag && (
-                                <Button size="2" variant="soft" color="gray" onClick={() => { setEditingTag(null); setTagForm({ name: '', color: 'gray' }) }}>
+                                <Button size="2" variant="soft" color="gray" onClick={() => { setEditingTag(null); setTagForm({ name: '', color: '' }) }}>
                                     {t('common.cancel')}
                                 </Button>
                             )}
@@ -778,8 +941,8 @@ function GeneralTab({ showMessage }) {
                             {allTags.map(tag => (
                                 <Card key={tag.id} style={{ background: 'var(--cp-input-bg)', border: '1px solid var(--cp-border-subtle)', padding: '8px 12px' }}>
                                     <Flex align="center" gap="2">
-                                        <Badge color={tag.color || 'gray'} variant="solid" size="2">{tag.name}</Badge>
-                                        <IconButton size="1" variant="ghost" onClick={() => { setEditingTag(tag); setTagForm({ name: tag.name, color: tag.color || 'gray' }) }}>
+                                        <Badge color={badgeColor(tag.color)} variant="solid" size="2">{tag.name}</Badge>
+                                        <IconButton size="1" variant="ghost" onClick={() => { setEditingTag(tag); setTagForm({ name: tag.name, color: paletteHex(tag.color, palette) }) }}>
                                             <Pencil size={12} />
                                         </IconButton>
                                         <IconButton size="1" variant="ghost" color="red" onClick={() => handleDeleteTag(tag)}>
@@ -791,6 +954,67 @@ function GeneralTab({ showMessage }) {
                         </Flex>
                     )}
                 </Card>
+
+                <Card mt="4" style={{ background: 'var(--cp-card)', border: '1px solid var(--cp-border)' }}>
+                    <Flex justify="between" align="center" mb="2">
+                        <Heading size="3">{t('tag.rules_title')}</Heading>
+                        <Button size="1" variant="soft" onClick={handleApplyRules} disabled={groupTagLoading || tagRules.length === 0}>
+                            <RefreshCw size={12} /> {t('tag.rules_apply')}
+                        </Button>
+                    </Flex>
+                    <Text size="1" color="gray" mb="3" as="p">{t('tag.rules_hint')}</Text>
+                    <Flex gap="2" mb="4" align="end" wrap="wrap" direction={isMobile ? 'column' : 'row'}>
+                        <Flex direction="column" gap="1">
+                            <Text size="1" color="gray">{t('tag.label')}</Text>
+                            <Select.Root value={ruleForm.tag_id} onValueChange={(v) => setRuleForm({ ...ruleForm, tag_id: v })}>
+                                <Select.Trigger placeholder={t('tag.rule_tag_placeholder')} />
+                                <Select.Content>
+                                    {allTags.map(tag => (
+                                        <Select.Item key={tag.id} value={String(tag.id)}>{tag.name}</Select.Item>
+                                    ))}
+                                </Select.Content>
+                            </Select.Root>
+                        </Flex>
+                        <Flex direction="column" gap="1">
+                            <Text size="1" color="gray">{t('tag.rule_field')}</Text>
+                            <Select.Root value={ruleForm.match_field} onValueChange={(v) => setRuleForm({ ...ruleForm, match_field: v })}>
+                                <Select.Trigger />
+                                <Select.Content>
+                                    <Select.Item value="domain">{t('tag.rule_field_domain')}</Select.Item>
+                                    <Select.Item value="host_type">{t('tag.rule_field_host_type')}</Select.Item>
+                                </Select.Content>
+                            </Select.Root>
+                        </Flex>
+                        <Flex direction="column" gap="1" style={{ flex: 1, minWidth: 160 }}>
+                            <Text size="1" color="gray">{t('tag.rule_pattern')}</Text>
+                            <TextField.Root placeholder={ruleForm.match_field === 'domain' ? '*.example.com' : 'proxy'} value={ruleForm.pattern} onChange={(e) => setRuleForm({ ...ruleForm, pattern: e.target.value })} size="2" />
+                        </Flex>
+                        <Button size="2" onClick={handleCreateRule} disabled={groupTagLoading || !ruleForm.tag_id || !ruleForm.pattern.trim()}>
+                            <Plus size={14} /> {t('tag.rule_add')}
+                        </Button>
+                    </Flex>
+
+                    {tagRules.length === 0 ? (
+                        <Text size="2" color="gray">{t('tag.no_rules')}</Text>
+                    ) : (
+                        <Flex direction="column" gap="2">
+                            {tagRules.map(rule => {
+                                const tag = allTags.find(tg => tg.id === rule.tag_id)
+                                return (
+                                    <Flex key={rule.id} align="center" gap="2">
+                                        <Badge color="gray" variant="soft">{rule.match_field === 'domain' ? t('tag.rule_field_domain') : t('tag.rule_field_host_type')}</Badge>
+                                        <Code size="2">{rule.pattern}</Code>
+                                        <Text size="1" color="gray">→</Text>
+                                        <Badge color={badgeColor(tag?.color)} variant="solid">{tag?.name || rule.tag_id}</Badge>
+                                        <IconButton size="1" variant="ghost" color="red" onClick={() => handleDeleteRule(rule)} disabled={groupTagLoading}>
+                                            <Trash2 size={12} />
+                                        </IconButton>
+                                    </Flex>
+                                )
+                            })}
+                        </Flex>
+                    )}
+                </Card>
             </Tabs.Content>
         </Tabs.Root>
     )
@@ -1054,7 +1278,7 @@ function CaddyLogsPanel() {
 
 function AuditLogsPanel() {
     const { t } = useTranslation()
-    const actionColors = { CREATE: 'green', UPDATE: 'blue', DELETE: 'red', ENABLE: 'green', DISABLE: 'orange', TOGGLE: 'orange', START: 'green', STOP: 'red', RELOAD: 'blue' }
+    const actionColors = { CREATE: 'green', UPDATE: 'blue', DELETE: 'red', ENABLE: 'green', DISABLE: 'orange', TOGGLE: 'orange', START: 'green', STOP: 'red', RELOAD: 'blue', DOWN: 'red', RESTART: 'green', RESTART_FAILED: 'red' }
     const [logs, setLogs] = useState([])
     const [total, setTotal] = useState(0)
     const [page, setPage] = useState(1)
@@ -1586,6 +1810,7 @@ function DnsTab() {
     const [saving, setSaving] = useState(false)
     const [error, setError] = useState('')
     const [deleteId, setDeleteId] = useState(null)
+    const [preview, setPreview] = useState(null)
 
     const load = useCallback(async () => {
         setLoading(true)
@@ -1614,10 +1839,23 @@ function DnsTab() {
             const payload = { name: form.name, provider: form.provider, config: JSON.stringify(form.config), is_default: form.is_default }
             if (editId) await dnsProviderAPI.update(editId, payload); else await dnsProviderAPI.create(payload)
             setDialogOpen(false); load()
-        } catch (e) { setError(e.response?.data?.error || t('common.save_failed')) }
+        } catch (e) {
+            const key = e.response?.data?.error_key
+            setError(key ? t(key) : e.response?.data?.error || t('common.save_failed'))
+        }
         setSaving(false)
     }
 
+    const openPreview = async (p) => {
+        try {
+            const res = await dnsProviderAPI.renderPreview(p.id)
+            setPreview({ name: p.name, block: res.data.block })
+        } catch (e) {
+            const key = e.response?.data?.error_key
+            setPreview({ name: p.name, error: key ? t(key) : e.response?.data?.error || t('common.operation_failed') })
+        }
+    }
+
     const handleDelete = async () => {
         try { await dnsProviderAPI.delete(deleteId); setDeleteId(null); load() }
         catch (e) { alert(e.response?.data?.error || t('common.delete_failed')); setDeleteId(null) }
@@ -1660,6 +1898,7 @@ function DnsTab() {
                                     <Table.Cell>{p.is_default && <Tooltip content={t('dns.default_provider_tooltip')}><Star size={14} color="#f59e0b" fill="#f59e0b" /></Tooltip>}</Table.Cell>
                                     <Table.Cell>
                                         <Flex gap="2">
+                                            <Tooltip content={t('dns.render_preview')}><IconButton variant="ghost" size="1" onClick={() => openPreview(p)}><Eye size={14} /></IconButton></Tooltip>
                                             <Tooltip content={t('common.edit')}><IconButton variant="ghost" size="1" onClick={() => openEdit(p)}><Pencil size={14} /></IconButton></Tooltip>
                                             <Tooltip content={t('common.delete')}><IconButton variant="ghost" size="1" color="red" onClick={() => setDeleteId(p.id)}><Trash2 size={14} /></IconButton></Tooltip>
                                         </Flex>
@@ -1722,6 +1961,23 @@ function DnsTab() {
                     </Flex>
                 </Dialog.Content>
             </Dialog.Root>
+
+            <Dialog.Root open={!!preview} onOpenChange={(o) => !o && setPreview(null)}>
+                <Dialog.Content maxWidth="560px" style={{ background: 'var(--cp-card)' }}>
+                    <Dialog.Title>{t('dns.render_preview')}: {preview?.name}</Dialog.Title>
+                    <Dialog.Description size="2" color="gray">{t('dns.render_preview_hint')}</Dialog.Description>
+                    {preview?.error ? (
+                        <Callout.Root color="red" size="1" mt="3"><Callout.Icon><AlertCircle size={14} /></Callout.Icon><Callout.Text>{preview.error}</Callout.Text></Callout.Root>
+                    ) : (
+                        <Box mt="3" style={{ background: 'var(--cp-code-bg)', border: '1px solid var(--cp-border)', borderRadius: 8, padding: 16, overflow: 'auto' }}>
+                            <pre className="log-viewer" style={{ margin: 0, color: 'var(--cp-text)' }}>{preview?.block}</pre>
+                        </Box>
+                    )}
+                    <Flex gap="3" mt="4" justify="end">
+                        <Dialog.Close><Button variant="soft" color="gray">{t('common.close')}</Button></Dialog.Close>
+                    </Flex>
+                </Dialog.Content>
+            </Dialog.Root>
         </Box>
     )
 }
@@ -1761,9 +2017,11 @@ function CertificatesTab() {
             formData.append('name', uploadForm.name.trim())
             formData.append('cert', uploadForm.certFile)
             formData.append('key', uploadForm.keyFile)
-            await certificateAPI.upload(formData)
+            const res = await certificateAPI.upload(formData)
             setUploadForm({ name: '', certFile: null, keyFile: null })
-            setUploadOpen(false); fetchCerts(); showMsg('success', t('common.save_success'))
+            setUploadOpen(false); fetchCerts()
+            if (res.data.cert_chain_incomplete) showMsg('warning', t('cert.chain_incomplete'))
+            else showMsg('success', t('common.save_success'))
         } catch (err) { showMsg('error', err.response?.data?.error || t('common.operation_failed')) }
         setUploading(false)
     }
@@ -1784,7 +2042,7 @@ function CertificatesTab() {
             </Flex>
 
             {message && (
-                <Callout.Root color={message.type === 'success' ? 'green' : 'red'} size="1" mb="4">
+                <Callout.Root color={message.type === 'success' ? 'green' : message.type === 'warning' ? 'orange' : 'red'} size="1" mb="4">
                     <Callout.Icon>{message.type === 'success' ? <CheckCircle2 size={14} /> : <AlertCircle size={14} />}</Callout.Icon>
                     <Callout.Text>{message.text}</Callout.Text>
                 </Callout.Root>
@@ -1879,6 +2137,9 @@ const EVENT_OPTIONS = [
     { value: 'monitoring.alert.*', label: 'Monitoring Alerts' },
     { value: 'system.inspection.*', label: 'System Inspection' },
     { value: 'system.selfheal.*', label: 'Self-Heal Actions' },
+    { value: 'system.caddy.*', label: 'Caddy Events' },
+    { value: 'host.*', label: 'Host Events' },
+    { value: 'cert.expiring', label: 'Certificate Expiring' },
     { value: '*', label: 'All Events' },
 ]
 
@@ -1911,6 +2172,7 @@ function NotifyConfigForm({ type, config, onChange, t }) {
         case 'email':
             return (
                 <Flex direction="column" gap="2">
+                    <Text size="1" color="gray">{t('notify.smtp_channel_hint')}</Text>
                     <Flex gap="2">
                         <Box style={{ flex: 1 }}><label><Text size="2" weight="medium">SMTP Host</Text><TextField.Root placeholder="smtp.gmail.com" value={parsed.smtp_host || ''} onChange={e => update('smtp_host', e.target.value)} /></label></Box>
                         <Box style={{ width: 80 }}><label><Text size="2" weight="medium">Port</Text><TextField.Root type="number" value={parsed.smtp_port || 587} onChange={e => update('smtp_port', parseInt(e.target.value) || 587)} /></label></Box>
@@ -1961,6 +2223,76 @@ function NotifyConfigForm({ type, config, onChange, t }) {
     }
 }
 
+// SmtpSettings edits the panel SMTP server, used for email 2FA codes and by
+// email channels that leave SMTP Host empty.
+function SmtpSettings({ showMessage }) {
+    const { t } = useTranslation()
+    const [form, setForm] = useState({
+        smtp_host: '', smtp_port: '587', smtp_username: '', smtp_password: '', smtp_from: '', smtp_use_tls: 'true',
+    })
+    const [testTo, setTestTo] = useState('')
+    const [saving, setSaving] = useState(false)
+    const [testing, setTesting] = useState(false)
+
+    useEffect(() => {
+        settingAPI.getAll().then(res => {
+            const s = res.data?.settings || {}
+            setForm(f => Object.fromEntries(Object.keys(f).map(k => [k, s[k] ?? f[k]])))
+        }).catch(console.error)
+    }, [])
+
+    const set = (key, value) => setForm(f => ({ ...f, [key]: value }))
+
+    const handleSave = async () => {
+        setSaving(true)
+        try {
+            for (const [key, value] of Object.entries(form)) {
+                await settingAPI.update(key, value)
+            }
+            showMessage('success', t('common.saved'))
+        } catch (e) {
+            showMessage('error', e.response?.data?.error || t('common.operation_failed'))
+        } finally { setSaving(false) }
+    }
+
+    const handleTest = async () => {
+        setTesting(true)
+        try {
+            const res = await settingAPI.testSMTP(testTo.trim())
+            showMessage('success', t('notify.smtp_test_sent', { to: res.data?.to }))
+        } catch (e) {
+            const key = e.response?.data?.error_key
+            showMessage('error', key ? t(key) : (e.response?.data?.error || t('notify.test_failed')))
+        } finally { setTesting(false) }
+    }
+
+    return (
+        <Card mt="5">
+            <Heading size="3" mb="1">{t('notify.smtp_title')}</Heading>
+            <Text size="2" color="gray">{t('notify.smtp_subtitle')}</Text>
+            <Flex direction="column" gap="2" mt="3">
+                <Flex gap="2">
+                    <Box style={{ flex: 1 }}><label><Text size="2" weight="medium">SMTP Host</Text><TextField.Root placeholder="smtp.gmail.com" value={form.smtp_host} onChange={e => set('smtp_host', e.target.value)} /></label></Box>
+                    <Box style={{ width: 80 }}><label><Text size="2" weight="medium">Port</Text><TextField.Root type="number" value={form.smtp_port} onChange={e => set('smtp_port', e.target.value)} /></label></Box>
+                </Flex>
+                <Flex gap="2">
+                    <Box style={{ flex: 1 }}><label><Text size="2" weight="medium">{t('common.username')}</Text><TextField.Root value={form.smtp_username} onChange={e => set('smtp_username', e.target.value)} /></label></Box>
+                    <Box style={{ flex: 1 }}><label><Text size="2" weight="medium">{t('common.password')}</Text><TextField.Root type="password" value={form.smtp_password} onChange={e => set('smtp_password', e.target.value)} /></label></Box>
+                </Flex>
+                <Flex gap="2" align="end">
+                    <Box style={{ flex: 1 }}><label><Text size="2" weight="medium">From</Text><TextField.Root placeholder="noreply@example.com" value={form.smtp_from} onChange={e => set('smtp_from', e.target.value)} /></label></Box>
+                    <Flex align="center" gap="2" pb="2"><Switch size="1" checked={form.smtp_use_tls !== 'false'} onCheckedChange={v => set('smtp_use_tls', v ? 'true' : 'false')} /><Text size="2">TLS</Text></Flex>
+                </Flex>
+                <Flex gap="2" justify="end" align="center" mt="2">
+                    <TextField.Root style={{ width: 240 }} placeholder={t('notify.smtp_test_to')} value={testTo} onChange={e => setTestTo(e.target.value)} />
+                    <Button variant="soft" onClick={handleTest} disabled={testing}>{testing ? <Spinner size="1" /> : <TestTube size={14} />} {t('notify.smtp_send_test')}</Button>
+                    <Button onClick={handleSave} disabled={saving}><Save size={14} /> {t('common.save')}</Button>
+                </Flex>
+            </Flex>
+        </Card>
+    )
+}
+
 function NotifyTab({ showMessage }) {
     const { t } = useTranslation()
     const [channels, setChannels] = useState([])
@@ -2081,6 +2413,8 @@ function NotifyTab({ showMessage }) {
                 </Table.Root>
             )}
 
+            <SmtpSettings showMessage={showMessage} />
+
             <Dialog.Root open={dialogOpen} onOpenChange={setDialogOpen}>
                 <Dialog.Content maxWidth="500px">
                     <Dialog.Title>{editChannel ? t('notify.edit_channel') : t('notify.add_channel')}</Dialog.Title>
@@ -0,0 +1,42 @@
+/**
+ * Group and tag colors are stored as hex codes. The default palette (served
+ * by GET /groups/palette) uses the solid shades of the theme's named colors,
+ * so badges can map them back to a themed color.
+ */
+export const DEFAULT_PALETTE = [
+    { name: 'red', color: '#e5484d' },
+    { name: 'orange', color: '#f76b15' },
+    { name: 'yellow', color: '#ffe629' },
+    { name: 'green', color: '#30a46c' },
+    { name: 'blue', color: '#0090ff' },
+    { name: 'purple', color: '#8e4ec6' },
+    { name: 'pink', color: '#d6409f' },
+    { name: 'gray', color: '#8d8d8d' },
+]
+
+/**
+ * Returns the theme color name to use for a Badge showing a group or tag
+ * color. Colors saved before validation was added may be plain names, which
+ * pass through; hex codes outside the palette fall back to gray.
+ *
+ * @param {string} color - The stored color.
+ */
+export function badgeColor(color) {
+    if (!color) return 'gray'
+    const value = color.toLowerCase()
+    const entry = DEFAULT_PALETTE.find(p => p.color === value || p.name === value)
+    return entry ? entry.name : 'gray'
+}
+
+/**
+ * Returns the hex code to edit for a stored color, translating legacy named
+ * colors to their palette entry so that saving them again passes validation.
+ *
+ * @param {string} color - The stored color.
+ * @param {Array<{name: string, color: string}>} [palette]
+ */
+export function paletteHex(color, palette = DEFAULT_PALETTE) {
+    if (!color) return ''
+    const entry = palette.find(p => p.name === color.toLowerCase())
+    return entry ? entry.color : color
+}